@@ -0,0 +1,112 @@
+// Package buildinfo reports the version, commit and enabled features of the
+// running library or service, so that behaviour changes can be correlated
+// with deployed versions.
+//
+// The version and commit can be set at build time:
+//
+//	go build -ldflags "-X github.com/agill17/pkg/buildinfo.version=v1.2.0 -X github.com/agill17/pkg/buildinfo.commit=abc1234"
+//
+// If the version is not set, it's read from the module build info.
+//
+// Building with the minimal tag removes the optional subsystems that need
+// network services, and their dependencies, e.g. the Kubernetes LeaseLocker:
+//
+//	go build -tags minimal
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// Name identifies this module in User-Agents and trailers.
+const Name = "agill17-pkg"
+
+const modulePath = "github.com/agill17/pkg"
+
+var (
+	version string
+	commit  string
+
+	mu       sync.Mutex
+	features = map[string]bool{}
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string          `json:"version"`
+	Commit    string          `json:"commit,omitempty"`
+	GoVersion string          `json:"goVersion"`
+	Features  map[string]bool `json:"features,omitempty"`
+	// Minimal is true if the build has the minimal tag, which removes the
+	// optional subsystems that need network services.
+	Minimal bool `json:"minimal,omitempty"`
+}
+
+// Get returns the Info for the running build.
+func Get() Info {
+	mu.Lock()
+	defer mu.Unlock()
+	i := Info{Version: version, Commit: commit, GoVersion: runtime.Version(), Minimal: minimal}
+	if i.Version == "" {
+		i.Version = moduleVersion()
+	}
+	if len(features) > 0 {
+		i.Features = make(map[string]bool, len(features))
+		for k, v := range features {
+			i.Features[k] = v
+		}
+	}
+	return i
+}
+
+// SetFeature records whether a feature is enabled, so that it's reported in
+// the build info.
+func SetFeature(name string, enabled bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	features[name] = enabled
+}
+
+// String returns the version, and if known, the commit e.g. "v1.2.0 (abc1234)".
+func (i Info) String() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	return i.Version + " (" + i.Commit + ")"
+}
+
+// EnabledFeatures returns the names of the enabled features in sorted order.
+func (i Info) EnabledFeatures() []string {
+	enabled := []string{}
+	for k, v := range i.Features {
+		if v {
+			enabled = append(enabled, k)
+		}
+	}
+	sort.Strings(enabled)
+	return enabled
+}
+
+// UserAgent returns a User-Agent for the build e.g. "agill17-pkg/v1.2.0".
+func (i Info) UserAgent() string {
+	return Name + "/" + i.Version
+}
+
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
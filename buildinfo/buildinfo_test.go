@@ -0,0 +1,75 @@
+package buildinfo
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestGet(t *testing.T) {
+	defer reset()
+	version, commit = "v1.2.0", "abc1234"
+	SetFeature("semantic-diff", true)
+	SetFeature("encryption", false)
+
+	want := Info{
+		Version:   "v1.2.0",
+		Commit:    "abc1234",
+		GoVersion: runtime.Version(),
+		Features:  map[string]bool{"semantic-diff": true, "encryption": false},
+		Minimal:   minimal,
+	}
+	if diff := cmp.Diff(want, Get()); diff != "" {
+		t.Fatalf("failed to get build info:\n%s", diff)
+	}
+}
+
+func TestGetWithNoVersion(t *testing.T) {
+	defer reset()
+
+	i := Get()
+
+	// Tests don't have module build info for the main module.
+	if i.Version == "" {
+		t.Fatalf("no version reported: %#v", i)
+	}
+	if i.Features != nil {
+		t.Fatalf("got features %#v, want none", i.Features)
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	stringTests := []struct {
+		info Info
+		want string
+	}{
+		{Info{Version: "v1.2.0"}, "v1.2.0"},
+		{Info{Version: "v1.2.0", Commit: "abc1234"}, "v1.2.0 (abc1234)"},
+	}
+
+	for _, tt := range stringTests {
+		if s := tt.info.String(); s != tt.want {
+			t.Errorf("String() got %q, want %q", s, tt.want)
+		}
+	}
+}
+
+func TestEnabledFeatures(t *testing.T) {
+	i := Info{Features: map[string]bool{"b": true, "c": false, "a": true}}
+
+	if diff := cmp.Diff([]string{"a", "b"}, i.EnabledFeatures()); diff != "" {
+		t.Fatalf("failed to get features:\n%s", diff)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	if ua := (Info{Version: "(devel)"}).UserAgent(); ua != "agill17-pkg/(devel)" {
+		t.Fatalf("got %q, want %q", ua, "agill17-pkg/(devel)")
+	}
+}
+
+func reset() {
+	version, commit = "", ""
+	features = map[string]bool{}
+}
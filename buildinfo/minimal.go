@@ -0,0 +1,7 @@
+//go:build minimal
+// +build minimal
+
+package buildinfo
+
+// minimal is true when built with the minimal tag.
+const minimal = true
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+const apiVersionHeader = "X-GitHub-Api-Version"
+
+// APIVersion is an option func for the SCMClient creation function, it pins
+// the version of the GitHub REST API e.g. "2022-11-28", other drivers ignore
+// it.
+//
+// If the server rejects the version, e.g. an older GitHub Enterprise Server,
+// the request is retried without it, and the server's default version is used
+// for the rest of the client's calls.
+func APIVersion(version string) Option {
+	return func(o *options) {
+		o.apiVersion = version
+	}
+}
+
+// apiVersionTransport sends the API version with each request, until the
+// server rejects it.
+type apiVersionTransport struct {
+	version  string
+	rejected int32
+	next     http.RoundTripper
+}
+
+func (t *apiVersionTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if atomic.LoadInt32(&t.rejected) == 1 {
+		return t.next.RoundTrip(r)
+	}
+	versioned := r.Clone(r.Context())
+	versioned.Header.Set(apiVersionHeader, t.version)
+	if r.Body != nil && r.GetBody == nil {
+		// The body can't be replayed, so the request can't be retried.
+		return t.next.RoundTrip(versioned)
+	}
+	if r.GetBody != nil {
+		body, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		versioned.Body = body
+	}
+	res, err := t.next.RoundTrip(versioned)
+	if err != nil || res.StatusCode != http.StatusBadRequest {
+		return res, err
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(bytes.ToLower(b), []byte("x-github-api-version")) {
+		res.Body = ioutil.NopCloser(bytes.NewReader(b))
+		return res, nil
+	}
+	atomic.StoreInt32(&t.rejected, 1)
+	retry := r.Clone(r.Context())
+	if r.GetBody != nil {
+		if retry.Body, err = r.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.next.RoundTrip(retry)
+}
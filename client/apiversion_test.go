@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestAPIVersion(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/refs/heads/master").
+		MatchHeader(apiVersionHeader, "^2022-11-28$").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/single_ref.json")
+	defer gock.Off()
+	client, err := NewClient("github", "", "", APIVersion("2022-11-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetBranchHead(context.TODO(), "Codertocat/Hello-World", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the API version was not sent")
+	}
+}
+
+func TestAPIVersionRejected(t *testing.T) {
+	gock.New("https://ghe.example.com").
+		Post("/api/v3/repos/Codertocat/Hello-World/issues/2/comments").
+		MatchHeader(apiVersionHeader, "^2022-11-28$").
+		Reply(http.StatusBadRequest).
+		Type("application/json").
+		JSON(map[string]string{"message": "Unsupported 'X-GitHub-Api-Version': 2022-11-28"})
+	for i := 0; i < 2; i++ {
+		gock.New("https://ghe.example.com").
+			Post("/api/v3/repos/Codertocat/Hello-World/issues/2/comments").
+			AddMatcher(withoutAPIVersion).
+			BodyString(`"body":"Please review"`).
+			Reply(http.StatusCreated).
+			Type("application/json").
+			JSON(map[string]interface{}{"id": 1})
+	}
+	defer gock.Off()
+	client, err := NewClient("github", "https://ghe.example.com", "", APIVersion("2022-11-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first comment is retried without the version, and the second is
+	// sent without it.
+	for i := 0; i < 2; i++ {
+		if err := client.CreateComment(context.TODO(), "Codertocat/Hello-World", 2, "Please review"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !gock.IsDone() {
+		t.Fatal("the API version was not dropped")
+	}
+}
+
+func TestAPIVersionWithOtherBadRequests(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/2/comments").
+		MatchHeader(apiVersionHeader, "^2022-11-28$").
+		Reply(http.StatusBadRequest).
+		Type("application/json").
+		JSON(map[string]string{"message": "Problems parsing JSON"})
+	defer gock.Off()
+	client, err := NewClient("github", "", "", APIVersion("2022-11-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.CreateComment(context.TODO(), "Codertocat/Hello-World", 2, "Please review")
+
+	if StatusCode(err) != http.StatusBadRequest {
+		t.Fatalf("got %v, want a bad request error", err)
+	}
+}
+
+func TestAPIVersionIgnoredByGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World").
+		AddMatcher(withoutAPIVersion).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]bool{"archived": false})
+	defer gock.Off()
+	client, err := NewClient("gitlab", "", "", APIVersion("2022-11-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.GetRepoStatus(context.TODO(), "Codertocat/Hello-World"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the API version was sent")
+	}
+}
+
+func withoutAPIVersion(r *http.Request, _ *gock.Request) (bool, error) {
+	return r.Header.Get(apiVersionHeader) == "", nil
+}
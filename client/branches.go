@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// BranchHeadStats records the use of a BranchHeadCache.
+type BranchHeadStats struct {
+	Hits          int
+	Misses        int
+	Invalidations int
+}
+
+// NewBranchHeadCache wraps a GitClient and caches the heads of branches.
+func NewBranchHeadCache(c GitClient) *BranchHeadCache {
+	return &BranchHeadCache{GitClient: c, heads: make(map[string]string)}
+}
+
+// BranchHeadCache is a GitClient that caches the results of GetBranchHead, so
+// that several updates in a batch see consistent refs without refetching
+// them.
+//
+// Creating or resetting a branch records the head of the branch, updating a
+// file invalidates the cached head for the branch it's committed to, deleting
+// a branch invalidates its cached head, and merging a PullRequest, or getting
+// a merged PullRequest, invalidates the cached head of its target branch.
+type BranchHeadCache struct {
+	GitClient
+
+	mu    sync.Mutex
+	heads map[string]string
+	stats BranchHeadStats
+}
+
+// GetBranchHead returns the cached head of the branch, fetching it if it's
+// not cached.
+func (c *BranchHeadCache) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
+	c.mu.Lock()
+	sha, ok := c.heads[branchKey(repo, branch)]
+	if ok {
+		c.stats.Hits++
+	} else {
+		c.stats.Misses++
+	}
+	c.mu.Unlock()
+	if ok {
+		return sha, nil
+	}
+	sha, err := c.GitClient.GetBranchHead(ctx, repo, branch)
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heads[branchKey(repo, branch)] = sha
+	return sha, nil
+}
+
+// CreateBranch creates the branch, and records the SHA as its head.
+func (c *BranchHeadCache) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	if err := c.GitClient.CreateBranch(ctx, repo, branch, sha); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heads[branchKey(repo, branch)] = sha
+	return nil
+}
+
+// ResetBranch resets the branch, and records the SHA as its head.
+func (c *BranchHeadCache) ResetBranch(ctx context.Context, repo, branch, sha string) error {
+	if err := c.GitClient.ResetBranch(ctx, repo, branch, sha); err != nil {
+		c.Invalidate(repo, branch)
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.heads[branchKey(repo, branch)] = sha
+	return nil
+}
+
+// UpdateFile commits the file, and invalidates the cached head of the branch,
+// even if the update fails, as the branch may have changed.
+func (c *BranchHeadCache) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error {
+	defer c.Invalidate(repo, branch)
+	return c.GitClient.UpdateFile(ctx, repo, branch, path, message, previousSHA, content)
+}
+
+// DeleteBranch deletes the branch, and invalidates its cached head, even if
+// the delete fails, as the branch may have been deleted.
+func (c *BranchHeadCache) DeleteBranch(ctx context.Context, repo, branch string) error {
+	defer c.Invalidate(repo, branch)
+	return c.GitClient.DeleteBranch(ctx, repo, branch)
+}
+
+// MergePullRequest merges the PullRequest, and invalidates the cached head of
+// its target branch, even if the merge fails, as it may have been merged, all
+// the cached heads of the repo are invalidated if the target isn't known.
+func (c *BranchHeadCache) MergePullRequest(ctx context.Context, repo string, number int, method string) error {
+	defer c.invalidateTarget(ctx, repo, number)
+	return c.GitClient.MergePullRequest(ctx, repo, number, method)
+}
+
+// GetPullRequest returns the PullRequest, and invalidates the cached head of
+// the target branch if it's merged, as it may have been merged after the head
+// was cached, e.g. with auto-merge.
+func (c *BranchHeadCache) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	pr, err := c.GitClient.GetPullRequest(ctx, repo, number)
+	if err == nil && pr.Merged {
+		c.Invalidate(repo, pr.Target)
+	}
+	return pr, err
+}
+
+func (c *BranchHeadCache) invalidateTarget(ctx context.Context, repo string, number int) {
+	pr, err := c.GitClient.GetPullRequest(ctx, repo, number)
+	if err != nil || pr.Target == "" {
+		c.invalidateRepo(repo)
+		return
+	}
+	c.Invalidate(repo, pr.Target)
+}
+
+func (c *BranchHeadCache) invalidateRepo(repo string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.heads {
+		if strings.HasPrefix(k, repo+":") {
+			delete(c.heads, k)
+			c.stats.Invalidations++
+		}
+	}
+}
+
+// Invalidate removes the cached head of the branch.
+func (c *BranchHeadCache) Invalidate(repo, branch string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.heads[branchKey(repo, branch)]; ok {
+		delete(c.heads, branchKey(repo, branch))
+		c.stats.Invalidations++
+	}
+}
+
+// Stats returns the hits, misses and invalidations of the cache.
+func (c *BranchHeadCache) Stats() BranchHeadStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+func branchKey(repo, branch string) string {
+	return repo + ":" + branch
+}
@@ -0,0 +1,276 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+var _ GitClient = (*BranchHeadCache)(nil)
+
+type fakeBranchClient struct {
+	GitClient
+	heads     map[string]string
+	prs       map[int]*scm.PullRequest
+	fetches   int
+	updateErr error
+}
+
+func (f *fakeBranchClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	pr, ok := f.prs[number]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return pr, nil
+}
+
+func (f *fakeBranchClient) MergePullRequest(ctx context.Context, repo string, number int, method string) error {
+	pr, ok := f.prs[number]
+	if !ok {
+		return errors.New("not found")
+	}
+	pr.Merged = true
+	f.heads[branchKey(repo, pr.Target)] = "merge-sha"
+	return nil
+}
+
+func (f *fakeBranchClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
+	f.fetches++
+	sha, ok := f.heads[branchKey(repo, branch)]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return sha, nil
+}
+
+func (f *fakeBranchClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	f.heads[branchKey(repo, branch)] = sha
+	return nil
+}
+
+func (f *fakeBranchClient) ResetBranch(ctx context.Context, repo, branch, sha string) error {
+	f.heads[branchKey(repo, branch)] = sha
+	return nil
+}
+
+func (f *fakeBranchClient) DeleteBranch(ctx context.Context, repo, branch string) error {
+	delete(f.heads, branchKey(repo, branch))
+	return nil
+}
+
+func (f *fakeBranchClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error {
+	if f.updateErr != nil {
+		return f.updateErr
+	}
+	f.heads[branchKey(repo, branch)] = "new-sha"
+	return nil
+}
+
+func TestBranchHeadCache(t *testing.T) {
+	fake := &fakeBranchClient{heads: map[string]string{"my-org/my-repo:main": "main-sha"}}
+	cache := NewBranchHeadCache(fake)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", "main")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sha != "main-sha" {
+			t.Fatalf("got %q, want %q", sha, "main-sha")
+		}
+	}
+	if fake.fetches != 1 {
+		t.Fatalf("branch head fetched %d times, want 1", fake.fetches)
+	}
+	if diff := cmp.Diff(BranchHeadStats{Hits: 1, Misses: 1}, cache.Stats()); diff != "" {
+		t.Fatalf("incorrect stats:\n%s", diff)
+	}
+}
+
+func TestBranchHeadCacheWithCreateBranch(t *testing.T) {
+	fake := &fakeBranchClient{heads: map[string]string{}}
+	cache := NewBranchHeadCache(fake)
+	ctx := context.Background()
+
+	if err := cache.CreateBranch(ctx, "my-org/my-repo", "update-a", "main-sha"); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", "update-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sha != "main-sha" || fake.fetches != 0 {
+		t.Fatalf("got %q after %d fetches, want %q from the cache", sha, fake.fetches, "main-sha")
+	}
+}
+
+func TestBranchHeadCacheInvalidatesOnUpdateFile(t *testing.T) {
+	updateTests := []struct {
+		name      string
+		updateErr error
+		want      string
+	}{
+		{"successful update", nil, "new-sha"},
+		{"failed update", errors.New("conflict"), "main-sha"},
+	}
+
+	for _, tt := range updateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			fake := &fakeBranchClient{heads: map[string]string{"my-org/my-repo:main": "main-sha"}, updateErr: tt.updateErr}
+			cache := NewBranchHeadCache(fake)
+			ctx := context.Background()
+			if _, err := cache.GetBranchHead(ctx, "my-org/my-repo", "main"); err != nil {
+				rt.Fatal(err)
+			}
+
+			err := cache.UpdateFile(ctx, "my-org/my-repo", "main", "test.yaml", "testing", "file-sha", []byte("test"))
+			if err != tt.updateErr {
+				rt.Fatalf("got error %v, want %v", err, tt.updateErr)
+			}
+
+			sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", "main")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if sha != tt.want {
+				rt.Fatalf("got %q, want %q", sha, tt.want)
+			}
+			if diff := cmp.Diff(BranchHeadStats{Misses: 2, Invalidations: 1}, cache.Stats()); diff != "" {
+				rt.Fatalf("incorrect stats:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBranchHeadCacheInvalidatesOnDeleteBranch(t *testing.T) {
+	fake := &fakeBranchClient{heads: map[string]string{"my-org/my-repo:update-image": "old-sha"}}
+	cache := NewBranchHeadCache(fake)
+	ctx := context.Background()
+	if _, err := cache.GetBranchHead(ctx, "my-org/my-repo", "update-image"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cache.DeleteBranch(ctx, "my-org/my-repo", "update-image"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.GetBranchHead(ctx, "my-org/my-repo", "update-image"); err == nil {
+		t.Fatal("expected an error for the deleted branch")
+	}
+	if diff := cmp.Diff(BranchHeadStats{Misses: 2, Invalidations: 1}, cache.Stats()); diff != "" {
+		t.Fatalf("incorrect stats:\n%s", diff)
+	}
+}
+
+func TestBranchHeadCacheDoesNotCacheErrors(t *testing.T) {
+	fake := &fakeBranchClient{heads: map[string]string{}}
+	cache := NewBranchHeadCache(fake)
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetBranchHead(context.Background(), "my-org/my-repo", "main"); err == nil {
+			t.Fatal("expected an error")
+		}
+	}
+	if fake.fetches != 2 {
+		t.Fatalf("branch head fetched %d times, want 2", fake.fetches)
+	}
+}
+
+func TestBranchHeadCacheWithResetBranch(t *testing.T) {
+	fake := &fakeBranchClient{heads: map[string]string{"my-org/my-repo:update-image": "old-sha"}}
+	cache := NewBranchHeadCache(fake)
+	ctx := context.Background()
+
+	if err := cache.ResetBranch(ctx, "my-org/my-repo", "update-image", "main-sha"); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", "update-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sha != "main-sha" {
+		t.Fatalf("got %q, want %q", sha, "main-sha")
+	}
+	if fake.fetches != 0 {
+		t.Fatalf("branch head fetched %d times, want 0", fake.fetches)
+	}
+}
+
+func TestBranchHeadCacheInvalidatesOnMergePullRequest(t *testing.T) {
+	mergeTests := []struct {
+		name   string
+		number int
+		want   map[string]string
+		stats  BranchHeadStats
+	}{
+		{"merged target", 1, map[string]string{"main": "merge-sha", "release": "release-sha"}, BranchHeadStats{Hits: 1, Misses: 3, Invalidations: 1}},
+		{"unknown pull request", 2, map[string]string{"main": "main-sha", "release": "release-sha"}, BranchHeadStats{Misses: 4, Invalidations: 2}},
+	}
+
+	for _, tt := range mergeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			fake := &fakeBranchClient{
+				heads: map[string]string{"my-org/my-repo:main": "main-sha", "my-org/my-repo:release": "release-sha"},
+				prs:   map[int]*scm.PullRequest{1: {Number: 1, Target: "main"}},
+			}
+			cache := NewBranchHeadCache(fake)
+			ctx := context.Background()
+			for _, branch := range []string{"main", "release"} {
+				if _, err := cache.GetBranchHead(ctx, "my-org/my-repo", branch); err != nil {
+					rt.Fatal(err)
+				}
+			}
+
+			cache.MergePullRequest(ctx, "my-org/my-repo", tt.number, "squash")
+
+			got := map[string]string{}
+			for _, branch := range []string{"main", "release"} {
+				sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", branch)
+				if err != nil {
+					rt.Fatal(err)
+				}
+				got[branch] = sha
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				rt.Fatalf("incorrect heads:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.stats, cache.Stats()); diff != "" {
+				rt.Fatalf("incorrect stats:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBranchHeadCacheInvalidatesOnGetMergedPullRequest(t *testing.T) {
+	fake := &fakeBranchClient{
+		heads: map[string]string{"my-org/my-repo:main": "main-sha"},
+		prs:   map[int]*scm.PullRequest{1: {Number: 1, Target: "main"}},
+	}
+	cache := NewBranchHeadCache(fake)
+	ctx := context.Background()
+	if _, err := cache.GetBranchHead(ctx, "my-org/my-repo", "main"); err != nil {
+		t.Fatal(err)
+	}
+	// Merged outside the cache, e.g. with auto-merge.
+	if err := fake.MergePullRequest(ctx, "my-org/my-repo", 1, "squash"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cache.GetPullRequest(ctx, "my-org/my-repo", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	sha, err := cache.GetBranchHead(ctx, "my-org/my-repo", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "merge-sha" {
+		t.Fatalf("got %q, want %q", sha, "merge-sha")
+	}
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// DefaultRefreshBefore is how long before the credentials expire that a
+// cached client is replaced.
+const DefaultRefreshBefore = 5 * time.Minute
+
+// Clock provides the current time, it can be replaced in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// ClientFactory creates an authenticated client for a key, e.g. a GitHub App
+// installation ID, or OAuth user, and returns the time at which the
+// credentials expire.
+//
+// A zero expiry time indicates that the credentials do not expire.
+type ClientFactory func(ctx context.Context, key string) (*scm.Client, time.Time, error)
+
+// CacheFunc is an option for creating new ClientCaches.
+type CacheFunc func(c *ClientCache)
+
+// CacheClock is an option func for the ClientCache creation function, it
+// replaces the Clock used to check expiry times.
+func CacheClock(clock Clock) CacheFunc {
+	return func(c *ClientCache) {
+		c.clock = clock
+	}
+}
+
+// RefreshBefore is an option func for the ClientCache creation function, it
+// configures how long before expiry clients are refreshed.
+func RefreshBefore(d time.Duration) CacheFunc {
+	return func(c *ClientCache) {
+		c.refreshBefore = d
+	}
+}
+
+// CacheClientOptions is an option func for the ClientCache creation function,
+// the options are used when creating SCMClients e.g. to set the User-Agent.
+func CacheClientOptions(opts ...Option) CacheFunc {
+	return func(c *ClientCache) {
+		c.clientOptions = opts
+	}
+}
+
+// NewClientCache creates and returns a new ClientCache.
+func NewClientCache(f ClientFactory, opts ...CacheFunc) *ClientCache {
+	c := &ClientCache{
+		factory:       f,
+		clock:         realClock{},
+		refreshBefore: DefaultRefreshBefore,
+		entries:       make(map[string]cachedClient),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// ClientCache caches authenticated clients, replacing them before their
+// credentials expire, so that long-running services don't fail midway
+// through an update.
+type ClientCache struct {
+	factory       ClientFactory
+	clock         Clock
+	refreshBefore time.Duration
+	clientOptions []Option
+
+	mu      sync.Mutex
+	entries map[string]cachedClient
+}
+
+type cachedClient struct {
+	client  *SCMClient
+	expires time.Time
+}
+
+// Get returns a cached client for the key, creating a new one if there is no
+// cached client, or the credentials for the cached client are about to
+// expire.
+func (c *ClientCache) Get(ctx context.Context, key string) (*SCMClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[key]; ok && !c.needsRefresh(e) {
+		return e.client, nil
+	}
+	scmClient, expires, err := c.factory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	e := cachedClient{client: New(scmClient, c.clientOptions...), expires: expires}
+	c.entries[key] = e
+	return e.client, nil
+}
+
+// Invalidate removes the cached client for the key, e.g. when the
+// credentials have been revoked.
+func (c *ClientCache) Invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *ClientCache) needsRefresh(e cachedClient) bool {
+	if e.expires.IsZero() {
+		return false
+	}
+	return !c.clock.Now().Add(c.refreshBefore).Before(e.expires)
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	return f.now
+}
+
+func TestClientCache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, time.July, 1, 12, 0, 0, 0, time.UTC)}
+	created := 0
+	cache := NewClientCache(func(ctx context.Context, key string) (*scm.Client, time.Time, error) {
+		created++
+		return &scm.Client{}, clock.now.Add(time.Hour), nil
+	}, CacheClock(clock))
+
+	first, err := cache.Get(context.Background(), "installation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(50 * time.Minute)
+	second, err := cache.Get(context.Background(), "installation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second || created != 1 {
+		t.Fatalf("client was not cached, created %d clients", created)
+	}
+
+	// Within the refresh period of the expiry time.
+	clock.now = clock.now.Add(6 * time.Minute)
+	third, err := cache.Get(context.Background(), "installation-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == second || created != 2 {
+		t.Fatalf("client was not refreshed, created %d clients", created)
+	}
+}
+
+func TestClientCacheWithNoExpiry(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2020, time.July, 1, 12, 0, 0, 0, time.UTC)}
+	created := 0
+	cache := NewClientCache(func(ctx context.Context, key string) (*scm.Client, time.Time, error) {
+		created++
+		return &scm.Client{}, time.Time{}, nil
+	}, CacheClock(clock))
+
+	if _, err := cache.Get(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	clock.now = clock.now.Add(24 * 365 * time.Hour)
+	if _, err := cache.Get(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if created != 1 {
+		t.Fatalf("got %d clients created, want 1", created)
+	}
+}
+
+func TestClientCacheInvalidate(t *testing.T) {
+	created := 0
+	cache := NewClientCache(func(ctx context.Context, key string) (*scm.Client, time.Time, error) {
+		created++
+		return &scm.Client{}, time.Time{}, nil
+	})
+
+	if _, err := cache.Get(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+	cache.Invalidate("token")
+	if _, err := cache.Get(context.Background(), "token"); err != nil {
+		t.Fatal(err)
+	}
+
+	if created != 2 {
+		t.Fatalf("got %d clients created, want 2", created)
+	}
+}
+
+func TestClientCacheWithFactoryError(t *testing.T) {
+	testErr := errors.New("bad credentials")
+	cache := NewClientCache(func(ctx context.Context, key string) (*scm.Client, time.Time, error) {
+		return nil, time.Time{}, testErr
+	})
+
+	_, err := cache.Get(context.Background(), "token")
+
+	if err != testErr {
+		t.Fatalf("got %s, want %s", err, testErr)
+	}
+}
@@ -0,0 +1,121 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// Capabilities describes the optional features supported by a git provider,
+// higher-level features consult these to degrade gracefully.
+type Capabilities struct {
+	// Version is the version of the provider, where it is known, e.g. for
+	// GitHub Enterprise Server.
+	Version           string
+	DraftPullRequests bool
+	AutoMerge         bool
+	TreeAPI           bool
+	// Variables is true if CI variables can be read and updated.
+	Variables bool
+	// Releases is true if annotated tags and Releases can be created.
+	Releases bool
+	// BranchRefPrefix is prepended to branch names when creating refs.
+	BranchRefPrefix string
+}
+
+var capabilityCache = struct {
+	sync.Mutex
+	hosts map[string]Capabilities
+}{hosts: map[string]Capabilities{}}
+
+// Capabilities returns the capabilities of the provider, these are detected
+// from the driver, and for GitHub Enterprise, the installed version.
+//
+// The detected capabilities are cached per host.
+func (c *SCMClient) Capabilities(ctx context.Context) (Capabilities, error) {
+	key := c.scmClient.Driver.String() + ":" + c.scmClient.BaseURL.Host
+	capabilityCache.Lock()
+	caps, ok := capabilityCache.hosts[key]
+	capabilityCache.Unlock()
+	if ok {
+		return caps, nil
+	}
+	caps = driverCapabilities(c.scmClient.Driver)
+	if isGitHub(c.scmClient) && c.scmClient.BaseURL.Host != "api.github.com" {
+		v, err := c.gitHubEnterpriseVersion(ctx)
+		if err != nil {
+			return caps, err
+		}
+		caps = gitHubEnterpriseCapabilities(caps, v)
+	}
+	capabilityCache.Lock()
+	capabilityCache.hosts[key] = caps
+	capabilityCache.Unlock()
+	return caps, nil
+}
+
+func (c *SCMClient) gitHubEnterpriseVersion(ctx context.Context) (string, error) {
+	res, err := c.scmClient.Do(ctx, &scm.Request{Method: "GET", Path: "meta"})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) {
+		return "", scmError{msg: "failed to get the GitHub Enterprise version", Status: res.Status}
+	}
+	meta := struct {
+		InstalledVersion string `json:"installed_version"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&meta); err != nil {
+		return "", fmt.Errorf("failed to decode the GitHub Enterprise version: %w", err)
+	}
+	return meta.InstalledVersion, nil
+}
+
+func driverCapabilities(d scm.Driver) Capabilities {
+	switch d {
+	case scm.DriverGithub:
+		return Capabilities{DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true, BranchRefPrefix: "refs/heads/"}
+	case scm.DriverGitlab:
+		return Capabilities{DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true}
+	default:
+		return Capabilities{}
+	}
+}
+
+// gitHubEnterpriseCapabilities disables features that are not available in
+// older GitHub Enterprise Server versions.
+//
+// Draft PullRequests were added in 2.17, auto-merge in 3.1 and Actions
+// variables in 3.8.
+func gitHubEnterpriseCapabilities(caps Capabilities, version string) Capabilities {
+	caps.Version = version
+	if version == "" {
+		return caps
+	}
+	caps.DraftPullRequests = versionAtLeast(version, 2, 17)
+	caps.AutoMerge = versionAtLeast(version, 3, 1)
+	caps.Variables = versionAtLeast(version, 3, 8)
+	return caps
+}
+
+func versionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	gotMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	gotMinor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return gotMajor > major || (gotMajor == major && gotMinor >= minor)
+}
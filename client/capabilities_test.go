@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestCapabilities(t *testing.T) {
+	capabilityTests := []struct {
+		driver string
+		want   Capabilities
+	}{
+		{"github", Capabilities{DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true, BranchRefPrefix: "refs/heads/"}},
+		{"gitlab", Capabilities{DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true}},
+		{"bitbucketcloud", Capabilities{}},
+	}
+
+	for _, tt := range capabilityTests {
+		t.Run(tt.driver, func(rt *testing.T) {
+			scmClient, err := factory.NewClient(tt.driver, "", "")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			client := New(scmClient)
+
+			caps, err := client.Capabilities(context.Background())
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, caps); diff != "" {
+				rt.Fatalf("capabilities differ:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesWithGitHubEnterprise(t *testing.T) {
+	versionTests := []struct {
+		host    string
+		version string
+		want    Capabilities
+	}{
+		{"https://ghe1.example.com", "2.16.3", Capabilities{Version: "2.16.3", TreeAPI: true, Releases: true, BranchRefPrefix: "refs/heads/"}},
+		{"https://ghe2.example.com", "2.22.0", Capabilities{Version: "2.22.0", DraftPullRequests: true, TreeAPI: true, Releases: true, BranchRefPrefix: "refs/heads/"}},
+		{"https://ghe3.example.com", "3.1.0", Capabilities{Version: "3.1.0", DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Releases: true, BranchRefPrefix: "refs/heads/"}},
+		{"https://ghe4.example.com", "3.8.2", Capabilities{Version: "3.8.2", DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true, BranchRefPrefix: "refs/heads/"}},
+	}
+
+	for _, tt := range versionTests {
+		t.Run(tt.version, func(rt *testing.T) {
+			gock.New(tt.host).
+				Get("/api/v3/meta").
+				Times(1).
+				Reply(http.StatusOK).
+				Type("application/json").
+				JSON(map[string]string{"installed_version": tt.version})
+			defer gock.Off()
+			scmClient, err := factory.NewClient("github", tt.host+"/api/v3", "")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			client := New(scmClient)
+
+			caps, err := client.Capabilities(context.Background())
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, caps); diff != "" {
+				rt.Fatalf("capabilities differ:\n%s", diff)
+			}
+			// The second call is cached and makes no request.
+			cached, err := client.Capabilities(context.Background())
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, cached); diff != "" {
+				rt.Fatalf("cached capabilities differ:\n%s", diff)
+			}
+			if !gock.IsDone() {
+				rt.Fatal("version was not fetched")
+			}
+		})
+	}
+}
+
+func TestCapabilitiesWithGitHubEnterpriseRejectingAPIVersion(t *testing.T) {
+	gock.New("https://ghe5.example.com").
+		Get("/api/v3/meta").
+		MatchHeader(apiVersionHeader, "^2022-11-28$").
+		Reply(http.StatusBadRequest).
+		Type("application/json").
+		JSON(map[string]string{"message": "Unsupported 'X-GitHub-Api-Version': 2022-11-28"})
+	gock.New("https://ghe5.example.com").
+		Get("/api/v3/meta").
+		AddMatcher(withoutAPIVersion).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]string{"installed_version": "3.0.4"})
+	defer gock.Off()
+	client, err := NewClient("github", "https://ghe5.example.com", "", APIVersion("2022-11-28"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caps, err := client.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Capabilities{Version: "3.0.4", DraftPullRequests: true, TreeAPI: true, Releases: true, BranchRefPrefix: "refs/heads/"}
+	if diff := cmp.Diff(want, caps); diff != "" {
+		t.Fatalf("capabilities differ:\n%s", diff)
+	}
+}
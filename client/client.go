@@ -3,11 +3,26 @@ package client
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
 	"github.com/jenkins-x/go-scm/scm"
 )
 
 // New creates and returns a new SCMClient.
-func New(c *scm.Client) *SCMClient {
+//
+// All provider calls made by the client identify it with a User-Agent, which
+// defaults to DefaultUserAgent.
+func New(c *scm.Client, opts ...Option) *SCMClient {
+	o := &options{userAgent: DefaultUserAgent(), header: http.Header{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	c.Client = annotate(c.Client, o)
+	if o.apiVersion != "" && isGitHub(c) {
+		c.Client.Transport = &apiVersionTransport{version: o.apiVersion, next: c.Client.Transport}
+	}
 	return &SCMClient{scmClient: c}
 }
 
@@ -31,15 +46,245 @@ func (c *SCMClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.C
 	return content, nil
 }
 
+// ListFiles returns the paths of the files in the directory in the repo, and
+// its subdirectories, an empty directory lists all the files in the repo.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListFiles(ctx context.Context, repo, ref, dir string) ([]string, error) {
+	type entry struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}
+	entries := []entry{}
+	var status int
+	var err error
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		tree := struct {
+			Tree      []entry `json:"tree"`
+			Truncated bool    `json:"truncated"`
+		}{}
+		status, err = c.getJSON(ctx, fmt.Sprintf("repos/%s/git/trees/%s?recursive=1", repo, ref), &tree)
+		if err == nil && tree.Truncated {
+			// Large trees exceed the limit of the recursive listing, which is
+			// lower in older Enterprise Servers.
+			return c.listGitHubContents(ctx, repo, ref, strings.TrimSuffix(dir, "/"))
+		}
+		entries = tree.Tree
+	case scm.DriverGitlab:
+		for page := 1; ; page++ {
+			pageEntries := []entry{}
+			status, err = c.getJSON(ctx, fmt.Sprintf("api/v4/projects/%s/repository/tree?path=%s&ref=%s&recursive=true&per_page=100&page=%d", gitLabProject(repo), url.QueryEscape(dir), url.QueryEscape(ref), page), &pageEntries)
+			entries = append(entries, pageEntries...)
+			if err != nil || isErrorStatus(status) || len(pageEntries) < 100 {
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("listing files is not supported by the %s driver", c.scmClient.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to list files in %s from repo %s ref %s", dir, repo, ref), Status: status}
+	}
+	prefix := ""
+	if dir != "" {
+		prefix = strings.TrimSuffix(dir, "/") + "/"
+	}
+	files := []string{}
+	for _, e := range entries {
+		if e.Type == "blob" && strings.HasPrefix(e.Path, prefix) {
+			files = append(files, e.Path)
+		}
+	}
+	return files, nil
+}
+
+// listGitHubContents lists the files in the directory, and its
+// subdirectories, with the contents API, one directory at a time.
+func (c *SCMClient) listGitHubContents(ctx context.Context, repo, ref, dir string) ([]string, error) {
+	entries := []struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+	}{}
+	status, err := c.getJSON(ctx, fmt.Sprintf("repos/%s/contents/%s?ref=%s", repo, dir, url.QueryEscape(ref)), &entries)
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to list files in %s from repo %s ref %s", dir, repo, ref), Status: status}
+	}
+	files := []string{}
+	for _, e := range entries {
+		switch e.Type {
+		case "file":
+			files = append(files, e.Path)
+		case "dir":
+			nested, err := c.listGitHubContents(ctx, repo, ref, e.Path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, nested...)
+		}
+	}
+	return files, nil
+}
+
 // CreateBranch will create a new branch in the repo from the SHA.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
 func (c *SCMClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
-	if isGitHub(c.scmClient) {
-		branch = fmt.Sprintf("refs/heads/%s", branch)
+	ref := driverCapabilities(c.scmClient.Driver).BranchRefPrefix + branch
+	_, r, err := c.scmClient.Git.CreateRef(ctx, repo, ref, sha)
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to create branch %s in repo %s", branch, repo), Status: r.Status}
 	}
-	_, _, err := c.scmClient.Git.CreateRef(ctx, repo, branch, sha)
 	return err
 }
 
+// ResetBranch points the branch at the SHA, creating it if it doesn't exist,
+// commits on the branch that aren't reachable from the SHA are discarded.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ResetBranch(ctx context.Context, repo, branch, sha string) error {
+	if err := c.CreateBranch(ctx, repo, branch, sha); err == nil {
+		return nil
+	}
+	var status int
+	var err error
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		status, err = c.sendJSON(ctx, http.MethodPatch, fmt.Sprintf("repos/%s/git/refs/heads/%s", repo, branch), map[string]interface{}{"sha": sha, "force": true}, nil)
+	case scm.DriverGitlab:
+		// GitLab can't move a branch, it's recreated at the SHA, open
+		// MergeRequests from the branch are retained.
+		var res *scm.Response
+		res, err = c.scmClient.Do(ctx, &scm.Request{Method: http.MethodDelete, Path: fmt.Sprintf("api/v4/projects/%s/repository/branches/%s", gitLabProject(repo), url.PathEscape(branch))})
+		if err == nil {
+			res.Body.Close()
+			status = res.Status
+			if !isErrorStatus(status) {
+				err = c.CreateBranch(ctx, repo, branch, sha)
+			}
+		}
+	default:
+		return fmt.Errorf("resetting branches is not supported by the %s driver", c.scmClient.Driver)
+	}
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to reset branch %s in repo %s", branch, repo), Status: status}
+	}
+	return nil
+}
+
+// DeleteBranch deletes the branch from the repo.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) DeleteBranch(ctx context.Context, repo, branch string) error {
+	var r *scm.Response
+	var err error
+	switch c.scmClient.Driver {
+	case scm.DriverGitlab:
+		// go-scm doesn't support deleting refs in GitLab.
+		r, err = c.scmClient.Do(ctx, &scm.Request{Method: http.MethodDelete, Path: fmt.Sprintf("api/v4/projects/%s/repository/branches/%s", gitLabProject(repo), url.PathEscape(branch))})
+		if err == nil {
+			r.Body.Close()
+		}
+	default:
+		r, err = c.scmClient.Git.DeleteRef(ctx, repo, "heads/"+branch)
+	}
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to delete branch %s in repo %s", branch, repo), Status: r.Status}
+	}
+	return err
+}
+
+// FindPullRequest returns the open PullRequest from the head branch, if there
+// is no open PullRequest, a NotFound error is returned.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) FindPullRequest(ctx context.Context, repo, head string) (*scm.PullRequest, error) {
+	prs, err := c.ListPullRequests(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if pr.Source == head {
+			return pr, nil
+		}
+	}
+	return nil, NewNotFoundError(fmt.Sprintf("no open pull request from %s in repo %s", head, repo))
+}
+
+// ListPullRequests returns the open PullRequests in the repo.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error) {
+	open := []*scm.PullRequest{}
+	opts := scm.PullRequestListOptions{Open: true, Size: 100}
+	for opts.Page = 1; ; opts.Page++ {
+		prs, r, err := c.scmClient.PullRequests.List(ctx, repo, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, scmError{msg: fmt.Sprintf("failed to list pull requests in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			if !pr.Closed {
+				open = append(open, pr)
+			}
+		}
+		if len(prs) < opts.Size {
+			return open, nil
+		}
+	}
+}
+
+// ListClosedPullRequests returns the most recent closed PullRequests in the
+// repo, including the merged PullRequests, up to the limit, or all of them if
+// the limit is 0.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ListClosedPullRequests(ctx context.Context, repo string, limit int) ([]*scm.PullRequest, error) {
+	closed := []*scm.PullRequest{}
+	opts := scm.PullRequestListOptions{Closed: true, Size: 100}
+	if limit > 0 && limit < opts.Size {
+		opts.Size = limit
+	}
+	for opts.Page = 1; ; opts.Page++ {
+		prs, r, err := c.scmClient.PullRequests.List(ctx, repo, opts)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, scmError{msg: fmt.Sprintf("failed to list closed pull requests in repo %s", repo), Status: r.Status}
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			if pr.Closed || pr.Merged {
+				closed = append(closed, pr)
+			}
+		}
+		if len(prs) < opts.Size || limit > 0 && len(closed) >= limit {
+			if limit > 0 && len(closed) > limit {
+				closed = closed[:limit]
+			}
+			return closed, nil
+		}
+	}
+}
+
 // CreatePullRequest creates a PullRequest with the provided input.
 //
 // If an HTTP error is returned by the upstream service, an error with the
@@ -49,10 +294,94 @@ func (c *SCMClient) CreatePullRequest(ctx context.Context, repo string, inp *scm
 	return pr, err
 }
 
-// UpdateFile updates an existing file in a repository.
+// CreateDraftPullRequest creates a draft PullRequest with the provided input,
+// on GitLab, the title is prefixed with "Draft: ".
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateDraftPullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		created := struct {
+			Number  int    `json:"number"`
+			Title   string `json:"title"`
+			Body    string `json:"body"`
+			HTMLURL string `json:"html_url"`
+			Draft   bool   `json:"draft"`
+		}{}
+		status, err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("repos/%s/pulls", repo), map[string]interface{}{
+			"title": inp.Title,
+			"body":  inp.Body,
+			"head":  inp.Head,
+			"base":  inp.Base,
+			"draft": true,
+		}, &created)
+		if err != nil {
+			return nil, err
+		}
+		if isErrorStatus(status) {
+			return nil, scmError{msg: fmt.Sprintf("failed to create a draft pull request in %s", repo), Status: status}
+		}
+		return &scm.PullRequest{Number: created.Number, Title: created.Title, Body: created.Body, Link: created.HTMLURL,
+			Source: inp.Head, Target: inp.Base, Draft: created.Draft}, nil
+	case scm.DriverGitlab:
+		draft := *inp
+		if !strings.HasPrefix(draft.Title, "Draft:") {
+			draft.Title = "Draft: " + draft.Title
+		}
+		pr, r, err := c.scmClient.PullRequests.Create(ctx, repo, &draft)
+		if r != nil && isErrorStatus(r.Status) {
+			return nil, scmError{msg: fmt.Sprintf("failed to create a draft merge request in %s", repo), Status: r.Status}
+		}
+		return pr, err
+	}
+	return nil, fmt.Errorf("draft pull requests are not supported by the %s driver", c.scmClient.Driver)
+}
+
+// UpdatePullRequest updates the title and body of the PullRequest, empty
+// fields are not changed.
 //
 // If an HTTP error is returned by the upstream service, an error with the
 // response status code is returned.
+func (c *SCMClient) UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	pr, r, err := c.scmClient.PullRequests.Update(ctx, repo, number, inp)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to update pull request %s#%d", repo, number), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// ClosePullRequest closes the PullRequest, without merging it.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ClosePullRequest(ctx context.Context, repo string, number int) error {
+	r, err := c.scmClient.PullRequests.Close(ctx, repo, number)
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to close pull request %s#%d", repo, number), Status: r.Status}
+	}
+	return err
+}
+
+// CreateComment adds a comment to an issue or PullRequest.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateComment(ctx context.Context, repo string, number int, body string) error {
+	_, r, err := c.scmClient.Issues.CreateComment(ctx, repo, number, &scm.CommentInput{Body: body})
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to create comment on %s#%d", repo, number), Status: r.Status}
+	}
+	return err
+}
+
+// UpdateFile updates an existing file in a repository.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code, and the message from the response, is returned.
 func (c *SCMClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error {
 	params := scm.ContentParams{
 		Message: message,
@@ -61,13 +390,15 @@ func (c *SCMClient) UpdateFile(ctx context.Context, repo, branch, path, message,
 		Sha:     previousSHA,
 	}
 	r, err := c.scmClient.Contents.Update(ctx, repo, path, &params)
-	if err != nil {
-		return err
-	}
-	if isErrorStatus(r.Status) {
-		return scmError{msg: fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch), Status: r.Status}
+	// Some drivers, e.g. the fake driver, don't return a response.
+	if r != nil && isErrorStatus(r.Status) {
+		e := scmError{msg: fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch), Status: r.Status}
+		if err != nil {
+			e.Message = err.Error()
+		}
+		return e
 	}
-	return nil
+	return err
 }
 
 // GetBranchHead gets the head SHA for a specific branch.
@@ -79,6 +410,97 @@ func (c *SCMClient) GetBranchHead(ctx context.Context, repo, branch string) (str
 	return sha, err
 }
 
+// GetRepoPermissions returns the permissions that the authenticated user has
+// on the repository.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetRepoPermissions(ctx context.Context, repo string) (*scm.Perm, error) {
+	perm, r, err := c.scmClient.Repositories.FindPerms(ctx, repo)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to get permissions for repo %s", repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return perm, nil
+}
+
+// RepoStatus describes whether a repository accepts changes.
+type RepoStatus struct {
+	// Archived is true if the repository is archived, and read-only.
+	Archived bool
+	// Disabled is true if the repository is disabled by GitHub, e.g. for
+	// billing reasons.
+	Disabled bool
+}
+
+// ReadOnly returns true if changes can't be made to the repository.
+func (s RepoStatus) ReadOnly() bool {
+	return s.Archived || s.Disabled
+}
+
+// GetRepoStatus returns whether the repository is archived or disabled.
+//
+// The status is only known for GitHub and GitLab, other drivers return an
+// empty status.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetRepoStatus(ctx context.Context, repo string) (*RepoStatus, error) {
+	var path string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s", repo)
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s", gitLabProject(repo))
+	default:
+		return &RepoStatus{}, nil
+	}
+	status := struct {
+		Archived bool `json:"archived"`
+		Disabled bool `json:"disabled"`
+	}{}
+	code, err := c.getJSON(ctx, path, &status)
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(code) {
+		return nil, scmError{msg: fmt.Sprintf("failed to get the status of repo %s", repo), Status: code}
+	}
+	return &RepoStatus{Archived: status.Archived, Disabled: status.Disabled}, nil
+}
+
+// CreateFork forks the repository to the organization, or the authenticated
+// user if it's empty, and returns the full name of the fork e.g.
+// my-bot/my-repo, an existing fork is returned rather than creating another.
+//
+// Forks are only supported by GitHub, GitHub creates the fork asynchronously,
+// so it may not be usable immediately.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateFork(ctx context.Context, repo, organization string) (string, error) {
+	if !isGitHub(c.scmClient) {
+		return "", fmt.Errorf("forks are not supported by the %s driver", c.scmClient.Driver)
+	}
+	body := map[string]string{}
+	if organization != "" {
+		body["organization"] = organization
+	}
+	fork := struct {
+		FullName string `json:"full_name"`
+	}{}
+	status, err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("repos/%s/forks", repo), body, &fork)
+	if err != nil {
+		return "", err
+	}
+	if isErrorStatus(status) {
+		return "", scmError{msg: fmt.Sprintf("failed to fork repo %s", repo), Status: status}
+	}
+	return fork.FullName, nil
+}
+
 func isGitHub(c *scm.Client) bool {
 	return c.Driver == scm.DriverGithub
 }
@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/agill17/pkg/test"
 	"github.com/google/go-cmp/cmp"
 	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
 	"github.com/jenkins-x/go-scm/scm/factory"
 	"gopkg.in/h2non/gock.v1"
 )
@@ -108,6 +112,60 @@ func TestUpdateFile(t *testing.T) {
 	}
 }
 
+func TestUpdateFileWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/contents/config/my/file.yaml").
+		Reply(http.StatusConflict).
+		JSON(map[string]string{"message": "Changes must be made through a pull request."})
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "main",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		[]byte(`testing`))
+
+	if s := StatusCode(err); s != http.StatusConflict {
+		t.Fatalf("got status %d, want %d", s, http.StatusConflict)
+	}
+	if m := ResponseMessage(err); m != "Changes must be made through a pull request." {
+		t.Fatalf("got message %q", m)
+	}
+}
+
+func TestUpdateFileWithFakeDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "Codertocat/Hello-World/config/my"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	scmClient, data := fake.NewDefault()
+	data.ContentDir = dir
+	client := New(scmClient)
+
+	err = client.UpdateFile(context.TODO(), "Codertocat/Hello-World", "my-test-branch",
+		"config/my/file.yaml", "just a test message", "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		[]byte(`testing`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "Codertocat/Hello-World/config/my/file.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(b); s != "testing" {
+		t.Fatalf("got %q, want %q", s, "testing")
+	}
+}
+
 func TestUpdateFileWithNoConnection(t *testing.T) {
 	message := "just a test message"
 	branch := "my-test-branch"
@@ -153,6 +211,390 @@ func TestCreateBranchInGitHub(t *testing.T) {
 	}
 }
 
+func TestCreateBranchInGitHubWithExistingBranch(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "Reference already exists"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.CreateBranch(context.Background(), "Codertocat/Hello-World", "new-feature", "aa218f56b14c9653891f9e74264a383fa43fefbd")
+
+	if s := StatusCode(err); s != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want %d: %v", s, http.StatusUnprocessableEntity, err)
+	}
+}
+
+func TestResetBranchInGitHub(t *testing.T) {
+	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "Reference already exists"})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/git/refs/heads/update-image").
+		MatchType("json").
+		JSON(map[string]interface{}{"sha": sha, "force": true}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"ref": "refs/heads/update-image"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.ResetBranch(context.Background(), "Codertocat/Hello-World", "update-image", sha); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("branch was not reset")
+	}
+}
+
+func TestResetBranchInGitLab(t *testing.T) {
+	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/branches").
+		Reply(http.StatusBadRequest).
+		JSON(map[string]string{"message": "Branch already exists"})
+	gock.New("https://gitlab.com").
+		Delete("/api/v4/projects/Codertocat/Hello-World/repository/branches/update-image").
+		Reply(http.StatusNoContent)
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/branches").
+		MatchParam("branch", "update-image").
+		MatchParam("ref", sha).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"name": "update-image"})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	if err := client.ResetBranch(context.Background(), "Codertocat/Hello-World", "update-image", sha); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("branch was not reset")
+	}
+}
+
+func TestDeleteBranch(t *testing.T) {
+	deleteTests := []struct {
+		driver string
+		host   string
+		path   string
+	}{
+		{"github", "https://api.github.com", "/repos/Codertocat/Hello-World/git/refs/heads/update-image"},
+		{"gitlab", "https://gitlab.com", "/api/v4/projects/Codertocat/Hello-World/repository/branches/update-image"},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.driver, func(rt *testing.T) {
+			gock.New(tt.host).
+				Delete(tt.path).
+				Reply(http.StatusNoContent)
+			defer gock.Off()
+			client := newTestClient(rt, tt.driver, "")
+
+			if err := client.DeleteBranch(context.Background(), "Codertocat/Hello-World", "update-image"); err != nil {
+				rt.Fatal(err)
+			}
+			if !gock.IsDone() {
+				rt.Fatal("branch was not deleted")
+			}
+		})
+	}
+}
+
+func TestDeleteBranchWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Delete("/repos/Codertocat/Hello-World/git/refs/heads/update-image").
+		Reply(http.StatusUnprocessableEntity)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.DeleteBranch(context.Background(), "Codertocat/Hello-World", "update-image")
+	if !test.MatchError(t, "failed to delete branch update-image in repo Codertocat/Hello-World", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestResetBranchCreatesMissingBranch(t *testing.T) {
+	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		MatchType("json").
+		JSON(map[string]string{"ref": "refs/heads/update-image", "sha": sha}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.ResetBranch(context.Background(), "Codertocat/Hello-World", "update-image", sha); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("branch was not created")
+	}
+}
+
+func TestResetBranchWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		Reply(http.StatusForbidden)
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/git/refs/heads/update-image").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.ResetBranch(context.Background(), "Codertocat/Hello-World", "update-image", "aa218f56b14c9653891f9e74264a383fa43fefbd")
+
+	if !test.MatchError(t, `failed to reset branch update-image in repo Codertocat/Hello-World: \(403\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestFindPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{
+			{"number": 1, "state": "open", "head": map[string]string{"ref": "other-branch"}},
+			{"number": 2, "state": "open", "head": map[string]string{"ref": "update-image"}},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	pr, err := client.FindPullRequest(context.Background(), "Codertocat/Hello-World", "update-image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 2 {
+		t.Fatalf("got PullRequest %d, want 2", pr.Number)
+	}
+}
+
+func TestFindPullRequestWithNoOpenPullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.FindPullRequest(context.Background(), "Codertocat/Hello-World", "update-image")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestListPullRequests(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{
+			{"number": 1, "state": "open", "head": map[string]string{"ref": "update-image-abcde"}, "base": map[string]string{"ref": "main"}},
+			{"number": 2, "state": "open", "head": map[string]string{"ref": "update-image-fghij"}, "base": map[string]string{"ref": "main"}},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	prs, err := client.ListPullRequests(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	heads := []string{}
+	for _, pr := range prs {
+		heads = append(heads, pr.Source+"->"+pr.Target)
+	}
+	if diff := cmp.Diff([]string{"update-image-abcde->main", "update-image-fghij->main"}, heads); diff != "" {
+		t.Fatalf("incorrect pull requests:\n%s", diff)
+	}
+}
+
+func TestListPullRequestsWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.ListPullRequests(context.Background(), "Codertocat/Hello-World")
+	if !test.MatchError(t, "failed to list pull requests in repo Codertocat/Hello-World", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestListClosedPullRequests(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		MatchParam("state", "closed").
+		MatchParam("per_page", "2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{
+			{"number": 1, "state": "closed", "merged": true, "head": map[string]string{"ref": "update-image-abcde"}, "base": map[string]string{"ref": "main"}},
+			{"number": 2, "state": "closed", "head": map[string]string{"ref": "update-image-fghij"}, "base": map[string]string{"ref": "main"}},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	prs, err := client.ListClosedPullRequests(context.Background(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	heads := []string{}
+	for _, pr := range prs {
+		heads = append(heads, fmt.Sprintf("%s merged=%v", pr.Source, pr.Merged))
+	}
+	if diff := cmp.Diff([]string{"update-image-abcde merged=true", "update-image-fghij merged=false"}, heads); diff != "" {
+		t.Fatalf("incorrect pull requests:\n%s", diff)
+	}
+}
+
+func TestListClosedPullRequestsWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.ListClosedPullRequests(context.Background(), "Codertocat/Hello-World", 0)
+	if !test.MatchError(t, "failed to list closed pull requests in repo Codertocat/Hello-World", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCreateDraftPullRequestInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		MatchType("json").
+		JSON(map[string]interface{}{"title": "Update the image", "body": "Production", "head": "update-image", "base": "main", "draft": true}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "title": "Update the image", "body": "Production", "html_url": "https://github.com/Codertocat/Hello-World/pull/2", "draft": true})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	pr, err := client.CreateDraftPullRequest(context.TODO(), "Codertocat/Hello-World", &scm.PullRequestInput{Title: "Update the image", Body: "Production", Head: "update-image", Base: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &scm.PullRequest{Number: 2, Title: "Update the image", Body: "Production", Link: "https://github.com/Codertocat/Hello-World/pull/2", Source: "update-image", Target: "main", Draft: true}
+	if diff := cmp.Diff(want, pr); diff != "" {
+		t.Fatalf("incorrect PullRequest:\n%s", diff)
+	}
+}
+
+func TestCreateDraftPullRequestWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "Draft pull requests are not supported in this repository."})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.CreateDraftPullRequest(context.TODO(), "Codertocat/Hello-World", &scm.PullRequestInput{Title: "Update", Head: "update-image", Base: "main"})
+
+	if s := StatusCode(err); s != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want %d: %v", s, http.StatusUnprocessableEntity, err)
+	}
+}
+
+func TestCreateDraftPullRequestInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/merge_requests").
+		MatchType("json").
+		JSON(map[string]string{"title": "Draft: Update the image", "description": "Production", "source_branch": "update-image", "target_branch": "main"}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"iid": 2, "title": "Draft: Update the image", "work_in_progress": true})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	pr, err := client.CreateDraftPullRequest(context.TODO(), "Codertocat/Hello-World", &scm.PullRequestInput{Title: "Update the image", Body: "Production", Head: "update-image", Base: "main"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr.Number != 2 || pr.Title != "Draft: Update the image" {
+		t.Fatalf("got PullRequest %#v, want the draft merge request", pr)
+	}
+}
+
+func TestUpdatePullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/2").
+		MatchType("json").
+		JSON(map[string]string{"title": "Update the image", "body": "Updated to v2"}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "state": "open", "title": "Update the image"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	pr, err := client.UpdatePullRequest(context.Background(), "Codertocat/Hello-World", 2, &scm.PullRequestInput{Title: "Update the image", Body: "Updated to v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Number != 2 || pr.Title != "Update the image" {
+		t.Fatalf("got PullRequest %d %q", pr.Number, pr.Title)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not updated")
+	}
+}
+
+func TestUpdatePullRequestWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.UpdatePullRequest(context.Background(), "Codertocat/Hello-World", 2, &scm.PullRequestInput{Title: "Update the image"})
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestClosePullRequest(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/2").
+		MatchType("json").
+		JSON(map[string]string{"state": "closed"}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "state": "closed"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.ClosePullRequest(context.Background(), "Codertocat/Hello-World", 2); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not closed")
+	}
+}
+
+func TestClosePullRequestWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.ClosePullRequest(context.Background(), "Codertocat/Hello-World", 2)
+	if !test.MatchError(t, "failed to close pull request Codertocat/Hello-World#2", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
 func TestCreateBranchInGitLab(t *testing.T) {
 	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
 	branch := "new-feature"
@@ -217,6 +659,33 @@ func TestCreatePullRequest(t *testing.T) {
 	}
 }
 
+func TestCreateComment(t *testing.T) {
+	body := "Me too"
+
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/1347/comments").
+		MatchType("json").
+		JSON(map[string]string{"body": body}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/comment_create.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	err = client.CreateComment(context.Background(), "Codertocat/Hello-World", 1347, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("comment was not created")
+	}
+}
+
 func TestGetBranchHead(t *testing.T) {
 	gock.New("https://api.github.com").
 		Get("/repos/Codertocat/Hello-World/git/refs/heads/master").
@@ -240,6 +709,104 @@ func TestGetBranchHead(t *testing.T) {
 	}
 }
 
+func TestGetRepoPermissions(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/repo.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	perm, err := client.GetRepoPermissions(context.Background(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &scm.Perm{Pull: true, Push: true}
+	if diff := cmp.Diff(want, perm); diff != "" {
+		t.Fatalf("got different permissions: %s\n", diff)
+	}
+}
+
+func TestGetRepoPermissionsWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusNotFound).
+		BodyString("not found")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient)
+
+	_, err = client.GetRepoPermissions(context.Background(), "Codertocat/Hello-World")
+	if !IsNotFound(err) {
+		t.Fatalf("got %s, want a not found error", err)
+	}
+}
+
+func TestGetRepoStatus(t *testing.T) {
+	statusTests := []struct {
+		driver string
+		path   string
+		body   map[string]interface{}
+		want   *RepoStatus
+	}{
+		{"github", "/repos/Codertocat/Hello-World", map[string]interface{}{"archived": false}, &RepoStatus{}},
+		{"github", "/repos/Codertocat/Hello-World", map[string]interface{}{"archived": true}, &RepoStatus{Archived: true}},
+		{"github", "/repos/Codertocat/Hello-World", map[string]interface{}{"disabled": true}, &RepoStatus{Disabled: true}},
+		{"gitlab", "/api/v4/projects/Codertocat/Hello-World", map[string]interface{}{"archived": true}, &RepoStatus{Archived: true}},
+	}
+
+	for _, tt := range statusTests {
+		t.Run(tt.driver, func(rt *testing.T) {
+			host := "https://api.github.com"
+			if tt.driver == "gitlab" {
+				host = "https://gitlab.com"
+			}
+			gock.New(host).
+				Get(tt.path).
+				Reply(http.StatusOK).
+				Type("application/json").
+				JSON(tt.body)
+			defer gock.Off()
+			client := newTestClient(rt, tt.driver, "")
+
+			status, err := client.GetRepoStatus(context.Background(), "Codertocat/Hello-World")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, status); diff != "" {
+				rt.Fatalf("got different status: %s\n", diff)
+			}
+			if status.ReadOnly() != (tt.want.Archived || tt.want.Disabled) {
+				rt.Fatalf("got ReadOnly() %v for %#v", status.ReadOnly(), status)
+			}
+		})
+	}
+}
+
+func TestGetRepoStatusWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World").
+		Reply(http.StatusNotFound).
+		BodyString("not found")
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.GetRepoStatus(context.Background(), "Codertocat/Hello-World")
+	if !IsNotFound(err) {
+		t.Fatalf("got %s, want a not found error", err)
+	}
+}
+
 func mustParseJSONAsContent(t *testing.T, filename string) *scm.Content {
 	t.Helper()
 	body, err := ioutil.ReadFile(filename)
@@ -261,3 +828,178 @@ func mustParseJSONAsContent(t *testing.T, filename string) *scm.Content {
 		Data: content,
 	}
 }
+
+func TestListFilesInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/v1.0.0").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"tree": []map[string]string{
+			{"path": "README.md", "type": "blob"},
+			{"path": "charts/app", "type": "tree"},
+			{"path": "charts/app/Chart.yaml", "type": "blob"},
+			{"path": "charts/app/templates/deployment.yaml", "type": "blob"},
+			{"path": "charts/application/Chart.yaml", "type": "blob"},
+		}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	files, err := client.ListFiles(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "charts/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"charts/app/Chart.yaml", "charts/app/templates/deployment.yaml"}
+	if diff := cmp.Diff(want, files); diff != "" {
+		t.Fatalf("failed to list files:\n%s", diff)
+	}
+}
+
+func TestListFilesInRepoRoot(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/main").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"tree": []map[string]string{
+			{"path": "README.md", "type": "blob"},
+			{"path": "charts", "type": "tree"},
+			{"path": "charts/app/Chart.yaml", "type": "blob"},
+		}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	files, err := client.ListFiles(context.TODO(), "Codertocat/Hello-World", "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"README.md", "charts/app/Chart.yaml"}, files); diff != "" {
+		t.Fatalf("failed to list files:\n%s", diff)
+	}
+}
+
+func TestListFilesWithTruncatedTree(t *testing.T) {
+	gock.New("https://ghe.example.com").
+		Get("/api/v3/repos/Codertocat/Hello-World/git/trees/v1.0.0").
+		MatchParam("recursive", "1").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"truncated": true, "tree": []map[string]string{
+			{"path": "charts/app/Chart.yaml", "type": "blob"},
+		}})
+	gock.New("https://ghe.example.com").
+		Get("/api/v3/repos/Codertocat/Hello-World/contents/charts/app").
+		MatchParam("ref", "v1.0.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{
+			{"path": "charts/app/Chart.yaml", "type": "file"},
+			{"path": "charts/app/templates", "type": "dir"},
+		})
+	gock.New("https://ghe.example.com").
+		Get("/api/v3/repos/Codertocat/Hello-World/contents/charts/app/templates").
+		MatchParam("ref", "v1.0.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{
+			{"path": "charts/app/templates/deployment.yaml", "type": "file"},
+			{"path": "charts/app/templates/vendor", "type": "submodule"},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "github", "https://ghe.example.com/api/v3")
+
+	files, err := client.ListFiles(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "charts/app/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"charts/app/Chart.yaml", "charts/app/templates/deployment.yaml"}
+	if diff := cmp.Diff(want, files); diff != "" {
+		t.Fatalf("failed to list files:\n%s", diff)
+	}
+	if !gock.IsDone() {
+		t.Fatal("the contents were not listed")
+	}
+}
+
+func TestListFilesInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/tree").
+		MatchParam("path", "charts/app").
+		MatchParam("ref", "v1.0.0").
+		MatchParam("recursive", "true").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{
+			{"path": "charts/app/Chart.yaml", "type": "blob"},
+			{"path": "charts/app/templates", "type": "tree"},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	files, err := client.ListFiles(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "charts/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"charts/app/Chart.yaml"}, files); diff != "" {
+		t.Fatalf("failed to list files:\n%s", diff)
+	}
+}
+
+func TestListFilesWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/trees/v1.0.0").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.ListFiles(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "charts/app")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestCreateFork(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/forks").
+		MatchType("json").
+		JSON(map[string]string{"organization": "my-bots"}).
+		Reply(http.StatusAccepted).
+		Type("application/json").
+		JSON(map[string]interface{}{"full_name": "my-bots/Hello-World"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	fork, err := client.CreateFork(context.Background(), "Codertocat/Hello-World", "my-bots")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fork != "my-bots/Hello-World" {
+		t.Fatalf("got fork %q, want my-bots/Hello-World", fork)
+	}
+}
+
+func TestCreateForkWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/forks").
+		Reply(http.StatusForbidden).
+		BodyString("forbidden")
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.CreateFork(context.Background(), "Codertocat/Hello-World", "")
+
+	if !test.MatchError(t, "failed to fork repo Codertocat/Hello-World", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCreateForkWithUnsupportedDriver(t *testing.T) {
+	client := newTestClient(t, "gitlab", "")
+
+	_, err := client.CreateFork(context.Background(), "Codertocat/Hello-World", "")
+
+	if !test.MatchError(t, "forks are not supported by the gitlab driver", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// ResolveRef returns the SHA of the commit that a branch, tag or SHA refers
+// to.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) ResolveRef(ctx context.Context, repo, ref string) (string, error) {
+	commit, r, err := c.scmClient.Git.FindCommit(ctx, repo, ref)
+	if r != nil && isErrorStatus(r.Status) {
+		return "", scmError{msg: fmt.Sprintf("failed to find commit %s in repo %s", ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return "", err
+	}
+	return commit.Sha, nil
+}
+
+// GetCommit returns the commit that a branch, tag or SHA refers to.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetCommit(ctx context.Context, repo, ref string) (*scm.Commit, error) {
+	commit, r, err := c.scmClient.Git.FindCommit(ctx, repo, ref)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to find commit %s in repo %s", ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return commit, nil
+}
+
+// CompareCommits returns the commits that are reachable from the head, but not
+// from the base, oldest first.
+func (c *SCMClient) CompareCommits(ctx context.Context, repo, base, head string) ([]*scm.Commit, error) {
+	commits := []*scm.Commit{}
+	var status int
+	var err error
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		comparison := struct {
+			Commits []struct {
+				Sha    string `json:"sha"`
+				Commit struct {
+					Message string `json:"message"`
+				} `json:"commit"`
+			} `json:"commits"`
+		}{}
+		status, err = c.getJSON(ctx, fmt.Sprintf("repos/%s/compare/%s...%s", repo, base, head), &comparison)
+		for _, v := range comparison.Commits {
+			commits = append(commits, &scm.Commit{Sha: v.Sha, Message: v.Commit.Message})
+		}
+	case scm.DriverGitlab:
+		comparison := struct {
+			Commits []struct {
+				ID      string `json:"id"`
+				Message string `json:"message"`
+			} `json:"commits"`
+		}{}
+		status, err = c.getJSON(ctx, fmt.Sprintf("api/v4/projects/%s/repository/compare?from=%s&to=%s", gitLabProject(repo), base, head), &comparison)
+		for _, v := range comparison.Commits {
+			commits = append(commits, &scm.Commit{Sha: v.ID, Message: v.Message})
+		}
+	default:
+		return nil, fmt.Errorf("comparing commits is not supported by the %s driver", c.scmClient.Driver)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to compare %s...%s in repo %s", base, head, repo), Status: status}
+	}
+	return commits, nil
+}
+
+// CreateStatus reports a commit status for the SHA, e.g. so that branch
+// protection that requires the status is satisfied.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) CreateStatus(ctx context.Context, repo, sha string, status *scm.StatusInput) error {
+	_, r, err := c.scmClient.Repositories.CreateStatus(ctx, repo, sha, status)
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to create status %s for commit %s in repo %s", status.Label, sha, repo), Status: r.Status}
+	}
+	return err
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestResolveRef(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/v1.0.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]string{"sha": "aa218f56b14c9653891f9e74264a383fa43fefbd"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	sha, err := client.ResolveRef(context.TODO(), "Codertocat/Hello-World", "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sha != "aa218f56b14c9653891f9e74264a383fa43fefbd" {
+		t.Fatalf("got %#v, want %#v", sha, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	}
+}
+
+func TestResolveRefWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/v1.0.0").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.ResolveRef(context.TODO(), "Codertocat/Hello-World", "v1.0.0")
+	if !test.MatchError(t, `failed to find commit v1.0.0 in repo Codertocat/Hello-World: \(404\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetCommit(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/aa218f56b14c9653891f9e74264a383fa43fefbd").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{
+			"sha":    "aa218f56b14c9653891f9e74264a383fa43fefbd",
+			"commit": map[string]interface{}{"message": "Merge pull request #1", "committer": map[string]string{"date": "2020-06-01T10:00:00Z"}},
+		})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	commit, err := client.GetCommit(context.TODO(), "Codertocat/Hello-World", "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Sha != "aa218f56b14c9653891f9e74264a383fa43fefbd" {
+		t.Fatalf("got %#v, want %#v", commit.Sha, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	}
+	if want := time.Date(2020, time.June, 1, 10, 0, 0, 0, time.UTC); !commit.Committer.Date.Equal(want) {
+		t.Fatalf("got %s, want %s", commit.Committer.Date, want)
+	}
+}
+
+func TestGetCommitWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/commits/v1.0.0").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.GetCommit(context.TODO(), "Codertocat/Hello-World", "v1.0.0")
+	if !test.MatchError(t, `failed to find commit v1.0.0 in repo Codertocat/Hello-World: \(404\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCompareCommitsInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"commits": []map[string]interface{}{
+			{"sha": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "commit": map[string]string{"message": "Add a feature"}},
+			{"sha": "762941318ee16e59dabbacb1b4049eec22f0d303", "commit": map[string]string{"message": "Fix a bug"}},
+		}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	commits, err := client.CompareCommits(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*scm.Commit{
+		{Sha: "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", Message: "Add a feature"},
+		{Sha: "762941318ee16e59dabbacb1b4049eec22f0d303", Message: "Fix a bug"},
+	}
+	if diff := cmp.Diff(want, commits); diff != "" {
+		t.Fatalf("failed to compare commits:\n%s", diff)
+	}
+}
+
+func TestCompareCommitsInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/compare").
+		MatchParam("from", "v1.0.0").
+		MatchParam("to", "v1.1.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"commits": []map[string]string{
+			{"id": "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", "message": "Add a feature"},
+		}})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	commits, err := client.CompareCommits(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*scm.Commit{{Sha: "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", Message: "Add a feature"}}
+	if diff := cmp.Diff(want, commits); diff != "" {
+		t.Fatalf("failed to compare commits:\n%s", diff)
+	}
+}
+
+func TestCompareCommitsWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/compare/v1.0.0...v1.1.0").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.CompareCommits(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "v1.1.0")
+	if !test.MatchError(t, `failed to compare v1.0.0...v1.1.0 in repo Codertocat/Hello-World: \(404\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCreateStatusInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/statuses/aa218f56b14c9653891f9e74264a383fa43fefbd").
+		MatchType("json").
+		BodyString(`"description":"rendered manifests valid","context":"gitops-updater"`).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]string{"state": "success", "context": "gitops-updater"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.CreateStatus(context.TODO(), "Codertocat/Hello-World", "aa218f56b14c9653891f9e74264a383fa43fefbd", &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater", Desc: "rendered manifests valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("status was not created")
+	}
+}
+
+func TestCreateStatusInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/statuses/aa218f56b14c9653891f9e74264a383fa43fefbd").
+		MatchParam("name", "gitops-updater").
+		MatchParam("state", "success").
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]string{"status": "success", "name": "gitops-updater"})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	err := client.CreateStatus(context.TODO(), "Codertocat/Hello-World", "aa218f56b14c9653891f9e74264a383fa43fefbd", &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater", Desc: "rendered manifests valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("status was not created")
+	}
+}
+
+func TestCreateStatusWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/statuses/aa218f56b14c9653891f9e74264a383fa43fefbd").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.CreateStatus(context.TODO(), "Codertocat/Hello-World", "aa218f56b14c9653891f9e74264a383fa43fefbd", &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater"})
+
+	if !test.MatchError(t, "failed to create status gitops-updater for commit aa218f56b14c9653891f9e74264a383fa43fefbd in repo Codertocat/Hello-World", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -1,22 +1,74 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// ErrMergeable is returned by EnableAutoMerge when the PullRequest can be
+// merged already, e.g. its checks have passed, so there is nothing to wait for.
+var ErrMergeable = errors.New("the pull request can be merged now")
+
+// ErrAutoMergeUnsupported is returned by EnableAutoMerge when the provider
+// doesn't support auto-merge, e.g. GitHub Enterprise Server before 3.1.
+var ErrAutoMergeUnsupported = errors.New("the git provider does not support auto-merge")
+
 // IsNotFound returns true if the error represents a NotFound response from an
 // upstream service.
 func IsNotFound(err error) bool {
-	e, ok := err.(scmError)
-	return ok && e.Status == http.StatusNotFound
+	return StatusCode(err) == http.StatusNotFound
+}
+
+// StatusCode returns the status code of the response from an upstream service
+// that the error represents, or 0 if it doesn't represent a response.
+func StatusCode(err error) int {
+	var e scmError
+	if errors.As(err, &e) {
+		return e.Status
+	}
+	return 0
+}
+
+// NewNotFoundError returns an error that represents a NotFound response, for
+// use by fake implementations of GitClient.
+func NewNotFoundError(msg string) error {
+	return NewStatusError(msg, http.StatusNotFound)
+}
+
+// NewStatusError returns an error that represents a response with the status
+// code, for use by fake implementations of GitClient.
+func NewStatusError(msg string, status int) error {
+	return scmError{msg: msg, Status: status}
+}
+
+// NewResponseError returns an error that represents a response with the
+// status code and the message from the upstream service, for use by fake
+// implementations of GitClient.
+func NewResponseError(msg string, status int, message string) error {
+	return scmError{msg: msg, Status: status, Message: message}
+}
+
+// ResponseMessage returns the message in the response from an upstream
+// service that the error represents, e.g. the reason a commit was rejected, or
+// "" if there isn't one.
+func ResponseMessage(err error) string {
+	var e scmError
+	if errors.As(err, &e) {
+		return e.Message
+	}
+	return ""
 }
 
 type scmError struct {
-	msg    string
-	Status int
+	msg     string
+	Status  int
+	Message string
 }
 
 func (s scmError) Error() string {
+	if s.Message != "" {
+		return fmt.Sprintf("%s: %s (%d)", s.msg, s.Message, s.Status)
+	}
 	return fmt.Sprintf("%s: (%d)", s.msg, s.Status)
 }
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm/factory"
+)
+
+// NewClient creates an SCMClient for the driver e.g. "github", authenticated
+// with the token, the provider's public URL is used if the serverURL is empty.
+//
+// For GitHub Enterprise Server, the serverURL can be the URL of the server,
+// e.g. "https://ghe.example.com", or of its REST API, e.g.
+// "https://ghe.example.com/api/v3".
+func NewClient(driver, serverURL, token string, opts ...Option) (*SCMClient, error) {
+	if serverURL != "" && (driver == "" || driver == "github") {
+		u, err := gitHubAPIURL(serverURL)
+		if err != nil {
+			return nil, err
+		}
+		serverURL = u
+	}
+	c, err := factory.NewClient(driver, serverURL, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the %s client: %w", driver, err)
+	}
+	return New(c, opts...), nil
+}
+
+// gitHubAPIURL returns the URL of the REST API for the GitHub Enterprise
+// Server, which is served from /api/v3, or an empty URL for the public
+// github.com.
+func gitHubAPIURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the server URL %q: %w", serverURL, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q: an http or https URL is required", serverURL)
+	}
+	switch u.Host {
+	case "github.com", "api.github.com":
+		return "", nil
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(u.Path, "/api/v3") {
+		u.Path += "/api/v3"
+	}
+	return u.String(), nil
+}
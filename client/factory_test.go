@@ -0,0 +1,75 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestNewClient(t *testing.T) {
+	clientTests := []struct {
+		driver    string
+		serverURL string
+		want      string
+	}{
+		{"github", "", "https://api.github.com/"},
+		{"", "https://github.com", "https://api.github.com/"},
+		{"github", "https://ghe.example.com", "https://ghe.example.com/api/v3/"},
+		{"github", "https://ghe.example.com/", "https://ghe.example.com/api/v3/"},
+		{"github", "https://ghe.example.com/api/v3", "https://ghe.example.com/api/v3/"},
+		{"github", "https://example.com/github/api/v3/", "https://example.com/github/api/v3/"},
+		{"gitlab", "https://gitlab.example.com", "https://gitlab.example.com/"},
+	}
+
+	for _, tt := range clientTests {
+		t.Run(tt.serverURL, func(rt *testing.T) {
+			c, err := NewClient(tt.driver, tt.serverURL, "test-token")
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if got := c.scmClient.BaseURL.String(); got != tt.want {
+				rt.Fatalf("got base URL %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewClientForGitHubEnterpriseGraphQL(t *testing.T) {
+	c, err := NewClient("github", "https://ghe.example.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.scmClient.Driver != scm.DriverGithub {
+		t.Fatalf("got driver %s, want github", c.scmClient.Driver)
+	}
+	if got := c.gitHubGraphQLPath(); got != "../graphql" {
+		t.Fatalf("got GraphQL path %q, want ../graphql", got)
+	}
+}
+
+func TestNewClientErrors(t *testing.T) {
+	errorTests := []struct {
+		driver    string
+		serverURL string
+		wantErr   string
+	}{
+		{"github", "ghe.example.com", `invalid server URL "ghe.example.com"`},
+		{"github", "ftp://ghe.example.com", `invalid server URL "ftp://ghe.example.com"`},
+		{"github", "https://ghe example.com", `failed to parse the server URL`},
+		{"gitea", "", `failed to create the gitea client`},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.serverURL, func(rt *testing.T) {
+			_, err := NewClient(tt.driver, tt.serverURL, "")
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
@@ -9,8 +9,41 @@ import (
 // GitClient wraps go-scm's Client with a simplified API.
 type GitClient interface {
 	GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error)
+	ListFiles(ctx context.Context, repo, ref, dir string) ([]string, error)
 	UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error
 	CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+	CreateDraftPullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error)
 	CreateBranch(ctx context.Context, repo, branch, sha string) error
+	ResetBranch(ctx context.Context, repo, branch, sha string) error
+	FindPullRequest(ctx context.Context, repo, head string) (*scm.PullRequest, error)
+	ListPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error)
+	ListClosedPullRequests(ctx context.Context, repo string, limit int) ([]*scm.PullRequest, error)
+	UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error)
+	ClosePullRequest(ctx context.Context, repo string, number int) error
+	DeleteBranch(ctx context.Context, repo, branch string) error
 	GetBranchHead(ctx context.Context, repo, branch string) (string, error)
+	ResolveRef(ctx context.Context, repo, ref string) (string, error)
+	GetCommit(ctx context.Context, repo, ref string) (*scm.Commit, error)
+	CompareCommits(ctx context.Context, repo, base, head string) ([]*scm.Commit, error)
+	CreateComment(ctx context.Context, repo string, number int, body string) error
+	GetRepoPermissions(ctx context.Context, repo string) (*scm.Perm, error)
+	GetRepoStatus(ctx context.Context, repo string) (*RepoStatus, error)
+	CreateFork(ctx context.Context, repo, organization string) (string, error)
+	Capabilities(ctx context.Context) (Capabilities, error)
+	GetVariable(ctx context.Context, repo, name string) (string, error)
+	SetVariable(ctx context.Context, repo, name, value string) error
+	CreateTag(ctx context.Context, repo, name, sha, message string) error
+	CreateRelease(ctx context.Context, repo string, input *ReleaseInput) error
+	GetLatestRelease(ctx context.Context, repo string) (string, error)
+	AddLabels(ctx context.Context, repo string, number int, labels []string) error
+	RequestReviewers(ctx context.Context, repo string, number int, logins []string) error
+	RequestTeamReviewers(ctx context.Context, repo string, number int, teams []string) error
+	AddAssignees(ctx context.Context, repo string, number int, logins []string) error
+	SetMilestone(ctx context.Context, repo string, number int, title string) error
+	AddToProject(ctx context.Context, repo string, number int, project, column string) error
+	EnableAutoMerge(ctx context.Context, repo string, number int, method string) error
+	MergePullRequest(ctx context.Context, repo string, number int, method string) error
+	GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error)
+	GetCombinedStatus(ctx context.Context, repo, ref string) (*scm.CombinedStatus, error)
+	CreateStatus(ctx context.Context, repo, sha string, status *scm.StatusInput) error
 }
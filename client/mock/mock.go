@@ -5,7 +5,9 @@ import (
 	"crypto/sha1"
 	"errors"
 	"fmt"
+	"net/http"
 	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
@@ -20,26 +22,112 @@ func New(t *testing.T) *MockClient {
 	return &MockClient{
 		t:                   t,
 		files:               make(map[string][]byte),
+		missingFiles:        make(map[string]bool),
 		updatedFiles:        make(map[string][]byte),
+		commitMessages:      make(map[string]string),
 		createdBranches:     make(map[string]bool),
 		branchHeads:         make(map[string]string),
 		createdPullRequests: make(map[string][]*scm.PullRequestInput),
+		drafts:              make(map[string]bool),
+		createdComments:     make(map[string][]string),
+		repoPermissions:     make(map[string]*scm.Perm),
+		repoStatuses:        make(map[string]*client.RepoStatus),
+		forks:               make(map[string]string),
+		protectedBranches:   make(map[string]bool),
+		closedPRs:           make(map[string][]*scm.PullRequest),
+		variables:           make(map[string]string),
+		setVariables:        make(map[string]string),
+		createdTags:         make(map[string]string),
+		createdReleases:     make(map[string]*client.ReleaseInput),
+		latestReleases:      make(map[string]string),
+		refs:                make(map[string]string),
+		foundCommits:        make(map[string]*scm.Commit),
+		commits:             make(map[string][]*scm.Commit),
+		resetBranches:       make(map[string]bool),
+		openPullRequests:    make(map[string]*scm.PullRequest),
+		updatedPullRequests: make(map[string][]*scm.PullRequestInput),
+		closedPullRequests:  make(map[string]bool),
+		deletedBranches:     make(map[string]bool),
+		addedLabels:         make(map[string][]string),
+		requestedReviewers:  make(map[string][]string),
+		requestedTeams:      make(map[string][]string),
+		assignees:           make(map[string][]string),
+		autoMerges:          make(map[string]string),
+		merges:              make(map[string]string),
+		milestones:          make(map[string]string),
+		projects:            make(map[string]string),
+		pullRequests:        make(map[string][]*scm.PullRequest),
+		combinedStatuses:    make(map[string][]*scm.CombinedStatus),
+		createdStatuses:     make(map[string][]*scm.StatusInput),
+		Caps:                client.Capabilities{DraftPullRequests: true, AutoMerge: true, TreeAPI: true, Variables: true, Releases: true},
 	}
 }
 
 // MockClient implements the client.GitClient interface with an in-memory
 // representation of files.
 type MockClient struct {
-	t                    *testing.T
-	files                map[string][]byte
-	GetFileErr           error
-	updatedFiles         map[string][]byte
-	UpdateFileErr        error
-	createdBranches      map[string]bool
-	CreateBranchErr      error
-	branchHeads          map[string]string
-	createdPullRequests  map[string][]*scm.PullRequestInput
-	CreatePullRequestErr error
+	t                       *testing.T
+	files                   map[string][]byte
+	missingFiles            map[string]bool
+	GetFileErr              error
+	updatedFiles            map[string][]byte
+	commitMessages          map[string]string
+	UpdateFileErr           error
+	createdBranches         map[string]bool
+	CreateBranchErr         error
+	branchHeads             map[string]string
+	createdPullRequests     map[string][]*scm.PullRequestInput
+	drafts                  map[string]bool
+	CreatePullRequestErr    error
+	createdComments         map[string][]string
+	CreateCommentErr        error
+	repoPermissions         map[string]*scm.Perm
+	repoStatuses            map[string]*client.RepoStatus
+	GetRepoStatusErr        error
+	forks                   map[string]string
+	protectedBranches       map[string]bool
+	closedPRs               map[string][]*scm.PullRequest
+	CreateForkErr           error
+	variables               map[string]string
+	setVariables            map[string]string
+	SetVariableErr          error
+	createdTags             map[string]string
+	CreateTagErr            error
+	createdReleases         map[string]*client.ReleaseInput
+	CreateReleaseErr        error
+	latestReleases          map[string]string
+	refs                    map[string]string
+	foundCommits            map[string]*scm.Commit
+	commits                 map[string][]*scm.Commit
+	resetBranches           map[string]bool
+	openPullRequests        map[string]*scm.PullRequest
+	updatedPullRequests     map[string][]*scm.PullRequestInput
+	UpdatePullRequestErr    error
+	closedPullRequests      map[string]bool
+	ClosePullRequestErr     error
+	deletedBranches         map[string]bool
+	DeleteBranchErr         error
+	addedLabels             map[string][]string
+	AddLabelsErr            error
+	requestedReviewers      map[string][]string
+	RequestReviewersErr     error
+	requestedTeams          map[string][]string
+	RequestTeamReviewersErr error
+	assignees               map[string][]string
+	AddAssigneesErr         error
+	autoMerges              map[string]string
+	EnableAutoMergeErr      error
+	merges                  map[string]string
+	milestones              map[string]string
+	SetMilestoneErr         error
+	projects                map[string]string
+	AddToProjectErr         error
+	MergePullRequestErr     error
+	pullRequests            map[string][]*scm.PullRequest
+	combinedStatuses        map[string][]*scm.CombinedStatus
+	createdStatuses         map[string][]*scm.StatusInput
+	CreateStatusErr         error
+	Caps                    client.Capabilities
 }
 
 // GetFile implements the client.GitClient interface.
@@ -50,16 +138,41 @@ func (m *MockClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.
 	if b, ok := m.files[key(repo, path, ref)]; ok {
 		return &scm.Content{Data: b, Sha: bytesSha1(b)}, nil
 	}
+	if m.missingFiles[key(repo, path, ref)] {
+		return nil, client.NewNotFoundError("not found")
+	}
 	return nil, errors.New("not found")
 }
 
+// ListFiles implements the client.GitClient interface.
+func (m *MockClient) ListFiles(ctx context.Context, repo, ref, dir string) ([]string, error) {
+	prefix, suffix := repo+":", ":"+ref
+	if dir != "" {
+		prefix = key(repo, strings.TrimSuffix(dir, "/")+"/")
+	}
+	files := []string{}
+	for k := range m.files {
+		if strings.HasPrefix(k, prefix) && strings.HasSuffix(k, suffix) {
+			files = append(files, strings.TrimSuffix(strings.TrimPrefix(k, repo+":"), suffix))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
 // UpdateFile implements the client.GitClient interface.
 func (m *MockClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error {
 	if m.UpdateFileErr != nil {
 		return m.UpdateFileErr
 	}
+	if m.protectedBranches[key(repo, branch)] {
+		return client.NewResponseError(fmt.Sprintf("failed to update file %s in repo %s branch %s", path, repo, branch), http.StatusConflict, "Changes must be made through a pull request.")
+	}
 	// TODO: Do we need something to validate the previousSHA?
 	m.updatedFiles[key(repo, path, branch)] = content
+	m.commitMessages[key(repo, path, branch)] = message
+	// The branch head is moved to a commit SHA for the change.
+	m.branchHeads[key(repo, branch)] = bytesSha1(append([]byte(key(repo, branch, path, message)), content...))
 	return nil
 }
 
@@ -78,6 +191,34 @@ func (m *MockClient) CreatePullRequest(ctx context.Context, repo string, inp *sc
 	return &scm.PullRequest{Number: number, Link: fmt.Sprintf("https://example.com/pull-request/%d", number)}, nil
 }
 
+// CreateDraftPullRequest implements the client.GitClient interface, the draft
+// is recorded with the PullRequests created by CreatePullRequest.
+func (m *MockClient) CreateDraftPullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	pr, err := m.CreatePullRequest(ctx, repo, inp)
+	if err != nil {
+		return nil, err
+	}
+	m.drafts[key(repo, fmt.Sprint(pr.Number))] = true
+	pr.Draft = true
+	return pr, nil
+}
+
+// AssertDraftPullRequest fails if the PullRequest was not created as a draft.
+func (m *MockClient) AssertDraftPullRequest(repo string, number int) {
+	m.t.Helper()
+	if !m.drafts[key(repo, fmt.Sprint(number))] {
+		m.t.Fatalf("pullrequest %d was not created as a draft in repo %s", number, repo)
+	}
+}
+
+// RefuteDraftPullRequest fails if the PullRequest was created as a draft.
+func (m *MockClient) RefuteDraftPullRequest(repo string, number int) {
+	m.t.Helper()
+	if m.drafts[key(repo, fmt.Sprint(number))] {
+		m.t.Fatalf("pullrequest %d was created as a draft in repo %s", number, repo)
+	}
+}
+
 // CreateBranch implements the client.GitClient interface.
 func (m *MockClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
 	if m.CreateBranchErr != nil {
@@ -87,6 +228,143 @@ func (m *MockClient) CreateBranch(ctx context.Context, repo, branch, sha string)
 	return nil
 }
 
+// ResetBranch implements the client.GitClient interface.
+func (m *MockClient) ResetBranch(ctx context.Context, repo, branch, sha string) error {
+	if m.CreateBranchErr != nil {
+		return m.CreateBranchErr
+	}
+	m.resetBranches[key(repo, branch, sha)] = true
+	return nil
+}
+
+// FindPullRequest implements the client.GitClient interface.
+func (m *MockClient) FindPullRequest(ctx context.Context, repo, head string) (*scm.PullRequest, error) {
+	pr, ok := m.openPullRequests[key(repo, head)]
+	if !ok {
+		return nil, client.NewNotFoundError("pull request not found")
+	}
+	return pr, nil
+}
+
+// AddOpenPullRequest is a mock for setting up a response for FindPullRequest
+// and ListPullRequests.
+func (m *MockClient) AddOpenPullRequest(repo string, pr *scm.PullRequest) {
+	m.openPullRequests[key(repo, pr.Source)] = pr
+}
+
+// ListPullRequests implements the client.GitClient interface.
+func (m *MockClient) ListPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error) {
+	prs := []*scm.PullRequest{}
+	for k, pr := range m.openPullRequests {
+		if strings.HasPrefix(k, repo+":") {
+			prs = append(prs, pr)
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool { return prs[i].Number < prs[j].Number })
+	return prs, nil
+}
+
+// ListClosedPullRequests implements the client.GitClient interface.
+func (m *MockClient) ListClosedPullRequests(ctx context.Context, repo string, limit int) ([]*scm.PullRequest, error) {
+	prs := append([]*scm.PullRequest{}, m.closedPRs[repo]...)
+	if limit > 0 && len(prs) > limit {
+		prs = prs[:limit]
+	}
+	return prs, nil
+}
+
+// AddClosedPullRequest is a mock for setting up a response for
+// ListClosedPullRequests.
+func (m *MockClient) AddClosedPullRequest(repo string, pr *scm.PullRequest) {
+	m.closedPRs[repo] = append(m.closedPRs[repo], pr)
+}
+
+// UpdatePullRequest implements the client.GitClient interface.
+func (m *MockClient) UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	if m.UpdatePullRequestErr != nil {
+		return nil, m.UpdatePullRequestErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.updatedPullRequests[k] = append(m.updatedPullRequests[k], inp)
+	return &scm.PullRequest{Number: number, Title: inp.Title, Body: inp.Body, Link: fmt.Sprintf("https://example.com/pull-request/%d", number)}, nil
+}
+
+// ClosePullRequest implements the client.GitClient interface.
+func (m *MockClient) ClosePullRequest(ctx context.Context, repo string, number int) error {
+	if m.ClosePullRequestErr != nil {
+		return m.ClosePullRequestErr
+	}
+	m.closedPullRequests[key(repo, fmt.Sprint(number))] = true
+	for k, pr := range m.openPullRequests {
+		if strings.HasPrefix(k, repo+":") && pr.Number == number {
+			delete(m.openPullRequests, k)
+		}
+	}
+	return nil
+}
+
+// AssertPullRequestClosed fails if the PullRequest was not closed.
+func (m *MockClient) AssertPullRequestClosed(repo string, number int) {
+	m.t.Helper()
+	if !m.closedPullRequests[key(repo, fmt.Sprint(number))] {
+		m.t.Fatalf("pullrequest %d not closed in repo %s", number, repo)
+	}
+}
+
+// RefutePullRequestClosed fails if the PullRequest was closed.
+func (m *MockClient) RefutePullRequestClosed(repo string, number int) {
+	m.t.Helper()
+	if m.closedPullRequests[key(repo, fmt.Sprint(number))] {
+		m.t.Fatalf("pullrequest %d was closed in repo %s", number, repo)
+	}
+}
+
+// DeleteBranch implements the client.GitClient interface.
+func (m *MockClient) DeleteBranch(ctx context.Context, repo, branch string) error {
+	if m.DeleteBranchErr != nil {
+		return m.DeleteBranchErr
+	}
+	m.deletedBranches[key(repo, branch)] = true
+	return nil
+}
+
+// AssertBranchDeleted fails if the branch was not deleted.
+func (m *MockClient) AssertBranchDeleted(repo, branch string) {
+	m.t.Helper()
+	if !m.deletedBranches[key(repo, branch)] {
+		m.t.Fatalf("branch %s not deleted in repo %s", branch, repo)
+	}
+}
+
+// RefuteBranchDeleted fails if the branch was deleted.
+func (m *MockClient) RefuteBranchDeleted(repo, branch string) {
+	m.t.Helper()
+	if m.deletedBranches[key(repo, branch)] {
+		m.t.Fatalf("branch %s deleted in repo %s", branch, repo)
+	}
+}
+
+// AssertPullRequestUpdated fails if the PullRequest was not updated with the
+// input.
+func (m *MockClient) AssertPullRequestUpdated(repo string, number int, inp *scm.PullRequestInput) {
+	m.t.Helper()
+	for _, pr := range m.updatedPullRequests[key(repo, fmt.Sprint(number))] {
+		if reflect.DeepEqual(inp, pr) {
+			return
+		}
+	}
+	m.t.Fatalf("pullrequest %d not updated in repo %s", number, repo)
+}
+
+// AssertBranchReset fails if the branch was not reset to the SHA using
+// ResetBranch.
+func (m *MockClient) AssertBranchReset(repo, branch, sha string) {
+	m.t.Helper()
+	if !m.resetBranches[key(repo, branch, sha)] {
+		m.t.Fatalf("branch %s was not reset to %s in repo %s", branch, sha, repo)
+	}
+}
+
 // GetBranchHead implements the client.GitClient interface.
 func (m *MockClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
 	ref, ok := m.branchHeads[key(repo, branch)]
@@ -96,12 +374,483 @@ func (m *MockClient) GetBranchHead(ctx context.Context, repo, branch string) (st
 	return ref, nil
 }
 
+// ResolveRef implements the client.GitClient interface.
+func (m *MockClient) ResolveRef(ctx context.Context, repo, ref string) (string, error) {
+	sha, ok := m.refs[key(repo, ref)]
+	if !ok {
+		return "", client.NewNotFoundError("ref not found")
+	}
+	return sha, nil
+}
+
+// GetCommit implements the client.GitClient interface.
+func (m *MockClient) GetCommit(ctx context.Context, repo, ref string) (*scm.Commit, error) {
+	commit, ok := m.foundCommits[key(repo, ref)]
+	if !ok {
+		return nil, client.NewNotFoundError("commit not found")
+	}
+	return commit, nil
+}
+
+// CompareCommits implements the client.GitClient interface.
+func (m *MockClient) CompareCommits(ctx context.Context, repo, base, head string) ([]*scm.Commit, error) {
+	commits, ok := m.commits[key(repo, base, head)]
+	if !ok {
+		return nil, client.NewNotFoundError("commits not found")
+	}
+	return commits, nil
+}
+
+// CreateComment implements the client.GitClient interface.
+func (m *MockClient) CreateComment(ctx context.Context, repo string, number int, body string) error {
+	if m.CreateCommentErr != nil {
+		return m.CreateCommentErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.createdComments[k] = append(m.createdComments[k], body)
+	return nil
+}
+
+// GetRepoPermissions implements the client.GitClient interface.
+func (m *MockClient) GetRepoPermissions(ctx context.Context, repo string) (*scm.Perm, error) {
+	perm, ok := m.repoPermissions[repo]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return perm, nil
+}
+
+// GetRepoStatus implements the client.GitClient interface, repos are writable
+// unless they're set up with AddRepoStatus.
+func (m *MockClient) GetRepoStatus(ctx context.Context, repo string) (*client.RepoStatus, error) {
+	if m.GetRepoStatusErr != nil {
+		return nil, m.GetRepoStatusErr
+	}
+	if status, ok := m.repoStatuses[repo]; ok {
+		return status, nil
+	}
+	return &client.RepoStatus{}, nil
+}
+
+// CreateFork implements the client.GitClient interface, the fork has the name
+// of the repo, in the organization, or mock-user if it's empty.
+func (m *MockClient) CreateFork(ctx context.Context, repo, organization string) (string, error) {
+	if m.CreateForkErr != nil {
+		return "", m.CreateForkErr
+	}
+	if organization == "" {
+		organization = "mock-user"
+	}
+	fork := organization + "/" + repo[strings.LastIndex(repo, "/")+1:]
+	m.forks[repo] = fork
+	return fork, nil
+}
+
+// AssertForked fails if the repo was not forked to the fork.
+func (m *MockClient) AssertForked(repo, fork string) {
+	m.t.Helper()
+	if got := m.forks[repo]; got != fork {
+		m.t.Fatalf("repo %s forked to %q, want %q", repo, got, fork)
+	}
+}
+
+// RefuteForked fails if the repo was forked.
+func (m *MockClient) RefuteForked(repo string) {
+	m.t.Helper()
+	if fork, ok := m.forks[repo]; ok {
+		m.t.Fatalf("repo %s was forked to %s", repo, fork)
+	}
+}
+
+// Capabilities implements the client.GitClient interface.
+func (m *MockClient) Capabilities(ctx context.Context) (client.Capabilities, error) {
+	return m.Caps, nil
+}
+
+// GetVariable implements the client.GitClient interface.
+func (m *MockClient) GetVariable(ctx context.Context, repo, name string) (string, error) {
+	value, ok := m.variables[key(repo, name)]
+	if !ok {
+		return "", client.NewNotFoundError("variable not found")
+	}
+	return value, nil
+}
+
+// SetVariable implements the client.GitClient interface.
+func (m *MockClient) SetVariable(ctx context.Context, repo, name, value string) error {
+	if m.SetVariableErr != nil {
+		return m.SetVariableErr
+	}
+	m.variables[key(repo, name)] = value
+	m.setVariables[key(repo, name)] = value
+	return nil
+}
+
+// CreateTag implements the client.GitClient interface.
+func (m *MockClient) CreateTag(ctx context.Context, repo, name, sha, message string) error {
+	if m.CreateTagErr != nil {
+		return m.CreateTagErr
+	}
+	m.createdTags[key(repo, name, sha)] = message
+	return nil
+}
+
+// CreateRelease implements the client.GitClient interface.
+func (m *MockClient) CreateRelease(ctx context.Context, repo string, input *client.ReleaseInput) error {
+	if m.CreateReleaseErr != nil {
+		return m.CreateReleaseErr
+	}
+	m.createdReleases[key(repo, input.Tag)] = input
+	return nil
+}
+
+// GetLatestRelease implements the client.GitClient interface.
+func (m *MockClient) GetLatestRelease(ctx context.Context, repo string) (string, error) {
+	tag, ok := m.latestReleases[repo]
+	if !ok {
+		return "", client.NewNotFoundError("release not found")
+	}
+	return tag, nil
+}
+
+// AddLabels implements the client.GitClient interface.
+func (m *MockClient) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	if m.AddLabelsErr != nil {
+		return m.AddLabelsErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.addedLabels[k] = append(m.addedLabels[k], labels...)
+	return nil
+}
+
+// RequestReviewers implements the client.GitClient interface.
+func (m *MockClient) RequestReviewers(ctx context.Context, repo string, number int, logins []string) error {
+	if m.RequestReviewersErr != nil {
+		return m.RequestReviewersErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.requestedReviewers[k] = append(m.requestedReviewers[k], logins...)
+	return nil
+}
+
+// RequestTeamReviewers implements the client.GitClient interface.
+func (m *MockClient) RequestTeamReviewers(ctx context.Context, repo string, number int, teams []string) error {
+	if m.RequestTeamReviewersErr != nil {
+		return m.RequestTeamReviewersErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.requestedTeams[k] = append(m.requestedTeams[k], teams...)
+	return nil
+}
+
+// AddAssignees implements the client.GitClient interface.
+func (m *MockClient) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	if m.AddAssigneesErr != nil {
+		return m.AddAssigneesErr
+	}
+	k := key(repo, fmt.Sprint(number))
+	m.assignees[k] = append(m.assignees[k], logins...)
+	return nil
+}
+
+// EnableAutoMerge implements the client.GitClient interface.
+func (m *MockClient) EnableAutoMerge(ctx context.Context, repo string, number int, method string) error {
+	if m.EnableAutoMergeErr != nil {
+		return m.EnableAutoMergeErr
+	}
+	m.autoMerges[key(repo, fmt.Sprint(number))] = method
+	return nil
+}
+
+// SetMilestone implements the client.GitClient interface.
+func (m *MockClient) SetMilestone(ctx context.Context, repo string, number int, title string) error {
+	if m.SetMilestoneErr != nil {
+		return m.SetMilestoneErr
+	}
+	m.milestones[key(repo, fmt.Sprint(number))] = title
+	return nil
+}
+
+// AddToProject implements the client.GitClient interface.
+func (m *MockClient) AddToProject(ctx context.Context, repo string, number int, project, column string) error {
+	if m.AddToProjectErr != nil {
+		return m.AddToProjectErr
+	}
+	m.projects[key(repo, fmt.Sprint(number), project)] = column
+	return nil
+}
+
+// MergePullRequest implements the client.GitClient interface.
+func (m *MockClient) MergePullRequest(ctx context.Context, repo string, number int, method string) error {
+	if m.MergePullRequestErr != nil {
+		return m.MergePullRequestErr
+	}
+	m.merges[key(repo, fmt.Sprint(number))] = method
+	return nil
+}
+
+// GetPullRequest implements the client.GitClient interface, it returns the
+// states set up with AddPullRequestStates in turn.
+func (m *MockClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	k := key(repo, fmt.Sprint(number))
+	states := m.pullRequests[k]
+	if len(states) == 0 {
+		return nil, client.NewNotFoundError("pull request not found")
+	}
+	if len(states) > 1 {
+		m.pullRequests[k] = states[1:]
+	}
+	return states[0], nil
+}
+
+// AddPullRequestStates is a mock for setting up the responses for
+// GetPullRequest, each call returns the next state, and the last is repeated.
+func (m *MockClient) AddPullRequestStates(repo string, states ...*scm.PullRequest) {
+	for _, pr := range states {
+		k := key(repo, fmt.Sprint(pr.Number))
+		m.pullRequests[k] = append(m.pullRequests[k], pr)
+	}
+}
+
+// GetCombinedStatus implements the client.GitClient interface, it returns the
+// statuses set up with AddCombinedStatuses in turn, or an unknown status.
+func (m *MockClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*scm.CombinedStatus, error) {
+	k := key(repo, ref)
+	statuses := m.combinedStatuses[k]
+	if len(statuses) == 0 {
+		return &scm.CombinedStatus{State: scm.StateUnknown, Sha: ref}, nil
+	}
+	if len(statuses) > 1 {
+		m.combinedStatuses[k] = statuses[1:]
+	}
+	return statuses[0], nil
+}
+
+// AddCombinedStatuses is a mock for setting up the responses for
+// GetCombinedStatus, each call returns the next status, and the last is
+// repeated.
+func (m *MockClient) AddCombinedStatuses(repo, ref string, statuses ...*scm.CombinedStatus) {
+	k := key(repo, ref)
+	m.combinedStatuses[k] = append(m.combinedStatuses[k], statuses...)
+}
+
+// CreateStatus implements the client.GitClient interface.
+func (m *MockClient) CreateStatus(ctx context.Context, repo, sha string, status *scm.StatusInput) error {
+	if m.CreateStatusErr != nil {
+		return m.CreateStatusErr
+	}
+	k := key(repo, sha)
+	m.createdStatuses[k] = append(m.createdStatuses[k], status)
+	return nil
+}
+
+// AssertStatusCreated fails if no matching status was created for the commit.
+func (m *MockClient) AssertStatusCreated(repo, sha string, status *scm.StatusInput) {
+	m.t.Helper()
+	for _, s := range m.createdStatuses[key(repo, sha)] {
+		if reflect.DeepEqual(s, status) {
+			return
+		}
+	}
+	m.t.Fatalf("status %#v not created for commit %s in repo %s, got %#v", status, sha, repo, m.createdStatuses[key(repo, sha)])
+}
+
+// RefuteStatusCreated fails if a status was created for the commit.
+func (m *MockClient) RefuteStatusCreated(repo, sha string) {
+	m.t.Helper()
+	if s := m.createdStatuses[key(repo, sha)]; len(s) > 0 {
+		m.t.Fatalf("statuses created for commit %s in repo %s: %#v", sha, repo, s)
+	}
+}
+
+// AssertLabelsAdded fails if the labels added to the PullRequest differ.
+func (m *MockClient) AssertLabelsAdded(repo string, number int, labels ...string) {
+	m.t.Helper()
+	if got := m.addedLabels[key(repo, fmt.Sprint(number))]; !reflect.DeepEqual(labels, got) {
+		m.t.Fatalf("labels added to %s#%d differ, got %#v, want %#v", repo, number, got, labels)
+	}
+}
+
+// AssertReviewersRequested fails if the reviewers requested for the
+// PullRequest differ.
+func (m *MockClient) AssertReviewersRequested(repo string, number int, logins ...string) {
+	m.t.Helper()
+	if got := m.requestedReviewers[key(repo, fmt.Sprint(number))]; !reflect.DeepEqual(logins, got) {
+		m.t.Fatalf("reviewers requested for %s#%d differ, got %#v, want %#v", repo, number, got, logins)
+	}
+}
+
+// AssertTeamReviewersRequested fails if the team reviewers requested for the
+// PullRequest differ.
+func (m *MockClient) AssertTeamReviewersRequested(repo string, number int, teams ...string) {
+	m.t.Helper()
+	if got := m.requestedTeams[key(repo, fmt.Sprint(number))]; !reflect.DeepEqual(teams, got) {
+		m.t.Fatalf("team reviewers requested for %s#%d differ, got %#v, want %#v", repo, number, got, teams)
+	}
+}
+
+// AssertAssigneesAdded fails if the assignees added to the PullRequest differ.
+func (m *MockClient) AssertAssigneesAdded(repo string, number int, logins ...string) {
+	m.t.Helper()
+	if got := m.assignees[key(repo, fmt.Sprint(number))]; !reflect.DeepEqual(logins, got) {
+		m.t.Fatalf("assignees added to %s#%d differ, got %#v, want %#v", repo, number, got, logins)
+	}
+}
+
+// AssertMilestoneSet fails if the PullRequest wasn't added to the milestone.
+func (m *MockClient) AssertMilestoneSet(repo string, number int, title string) {
+	m.t.Helper()
+	if got, ok := m.milestones[key(repo, fmt.Sprint(number))]; !ok || got != title {
+		m.t.Fatalf("milestone of %s#%d is %#v, want %#v", repo, number, got, title)
+	}
+}
+
+// AssertAddedToProject fails if the PullRequest wasn't added to the project,
+// in the column.
+func (m *MockClient) AssertAddedToProject(repo string, number int, project, column string) {
+	m.t.Helper()
+	got, ok := m.projects[key(repo, fmt.Sprint(number), project)]
+	if !ok {
+		m.t.Fatalf("%s#%d was not added to project %s", repo, number, project)
+	}
+	if got != column {
+		m.t.Fatalf("%s#%d was added to project %s in column %#v, want %#v", repo, number, project, got, column)
+	}
+}
+
+// AssertAutoMergeEnabled fails if auto-merge was not enabled for the
+// PullRequest with the method.
+func (m *MockClient) AssertAutoMergeEnabled(repo string, number int, method string) {
+	m.t.Helper()
+	got, ok := m.autoMerges[key(repo, fmt.Sprint(number))]
+	if !ok {
+		m.t.Fatalf("auto-merge was not enabled for %s#%d", repo, number)
+	}
+	if got != method {
+		m.t.Fatalf("auto-merge enabled for %s#%d with %#v, want %#v", repo, number, got, method)
+	}
+}
+
+// RefuteAutoMergeEnabled fails if auto-merge was enabled for the
+// PullRequest.
+func (m *MockClient) RefuteAutoMergeEnabled(repo string, number int) {
+	m.t.Helper()
+	if got, ok := m.autoMerges[key(repo, fmt.Sprint(number))]; ok {
+		m.t.Fatalf("auto-merge was enabled for %s#%d with %#v", repo, number, got)
+	}
+}
+
+// AssertPullRequestMerged fails if the PullRequest was not merged with the
+// method.
+func (m *MockClient) AssertPullRequestMerged(repo string, number int, method string) {
+	m.t.Helper()
+	got, ok := m.merges[key(repo, fmt.Sprint(number))]
+	if !ok {
+		m.t.Fatalf("pull request %s#%d was not merged", repo, number)
+	}
+	if got != method {
+		m.t.Fatalf("pull request %s#%d merged with %#v, want %#v", repo, number, got, method)
+	}
+}
+
+// RefutePullRequestMerged fails if the PullRequest was merged.
+func (m *MockClient) RefutePullRequestMerged(repo string, number int) {
+	m.t.Helper()
+	if got, ok := m.merges[key(repo, fmt.Sprint(number))]; ok {
+		m.t.Fatalf("pull request %s#%d was merged with %#v", repo, number, got)
+	}
+}
+
+// AddLatestRelease is a mock method for setting up a fixture for
+// GetLatestRelease.
+func (m *MockClient) AddLatestRelease(repo, tag string) {
+	m.latestReleases[repo] = tag
+}
+
+// AddRef is a mock method for setting up a fixture for ResolveRef.
+func (m *MockClient) AddRef(repo, ref, sha string) {
+	m.refs[key(repo, ref)] = sha
+}
+
+// AddCommit is a mock method for setting up a fixture for GetCommit.
+func (m *MockClient) AddCommit(repo string, commit *scm.Commit) {
+	m.foundCommits[key(repo, commit.Sha)] = commit
+}
+
+// AddCommits is a mock method for setting up a fixture for CompareCommits.
+func (m *MockClient) AddCommits(repo, base, head string, commits ...*scm.Commit) {
+	m.commits[key(repo, base, head)] = commits
+}
+
+// AddVariable is a mock method for setting up a fixture for GetVariable.
+func (m *MockClient) AddVariable(repo, name, value string) {
+	m.variables[key(repo, name)] = value
+}
+
+// AssertVariableSet fails if the variable was not set to the value.
+func (m *MockClient) AssertVariableSet(repo, name, value string) {
+	m.t.Helper()
+	got, ok := m.setVariables[key(repo, name)]
+	if !ok {
+		m.t.Fatalf("variable %s was not set in %s", name, repo)
+	}
+	if got != value {
+		m.t.Fatalf("variable %s set to %#v, want %#v", name, got, value)
+	}
+}
+
+// RefuteVariableSet fails if the variable was set.
+func (m *MockClient) RefuteVariableSet(repo, name string) {
+	m.t.Helper()
+	if got, ok := m.setVariables[key(repo, name)]; ok {
+		m.t.Fatalf("variable %s was set to %#v in %s", name, got, repo)
+	}
+}
+
+// AssertTagCreated fails if the tag was not created for the SHA with the
+// message.
+func (m *MockClient) AssertTagCreated(repo, name, sha, message string) {
+	m.t.Helper()
+	got, ok := m.createdTags[key(repo, name, sha)]
+	if !ok {
+		m.t.Fatalf("tag %s was not created for %s in %s", name, sha, repo)
+	}
+	if got != message {
+		m.t.Fatalf("tag %s created with message %#v, want %#v", name, got, message)
+	}
+}
+
+// AssertReleaseCreated fails if the Release was not created.
+func (m *MockClient) AssertReleaseCreated(repo string, input *client.ReleaseInput) {
+	m.t.Helper()
+	got, ok := m.createdReleases[key(repo, input.Tag)]
+	if !ok {
+		m.t.Fatalf("release %s was not created in %s", input.Tag, repo)
+	}
+	if !reflect.DeepEqual(input, got) {
+		m.t.Fatalf("release %s differs, got %#v, want %#v", input.Tag, got, input)
+	}
+}
+
 // AddFileContents is a mock method for setting up a fixture for
 // GetFileContents.
 func (m *MockClient) AddFileContents(repo, path, ref string, body []byte) {
 	m.files[key(repo, path, ref)] = body
 }
 
+// ProtectBranch is a mock method for setting up a branch that rejects direct
+// commits with a conflict, and the message GitHub responds with for protected
+// branches.
+func (m *MockClient) ProtectBranch(repo, branch string) {
+	m.protectedBranches[key(repo, branch)] = true
+}
+
+// AddMissingFile is a mock method for setting up a file that GetFile reports
+// as NotFound, rather than failing with an error.
+func (m *MockClient) AddMissingFile(repo, path, ref string) {
+	m.missingFiles[key(repo, path, ref)] = true
+}
+
 // GetUpdatedContents returns the bytes captured by the mock implementation for
 // UpdateFile.
 func (m *MockClient) GetUpdatedContents(repo, path, ref string) []byte {
@@ -109,11 +858,35 @@ func (m *MockClient) GetUpdatedContents(repo, path, ref string) []byte {
 	return c
 }
 
+// AssertCommitMessage fails if the file was not updated in the branch with
+// the commit message.
+func (m *MockClient) AssertCommitMessage(repo, path, branch, message string) {
+	m.t.Helper()
+	got, ok := m.commitMessages[key(repo, path, branch)]
+	if !ok {
+		m.t.Fatalf("file %s was not updated in %s/%s", path, repo, branch)
+	}
+	if got != message {
+		m.t.Fatalf("incorrect commit message, got %#v, want %#v", got, message)
+	}
+}
+
 // AddBranchHead is a mock for setting up a response for GetBranchHead.
 func (m *MockClient) AddBranchHead(repo, branch, sha string) {
 	m.branchHeads[key(repo, branch)] = sha
 }
 
+// AddRepoPermissions is a mock for setting up a response for
+// GetRepoPermissions.
+func (m *MockClient) AddRepoPermissions(repo string, perm *scm.Perm) {
+	m.repoPermissions[repo] = perm
+}
+
+// AddRepoStatus is a mock for setting up a response for GetRepoStatus.
+func (m *MockClient) AddRepoStatus(repo string, status *client.RepoStatus) {
+	m.repoStatuses[repo] = status
+}
+
 // AssertBranchCreated fails if no matching branch was created using
 // CreateBranch.
 func (m *MockClient) AssertBranchCreated(repo, branch, sha string) {
@@ -154,9 +927,21 @@ func (m *MockClient) RefutePullRequestCreated(repo string, inp *scm.PullRequestI
 	}
 }
 
-// AssertNoBranchesCreated fails if a branch was created.
+// AssertCommentCreated fails if no matching comment was created on the issue
+// or PullRequest.
+func (m *MockClient) AssertCommentCreated(repo string, number int, body string) {
+	m.t.Helper()
+	for _, c := range m.createdComments[key(repo, fmt.Sprint(number))] {
+		if c == body {
+			return
+		}
+	}
+	m.t.Fatalf("comment not created on %s#%d", repo, number)
+}
+
+// AssertNoBranchesCreated fails if a branch was created, or reset.
 func (m *MockClient) AssertNoBranchesCreated() {
-	if l := len(m.createdBranches); l > 0 {
+	if l := len(m.createdBranches) + len(m.resetBranches); l > 0 {
 		m.t.Fatalf("expected no branches to be created: got %d", l)
 	}
 
@@ -182,6 +967,22 @@ func (m *MockClient) AssertNoInteractions() {
 	if len(m.createdPullRequests) != 0 {
 		m.t.Fatalf("pull requests created %#v", m.createdPullRequests)
 	}
+
+	if len(m.createdComments) != 0 {
+		m.t.Fatalf("comments created %#v", m.createdComments)
+	}
+
+	if len(m.setVariables) != 0 {
+		m.t.Fatalf("variables set %#v", m.setVariables)
+	}
+
+	if len(m.createdTags) != 0 {
+		m.t.Fatalf("tags created %#v", m.createdTags)
+	}
+
+	if len(m.createdReleases) != 0 {
+		m.t.Fatalf("releases created %#v", m.createdReleases)
+	}
 }
 
 func key(s ...string) string {
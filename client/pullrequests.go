@@ -0,0 +1,447 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// The merge methods for EnableAutoMerge.
+const (
+	MergeCommit = "merge"
+	Squash      = "squash"
+	Rebase      = "rebase"
+)
+
+// AddLabels adds the labels to the PullRequest, labels it already has are
+// retained.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	for _, l := range labels {
+		r, err := c.scmClient.PullRequests.AddLabel(ctx, repo, number, l)
+		if r != nil && isErrorStatus(r.Status) {
+			return scmError{msg: fmt.Sprintf("failed to add label %s to pull request %s#%d", l, repo, number), Status: r.Status}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestReviewers requests reviews of the PullRequest from the users, on
+// GitLab, the users are assigned to the MergeRequest.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) RequestReviewers(ctx context.Context, repo string, number int, logins []string) error {
+	r, err := c.scmClient.PullRequests.RequestReview(ctx, repo, number, logins)
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to request reviewers for pull request %s#%d", repo, number), Status: r.Status}
+	}
+	return err
+}
+
+// RequestTeamReviewers requests reviews of the PullRequest from the teams,
+// which are identified by their slugs, e.g. frontend, or my-org/frontend, this
+// is only supported by GitHub.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) RequestTeamReviewers(ctx context.Context, repo string, number int, teams []string) error {
+	if c.scmClient.Driver != scm.DriverGithub {
+		return fmt.Errorf("team reviewers are not supported by the %s driver", c.scmClient.Driver)
+	}
+	slugs := []string{}
+	for _, t := range teams {
+		slugs = append(slugs, t[strings.LastIndex(t, "/")+1:])
+	}
+	status, err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("repos/%s/pulls/%d/requested_reviewers", repo, number), map[string][]string{
+		"team_reviewers": slugs,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to request team reviewers for pull request %s#%d", repo, number), Status: status}
+	}
+	return nil
+}
+
+// AddAssignees assigns the users to the PullRequest, users that are already
+// assigned are retained.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	r, err := c.scmClient.PullRequests.AssignIssue(ctx, repo, number, logins)
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to add assignees to pull request %s#%d", repo, number), Status: r.Status}
+	}
+	return err
+}
+
+// SetMilestone adds the PullRequest to the open milestone with the title,
+// replacing any milestone it's in.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) SetMilestone(ctx context.Context, repo string, number int, title string) error {
+	type milestone struct {
+		ID     int    `json:"id"`
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+	}
+	milestones := []milestone{}
+	var listPath, updatePath string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		listPath = fmt.Sprintf("repos/%s/milestones?state=open&per_page=100", repo)
+		updatePath = fmt.Sprintf("repos/%s/issues/%d", repo, number)
+	case scm.DriverGitlab:
+		listPath = fmt.Sprintf("api/v4/projects/%s/milestones?state=active&title=%s", gitLabProject(repo), url.QueryEscape(title))
+		updatePath = fmt.Sprintf("api/v4/projects/%s/merge_requests/%d", gitLabProject(repo), number)
+	default:
+		return fmt.Errorf("milestones are not supported by the %s driver", c.scmClient.Driver)
+	}
+	status, err := c.getJSON(ctx, listPath, &milestones)
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to list the milestones of repo %s", repo), Status: status}
+	}
+	var found *milestone
+	for i := range milestones {
+		if milestones[i].Title == title {
+			found = &milestones[i]
+		}
+	}
+	if found == nil {
+		return fmt.Errorf("repo %s has no open milestone %q", repo, title)
+	}
+	var body interface{} = map[string]int{"milestone": found.Number}
+	method := http.MethodPatch
+	if c.scmClient.Driver == scm.DriverGitlab {
+		body, method = map[string]int{"milestone_id": found.ID}, http.MethodPut
+	}
+	status, err = c.sendJSON(ctx, method, updatePath, body, nil)
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to set the milestone of pull request %s#%d", repo, number), Status: status}
+	}
+	return nil
+}
+
+// AddToProject adds the PullRequest to the GitHub project, identified by its
+// node ID e.g. PVT_kwDOA, and if the column isn't empty, sets the item's
+// Status to it, e.g. "In review".
+//
+// Only GitHub projects are supported, GitLab boards are organised by labels.
+func (c *SCMClient) AddToProject(ctx context.Context, repo string, number int, project, column string) error {
+	if !isGitHub(c.scmClient) {
+		return fmt.Errorf("projects are not supported by the %s driver", c.scmClient.Driver)
+	}
+	id, err := c.gitHubNodeID(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	added := struct {
+		AddProjectV2ItemByID struct {
+			Item struct {
+				ID string `json:"id"`
+			} `json:"item"`
+		} `json:"addProjectV2ItemById"`
+	}{}
+	status, err := c.gitHubGraphQL(ctx, `mutation($project: ID!, $id: ID!) {
+  addProjectV2ItemById(input: {projectId: $project, contentId: $id}) { item { id } }
+}`, map[string]interface{}{"project": project, "id": id}, &added)
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to add pull request %s#%d to project %s", repo, number, project), Status: status}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to add pull request %s#%d to project %s: %w", repo, number, project, err)
+	}
+	if column == "" {
+		return nil
+	}
+	fields := struct {
+		Node struct {
+			Field struct {
+				ID      string `json:"id"`
+				Options []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"options"`
+			} `json:"field"`
+		} `json:"node"`
+	}{}
+	status, err = c.gitHubGraphQL(ctx, `query($project: ID!) {
+  node(id: $project) { ... on ProjectV2 { field(name: "Status") { ... on ProjectV2SingleSelectField { id options { id name } } } } }
+}`, map[string]interface{}{"project": project}, &fields)
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to get the Status field of project %s", project), Status: status}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the Status field of project %s: %w", project, err)
+	}
+	option := ""
+	for _, o := range fields.Node.Field.Options {
+		if o.Name == column {
+			option = o.ID
+		}
+	}
+	if option == "" {
+		return fmt.Errorf("project %s has no %q Status", project, column)
+	}
+	status, err = c.gitHubGraphQL(ctx, `mutation($project: ID!, $item: ID!, $field: ID!, $option: String!) {
+  updateProjectV2ItemFieldValue(input: {projectId: $project, itemId: $item, fieldId: $field, value: {singleSelectOptionId: $option}}) { clientMutationId }
+}`, map[string]interface{}{"project": project, "item": added.AddProjectV2ItemByID.Item.ID, "field": fields.Node.Field.ID, "option": option}, nil)
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to set the Status of pull request %s#%d in project %s", repo, number, project), Status: status}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set the Status of pull request %s#%d in project %s: %w", repo, number, project, err)
+	}
+	return nil
+}
+
+// EnableAutoMerge configures the PullRequest to be merged with the method,
+// once its checks pass, GitHub auto-merge and GitLab merge when pipeline
+// succeeds are supported.
+//
+// GitHub returns an error wrapping ErrMergeable if the PullRequest can be
+// merged already, GitLab merges it, and an error wrapping
+// ErrAutoMergeUnsupported if the server predates auto-merge.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) EnableAutoMerge(ctx context.Context, repo string, number int, method string) error {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return c.enableGitHubAutoMerge(ctx, repo, number, method)
+	case scm.DriverGitlab:
+		if method == Rebase {
+			return fmt.Errorf("the %s merge method is not supported by GitLab", method)
+		}
+		status, err := c.sendJSON(ctx, http.MethodPut, fmt.Sprintf("api/v4/projects/%s/merge_requests/%d/merge", gitLabProject(repo), number), map[string]bool{
+			"merge_when_pipeline_succeeds": true,
+			"squash":                       method == Squash,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if isErrorStatus(status) {
+			return scmError{msg: fmt.Sprintf("failed to enable auto-merge for merge request %s!%d", repo, number), Status: status}
+		}
+		return nil
+	}
+	return fmt.Errorf("auto-merge is not supported by the %s driver", c.scmClient.Driver)
+}
+
+// MergePullRequest merges the PullRequest with the method, now, rather than
+// once its checks pass.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) MergePullRequest(ctx context.Context, repo string, number int, method string) error {
+	if c.scmClient.Driver == scm.DriverGitlab && method == Rebase {
+		return fmt.Errorf("the %s merge method is not supported by GitLab", method)
+	}
+	r, err := c.scmClient.PullRequests.Merge(ctx, repo, number, &scm.PullRequestMergeOptions{MergeMethod: method})
+	if r != nil && isErrorStatus(r.Status) {
+		return scmError{msg: fmt.Sprintf("failed to merge pull request %s#%d", repo, number), Status: r.Status}
+	}
+	return err
+}
+
+// enableGitHubAutoMerge uses the GraphQL API, which identifies the
+// PullRequest by its node ID.
+func (c *SCMClient) enableGitHubAutoMerge(ctx context.Context, repo string, number int, method string) error {
+	id, err := c.gitHubNodeID(ctx, repo, number)
+	if err != nil {
+		return err
+	}
+	status, err := c.gitHubGraphQL(ctx, `mutation($id: ID!, $method: PullRequestMergeMethod!) {
+  enablePullRequestAutoMerge(input: {pullRequestId: $id, mergeMethod: $method}) { clientMutationId }
+}`, map[string]interface{}{"id": id, "method": strings.ToUpper(method)}, nil)
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to enable auto-merge for pull request %s#%d", repo, number), Status: status}
+	}
+	var gerr graphQLError
+	switch {
+	case errors.As(err, &gerr) && strings.Contains(gerr.message, "clean status"):
+		// GitHub refuses to wait for checks that have already passed.
+		return fmt.Errorf("failed to enable auto-merge for pull request %s#%d: %w", repo, number, ErrMergeable)
+	case errors.As(err, &gerr) && strings.Contains(gerr.message, "enablePullRequestAutoMerge' doesn't exist"):
+		// Older Enterprise Servers don't have the mutation in their schema.
+		return fmt.Errorf("failed to enable auto-merge for pull request %s#%d: %w", repo, number, ErrAutoMergeUnsupported)
+	case errors.As(err, &gerr):
+		return fmt.Errorf("failed to enable auto-merge for pull request %s#%d: %w", repo, number, err)
+	}
+	return err
+}
+
+// gitHubNodeID returns the GraphQL node ID of the PullRequest.
+func (c *SCMClient) gitHubNodeID(ctx context.Context, repo string, number int) (string, error) {
+	pr := struct {
+		NodeID string `json:"node_id"`
+	}{}
+	status, err := c.getJSON(ctx, fmt.Sprintf("repos/%s/pulls/%d", repo, number), &pr)
+	if err != nil {
+		return "", fmt.Errorf("failed to get pull request %s#%d: %w", repo, number, err)
+	}
+	if isErrorStatus(status) {
+		return "", scmError{msg: fmt.Sprintf("failed to get pull request %s#%d", repo, number), Status: status}
+	}
+	return pr.NodeID, nil
+}
+
+// graphQLError is the first error in a GraphQL response.
+type graphQLError struct {
+	message string
+}
+
+func (e graphQLError) Error() string {
+	return e.message
+}
+
+// gitHubGraphQL sends the query to the GraphQL API, and decodes the data of
+// the response into out, if it's not nil, the first error in the response is
+// returned as a graphQLError.
+func (c *SCMClient) gitHubGraphQL(ctx context.Context, query string, variables map[string]interface{}, out interface{}) (int, error) {
+	result := struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	status, err := c.sendJSON(ctx, http.MethodPost, c.gitHubGraphQLPath(), map[string]interface{}{"query": query, "variables": variables}, &result)
+	if err != nil || isErrorStatus(status) {
+		return status, err
+	}
+	if len(result.Errors) > 0 {
+		return status, graphQLError{message: result.Errors[0].Message}
+	}
+	if out != nil && len(result.Data) > 0 {
+		if err := json.Unmarshal(result.Data, out); err != nil {
+			return status, fmt.Errorf("failed to decode the GraphQL response: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// gitHubGraphQLPath returns the path of the GraphQL API, relative to the base
+// URL of the REST API, which is /api/v3/ for GitHub Enterprise Server.
+func (c *SCMClient) gitHubGraphQLPath() string {
+	if strings.HasSuffix(c.scmClient.BaseURL.Path, "/api/v3/") {
+		return "../graphql"
+	}
+	return "graphql"
+}
+
+// GetPullRequest returns the PullRequest, with its current state, e.g. whether
+// it has been merged.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	pr, r, err := c.scmClient.PullRequests.Find(ctx, repo, number)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to get pull request %s#%d", repo, number), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// GetCombinedStatus returns the statuses of the ref, and their combined state,
+// which is StateUnknown if there are none.
+//
+// GitHub check runs, e.g. from Actions, are included with the commit
+// statuses, labelled with their name.
+//
+// If an HTTP error is returned by the upstream service, an error with the
+// response status code is returned.
+func (c *SCMClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*scm.CombinedStatus, error) {
+	status, r, err := c.scmClient.Repositories.FindCombinedStatus(ctx, repo, ref)
+	if r != nil && isErrorStatus(r.Status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to get the status of %s in repo %s", ref, repo), Status: r.Status}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if isGitHub(c.scmClient) {
+		runs, err := c.gitHubCheckRuns(ctx, repo, ref)
+		if err != nil {
+			return nil, err
+		}
+		status.Statuses = append(status.Statuses, runs...)
+	}
+	status.State = combinedState(status.Statuses)
+	return status, nil
+}
+
+func (c *SCMClient) gitHubCheckRuns(ctx context.Context, repo, ref string) ([]*scm.Status, error) {
+	runs := struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}{}
+	status, err := c.getJSON(ctx, fmt.Sprintf("repos/%s/commits/%s/check-runs?per_page=100", repo, ref), &runs)
+	if err != nil {
+		return nil, err
+	}
+	if isErrorStatus(status) {
+		return nil, scmError{msg: fmt.Sprintf("failed to get the check runs of %s in repo %s", ref, repo), Status: status}
+	}
+	statuses := []*scm.Status{}
+	for _, run := range runs.CheckRuns {
+		s := &scm.Status{Label: run.Name, Link: run.HTMLURL, State: scm.StatePending}
+		if run.Status == "completed" {
+			switch run.Conclusion {
+			case "success", "neutral", "skipped":
+				s.State = scm.StateSuccess
+			case "cancelled":
+				s.State = scm.StateCanceled
+			default:
+				s.State = scm.StateFailure
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// combinedState is the failed state if any status failed, pending if any are
+// still pending, or successful if they all succeeded.
+func combinedState(statuses []*scm.Status) scm.State {
+	if len(statuses) == 0 {
+		return scm.StateUnknown
+	}
+	state := scm.StateSuccess
+	for _, s := range statuses {
+		switch s.State {
+		case scm.StateFailure, scm.StateError, scm.StateCanceled:
+			return scm.StateFailure
+		case scm.StateSuccess:
+		default:
+			state = scm.StatePending
+		}
+	}
+	return state
+}
@@ -0,0 +1,555 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestAddLabelsInGitHub(t *testing.T) {
+	for _, l := range []string{"automated", "image-update"} {
+		gock.New("https://api.github.com").
+			Post("/repos/Codertocat/Hello-World/issues/2/labels").
+			MatchType("json").
+			JSON([]string{l}).
+			Reply(http.StatusOK).
+			Type("application/json").
+			JSON([]map[string]string{{"name": l}})
+	}
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.AddLabels(context.TODO(), "Codertocat/Hello-World", 2, []string{"automated", "image-update"}); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("labels were not added")
+	}
+}
+
+func TestAddLabelsWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/2/labels").
+		Reply(http.StatusForbidden).
+		JSON(map[string]string{"message": "Forbidden"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.AddLabels(context.TODO(), "Codertocat/Hello-World", 2, []string{"automated"})
+
+	if s := StatusCode(err); s != http.StatusForbidden {
+		t.Fatalf("got status %d, want %d: %v", s, http.StatusForbidden, err)
+	}
+}
+
+func TestRequestReviewersInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls/2/requested_reviewers").
+		MatchType("json").
+		JSON(map[string][]string{"reviewers": {"octocat"}}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.RequestReviewers(context.TODO(), "Codertocat/Hello-World", 2, []string{"octocat"}); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("reviewers were not requested")
+	}
+}
+
+func TestRequestTeamReviewersInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls/2/requested_reviewers").
+		MatchType("json").
+		JSON(map[string][]string{"team_reviewers": {"frontend", "platform"}}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.RequestTeamReviewers(context.TODO(), "Codertocat/Hello-World", 2, []string{"Codertocat/frontend", "platform"}); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("team reviewers were not requested")
+	}
+}
+
+func TestRequestTeamReviewersWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/pulls/2/requested_reviewers").
+		Reply(http.StatusUnprocessableEntity).
+		JSON(map[string]string{"message": "Reviews may only be requested from collaborators"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.RequestTeamReviewers(context.TODO(), "Codertocat/Hello-World", 2, []string{"frontend"})
+
+	if s := StatusCode(err); s != http.StatusUnprocessableEntity {
+		t.Fatalf("got status %d, want %d: %v", s, http.StatusUnprocessableEntity, err)
+	}
+}
+
+func TestRequestTeamReviewersInGitLab(t *testing.T) {
+	client := newTestClient(t, "gitlab", "")
+
+	err := client.RequestTeamReviewers(context.TODO(), "Codertocat/Hello-World", 2, []string{"frontend"})
+
+	if !test.MatchError(t, "team reviewers are not supported by the gitlab driver", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestAddAssigneesInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/issues/2/assignees").
+		MatchType("json").
+		JSON(map[string][]string{"assignees": {"octocat"}}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "assignees": []map[string]string{{"login": "octocat"}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.AddAssignees(context.TODO(), "Codertocat/Hello-World", 2, []string{"octocat"}); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("assignees were not added")
+	}
+}
+
+func TestSetMilestoneInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/milestones").
+		MatchParam("state", "open").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{{"number": 1, "title": "v1.1.0"}, {"number": 3, "title": "v1.2.0"}})
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/issues/2").
+		MatchType("json").
+		JSON(map[string]int{"milestone": 3}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.SetMilestone(context.TODO(), "Codertocat/Hello-World", 2, "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("milestone was not set")
+	}
+}
+
+func TestSetMilestoneInGitHubWithMissingMilestone(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/milestones").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{{"number": 1, "title": "v1.1.0"}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.SetMilestone(context.TODO(), "Codertocat/Hello-World", 2, "v1.2.0")
+
+	if !test.MatchError(t, `repo Codertocat/Hello-World has no open milestone "v1.2.0"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestSetMilestoneInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/milestones").
+		MatchParam("title", "v1.2.0").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]interface{}{{"id": 12, "iid": 3, "title": "v1.2.0"}})
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/2").
+		MatchType("json").
+		JSON(map[string]int{"milestone_id": 12}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"iid": 2})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	if err := client.SetMilestone(context.TODO(), "Codertocat/Hello-World", 2, "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("milestone was not set")
+	}
+}
+
+func TestAddToProjectInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		MatchType("json").
+		BodyString(`"variables":{"id":"PR_kwDOA","project":"PVT_kwDOA"}`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{"addProjectV2ItemById": map[string]interface{}{"item": map[string]string{"id": "PVTI_lADOA"}}}})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"variables":{"project":"PVT_kwDOA"}`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{"node": map[string]interface{}{"field": map[string]interface{}{
+			"id":      "PVTSSF_lADOA",
+			"options": []map[string]string{{"id": "f75ad846", "name": "Todo"}, {"id": "47fc9ee4", "name": "In review"}},
+		}}}})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`"variables":{"field":"PVTSSF_lADOA","item":"PVTI_lADOA","option":"47fc9ee4","project":"PVT_kwDOA"}`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.AddToProject(context.TODO(), "Codertocat/Hello-World", 2, "PVT_kwDOA", "In review"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not added to the project")
+	}
+}
+
+func TestAddToProjectInGitHubWithUnknownColumn(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		BodyString(`addProjectV2ItemById`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{"addProjectV2ItemById": map[string]interface{}{"item": map[string]string{"id": "PVTI_lADOA"}}}})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{"node": map[string]interface{}{"field": map[string]interface{}{
+			"id":      "PVTSSF_lADOA",
+			"options": []map[string]string{{"id": "f75ad846", "name": "Todo"}},
+		}}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.AddToProject(context.TODO(), "Codertocat/Hello-World", 2, "PVT_kwDOA", "In review")
+
+	if !test.MatchError(t, `project PVT_kwDOA has no "In review" Status`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestAddToProjectInGitLab(t *testing.T) {
+	client := newTestClient(t, "gitlab", "")
+
+	err := client.AddToProject(context.TODO(), "Codertocat/Hello-World", 2, "PVT_kwDOA", "")
+
+	if !test.MatchError(t, "projects are not supported by the gitlab driver", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestEnableAutoMergeInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		MatchType("json").
+		BodyString(`"variables":{"id":"PR_kwDOA","method":"SQUASH"}`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{"enablePullRequestAutoMerge": map[string]interface{}{"clientMutationId": nil}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Squash); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("auto-merge was not enabled")
+	}
+}
+
+func TestEnableAutoMergeInGitHubEnterprise(t *testing.T) {
+	gock.New("https://ghe.example.com").
+		Get("/api/v3/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://ghe.example.com").
+		Post("/api/graphql").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"data": map[string]interface{}{}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "https://ghe.example.com/api/v3")
+
+	if err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, MergeCommit); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("auto-merge was not enabled")
+	}
+}
+
+func TestEnableAutoMergeInGitHubWithGraphQLError(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"errors": []map[string]string{{"message": "Auto merge is not allowed for this repository"}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Squash)
+
+	if !test.MatchError(t, "failed to enable auto-merge for pull request Codertocat/Hello-World#2: Auto merge is not allowed", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestEnableAutoMergeInGitHubWhenMergeable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "PR_kwDOA"})
+	gock.New("https://api.github.com").
+		Post("/graphql").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"errors": []map[string]string{{"message": "Pull request Pull request is in clean status"}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Squash)
+
+	if !errors.Is(err, ErrMergeable) {
+		t.Fatalf("got error %v, want ErrMergeable", err)
+	}
+}
+
+func TestEnableAutoMergeInOlderGitHubEnterprise(t *testing.T) {
+	gock.New("https://ghe.example.com").
+		Get("/api/v3/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "node_id": "MDExOlB1bGxSZXF1ZXN0Mg=="})
+	gock.New("https://ghe.example.com").
+		Post("/api/graphql").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"errors": []map[string]string{{"message": "Field 'enablePullRequestAutoMerge' doesn't exist on type 'Mutation'"}}})
+	defer gock.Off()
+	client := newTestClient(t, "github", "https://ghe.example.com/api/v3")
+
+	err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Squash)
+
+	if !errors.Is(err, ErrAutoMergeUnsupported) {
+		t.Fatalf("got error %v, want ErrAutoMergeUnsupported", err)
+	}
+}
+
+func TestEnableAutoMergeInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/merge_requests/2/merge").
+		MatchType("json").
+		JSON(map[string]bool{"merge_when_pipeline_succeeds": true, "squash": true}).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"iid": 2})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	if err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Squash); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("auto-merge was not enabled")
+	}
+}
+
+func TestEnableAutoMergeInGitLabWithRebase(t *testing.T) {
+	client := newTestClient(t, "gitlab", "")
+
+	err := client.EnableAutoMerge(context.TODO(), "Codertocat/Hello-World", 2, Rebase)
+
+	if !test.MatchError(t, "the rebase merge method is not supported by GitLab", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestMergePullRequestInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/2/merge").
+		MatchType("json").
+		BodyString(`"merge_method":"squash"`).
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"merged": true})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.MergePullRequest(context.TODO(), "Codertocat/Hello-World", 2, Squash); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("pull request was not merged")
+	}
+}
+
+func TestMergePullRequestInGitHubWithConflict(t *testing.T) {
+	gock.New("https://api.github.com").
+		Put("/repos/Codertocat/Hello-World/pulls/2/merge").
+		Reply(http.StatusMethodNotAllowed).
+		Type("application/json").
+		JSON(map[string]string{"message": "Pull Request is not mergeable"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.MergePullRequest(context.TODO(), "Codertocat/Hello-World", 2, Squash)
+
+	if !test.MatchError(t, "failed to merge pull request Codertocat/Hello-World#2", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestMergePullRequestInGitLabWithRebase(t *testing.T) {
+	client := newTestClient(t, "gitlab", "")
+
+	err := client.MergePullRequest(context.TODO(), "Codertocat/Hello-World", 2, Rebase)
+
+	if !test.MatchError(t, "the rebase merge method is not supported by GitLab", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetPullRequestInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]interface{}{"number": 2, "state": "closed", "merged": true, "merge_commit_sha": "e5bd3914e2e596debea16f433f57875b5b90bcd6"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	pr, err := client.GetPullRequest(context.TODO(), "Codertocat/Hello-World", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !pr.Merged || pr.MergeSha != "e5bd3914e2e596debea16f433f57875b5b90bcd6" {
+		t.Fatalf("got %#v, want a merged pull request", pr)
+	}
+}
+
+func TestGetPullRequestWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/pulls/2").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.GetPullRequest(context.TODO(), "Codertocat/Hello-World", 2)
+
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestGetCombinedStatusInGitHub(t *testing.T) {
+	statusTests := []struct {
+		name     string
+		statuses []map[string]string
+		runs     []map[string]string
+		want     scm.State
+	}{
+		{"no checks", nil, nil, scm.StateUnknown},
+		{"passed", []map[string]string{{"context": "ci/build", "state": "success"}}, []map[string]string{{"name": "test", "status": "completed", "conclusion": "success"}, {"name": "docs", "status": "completed", "conclusion": "skipped"}}, scm.StateSuccess},
+		{"running check", []map[string]string{{"context": "ci/build", "state": "success"}}, []map[string]string{{"name": "test", "status": "in_progress"}}, scm.StatePending},
+		{"failed check", []map[string]string{{"context": "ci/build", "state": "pending"}}, []map[string]string{{"name": "test", "status": "completed", "conclusion": "timed_out"}}, scm.StateFailure},
+		{"failed status", []map[string]string{{"context": "ci/build", "state": "failure"}}, nil, scm.StateFailure},
+	}
+
+	for _, tt := range statusTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			gock.New("https://api.github.com").
+				Get("/repos/Codertocat/Hello-World/commits/6dcb09b5b57875f334f61aebed695e2e4193db5e/status").
+				Reply(http.StatusOK).
+				Type("application/json").
+				JSON(map[string]interface{}{"state": "pending", "statuses": tt.statuses})
+			gock.New("https://api.github.com").
+				Get("/repos/Codertocat/Hello-World/commits/6dcb09b5b57875f334f61aebed695e2e4193db5e/check-runs").
+				Reply(http.StatusOK).
+				Type("application/json").
+				JSON(map[string]interface{}{"check_runs": tt.runs})
+			defer gock.Off()
+			client := newTestClient(rt, "github", "")
+
+			status, err := client.GetCombinedStatus(context.TODO(), "Codertocat/Hello-World", "6dcb09b5b57875f334f61aebed695e2e4193db5e")
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if status.State != tt.want {
+				rt.Fatalf("got state %s, want %s", status.State, tt.want)
+			}
+			if l := len(status.Statuses); l != len(tt.statuses)+len(tt.runs) {
+				rt.Fatalf("got %d statuses, want %d", l, len(tt.statuses)+len(tt.runs))
+			}
+		})
+	}
+}
+
+func TestGetCombinedStatusInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/repository/commits/6dcb09b5b57875f334f61aebed695e2e4193db5e/statuses").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{{"name": "build", "status": "success"}, {"name": "test", "status": "running"}})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	status, err := client.GetCombinedStatus(context.TODO(), "Codertocat/Hello-World", "6dcb09b5b57875f334f61aebed695e2e4193db5e")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status.State != scm.StatePending {
+		t.Fatalf("got state %s, want pending", status.State)
+	}
+}
@@ -0,0 +1,163 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// ReleaseInput provides the configuration for a provider Release.
+type ReleaseInput struct {
+	Tag  string // The tag the Release is for, e.g. v1.2.0
+	Name string
+	Body string
+}
+
+// CreateTag creates an annotated tag for the commit with the message.
+func (c *SCMClient) CreateTag(ctx context.Context, repo, name, sha, message string) error {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		tag := struct {
+			Sha string `json:"sha"`
+		}{}
+		status, err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("repos/%s/git/tags", repo), map[string]string{
+			"tag":     name,
+			"message": message,
+			"object":  sha,
+			"type":    "commit",
+		}, &tag)
+		if err != nil {
+			return err
+		}
+		if isErrorStatus(status) {
+			return scmError{msg: fmt.Sprintf("failed to create tag %s in repo %s", name, repo), Status: status}
+		}
+		_, r, err := c.scmClient.Git.CreateRef(ctx, repo, "refs/tags/"+name, tag.Sha)
+		if r != nil && isErrorStatus(r.Status) {
+			return scmError{msg: fmt.Sprintf("failed to create ref for tag %s in repo %s", name, repo), Status: r.Status}
+		}
+		return err
+	case scm.DriverGitlab:
+		status, err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("api/v4/projects/%s/repository/tags", gitLabProject(repo)), map[string]string{
+			"tag_name": name,
+			"ref":      sha,
+			"message":  message,
+		}, nil)
+		if err != nil {
+			return err
+		}
+		if isErrorStatus(status) {
+			return scmError{msg: fmt.Sprintf("failed to create tag %s in repo %s", name, repo), Status: status}
+		}
+		return nil
+	}
+	return fmt.Errorf("tags are not supported by the %s driver", c.scmClient.Driver)
+}
+
+// CreateRelease creates a provider Release for an existing tag.
+func (c *SCMClient) CreateRelease(ctx context.Context, repo string, input *ReleaseInput) error {
+	var path string
+	var body map[string]string
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		path = fmt.Sprintf("repos/%s/releases", repo)
+		body = map[string]string{"tag_name": input.Tag, "name": input.Name, "body": input.Body}
+	case scm.DriverGitlab:
+		path = fmt.Sprintf("api/v4/projects/%s/releases", gitLabProject(repo))
+		body = map[string]string{"tag_name": input.Tag, "name": input.Name, "description": input.Body}
+	default:
+		return fmt.Errorf("releases are not supported by the %s driver", c.scmClient.Driver)
+	}
+	status, err := c.sendJSON(ctx, http.MethodPost, path, body, nil)
+	if err != nil {
+		return err
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to create release %s in repo %s", input.Tag, repo), Status: status}
+	}
+	return nil
+}
+
+// GetLatestRelease returns the tag of the most recent Release in the repo.
+//
+// If the repo has no Releases, a not found error is returned.
+func (c *SCMClient) GetLatestRelease(ctx context.Context, repo string) (string, error) {
+	release := struct {
+		Tag string `json:"tag_name"`
+	}{}
+	var status int
+	var err error
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		status, err = c.getJSON(ctx, fmt.Sprintf("repos/%s/releases/latest", repo), &release)
+	case scm.DriverGitlab:
+		releases := []struct {
+			Tag string `json:"tag_name"`
+		}{}
+		status, err = c.getJSON(ctx, fmt.Sprintf("api/v4/projects/%s/releases?per_page=1", gitLabProject(repo)), &releases)
+		if err == nil && !isErrorStatus(status) && len(releases) == 0 {
+			status = http.StatusNotFound
+		}
+		if len(releases) > 0 {
+			release.Tag = releases[0].Tag
+		}
+	default:
+		return "", fmt.Errorf("releases are not supported by the %s driver", c.scmClient.Driver)
+	}
+	if err != nil {
+		return "", err
+	}
+	if isErrorStatus(status) {
+		return "", scmError{msg: fmt.Sprintf("failed to get the latest release in repo %s", repo), Status: status}
+	}
+	return release.Tag, nil
+}
+
+// getJSON decodes successful responses to the request into out.
+func (c *SCMClient) getJSON(ctx context.Context, path string, out interface{}) (int, error) {
+	res, err := c.scmClient.Do(ctx, &scm.Request{Method: http.MethodGet, Path: path})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if !isErrorStatus(res.Status) {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.Status, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return res.Status, nil
+}
+
+// sendJSON sends the body as JSON, and decodes successful responses into out
+// if it's not nil.
+func (c *SCMClient) sendJSON(ctx context.Context, method, path string, body, out interface{}) (int, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	res, err := c.scmClient.Do(ctx, &scm.Request{
+		Method: method,
+		Path:   path,
+		Header: http.Header{"Content-Type": []string{"application/json"}},
+		Body:   bytes.NewReader(b),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	if out != nil && !isErrorStatus(res.Status) {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			return res.Status, fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return res.Status, nil
+}
+
+func gitLabProject(repo string) string {
+	return strings.ReplaceAll(repo, "/", "%2F")
+}
@@ -0,0 +1,147 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestCreateTagInGitHub(t *testing.T) {
+	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/tags").
+		MatchType("json").
+		JSON(map[string]string{"tag": "v1.0.0", "message": "Release v1.0.0", "object": sha, "type": "commit"}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		JSON(map[string]string{"sha": "940bd336248efae0f9ee5bc7b2d5c985887b16ac"})
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/refs").
+		MatchType("json").
+		JSON(map[string]string{"ref": "refs/tags/v1.0.0", "sha": "940bd336248efae0f9ee5bc7b2d5c985887b16ac"}).
+		Reply(http.StatusCreated).
+		Type("application/json").
+		File("testdata/content.json")
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.CreateTag(context.TODO(), "Codertocat/Hello-World", "v1.0.0", sha, "Release v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("tag was not created")
+	}
+}
+
+func TestCreateTagInGitLab(t *testing.T) {
+	sha := "aa218f56b14c9653891f9e74264a383fa43fefbd"
+	gock.New("https://gitlab.com").
+		Post("/api/v4/projects/Codertocat/Hello-World/repository/tags").
+		MatchType("json").
+		JSON(map[string]string{"tag_name": "v1.0.0", "ref": sha, "message": "Release v1.0.0"}).
+		Reply(http.StatusCreated)
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	if err := client.CreateTag(context.TODO(), "Codertocat/Hello-World", "v1.0.0", sha, "Release v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("tag was not created")
+	}
+}
+
+func TestCreateTagWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/git/tags").
+		Reply(http.StatusUnprocessableEntity)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.CreateTag(context.TODO(), "Codertocat/Hello-World", "v1.0.0", "aa218f56b14c9653891f9e74264a383fa43fefbd", "Release")
+	if !test.MatchError(t, `failed to create tag v1.0.0 in repo Codertocat/Hello-World: \(422\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCreateRelease(t *testing.T) {
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/releases").
+		MatchType("json").
+		JSON(map[string]string{"tag_name": "v1.0.0", "name": "Release v1.0.0", "body": "Promoted"}).
+		Reply(http.StatusCreated)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.CreateRelease(context.TODO(), "Codertocat/Hello-World", &ReleaseInput{Tag: "v1.0.0", Name: "Release v1.0.0", Body: "Promoted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("release was not created")
+	}
+}
+
+func TestCreateReleaseWithUnsupportedDriver(t *testing.T) {
+	client := newTestClient(t, "bitbucketcloud", "")
+
+	err := client.CreateRelease(context.TODO(), "Codertocat/Hello-World", &ReleaseInput{Tag: "v1.0.0"})
+	if !test.MatchError(t, `releases are not supported by the bitbucket driver`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestGetLatestReleaseInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/releases/latest").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]string{"tag_name": "v1.1.0"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	tag, err := client.GetLatestRelease(context.TODO(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "v1.1.0" {
+		t.Fatalf("got %#v, want %#v", tag, "v1.1.0")
+	}
+}
+
+func TestGetLatestReleaseInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/releases").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{{"tag_name": "v1.1.0"}})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	tag, err := client.GetLatestRelease(context.TODO(), "Codertocat/Hello-World")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "v1.1.0" {
+		t.Fatalf("got %#v, want %#v", tag, "v1.1.0")
+	}
+}
+
+func TestGetLatestReleaseWithNoReleases(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Get("/api/v4/projects/Codertocat/Hello-World/releases").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON([]map[string]string{})
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	_, err := client.GetLatestRelease(context.TODO(), "Codertocat/Hello-World")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
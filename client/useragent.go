@@ -0,0 +1,76 @@
+package client
+
+import (
+	"net/http"
+
+	"github.com/agill17/pkg/buildinfo"
+)
+
+// Option is an option for creating new SCMClients.
+type Option func(o *options)
+
+type options struct {
+	userAgent  string
+	header     http.Header
+	apiVersion string
+}
+
+// UserAgent is an option func for the SCMClient creation function, it sets
+// the User-Agent sent with all provider calls, e.g. "my-tool/1.2.0 (prod-1)".
+//
+// The instance is optional.
+func UserAgent(name, version, instance string) Option {
+	return func(o *options) {
+		o.userAgent = name + "/" + version
+		if instance != "" {
+			o.userAgent += " (" + instance + ")"
+		}
+	}
+}
+
+// RequestHeader is an option func for the SCMClient creation function, it
+// adds a header to all provider calls, e.g. to annotate the calls with a
+// request ID.
+func RequestHeader(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+// DefaultUserAgent is the User-Agent sent when none is configured, it
+// identifies the version of this module.
+func DefaultUserAgent() string {
+	return buildinfo.Get().UserAgent()
+}
+
+// annotate wraps the HTTP client used by the scm.Client so that the
+// User-Agent and headers are sent with every request.
+//
+// The HTTP client is copied, as it may be shared e.g. http.DefaultClient.
+func annotate(c *http.Client, o *options) *http.Client {
+	header := o.header.Clone()
+	header.Set("User-Agent", o.userAgent)
+	annotated := &http.Client{}
+	if c != nil {
+		*annotated = *c
+	}
+	annotated.Transport = &headerTransport{header: header, next: annotated.Transport}
+	return annotated
+}
+
+type headerTransport struct {
+	header http.Header
+	next   http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	for k, v := range t.header {
+		r.Header[k] = v
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(r)
+}
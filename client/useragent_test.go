@@ -0,0 +1,86 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+)
+
+func TestUserAgent(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/refs/heads/master").
+		MatchHeader("User-Agent", `^my-tool/1\.2\.0 \(prod-1\)$`).
+		MatchHeader("X-Request-Id", "^12345$").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/single_ref.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := New(scmClient, UserAgent("my-tool", "1.2.0", "prod-1"), RequestHeader("X-Request-Id", "12345"))
+
+	if _, err := client.GetBranchHead(context.TODO(), "Codertocat/Hello-World", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("request was not annotated")
+	}
+}
+
+func TestDefaultUserAgent(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/git/refs/heads/master").
+		MatchHeader("User-Agent", "^agill17-pkg/").
+		Reply(http.StatusOK).
+		Type("application/json").
+		File("testdata/single_ref.json")
+	defer gock.Off()
+
+	scmClient, err := factory.NewClient("github", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(scmClient).GetBranchHead(context.TODO(), "Codertocat/Hello-World", "master"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("request did not have the default User-Agent")
+	}
+}
+
+func TestNewDoesNotModifySharedHTTPClient(t *testing.T) {
+	transport := http.DefaultClient.Transport
+
+	New(&scm.Client{Client: http.DefaultClient}, UserAgent("my-tool", "1.2.0", ""))
+
+	if http.DefaultClient.Transport != transport {
+		t.Fatal("the shared HTTP client was modified")
+	}
+}
+
+func TestClientCacheWithClientOptions(t *testing.T) {
+	cache := NewClientCache(func(ctx context.Context, key string) (*scm.Client, time.Time, error) {
+		return &scm.Client{}, time.Time{}, nil
+	}, CacheClientOptions(UserAgent("my-tool", "1.2.0", "")))
+
+	c, err := cache.Get(context.Background(), "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, ok := c.scmClient.Client.Transport.(*headerTransport)
+	if !ok {
+		t.Fatalf("client was not annotated, got transport %T", c.scmClient.Client.Transport)
+	}
+	if ua := transport.header.Get("User-Agent"); ua != "my-tool/1.2.0" {
+		t.Fatalf("got User-Agent %q, want %q", ua, "my-tool/1.2.0")
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// GetVariable returns the value of a CI variable in the repo, GitHub Actions
+// variables and GitLab CI variables are supported.
+//
+// If the variable doesn't exist, a not found error is returned.
+func (c *SCMClient) GetVariable(ctx context.Context, repo, name string) (string, error) {
+	path, err := c.variablePath(repo, name)
+	if err != nil {
+		return "", err
+	}
+	res, err := c.scmClient.Do(ctx, &scm.Request{Method: http.MethodGet, Path: path})
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	if isErrorStatus(res.Status) {
+		return "", scmError{msg: fmt.Sprintf("failed to get variable %s in repo %s", name, repo), Status: res.Status}
+	}
+	variable := struct {
+		Value string `json:"value"`
+	}{}
+	if err := json.NewDecoder(res.Body).Decode(&variable); err != nil {
+		return "", fmt.Errorf("failed to decode variable %s: %w", name, err)
+	}
+	return variable.Value, nil
+}
+
+// SetVariable updates a CI variable in the repo, creating it if it doesn't
+// exist.
+func (c *SCMClient) SetVariable(ctx context.Context, repo, name, value string) error {
+	path, err := c.variablePath(repo, name)
+	if err != nil {
+		return err
+	}
+	update := http.MethodPatch
+	if c.scmClient.Driver == scm.DriverGitlab {
+		update = http.MethodPut
+	}
+	body := map[string]string{"name": name, "value": value}
+	if c.scmClient.Driver == scm.DriverGitlab {
+		body = map[string]string{"key": name, "value": value}
+	}
+	status, err := c.sendJSON(ctx, update, path, body, nil)
+	if err != nil {
+		return err
+	}
+	if status == http.StatusNotFound {
+		status, err = c.sendJSON(ctx, http.MethodPost, path[:strings.LastIndex(path, "/")], body, nil)
+		if err != nil {
+			return err
+		}
+	}
+	if isErrorStatus(status) {
+		return scmError{msg: fmt.Sprintf("failed to set variable %s in repo %s", name, repo), Status: status}
+	}
+	return nil
+}
+
+func (c *SCMClient) variablePath(repo, name string) (string, error) {
+	switch c.scmClient.Driver {
+	case scm.DriverGithub:
+		return fmt.Sprintf("repos/%s/actions/variables/%s", repo, name), nil
+	case scm.DriverGitlab:
+		return fmt.Sprintf("api/v4/projects/%s/variables/%s", gitLabProject(repo), name), nil
+	}
+	return "", fmt.Errorf("CI variables are not supported by the %s driver", c.scmClient.Driver)
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm/factory"
+	"gopkg.in/h2non/gock.v1"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestGetVariable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/variables/IMAGE_TAG").
+		Reply(http.StatusOK).
+		Type("application/json").
+		JSON(map[string]string{"name": "IMAGE_TAG", "value": "v1"})
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	value, err := client.GetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "v1" {
+		t.Fatalf("got %#v, want %#v", value, "v1")
+	}
+}
+
+func TestGetVariableWithMissingVariable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Get("/repos/Codertocat/Hello-World/actions/variables/IMAGE_TAG").
+		Reply(http.StatusNotFound)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	_, err := client.GetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG")
+	if !IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+}
+
+func TestSetVariableInGitHub(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/actions/variables/IMAGE_TAG").
+		MatchType("json").
+		JSON(map[string]string{"name": "IMAGE_TAG", "value": "v2"}).
+		Reply(http.StatusNoContent)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.SetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("variable was not updated")
+	}
+}
+
+func TestSetVariableCreatesMissingVariable(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/actions/variables/IMAGE_TAG").
+		Reply(http.StatusNotFound)
+	gock.New("https://api.github.com").
+		Post("/repos/Codertocat/Hello-World/actions/variables").
+		MatchType("json").
+		JSON(map[string]string{"name": "IMAGE_TAG", "value": "v2"}).
+		Reply(http.StatusCreated)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	if err := client.SetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("variable was not created")
+	}
+}
+
+func TestSetVariableInGitLab(t *testing.T) {
+	gock.New("https://gitlab.com").
+		Put("/api/v4/projects/Codertocat/Hello-World/variables/IMAGE_TAG").
+		MatchType("json").
+		JSON(map[string]string{"key": "IMAGE_TAG", "value": "v2"}).
+		Reply(http.StatusOK)
+	defer gock.Off()
+	client := newTestClient(t, "gitlab", "")
+
+	if err := client.SetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG", "v2"); err != nil {
+		t.Fatal(err)
+	}
+	if !gock.IsDone() {
+		t.Fatal("variable was not updated")
+	}
+}
+
+func TestSetVariableWithErrorResponse(t *testing.T) {
+	gock.New("https://api.github.com").
+		Patch("/repos/Codertocat/Hello-World/actions/variables/IMAGE_TAG").
+		Reply(http.StatusForbidden)
+	defer gock.Off()
+	client := newTestClient(t, "github", "")
+
+	err := client.SetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG", "v2")
+	if !test.MatchError(t, `failed to set variable IMAGE_TAG in repo Codertocat/Hello-World: \(403\)`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestSetVariableWithUnsupportedDriver(t *testing.T) {
+	client := newTestClient(t, "bitbucketcloud", "")
+
+	err := client.SetVariable(context.TODO(), "Codertocat/Hello-World", "IMAGE_TAG", "v2")
+	if !test.MatchError(t, `CI variables are not supported by the bitbucket driver`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func newTestClient(t *testing.T, driver, serverURL string) *SCMClient {
+	t.Helper()
+	scmClient, err := factory.NewClient(driver, serverURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(scmClient)
+}
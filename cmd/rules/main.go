@@ -0,0 +1,126 @@
+// Command rules checks declarative rule files.
+//
+//	rules validate rules.yaml...
+//	rules lint -driver github rules.yaml...
+//	rules schema > rules.schema.json
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/rules"
+)
+
+const usage = `usage: rules <command> [arguments]
+
+commands:
+  validate FILE...                           validate the rule files
+  lint [-driver NAME] [-server URL] FILE...  check the rules against their repos
+  schema                                     print the JSON Schema for rule files
+`
+
+// newGitClient creates the client used to lint rules, it's replaced in tests.
+var newGitClient = func(driver, serverURL, token string) (client.GitClient, error) {
+	c, err := client.NewClient(driver, serverURL, token)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command, returning the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "validate":
+		return validate(args[1:], stdout, stderr)
+	case "lint":
+		return lint(args[1:], stdout, stderr)
+	case "schema":
+		fmt.Fprintln(stdout, rules.Schema)
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command %q\n%s", args[0], usage)
+	return 2
+}
+
+// validate validates each of the files, and prints the problems in the
+// file:line:column form understood by editors and CI annotations.
+func validate(filenames []string, stdout, stderr io.Writer) int {
+	if len(filenames) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	code := 0
+	for _, filename := range filenames {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read %s: %s\n", filename, err)
+			code = 1
+			continue
+		}
+		err = rules.Validate(b)
+		var verr rules.ValidationError
+		switch {
+		case errors.As(err, &verr):
+			for _, p := range verr.Problems {
+				fmt.Fprintf(stdout, "%s:%s\n", filename, p)
+			}
+			code = 1
+		case err != nil:
+			fmt.Fprintf(stdout, "%s: %s\n", filename, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// lint checks the rules in each of the files against their repos, the rule
+// files are loaded with variables from the environment, and the token from
+// each file is used to access the git provider.
+func lint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	driver := flags.String("driver", "github", "the git provider driver e.g. github or gitlab")
+	serverURL := flags.String("server", "", "the URL of the git provider, if not the public service")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	code := 0
+	for _, filename := range flags.Args() {
+		f, err := rules.LoadFile(filename)
+		if err != nil {
+			fmt.Fprintln(stdout, err)
+			code = 1
+			continue
+		}
+		c, err := newGitClient(*driver, *serverURL, f.Token)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to create the git client: %s\n", err)
+			return 1
+		}
+		for _, p := range rules.Lint(context.Background(), c, f) {
+			fmt.Fprintf(stdout, "%s: %s\n", filename, p)
+			code = 1
+		}
+	}
+	return code
+}
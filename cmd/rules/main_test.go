@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestValidate(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"valid.yaml":   "rules:\n  - {name: frontend, repo: my-org/a, branch: main, file: a.yaml, key: a}\n",
+		"invalid.yaml": "rules:\n  - name: frontend\n    repo: frontend\n",
+		"broken.yaml":  "rules: [\n",
+	})
+	validateTests := []struct {
+		name     string
+		files    []string
+		wantCode int
+		wantOut  string
+	}{
+		{"valid", []string{"valid.yaml"}, 0, ""},
+		{
+			"invalid", []string{"valid.yaml", "invalid.yaml"}, 1,
+			"invalid.yaml:2:5: rules.0: branch is required\n" +
+				"invalid.yaml:2:5: rules.0: file is required\n" +
+				"invalid.yaml:2:5: rules.0: key is required\n" +
+				"invalid.yaml:3:11: rules.0.repo: Does not match pattern '^[^/]+/.+$'\n",
+		},
+		{"broken", []string{"broken.yaml"}, 1, "broken.yaml: failed to parse the rules: yaml: line 1: did not find expected node content\n"},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			args := []string{"validate"}
+			for _, f := range tt.files {
+				args = append(args, filepath.Join(dir, f))
+			}
+			var stdout, stderr bytes.Buffer
+
+			code := run(args, &stdout, &stderr)
+
+			if code != tt.wantCode {
+				rt.Errorf("got exit code %d, want %d, stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			got := string(bytes.ReplaceAll(stdout.Bytes(), []byte(dir+string(filepath.Separator)), nil))
+			if diff := cmp.Diff(tt.wantOut, got); diff != "" {
+				rt.Errorf("incorrect output:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	m := mock.New(t)
+	m.AddRepoPermissions("my-org/frontend", &scm.Perm{Pull: true, Push: true})
+	m.AddFileContents("my-org/frontend", "deployment.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	var gotDriver, gotToken string
+	stubGitClient(t, func(driver, serverURL, token string) (client.GitClient, error) {
+		gotDriver, gotToken = driver, token
+		return m, nil
+	})
+	t.Cleanup(func() {
+		os.Unsetenv("TEST_LINT_TOKEN")
+	})
+	os.Setenv("TEST_LINT_TOKEN", "abc123")
+	dir := writeFiles(t, map[string]string{
+		"rules.yaml": "token: ${TEST_LINT_TOKEN}\nrules:\n" +
+			"  - {name: image, repo: my-org/frontend, branch: main, file: deployment.yaml, key: spec.image}\n" +
+			"  - {name: replicas, repo: my-org/frontend, branch: main, file: deployment.yaml, key: spec.replicas}\n",
+	})
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"lint", "-driver", "gitlab", filepath.Join(dir, "rules.yaml")}, &stdout, &stderr)
+
+	if code != 1 {
+		t.Errorf("got exit code %d, want 1, stderr: %s", code, stderr.String())
+	}
+	want := filepath.Join(dir, "rules.yaml") + ": replicas: key spec.replicas has no value in file deployment.yaml\n"
+	if diff := cmp.Diff(want, stdout.String()); diff != "" {
+		t.Errorf("incorrect output:\n%s", diff)
+	}
+	if gotDriver != "gitlab" || gotToken != "abc123" {
+		t.Errorf("got driver %q and token %q", gotDriver, gotToken)
+	}
+}
+
+func TestLintInvalidRules(t *testing.T) {
+	stubGitClient(t, func(driver, serverURL, token string) (client.GitClient, error) {
+		t.Fatal("the git client should not be created")
+		return nil, nil
+	})
+	dir := writeFiles(t, map[string]string{"rules.yaml": "rules:\n  - name: frontend\n"})
+	var stdout, stderr bytes.Buffer
+
+	if code := run([]string{"lint", filepath.Join(dir, "rules.yaml")}, &stdout, &stderr); code != 1 {
+		t.Fatalf("got exit code %d, want 1", code)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte("invalid rules: 2:5: rules.0: branch is required; 2:5: rules.0: file is required; 2:5: rules.0: key is required; 2:5: rules.0: repo is required")) {
+		t.Fatalf("problems not printed: %s", stdout.String())
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	usageTests := []struct {
+		name string
+		args []string
+	}{
+		{"no command", nil},
+		{"unknown command", []string{"check"}},
+		{"no files", []string{"validate"}},
+		{"no lint files", []string{"lint"}},
+	}
+
+	for _, tt := range usageTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, &stdout, &stderr); code != 2 {
+				rt.Errorf("got exit code %d, want 2", code)
+			}
+			if !bytes.Contains(stderr.Bytes(), []byte("usage: rules")) {
+				rt.Errorf("usage not printed: %s", stderr.String())
+			}
+		})
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var stdout bytes.Buffer
+	if code := run([]string{"schema"}, &stdout, &stdout); code != 0 {
+		t.Fatalf("got exit code %d", code)
+	}
+	if !bytes.Contains(stdout.Bytes(), []byte(`"title": "Update rules"`)) {
+		t.Fatalf("schema not printed: %s", stdout.String())
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	for name, body := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func stubGitClient(t *testing.T, f func(driver, serverURL, token string) (client.GitClient, error)) {
+	old := newGitClient
+	newGitClient = f
+	t.Cleanup(func() {
+		newGitClient = old
+	})
+}
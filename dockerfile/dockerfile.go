@@ -0,0 +1,139 @@
+package dockerfile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	fromRE = regexp.MustCompile(`(?i)^(\s*FROM\s+(?:--\S+\s+)*)(\S+)(.*)$`)
+	argRE  = regexp.MustCompile(`(?i)^(\s*ARG\s+)(.*)$`)
+)
+
+// SetFrom updates the FROM instructions for the image in a Dockerfile to the
+// new image reference, e.g.
+//
+//	SetFrom(b, "golang:1.15@sha256:4d4d...")
+//
+// would update "FROM golang:1.14 AS builder" to
+// "FROM golang:1.15@sha256:4d4d... AS builder", all stages using the image are
+// updated, and flags and stage names are preserved.
+//
+// Images are matched by repository, ignoring the tag and digest, images that
+// use build arguments, or refer to earlier stages are not updated.
+func SetFrom(b []byte, ref string) ([]byte, error) {
+	repo := Repository(ref)
+	lines := strings.Split(string(b), "\n")
+	found := false
+	for i, l := range lines {
+		cr := strings.HasSuffix(l, "\r")
+		m := fromRE.FindStringSubmatch(strings.TrimSuffix(l, "\r"))
+		if m == nil || strings.Contains(m[2], "$") || normalize(Repository(m[2])) != normalize(repo) {
+			continue
+		}
+		found = true
+		lines[i] = m[1] + ref + m[3]
+		if cr {
+			lines[i] += "\r"
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no FROM instructions found for image %s", repo)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// SetArg updates the default value of the build argument in a Dockerfile,
+// e.g. SetArg(b, "GO_VERSION", "1.15") would update "ARG GO_VERSION=1.14" to
+// "ARG GO_VERSION=1.15".
+//
+// Arguments without a default have one added, and quoting is preserved.
+func SetArg(b []byte, name, value string) ([]byte, error) {
+	lines := strings.Split(string(b), "\n")
+	found := false
+	for i, l := range lines {
+		cr := strings.HasSuffix(l, "\r")
+		m := argRE.FindStringSubmatch(strings.TrimSuffix(l, "\r"))
+		if m == nil {
+			continue
+		}
+		args := splitArgs(m[2])
+		changed := false
+		for j, arg := range args {
+			argName := strings.SplitN(arg, "=", 2)[0]
+			if argName != name {
+				continue
+			}
+			args[j] = name + "=" + quoteLike(arg[len(argName):], value)
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		found = true
+		lines[i] = m[1] + strings.Join(args, " ")
+		if cr {
+			lines[i] += "\r"
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no ARG instructions found for %s", name)
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// Repository returns the image reference without the tag or digest.
+func Repository(ref string) string {
+	if i := strings.Index(ref, "@"); i != -1 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// normalize removes the implied Docker Hub registry and library namespace.
+func normalize(repo string) string {
+	repo = strings.TrimPrefix(repo, "docker.io/")
+	return strings.TrimPrefix(repo, "library/")
+}
+
+// splitArgs splits the arguments of an ARG instruction on whitespace outside
+// of quotes.
+func splitArgs(s string) []string {
+	args := []string{}
+	var current strings.Builder
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ' ' || r == '\t':
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
+
+// quoteLike quotes the value in the same way as the existing "=value".
+func quoteLike(existing, value string) string {
+	existing = strings.TrimPrefix(existing, "=")
+	if len(existing) > 1 && (existing[0] == '"' || existing[0] == '\'') {
+		return existing[:1] + value + existing[:1]
+	}
+	return value
+}
@@ -0,0 +1,130 @@
+package dockerfile
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testDockerfile = `ARG GO_VERSION=1.14
+# Build the binary
+FROM --platform=$BUILDPLATFORM golang:1.14 AS builder
+RUN go build ./...
+
+FROM builder AS test
+RUN go test ./...
+
+FROM gcr.io/distroless/static:nonroot
+COPY --from=builder /app /app
+`
+
+func TestSetFrom(t *testing.T) {
+	fromTests := []struct {
+		name   string
+		source string
+		ref    string
+		want   string
+	}{
+		{
+			name:   "multi-stage build",
+			source: testDockerfile,
+			ref:    "golang:1.15",
+			want:   "ARG GO_VERSION=1.14\n# Build the binary\nFROM --platform=$BUILDPLATFORM golang:1.15 AS builder\nRUN go build ./...\n\nFROM builder AS test\nRUN go test ./...\n\nFROM gcr.io/distroless/static:nonroot\nCOPY --from=builder /app /app\n",
+		},
+		{
+			name:   "digest pinning",
+			source: testDockerfile,
+			ref:    "gcr.io/distroless/static:nonroot@sha256:4d4d6a3b0c7e5f0c2f7e3b4ab3f5d0b5b8ae6af3c4a4d2f4ab5c1f3d14b6e5f1",
+			want:   "ARG GO_VERSION=1.14\n# Build the binary\nFROM --platform=$BUILDPLATFORM golang:1.14 AS builder\nRUN go build ./...\n\nFROM builder AS test\nRUN go test ./...\n\nFROM gcr.io/distroless/static:nonroot@sha256:4d4d6a3b0c7e5f0c2f7e3b4ab3f5d0b5b8ae6af3c4a4d2f4ab5c1f3d14b6e5f1\nCOPY --from=builder /app /app\n",
+		},
+		{
+			name:   "docker hub library images",
+			source: "from docker.io/library/alpine:3.11@sha256:abcd\r\nRUN apk add git\r\n",
+			ref:    "alpine:3.12",
+			want:   "from alpine:3.12\r\nRUN apk add git\r\n",
+		},
+		{
+			name:   "registry with a port",
+			source: "FROM localhost:5000/app:v1\n",
+			ref:    "localhost:5000/app:v2",
+			want:   "FROM localhost:5000/app:v2\n",
+		},
+	}
+
+	for _, tt := range fromTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetFrom([]byte(tt.source), tt.ref)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFromWithMissingImage(t *testing.T) {
+	_, err := SetFrom([]byte(testDockerfile), "node:14")
+
+	if !test.MatchError(t, "no FROM instructions found for image node", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestSetArg(t *testing.T) {
+	argTests := []struct {
+		name   string
+		source string
+		arg    string
+		value  string
+		want   string
+	}{
+		{"default", "ARG GO_VERSION=1.14\nFROM golang:${GO_VERSION}\n", "GO_VERSION", "1.15", "ARG GO_VERSION=1.15\nFROM golang:${GO_VERSION}\n"},
+		{"no default", "ARG GO_VERSION\n", "GO_VERSION", "1.15", "ARG GO_VERSION=1.15\n"},
+		{"quoted", "ARG BASE=\"alpine:3.11\"\n", "BASE", "alpine:3.12", "ARG BASE=\"alpine:3.12\"\n"},
+		{"several arguments", "ARG A=1 B='2'\n", "B", "3", "ARG A=1 B='3'\n"},
+		{"prefix of another argument", "ARG VERSION_MAJOR=1\nARG VERSION=1.0\n", "VERSION", "2.0", "ARG VERSION_MAJOR=1\nARG VERSION=2.0\n"},
+	}
+
+	for _, tt := range argTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetArg([]byte(tt.source), tt.arg, tt.value)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetArgWithMissingArg(t *testing.T) {
+	_, err := SetArg([]byte(testDockerfile), "NODE_VERSION", "14")
+
+	if !test.MatchError(t, "no ARG instructions found for NODE_VERSION", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	repoTests := []struct {
+		ref  string
+		want string
+	}{
+		{"golang", "golang"},
+		{"golang:1.14", "golang"},
+		{"golang@sha256:abcd", "golang"},
+		{"gcr.io/distroless/static:nonroot@sha256:abcd", "gcr.io/distroless/static"},
+		{"localhost:5000/app", "localhost:5000/app"},
+	}
+
+	for _, tt := range repoTests {
+		if got := Repository(tt.ref); got != tt.want {
+			t.Errorf("Repository(%s) got %s, want %s", tt.ref, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package features provides feature gates, so that experimental behaviour can
+// be enabled per deployment, explicitly, or from the environment.
+//
+// A Gates is created with the known features and their defaults, setting an
+// unknown feature is an error, so misspelled gates are reported.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EnvVar is the default environment variable that gates are read from, e.g.
+// AGILL17_FEATURE_GATES=HashedBranchNames=true,OtherFeature=false.
+const EnvVar = "AGILL17_FEATURE_GATES"
+
+// Feature is the name of a gated feature.
+type Feature string
+
+// LookupFunc looks up the value of an environment variable, os.LookupEnv is a
+// LookupFunc.
+type LookupFunc func(string) (string, bool)
+
+// New creates and returns a new Gates, with the known features and their
+// defaults.
+func New(known map[Feature]bool) *Gates {
+	g := &Gates{defaults: map[Feature]bool{}, enabled: map[Feature]bool{}}
+	g.Add(known)
+	return g
+}
+
+// Gates records which features are enabled, it's safe for concurrent use.
+//
+// A nil Gates has all features disabled.
+type Gates struct {
+	mu       sync.RWMutex
+	defaults map[Feature]bool
+	enabled  map[Feature]bool
+}
+
+// Add adds known features and their defaults, features that are already known
+// keep their current value.
+func (g *Gates) Add(known map[Feature]bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for f, v := range known {
+		if _, ok := g.defaults[f]; ok {
+			continue
+		}
+		g.defaults[f] = v
+		g.enabled[f] = v
+	}
+}
+
+// Enabled returns true if the feature is enabled.
+func (g *Gates) Enabled(f Feature) bool {
+	if g == nil {
+		return false
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.enabled[f]
+}
+
+// Set enables or disables the feature.
+func (g *Gates) Set(f Feature, enabled bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.defaults[f]; !ok {
+		return fmt.Errorf("unknown feature %q", f)
+	}
+	g.enabled[f] = enabled
+	return nil
+}
+
+// Reset returns the feature to its default.
+func (g *Gates) Reset(f Feature) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if v, ok := g.defaults[f]; ok {
+		g.enabled[f] = v
+	}
+}
+
+// Parse sets the features in a comma separated list, e.g.
+// "HashedBranchNames=true,OtherFeature=false", a feature without a value is
+// enabled.
+//
+// No features are set if any of the list is invalid.
+func (g *Gates) Parse(s string) error {
+	values := map[Feature]bool{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value := pair, "true"
+		if n := strings.Index(pair, "="); n >= 0 {
+			name, value = strings.TrimSpace(pair[:n]), strings.TrimSpace(pair[n+1:])
+		}
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse feature gate %q: %w", pair, err)
+		}
+		values[Feature(name)] = enabled
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for f := range values {
+		if _, ok := g.defaults[f]; !ok {
+			return fmt.Errorf("unknown feature %q", f)
+		}
+	}
+	for f, v := range values {
+		g.enabled[f] = v
+	}
+	return nil
+}
+
+// ParseEnv sets the features from the environment variable, e.g. EnvVar, if
+// it's set.
+func (g *Gates) ParseEnv(lookup LookupFunc, name string) error {
+	s, ok := lookup(name)
+	if !ok {
+		return nil
+	}
+	if err := g.Parse(s); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+// String returns the features and whether they're enabled, in the format
+// accepted by Parse.
+func (g *Gates) String() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pairs := []string{}
+	for f, v := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", f, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
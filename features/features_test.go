@@ -0,0 +1,131 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/agill17/pkg/test"
+)
+
+const (
+	testAlpha Feature = "Alpha"
+	testBeta  Feature = "Beta"
+)
+
+func newTestGates() *Gates {
+	return New(map[Feature]bool{testAlpha: false, testBeta: true})
+}
+
+func TestEnabled(t *testing.T) {
+	g := newTestGates()
+
+	if g.Enabled(testAlpha) {
+		t.Errorf("got %s enabled, want disabled", testAlpha)
+	}
+	if !g.Enabled(testBeta) {
+		t.Errorf("got %s disabled, want enabled", testBeta)
+	}
+	if g.Enabled("Unknown") {
+		t.Error("got an unknown feature enabled")
+	}
+}
+
+func TestEnabledWithNilGates(t *testing.T) {
+	var g *Gates
+
+	if g.Enabled(testBeta) {
+		t.Errorf("got %s enabled, want disabled", testBeta)
+	}
+}
+
+func TestSet(t *testing.T) {
+	g := newTestGates()
+
+	if err := g.Set(testAlpha, true); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Enabled(testAlpha) {
+		t.Errorf("got %s disabled, want enabled", testAlpha)
+	}
+	g.Reset(testAlpha)
+	if g.Enabled(testAlpha) {
+		t.Errorf("got %s enabled after reset, want disabled", testAlpha)
+	}
+}
+
+func TestSetWithUnknownFeature(t *testing.T) {
+	err := newTestGates().Set("Unknown", true)
+
+	if !test.MatchError(t, `unknown feature "Unknown"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	g := newTestGates()
+	if err := g.Set(testAlpha, true); err != nil {
+		t.Fatal(err)
+	}
+
+	g.Add(map[Feature]bool{testAlpha: false, "Gamma": true})
+
+	if !g.Enabled(testAlpha) {
+		t.Errorf("got %s disabled, want the value kept", testAlpha)
+	}
+	if !g.Enabled("Gamma") {
+		t.Error("got Gamma disabled, want enabled")
+	}
+}
+
+func TestParse(t *testing.T) {
+	parseTests := []struct {
+		name    string
+		s       string
+		want    string
+		wantErr string
+	}{
+		{"empty", "", "Alpha=false,Beta=true", ""},
+		{"values", "Alpha=true,Beta=false", "Alpha=true,Beta=false", ""},
+		{"no value", "Alpha", "Alpha=true,Beta=true", ""},
+		{"spaces", " Alpha = 1 , ", "Alpha=true,Beta=true", ""},
+		{"invalid value", "Alpha=true,Beta=maybe", "Alpha=false,Beta=true", `failed to parse feature gate "Beta=maybe"`},
+		{"unknown feature", "Alpha=true,Unknown=true", "Alpha=false,Beta=true", `unknown feature "Unknown"`},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			g := newTestGates()
+
+			err := g.Parse(tt.s)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			if s := g.String(); s != tt.want {
+				rt.Errorf("got %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	env := map[string]string{EnvVar: "Alpha=true", "INVALID": "Alpha=maybe"}
+	lookup := func(s string) (string, bool) {
+		v, ok := env[s]
+		return v, ok
+	}
+	g := newTestGates()
+
+	if err := g.ParseEnv(lookup, "UNSET"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.ParseEnv(lookup, EnvVar); err != nil {
+		t.Fatal(err)
+	}
+	if !g.Enabled(testAlpha) {
+		t.Errorf("got %s disabled, want enabled", testAlpha)
+	}
+	err := g.ParseEnv(lookup, "INVALID")
+	if !test.MatchError(t, `failed to parse INVALID: failed to parse feature gate "Alpha=maybe"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,97 @@
+package formats
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/agill17/pkg/shcl"
+	"github.com/agill17/pkg/sini"
+	"github.com/agill17/pkg/sjson"
+	"github.com/agill17/pkg/skv"
+	"github.com/agill17/pkg/stoml"
+	"github.com/agill17/pkg/sxml"
+	"github.com/agill17/pkg/syaml"
+)
+
+func registerBuiltins(r *Registry) {
+	r.Register("yaml", yamlFormat{}, ".yaml", ".yml")
+	r.Register("json", funcs{sjson.GetBytes, sjson.SetBytes, sjson.DeleteBytes, sjson.ErrKeyNotFound}, ".json")
+	r.Register("toml", tomlFormat{}, ".toml")
+	r.Register("hcl", funcs{shcl.GetBytes, shcl.SetBytes, shcl.DeleteBytes, shcl.ErrKeyNotFound}, ".tf", ".tfvars", ".hcl")
+	r.Register("ini", funcs{sini.GetBytes, sini.SetBytes, sini.DeleteBytes, sini.ErrKeyNotFound}, ".ini", ".cfg")
+	r.Register("xml", funcs{sxml.GetBytes, sxml.SetBytes, sxml.DeleteBytes, sxml.ErrKeyNotFound}, ".xml")
+	r.Register("kv", funcs{skv.GetBytes, skv.SetBytes, skv.DeleteBytes, skv.ErrKeyNotFound}, ".env", ".properties")
+}
+
+// funcs is a Format made from the functions of a package, its notFound error
+// is translated to ErrKeyNotFound.
+type funcs struct {
+	get      func([]byte, string) (string, error)
+	set      func([]byte, string, interface{}) ([]byte, error)
+	delete   func([]byte, string) ([]byte, error)
+	notFound error
+}
+
+func (f funcs) Get(b []byte, path string) (string, error) {
+	v, err := f.get(b, path)
+	if errors.Is(err, f.notFound) {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	return v, err
+}
+
+func (f funcs) Set(b []byte, path string, value interface{}) ([]byte, error) {
+	return f.set(b, path, value)
+}
+
+func (f funcs) Delete(b []byte, path string) ([]byte, error) {
+	return f.delete(b, path)
+}
+
+type yamlFormat struct{}
+
+func (yamlFormat) Get(b []byte, path string) (string, error) {
+	v, err := syaml.GetBytes(b, path)
+	if err != nil {
+		return "", err
+	}
+	if v == nil {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	return *v, nil
+}
+
+func (yamlFormat) Set(b []byte, path string, value interface{}) ([]byte, error) {
+	return syaml.SetBytes(b, path, value)
+}
+
+func (yamlFormat) Delete(b []byte, path string) ([]byte, error) {
+	return syaml.DeleteBytes(b, path)
+}
+
+// tomlFormat adapts stoml, which returns the decoded values, and fails to
+// delete missing keys.
+type tomlFormat struct{}
+
+func (tomlFormat) Get(b []byte, path string) (string, error) {
+	v, err := stoml.GetBytes(b, path)
+	if errors.Is(err, stoml.ErrKeyNotFound) {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(v), nil
+}
+
+func (tomlFormat) Set(b []byte, path string, value interface{}) ([]byte, error) {
+	return stoml.SetBytes(b, path, value)
+}
+
+func (tomlFormat) Delete(b []byte, path string) ([]byte, error) {
+	updated, err := stoml.DeleteBytes(b, path)
+	if errors.Is(err, stoml.ErrKeyNotFound) {
+		return b, nil
+	}
+	return updated, err
+}
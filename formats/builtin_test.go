@@ -0,0 +1,96 @@
+package formats
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestBuiltins(t *testing.T) {
+	builtinTests := []struct {
+		format  string
+		source  string
+		path    string
+		current string
+		updated string
+		deleted string
+	}{
+		{"yaml", "test:\n  image: old\n  tag: v1\n", "test.tag", "v1", "test:\n  image: old\n  tag: v2\n", "test:\n  image: old\n"},
+		{"json", "{\n  \"image\": \"old\",\n  \"tag\": \"v1\"\n}\n", "tag", "v1", "{\n  \"image\": \"old\",\n  \"tag\": \"v2\"\n}\n", "{\n  \"image\": \"old\"\n}\n"},
+		{"toml", "[test]\nimage = \"old\"\ntag = \"v1\"\n", "test.tag", "v1", "[test]\nimage = \"old\"\ntag = \"v2\"\n", "[test]\nimage = \"old\"\n"},
+		{"hcl", "image = \"old\"\ntag   = \"v1\"\n", "tag", "v1", "image = \"old\"\ntag   = \"v2\"\n", "image = \"old\"\n"},
+		{"ini", "[test]\nimage = old\ntag = v1\n", "test.tag", "v1", "[test]\nimage = old\ntag = v2\n", "[test]\nimage = old\n"},
+		{"xml", "<test>\n  <image>old</image>\n  <tag>v1</tag>\n</test>\n", "test.tag", "v1", "<test>\n  <image>old</image>\n  <tag>v2</tag>\n</test>\n", "<test>\n  <image>old</image>\n</test>\n"},
+		{"kv", "IMAGE=old\nTAG=v1\n", "TAG", "v1", "IMAGE=old\nTAG=v2\n", "IMAGE=old\n"},
+	}
+
+	for _, tt := range builtinTests {
+		t.Run(tt.format, func(rt *testing.T) {
+			f, err := Lookup(tt.format)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			current, err := f.Get([]byte(tt.source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if current != tt.current {
+				rt.Errorf("Get got %#v, want %#v", current, tt.current)
+			}
+			updated, err := f.Set([]byte(tt.source), tt.path, "v2")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.updated, string(updated)); diff != "" {
+				rt.Errorf("failed to set:\n%s", diff)
+			}
+			deleted, err := f.Delete([]byte(tt.source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.deleted, string(deleted)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuiltinsMissingKey(t *testing.T) {
+	sources := map[string]string{
+		"yaml": "test:\n  image: old\n",
+		"json": `{"test": {"image": "old"}}`,
+		"toml": "[test]\nimage = \"old\"\n",
+		"hcl":  "test {\n  image = \"old\"\n}\n",
+		"ini":  "[test]\nimage = old\n",
+		"xml":  "<root><test><image>old</image></test></root>",
+		"kv":   "test.image=old\n",
+	}
+	paths := map[string]string{"xml": "root.test.tag"}
+
+	for name, source := range sources {
+		t.Run(name, func(rt *testing.T) {
+			f, err := Lookup(name)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			path := "test.tag"
+			if p, ok := paths[name]; ok {
+				path = p
+			}
+
+			_, err = f.Get([]byte(source), path)
+			if !errors.Is(err, ErrKeyNotFound) {
+				rt.Fatalf("Get got %v, want ErrKeyNotFound", err)
+			}
+			deleted, err := f.Delete([]byte(source), path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if string(deleted) != source {
+				rt.Errorf("Delete got %#v, want it unchanged", string(deleted))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+// Package formats provides a registry of the file formats that values can be
+// read and updated in, by a dotted path.
+//
+// Each Format preserves the formatting and comments of the documents it
+// updates, adding a file format is a matter of implementing Format and
+// registering it.
+package formats
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrKeyNotFound is returned by Get when there is no value at the path.
+var ErrKeyNotFound = errors.New("key not found")
+
+// Format reads and updates values at a dotted path in a document, the syntax
+// of the path is specific to the format.
+type Format interface {
+	// Get returns the value at the path, or an error wrapping ErrKeyNotFound.
+	Get(b []byte, path string) (string, error)
+	// Set updates the value at the path, adding it if it doesn't exist.
+	Set(b []byte, path string, value interface{}) ([]byte, error)
+	// Delete removes the value at the path, the document is returned
+	// unchanged if it doesn't exist.
+	Delete(b []byte, path string) ([]byte, error)
+}
+
+// Registry maps format names, and file extensions, to Formats.
+type Registry struct {
+	mu         sync.RWMutex
+	formats    map[string]Format
+	extensions map[string]string
+}
+
+// NewRegistry creates and returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{formats: map[string]Format{}, extensions: map[string]string{}}
+}
+
+// Default is the Registry of the built-in formats, yaml, json, toml, hcl,
+// ini, xml and kv.
+var Default = NewRegistry()
+
+func init() {
+	registerBuiltins(Default)
+}
+
+// Register adds the Format to the Registry with the name, replacing any
+// existing Format with the name, files with the extensions e.g. ".yaml" are
+// matched by ForFile.
+func (r *Registry) Register(name string, f Format, extensions ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name = strings.ToLower(name)
+	r.formats[name] = f
+	for _, ext := range extensions {
+		r.extensions[strings.ToLower(ext)] = name
+	}
+}
+
+// Lookup returns the Format registered with the name.
+func (r *Registry) Lookup(name string) (Format, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.formats[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q", name)
+	}
+	return f, nil
+}
+
+// ForFile returns the Format registered for the extension of the filename.
+func (r *Registry) ForFile(filename string) (Format, error) {
+	r.mu.RLock()
+	name, ok := r.extensions[strings.ToLower(path.Ext(filename))]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no format is registered for %s", filename)
+	}
+	return r.Lookup(name)
+}
+
+// Names returns the sorted names of the registered Formats.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := []string{}
+	for name := range r.formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Register adds the Format to the Default Registry.
+func Register(name string, f Format, extensions ...string) {
+	Default.Register(name, f, extensions...)
+}
+
+// Lookup returns the Format registered with the name in the Default Registry.
+func Lookup(name string) (Format, error) {
+	return Default.Lookup(name)
+}
+
+// ForFile returns the Format registered for the extension of the filename in
+// the Default Registry.
+func ForFile(filename string) (Format, error) {
+	return Default.ForFile(filename)
+}
@@ -0,0 +1,66 @@
+package formats
+
+import (
+	"path"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+type stubFormat struct {
+	funcs
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	f := &stubFormat{}
+	r.Register("Custom", f, ".CST")
+
+	got, err := r.Lookup("custom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != f {
+		t.Fatalf("Lookup got %#v, want %#v", got, f)
+	}
+	got, err = r.ForFile("config/app.cst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != f {
+		t.Fatalf("ForFile got %#v, want %#v", got, f)
+	}
+	if diff := cmp.Diff([]string{"custom"}, r.Names()); diff != "" {
+		t.Fatalf("incorrect names:\n%s", diff)
+	}
+}
+
+func TestRegistryFailures(t *testing.T) {
+	r := NewRegistry()
+
+	_, err := r.Lookup("custom")
+	if !test.MatchError(t, `unknown format "custom"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	_, err = r.ForFile("app.cst")
+	if !test.MatchError(t, "no format is registered for app.cst", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	want := []string{"hcl", "ini", "json", "kv", "toml", "xml", "yaml"}
+	if diff := cmp.Diff(want, Default.Names()); diff != "" {
+		t.Fatalf("incorrect names:\n%s", diff)
+	}
+	for filename, name := range map[string]string{"values.yml": "yaml", "main.tf": "hcl", ".env": "kv", "pom.xml": "xml"} {
+		if _, err := ForFile(filename); err != nil {
+			t.Fatal(err)
+		}
+		if got := Default.extensions[path.Ext(filename)]; got != name {
+			t.Errorf("ForFile(%s) got %s, want %s", filename, got, name)
+		}
+	}
+}
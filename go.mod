@@ -3,12 +3,19 @@ module github.com/agill17/pkg
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.3.1
 	github.com/go-logr/logr v0.1.0
 	github.com/google/go-cmp v0.4.0
+	github.com/hashicorp/hcl/v2 v2.12.0
 	github.com/jenkins-x/go-scm v1.5.157
+	github.com/tidwall/gjson v1.6.0
 	github.com/tidwall/sjson v1.1.1
+	github.com/xeipuuv/gojsonschema v1.2.0
+	github.com/zclconf/go-cty v1.8.0
 	go.uber.org/zap v1.15.0 // indirect
+	golang.org/x/crypto v0.0.0-20191011191535-87dc89f01550
 	gopkg.in/h2non/gock.v1 v1.0.15
+	gopkg.in/yaml.v3 v3.0.1
 	k8s.io/api v0.17.2
 	k8s.io/apimachinery v0.17.2
 	sigs.k8s.io/controller-runtime v0.5.2
@@ -0,0 +1,71 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/agill17/pkg/syaml"
+)
+
+// BumpChart increments the version in a Chart.yaml, and if the appVersion is
+// not empty, sets the appVersion.
+//
+// BumpChart(b, helm.Minor, "1.16.0")
+func BumpChart(b []byte, bump Bump, appVersion string) ([]byte, error) {
+	chart := struct {
+		Version string `json:"version"`
+	}{}
+	if err := yaml.Unmarshal(b, &chart); err != nil {
+		return nil, fmt.Errorf("failed to parse chart: %w", err)
+	}
+	version, err := BumpVersion(chart.Version, bump)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bump the chart version: %w", err)
+	}
+	b, err = syaml.SetBytes(b, "version", version)
+	if err != nil {
+		return nil, err
+	}
+	if appVersion == "" {
+		return b, nil
+	}
+	return syaml.SetBytes(b, "appVersion", appVersion)
+}
+
+// SetImageTag sets the tag of the image at the path in a values.yaml, e.g.
+// SetImageTag(b, "image", "v2") updates "image.tag".
+//
+// Where the image is a single "repository:tag" string, the tag in the string
+// is replaced.
+func SetImageTag(b []byte, path, tag string) ([]byte, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(b, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values: %w", err)
+	}
+	var current interface{} = values
+	for _, s := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			current = nil
+			break
+		}
+		current = m[s]
+	}
+	if image, ok := current.(string); ok {
+		return syaml.SetBytes(b, path, replaceTag(image, tag))
+	}
+	return syaml.SetBytes(b, path+".tag", tag)
+}
+
+// replaceTag replaces the tag in an image reference, dropping any digest.
+func replaceTag(image, tag string) string {
+	if i := strings.Index(image, "@"); i != -1 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
@@ -0,0 +1,73 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testChart = `apiVersion: v2
+name: example
+# The chart version
+version: 0.1.0
+appVersion: "1.16.0"
+`
+
+func TestBumpChart(t *testing.T) {
+	chartTests := []struct {
+		name       string
+		bump       Bump
+		appVersion string
+		want       string
+	}{
+		{"patch", Patch, "", "apiVersion: v2\nname: example\n# The chart version\nversion: 0.1.1\nappVersion: \"1.16.0\"\n"},
+		{"minor with appVersion", Minor, "1.17.0", "apiVersion: v2\nname: example\n# The chart version\nversion: 0.2.0\nappVersion: \"1.17.0\"\n"},
+	}
+
+	for _, tt := range chartTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := BumpChart([]byte(testChart), tt.bump, tt.appVersion)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBumpChartWithInvalidVersion(t *testing.T) {
+	_, err := BumpChart([]byte("name: example\n"), Patch, "")
+
+	if !test.MatchError(t, `failed to bump the chart version: invalid semantic version ""`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestSetImageTag(t *testing.T) {
+	tagTests := []struct {
+		name   string
+		source string
+		path   string
+		want   string
+	}{
+		{"image map", "image:\n  repository: nginx\n  tag: \"1.18\" # pinned\n", "image", "image:\n  repository: nginx\n  tag: \"1.19\" # pinned\n"},
+		{"nested image map", "app:\n  image:\n    repository: nginx\n    tag: 1.18.0\n", "app.image", "app:\n  image:\n    repository: nginx\n    tag: \"1.19\"\n"},
+		{"image string", "image: registry.example.com:5000/nginx:1.18@sha256:abcd\n", "image", "image: registry.example.com:5000/nginx:1.19\n"},
+	}
+
+	for _, tt := range tagTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetImageTag([]byte(tt.source), tt.path, "1.19")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package helm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Bump is the semantic version component to increment.
+type Bump int
+
+const (
+	Patch Bump = iota
+	Minor
+	Major
+)
+
+var semverRE = regexp.MustCompile(`^(v?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// BumpVersion increments the version following semantic versioning, the
+// pre-release and build metadata are dropped, and a leading "v" is retained.
+//
+// Bumping a pre-release to the version it's a pre-release of releases it, e.g.
+// a Patch bump of 1.2.3-rc.1 is 1.2.3.
+func BumpVersion(version string, bump Bump) (string, error) {
+	m := semverRE.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("invalid semantic version %q", version)
+	}
+	major, minor, patch := atoi(m[2]), atoi(m[3]), atoi(m[4])
+	pre := m[5] != ""
+	switch bump {
+	case Patch:
+		if !pre {
+			patch++
+		}
+	case Minor:
+		if !pre || patch != 0 {
+			minor++
+		}
+		patch = 0
+	case Major:
+		if !pre || minor != 0 || patch != 0 {
+			major++
+		}
+		minor, patch = 0, 0
+	default:
+		return "", fmt.Errorf("unknown bump %d", bump)
+	}
+	return fmt.Sprintf("%s%d.%d.%d", m[1], major, minor, patch), nil
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,46 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestBumpVersion(t *testing.T) {
+	bumpTests := []struct {
+		version string
+		bump    Bump
+		want    string
+	}{
+		{"1.2.3", Patch, "1.2.4"},
+		{"1.2.3", Minor, "1.3.0"},
+		{"1.2.3", Major, "2.0.0"},
+		{"v0.1.9", Patch, "v0.1.10"},
+		{"1.2.3+build.5", Patch, "1.2.4"},
+		{"1.2.3-rc.1", Patch, "1.2.3"},
+		{"1.3.0-rc.1", Minor, "1.3.0"},
+		{"1.2.3-rc.1", Minor, "1.3.0"},
+		{"2.0.0-beta", Major, "2.0.0"},
+		{"2.1.0-beta", Major, "3.0.0"},
+	}
+
+	for _, tt := range bumpTests {
+		got, err := BumpVersion(tt.version, tt.bump)
+		if err != nil {
+			t.Errorf("BumpVersion(%s, %d) failed: %s", tt.version, tt.bump, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("BumpVersion(%s, %d) got %s, want %s", tt.version, tt.bump, got, tt.want)
+		}
+	}
+}
+
+func TestBumpVersionWithInvalidVersion(t *testing.T) {
+	for _, v := range []string{"", "1.2", "01.2.3", "latest"} {
+		_, err := BumpVersion(v, Patch)
+		if !test.MatchError(t, "invalid semantic version", err) {
+			t.Errorf("BumpVersion(%#v) got %v", v, err)
+		}
+	}
+}
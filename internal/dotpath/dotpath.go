@@ -0,0 +1,31 @@
+// Package dotpath splits the dotted key paths used by the format packages.
+package dotpath
+
+import "strings"
+
+// Split splits a dotted path into the individual segments, dots can be escaped
+// with a backslash e.g. "metadata.annotations.example\.com/name".
+//
+// All other characters are literal, an empty path has no segments.
+func Split(path string) []string {
+	if path == "" {
+		return nil
+	}
+	segments := []string{}
+	var current strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 < len(path) {
+				i++
+				current.WriteByte(path[i])
+			}
+		case '.':
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(segments, current.String())
+}
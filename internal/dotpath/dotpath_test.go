@@ -0,0 +1,30 @@
+package dotpath
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplit(t *testing.T) {
+	splitTests := []struct {
+		path string
+		want []string
+	}{
+		{"", nil},
+		{"image", []string{"image"}},
+		{"spec.template.image", []string{"spec", "template", "image"}},
+		{`metadata.annotations.example\.com/name`, []string{"metadata", "annotations", "example.com/name"}},
+		{`a\\.b`, []string{`a\`, "b"}},
+		{"a..b", []string{"a", "", "b"}},
+		{`trailing\`, []string{"trailing"}},
+	}
+
+	for _, tt := range splitTests {
+		t.Run(tt.path, func(rt *testing.T) {
+			if diff := cmp.Diff(tt.want, Split(tt.path)); diff != "" {
+				rt.Fatalf("segments differ:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// Package e2e tests the updater flows end-to-end, through the SCMClient and
+// the go-scm fake driver, to guard their behaviour across go-scm upgrades.
+//
+// The fake driver stores the files in a directory, and doesn't implement
+// refs, listing, updating and closing pull requests, or permissions, these
+// are provided by the tests. It can't reset existing branches or create forks,
+// so flows that need them start with a new branch, or an existing fork.
+package e2e
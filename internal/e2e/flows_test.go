@@ -0,0 +1,441 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/names"
+	"github.com/agill17/pkg/rules"
+	"github.com/agill17/pkg/updater"
+)
+
+const (
+	testRepo       = "testorg/testrepo"
+	testBranch     = "main"
+	testSHA        = "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	testFilePath   = "environments/test/services/service-a/test.yaml"
+	testSecondPath = "environments/test/services/service-b/test.yaml"
+	testFork       = "my-bots/testrepo"
+	// The first names generated with the seeded generator.
+	testNewBranch    = "update-DlPsU"
+	testRevertBranch = "revert-DlPsU"
+	// The branch name derived from the change to test.image.
+	testHashedBranch = "update-4beeb60205"
+)
+
+var testTime = time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+func TestFlows(t *testing.T) {
+	flowTests := []struct {
+		name             string
+		files            map[string]string
+		setup            func(*provider)
+		opts             []updater.UpdaterFunc
+		run              func(context.Context, *updater.Updater) (*updater.UpdateResult, error)
+		wantState        updater.UpdateState
+		wantFiles        map[string]string
+		wantBranches     map[string]string
+		wantForkBranches map[string]string
+		wantCommits      []commit
+		wantPRs          []*scm.PullRequestInput
+		wantUpdatedPRs   map[int]*scm.PullRequestInput
+		wantClosedPRs    []int
+	}{
+		{
+			name:  "update with a pull request",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return u.Update(ctx, makeInput())
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "direct commit",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.BranchGenerateName = ""
+				return u.Update(ctx, input)
+			},
+			wantState:    updater.Committed,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testBranch, testFilePath, "Update test.image"}},
+		},
+		{
+			name:  "no change",
+			files: map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return u.Update(ctx, makeInput())
+			},
+			wantState:    updater.Unchanged,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA},
+		},
+		{
+			name: "multiple files",
+			files: map[string]string{
+				testFilePath:   "test:\n  image: old-image\n",
+				testSecondPath: "test:\n  image: old-image\n",
+			},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.Files = []updater.FileChange{{Filename: testSecondPath, Key: "test.image", NewValue: "new-image"}}
+				return u.Update(ctx, input)
+			},
+			wantState: updater.PullRequestCreated,
+			wantFiles: map[string]string{
+				testFilePath:   "test:\n  image: new-image\n",
+				testSecondPath: "test:\n  image: new-image\n",
+			},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits: []commit{
+				{testRepo, testNewBranch, testFilePath, "Update test.image"},
+				{testRepo, testNewBranch, testSecondPath, "Update test.image"},
+			},
+			wantPRs: []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "delete a key",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n  debug: true\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.Key, input.Delete = "test.debug", true
+				input.CommitMessage, input.PullRequest.Body = "Remove {{ .Key }}", "Removed {{ .Key }}"
+				return u.Update(ctx, input)
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Remove test.debug"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Removed test.debug", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name: "batch grouped by repo",
+			files: map[string]string{
+				testFilePath:   "test:\n  image: old-image\n",
+				testSecondPath: "test:\n  image: old-image\n",
+			},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				second := makeInput()
+				second.Filename = testSecondPath
+				_, err := u.UpdateBatch(ctx, &updater.Batch{
+					Inputs:             []*updater.Input{makeInput(), second},
+					GroupByRepo:        true,
+					BranchGenerateName: "update-",
+					PullRequest:        updater.PullRequestInput{Title: "Update the images", Body: "Batch update"},
+				})
+				return nil, err
+			},
+			wantFiles: map[string]string{
+				testFilePath:   "test:\n  image: new-image\n",
+				testSecondPath: "test:\n  image: new-image\n",
+			},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits: []commit{
+				{testRepo, testNewBranch, testFilePath, "Update test.image"},
+				{testRepo, testNewBranch, testSecondPath, "Update test.image"},
+			},
+			wantPRs: []*scm.PullRequestInput{{Title: "Update the images", Body: "Batch update", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "apply update to file with no change",
+			files: map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				_, err := u.ApplyUpdateToFile(ctx, updater.CommitInput{
+					Repo:               testRepo,
+					Filename:           testFilePath,
+					Branch:             testBranch,
+					BranchGenerateName: "update-",
+					CommitMessage:      "Update the image",
+				}, updater.UpdateYAML("test.image", "new-image"))
+				if !errors.Is(err, updater.ErrNoChange) {
+					return nil, fmt.Errorf("got %v, want %v", err, updater.ErrNoChange)
+				}
+				return nil, nil
+			},
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA},
+		},
+		{
+			name:  "reset a new branch",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.BranchGenerateName, input.NewBranchName, input.ResetBranch = "", "update-image", true
+				return u.Update(ctx, input)
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, "update-image": testSHA},
+			wantCommits:  []commit{{testRepo, "update-image", testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: "update-image", Base: testBranch}},
+		},
+		{
+			name:  "reuse an open pull request",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			setup: func(p *provider) {
+				p.addBranch(testRepo, "update-existing", testSHA)
+				p.addPullRequest(&scm.PullRequest{
+					Title:  "Update the image",
+					Body:   "Updated from older-image\n\nUpdate-Target: " + testFilePath + "#test.image",
+					Source: "update-existing",
+					Target: testBranch,
+					Base:   scm.PullRequestBranch{Ref: testBranch},
+					Head:   scm.PullRequestBranch{Ref: "update-existing"},
+				})
+			},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.ReusePullRequest = true
+				return u.Update(ctx, input)
+			},
+			wantState:      updater.PullRequestUpdated,
+			wantFiles:      map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches:   map[string]string{testBranch: testSHA, "update-existing": testSHA},
+			wantCommits:    []commit{{testRepo, "update-existing", testFilePath, "Update test.image"}},
+			wantUpdatedPRs: map[int]*scm.PullRequestInput{1: {Title: "Update the image", Body: "Updated from old-image\n\nUpdate-Target: " + testFilePath + "#test.image"}},
+		},
+		{
+			name:  "hashed branch names",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			opts:  []updater.UpdaterFunc{updater.HashedBranchNames()},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return u.Update(ctx, makeInput())
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testHashedBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testHashedBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image\n\nUpdate-Target: " + testFilePath + "#test.image", Head: testHashedBranch, Base: testBranch}},
+		},
+		{
+			name:  "pull request from a fork",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			setup: func(p *provider) {
+				p.addFile(testFork, testFilePath, "test:\n  image: old-image\n")
+			},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.Fork = testFork
+				return u.Update(ctx, input)
+			},
+			wantState:        updater.PullRequestCreated,
+			wantFiles:        map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			wantBranches:     map[string]string{testBranch: testSHA},
+			wantForkBranches: map[string]string{testNewBranch: testSHA},
+			wantCommits:      []commit{{testFork, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:          []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: "my-bots:" + testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "forks with push permission",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			opts:  []updater.UpdaterFunc{updater.Forks("my-bots", updater.ForkDeleteBranchOnFailure)},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return u.Update(ctx, makeInput())
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "protected branch fallback",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			setup: func(p *provider) {
+				p.protectBranch(testRepo, testBranch)
+			},
+			opts: []updater.UpdaterFunc{updater.ProtectedBranchFallback("update-")},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				input := makeInput()
+				input.BranchGenerateName = ""
+				return u.Update(ctx, input)
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image\n\nUpdate-Target: " + testFilePath + "#test.image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "cleanup branches",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			setup: func(p *provider) {
+				p.addBranch(testRepo, "update-merged", testSHA)
+				p.addBranch(testRepo, "feature", testSHA)
+				p.addPullRequest(&scm.PullRequest{Source: "update-merged", Merged: true, Closed: true})
+				p.addPullRequest(&scm.PullRequest{Source: "feature", Merged: true, Closed: true})
+			},
+			opts: []updater.UpdaterFunc{updater.CleanupBranches()},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return u.Update(ctx, makeInput())
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA, "feature": testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "deduplicated rule value",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				dir, err := ioutil.TempDir("", "history")
+				if err != nil {
+					return nil, err
+				}
+				defer os.RemoveAll(dir)
+				store := rules.NewFileHistoryStore(dir)
+				r := makeRule()
+				if _, _, err := r.ApplyRecorded(ctx, u, store, "new-image", testTime); err != nil {
+					return nil, err
+				}
+				result, _, err := r.ApplyRecorded(ctx, u, store, "new-image", testTime.Add(time.Hour))
+				return result, err
+			},
+			wantState:    updater.Unchanged,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testNewBranch: testSHA},
+			wantCommits:  []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:      []*scm.PullRequestInput{{Title: "Update the image", Body: "Updated from old-image", Head: testNewBranch, Base: testBranch}},
+		},
+		{
+			name:  "revert an open pull request",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return revertBatch(ctx, u, "update-")
+			},
+			wantState:     updater.Unchanged,
+			wantFiles:     map[string]string{testFilePath: "test:\n  image: new-image\n"},
+			wantBranches:  map[string]string{testBranch: testSHA},
+			wantCommits:   []commit{{testRepo, testNewBranch, testFilePath, "Update test.image"}},
+			wantPRs:       []*scm.PullRequestInput{{Title: "Update the images", Body: "Batch update", Head: testNewBranch, Base: testBranch}},
+			wantClosedPRs: []int{1},
+		},
+		{
+			name:  "revert a direct commit",
+			files: map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			setup: func(p *provider) {
+				p.addFileAt(testRepo, testFilePath, testSHA, "test:\n  image: old-image\n")
+			},
+			run: func(ctx context.Context, u *updater.Updater) (*updater.UpdateResult, error) {
+				return revertBatch(ctx, u, "")
+			},
+			wantState:    updater.PullRequestCreated,
+			wantFiles:    map[string]string{testFilePath: "test:\n  image: old-image\n"},
+			wantBranches: map[string]string{testBranch: testSHA, testRevertBranch: testSHA},
+			wantCommits: []commit{
+				{testRepo, testBranch, testFilePath, "Update test.image"},
+				{testRepo, testRevertBranch, testFilePath, "Revert " + testFilePath},
+			},
+			wantPRs: []*scm.PullRequestInput{{Title: `Revert "Update the image"`, Body: "This restores the files changed by the update of " + testRepo + " from " + testSHA + ".", Head: testRevertBranch, Base: testBranch}},
+		},
+	}
+
+	for _, tt := range flowTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			p := newProvider(rt)
+			for path, body := range tt.files {
+				p.addFile(testRepo, path, body)
+			}
+			p.addBranch(testRepo, testBranch, testSHA)
+			if tt.setup != nil {
+				tt.setup(p)
+			}
+			opts := append([]updater.UpdaterFunc{updater.NameGenerator(names.New(rand.New(rand.NewSource(100))))}, tt.opts...)
+			u := updater.New(zap.New(), p.client, opts...)
+
+			r, err := tt.run(context.Background(), u)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r != nil && r.State != tt.wantState {
+				rt.Errorf("got state %s, want %s", r.State, tt.wantState)
+			}
+			for path, want := range tt.wantFiles {
+				p.assertFile(testRepo, path, want)
+			}
+			p.assertBranches(testRepo, tt.wantBranches)
+			p.assertCommits(tt.wantCommits)
+			if tt.wantPRs == nil {
+				tt.wantPRs = []*scm.PullRequestInput{}
+			}
+			p.assertPullRequests(tt.wantPRs)
+			p.assertUpdatedPullRequests(tt.wantUpdatedPRs)
+			p.assertClosedPullRequests(tt.wantClosedPRs)
+			if tt.wantForkBranches != nil {
+				p.assertBranches(testFork, tt.wantForkBranches)
+			}
+		})
+	}
+}
+
+func makeInput() *updater.Input {
+	return &updater.Input{
+		Repo:               testRepo,
+		Filename:           testFilePath,
+		Branch:             testBranch,
+		Key:                "test.image",
+		NewValue:           "new-image",
+		BranchGenerateName: "update-",
+		CommitMessage:      "Update {{ .Key }}",
+		PullRequest: updater.PullRequestInput{
+			Title: "Update the image",
+			Body:  "Updated from {{ .Previous }}",
+		},
+	}
+}
+
+// revertBatch updates the repo in a batch, and reverts the update.
+func revertBatch(ctx context.Context, u *updater.Updater, generateName string) (*updater.UpdateResult, error) {
+	input := makeInput()
+	input.BranchGenerateName = generateName
+	results, err := u.UpdateBatchResults(ctx, &updater.Batch{
+		Inputs:             []*updater.Input{input},
+		GroupByRepo:        true,
+		BranchGenerateName: generateName,
+		PullRequest:        updater.PullRequestInput{Title: "Update the images", Body: "Batch update"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	reverted, err := u.Compensate(ctx, &updater.Compensation{Kind: updater.RevertUpdate, Result: results[0]})
+	if err != nil {
+		return nil, err
+	}
+	return reverted.UpdateResult, nil
+}
+
+func makeRule() rules.Rule {
+	return rules.Rule{
+		Name:               "service-a",
+		Repo:               testRepo,
+		Branch:             testBranch,
+		File:               testFilePath,
+		Key:                "test.image",
+		BranchGenerateName: "update-",
+		CommitMessage:      "Update {{ .Key }}",
+		PullRequest: rules.PullRequest{
+			Title: "Update the image",
+			Body:  "Updated from {{ .Previous }}",
+		},
+	}
+}
@@ -0,0 +1,307 @@
+package e2e
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+
+	"github.com/agill17/pkg/client"
+)
+
+// provider is a fake git provider, the go-scm fake driver with refs, pull
+// requests, and a record of the commits.
+type provider struct {
+	t            *testing.T
+	dir          string
+	data         *fake.Data
+	git          *fakeGit
+	contents     *fakeContents
+	pullRequests *fakePullRequests
+	client       *client.SCMClient
+}
+
+type commit struct {
+	Repo    string
+	Branch  string
+	Path    string
+	Message string
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	scmClient, data := fake.NewDefault()
+	data.ContentDir = dir
+	git := &fakeGit{GitService: scmClient.Git, refs: map[string]string{}}
+	contents := &fakeContents{ContentService: scmClient.Contents, versions: map[string]string{}, protected: map[string]bool{}}
+	pullRequests := &fakePullRequests{PullRequestService: scmClient.PullRequests, data: data}
+	scmClient.Git = git
+	scmClient.Contents = contents
+	scmClient.PullRequests = pullRequests
+	scmClient.Repositories = &fakeRepositories{RepositoryService: scmClient.Repositories}
+	return &provider{t: t, dir: dir, data: data, git: git, contents: contents, pullRequests: pullRequests, client: client.New(scmClient)}
+}
+
+func (p *provider) addFile(repo, path, body string) {
+	p.t.Helper()
+	f := filepath.Join(p.dir, repo, path)
+	if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+		p.t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(f, []byte(body), 0644); err != nil {
+		p.t.Fatal(err)
+	}
+}
+
+// addFileAt adds the content of the file at the ref, which is returned rather
+// than the current content when the file is read at the ref.
+func (p *provider) addFileAt(repo, path, ref, body string) {
+	p.contents.versions[versionKey(repo, path, ref)] = body
+}
+
+func (p *provider) addBranch(repo, branch, sha string) {
+	p.git.refs[refKey(repo, branch)] = sha
+}
+
+// protectBranch rejects direct commits to the branch.
+func (p *provider) protectBranch(repo, branch string) {
+	p.contents.protected[refKey(repo, branch)] = true
+}
+
+// addPullRequest adds an existing pull request, which isn't recorded as
+// created.
+func (p *provider) addPullRequest(pr *scm.PullRequest) {
+	p.data.PullRequestID++
+	pr.Number = p.data.PullRequestID
+	p.data.PullRequests[pr.Number] = pr
+}
+
+func (p *provider) assertFile(repo, path, want string) {
+	p.t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(p.dir, repo, path))
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		p.t.Errorf("incorrect content of %s:\n%s", path, diff)
+	}
+}
+
+func (p *provider) assertBranches(repo string, want map[string]string) {
+	p.t.Helper()
+	got := map[string]string{}
+	for k, sha := range p.git.refs {
+		if strings.HasPrefix(k, repo+":") {
+			got[strings.TrimPrefix(k, repo+":")] = sha
+		}
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		p.t.Errorf("incorrect branches:\n%s", diff)
+	}
+}
+
+func (p *provider) assertCommits(want []commit) {
+	p.t.Helper()
+	if diff := cmp.Diff(want, p.contents.commits); diff != "" {
+		p.t.Errorf("incorrect commits:\n%s", diff)
+	}
+}
+
+func (p *provider) assertPullRequests(want []*scm.PullRequestInput) {
+	p.t.Helper()
+	ids := []int{}
+	for id := range p.data.PullRequestsCreated {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	got := []*scm.PullRequestInput{}
+	for _, id := range ids {
+		got = append(got, p.data.PullRequestsCreated[id])
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		p.t.Errorf("incorrect pull requests:\n%s", diff)
+	}
+}
+
+func (p *provider) assertUpdatedPullRequests(want map[int]*scm.PullRequestInput) {
+	p.t.Helper()
+	if diff := cmp.Diff(want, p.pullRequests.updated); diff != "" {
+		p.t.Errorf("incorrect updated pull requests:\n%s", diff)
+	}
+}
+
+func (p *provider) assertClosedPullRequests(want []int) {
+	p.t.Helper()
+	if diff := cmp.Diff(want, p.pullRequests.closed); diff != "" {
+		p.t.Errorf("incorrect closed pull requests:\n%s", diff)
+	}
+}
+
+// fakeGit provides the branch refs, which the fake driver doesn't implement.
+type fakeGit struct {
+	scm.GitService
+	mu   sync.Mutex
+	refs map[string]string
+}
+
+func (g *fakeGit) CreateRef(ctx context.Context, repo, ref, sha string) (*scm.Reference, *scm.Response, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := refKey(repo, ref)
+	if _, ok := g.refs[key]; ok {
+		return nil, &scm.Response{Status: http.StatusUnprocessableEntity}, fmt.Errorf("reference %s already exists", ref)
+	}
+	g.refs[key] = sha
+	return &scm.Reference{Name: ref, Sha: sha}, nil, nil
+}
+
+func (g *fakeGit) FindRef(ctx context.Context, repo, ref string) (string, *scm.Response, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	sha, ok := g.refs[refKey(repo, ref)]
+	if !ok {
+		return "", &scm.Response{Status: http.StatusNotFound}, fmt.Errorf("reference %s not found", ref)
+	}
+	return sha, nil, nil
+}
+
+func (g *fakeGit) DeleteRef(ctx context.Context, repo, ref string) (*scm.Response, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := refKey(repo, ref)
+	if _, ok := g.refs[key]; !ok {
+		return &scm.Response{Status: http.StatusUnprocessableEntity}, fmt.Errorf("reference %s does not exist", ref)
+	}
+	delete(g.refs, key)
+	return g.GitService.DeleteRef(ctx, repo, ref)
+}
+
+func refKey(repo, ref string) string {
+	return repo + ":" + strings.TrimPrefix(strings.TrimPrefix(ref, "refs/"), "heads/")
+}
+
+// fakeContents records the commits, and provides the content of files at
+// refs, and protected branches, which the fake driver doesn't.
+type fakeContents struct {
+	scm.ContentService
+	commits   []commit
+	versions  map[string]string
+	protected map[string]bool
+}
+
+func (c *fakeContents) Find(ctx context.Context, repo, path, ref string) (*scm.Content, *scm.Response, error) {
+	if body, ok := c.versions[versionKey(repo, path, ref)]; ok {
+		return &scm.Content{Path: path, Data: []byte(body), Sha: ref}, nil, nil
+	}
+	return c.ContentService.Find(ctx, repo, path, ref)
+}
+
+func (c *fakeContents) Update(ctx context.Context, repo, path string, params *scm.ContentParams) (*scm.Response, error) {
+	if c.protected[refKey(repo, params.Branch)] {
+		return &scm.Response{Status: http.StatusConflict}, errors.New("Changes must be made through a pull request.")
+	}
+	r, err := c.ContentService.Update(ctx, repo, path, params)
+	if err == nil {
+		c.commits = append(c.commits, commit{Repo: repo, Branch: params.Branch, Path: path, Message: params.Message})
+	}
+	return r, err
+}
+
+func versionKey(repo, path, ref string) string {
+	return repo + ":" + ref + ":" + path
+}
+
+// fakePullRequests lists, updates and closes the pull requests, which the fake
+// driver doesn't implement, with the source and target branches, and fork of
+// the pull requests it creates.
+type fakePullRequests struct {
+	scm.PullRequestService
+	data    *fake.Data
+	updated map[int]*scm.PullRequestInput
+	closed  []int
+}
+
+func (s *fakePullRequests) Create(ctx context.Context, repo string, input *scm.PullRequestInput) (*scm.PullRequest, *scm.Response, error) {
+	pr, r, err := s.PullRequestService.Create(ctx, repo, input)
+	if err != nil {
+		return pr, r, err
+	}
+	pr.Source, pr.Target = input.Head, input.Base
+	if n := strings.Index(input.Head, ":"); n >= 0 {
+		// Forks have the name of the repo.
+		pr.Source, pr.Fork = input.Head[n+1:], input.Head[:n]+repo[strings.Index(repo, "/"):]
+	}
+	return pr, r, nil
+}
+
+func (s *fakePullRequests) List(ctx context.Context, repo string, opts scm.PullRequestListOptions) ([]*scm.PullRequest, *scm.Response, error) {
+	prs := []*scm.PullRequest{}
+	if opts.Page > 1 {
+		return prs, nil, nil
+	}
+	numbers := []int{}
+	for n := range s.data.PullRequests {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	for _, n := range numbers {
+		if pr := s.data.PullRequests[n]; pr.Closed && opts.Closed || !pr.Closed && opts.Open {
+			prs = append(prs, pr)
+		}
+	}
+	return prs, nil, nil
+}
+
+func (s *fakePullRequests) Update(ctx context.Context, repo string, number int, input *scm.PullRequestInput) (*scm.PullRequest, *scm.Response, error) {
+	pr, ok := s.data.PullRequests[number]
+	if !ok {
+		return nil, &scm.Response{Status: http.StatusNotFound}, fmt.Errorf("pull request %d not found", number)
+	}
+	if input.Title != "" {
+		pr.Title = input.Title
+	}
+	if input.Body != "" {
+		pr.Body = input.Body
+	}
+	if s.updated == nil {
+		s.updated = map[int]*scm.PullRequestInput{}
+	}
+	s.updated[number] = input
+	return pr, nil, nil
+}
+
+func (s *fakePullRequests) Close(ctx context.Context, repo string, number int) (*scm.Response, error) {
+	pr, ok := s.data.PullRequests[number]
+	if !ok {
+		return &scm.Response{Status: http.StatusNotFound}, fmt.Errorf("pull request %d not found", number)
+	}
+	pr.Closed = true
+	s.closed = append(s.closed, number)
+	return nil, nil
+}
+
+// fakeRepositories provides the permissions, which the fake driver doesn't
+// implement, the bot can push to every repo.
+type fakeRepositories struct {
+	scm.RepositoryService
+}
+
+func (s *fakeRepositories) FindPerms(ctx context.Context, repo string) (*scm.Perm, *scm.Response, error) {
+	return &scm.Perm{Pull: true, Push: true}, nil, nil
+}
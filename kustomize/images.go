@@ -0,0 +1,107 @@
+package kustomize
+
+import (
+	"errors"
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/agill17/pkg/syaml"
+)
+
+// Image is an entry in the kustomize images transformer list.
+//
+// Empty fields are left unchanged when updating an existing entry.
+type Image struct {
+	Name    string `json:"name"`
+	NewName string `json:"newName,omitempty"`
+	NewTag  string `json:"newTag,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+}
+
+// SetImage updates the entry for the image name in the images list of a
+// kustomization.yaml, creating the entry, and the list, if they don't exist.
+//
+// SetImage(b, Image{Name: "nginx", NewTag: "1.19"})
+func SetImage(b []byte, image Image) ([]byte, error) {
+	if image.Name == "" {
+		return nil, errors.New("an image name is required")
+	}
+	index, count, err := findImage(b, image.Name)
+	if err != nil {
+		return nil, err
+	}
+	if index == -1 {
+		entry := map[string]interface{}{"name": image.Name}
+		for k, v := range image.fields() {
+			entry[k] = v
+		}
+		if count == -1 {
+			return syaml.SetBytes(b, "images", []interface{}{entry})
+		}
+		return syaml.SetBytes(b, fmt.Sprintf("images.%d", count), entry)
+	}
+	for _, field := range []string{"newName", "newTag", "digest"} {
+		v, ok := image.fields()[field]
+		if !ok {
+			continue
+		}
+		b, err = syaml.SetBytes(b, fmt.Sprintf("images.%d.%s", index, field), v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set %s for image %s: %w", field, image.Name, err)
+		}
+	}
+	return b, nil
+}
+
+func (i Image) fields() map[string]string {
+	f := map[string]string{}
+	if i.NewName != "" {
+		f["newName"] = i.NewName
+	}
+	if i.NewTag != "" {
+		f["newTag"] = i.NewTag
+	}
+	if i.Digest != "" {
+		f["digest"] = i.Digest
+	}
+	return f
+}
+
+// findImage returns the index of the named image in the images list, and the
+// number of entries in the list, which is -1 if there is no list.
+func findImage(b []byte, name string) (int, int, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(b, &doc); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse kustomization: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return -1, -1, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml3.MappingNode {
+		return 0, 0, errors.New("failed to parse kustomization: not a mapping")
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "images" {
+			continue
+		}
+		images := root.Content[i+1]
+		if images.Kind != yaml3.SequenceNode {
+			return 0, 0, errors.New("failed to parse kustomization: images is not a list")
+		}
+		for j, entry := range images.Content {
+			image := struct {
+				Name string `yaml:"name"`
+			}{}
+			if err := entry.Decode(&image); err != nil {
+				return 0, 0, fmt.Errorf("failed to parse image %d: %w", j, err)
+			}
+			if image.Name == name {
+				return j, len(images.Content), nil
+			}
+		}
+		return -1, len(images.Content), nil
+	}
+	return -1, -1, nil
+}
@@ -0,0 +1,95 @@
+package kustomize
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testKustomization = `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- deployment.yaml
+images:
+- name: nginx
+  newTag: "1.18" # pinned
+- name: example/app
+  newName: registry.example.com/app
+  newTag: v1
+`
+
+func TestSetImage(t *testing.T) {
+	imageTests := []struct {
+		name   string
+		source string
+		image  Image
+		want   string
+	}{
+		{
+			name:   "update tag",
+			source: testKustomization,
+			image:  Image{Name: "nginx", NewTag: "1.19"},
+			want:   "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- deployment.yaml\nimages:\n- name: nginx\n  newTag: \"1.19\" # pinned\n- name: example/app\n  newName: registry.example.com/app\n  newTag: v1\n",
+		},
+		{
+			name:   "update name and tag",
+			source: testKustomization,
+			image:  Image{Name: "example/app", NewName: "registry.example.com/app-v2", NewTag: "v2"},
+			want:   "apiVersion: kustomize.config.k8s.io/v1beta1\nkind: Kustomization\nresources:\n- deployment.yaml\nimages:\n- name: nginx\n  newTag: \"1.18\" # pinned\n- name: example/app\n  newName: registry.example.com/app-v2\n  newTag: v2\n",
+		},
+		{
+			name:   "add digest",
+			source: "images:\n- name: nginx\n  newTag: \"1.18\"\n",
+			image:  Image{Name: "nginx", Digest: "sha256:abcd"},
+			want:   "images:\n- name: nginx\n  newTag: \"1.18\"\n  digest: sha256:abcd\n",
+		},
+		{
+			name:   "new entry",
+			source: "images:\n- name: nginx\n  newTag: \"1.18\"\n",
+			image:  Image{Name: "redis", NewTag: "6"},
+			want:   "images:\n- name: nginx\n  newTag: \"1.18\"\n- name: redis\n  newTag: \"6\"\n",
+		},
+		{
+			name:   "new list",
+			source: "resources:\n- deployment.yaml\n",
+			image:  Image{Name: "redis", NewTag: "6"},
+			want:   "resources:\n- deployment.yaml\nimages:\n- name: redis\n  newTag: \"6\"\n",
+		},
+	}
+
+	for _, tt := range imageTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetImage([]byte(tt.source), tt.image)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetImageFailures(t *testing.T) {
+	imageTests := []struct {
+		name    string
+		source  string
+		image   Image
+		wantErr string
+	}{
+		{"no name", testKustomization, Image{NewTag: "1.19"}, "an image name is required"},
+		{"images is not a list", "images: nginx\n", Image{Name: "nginx", NewTag: "1.19"}, "images is not a list"},
+		{"not a mapping", "- nginx\n", Image{Name: "nginx", NewTag: "1.19"}, "not a mapping"},
+	}
+
+	for _, tt := range imageTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetImage([]byte(tt.source), tt.image)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+//go:build !minimal
+// +build !minimal
+
+package lock
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewLeaseLocker creates and returns a new LeaseLocker, the Leases are
+// created in the namespace.
+func NewLeaseLocker(c client.Client, namespace string) *LeaseLocker {
+	return &LeaseLocker{kubeClient: c, namespace: namespace, now: time.Now}
+}
+
+// LeaseLocker is a Locker that holds the locks as Kubernetes Leases, it
+// coordinates updaters in processes with access to the namespace.
+//
+// The Lease names are derived from the lock names, as lock names may not be
+// valid resource names.
+type LeaseLocker struct {
+	kubeClient client.Client
+	namespace  string
+	now        func() time.Time
+}
+
+// TryLock implements the Locker interface.
+func (l *LeaseLocker) TryLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := metav1.NewMicroTime(l.now())
+	seconds := int32(ttl.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	lease := &coordinationv1.Lease{}
+	err := l.kubeClient.Get(ctx, types.NamespacedName{Namespace: l.namespace, Name: leaseName(name)}, lease)
+	if apierrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Namespace: l.namespace, Name: leaseName(name)},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &owner,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		err = l.kubeClient.Create(ctx, lease)
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to create lease %s/%s: %w", l.namespace, leaseName(name), err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get lease %s/%s: %w", l.namespace, leaseName(name), err)
+	}
+	if isHeld(lease, now.Time) {
+		return false, nil
+	}
+	lease.Spec.HolderIdentity = &owner
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.AcquireTime = &now
+	lease.Spec.RenewTime = &now
+	// The update fails with a conflict if another owner acquired the Lease
+	// since it was read.
+	err = l.kubeClient.Update(ctx, lease)
+	if apierrors.IsConflict(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update lease %s/%s: %w", l.namespace, leaseName(name), err)
+	}
+	return true, nil
+}
+
+// Unlock implements the Locker interface.
+func (l *LeaseLocker) Unlock(ctx context.Context, name, owner string) error {
+	lease := &coordinationv1.Lease{}
+	err := l.kubeClient.Get(ctx, types.NamespacedName{Namespace: l.namespace, Name: leaseName(name)}, lease)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get lease %s/%s: %w", l.namespace, leaseName(name), err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != owner {
+		return nil
+	}
+	lease.Spec.HolderIdentity = nil
+	if err := l.kubeClient.Update(ctx, lease); err != nil && !apierrors.IsConflict(err) {
+		return fmt.Errorf("failed to update lease %s/%s: %w", l.namespace, leaseName(name), err)
+	}
+	return nil
+}
+
+func isHeld(lease *coordinationv1.Lease, now time.Time) bool {
+	spec := lease.Spec
+	if spec.HolderIdentity == nil || *spec.HolderIdentity == "" || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+		return false
+	}
+	return now.Before(spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second))
+}
+
+// leaseName returns a valid resource name for the lock, with a hash of the
+// name, so that names that differ only in invalid characters don't collide.
+func leaseName(name string) string {
+	valid := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '-'
+	}, name)
+	if len(valid) > 50 {
+		valid = valid[:50]
+	}
+	sum := sha256.Sum256([]byte(name))
+	return fmt.Sprintf("lock-%s-%x", strings.Trim(valid, "-"), sum[:4])
+}
@@ -0,0 +1,64 @@
+//go:build !minimal
+// +build !minimal
+
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ Locker = (*LeaseLocker)(nil)
+
+func TestLeaseLocker(t *testing.T) {
+	now := time.Date(2020, time.July, 1, 10, 0, 0, 0, time.UTC)
+	kube := fake.NewFakeClient()
+	l := NewLeaseLocker(kube, "updaters")
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assertTryLock(t, l, "my-org/my-repo", "first", true)
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+	assertTryLock(t, l, "my-org/other-repo", "second", true)
+
+	lease := &coordinationv1.Lease{}
+	if err := kube.Get(ctx, types.NamespacedName{Namespace: "updaters", Name: leaseName("my-org/my-repo")}, lease); err != nil {
+		t.Fatal(err)
+	}
+	if *lease.Spec.HolderIdentity != "first" || *lease.Spec.LeaseDurationSeconds != 60 {
+		t.Fatalf("incorrect lease: %#v", lease.Spec)
+	}
+
+	if err := l.Unlock(ctx, "my-org/my-repo", "second"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+	if err := l.Unlock(ctx, "my-org/my-repo", "first"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", true)
+
+	now = now.Add(2 * time.Minute)
+	assertTryLock(t, l, "my-org/my-repo", "first", true)
+}
+
+func TestLeaseName(t *testing.T) {
+	nameTests := []struct {
+		name string
+		want string
+	}{
+		{"my-org/my-repo", "lock-my-org-my-repo-bcc89b7f"},
+		{"My-Org/My-Repo", "lock-my-org-my-repo-b13353c8"},
+	}
+
+	for _, tt := range nameTests {
+		if got := leaseName(tt.name); got != tt.want {
+			t.Errorf("leaseName(%q) got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
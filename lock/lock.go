@@ -0,0 +1,59 @@
+// Package lock provides advisory locks, so that independently deployed
+// processes can coordinate their changes to shared resources, e.g. repos.
+//
+// Locks are held by an owner for at most a TTL, so a lock held by a process
+// that fails is eventually released.
+//
+// The LeaseLocker is not built with the minimal tag, which removes the
+// Kubernetes dependencies.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Locker is implemented by stores of advisory locks.
+type Locker interface {
+	// TryLock acquires the named lock for the owner, for the TTL, if it's
+	// not held, it returns false if the lock is held, even by the owner.
+	TryLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	// Unlock releases the named lock, if it's held by the owner.
+	Unlock(ctx context.Context, name, owner string) error
+}
+
+// Acquire blocks until the named lock is acquired for the owner, trying every
+// interval, or the context is done.
+func Acquire(ctx context.Context, l Locker, name, owner string, ttl, interval time.Duration) error {
+	for {
+		ok, err := l.TryLock(ctx, name, owner, ttl)
+		if err != nil {
+			return fmt.Errorf("failed to acquire lock %s: %w", name, err)
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to acquire lock %s: %w", name, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// NewOwner returns a unique owner, identifying the host.
+func NewOwner() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%s-%d", host, time.Now().UnixNano())
+	}
+	return host + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,60 @@
+package lock
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agill17/pkg/test"
+)
+
+var _ Locker = (*MemoryLocker)(nil)
+
+func TestAcquire(t *testing.T) {
+	l := NewMemoryLocker()
+	ctx := context.Background()
+	if err := Acquire(ctx, l, "my-org/my-repo", "first", time.Minute, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		if err := l.Unlock(ctx, "my-org/my-repo", "first"); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := Acquire(ctx, l, "my-org/my-repo", "second", time.Minute, time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := l.TryLock(ctx, "my-org/my-repo", "first", time.Minute); ok {
+		t.Fatal("lock acquired while held by the second owner")
+	}
+}
+
+func TestAcquireWithCancelledContext(t *testing.T) {
+	l := NewMemoryLocker()
+	if _, err := l.TryLock(context.Background(), "my-org/my-repo", "first", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	err := Acquire(ctx, l, "my-org/my-repo", "second", time.Minute, time.Millisecond)
+
+	if !test.MatchError(t, "failed to acquire lock my-org/my-repo: context deadline exceeded", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestNewOwner(t *testing.T) {
+	first, second := NewOwner(), NewOwner()
+
+	if first == second {
+		t.Fatalf("owners are not unique: %s", first)
+	}
+	if strings.Count(first, "-") < 1 {
+		t.Fatalf("owner does not identify the host: %s", first)
+	}
+}
@@ -0,0 +1,47 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// NewMemoryLocker creates and returns a new MemoryLocker.
+func NewMemoryLocker() *MemoryLocker {
+	return &MemoryLocker{locks: make(map[string]held), now: time.Now}
+}
+
+// MemoryLocker is a Locker that holds the locks in memory, it only
+// coordinates updaters in the same process.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]held
+	now   func() time.Time
+}
+
+type held struct {
+	owner   string
+	expires time.Time
+}
+
+// TryLock implements the Locker interface.
+func (m *MemoryLocker) TryLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if h, ok := m.locks[name]; ok && now.Before(h.expires) {
+		return false, nil
+	}
+	m.locks[name] = held{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Unlock implements the Locker interface.
+func (m *MemoryLocker) Unlock(ctx context.Context, name, owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if h, ok := m.locks[name]; ok && h.owner == owner {
+		delete(m.locks, name)
+	}
+	return nil
+}
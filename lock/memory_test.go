@@ -0,0 +1,43 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	now := time.Date(2020, time.July, 1, 10, 0, 0, 0, time.UTC)
+	l := NewMemoryLocker()
+	l.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	assertTryLock(t, l, "my-org/my-repo", "first", true)
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+	assertTryLock(t, l, "my-org/my-repo", "first", false)
+	assertTryLock(t, l, "my-org/other-repo", "second", true)
+
+	if err := l.Unlock(ctx, "my-org/my-repo", "second"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+
+	if err := l.Unlock(ctx, "my-org/my-repo", "first"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", true)
+
+	now = now.Add(2 * time.Minute)
+	assertTryLock(t, l, "my-org/my-repo", "first", true)
+}
+
+func assertTryLock(t *testing.T, l Locker, name, owner string, want bool) {
+	t.Helper()
+	ok, err := l.TryLock(context.Background(), name, owner, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != want {
+		t.Fatalf("TryLock(%s, %s) got %v, want %v", name, owner, ok, want)
+	}
+}
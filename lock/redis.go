@@ -0,0 +1,53 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// unlockScript deletes the lock only if it's held by the owner, atomically.
+const unlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`
+
+// RedisClient is the subset of a Redis client used by the RedisLocker, e.g.
+// a go-redis client with an adapter.
+type RedisClient interface {
+	// SetNX sets the key to the value with an expiry, if the key doesn't
+	// exist, and returns true if it was set.
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Eval runs the Lua script with the keys and arguments.
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// NewRedisLocker creates and returns a new RedisLocker, the names of the locks
+// are prefixed, e.g. "updater-locks:".
+func NewRedisLocker(c RedisClient, prefix string) *RedisLocker {
+	return &RedisLocker{client: c, prefix: prefix}
+}
+
+// RedisLocker is a Locker that holds the locks as expiring Redis keys, it
+// coordinates updaters in processes sharing the Redis server.
+type RedisLocker struct {
+	client RedisClient
+	prefix string
+}
+
+// TryLock implements the Locker interface.
+func (r *RedisLocker) TryLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.prefix+name, owner, ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to set lock key %s: %w", r.prefix+name, err)
+	}
+	return ok, nil
+}
+
+// Unlock implements the Locker interface.
+func (r *RedisLocker) Unlock(ctx context.Context, name, owner string) error {
+	if _, err := r.client.Eval(ctx, unlockScript, []string{r.prefix + name}, owner); err != nil {
+		return fmt.Errorf("failed to delete lock key %s: %w", r.prefix+name, err)
+	}
+	return nil
+}
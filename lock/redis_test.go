@@ -0,0 +1,87 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+var _ Locker = (*RedisLocker)(nil)
+
+type fakeRedis struct {
+	values  map[string]string
+	ttls    map[string]time.Duration
+	scripts []string
+	err     error
+}
+
+func (f *fakeRedis) SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if _, ok := f.values[key]; ok {
+		return false, nil
+	}
+	f.values[key], f.ttls[key] = value, ttl
+	return true, nil
+}
+
+// Eval emulates the unlock script.
+func (f *fakeRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.scripts = append(f.scripts, script)
+	if f.values[keys[0]] == args[0] {
+		delete(f.values, keys[0])
+		return int64(1), nil
+	}
+	return int64(0), nil
+}
+
+func TestRedisLocker(t *testing.T) {
+	fake := &fakeRedis{values: map[string]string{}, ttls: map[string]time.Duration{}}
+	l := NewRedisLocker(fake, "updater-locks:")
+	ctx := context.Background()
+
+	assertTryLock(t, l, "my-org/my-repo", "first", true)
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+	if diff := cmp.Diff(map[string]string{"updater-locks:my-org/my-repo": "first"}, fake.values); diff != "" {
+		t.Fatalf("incorrect keys:\n%s", diff)
+	}
+	if fake.ttls["updater-locks:my-org/my-repo"] != time.Minute {
+		t.Fatalf("got TTL %s", fake.ttls["updater-locks:my-org/my-repo"])
+	}
+
+	if err := l.Unlock(ctx, "my-org/my-repo", "second"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", false)
+	if err := l.Unlock(ctx, "my-org/my-repo", "first"); err != nil {
+		t.Fatal(err)
+	}
+	assertTryLock(t, l, "my-org/my-repo", "second", true)
+	if fake.scripts[0] != unlockScript {
+		t.Fatalf("got script %q", fake.scripts[0])
+	}
+}
+
+func TestRedisLockerWithErrors(t *testing.T) {
+	fake := &fakeRedis{err: errors.New("connection refused")}
+	l := NewRedisLocker(fake, "updater-locks:")
+
+	_, err := l.TryLock(context.Background(), "my-org/my-repo", "first", time.Minute)
+	if !test.MatchError(t, "failed to set lock key updater-locks:my-org/my-repo: connection refused", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+
+	err = l.Unlock(context.Background(), "my-org/my-repo", "first")
+	if !test.MatchError(t, "failed to delete lock key updater-locks:my-org/my-repo: connection refused", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,59 @@
+package rules
+
+import (
+	"github.com/agill17/pkg/updater"
+)
+
+// PostAction configures an action applied to the PullRequest opened for a
+// Rule, each of the fields that is set is applied, in the order of the fields.
+//
+//	postActions:
+//	  - labels: [automated, image-update]
+//	  - reviewers: [platform-bot]
+//	  - milestone: v1.2.0
+//	  - project: PVT_kwDOA
+//	    projectColumn: In review
+//	  - autoMerge: squash
+type PostAction struct {
+	Labels        []string `yaml:"labels,omitempty"`
+	Reviewers     []string `yaml:"reviewers,omitempty"`
+	TeamReviewers []string `yaml:"teamReviewers,omitempty"`
+	Assignees     []string `yaml:"assignees,omitempty"`
+	Comment       string   `yaml:"comment,omitempty"`
+	Milestone     string   `yaml:"milestone,omitempty"`     // the title of an open milestone
+	Project       string   `yaml:"project,omitempty"`       // the node ID of a GitHub project
+	ProjectColumn string   `yaml:"projectColumn,omitempty"` // the Status in the project
+	AutoMerge     string   `yaml:"autoMerge,omitempty"`     // merge, squash or rebase
+}
+
+// postActions returns the updater PostActions for the configured actions.
+func postActions(configured []PostAction) []updater.PostAction {
+	var actions []updater.PostAction
+	for _, a := range configured {
+		if len(a.Labels) > 0 {
+			actions = append(actions, updater.AddLabels(a.Labels...))
+		}
+		if len(a.Reviewers) > 0 {
+			actions = append(actions, updater.RequestReviewers(a.Reviewers...))
+		}
+		if len(a.TeamReviewers) > 0 {
+			actions = append(actions, updater.RequestTeamReviewers(a.TeamReviewers...))
+		}
+		if len(a.Assignees) > 0 {
+			actions = append(actions, updater.AddAssignees(a.Assignees...))
+		}
+		if a.Comment != "" {
+			actions = append(actions, updater.Comment(a.Comment))
+		}
+		if a.Milestone != "" {
+			actions = append(actions, updater.SetMilestone(a.Milestone))
+		}
+		if a.Project != "" {
+			actions = append(actions, updater.AddToProject(a.Project, a.ProjectColumn))
+		}
+		if a.AutoMerge != "" {
+			actions = append(actions, updater.EnableAutoMerge(a.AutoMerge))
+		}
+	}
+	return actions
+}
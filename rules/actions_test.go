@@ -0,0 +1,50 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRuleInputWithPostActions(t *testing.T) {
+	r := Rule{
+		Repo:   "my-org/frontend-deploy",
+		Branch: "main",
+		File:   "deployment.yaml",
+		Key:    "spec.image",
+		PostActions: []PostAction{
+			{Labels: []string{"automated"}, Reviewers: []string{"octocat"}},
+			{TeamReviewers: []string{"my-org/frontend"}, Assignees: []string{"hubot"}},
+			{Comment: "Please review", Milestone: "v1.2.0"},
+			{Project: "PVT_kwDOA", ProjectColumn: "In review", AutoMerge: "squash"},
+		},
+	}
+
+	names := []string{}
+	for _, a := range r.Input("app:v2").PostActions {
+		names = append(names, a.Name())
+	}
+
+	want := []string{"add-labels", "request-reviewers", "request-team-reviewers", "add-assignees", "comment", "set-milestone", "add-to-project", "enable-auto-merge"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Fatalf("incorrect post-actions:\n%s", diff)
+	}
+}
+
+func TestDelayedInputWithPostActions(t *testing.T) {
+	r := Rule{
+		Name:        "frontend",
+		File:        "canary.yaml",
+		Secondary:   &Secondary{File: "stable.yaml", Delay: "1h"},
+		PostActions: []PostAction{{Labels: []string{"automated"}}},
+	}
+
+	input, _, err := r.DelayedInput("app:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if input.PostActions != nil {
+		t.Fatalf("got post-actions %#v for the delayed input", input.PostActions)
+	}
+}
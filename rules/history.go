@@ -0,0 +1,157 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/agill17/pkg/updater"
+)
+
+// maxHistory is the number of applied values retained for each rule.
+const maxHistory = 50
+
+// AppliedValue records a new value that was applied by a Rule.
+type AppliedValue struct {
+	Value       string    `json:"value"` // The new value, in its YAML form
+	State       string    `json:"state"`
+	Branch      string    `json:"branch,omitempty"`
+	Commit      string    `json:"commit,omitempty"`
+	PullRequest int       `json:"pullRequest,omitempty"`
+	AppliedAt   time.Time `json:"appliedAt"`
+}
+
+// History is the record of the values applied by a Rule, oldest first, it's
+// retained while the Rule is disabled, or removed from the rules file.
+type History struct {
+	Rule    string         `json:"rule"`
+	Applied []AppliedValue `json:"applied,omitempty"`
+}
+
+// Last returns the last value applied by the Rule, or nil if there is none.
+func (h *History) Last() *AppliedValue {
+	if len(h.Applied) == 0 {
+		return nil
+	}
+	return &h.Applied[len(h.Applied)-1]
+}
+
+func (h *History) record(v AppliedValue) {
+	h.Applied = append(h.Applied, v)
+	if l := len(h.Applied); l > maxHistory {
+		h.Applied = append([]AppliedValue{}, h.Applied[l-maxHistory:]...)
+	}
+}
+
+// HistoryStore persists the History of rules.
+type HistoryStore interface {
+	// Load returns the History of the rule, which is empty if nothing has
+	// been recorded.
+	Load(ctx context.Context, rule string) (*History, error)
+	Save(ctx context.Context, h *History) error
+}
+
+// ApplyRecorded applies the rule with the new value as Apply does, and
+// records it in the rule's History.
+//
+// A disabled rule is Skipped, and its History is kept, so that when it's
+// enabled again, a value that it already applied is Unchanged, rather than
+// opening another PullRequest, both are recorded as Decisions of the Updater.
+func (r Rule) ApplyRecorded(ctx context.Context, u *updater.Updater, store HistoryStore, newValue interface{}, now time.Time) (*updater.UpdateResult, *updater.DelayedUpdate, error) {
+	if r.Disabled {
+		return r.skipDisabled(u, now), nil, nil
+	}
+	value, err := yaml3.Marshal(newValue)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal the new value of rule %s: %w", r.Name, err)
+	}
+	h, err := store.Load(ctx, r.Name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the history of rule %s: %w", r.Name, err)
+	}
+	if last := h.Last(); last != nil && last.Value == string(value) {
+		d := r.decision(updater.DecisionDeduplicated, now)
+		d.Detail = fmt.Sprintf("the value was applied at %s", last.AppliedAt.Format(time.RFC3339))
+		u.RecordDecision(d)
+		return &updater.UpdateResult{State: updater.Unchanged, Branch: last.Branch, Commit: last.Commit}, nil, nil
+	}
+	result, d, err := r.Apply(ctx, u, newValue, now)
+	if err != nil || result.State == updater.Skipped {
+		return result, d, err
+	}
+	applied := AppliedValue{Value: string(value), State: result.State.String(), Branch: result.Branch, Commit: result.Commit, AppliedAt: now.UTC()}
+	if result.PullRequest != nil {
+		applied.PullRequest = result.PullRequest.Number
+	}
+	h.Rule = r.Name
+	h.record(applied)
+	if err := store.Save(ctx, h); err != nil {
+		return result, d, fmt.Errorf("failed to save the history of rule %s: %w", r.Name, err)
+	}
+	return result, d, nil
+}
+
+// skipDisabled records the Decision for a disabled rule, and returns its
+// Skipped result.
+func (r Rule) skipDisabled(u *updater.Updater, now time.Time) *updater.UpdateResult {
+	u.RecordDecision(r.decision(updater.DecisionDisabled, now))
+	return &updater.UpdateResult{State: updater.Skipped}
+}
+
+func (r Rule) decision(reason updater.DecisionReason, now time.Time) updater.Decision {
+	return updater.Decision{Time: now, Reason: reason, Rule: r.Name, Repo: r.Repo, Branch: r.Branch, Filename: r.File, Key: r.Key}
+}
+
+// FileHistoryStore is a HistoryStore that persists the History of each rule as
+// a JSON file in a directory.
+type FileHistoryStore struct {
+	dir string
+}
+
+// NewFileHistoryStore creates and returns a FileHistoryStore that uses the
+// directory, which must exist.
+func NewFileHistoryStore(dir string) *FileHistoryStore {
+	return &FileHistoryStore{dir: dir}
+}
+
+// Load implements the HistoryStore interface.
+func (s *FileHistoryStore) Load(ctx context.Context, rule string) (*History, error) {
+	b, err := ioutil.ReadFile(s.path(rule))
+	if os.IsNotExist(err) {
+		return &History{Rule: rule}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	h := &History{}
+	if err := json.NewDecoder(bytes.NewReader(b)).Decode(h); err != nil {
+		return nil, fmt.Errorf("failed to decode the history of rule %s: %w", rule, err)
+	}
+	return h, nil
+}
+
+// Save implements the HistoryStore interface.
+func (s *FileHistoryStore) Save(ctx context.Context, h *History) error {
+	b, err := json.Marshal(h)
+	if err != nil {
+		return fmt.Errorf("failed to encode the history of rule %s: %w", h.Rule, err)
+	}
+	// Writing to a temporary file and renaming it avoids partial files.
+	tmp := filepath.Join(s.dir, "."+url.PathEscape(h.Rule)+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(h.Rule))
+}
+
+func (s *FileHistoryStore) path(rule string) string {
+	return filepath.Join(s.dir, url.PathEscape(rule)+".json")
+}
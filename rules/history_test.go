@@ -0,0 +1,228 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/updater"
+)
+
+func TestApplyRecorded(t *testing.T) {
+	store := makeHistoryStore(t)
+	now := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	r := makeHistoryRule()
+
+	m := makeHistoryClient(t)
+	result, _, err := r.ApplyRecorded(context.Background(), newHistoryUpdater(m), store, "app:v2", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := result.Commit
+	if result.State != updater.PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", result.State)
+	}
+
+	m = makeHistoryClient(t)
+	result, _, err = r.ApplyRecorded(context.Background(), newHistoryUpdater(m), store, "app:v2", now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.State != updater.Unchanged {
+		t.Fatalf("got state %s, want unchanged", result.State)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	h, err := store.Load(context.Background(), r.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &History{
+		Rule:    r.Name,
+		Applied: []AppliedValue{{Value: "app:v2\n", State: "pull-request-created", Branch: "update-image-a", Commit: commit, PullRequest: 1, AppliedAt: now}},
+	}
+	if diff := cmp.Diff(want, h); diff != "" {
+		t.Fatalf("incorrect history:\n%s", diff)
+	}
+}
+
+func TestApplyRecordedWithDisabledRule(t *testing.T) {
+	store := makeHistoryStore(t)
+	now := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	r := makeHistoryRule()
+	if _, _, err := r.ApplyRecorded(context.Background(), newHistoryUpdater(makeHistoryClient(t)), store, "app:v2", now); err != nil {
+		t.Fatal(err)
+	}
+
+	r.Disabled = true
+	m := makeHistoryClient(t)
+	result, _, err := r.ApplyRecorded(context.Background(), newHistoryUpdater(m), store, "app:v3", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.State != updater.Skipped {
+		t.Fatalf("got state %s, want skipped", result.State)
+	}
+	m.AssertNoPullRequestsCreated()
+
+	r.Disabled = false
+	result, _, err = r.ApplyRecorded(context.Background(), newHistoryUpdater(m), store, "app:v2", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.State != updater.Unchanged || result.Branch != "update-image-a" {
+		t.Fatalf("got %#v, want the recorded update", result)
+	}
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestApplyRecordedWithNewValue(t *testing.T) {
+	store := makeHistoryStore(t)
+	now := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	r := makeHistoryRule()
+	for _, v := range []string{"app:v2", "app:v3"} {
+		if _, _, err := r.ApplyRecorded(context.Background(), newHistoryUpdater(makeHistoryClient(t)), store, v, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h, err := store.Load(context.Background(), r.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(h.Applied); l != 2 {
+		t.Fatalf("got %d applied values, want 2", l)
+	}
+	if v := h.Last().Value; v != "app:v3\n" {
+		t.Fatalf("got last value %q, want app:v3", v)
+	}
+}
+
+func TestApplyRecordedDecisions(t *testing.T) {
+	store := makeHistoryStore(t)
+	now := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	r := makeHistoryRule()
+	var buf bytes.Buffer
+	u := updater.New(zap.New(), makeHistoryClient(t), updater.NameGenerator(stubNameGenerator{"a"}), updater.Decisions(&buf))
+	for _, v := range []string{"app:v2", "app:v2"} {
+		if _, _, err := r.ApplyRecorded(context.Background(), u, store, v, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Disabled = true
+	if _, _, err := r.ApplyRecorded(context.Background(), u, store, "app:v3", now); err != nil {
+		t.Fatal(err)
+	}
+
+	got := []updater.Decision{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var b json.RawMessage
+		if err := dec.Decode(&b); err != nil {
+			t.Fatal(err)
+		}
+		var d updater.Decision
+		if err := updater.Decode(b, &d); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, d)
+	}
+	base := updater.Decision{Time: now, Repo: "my-org/frontend-deploy", Branch: "main", Filename: "deployment.yaml", Key: "spec.image"}
+	applied, deduped, disabled := base, base, base
+	applied.Reason, applied.State, applied.PullRequest = updater.DecisionApplied, "pull-request-created", 1
+	applied.Time = got[0].Time
+	deduped.Reason, deduped.Rule, deduped.Detail = updater.DecisionDeduplicated, "frontend", "the value was applied at 2024-01-31T12:00:00Z"
+	disabled.Reason, disabled.Rule = updater.DecisionDisabled, "frontend"
+	if diff := cmp.Diff([]updater.Decision{applied, deduped, disabled}, got); diff != "" {
+		t.Fatalf("incorrect decisions:\n%s", diff)
+	}
+}
+
+func TestHistoryRetainsRecentValues(t *testing.T) {
+	h := &History{Rule: "frontend"}
+
+	for i := 0; i < maxHistory+5; i++ {
+		h.record(AppliedValue{Value: fmt.Sprint(i)})
+	}
+
+	if l := len(h.Applied); l != maxHistory {
+		t.Fatalf("got %d applied values, want %d", l, maxHistory)
+	}
+	if v := h.Applied[0].Value; v != "5" {
+		t.Fatalf("got oldest value %q, want 5", v)
+	}
+}
+
+func TestFileHistoryStore(t *testing.T) {
+	store := makeHistoryStore(t)
+	h := &History{Rule: "frontend/image", Applied: []AppliedValue{{Value: "app:v2\n", State: "committed", Commit: "980a0d5f19a64b4b30a87d4206aade58726b60e3", AppliedAt: time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)}}}
+
+	if err := store.Save(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := store.Load(context.Background(), "frontend/image")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(h, loaded); diff != "" {
+		t.Fatalf("incorrect history:\n%s", diff)
+	}
+	empty, err := store.Load(context.Background(), "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&History{Rule: "backend"}, empty); diff != "" {
+		t.Fatalf("incorrect history:\n%s", diff)
+	}
+}
+
+func makeHistoryRule() Rule {
+	return Rule{
+		Name:               "frontend",
+		Repo:               "my-org/frontend-deploy",
+		Branch:             "main",
+		File:               "deployment.yaml",
+		Key:                "spec.image",
+		BranchGenerateName: "update-image-",
+		CommitMessage:      "Update the image",
+		PullRequest:        PullRequest{Title: "Update the image"},
+	}
+}
+
+type stubNameGenerator struct {
+	name string
+}
+
+func (s stubNameGenerator) PrefixedName(p string) string {
+	return p + s.name
+}
+
+func newHistoryUpdater(m *mock.MockClient) *updater.Updater {
+	return updater.New(zap.New(), m, updater.NameGenerator(stubNameGenerator{"a"}))
+}
+
+func makeHistoryClient(t *testing.T) *mock.MockClient {
+	m := mock.New(t)
+	m.AddFileContents("my-org/frontend-deploy", "deployment.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	m.AddBranchHead("my-org/frontend-deploy", "main", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	return m
+}
+
+func makeHistoryStore(t *testing.T) *FileHistoryStore {
+	dir, err := ioutil.TempDir("", "history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewFileHistoryStore(dir)
+}
@@ -0,0 +1,91 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LookupFunc looks up the value of a variable, returning false if it's not
+// set, os.LookupEnv is a LookupFunc.
+type LookupFunc func(name string) (string, bool)
+
+// Interpolate replaces the variable references in the string with their
+// values.
+//
+//	${VAR}            the value of VAR, which must be set
+//	${VAR:-default}   the value of VAR, or default if VAR is unset or empty
+//	${VAR:?message}   the value of VAR, or an error with the message if VAR is
+//	                  unset or empty
+//	$$                a literal $
+func Interpolate(s string, lookup LookupFunc) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch s[i+1] {
+		case '$':
+			b.WriteByte('$')
+			i++
+			continue
+		case '{':
+		default:
+			b.WriteByte(s[i])
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end == -1 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		v, err := expand(s[i+2:i+end], lookup)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+		i += end
+	}
+	return b.String(), nil
+}
+
+func expand(ref string, lookup LookupFunc) (string, error) {
+	name, op, arg := ref, "", ""
+	if i := strings.Index(ref, ":"); i != -1 && i+1 < len(ref) && (ref[i+1] == '-' || ref[i+1] == '?') {
+		name, op, arg = ref[:i], ref[i:i+2], ref[i+2:]
+	}
+	if !isVariableName(name) {
+		return "", fmt.Errorf("invalid variable name %q", name)
+	}
+	v, ok := lookup(name)
+	switch op {
+	case ":-":
+		if v == "" {
+			return arg, nil
+		}
+	case ":?":
+		if v == "" {
+			if arg == "" {
+				arg = "is required"
+			}
+			return "", fmt.Errorf("%s %s", name, arg)
+		}
+	default:
+		if !ok {
+			return "", fmt.Errorf("%s is not set", name)
+		}
+	}
+	return v, nil
+}
+
+func isVariableName(s string) bool {
+	if s == "" || s[0] >= '0' && s[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestInterpolate(t *testing.T) {
+	interpolateTests := []struct {
+		s    string
+		want string
+	}{
+		{"no variables", "no variables"},
+		{"${ORG}/deploy", "my-org/deploy"},
+		{"${ORG}-${CLUSTER}", "my-org-staging"},
+		{"${MISSING:-default}", "default"},
+		{"${EMPTY:-default}", "default"},
+		{"${ORG:-default}", "my-org"},
+		{"${EMPTY}", ""},
+		{"${ORG:?must be set}", "my-org"},
+		{"$$ORG costs $5", "$ORG costs $5"},
+		{"trailing $", "trailing $"},
+		{"${MISSING:-}", ""},
+	}
+
+	for _, tt := range interpolateTests {
+		t.Run(tt.s, func(rt *testing.T) {
+			got, err := Interpolate(tt.s, testLookup)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if got != tt.want {
+				rt.Errorf("Interpolate(%q) got %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	interpolateTests := []struct {
+		s       string
+		wantErr string
+	}{
+		{"${MISSING}", "MISSING is not set"},
+		{"${MISSING:?the cluster is required}", "MISSING the cluster is required"},
+		{"${EMPTY:?}", "EMPTY is required"},
+		{"${ORG", "unterminated variable reference"},
+		{"${1ORG}", `invalid variable name "1ORG"`},
+		{"${}", `invalid variable name ""`},
+	}
+
+	for _, tt := range interpolateTests {
+		t.Run(tt.s, func(rt *testing.T) {
+			_, err := Interpolate(tt.s, testLookup)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("got error %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func testLookup(name string) (string, bool) {
+	v, ok := map[string]string{
+		"ORG":     "my-org",
+		"CLUSTER": "staging",
+		"EMPTY":   "",
+	}[name]
+	return v, ok
+}
@@ -0,0 +1,131 @@
+package rules
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/formats"
+	"github.com/agill17/pkg/updater"
+)
+
+// LintProblem is a problem found when checking a rule, or sync, against its
+// repo.
+type LintProblem struct {
+	Rule    string
+	Message string
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("%s: %s", p.Rule, p.Message)
+}
+
+// Lint checks each of the rules against its repo, without making changes, the
+// repo must be accessible with push permission, the file must exist on the
+// branch, or for a glob, at least one file must match, and the key must have a
+// value in each file.
+//
+// Disabled rules are not checked, and only the repo of a rule with a Variable
+// is checked.
+//
+// Each sync's repo must be accessible with push permission, and its upstream
+// branch, or latest release, must exist.
+//
+// This catches rules that no longer match the structure of the repos before
+// they fail when they're applied.
+func Lint(ctx context.Context, c client.GitClient, f *File) []LintProblem {
+	problems := []LintProblem{}
+	access := map[string]string{}
+	checkRepo := func(repo string) string {
+		problem, ok := access[repo]
+		if !ok {
+			problem = checkAccess(ctx, c, repo)
+			access[repo] = problem
+		}
+		return problem
+	}
+	for _, r := range f.Rules {
+		if r.Disabled {
+			continue
+		}
+		if problem := checkRepo(r.Repo); problem != "" {
+			problems = append(problems, LintProblem{Rule: r.Name, Message: problem})
+			continue
+		}
+		if r.Variable != "" {
+			continue
+		}
+		if problem := checkKey(ctx, c, r.Repo, r.Branch, r.File, r.Format, r.Key); problem != "" {
+			problems = append(problems, LintProblem{Rule: r.Name, Message: problem})
+			continue
+		}
+		if s := r.Secondary; s != nil {
+			if problem := checkKey(ctx, c, r.Repo, r.Branch, s.File, r.Format, s.key(r.Key)); problem != "" {
+				problems = append(problems, LintProblem{Rule: r.Name, Message: "secondary " + problem})
+			}
+		}
+	}
+	for _, s := range f.Syncs {
+		if problem := checkRepo(s.Repo); problem != "" {
+			problems = append(problems, LintProblem{Rule: s.Name, Message: problem})
+			continue
+		}
+		if problem := checkUpstream(ctx, c, s.Upstream); problem != "" {
+			problems = append(problems, LintProblem{Rule: s.Name, Message: problem})
+		}
+	}
+	return problems
+}
+
+func checkAccess(ctx context.Context, c client.GitClient, repo string) string {
+	perm, err := c.GetRepoPermissions(ctx, repo)
+	if err != nil {
+		return fmt.Sprintf("failed to access repo %s: %s", repo, err)
+	}
+	if perm == nil || !perm.Push {
+		return fmt.Sprintf("no push permission for repo %s", repo)
+	}
+	return ""
+}
+
+func checkKey(ctx context.Context, c client.GitClient, repo, branch, filename, format, key string) string {
+	if format == "" {
+		format = "yaml"
+	}
+	f, err := formats.Lookup(format)
+	if err != nil {
+		return err.Error()
+	}
+	files, err := updater.GlobFiles(ctx, c, repo, branch, filename)
+	if err != nil {
+		return fmt.Sprintf("failed to find files matching %s in branch %s: %s", filename, branch, err)
+	}
+	for _, file := range files {
+		current, err := c.GetFile(ctx, repo, branch, file)
+		if err != nil {
+			return fmt.Sprintf("failed to get file %s from branch %s: %s", file, branch, err)
+		}
+		_, err = f.Get(current.Data, key)
+		if errors.Is(err, formats.ErrKeyNotFound) {
+			return fmt.Sprintf("key %s has no value in file %s", key, file)
+		}
+		if err != nil {
+			return fmt.Sprintf("failed to parse file %s: %s", file, err)
+		}
+	}
+	return ""
+}
+
+func checkUpstream(ctx context.Context, c client.GitClient, u Upstream) string {
+	if u.LatestRelease {
+		if _, err := c.GetLatestRelease(ctx, u.Repo); err != nil {
+			return fmt.Sprintf("failed to get the latest release of upstream repo %s: %s", u.Repo, err)
+		}
+		return ""
+	}
+	if _, err := c.ResolveRef(ctx, u.Repo, u.Branch); err != nil {
+		return fmt.Sprintf("failed to find branch %s in upstream repo %s: %s", u.Branch, u.Repo, err)
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package rules
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestLint(t *testing.T) {
+	m := mock.New(t)
+	m.AddRepoPermissions("my-org/frontend", &scm.Perm{Pull: true, Push: true})
+	m.AddRepoPermissions("my-org/readonly", &scm.Perm{Pull: true})
+	m.AddFileContents("my-org/frontend", "deployment.yaml", "main", []byte("spec:\n  replicas: 1\n  image: app:v1\n"))
+	m.AddFileContents("my-org/frontend", "broken.yaml", "main", []byte("spec: [\n"))
+	m.AddFileContents("my-org/frontend", "environments/production/values.yaml", "main", []byte("image: app:v1\n"))
+	m.AddFileContents("my-org/frontend", "environments/staging/values.yaml", "main", []byte("tag: v1\n"))
+	m.AddFileContents("my-org/frontend", "package.json", "main", []byte(`{"version": "1.0.0"}`))
+	f := &File{
+		Rules: []Rule{
+			{Name: "image", Repo: "my-org/frontend", Branch: "main", File: "deployment.yaml", Key: "spec.image"},
+			{Name: "replicas", Repo: "my-org/frontend", Branch: "main", File: "deployment.yaml", Key: "spec.replicas"},
+			{Name: "renamed-key", Repo: "my-org/frontend", Branch: "main", File: "deployment.yaml", Key: "spec.template.image"},
+			{Name: "moved-file", Repo: "my-org/frontend", Branch: "main", File: "app.yaml", Key: "spec.image"},
+			{Name: "other-branch", Repo: "my-org/frontend", Branch: "release", File: "deployment.yaml", Key: "spec.image"},
+			{Name: "broken-file", Repo: "my-org/frontend", Branch: "main", File: "broken.yaml", Key: "spec"},
+			{Name: "environments", Repo: "my-org/frontend", Branch: "main", File: "environments/*/values.yaml", Key: "image"},
+			{Name: "no-environments", Repo: "my-org/frontend", Branch: "main", File: "environments/*/app.yaml", Key: "image"},
+			{Name: "json-version", Repo: "my-org/frontend", Branch: "main", File: "package.json", Format: "json", Key: "version"},
+			{Name: "json-name", Repo: "my-org/frontend", Branch: "main", File: "package.json", Format: "json", Key: "name"},
+			{Name: "unknown-format", Repo: "my-org/frontend", Branch: "main", File: "package.cue", Format: "cue", Key: "version"},
+			{Name: "readonly", Repo: "my-org/readonly", Branch: "main", File: "deployment.yaml", Key: "spec.image"},
+			{Name: "missing-repo", Repo: "my-org/missing", Branch: "main", File: "deployment.yaml", Key: "spec.image"},
+			{Name: "disabled", Repo: "my-org/decommissioned", Branch: "main", File: "deployment.yaml", Key: "spec.image", Disabled: true},
+			{Name: "variable", Repo: "my-org/frontend", Variable: "IMAGE_TAG"},
+			{Name: "readonly-variable", Repo: "my-org/readonly", Variable: "IMAGE_TAG"},
+		},
+	}
+
+	problems := Lint(context.Background(), m, f)
+
+	want := []LintProblem{
+		{Rule: "renamed-key", Message: "key spec.template.image has no value in file deployment.yaml"},
+		{Rule: "moved-file", Message: "failed to get file app.yaml from branch main: not found"},
+		{Rule: "other-branch", Message: "failed to get file deployment.yaml from branch release: not found"},
+		{Rule: "broken-file", Message: "failed to parse file broken.yaml: yaml: line 1: did not find expected node content"},
+		{Rule: "environments", Message: "key image has no value in file environments/staging/values.yaml"},
+		{Rule: "no-environments", Message: "failed to find files matching environments/*/app.yaml in branch main: no files in my-org/frontend match environments/*/app.yaml"},
+		{Rule: "json-name", Message: "key name has no value in file package.json"},
+		{Rule: "unknown-format", Message: `unknown format "cue"`},
+		{Rule: "readonly", Message: "no push permission for repo my-org/readonly"},
+		{Rule: "missing-repo", Message: "failed to access repo my-org/missing: not found"},
+		{Rule: "readonly-variable", Message: "no push permission for repo my-org/readonly"},
+	}
+	if diff := cmp.Diff(want, problems); diff != "" {
+		t.Fatalf("incorrect problems:\n%s", diff)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestLintNoProblems(t *testing.T) {
+	m := mock.New(t)
+	m.AddRepoPermissions("my-org/frontend", &scm.Perm{Pull: true, Push: true})
+	m.AddFileContents("my-org/frontend", "deployment.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	f := &File{Rules: []Rule{{Name: "image", Repo: "my-org/frontend", Branch: "main", File: "deployment.yaml", Key: "spec.image"}}}
+
+	if problems := Lint(context.Background(), m, f); len(problems) != 0 {
+		t.Fatalf("got problems %v", problems)
+	}
+}
+
+func TestLintSyncs(t *testing.T) {
+	m := mock.New(t)
+	m.AddRepoPermissions("my-org/frontend", &scm.Perm{Pull: true, Push: true})
+	m.AddRepoPermissions("my-org/readonly", &scm.Perm{Pull: true})
+	m.AddLatestRelease("upstream-org/charts", "v1.0.0")
+	m.AddRef("upstream-org/charts", "main", "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	f := &File{
+		Syncs: []Sync{
+			{Name: "release", Repo: "my-org/frontend", Upstream: Upstream{Repo: "upstream-org/charts", LatestRelease: true}},
+			{Name: "branch", Repo: "my-org/frontend", Upstream: Upstream{Repo: "upstream-org/charts", Branch: "main"}},
+			{Name: "no-releases", Repo: "my-org/frontend", Upstream: Upstream{Repo: "upstream-org/unreleased", LatestRelease: true}},
+			{Name: "missing-branch", Repo: "my-org/frontend", Upstream: Upstream{Repo: "upstream-org/charts", Branch: "master"}},
+			{Name: "readonly", Repo: "my-org/readonly", Upstream: Upstream{Repo: "upstream-org/charts", Branch: "main"}},
+		},
+	}
+
+	problems := Lint(context.Background(), m, f)
+
+	want := []LintProblem{
+		{Rule: "no-releases", Message: "failed to get the latest release of upstream repo upstream-org/unreleased: release not found: (404)"},
+		{Rule: "missing-branch", Message: "failed to find branch master in upstream repo upstream-org/charts: ref not found: (404)"},
+		{Rule: "readonly", Message: "no push permission for repo my-org/readonly"},
+	}
+	if diff := cmp.Diff(want, problems); diff != "" {
+		t.Fatalf("incorrect problems:\n%s", diff)
+	}
+}
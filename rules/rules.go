@@ -0,0 +1,288 @@
+// Package rules loads declarative update rules from YAML files.
+//
+//	token: ${GITHUB_TOKEN}
+//	rules:
+//	  - name: frontend-image
+//	    repo: ${ORG:-my-org}/frontend-deploy
+//	    branch: main
+//	    file: deployment.yaml
+//	    key: spec.template.spec.containers.0.image
+//	    branchGenerateName: update-image-
+//	    pullRequest:
+//	      title: Update the frontend image
+//	      labels: [automated, image-update]
+//	      teamReviewers: [my-org/frontend]
+//	    secondary:
+//	      file: overlays/canary/deployment.yaml
+//	      delay: 24h
+//	    postActions:
+//	      - comment: Please check the canary before merging
+//	  - name: frontend-tag
+//	    repo: ${ORG:-my-org}/frontend-deploy
+//	    variable: IMAGE_TAG
+//	syncs:
+//	  - name: vendored-chart
+//	    repo: my-org/frontend-deploy
+//	    branch: main
+//	    path: vendor/chart
+//	    stateFile: vendor/chart.sync.yaml
+//	    branchGenerateName: sync-chart-
+//	    upstream:
+//	      repo: upstream-org/charts
+//	      path: charts/frontend
+//	      directory: true
+//	      latestRelease: true
+//	      verify:
+//	        checksums: charts/SHA256SUMS
+//	        signature: charts/SHA256SUMS.asc
+//	        keyring: ${UPSTREAM_KEYS}
+package rules
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/agill17/pkg/updater"
+)
+
+// File is a set of rules, and the configuration shared between them.
+type File struct {
+	// Token is used to authenticate with the git provider, it's usually
+	// interpolated from the environment.
+	Token string `yaml:"token,omitempty"`
+	Rules []Rule `yaml:"rules"`
+	Syncs []Sync `yaml:"syncs,omitempty"`
+}
+
+// Rule declares the update of a key in a file, which is YAML unless a Format
+// from the formats registry is set, and the optional PullRequest for the
+// change, the new value is provided when the rule is applied.
+//
+// A Disabled rule is not applied or linted, its History is kept until it's
+// enabled again.
+//
+// A rule with a Variable updates the CI variable of the repo to the new value,
+// and has no Branch, File or Key.
+type Rule struct {
+	Name               string       `yaml:"name"`
+	Repo               string       `yaml:"repo"`
+	Branch             string       `yaml:"branch"`
+	BaseBranch         string       `yaml:"baseBranch,omitempty"` // e.g. release-1.2, the PullRequest is opened against this rather than the Branch
+	Fork               string       `yaml:"fork,omitempty"`       // e.g. my-bot/frontend-deploy, the branch is pushed to this fork
+	File               string       `yaml:"file"`
+	Key                string       `yaml:"key"`
+	Format             string       `yaml:"format,omitempty"` // e.g. json, the Secondary file has the same format
+	BranchGenerateName string       `yaml:"branchGenerateName,omitempty"`
+	CommitMessage      string       `yaml:"commitMessage,omitempty"`
+	PullRequest        PullRequest  `yaml:"pullRequest,omitempty"`
+	Source             *Source      `yaml:"source,omitempty"`
+	SecretKeys         []string     `yaml:"secretKeys,omitempty"` // Regular expressions matching keys with secret values
+	Secondary          *Secondary   `yaml:"secondary,omitempty"`
+	PostActions        []PostAction `yaml:"postActions,omitempty"`
+	Variable           string       `yaml:"variable,omitempty"` // e.g. IMAGE_TAG, the repo's CI variable is updated rather than a file
+	Disabled           bool         `yaml:"disabled,omitempty"`
+}
+
+// Source configures a Rule to read the content from a file in another repo,
+// e.g. a template, the result is committed to the Rule's file.
+type Source struct {
+	Repo   string `yaml:"repo"`
+	Branch string `yaml:"branch"`
+	File   string `yaml:"file"`
+}
+
+// Sync declares mirroring a file, or directory, from an upstream repo, a
+// PullRequest is opened when the upstream content changes.
+type Sync struct {
+	Name               string      `yaml:"name"`
+	Repo               string      `yaml:"repo"`
+	Branch             string      `yaml:"branch"`
+	Path               string      `yaml:"path"`
+	StateFile          string      `yaml:"stateFile"`
+	BranchGenerateName string      `yaml:"branchGenerateName,omitempty"`
+	CommitMessage      string      `yaml:"commitMessage,omitempty"`
+	PullRequest        PullRequest `yaml:"pullRequest,omitempty"`
+	Upstream           Upstream    `yaml:"upstream"`
+}
+
+// Upstream is the file, or directory, that a Sync mirrors, from the branch, or
+// the latest release.
+type Upstream struct {
+	Repo          string  `yaml:"repo"`
+	Branch        string  `yaml:"branch,omitempty"`
+	Path          string  `yaml:"path"`
+	Directory     bool    `yaml:"directory,omitempty"`
+	LatestRelease bool    `yaml:"latestRelease,omitempty"`
+	Verify        *Verify `yaml:"verify,omitempty"`
+}
+
+// Verify configures checking the upstream content against a sha256sum file,
+// and a detached signature, before it's committed.
+type Verify struct {
+	Checksums string `yaml:"checksums,omitempty"`
+	Signature string `yaml:"signature,omitempty"`
+	Keyring   string `yaml:"keyring,omitempty"`
+}
+
+// PullRequest configures the PullRequest opened for a Rule.
+type PullRequest struct {
+	Title         string   `yaml:"title,omitempty"`
+	Body          string   `yaml:"body,omitempty"`
+	Labels        []string `yaml:"labels,omitempty"`
+	Reviewers     []string `yaml:"reviewers,omitempty"`
+	TeamReviewers []string `yaml:"teamReviewers,omitempty"`
+	Assignees     []string `yaml:"assignees,omitempty"`
+	// CodeOwners requests reviews from the owners of the changed files in the
+	// repo's CODEOWNERS file.
+	CodeOwners bool `yaml:"codeOwners,omitempty"`
+	// Comment is posted on the PullRequest once it's created, like the Title
+	// and Body, it's a template.
+	Comment string `yaml:"comment,omitempty"`
+	// Draft opens the PullRequest as a draft, e.g. for production, so that
+	// it must be marked ready for review.
+	Draft bool `yaml:"draft,omitempty"`
+}
+
+// Input returns the updater Input to apply the rule with the new value, even
+// if the rule is Disabled, Apply checks it.
+func (r Rule) Input(newValue interface{}) *updater.Input {
+	var source *updater.SourceFile
+	if r.Source != nil {
+		source = &updater.SourceFile{Repo: r.Source.Repo, Branch: r.Source.Branch, Filename: r.Source.File}
+	}
+	return &updater.Input{
+		Repo:               r.Repo,
+		Filename:           r.File,
+		Branch:             r.Branch,
+		BaseBranch:         r.BaseBranch,
+		Fork:               r.Fork,
+		Key:                r.Key,
+		Format:             r.Format,
+		NewValue:           newValue,
+		BranchGenerateName: r.BranchGenerateName,
+		CommitMessage:      r.CommitMessage,
+		PullRequest: updater.PullRequestInput{
+			Title:         r.PullRequest.Title,
+			Body:          r.PullRequest.Body,
+			Labels:        r.PullRequest.Labels,
+			Reviewers:     r.PullRequest.Reviewers,
+			TeamReviewers: r.PullRequest.TeamReviewers,
+			Assignees:     r.PullRequest.Assignees,
+			Draft:         r.PullRequest.Draft,
+			Comment:       r.PullRequest.Comment,
+			CodeOwners:    r.PullRequest.CodeOwners,
+		},
+		Source:      source,
+		SecretKeys:  r.SecretKeys,
+		Files:       r.Secondary.files(r.Key, newValue),
+		PostActions: postActions(r.PostActions),
+	}
+}
+
+// Input returns the updater SyncInput to apply the sync.
+func (s Sync) Input() *updater.SyncInput {
+	var verification *updater.Verification
+	if v := s.Upstream.Verify; v != nil {
+		verification = &updater.Verification{Checksums: v.Checksums, Signature: v.Signature, Keyring: v.Keyring}
+	}
+	return &updater.SyncInput{
+		Upstream: updater.Upstream{
+			Repo:          s.Upstream.Repo,
+			Branch:        s.Upstream.Branch,
+			Path:          s.Upstream.Path,
+			Directory:     s.Upstream.Directory,
+			LatestRelease: s.Upstream.LatestRelease,
+			Verification:  verification,
+		},
+		Repo:               s.Repo,
+		Branch:             s.Branch,
+		Path:               s.Path,
+		StateFile:          s.StateFile,
+		BranchGenerateName: s.BranchGenerateName,
+		CommitMessage:      s.CommitMessage,
+		PullRequest: updater.PullRequestInput{
+			Title:         s.PullRequest.Title,
+			Body:          s.PullRequest.Body,
+			Labels:        s.PullRequest.Labels,
+			Reviewers:     s.PullRequest.Reviewers,
+			TeamReviewers: s.PullRequest.TeamReviewers,
+			Assignees:     s.PullRequest.Assignees,
+			Draft:         s.PullRequest.Draft,
+			Comment:       s.PullRequest.Comment,
+			CodeOwners:    s.PullRequest.CodeOwners,
+		},
+	}
+}
+
+// LoadFile reads and parses a rules file, interpolating variables from the
+// environment.
+func LoadFile(filename string) (*File, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the rules file: %w", err)
+	}
+	f, err := Load(b, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", filename, err)
+	}
+	return f, nil
+}
+
+// Load parses the rules, interpolating the variables in values with the
+// lookup, see Interpolate for the syntax.
+//
+// The variables are interpolated after parsing, so a value can't change the
+// structure of the file, all the missing variables are reported together, and
+// then the interpolated rules are validated.
+func Load(b []byte, lookup LookupFunc) (*File, error) {
+	root, err := parseRules(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := interpolateNode(root, lookup); err != nil {
+		return nil, err
+	}
+	if err := validateNode(root); err != nil {
+		return nil, err
+	}
+	f := &File{}
+	if err := root.Decode(f); err != nil {
+		return nil, fmt.Errorf("failed to parse the rules: %w", err)
+	}
+	return f, nil
+}
+
+// interpolateNode interpolates the scalar values in the tree, mapping keys are
+// left untouched.
+func interpolateNode(root *yaml3.Node, lookup LookupFunc) error {
+	errs := []string{}
+	var walk func(n *yaml3.Node)
+	walk = func(n *yaml3.Node) {
+		switch n.Kind {
+		case yaml3.ScalarNode:
+			v, err := Interpolate(n.Value, lookup)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("line %d: %s", n.Line, err))
+				return
+			}
+			n.Value = v
+		case yaml3.MappingNode:
+			for i := 1; i < len(n.Content); i += 2 {
+				walk(n.Content[i])
+			}
+		default:
+			for _, c := range n.Content {
+				walk(c)
+			}
+		}
+	}
+	walk(root)
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to interpolate the rules: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
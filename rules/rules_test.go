@@ -0,0 +1,275 @@
+package rules
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+	"github.com/agill17/pkg/updater"
+)
+
+const testRules = `token: ${TOKEN}
+rules:
+  - name: frontend-image
+    repo: ${ORG}/frontend-deploy
+    branch: ${BRANCH:-main}
+    file: deployment.yaml
+    key: spec.template.spec.containers.0.image
+    branchGenerateName: update-image-
+    pullRequest:
+      title: Update the ${CLUSTER} frontend image
+`
+
+func TestLoad(t *testing.T) {
+	f, err := Load([]byte(testRules), func(name string) (string, bool) {
+		v, ok := map[string]string{"TOKEN": "abc123", "ORG": "my-org", "CLUSTER": "staging"}[name]
+		return v, ok
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &File{
+		Token: "abc123",
+		Rules: []Rule{
+			{
+				Name:               "frontend-image",
+				Repo:               "my-org/frontend-deploy",
+				Branch:             "main",
+				File:               "deployment.yaml",
+				Key:                "spec.template.spec.containers.0.image",
+				BranchGenerateName: "update-image-",
+				PullRequest:        PullRequest{Title: "Update the staging frontend image"},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, f); diff != "" {
+		t.Fatalf("failed to load:\n%s", diff)
+	}
+}
+
+func TestLoadValuesDoNotChangeStructure(t *testing.T) {
+	f, err := Load([]byte("token: ${TOKEN}\nrules: []\n"), func(string) (string, bool) {
+		return "abc: def\nrules: [1]", true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Token != "abc: def\nrules: [1]" {
+		t.Fatalf("got token %q", f.Token)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	_, err := Load([]byte(testRules), func(name string) (string, bool) {
+		return "", name == "ORG"
+	})
+
+	if !test.MatchError(t, `failed to interpolate the rules: line 1: TOKEN is not set, line 10: CLUSTER is not set`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	filename := filepath.Join(dir, "rules.yaml")
+	if err := ioutil.WriteFile(filename, []byte(testRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{"TOKEN": "abc123", "ORG": "my-org", "CLUSTER": "staging", "BRANCH": "release"} {
+		setenv(t, k, v)
+	}
+
+	f, err := LoadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Rules[0].Branch != "release" {
+		t.Fatalf("got branch %q, want release", f.Rules[0].Branch)
+	}
+}
+
+func TestLoadFileMissingVariable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		os.RemoveAll(dir)
+	})
+	filename := filepath.Join(dir, "rules.yaml")
+	if err := ioutil.WriteFile(filename, []byte("token: ${TEST_RULES_UNSET_TOKEN:?is needed to open PullRequests}\nrules: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = LoadFile(filename)
+
+	if !test.MatchError(t, `failed to load .*rules.yaml: .*TEST_RULES_UNSET_TOKEN is needed to open PullRequests`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestRuleInputWithSource(t *testing.T) {
+	r := Rule{
+		Repo:   "my-org/frontend-deploy",
+		Branch: "main",
+		File:   "values.yaml",
+		Key:    "image.tag",
+		Source: &Source{Repo: "my-org/charts", Branch: "release", File: "frontend/values.yaml"},
+	}
+
+	want := &updater.SourceFile{Repo: "my-org/charts", Branch: "release", Filename: "frontend/values.yaml"}
+	if diff := cmp.Diff(want, r.Input("v2").Source); diff != "" {
+		t.Fatalf("incorrect source:\n%s", diff)
+	}
+}
+
+func TestRuleInputWithFormat(t *testing.T) {
+	r := Rule{
+		Repo:      "my-org/frontend",
+		Branch:    "main",
+		File:      "package.json",
+		Key:       "version",
+		Format:    "json",
+		Secondary: &Secondary{File: "packages/app/package.json"},
+	}
+
+	input := r.Input("1.1.0")
+
+	if input.Format != "json" {
+		t.Fatalf("got Format %q, want json", input.Format)
+	}
+	want := []updater.FileChange{{Filename: "packages/app/package.json", Key: "version", NewValue: "1.1.0"}}
+	if diff := cmp.Diff(want, input.Files); diff != "" {
+		t.Fatalf("incorrect files:\n%s", diff)
+	}
+}
+
+func TestRuleInput(t *testing.T) {
+	r := Rule{
+		Repo:               "my-org/frontend-deploy",
+		Branch:             "main",
+		BaseBranch:         "release-1.2",
+		Fork:               "my-bot/frontend-deploy",
+		File:               "deployment.yaml",
+		Key:                "spec.replicas",
+		BranchGenerateName: "update-replicas-",
+		CommitMessage:      "Scale up",
+		PullRequest:        PullRequest{Title: "Scale up", Body: "More replicas", Labels: []string{"automated"}, TeamReviewers: []string{"my-org/frontend"}, Comment: "Check the dashboards", Draft: true},
+		SecretKeys:         []string{`\.password$`},
+	}
+
+	want := &updater.Input{
+		Repo:               "my-org/frontend-deploy",
+		Filename:           "deployment.yaml",
+		Branch:             "main",
+		BaseBranch:         "release-1.2",
+		Fork:               "my-bot/frontend-deploy",
+		Key:                "spec.replicas",
+		NewValue:           3,
+		BranchGenerateName: "update-replicas-",
+		CommitMessage:      "Scale up",
+		PullRequest:        updater.PullRequestInput{Title: "Scale up", Body: "More replicas", Labels: []string{"automated"}, TeamReviewers: []string{"my-org/frontend"}, Comment: "Check the dashboards", Draft: true},
+		SecretKeys:         []string{`\.password$`},
+	}
+	if diff := cmp.Diff(want, r.Input(3)); diff != "" {
+		t.Fatalf("incorrect input:\n%s", diff)
+	}
+}
+
+func TestLoadSyncs(t *testing.T) {
+	f, err := Load([]byte(`rules: []
+syncs:
+  - name: vendored-chart
+    repo: my-org/frontend-deploy
+    branch: main
+    path: vendor/chart
+    stateFile: vendor/chart.sync.yaml
+    upstream:
+      repo: upstream-org/charts
+      path: charts/${CLUSTER}
+      directory: true
+      latestRelease: true
+`), testLookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Sync{
+		{
+			Name:      "vendored-chart",
+			Repo:      "my-org/frontend-deploy",
+			Branch:    "main",
+			Path:      "vendor/chart",
+			StateFile: "vendor/chart.sync.yaml",
+			Upstream:  Upstream{Repo: "upstream-org/charts", Path: "charts/staging", Directory: true, LatestRelease: true},
+		},
+	}
+	if diff := cmp.Diff(want, f.Syncs); diff != "" {
+		t.Fatalf("failed to load:\n%s", diff)
+	}
+}
+
+func TestSyncInput(t *testing.T) {
+	s := Sync{
+		Repo:               "my-org/frontend-deploy",
+		Branch:             "main",
+		Path:               "vendor/values.yaml",
+		StateFile:          "vendor/values.sync.yaml",
+		BranchGenerateName: "sync-values-",
+		CommitMessage:      "Sync the values",
+		PullRequest:        PullRequest{Title: "Sync the values", Body: "From upstream", Labels: []string{"sync"}, Reviewers: []string{"octocat"}, Assignees: []string{"hubot"}, CodeOwners: true},
+		Upstream: Upstream{
+			Repo:   "upstream-org/charts",
+			Branch: "main",
+			Path:   "charts/frontend/values.yaml",
+			Verify: &Verify{Signature: "charts/frontend/values.yaml.asc", Keyring: "keys"},
+		},
+	}
+
+	want := &updater.SyncInput{
+		Upstream: updater.Upstream{
+			Repo:         "upstream-org/charts",
+			Branch:       "main",
+			Path:         "charts/frontend/values.yaml",
+			Verification: &updater.Verification{Signature: "charts/frontend/values.yaml.asc", Keyring: "keys"},
+		},
+		Repo:               "my-org/frontend-deploy",
+		Branch:             "main",
+		Path:               "vendor/values.yaml",
+		StateFile:          "vendor/values.sync.yaml",
+		BranchGenerateName: "sync-values-",
+		CommitMessage:      "Sync the values",
+		PullRequest:        updater.PullRequestInput{Title: "Sync the values", Body: "From upstream", Labels: []string{"sync"}, Reviewers: []string{"octocat"}, Assignees: []string{"hubot"}, CodeOwners: true},
+	}
+	if diff := cmp.Diff(want, s.Input()); diff != "" {
+		t.Fatalf("incorrect input:\n%s", diff)
+	}
+}
+
+// setenv sets the environment variable for the duration of the test.
+func setenv(t *testing.T, k, v string) {
+	old, ok := os.LookupEnv(k)
+	if err := os.Setenv(k, v); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(k, old)
+			return
+		}
+		os.Unsetenv(k)
+	})
+}
@@ -0,0 +1,127 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/updater"
+)
+
+// Secondary is a further target for the new value of a Rule, e.g. a canary or
+// blue/green overlay, with its own Key, or the Rule's.
+//
+// Without a Delay, the secondary file is changed in the same branch and
+// PullRequest as the Rule's file, with a Delay, it's updated separately once
+// the Delay has passed since the change was committed, or its PullRequest was
+// merged.
+type Secondary struct {
+	File  string `yaml:"file"`
+	Key   string `yaml:"key,omitempty"`
+	Delay string `yaml:"delay,omitempty"` // e.g. 24h
+}
+
+// files returns the secondary file to change with the Rule's file, if it's not
+// delayed.
+func (s *Secondary) files(key string, newValue interface{}) []updater.FileChange {
+	if s == nil || s.Delay != "" {
+		return nil
+	}
+	return []updater.FileChange{{Filename: s.File, Key: s.key(key), NewValue: newValue}}
+}
+
+func (s *Secondary) key(key string) string {
+	if s.Key != "" {
+		return s.Key
+	}
+	return key
+}
+
+// DelayedInput returns the updater Input to apply the delayed Secondary of the
+// rule with the new value, and the delay, it returns nil if the rule has no
+// delayed Secondary.
+//
+// The PostActions of the rule can't be persisted, and are not applied to the
+// delayed PullRequest.
+func (r Rule) DelayedInput(newValue interface{}) (*updater.Input, time.Duration, error) {
+	if r.Secondary == nil || r.Secondary.Delay == "" {
+		return nil, 0, nil
+	}
+	delay, err := time.ParseDuration(r.Secondary.Delay)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse the delay of rule %s: %w", r.Name, err)
+	}
+	primary := r
+	primary.Secondary, primary.PostActions = nil, nil
+	input := primary.Input(newValue)
+	input.Filename, input.Key = r.Secondary.File, r.Secondary.key(r.Key)
+	return input, delay, nil
+}
+
+// FollowUp returns the updater FollowUp that applies the delayed Secondary of
+// the rule with the new value, once a PullRequest opened by the rule has been
+// merged, and the Delay has passed since the merge, e.g. with FollowUpMerged
+// from a merge webhook.
+//
+// It returns nil if the rule has no delayed Secondary, or doesn't open
+// PullRequests from branches with a fixed prefix.
+func (r Rule) FollowUp(newValue interface{}) (*updater.FollowUp, error) {
+	_, delay, err := r.DelayedInput(newValue)
+	if err != nil || delay == 0 {
+		return nil, err
+	}
+	prefix := strings.SplitN(r.BranchGenerateName, "{{", 2)[0]
+	if prefix == "" {
+		return nil, nil
+	}
+	return &updater.FollowUp{
+		Repo:         r.Repo,
+		BranchPrefix: prefix,
+		Delay:        delay,
+		Plan: func(*scm.PullRequest) (*updater.Batch, error) {
+			input, _, err := r.DelayedInput(newValue)
+			if err != nil {
+				return nil, err
+			}
+			return &updater.Batch{Inputs: []*updater.Input{input}}, nil
+		},
+	}, nil
+}
+
+// Apply applies the rule with the new value, and schedules the update of its
+// delayed Secondary, if it has one, the Updater needs a Store for delayed
+// updates.
+//
+// The Secondary is only scheduled when the update is Committed, the Delay is
+// measured from the commit. When the update opens a PullRequest, the Secondary
+// follows its merge instead, with the FollowUp, and nothing is scheduled for
+// Skipped or Unchanged updates. A Disabled rule is Skipped, and a rule with a
+// Variable updates the variable.
+func (r Rule) Apply(ctx context.Context, u *updater.Updater, newValue interface{}, now time.Time) (*updater.UpdateResult, *updater.DelayedUpdate, error) {
+	if r.Disabled {
+		return r.skipDisabled(u, now), nil, nil
+	}
+	if r.Variable != "" {
+		result, err := r.applyVariable(ctx, u, newValue)
+		return result, nil, err
+	}
+	delayed, delay, err := r.DelayedInput(newValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := u.Update(ctx, r.Input(newValue))
+	if err != nil {
+		return nil, nil, err
+	}
+	if delayed == nil || result.State != updater.Committed {
+		return result, nil, nil
+	}
+	d, err := u.ScheduleUpdate(ctx, delayed, now.Add(delay))
+	if err != nil {
+		return result, nil, fmt.Errorf("failed to schedule the secondary update of rule %s: %w", r.Name, err)
+	}
+	return result, d, nil
+}
@@ -0,0 +1,295 @@
+package rules
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	"github.com/agill17/pkg/updater"
+)
+
+func TestRuleInputWithSecondary(t *testing.T) {
+	r := Rule{
+		Repo:      "my-org/frontend-deploy",
+		Branch:    "main",
+		File:      "overlays/stable/deployment.yaml",
+		Key:       "spec.image",
+		Secondary: &Secondary{File: "overlays/canary/deployment.yaml"},
+	}
+
+	want := []updater.FileChange{{Filename: "overlays/canary/deployment.yaml", Key: "spec.image", NewValue: "app:v2"}}
+	if diff := cmp.Diff(want, r.Input("app:v2").Files); diff != "" {
+		t.Fatalf("incorrect files:\n%s", diff)
+	}
+}
+
+func TestDelayedInput(t *testing.T) {
+	r := Rule{
+		Name:          "frontend",
+		Repo:          "my-org/frontend-deploy",
+		Branch:        "main",
+		File:          "overlays/canary/deployment.yaml",
+		Key:           "spec.image",
+		CommitMessage: "Update the image",
+		Secondary:     &Secondary{File: "overlays/stable/values.yaml", Key: "image", Delay: "24h"},
+	}
+
+	input, delay, err := r.DelayedInput("app:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if delay != 24*time.Hour {
+		t.Fatalf("got delay %s, want 24h", delay)
+	}
+	want := &updater.Input{
+		Repo:          "my-org/frontend-deploy",
+		Filename:      "overlays/stable/values.yaml",
+		Branch:        "main",
+		Key:           "image",
+		NewValue:      "app:v2",
+		CommitMessage: "Update the image",
+	}
+	if diff := cmp.Diff(want, input); diff != "" {
+		t.Fatalf("incorrect input:\n%s", diff)
+	}
+	if files := r.Input("app:v2").Files; files != nil {
+		t.Fatalf("got files %#v for a delayed secondary", files)
+	}
+}
+
+func TestDelayedInputWithoutDelay(t *testing.T) {
+	r := Rule{Name: "frontend", Secondary: &Secondary{File: "canary.yaml"}}
+
+	input, _, err := r.DelayedInput("app:v2")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input != nil {
+		t.Fatalf("got input %#v, want nil", input)
+	}
+}
+
+func TestDelayedInputWithInvalidDelay(t *testing.T) {
+	r := Rule{Name: "frontend", Secondary: &Secondary{File: "canary.yaml", Delay: "1 day"}}
+
+	_, _, err := r.DelayedInput("app:v2")
+
+	if !test.MatchError(t, "failed to parse the delay of rule frontend", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestApplyWithDelayedSecondary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secondary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	m := mock.New(t)
+	m.AddFileContents("my-org/frontend-deploy", "canary.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	m.AddBranchHead("my-org/frontend-deploy", "main", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	u := updater.New(zap.New(), m, updater.WithStore(updater.NewFileStore(dir)))
+	now := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	r := Rule{
+		Name:          "frontend",
+		Repo:          "my-org/frontend-deploy",
+		Branch:        "main",
+		File:          "canary.yaml",
+		Key:           "spec.image",
+		CommitMessage: "Update the image",
+		Secondary:     &Secondary{File: "stable.yaml", Delay: "24h"},
+	}
+
+	result, delayed, err := r.Apply(context.Background(), u, "app:v2", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != updater.Committed {
+		t.Fatalf("got state %s, want committed", result.State)
+	}
+	if diff := cmp.Diff("spec:\n  image: app:v2\n", string(m.GetUpdatedContents("my-org/frontend-deploy", "canary.yaml", "main"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+	if want := now.Add(24 * time.Hour); !delayed.NotBefore.Equal(want) {
+		t.Fatalf("got NotBefore %s, want %s", delayed.NotBefore, want)
+	}
+	if delayed.Input.Filename != "stable.yaml" {
+		t.Fatalf("got delayed file %q, want stable.yaml", delayed.Input.Filename)
+	}
+}
+
+func TestApplyWithoutStore(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents("my-org/frontend-deploy", "canary.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	m.AddBranchHead("my-org/frontend-deploy", "main", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	r := Rule{
+		Name:          "frontend",
+		Repo:          "my-org/frontend-deploy",
+		Branch:        "main",
+		File:          "canary.yaml",
+		Key:           "spec.image",
+		CommitMessage: "Update the image",
+		Secondary:     &Secondary{File: "stable.yaml", Delay: "24h"},
+	}
+
+	result, _, err := r.Apply(context.Background(), updater.New(zap.New(), m), "app:v2", time.Now())
+
+	if !test.MatchError(t, "failed to schedule the secondary update of rule frontend", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if result == nil || result.State != updater.Committed {
+		t.Fatalf("got result %#v, want the primary update", result)
+	}
+}
+
+func TestApplyWithDisabledRule(t *testing.T) {
+	m := mock.New(t)
+	u := updater.New(zap.New(), m)
+	r := Rule{
+		Name:      "frontend",
+		Repo:      "my-org/frontend-deploy",
+		Branch:    "main",
+		File:      "canary.yaml",
+		Key:       "spec.image",
+		Secondary: &Secondary{File: "stable.yaml", Delay: "24h"},
+		Disabled:  true,
+	}
+
+	result, delayed, err := r.Apply(context.Background(), u, "app:v2", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != updater.Skipped {
+		t.Fatalf("got state %s, want %s", result.State, updater.Skipped)
+	}
+	if delayed != nil {
+		t.Fatalf("got delayed update %#v, want none", delayed)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestApplyWithDelayedSecondaryAndPullRequest(t *testing.T) {
+	applyTests := []struct {
+		name      string
+		current   string
+		wantState updater.UpdateState
+	}{
+		{"pull request", "spec:\n  image: app:v1\n", updater.PullRequestCreated},
+		{"unchanged", "spec:\n  image: app:v2\n", updater.Unchanged},
+	}
+
+	for _, tt := range applyTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			dir, err := ioutil.TempDir("", "secondary")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			rt.Cleanup(func() { os.RemoveAll(dir) })
+			m := mock.New(rt)
+			m.AddFileContents("my-org/frontend-deploy", "canary.yaml", "main", []byte(tt.current))
+			m.AddBranchHead("my-org/frontend-deploy", "main", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			store := updater.NewFileStore(dir)
+			u := updater.New(zap.New(), m, updater.WithStore(store))
+			r := Rule{
+				Name:               "frontend",
+				Repo:               "my-org/frontend-deploy",
+				Branch:             "main",
+				File:               "canary.yaml",
+				Key:                "spec.image",
+				BranchGenerateName: "update-image-",
+				Secondary:          &Secondary{File: "stable.yaml", Delay: "24h"},
+			}
+
+			result, delayed, err := r.Apply(context.Background(), u, "app:v2", time.Now())
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if result.State != tt.wantState {
+				rt.Fatalf("got state %s, want %s", result.State, tt.wantState)
+			}
+			if delayed != nil {
+				rt.Fatalf("got delayed update %#v, want none", delayed)
+			}
+			if stored, err := store.List(context.Background()); err != nil || len(stored) != 0 {
+				rt.Fatalf("got stored updates %#v (%v), want none", stored, err)
+			}
+		})
+	}
+}
+
+func TestFollowUp(t *testing.T) {
+	mergeSHA := "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+	m := mock.New(t)
+	m.AddFileContents("my-org/frontend-deploy", "stable.yaml", "main", []byte("spec:\n  image: app:v1\n"))
+	m.AddBranchHead("my-org/frontend-deploy", "main", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddCommit("my-org/frontend-deploy", &scm.Commit{Sha: mergeSHA, Committer: scm.Signature{Date: time.Now().Add(-25 * time.Hour)}})
+	u := newHistoryUpdater(m)
+	r := Rule{
+		Name:               "frontend",
+		Repo:               "my-org/frontend-deploy",
+		Branch:             "main",
+		File:               "canary.yaml",
+		Key:                "spec.image",
+		BranchGenerateName: "update-image-{{ .Key }}-",
+		Secondary:          &Secondary{File: "stable.yaml", Delay: "24h"},
+	}
+	pr := &scm.PullRequest{
+		Number:   1,
+		Merged:   true,
+		MergeSha: mergeSHA,
+		Base:     scm.PullRequestBranch{Ref: "main", Repo: scm.Repository{FullName: "my-org/frontend-deploy"}},
+		Head:     scm.PullRequestBranch{Ref: "update-image-spec.image-a"},
+	}
+
+	f, err := r.FollowUp("app:v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.BranchPrefix != "update-image-" {
+		t.Fatalf("got branch prefix %q, want update-image-", f.BranchPrefix)
+	}
+	if _, err := u.FollowUpMerged(context.Background(), pr, f); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff("spec:\n  image: app:v2\n", string(m.GetUpdatedContents("my-org/frontend-deploy", "stable.yaml", "update-image-spec.image-a"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+}
+
+func TestFollowUpWithoutPullRequests(t *testing.T) {
+	followUpTests := []struct {
+		name string
+		rule Rule
+	}{
+		{"no secondary", Rule{Name: "frontend", BranchGenerateName: "update-image-"}},
+		{"no delay", Rule{Name: "frontend", BranchGenerateName: "update-image-", Secondary: &Secondary{File: "stable.yaml"}}},
+		{"direct commits", Rule{Name: "frontend", Secondary: &Secondary{File: "stable.yaml", Delay: "24h"}}},
+		{"templated prefix", Rule{Name: "frontend", BranchGenerateName: "{{ .Key }}-", Secondary: &Secondary{File: "stable.yaml", Delay: "24h"}}},
+	}
+
+	for _, tt := range followUpTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			f, err := tt.rule.FollowUp("app:v2")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if f != nil {
+				rt.Fatalf("got follow-up %#v, want none", f)
+			}
+		})
+	}
+}
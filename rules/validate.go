@@ -0,0 +1,330 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema for rule files, it can be used by editors and
+// other tools to check rule files.
+const Schema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "Update rules",
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["rules"],
+  "properties": {
+    "token": {"type": "string"},
+    "rules": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/rule"}
+    },
+    "syncs": {
+      "type": "array",
+      "items": {"$ref": "#/definitions/sync"}
+    }
+  },
+  "definitions": {
+    "rule": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["name", "repo"],
+      "if": {"required": ["variable"]},
+      "else": {"required": ["branch", "file", "key"]},
+      "properties": {
+        "name": {"type": "string", "minLength": 1},
+        "repo": {"type": "string", "pattern": "^[^/]+/.+$"},
+        "branch": {"type": "string", "minLength": 1},
+        "baseBranch": {"type": "string", "minLength": 1},
+        "fork": {"type": "string", "pattern": "^[^/]+/.+$"},
+        "file": {"type": "string", "minLength": 1},
+        "key": {"type": "string", "minLength": 1},
+        "format": {"type": "string", "minLength": 1},
+        "branchGenerateName": {"type": "string"},
+        "commitMessage": {"type": "string"},
+        "pullRequest": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "title": {"type": "string"},
+            "body": {"type": "string"},
+            "labels": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "reviewers": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "teamReviewers": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "assignees": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "codeOwners": {"type": "boolean"},
+            "comment": {"type": "string"},
+            "draft": {"type": "boolean"}
+          }
+        },
+        "source": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["repo", "branch", "file"],
+          "properties": {
+            "repo": {"type": "string", "pattern": "^[^/]+/.+$"},
+            "branch": {"type": "string", "minLength": 1},
+            "file": {"type": "string", "minLength": 1}
+          }
+        },
+        "secretKeys": {
+          "type": "array",
+          "items": {"type": "string", "minLength": 1}
+        },
+        "secondary": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["file"],
+          "properties": {
+            "file": {"type": "string", "minLength": 1},
+            "key": {"type": "string", "minLength": 1},
+            "delay": {"type": "string", "pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"}
+          }
+        },
+        "postActions": {
+          "type": "array",
+          "items": {
+            "type": "object",
+            "additionalProperties": false,
+            "minProperties": 1,
+            "dependencies": {"projectColumn": ["project"]},
+            "properties": {
+              "labels": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+              "reviewers": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+              "teamReviewers": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+              "assignees": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+              "comment": {"type": "string", "minLength": 1},
+              "milestone": {"type": "string", "minLength": 1},
+              "project": {"type": "string", "minLength": 1},
+              "projectColumn": {"type": "string", "minLength": 1},
+              "autoMerge": {"enum": ["merge", "squash", "rebase"]}
+            }
+          }
+        },
+        "variable": {"type": "string", "minLength": 1},
+        "disabled": {"type": "boolean"}
+      }
+    },
+    "sync": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["name", "repo", "branch", "path", "stateFile", "upstream"],
+      "properties": {
+        "name": {"type": "string", "minLength": 1},
+        "repo": {"type": "string", "pattern": "^[^/]+/.+$"},
+        "branch": {"type": "string", "minLength": 1},
+        "path": {"type": "string", "minLength": 1},
+        "stateFile": {"type": "string", "minLength": 1},
+        "branchGenerateName": {"type": "string"},
+        "commitMessage": {"type": "string"},
+        "pullRequest": {
+          "type": "object",
+          "additionalProperties": false,
+          "properties": {
+            "title": {"type": "string"},
+            "body": {"type": "string"},
+            "labels": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "reviewers": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "teamReviewers": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "assignees": {"type": "array", "items": {"type": "string", "minLength": 1}},
+            "codeOwners": {"type": "boolean"},
+            "comment": {"type": "string"},
+            "draft": {"type": "boolean"}
+          }
+        },
+        "upstream": {
+          "type": "object",
+          "additionalProperties": false,
+          "required": ["repo", "path"],
+          "properties": {
+            "repo": {"type": "string", "pattern": "^[^/]+/.+$"},
+            "branch": {"type": "string", "minLength": 1},
+            "path": {"type": "string", "minLength": 1},
+            "directory": {"type": "boolean"},
+            "latestRelease": {"type": "boolean"},
+            "verify": {
+              "type": "object",
+              "additionalProperties": false,
+              "minProperties": 1,
+              "properties": {
+                "checksums": {"type": "string", "minLength": 1},
+                "signature": {"type": "string", "minLength": 1},
+                "keyring": {"type": "string", "minLength": 1}
+              },
+              "dependencies": {
+                "signature": ["keyring"],
+                "keyring": ["signature"]
+              }
+            }
+          },
+          "if": {"required": ["latestRelease"], "properties": {"latestRelease": {"const": true}}},
+          "else": {"required": ["branch"]}
+        }
+      }
+    }
+  }
+}`
+
+// Problem is a problem found when validating rules, at a position in the file.
+type Problem struct {
+	Line    int
+	Column  int
+	Field   string // e.g. rules.0.repo
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", p.Line, p.Column, p.Field, p.Message)
+}
+
+// ValidationError is returned when rules fail validation.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e ValidationError) Error() string {
+	problems := []string{}
+	for _, p := range e.Problems {
+		problems = append(problems, p.String())
+	}
+	return fmt.Sprintf("invalid rules: %s", strings.Join(problems, "; "))
+}
+
+// Validate checks the rules against the Schema, and that the rule names are
+// unique, returning a ValidationError with the positions of the problems.
+//
+// The rules are validated without interpolation, so variables aren't needed,
+// and values with variables aren't checked against the patterns of the
+// Schema, e.g. repo: ${REPO}.
+func Validate(b []byte) error {
+	root, err := parseRules(b)
+	if err != nil {
+		return err
+	}
+	return validateNode(root)
+}
+
+// parseRules returns the root node of the rules, an empty document is
+// invalid.
+func parseRules(b []byte) (*yaml3.Node, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the rules: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, ValidationError{Problems: []Problem{{Line: 1, Column: 1, Field: "(root)", Message: "rules is required"}}}
+	}
+	return doc.Content[0], nil
+}
+
+func validateNode(root *yaml3.Node) error {
+	var v interface{}
+	if err := root.Decode(&v); err != nil {
+		return fmt.Errorf("failed to parse the rules: %w", err)
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(Schema))
+	if err != nil {
+		return fmt.Errorf("failed to load the rules schema: %w", err)
+	}
+	result, err := schema.Validate(gojsonschema.NewGoLoader(v))
+	if err != nil {
+		return fmt.Errorf("failed to validate the rules: %w", err)
+	}
+	problems := []Problem{}
+	for _, e := range result.Errors() {
+		// The problems within a conditional schema are reported separately.
+		if e.Type() == "condition_then" || e.Type() == "condition_else" {
+			continue
+		}
+		n := fieldNode(root, e.Field())
+		// The pattern applies to the interpolated value.
+		if e.Type() == "pattern" && strings.Contains(n.Value, "${") {
+			continue
+		}
+		if e.Type() == "additional_property_not_allowed" {
+			if key, _ := mappingEntry(n, fmt.Sprint(e.Details()["property"])); key != nil {
+				n = key
+			}
+		}
+		problems = append(problems, Problem{Line: n.Line, Column: n.Column, Field: e.Field(), Message: e.Description()})
+	}
+	problems = append(problems, duplicateNames(root)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+	return ValidationError{Problems: problems}
+}
+
+// fieldNode returns the node for the schema field e.g. "rules.0.repo", or the
+// nearest parent that exists.
+func fieldNode(root *yaml3.Node, field string) *yaml3.Node {
+	n := root
+	if field == "(root)" {
+		return n
+	}
+	for _, segment := range strings.Split(field, ".") {
+		var next *yaml3.Node
+		switch n.Kind {
+		case yaml3.MappingNode:
+			_, next = mappingEntry(n, segment)
+		case yaml3.SequenceNode:
+			if i, err := strconv.Atoi(segment); err == nil && i < len(n.Content) {
+				next = n.Content[i]
+			}
+		}
+		if next == nil {
+			return n
+		}
+		n = next
+	}
+	return n
+}
+
+// mappingEntry returns the key and value nodes for the key in a mapping.
+func mappingEntry(n *yaml3.Node, key string) (*yaml3.Node, *yaml3.Node) {
+	if n.Kind != yaml3.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i], n.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// duplicateNames reports rules and syncs that share a name, the names are
+// used to identify both in reports.
+func duplicateNames(root *yaml3.Node) []Problem {
+	problems := []Problem{}
+	seen := map[string]int{}
+	for _, field := range []string{"rules", "syncs"} {
+		_, items := mappingEntry(root, field)
+		if items == nil || items.Kind != yaml3.SequenceNode {
+			continue
+		}
+		for i, item := range items.Content {
+			_, name := mappingEntry(item, "name")
+			if name == nil {
+				continue
+			}
+			if line, ok := seen[name.Value]; ok {
+				problems = append(problems, Problem{Line: name.Line, Column: name.Column, Field: fmt.Sprintf("%s.%d.name", field, i), Message: fmt.Sprintf("duplicate rule name %q, first declared on line %d", name.Value, line)})
+				continue
+			}
+			seen[name.Value] = name.Line
+		}
+	}
+	return problems
+}
@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Validate([]byte(testRules)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateWithVariable(t *testing.T) {
+	if err := Validate([]byte("rules:\n  - name: frontend-tag\n    repo: my-org/frontend\n    variable: IMAGE_TAG\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	validateTests := []struct {
+		name string
+		doc  string
+		want []Problem
+	}{
+		{
+			name: "empty document",
+			doc:  "",
+			want: []Problem{{Line: 1, Column: 1, Field: "(root)", Message: "rules is required"}},
+		},
+		{
+			name: "missing required field",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n",
+			want: []Problem{{Line: 2, Column: 5, Field: "rules.0", Message: "key is required"}},
+		},
+		{
+			name: "empty variable",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    variable: ''\n",
+			want: []Problem{{Line: 4, Column: 15, Field: "rules.0.variable", Message: "String length must be greater than or equal to 1"}},
+		},
+		{
+			name: "unknown field",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.replicas\n    brnach: main\n",
+			want: []Problem{{Line: 7, Column: 5, Field: "rules.0", Message: "Additional property brnach is not allowed"}},
+		},
+		{
+			name: "wrong type",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.replicas\n    pullRequest:\n      title: [Update]\n",
+			want: []Problem{{Line: 8, Column: 14, Field: "rules.0.pullRequest.title", Message: "Invalid type. Expected: string, given: array"}},
+		},
+		{
+			name: "empty label",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.replicas\n    pullRequest:\n      labels: ['']\n",
+			want: []Problem{{Line: 8, Column: 16, Field: "rules.0.pullRequest.labels.0", Message: "String length must be greater than or equal to 1"}},
+		},
+		{
+			name: "empty team reviewer",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.replicas\n    pullRequest:\n      teamReviewers: ['']\n",
+			want: []Problem{{Line: 8, Column: 23, Field: "rules.0.pullRequest.teamReviewers.0", Message: "String length must be greater than or equal to 1"}},
+		},
+		{
+			name: "empty format",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: package.json\n    key: version\n    format: ''\n",
+			want: []Problem{{Line: 7, Column: 13, Field: "rules.0.format", Message: "String length must be greater than or equal to 1"}},
+		},
+		{
+			name: "invalid repo",
+			doc:  "rules:\n  - name: frontend\n    repo: frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.replicas\n",
+			want: []Problem{{Line: 3, Column: 11, Field: "rules.0.repo", Message: `Does not match pattern '^[^/]+/.+$'`}},
+		},
+		{
+			name: "incomplete source",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: values.yaml\n    key: image.tag\n    source:\n      repo: my-org/charts\n      file: values.yaml\n",
+			want: []Problem{{Line: 8, Column: 7, Field: "rules.0.source", Message: "branch is required"}},
+		},
+		{
+			name: "invalid secondary delay",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.image\n    secondary:\n      file: canary.yaml\n      delay: 1 day\n",
+			want: []Problem{{Line: 9, Column: 14, Field: "rules.0.secondary.delay", Message: `Does not match pattern '^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$'`}},
+		},
+		{
+			name: "unknown merge method",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.image\n    postActions:\n      - autoMerge: fast-forward\n",
+			want: []Problem{{Line: 8, Column: 20, Field: "rules.0.postActions.0.autoMerge", Message: `rules.0.postActions.0.autoMerge must be one of the following: "merge", "squash", "rebase"`}},
+		},
+		{
+			name: "project column without a project",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: deployment.yaml\n    key: spec.image\n    postActions:\n      - projectColumn: In review\n",
+			want: []Problem{{Line: 8, Column: 9, Field: "rules.0.postActions.0", Message: "Has a dependency on project"}},
+		},
+		{
+			name: "empty secret key",
+			doc:  "rules:\n  - name: frontend\n    repo: my-org/frontend\n    branch: main\n    file: values.yaml\n    key: db.password\n    secretKeys: ['']\n",
+			want: []Problem{{Line: 7, Column: 18, Field: "rules.0.secretKeys.0", Message: "String length must be greater than or equal to 1"}},
+		},
+		{
+			name: "duplicate names",
+			doc:  "rules:\n  - {name: frontend, repo: my-org/a, branch: main, file: a.yaml, key: a}\n  - {name: frontend, repo: my-org/b, branch: main, file: b.yaml, key: b}\n",
+			want: []Problem{{Line: 3, Column: 12, Field: "rules.1.name", Message: `duplicate rule name "frontend", first declared on line 2`}},
+		},
+		{
+			name: "upstream without a branch",
+			doc:  "rules: []\nsyncs:\n  - name: chart\n    repo: my-org/frontend\n    branch: main\n    path: vendor/chart\n    stateFile: vendor/chart.sync.yaml\n    upstream:\n      repo: my-org/charts\n      path: charts/frontend\n",
+			want: []Problem{{Line: 9, Column: 7, Field: "syncs.0.upstream", Message: "branch is required"}},
+		},
+		{
+			name: "signature without a keyring",
+			doc:  "rules: []\nsyncs:\n  - name: chart\n    repo: my-org/frontend\n    branch: main\n    path: vendor/chart\n    stateFile: vendor/chart.sync.yaml\n    upstream:\n      repo: my-org/charts\n      path: charts/frontend\n      latestRelease: true\n      verify:\n        signature: charts/frontend.asc\n",
+			want: []Problem{{Line: 13, Column: 9, Field: "syncs.0.upstream.verify", Message: "Has a dependency on keyring"}},
+		},
+		{
+			name: "sync with a rule name",
+			doc:  "rules:\n  - {name: frontend, repo: my-org/a, branch: main, file: a.yaml, key: a}\nsyncs:\n  - {name: frontend, repo: my-org/a, branch: main, path: chart, stateFile: chart.sync.yaml, upstream: {repo: my-org/charts, path: chart, latestRelease: true}}\n",
+			want: []Problem{{Line: 4, Column: 12, Field: "syncs.0.name", Message: `duplicate rule name "frontend", first declared on line 2`}},
+		},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := Validate([]byte(tt.doc))
+
+			var verr ValidationError
+			if !errors.As(err, &verr) {
+				rt.Fatalf("got %v, want a ValidationError", err)
+			}
+			if diff := cmp.Diff(tt.want, verr.Problems); diff != "" {
+				rt.Errorf("incorrect problems:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateParseError(t *testing.T) {
+	err := Validate([]byte("rules: [\n"))
+
+	if !test.MatchError(t, `failed to parse the rules: yaml: line 1`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestValidateWithVariableInPattern(t *testing.T) {
+	if err := Validate([]byte("rules:\n  - name: frontend\n    repo: ${REPO}\n    branch: main\n    file: deploy.yaml\n    key: image\n")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadValidatesInterpolatedValues(t *testing.T) {
+	doc := []byte("rules:\n  - name: frontend\n    repo: ${REPO}\n    branch: main\n    file: deploy.yaml\n    key: image\n")
+	loadTests := []struct {
+		name    string
+		repo    string
+		wantErr string
+	}{
+		{"valid repo", "org/x", ""},
+		{"invalid repo", "x", `invalid rules: 3:11: rules.0.repo: Does not match pattern`},
+	}
+
+	for _, tt := range loadTests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Load(doc, func(string) (string, bool) { return tt.repo, true })
+
+			if !test.MatchError(t, tt.wantErr, err) {
+				t.Fatalf("got error %v", err)
+			}
+			if err == nil && f.Rules[0].Repo != tt.repo {
+				t.Fatalf("got repo %q, want %q", f.Rules[0].Repo, tt.repo)
+			}
+		})
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	_, err := Load([]byte("rules:\n  - name: frontend\n"), testLookup)
+
+	if !test.MatchError(t, `invalid rules: 2:5: rules.0: branch is required; 2:5: rules.0: file is required; 2:5: rules.0: key is required; 2:5: rules.0: repo is required`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(Schema), &v); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,30 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/agill17/pkg/updater"
+)
+
+// VariableInput returns the updater VariableInput to apply the rule with the
+// new value, it returns nil if the rule has no Variable.
+func (r Rule) VariableInput(newValue interface{}) *updater.VariableInput {
+	if r.Variable == "" {
+		return nil
+	}
+	return &updater.VariableInput{Repo: r.Repo, Name: r.Variable, Value: fmt.Sprint(newValue)}
+}
+
+// applyVariable updates the rule's Variable, the result is Committed if the
+// variable was changed.
+func (r Rule) applyVariable(ctx context.Context, u *updater.Updater, newValue interface{}) (*updater.UpdateResult, error) {
+	changed, err := u.UpdateVariable(ctx, r.VariableInput(newValue))
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return &updater.UpdateResult{State: updater.Unchanged}, nil
+	}
+	return &updater.UpdateResult{State: updater.Committed}, nil
+}
@@ -0,0 +1,64 @@
+package rules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/updater"
+)
+
+func TestVariableInput(t *testing.T) {
+	r := Rule{Name: "frontend-tag", Repo: "my-org/frontend-deploy", Variable: "IMAGE_TAG"}
+
+	want := &updater.VariableInput{Repo: "my-org/frontend-deploy", Name: "IMAGE_TAG", Value: "12"}
+	if diff := cmp.Diff(want, r.VariableInput(12)); diff != "" {
+		t.Fatalf("incorrect input:\n%s", diff)
+	}
+}
+
+func TestVariableInputWithoutVariable(t *testing.T) {
+	if i := (Rule{Name: "frontend"}).VariableInput("v2"); i != nil {
+		t.Fatalf("got %#v, want nil", i)
+	}
+}
+
+func TestApplyWithVariable(t *testing.T) {
+	applyTests := []struct {
+		name    string
+		current string
+		want    updater.UpdateState
+	}{
+		{"changed", "v1", updater.Committed},
+		{"unchanged", "v2", updater.Unchanged},
+	}
+
+	for _, tt := range applyTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddVariable("my-org/frontend-deploy", "IMAGE_TAG", tt.current)
+			r := Rule{Name: "frontend-tag", Repo: "my-org/frontend-deploy", Variable: "IMAGE_TAG"}
+
+			result, delayed, err := r.Apply(context.Background(), updater.New(zap.New(), m), "v2", time.Now())
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if result.State != tt.want {
+				rt.Fatalf("got state %s, want %s", result.State, tt.want)
+			}
+			if delayed != nil {
+				rt.Fatalf("got delayed update %#v, want none", delayed)
+			}
+			if tt.want == updater.Committed {
+				m.AssertVariableSet("my-org/frontend-deploy", "IMAGE_TAG", "v2")
+				return
+			}
+			m.RefuteVariableSet("my-org/frontend-deploy", "IMAGE_TAG")
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	"github.com/agill17/pkg/updater"
+)
+
+// RunFiles loads and runs the scenarios in the files matching the glob, each
+// as a subtest.
+func RunFiles(t *testing.T, glob string, opts ...updater.UpdaterFunc) {
+	t.Helper()
+	files, err := filepath.Glob(glob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no scenarios match %s", glob)
+	}
+	for _, f := range files {
+		s, err := LoadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		Run(t, s, opts...)
+	}
+}
+
+// Run runs the scenario as a subtest, the Updater is created with the options.
+func Run(t *testing.T, s *Scenario, opts ...updater.UpdaterFunc) {
+	t.Helper()
+	t.Run(s.Name, func(rt *testing.T) {
+		run(rt, s, opts)
+	})
+}
+
+func run(t *testing.T, s *Scenario, opts []updater.UpdaterFunc) {
+	m := mock.New(t)
+	for _, b := range s.Given.Branches {
+		m.AddBranchHead(b.Repo, b.Name, b.SHA)
+	}
+	for _, f := range s.Given.Files {
+		m.AddFileContents(f.Repo, f.Path, f.Branch, []byte(f.Content))
+	}
+	for _, pr := range s.Given.PullRequests {
+		m.AddOpenPullRequest(pr.Repo, &scm.PullRequest{Number: pr.Number, Title: pr.Title, Body: pr.Body, Source: pr.Head, Target: pr.Base})
+	}
+	u := updater.New(zap.New(), m, append([]updater.UpdaterFunc{updater.NameGenerator(&sequence{})}, opts...)...)
+
+	r, err := u.Update(context.Background(), s.When.Rule.Input(s.When.Value))
+
+	then := s.Then
+	if !test.MatchError(t, then.Error, err) {
+		t.Fatalf("got error %v, want %q", err, then.Error)
+	}
+	if r != nil && then.State != "" && r.State.String() != then.State {
+		t.Errorf("got state %s, want %s", r.State, then.State)
+	}
+	if then.Branches != nil && len(then.Branches) == 0 {
+		m.AssertNoBranchesCreated()
+	}
+	for _, b := range then.Branches {
+		m.AssertBranchCreated(b.Repo, b.Name, b.SHA)
+	}
+	for _, f := range then.Files {
+		if diff := cmp.Diff(f.Content, string(m.GetUpdatedContents(f.Repo, f.Path, f.Branch))); diff != "" {
+			t.Errorf("incorrect content of %s in %s/%s:\n%s", f.Path, f.Repo, f.Branch, diff)
+		}
+	}
+	for _, c := range then.Commits {
+		m.AssertCommitMessage(c.Repo, c.Path, c.Branch, c.Message)
+	}
+	if then.PullRequests != nil && len(then.PullRequests) == 0 {
+		m.AssertNoPullRequestsCreated()
+	}
+	for _, pr := range then.PullRequests {
+		m.AssertPullRequestCreated(pr.Repo, &scm.PullRequestInput{Title: pr.Title, Body: pr.Body, Head: pr.Head, Base: pr.Base})
+	}
+}
+
+// sequence generates names numbered in order, from 1.
+type sequence struct {
+	n int
+}
+
+func (s *sequence) PrefixedName(prefix string) string {
+	s.n++
+	return fmt.Sprintf("%s%d", prefix, s.n)
+}
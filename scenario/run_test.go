@@ -0,0 +1,23 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/agill17/pkg/names"
+)
+
+var _ names.Generator = (*sequence)(nil)
+
+func TestRunFiles(t *testing.T) {
+	RunFiles(t, "testdata/*.yaml")
+}
+
+func TestSequence(t *testing.T) {
+	s := &sequence{}
+
+	for _, want := range []string{"update-1", "update-2"} {
+		if got := s.PrefixedName("update-"); got != want {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package scenario runs declarative acceptance tests of rules, against the
+// in-memory mock client.
+//
+// A scenario declares the repos, the rule that is applied, and the expected
+// branches, commits and PullRequests.
+//
+//	name: updates the frontend image
+//	given:
+//	  branches:
+//	    - repo: my-org/frontend-deploy
+//	      name: main
+//	      sha: 980a0d5f19a64b4b30a87d4206aade58726b60e3
+//	  files:
+//	    - repo: my-org/frontend-deploy
+//	      branch: main
+//	      path: deployment.yaml
+//	      content: |
+//	        image: my-org/frontend:v1
+//	when:
+//	  rule:
+//	    name: frontend-image
+//	    repo: my-org/frontend-deploy
+//	    branch: main
+//	    file: deployment.yaml
+//	    key: image
+//	    branchGenerateName: update-image-
+//	    commitMessage: Update to {{ .NewValue }}
+//	    pullRequest:
+//	      title: Update the frontend image
+//	  value: my-org/frontend:v2
+//	then:
+//	  state: pull-request-created
+//	  branches:
+//	    - repo: my-org/frontend-deploy
+//	      name: update-image-1
+//	      sha: 980a0d5f19a64b4b30a87d4206aade58726b60e3
+//	  files:
+//	    - repo: my-org/frontend-deploy
+//	      branch: update-image-1
+//	      path: deployment.yaml
+//	      content: |
+//	        image: my-org/frontend:v2
+//	  pullRequests:
+//	    - repo: my-org/frontend-deploy
+//	      title: Update the frontend image
+//	      head: update-image-1
+//	      base: main
+//
+// Generated branch names are numbered in order, from 1, and an empty list of
+// expected branches or PullRequests expects none to be created.
+package scenario
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	yaml3 "gopkg.in/yaml.v3"
+
+	"github.com/agill17/pkg/rules"
+)
+
+// Scenario is an acceptance test of a rule.
+type Scenario struct {
+	Name  string `yaml:"name"`
+	Given Given  `yaml:"given"`
+	When  When   `yaml:"when"`
+	Then  Then   `yaml:"then"`
+}
+
+// Given is the state of the repos before the rule is applied.
+type Given struct {
+	Branches []Branch `yaml:"branches,omitempty"`
+	Files    []File   `yaml:"files,omitempty"`
+	// The PullRequests that are open.
+	PullRequests []PullRequest `yaml:"pullRequests,omitempty"`
+}
+
+// When is the rule that is applied, with the new value.
+type When struct {
+	Rule  *rules.Rule `yaml:"rule"`
+	Value interface{} `yaml:"value"`
+}
+
+// Then is the expected outcome of applying the rule, only the fields that are
+// set are checked.
+type Then struct {
+	// State is the expected UpdateState e.g. pull-request-created.
+	State string `yaml:"state,omitempty"`
+	// Error is a regular expression matching the expected error.
+	Error string `yaml:"error,omitempty"`
+	// The branches that are created.
+	Branches []Branch `yaml:"branches,omitempty"`
+	// The updated content of the files.
+	Files   []File   `yaml:"files,omitempty"`
+	Commits []Commit `yaml:"commits,omitempty"`
+	// The PullRequests that are created.
+	PullRequests []PullRequest `yaml:"pullRequests,omitempty"`
+}
+
+// Branch is a branch in a repo, and its head.
+type Branch struct {
+	Repo string `yaml:"repo"`
+	Name string `yaml:"name"`
+	SHA  string `yaml:"sha"`
+}
+
+// File is the content of a file in a branch.
+type File struct {
+	Repo    string `yaml:"repo"`
+	Branch  string `yaml:"branch"`
+	Path    string `yaml:"path"`
+	Content string `yaml:"content"`
+}
+
+// Commit is a commit of a file to a branch.
+type Commit struct {
+	Repo    string `yaml:"repo"`
+	Branch  string `yaml:"branch"`
+	Path    string `yaml:"path"`
+	Message string `yaml:"message"`
+}
+
+// PullRequest is a PullRequest from the head branch into the base branch.
+type PullRequest struct {
+	Repo   string `yaml:"repo"`
+	Number int    `yaml:"number,omitempty"`
+	Title  string `yaml:"title,omitempty"`
+	Body   string `yaml:"body,omitempty"`
+	Head   string `yaml:"head"`
+	Base   string `yaml:"base"`
+}
+
+// LoadFile reads and parses a scenario file.
+func LoadFile(filename string) (*Scenario, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the scenario file: %w", err)
+	}
+	s, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", filename, err)
+	}
+	return s, nil
+}
+
+// Parse parses a scenario, unknown fields are an error, so that mistyped
+// expectations aren't ignored.
+func Parse(b []byte) (*Scenario, error) {
+	d := yaml3.NewDecoder(bytes.NewReader(b))
+	d.KnownFields(true)
+	s := &Scenario{}
+	if err := d.Decode(s); err != nil {
+		return nil, fmt.Errorf("failed to parse the scenario: %w", err)
+	}
+	if s.Name == "" {
+		return nil, errors.New("the scenario has no name")
+	}
+	if s.When.Rule == nil {
+		return nil, fmt.Errorf("scenario %q has no rule", s.Name)
+	}
+	return s, nil
+}
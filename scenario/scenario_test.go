@@ -0,0 +1,72 @@
+package scenario
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestLoadFile(t *testing.T) {
+	s, err := LoadFile("testdata/pull-request.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Name != "updates the frontend image" {
+		t.Fatalf("got name %q", s.Name)
+	}
+	if s.When.Rule.Key != "image" || s.When.Value != "my-org/frontend:v2" {
+		t.Fatalf("got rule %#v with value %v", s.When.Rule, s.When.Value)
+	}
+	want := []PullRequest{{Repo: "my-org/frontend-deploy", Title: "Update the frontend image", Body: "Updated from my-org/frontend:v1", Head: "update-image-1", Base: "main"}}
+	if diff := cmp.Diff(want, s.Then.PullRequests); diff != "" {
+		t.Fatalf("incorrect pull requests:\n%s", diff)
+	}
+}
+
+func TestLoadFileWithMissingFile(t *testing.T) {
+	_, err := LoadFile("testdata/unknown.yaml")
+
+	if !test.MatchError(t, "failed to read the scenario file", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	parseTests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"valid", "name: test\nwhen:\n  rule:\n    key: image\n", ""},
+		{"no name", "when:\n  rule:\n    key: image\n", "the scenario has no name"},
+		{"no rule", "name: test\nwhen:\n  value: v2\n", `scenario "test" has no rule`},
+		{"unknown field", "name: test\nwhen:\n  rule:\n    key: image\nthen:\n  pullrequests: []\n", "field pullrequests not found in type scenario.Then"},
+	}
+
+	for _, tt := range parseTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := Parse([]byte(tt.body))
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseDistinguishesNoExpectations(t *testing.T) {
+	s, err := Parse([]byte("name: test\nwhen:\n  rule:\n    key: image\nthen:\n  branches: []\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Then.Branches == nil {
+		t.Fatal("an empty list of branches was not parsed")
+	}
+	if s.Then.PullRequests != nil {
+		t.Fatalf("got pull requests %#v, want no expectation", s.Then.PullRequests)
+	}
+}
@@ -0,0 +1,151 @@
+package shcl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+
+	"github.com/agill17/pkg/internal/dotpath"
+)
+
+var errEmptyPath = errors.New("empty path")
+
+// ErrKeyNotFound is returned when the attribute being read doesn't exist in
+// the body.
+var ErrKeyNotFound = errors.New("key not found")
+
+// SetBytes accepts an HCL body e.g. a Terraform .tf or .tfvars file, a path
+// and a new value, and updates the attribute in the body using the path.
+//
+// The path is made up of the block types and labels, followed by the
+// attribute name, e.g. "module.vpc.version" would update the version in
+//
+//	module "vpc" {
+//	  version = "2.0.0"
+//	}
+//
+// Top-level attributes, e.g. in .tfvars files, are addressed by their name.
+//
+// Comments and formatting are preserved, if the attribute doesn't exist, it's
+// added to the block, but the blocks must exist.
+func SetBytes(b []byte, path string, value interface{}) ([]byte, error) {
+	f, body, name, err := findBody(b, path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := toCtyValue(value)
+	if err != nil {
+		return nil, err
+	}
+	body.SetAttributeValue(name, v)
+	return f.Bytes(), nil
+}
+
+// GetBytes returns the value of the attribute at the path in the HCL body,
+// string literals are returned unquoted, and other expressions as they're
+// written in the body.
+func GetBytes(b []byte, path string) (string, error) {
+	_, body, name, err := findBody(b, path)
+	if err != nil {
+		return "", err
+	}
+	attr := body.GetAttribute(name)
+	if attr == nil {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	src := attr.Expr().BuildTokens(nil).Bytes()
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.InitialPos)
+	if !diags.HasErrors() {
+		if v, diags := expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+			return v.AsString(), nil
+		}
+	}
+	return strings.TrimSpace(string(src)), nil
+}
+
+// DeleteBytes removes the attribute at the path from the HCL body, the body is
+// returned unchanged if the attribute doesn't exist.
+func DeleteBytes(b []byte, path string) ([]byte, error) {
+	f, body, name, err := findBody(b, path)
+	if err != nil {
+		return nil, err
+	}
+	if body.GetAttribute(name) == nil {
+		return b, nil
+	}
+	body.RemoveAttribute(name)
+	return f.Bytes(), nil
+}
+
+// findBody parses the HCL and returns the body of the block matching the
+// path, and the name of the attribute in it.
+func findBody(b []byte, path string) (*hclwrite.File, *hclwrite.Body, string, error) {
+	segments := dotpath.Split(path)
+	if len(segments) == 0 {
+		return nil, nil, "", errEmptyPath
+	}
+	f, diags := hclwrite.ParseConfig(b, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, nil, "", diags
+	}
+	body := f.Body()
+	for len(segments) > 1 {
+		block, remaining := findBlock(body, segments)
+		if block == nil {
+			return nil, nil, "", fmt.Errorf("failed to find a block matching %q in path %q", strings.Join(segments[:len(segments)-1], "."), path)
+		}
+		body, segments = block.Body(), remaining
+	}
+	return f, body, segments[0], nil
+}
+
+// findBlock returns the first block in the body whose type and labels match
+// the start of the segments, and the remaining segments.
+//
+// At least one segment always remains for the attribute name.
+func findBlock(body *hclwrite.Body, segments []string) (*hclwrite.Block, []string) {
+	for _, block := range body.Blocks() {
+		labels := block.Labels()
+		if block.Type() != segments[0] || len(labels)+1 >= len(segments) {
+			continue
+		}
+		if equalLabels(labels, segments[1:len(labels)+1]) {
+			return block, segments[len(labels)+1:]
+		}
+	}
+	return nil, nil
+}
+
+func equalLabels(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// toCtyValue converts the value via JSON, so that maps and slices of mixed
+// types become objects and tuples.
+func toCtyValue(value interface{}) (cty.Value, error) {
+	j, err := json.Marshal(value)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("failed to convert value: %w", err)
+	}
+	t, err := ctyjson.ImpliedType(j)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("failed to convert value: %w", err)
+	}
+	v, err := ctyjson.Unmarshal(j, t)
+	if err != nil {
+		return cty.NilVal, fmt.Errorf("failed to convert value: %w", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,171 @@
+package shcl
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testModule = `# The VPC
+module "vpc" {
+  source  = "terraform-aws-modules/vpc/aws"
+  version = "2.0.0" # pinned
+}
+
+module "eks" {
+  source  = "terraform-aws-modules/eks/aws"
+  version = "12.0.0"
+}
+`
+
+func TestSet(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "module version",
+			source:   testModule,
+			path:     "module.eks.version",
+			newValue: "13.1.0",
+			want:     "# The VPC\nmodule \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"2.0.0\" # pinned\n}\n\nmodule \"eks\" {\n  source  = \"terraform-aws-modules/eks/aws\"\n  version = \"13.1.0\"\n}\n",
+		},
+		{
+			name:     "attribute with a comment",
+			source:   testModule,
+			path:     "module.vpc.version",
+			newValue: "2.1.0",
+			want:     "# The VPC\nmodule \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"2.1.0\" # pinned\n}\n\nmodule \"eks\" {\n  source  = \"terraform-aws-modules/eks/aws\"\n  version = \"12.0.0\"\n}\n",
+		},
+		{
+			name:     "variable default",
+			source:   "variable \"ami\" {\n  type    = string\n  default = \"ami-1234\"\n}\n",
+			path:     "variable.ami.default",
+			newValue: "ami-5678",
+			want:     "variable \"ami\" {\n  type    = string\n  default = \"ami-5678\"\n}\n",
+		},
+		{
+			name:     "tfvars",
+			source:   "# Instances\ninstance_count = 2\nami_id         = \"ami-1234\"\n",
+			path:     "instance_count",
+			newValue: 3,
+			want:     "# Instances\ninstance_count = 3\nami_id         = \"ami-1234\"\n",
+		},
+		{
+			name:     "nested blocks",
+			source:   "resource \"aws_instance\" \"web\" {\n  root_block_device {\n    volume_size = 10\n  }\n}\n",
+			path:     "resource.aws_instance.web.root_block_device.volume_size",
+			newValue: 20,
+			want:     "resource \"aws_instance\" \"web\" {\n  root_block_device {\n    volume_size = 20\n  }\n}\n",
+		},
+		{
+			name:     "structured value",
+			source:   "tags = {}\n",
+			path:     "tags",
+			newValue: map[string]interface{}{"team": "platform"},
+			want:     "tags = {\n  team = \"platform\"\n}\n",
+		},
+		{
+			name:     "new attribute",
+			source:   "terraform {\n  required_version = \">= 0.13\"\n}\n",
+			path:     "terraform.experiments",
+			newValue: []string{"example"},
+			want:     "terraform {\n  required_version = \">= 0.13\"\n  experiments      = [\"example\"]\n}\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFailures(t *testing.T) {
+	setTests := []struct {
+		name    string
+		source  string
+		path    string
+		wantErr string
+	}{
+		{"invalid document", "module \"vpc\" {\n", "module.vpc.version", "Unclosed configuration block"},
+		{"empty path", testModule, "", "empty path"},
+		{"missing block", testModule, "module.rds.version", `failed to find a block matching "module.rds" in path "module.rds.version"`},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(tt.source), tt.path, "test")
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	source := []byte(testModule + "instance_count = 2\ntags = { team = \"platform\" }\n")
+	getTests := []struct {
+		path string
+		want string
+	}{
+		{"module.vpc.version", "2.0.0"},
+		{"module.eks.source", "terraform-aws-modules/eks/aws"},
+		{"instance_count", "2"},
+		{"tags", `{ team = "platform" }`},
+	}
+
+	for _, tt := range getTests {
+		got, err := GetBytes(source, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetBytes(%s) got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	_, err := GetBytes(source, "module.vpc.name")
+	if !test.MatchError(t, "key not found: module.vpc.name", err) {
+		t.Errorf("error got %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	deleteTests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"attribute in a block", "module.eks.version", "# The VPC\nmodule \"vpc\" {\n  source  = \"terraform-aws-modules/vpc/aws\"\n  version = \"2.0.0\" # pinned\n}\n\nmodule \"eks\" {\n  source = \"terraform-aws-modules/eks/aws\"\n}\n"},
+		{"missing attribute", "module.eks.name", testModule},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := DeleteBytes([]byte(testModule), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+
+	_, err := DeleteBytes([]byte(testModule), "module.rds.version")
+	if !test.MatchError(t, `failed to find a block matching "module.rds"`, err) {
+		t.Errorf("error got %s", err)
+	}
+}
@@ -0,0 +1,240 @@
+package sini
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyNotFound is returned when the key being read doesn't exist in the
+// document.
+var ErrKeyNotFound = errors.New("key not found")
+
+var errEmptyKey = errors.New("empty key")
+
+// SetBytes accepts an INI body, a path and a new value, and updates the value
+// of the key in the body.
+//
+// The path is the section name, followed by the key, e.g. "server.port"
+// would update the port in
+//
+//	[server]
+//	port = 8080
+//
+// Keys before the first section are addressed by their name.
+//
+// Comments, ordering, separators and quoting are preserved, if the key doesn't
+// exist, it's added after the last key in the section, and if the section
+// doesn't exist, it's appended to the end of the body.
+func SetBytes(b []byte, path string, value interface{}) ([]byte, error) {
+	section, key := sectionKey(path)
+	if key == "" {
+		return nil, errEmptyKey
+	}
+	v := fmt.Sprint(value)
+	if strings.ContainsAny(v, "\r\n") {
+		return nil, fmt.Errorf("multi-line values are not supported for key %s", path)
+	}
+	doc := parse(b)
+	found := false
+	for _, e := range doc.entries {
+		if e.section != section || e.key != key {
+			continue
+		}
+		found = true
+		l, rendered := doc.lines[e.line], e.quote+v+e.quote
+		if e.bare {
+			rendered = e.separator + rendered
+		}
+		doc.lines[e.line] = l[:e.valueStart] + rendered + l[e.valueEnd:]
+	}
+	if found {
+		return doc.bytes(), nil
+	}
+	separator := " = "
+	if len(doc.entries) > 0 {
+		separator = doc.entries[len(doc.entries)-1].separator
+	}
+	line, empty := key+separator+v, len(doc.lines) == 0
+	at, ok := doc.insertAt(section)
+	if !ok {
+		if len(doc.lines) > 0 {
+			doc.lines = append(doc.lines, "")
+		}
+		doc.lines = append(doc.lines, "["+section+"]")
+		at = len(doc.lines)
+	}
+	doc.lines = append(doc.lines[:at], append([]string{line}, doc.lines[at:]...)...)
+	if empty {
+		doc.trailingNewline = true
+	}
+	return doc.bytes(), nil
+}
+
+// GetBytes returns the value of the key at the path in the body, where the key
+// appears several times in the section, the last value is returned.
+func GetBytes(b []byte, path string) (string, error) {
+	section, key := sectionKey(path)
+	doc := parse(b)
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		if e := doc.entries[i]; e.section == section && e.key == key {
+			return e.value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+}
+
+// DeleteBytes removes every occurrence of the key at the path from the body,
+// the body is returned unchanged if the key doesn't exist.
+//
+// The section is retained, even if it's left empty.
+func DeleteBytes(b []byte, path string) ([]byte, error) {
+	section, key := sectionKey(path)
+	if key == "" {
+		return nil, errEmptyKey
+	}
+	doc := parse(b)
+	found := false
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		if e := doc.entries[i]; e.section == section && e.key == key {
+			found = true
+			doc.lines = append(doc.lines[:e.line], doc.lines[e.line+1:]...)
+		}
+	}
+	if !found {
+		return b, nil
+	}
+	return doc.bytes(), nil
+}
+
+// sectionKey splits the path at the last dot into the section and the key.
+func sectionKey(path string) (string, string) {
+	i := strings.LastIndex(path, ".")
+	if i < 0 {
+		return "", path
+	}
+	return path[:i], path[i+1:]
+}
+
+type document struct {
+	lines           []string
+	eol             string
+	trailingNewline bool
+	entries         []*entry
+	// sections maps the section names to the line of their header.
+	sections map[string]int
+}
+
+type entry struct {
+	section    string
+	key        string
+	value      string
+	separator  string
+	quote      string
+	bare       bool // the key has no separator or value
+	line       int
+	valueStart int
+	valueEnd   int
+}
+
+func parse(b []byte) *document {
+	s := string(b)
+	doc := &document{eol: "\n", trailingNewline: strings.HasSuffix(s, "\n"), sections: map[string]int{}}
+	if strings.Contains(s, "\r\n") {
+		doc.eol = "\r\n"
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s != "" {
+		doc.lines = strings.Split(s, "\n")
+	}
+	section := ""
+	for i, l := range doc.lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			section = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+			if _, ok := doc.sections[section]; !ok {
+				doc.sections[section] = i
+			}
+			continue
+		}
+		e := parseEntry(l)
+		if e == nil {
+			continue
+		}
+		e.section, e.line = section, i
+		doc.entries = append(doc.entries, e)
+	}
+	return doc
+}
+
+// parseEntry parses a line, returning nil for blank lines and comments.
+//
+// Inline comments start with a ";" or "#" after whitespace.
+func parseEntry(l string) *entry {
+	start := len(l) - len(strings.TrimLeft(l, " \t"))
+	trimmed := l[start:]
+	if trimmed == "" || trimmed[0] == ';' || trimmed[0] == '#' {
+		return nil
+	}
+	keyEnd := strings.IndexAny(l, "=:")
+	if keyEnd < 0 {
+		// A key without a value, e.g. skip-name-resolve in a my.cnf.
+		key := strings.TrimRight(trimmed, " \t")
+		return &entry{key: key, separator: " = ", bare: true, valueStart: start + len(key), valueEnd: start + len(key)}
+	}
+	valueStart := keyEnd + 1
+	for valueStart < len(l) && (l[valueStart] == ' ' || l[valueStart] == '\t') {
+		valueStart++
+	}
+	key := strings.TrimRight(l[start:keyEnd], " \t")
+	e := &entry{
+		key:        key,
+		separator:  l[start+len(key) : valueStart],
+		valueStart: valueStart,
+	}
+	rest := l[valueStart:]
+	if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+		if end := strings.IndexByte(rest[1:], rest[0]); end >= 0 {
+			e.quote = rest[:1]
+			e.value = rest[1 : end+1]
+			e.valueEnd = valueStart + end + 2
+			return e
+		}
+	}
+	for i := 1; i < len(rest); i++ {
+		if (rest[i] == ';' || rest[i] == '#') && (rest[i-1] == ' ' || rest[i-1] == '\t') {
+			rest = rest[:i]
+			break
+		}
+	}
+	e.value = strings.TrimRight(rest, " \t")
+	e.valueEnd = valueStart + len(e.value)
+	return e
+}
+
+// insertAt returns the line to insert a new key into the section, after its
+// last key, or its header if it has no keys, false is returned if the section
+// doesn't exist.
+func (d *document) insertAt(section string) (int, bool) {
+	at, ok := d.sections[section]
+	if section == "" {
+		at, ok = -1, true
+	}
+	if !ok {
+		return 0, false
+	}
+	for _, e := range d.entries {
+		if e.section == section && e.line > at {
+			at = e.line
+		}
+	}
+	return at + 1, true
+}
+
+func (d *document) bytes() []byte {
+	s := strings.Join(d.lines, d.eol)
+	if d.trailingNewline {
+		s += d.eol
+	}
+	return []byte(s)
+}
@@ -0,0 +1,184 @@
+package sini
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testConfig = `; The defaults
+name = app
+
+[server]
+host = localhost
+port = 8080 ; the port
+
+[database]
+url: "postgres://db/app"
+`
+
+func TestSet(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "key in a section",
+			source:   testConfig,
+			path:     "server.host",
+			newValue: "example.com",
+			want:     "; The defaults\nname = app\n\n[server]\nhost = example.com\nport = 8080 ; the port\n\n[database]\nurl: \"postgres://db/app\"\n",
+		},
+		{
+			name:     "inline comments are preserved",
+			source:   testConfig,
+			path:     "server.port",
+			newValue: 9090,
+			want:     "; The defaults\nname = app\n\n[server]\nhost = localhost\nport = 9090 ; the port\n\n[database]\nurl: \"postgres://db/app\"\n",
+		},
+		{
+			name:     "quoting and separator are preserved",
+			source:   testConfig,
+			path:     "database.url",
+			newValue: "postgres://db/other",
+			want:     "; The defaults\nname = app\n\n[server]\nhost = localhost\nport = 8080 ; the port\n\n[database]\nurl: \"postgres://db/other\"\n",
+		},
+		{
+			name:     "key before the first section",
+			source:   testConfig,
+			path:     "name",
+			newValue: "other",
+			want:     "; The defaults\nname = other\n\n[server]\nhost = localhost\nport = 8080 ; the port\n\n[database]\nurl: \"postgres://db/app\"\n",
+		},
+		{
+			name:     "new key is added to the section",
+			source:   "[server]\nhost = localhost\n\n[database]\nurl = db\n",
+			path:     "server.port",
+			newValue: 8080,
+			want:     "[server]\nhost = localhost\nport = 8080\n\n[database]\nurl = db\n",
+		},
+		{
+			name:     "new section",
+			source:   "[server]\nhost = localhost\n",
+			path:     "cache.size",
+			newValue: 10,
+			want:     "[server]\nhost = localhost\n\n[cache]\nsize = 10\n",
+		},
+		{
+			name:     "dotted section names",
+			source:   "[remote.origin]\nurl = old\n",
+			path:     "remote.origin.url",
+			newValue: "new",
+			want:     "[remote.origin]\nurl = new\n",
+		},
+		{
+			name:     "key without a value",
+			source:   "[mysqld]\nskip-name-resolve\n",
+			path:     "mysqld.skip-name-resolve",
+			newValue: true,
+			want:     "[mysqld]\nskip-name-resolve = true\n",
+		},
+		{
+			name:     "empty file",
+			source:   "",
+			path:     "server.port",
+			newValue: 8080,
+			want:     "[server]\nport = 8080\n",
+		},
+		{
+			name:     "crlf line endings",
+			source:   "[server]\r\nport=8080\r\n",
+			path:     "server.port",
+			newValue: 9090,
+			want:     "[server]\r\nport=9090\r\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFailures(t *testing.T) {
+	setTests := []struct {
+		name     string
+		path     string
+		newValue interface{}
+		wantErr  string
+	}{
+		{"empty key", "server.", "test", "empty key"},
+		{"multi-line value", "server.host", "a\nb", "multi-line values are not supported for key server.host"},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(testConfig), tt.path, tt.newValue)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	getTests := []struct {
+		path string
+		want string
+	}{
+		{"name", "app"},
+		{"server.port", "8080"},
+		{"database.url", "postgres://db/app"},
+	}
+
+	for _, tt := range getTests {
+		got, err := GetBytes([]byte(testConfig), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetBytes(%s) got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	_, err := GetBytes([]byte(testConfig), "database.host")
+	if !test.MatchError(t, "key not found: database.host", err) {
+		t.Errorf("error got %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	deleteTests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{"key in a section", "server.port", "; The defaults\nname = app\n\n[server]\nhost = localhost\n\n[database]\nurl: \"postgres://db/app\"\n"},
+		{"missing key", "server.name", testConfig},
+		{"key in another section", "database.port", testConfig},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := DeleteBytes([]byte(testConfig), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+}
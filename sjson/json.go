@@ -0,0 +1,179 @@
+package sjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// SetBytes accepts a JSON body, a path and a new value, and updates the
+// specific key in the JSON body using the path.
+//
+// e.g. SetBytes([]byte(`{"name": "testing"}`), "name", "new name") would
+// return `{"name": "new name"}`.
+//
+// The formatting of the original document is preserved, new keys and
+// structured values are indented to match the surrounding document.
+func SetBytes(j []byte, path string, value interface{}) ([]byte, error) {
+	if !gjson.ValidBytes(j) {
+		return nil, errInvalidJSON
+	}
+	segments, ok := splitPath(path)
+	if !ok || !isMultiline(j) {
+		return sjson.SetBytes(j, path, value)
+	}
+	if existing := gjson.GetBytes(j, path); existing.Exists() {
+		if isScalar(value) {
+			return sjson.SetBytes(j, path, value)
+		}
+		raw, err := marshalIndent(value, lineIndent(j, existing.Index), detectIndent(j), lineEnding(j))
+		if err != nil {
+			return nil, err
+		}
+		return sjson.SetRawBytes(j, path, raw)
+	}
+	return insertKey(j, path, segments, value)
+}
+
+// GetBytes returns the value at the path in the JSON body, strings are
+// returned unquoted, and other values as they're written in the body.
+func GetBytes(j []byte, path string) (string, error) {
+	if !gjson.ValidBytes(j) {
+		return "", errInvalidJSON
+	}
+	r := gjson.GetBytes(j, path)
+	if !r.Exists() {
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	return r.String(), nil
+}
+
+// DeleteBytes removes the key at the path from the JSON body, the body is
+// returned unchanged if the key doesn't exist.
+func DeleteBytes(j []byte, path string) ([]byte, error) {
+	if !gjson.ValidBytes(j) {
+		return nil, errInvalidJSON
+	}
+	if !gjson.GetBytes(j, path).Exists() {
+		return j, nil
+	}
+	return sjson.DeleteBytes(j, path)
+}
+
+// insertKey adds a new key to the deepest existing object on the path.
+func insertKey(j []byte, path string, segments []string, value interface{}) ([]byte, error) {
+	depth := len(segments) - 1
+	var parent gjson.Result
+	for ; depth > 0; depth-- {
+		parent = gjson.GetBytes(j, joinPath(segments[:depth]))
+		if parent.Exists() {
+			break
+		}
+	}
+	start, closing := -1, -1
+	if depth == 0 {
+		parent = gjson.ParseBytes(j)
+		start, closing = bytes.IndexByte(j, '{'), bytes.LastIndexByte(j, '}')
+	} else {
+		start, closing = parent.Index, parent.Index+len(parent.Raw)-1
+	}
+	if !parent.IsObject() || start < 0 || !bytes.Contains(j[start:closing], []byte("\n")) {
+		return sjson.SetBytes(j, path, value)
+	}
+	for i := len(segments) - 1; i > depth; i-- {
+		value = map[string]interface{}{segments[i]: value}
+	}
+	last := closing - 1
+	for last > start && isSpace(j[last]) {
+		last--
+	}
+	eol, indent := lineEnding(j), detectIndent(j)
+	memberIndent := lineIndent(j, closing) + indent
+	raw, err := marshalIndent(value, memberIndent, indent, eol)
+	if err != nil {
+		return nil, err
+	}
+	key, err := json.Marshal(segments[depth])
+	if err != nil {
+		return nil, err
+	}
+	var insert bytes.Buffer
+	from, to := last+1, last+1
+	if last == start {
+		// The object is empty, the whitespace inside it is replaced.
+		to = closing
+	} else {
+		insert.WriteString(",")
+	}
+	insert.WriteString(eol + memberIndent)
+	insert.Write(key)
+	insert.WriteString(": ")
+	insert.Write(raw)
+	if last == start {
+		insert.WriteString(eol + lineIndent(j, closing))
+	}
+	updated := make([]byte, 0, len(j)+insert.Len())
+	updated = append(updated, j[:from]...)
+	updated = append(updated, insert.Bytes()...)
+	return append(updated, j[to:]...), nil
+}
+
+func marshalIndent(value interface{}, prefix, indent, eol string) ([]byte, error) {
+	b, err := json.MarshalIndent(value, prefix, indent)
+	if err != nil {
+		return nil, err
+	}
+	if eol != "\n" {
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte(eol))
+	}
+	return b, nil
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case nil, string, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return true
+	}
+	return false
+}
+
+func isMultiline(j []byte) bool {
+	return bytes.Contains(bytes.TrimSpace(j), []byte("\n"))
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+func lineEnding(j []byte) string {
+	if bytes.Contains(j, []byte("\r\n")) {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// lineIndent returns the leading whitespace of the line containing the
+// offset.
+func lineIndent(j []byte, offset int) string {
+	start := bytes.LastIndexByte(j[:offset], '\n') + 1
+	end := start
+	for end < len(j) && (j[end] == ' ' || j[end] == '\t') {
+		end++
+	}
+	return string(j[start:end])
+}
+
+// detectIndent returns the indentation used for the first indented line in
+// the document, defaulting to two spaces.
+func detectIndent(j []byte) string {
+	for _, line := range bytes.Split(j, []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		if len(trimmed) != len(line) && len(bytes.TrimSpace(trimmed)) > 0 {
+			return string(line[:len(line)-len(trimmed)])
+		}
+	}
+	return "  "
+}
@@ -0,0 +1,172 @@
+package sjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSetBytes(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "compact document",
+			source:   `{"name":"testing"}`,
+			path:     "name",
+			newValue: "new name",
+			want:     `{"name":"new name"}`,
+		},
+		{
+			name:     "nested value",
+			source:   "{\n  \"person\": {\n    \"name\": \"John\",\n    \"age\": 30\n  }\n}\n",
+			path:     "person.name",
+			newValue: "Anderson",
+			want:     "{\n  \"person\": {\n    \"name\": \"Anderson\",\n    \"age\": 30\n  }\n}\n",
+		},
+		{
+			name:     "array index",
+			source:   "{\n    \"items\": [\n        {\"age\": 30},\n        {\"age\": 29}\n    ]\n}\n",
+			path:     "items.1.age",
+			newValue: 20,
+			want:     "{\n    \"items\": [\n        {\"age\": 30},\n        {\"age\": 20}\n    ]\n}\n",
+		},
+		{
+			name:     "new key with four space indentation",
+			source:   "{\n    \"a\": 1,\n    \"arr\": [1, 2]\n}\n",
+			path:     "b",
+			newValue: "two",
+			want:     "{\n    \"a\": 1,\n    \"arr\": [1, 2],\n    \"b\": \"two\"\n}\n",
+		},
+		{
+			name:     "new nested key",
+			source:   "{\n  \"a\": {\n    \"x\": \"y\"\n  }\n}\n",
+			path:     "a.z",
+			newValue: true,
+			want:     "{\n  \"a\": {\n    \"x\": \"y\",\n    \"z\": true\n  }\n}\n",
+		},
+		{
+			name:     "new key with missing parents",
+			source:   "{\n  \"a\": 1\n}\n",
+			path:     "b.c",
+			newValue: 2,
+			want:     "{\n  \"a\": 1,\n  \"b\": {\n    \"c\": 2\n  }\n}\n",
+		},
+		{
+			name:     "new key in an empty object",
+			source:   "{\n  \"a\": {\n  }\n}\n",
+			path:     "a.b",
+			newValue: 1,
+			want:     "{\n  \"a\": {\n    \"b\": 1\n  }\n}\n",
+		},
+		{
+			name:     "structured value",
+			source:   "{\n  \"resources\": {}\n}\n",
+			path:     "resources",
+			newValue: map[string]interface{}{"limits": map[string]string{"cpu": "1"}},
+			want:     "{\n  \"resources\": {\n    \"limits\": {\n      \"cpu\": \"1\"\n    }\n  }\n}\n",
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "{\r\n  \"a\": 1\r\n}\r\n",
+			path:     "b",
+			newValue: 2,
+			want:     "{\r\n  \"a\": 1,\r\n  \"b\": 2\r\n}\r\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if string(updated) != tt.want {
+				rt.Errorf("got %#v, want %#v", string(updated), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBytesFailures(t *testing.T) {
+	setTests := []struct {
+		source  string
+		path    string
+		wantErr string
+	}{
+		{
+			source:  `{"name": `,
+			path:    "name",
+			wantErr: "invalid JSON document",
+		},
+		{
+			source:  `{"name": "testing"}`,
+			path:    "",
+			wantErr: "path cannot be empty",
+		},
+	}
+
+	for i, tt := range setTests {
+		_, err := SetBytes([]byte(tt.source), tt.path, "testing")
+		if err == nil || err.Error() != tt.wantErr {
+			t.Fatalf("%d failed, got %v, want %s", i, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	source := []byte(`{"name": "testing", "spec": {"replicas": 2, "ports": [80, 443]}}`)
+	getTests := []struct {
+		path string
+		want string
+	}{
+		{"name", "testing"},
+		{"spec.replicas", "2"},
+		{"spec.ports", "[80, 443]"},
+	}
+
+	for _, tt := range getTests {
+		got, err := GetBytes(source, tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetBytes(%s) got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	_, err := GetBytes(source, "spec.image")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestDeleteBytes(t *testing.T) {
+	deleteTests := []struct {
+		source string
+		path   string
+		want   string
+	}{
+		{"{\n  \"name\": \"testing\",\n  \"replicas\": 2\n}\n", "replicas", "{\n  \"name\": \"testing\"\n}\n"},
+		{"{\n  \"spec\": {\n    \"replicas\": 2,\n    \"image\": \"app\"\n  }\n}\n", "spec.replicas", "{\n  \"spec\": {\n    \"image\": \"app\"\n  }\n}\n"},
+		{"{\"name\": \"testing\"}", "replicas", "{\"name\": \"testing\"}"},
+	}
+
+	for i, tt := range deleteTests {
+		updated, err := DeleteBytes([]byte(tt.source), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(updated) != tt.want {
+			t.Errorf("%d failed, got %#v, want %#v", i, string(updated), tt.want)
+		}
+	}
+
+	if _, err := DeleteBytes([]byte(`{"name": `), "name"); err != errInvalidJSON {
+		t.Fatalf("got %v, want %v", err, errInvalidJSON)
+	}
+}
@@ -0,0 +1,55 @@
+package sjson
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/agill17/pkg/internal/dotpath"
+)
+
+var errInvalidJSON = errors.New("invalid JSON document")
+
+// ErrKeyNotFound is returned when the path being read doesn't exist in the
+// document.
+var ErrKeyNotFound = errors.New("key not found")
+
+// splitPath splits a dotted path into the individual segments, dots can be
+// escaped with a backslash.
+//
+// Returns false if the path uses syntax that is handled directly by sjson
+// e.g. wildcards or appending to arrays.
+func splitPath(path string) ([]string, bool) {
+	if path == "" || hasSyntax(path) {
+		return nil, false
+	}
+	segments := dotpath.Split(path)
+	for _, s := range segments {
+		if s == "-1" {
+			return nil, false
+		}
+	}
+	return segments, true
+}
+
+// hasSyntax returns true if the path has unescaped characters with a meaning
+// in sjson paths.
+func hasSyntax(path string) bool {
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '\\':
+			i++
+		case '*', '?', '#', '|', '@', ':':
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath is the inverse of splitPath, escaping dots in the segments.
+func joinPath(segments []string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = strings.ReplaceAll(s, ".", `\.`)
+	}
+	return strings.Join(escaped, ".")
+}
@@ -0,0 +1,267 @@
+package skv
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrKeyNotFound is returned when the key being read doesn't exist in the
+// document.
+var ErrKeyNotFound = errors.New("key not found")
+
+// SetBytes accepts a key=value body, e.g. a .env or Java .properties file, a
+// key and a new value, and updates the value of the key in the body.
+//
+// e.g. SetBytes([]byte("# The port\nPORT=8080\n"), "PORT", 9090) would return
+// "# The port\nPORT=9090\n"
+//
+// Comments, ordering, separators and quoting are preserved, every occurrence
+// of the key is updated, if the key doesn't exist, it's appended to the end of
+// the body.
+func SetBytes(b []byte, key string, value interface{}) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("empty key")
+	}
+	v := fmt.Sprint(value)
+	if strings.ContainsAny(v, "\r\n") {
+		return nil, fmt.Errorf("multi-line values are not supported for key %s", key)
+	}
+	doc := parse(b)
+	found := false
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		e := doc.entries[i]
+		if e.key != key {
+			continue
+		}
+		found = true
+		quote := e.quote
+		if quote == "'" && strings.Contains(v, "'") {
+			quote = `"`
+		}
+		l := doc.lines[e.line]
+		rendered := quote + escape(v, quote) + quote
+		doc.lines[e.line] = l[:e.valueStart] + rendered + l[e.valueEnd:]
+		if e.lastLine > e.line {
+			doc.lines = append(doc.lines[:e.line+1], doc.lines[e.lastLine+1:]...)
+		}
+	}
+	if !found {
+		separator := "="
+		if len(doc.entries) > 0 {
+			separator = doc.entries[len(doc.entries)-1].separator
+		}
+		doc.lines = append(doc.lines, key+separator+escape(v, ""))
+		if len(doc.lines) == 1 {
+			doc.trailingNewline = true
+		}
+	}
+	return doc.bytes(), nil
+}
+
+// GetBytes returns the value of the key in the body, where the key appears
+// several times, the last value is returned.
+func GetBytes(b []byte, key string) (string, error) {
+	doc := parse(b)
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		if e := doc.entries[i]; e.key == key {
+			return e.value, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+}
+
+// DeleteBytes removes every occurrence of the key from the body, the body is
+// returned unchanged if the key doesn't exist.
+func DeleteBytes(b []byte, key string) ([]byte, error) {
+	if key == "" {
+		return nil, errors.New("empty key")
+	}
+	doc := parse(b)
+	found := false
+	for i := len(doc.entries) - 1; i >= 0; i-- {
+		if e := doc.entries[i]; e.key == key {
+			found = true
+			doc.lines = append(doc.lines[:e.line], doc.lines[e.lastLine+1:]...)
+		}
+	}
+	if !found {
+		return b, nil
+	}
+	return doc.bytes(), nil
+}
+
+type document struct {
+	lines           []string
+	eol             string
+	trailingNewline bool
+	entries         []*entry
+}
+
+type entry struct {
+	key        string
+	value      string
+	separator  string
+	quote      string
+	line       int
+	lastLine   int
+	valueStart int
+	valueEnd   int
+}
+
+func parse(b []byte) *document {
+	s := string(b)
+	doc := &document{eol: "\n", trailingNewline: strings.HasSuffix(s, "\n")}
+	if strings.Contains(s, "\r\n") {
+		doc.eol = "\r\n"
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s != "" {
+		doc.lines = strings.Split(s, "\n")
+	}
+	for i := 0; i < len(doc.lines); i++ {
+		e := parseEntry(doc.lines[i])
+		if e == nil {
+			continue
+		}
+		e.line, e.lastLine = i, i
+		// Unquoted values ending in a backslash continue on the next line.
+		for e.quote == "" && continues(doc.lines[e.lastLine]) && e.lastLine+1 < len(doc.lines) {
+			e.lastLine++
+			e.value = strings.TrimSuffix(e.value, `\`) + strings.TrimLeft(doc.lines[e.lastLine], " \t")
+		}
+		if e.lastLine > e.line {
+			e.valueEnd = len(doc.lines[e.line])
+		}
+		doc.entries = append(doc.entries, e)
+		i = e.lastLine
+	}
+	return doc
+}
+
+// parseEntry parses a line, returning nil for blank lines and comments.
+//
+// Inline comments are only recognised after quoted values, as unquoted values
+// in .properties files can contain "#".
+func parseEntry(l string) *entry {
+	start := len(l) - len(strings.TrimLeft(l, " \t"))
+	trimmed := l[start:]
+	if trimmed == "" || trimmed[0] == '#' || trimmed[0] == '!' {
+		return nil
+	}
+	if strings.HasPrefix(trimmed, "export ") {
+		start += len("export ")
+		for start < len(l) && (l[start] == ' ' || l[start] == '\t') {
+			start++
+		}
+	}
+	keyEnd := start
+	for keyEnd < len(l) && !strings.ContainsRune("=: \t", rune(l[keyEnd])) {
+		if l[keyEnd] == '\\' {
+			keyEnd++
+		}
+		keyEnd++
+	}
+	if keyEnd > len(l) {
+		keyEnd = len(l)
+	}
+	valueStart := keyEnd
+	for valueStart < len(l) && (l[valueStart] == ' ' || l[valueStart] == '\t') {
+		valueStart++
+	}
+	if valueStart < len(l) && (l[valueStart] == '=' || l[valueStart] == ':') {
+		valueStart++
+		for valueStart < len(l) && (l[valueStart] == ' ' || l[valueStart] == '\t') {
+			valueStart++
+		}
+	}
+	e := &entry{
+		key:        unescape(l[start:keyEnd]),
+		separator:  l[keyEnd:valueStart],
+		valueStart: valueStart,
+		valueEnd:   len(l),
+	}
+	if e.separator == "" {
+		e.separator = "="
+	}
+	rest := l[valueStart:]
+	if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+		if end := closingQuote(rest); end > 0 {
+			e.quote = rest[:1]
+			e.valueEnd = valueStart + end + 1
+			e.value = unescape(rest[1:end])
+			if e.quote == "'" {
+				e.value = rest[1:end]
+			}
+			return e
+		}
+	}
+	e.value = unescape(strings.TrimRight(rest, " \t"))
+	e.valueEnd = valueStart + len(strings.TrimRight(rest, " \t"))
+	return e
+}
+
+// closingQuote returns the index of the quote that closes the quoted string
+// at the start of s, or -1.
+func closingQuote(s string) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && s[0] == '"' {
+			i++
+			continue
+		}
+		if s[i] == s[0] {
+			return i
+		}
+	}
+	return -1
+}
+
+func continues(l string) bool {
+	trailing := len(l) - len(strings.TrimRight(l, `\`))
+	return trailing%2 == 1
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// escape escapes the value for writing with the quote, single-quoted values
+// are written as is.
+func escape(v, quote string) string {
+	switch quote {
+	case "'":
+		return v
+	case `"`:
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v)
+	}
+	return strings.ReplaceAll(v, `\`, `\\`)
+}
+
+func (d *document) bytes() []byte {
+	s := strings.Join(d.lines, d.eol)
+	if d.trailingNewline {
+		s += d.eol
+	}
+	return []byte(s)
+}
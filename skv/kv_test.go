@@ -0,0 +1,177 @@
+package skv
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestSet(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		key      string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "env file",
+			source:   "# The image\nIMAGE=example/app:v1\nPORT=8080\n",
+			key:      "IMAGE",
+			newValue: "example/app:v2",
+			want:     "# The image\nIMAGE=example/app:v2\nPORT=8080\n",
+		},
+		{
+			name:     "exported and quoted values",
+			source:   "export IMAGE=\"example/app:v1\" # pinned\nexport NAME='app'\n",
+			key:      "IMAGE",
+			newValue: "example/app:v2",
+			want:     "export IMAGE=\"example/app:v2\" # pinned\nexport NAME='app'\n",
+		},
+		{
+			name:     "single quotes switch to double quotes",
+			source:   "NAME='app'\n",
+			key:      "NAME",
+			newValue: "it's",
+			want:     "NAME=\"it's\"\n",
+		},
+		{
+			name:     "properties separators",
+			source:   "! Settings\nserver.port : 8080\nserver.host   localhost\n",
+			key:      "server.host",
+			newValue: "example.com",
+			want:     "! Settings\nserver.port : 8080\nserver.host   example.com\n",
+		},
+		{
+			name:     "continued value",
+			source:   "servers = one,\\\n    two\nport = 80\n",
+			key:      "servers",
+			newValue: "three",
+			want:     "servers = three\nport = 80\n",
+		},
+		{
+			name:     "numeric value",
+			source:   "replicas=2\n",
+			key:      "replicas",
+			newValue: 3,
+			want:     "replicas=3\n",
+		},
+		{
+			name:     "new key uses the last separator",
+			source:   "a = 1\nb = 2\n",
+			key:      "c",
+			newValue: "3",
+			want:     "a = 1\nb = 2\nc = 3\n",
+		},
+		{
+			name:     "new key in an empty file",
+			source:   "",
+			key:      "IMAGE",
+			newValue: "app",
+			want:     "IMAGE=app\n",
+		},
+		{
+			name:     "duplicate keys are all updated",
+			source:   "A=1\nB=2\nA=3\n",
+			key:      "A",
+			newValue: "4",
+			want:     "A=4\nB=2\nA=4\n",
+		},
+		{
+			name:     "windows line endings",
+			source:   "A=1\r\nB=2\r\n",
+			key:      "B",
+			newValue: "3",
+			want:     "A=1\r\nB=3\r\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.key, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFailures(t *testing.T) {
+	setTests := []struct {
+		name     string
+		key      string
+		newValue interface{}
+		wantErr  string
+	}{
+		{"empty key", "", "test", "empty key"},
+		{"multi-line value", "A", "one\ntwo", "multi-line values are not supported for key A"},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte("A=1\n"), tt.key, tt.newValue)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	source := []byte("export IMAGE=\"example/app:v1\" # pinned\nservers = one,\\\n    two\nA=1\nA=2\n")
+	getTests := []struct {
+		key  string
+		want string
+	}{
+		{"IMAGE", "example/app:v1"},
+		{"servers", "one,two"},
+		{"A", "2"},
+	}
+
+	for _, tt := range getTests {
+		got, err := GetBytes(source, tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetBytes(%s) got %#v, want %#v", tt.key, got, tt.want)
+		}
+	}
+
+	_, err := GetBytes(source, "MISSING")
+	if !test.MatchError(t, "key not found: MISSING", err) {
+		t.Errorf("error got %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	deleteTests := []struct {
+		name   string
+		source string
+		key    string
+		want   string
+	}{
+		{"single key", "# The image\nIMAGE=app\nPORT=8080\n", "PORT", "# The image\nIMAGE=app\n"},
+		{"continued value", "servers = one,\\\n    two\nport = 80\n", "servers", "port = 80\n"},
+		{"duplicate keys", "A=1\nB=2\nA=3\n", "A", "B=2\n"},
+		{"crlf line endings", "A=1\r\nB=2\r\n", "A", "B=2\r\n"},
+		{"missing key", "A=1\n", "B", "A=1\n"},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := DeleteBytes([]byte(tt.source), tt.key)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+}
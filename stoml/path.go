@@ -0,0 +1,100 @@
+package stoml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/agill17/pkg/internal/dotpath"
+)
+
+var errEmptyPath = errors.New("empty path")
+
+// splitPath splits a dotted path into the individual segments, dots can be
+// escaped with a backslash e.g. "tool.example\.com".
+func splitPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, errEmptyPath
+	}
+	return dotpath.Split(path), nil
+}
+
+// parseKey parses a TOML key, which may be bare, quoted or dotted, into the
+// individual segments.
+func parseKey(s string) ([]string, error) {
+	segments := []string{}
+	s = strings.TrimSpace(s)
+	for {
+		var segment string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := 1
+			for ; end < len(s) && s[end] != '"'; end++ {
+				if s[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			unquoted, err := unquoteBasic(s[1:end])
+			if err != nil {
+				return nil, err
+			}
+			segment, s = unquoted, s[end+1:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.Index(s[1:], "'")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			segment, s = s[1:end+1], s[end+2:]
+		default:
+			end := 0
+			for end < len(s) && isBareKeyChar(s[end]) {
+				end++
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("invalid key %q", s)
+			}
+			segment, s = s[:end], s[end:]
+		}
+		segments = append(segments, segment)
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return segments, nil
+		}
+		if s[0] != '.' {
+			return nil, fmt.Errorf("invalid key %q", s)
+		}
+		s = strings.TrimSpace(s[1:])
+	}
+}
+
+// renderKey renders the segments as a dotted key, quoting segments that can't
+// be bare keys.
+func renderKey(segments []string) string {
+	rendered := make([]string, len(segments))
+	for i, s := range segments {
+		rendered[i] = s
+		if !isBareKey(s) {
+			rendered[i] = quoteBasic(s)
+		}
+	}
+	return strings.Join(rendered, ".")
+}
+
+func isBareKey(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isBareKeyChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
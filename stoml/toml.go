@@ -0,0 +1,368 @@
+package stoml
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ErrKeyNotFound is returned when the path being read or deleted doesn't
+// exist in the document.
+var ErrKeyNotFound = errors.New("key not found")
+
+// SetBytes accepts a TOML body, a path and a new value, and updates the
+// specific key in the TOML body using the path.
+//
+// e.g. SetBytes([]byte("[server]\nport = 8080\n"), "server.port", 9090) would
+// return "[server]\nport = 9090\n"
+//
+// The document is edited line by line, so comments and formatting are
+// preserved, if the key doesn't exist, it's added to the end of the deepest
+// existing table on the path, or to a new table.
+func SetBytes(t []byte, path string, value interface{}) ([]byte, error) {
+	doc, err := parse(t)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if kv := doc.find(segments); kv != nil {
+		rendered, err := renderValue(value, kv.literal())
+		if err != nil {
+			return nil, err
+		}
+		l := doc.lines[kv.line]
+		doc.lines[kv.line] = l[:kv.valueStart] + rendered + l[kv.valueEnd:]
+		return doc.bytes()
+	}
+	rendered, err := renderValue(value, false)
+	if err != nil {
+		return nil, err
+	}
+	table, key := doc.closestTable(segments)
+	if table == nil {
+		line := renderKey(segments[len(segments)-1:]) + " = " + rendered
+		header := "[" + renderKey(segments[:len(segments)-1]) + "]"
+		if len(doc.lines) > 0 && strings.TrimSpace(doc.lines[len(doc.lines)-1]) != "" {
+			doc.lines = append(doc.lines, "")
+		}
+		doc.lines = append(doc.lines, header, line)
+		return doc.bytes()
+	}
+	doc.insert(table.insertAt, renderKey(key)+" = "+rendered)
+	return doc.bytes()
+}
+
+// GetBytes returns the value at the path in the TOML body.
+func GetBytes(t []byte, path string) (interface{}, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if _, err := toml.Decode(string(t), &data); err != nil {
+		return nil, err
+	}
+	var current interface{} = data
+	for _, s := range segments {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+		}
+		if current, ok = m[s]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+		}
+	}
+	return current, nil
+}
+
+// DeleteBytes removes the key at the path from the TOML body.
+func DeleteBytes(t []byte, path string) ([]byte, error) {
+	doc, err := parse(t)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	kv := doc.find(segments)
+	if kv == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	doc.lines = append(doc.lines[:kv.line], doc.lines[kv.line+1:]...)
+	return doc.bytes()
+}
+
+type document struct {
+	lines           []string
+	eol             string
+	trailingNewline bool
+	keys            []*keyValue
+	tables          []*table
+}
+
+type keyValue struct {
+	line       int
+	path       []string
+	valueStart int
+	valueEnd   int
+	raw        string
+}
+
+func (kv *keyValue) literal() bool {
+	return strings.HasPrefix(kv.raw, "'")
+}
+
+type table struct {
+	path     []string
+	insertAt int
+}
+
+func parse(t []byte) (*document, error) {
+	var check map[string]interface{}
+	if _, err := toml.Decode(string(t), &check); err != nil {
+		return nil, err
+	}
+	s := string(t)
+	doc := &document{eol: "\n", trailingNewline: strings.HasSuffix(s, "\n")}
+	if strings.Contains(s, "\r\n") {
+		doc.eol = "\r\n"
+	}
+	s = strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	if s != "" {
+		doc.lines = strings.Split(s, "\n")
+	}
+	root := &table{}
+	doc.tables = append(doc.tables, root)
+	current := root
+	for i := 0; i < len(doc.lines); i++ {
+		trimmed := strings.TrimSpace(doc.lines[i])
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+		case strings.HasPrefix(trimmed, "[["):
+			// Keys in arrays of tables are not addressable.
+			current = &table{}
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.LastIndex(trimmed, "]")
+			path, err := parseKey(trimmed[1:end])
+			if err != nil {
+				return nil, err
+			}
+			current = &table{path: path, insertAt: i + 1}
+			doc.tables = append(doc.tables, current)
+		default:
+			kv, lastLine, err := parseKeyValue(doc.lines, i)
+			if err != nil {
+				return nil, err
+			}
+			if current.path != nil || current == root {
+				kv.path = append(append([]string{}, current.path...), kv.path...)
+				doc.keys = append(doc.keys, kv)
+			}
+			i = lastLine
+			current.insertAt = i + 1
+		}
+	}
+	return doc, nil
+}
+
+func (d *document) find(path []string) *keyValue {
+	for _, kv := range d.keys {
+		if equalPaths(kv.path, path) && kv.valueEnd >= 0 {
+			return kv
+		}
+	}
+	return nil
+}
+
+// closestTable returns the table with the longest path that is a prefix of the
+// path, and the remaining key.
+//
+// Nested keys are not added to the root table, so no table is returned if
+// there's no table that the key belongs to.
+func (d *document) closestTable(path []string) (*table, []string) {
+	var closest *table
+	for _, t := range d.tables {
+		if len(t.path) < len(path) && equalPaths(t.path, path[:len(t.path)]) {
+			if closest == nil || len(t.path) > len(closest.path) {
+				closest = t
+			}
+		}
+	}
+	if closest == nil || len(closest.path) == 0 && len(path) > 1 {
+		return nil, nil
+	}
+	return closest, path[len(closest.path):]
+}
+
+func (d *document) insert(at int, line string) {
+	d.lines = append(d.lines, "")
+	copy(d.lines[at+1:], d.lines[at:])
+	d.lines[at] = line
+	for _, t := range d.tables {
+		if t.insertAt >= at {
+			t.insertAt++
+		}
+	}
+}
+
+func (d *document) bytes() ([]byte, error) {
+	s := strings.Join(d.lines, d.eol)
+	if d.trailingNewline {
+		s += d.eol
+	}
+	var check map[string]interface{}
+	if _, err := toml.Decode(s, &check); err != nil {
+		return nil, fmt.Errorf("updated document is invalid: %w", err)
+	}
+	return []byte(s), nil
+}
+
+// parseKeyValue parses the key and the position of the value in a key/value
+// line, returning the last line of the value, which may be a multi-line
+// array, or string.
+//
+// Values that span multiple lines are not replaceable and have a valueEnd of
+// -1.
+func parseKeyValue(lines []string, i int) (*keyValue, int, error) {
+	l := lines[i]
+	eq := keyEnd(l)
+	if eq == -1 {
+		return nil, 0, fmt.Errorf("invalid key/value on line %d: %q", i+1, l)
+	}
+	path, err := parseKey(l[:eq])
+	if err != nil {
+		return nil, 0, err
+	}
+	start := eq + 1
+	for start < len(l) && (l[start] == ' ' || l[start] == '\t') {
+		start++
+	}
+	kv := &keyValue{line: i, path: path, valueStart: start, valueEnd: -1}
+	rest := l[start:]
+	if strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, `'''`) {
+		delim := rest[:3]
+		if strings.Contains(rest[3:], delim) {
+			return kv, i, nil
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if strings.Contains(lines[j], delim) {
+				return kv, j, nil
+			}
+		}
+		return kv, len(lines) - 1, nil
+	}
+	end, complete := valueEnd(rest)
+	if !complete {
+		// A multi-line array or inline table, the value is closed by the
+		// first line that balances the brackets.
+		depth := bracketDepth(rest)
+		for j := i + 1; j < len(lines); j++ {
+			depth += bracketDepth(lines[j])
+			if depth <= 0 {
+				return kv, j, nil
+			}
+		}
+		return kv, len(lines) - 1, nil
+	}
+	kv.valueEnd = start + end
+	kv.raw = rest[:end]
+	return kv, i, nil
+}
+
+// keyEnd returns the index of the "=" that separates a key from the value.
+func keyEnd(l string) int {
+	var quote byte
+	for i := 0; i < len(l); i++ {
+		c := l[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// valueEnd returns the length of the value at the start of the string, and
+// false if the value is not completed on the line.
+func valueEnd(s string) (int, bool) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+				if depth == 0 {
+					return i + 1, true
+				}
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case depth == 0 && (c == '#' || c == ' ' || c == '\t'):
+			return i, true
+		}
+	}
+	return len(s), depth == 0
+}
+
+func bracketDepth(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return depth
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+func equalPaths(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
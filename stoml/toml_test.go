@@ -0,0 +1,174 @@
+package stoml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestSet(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "top-level key",
+			source:   "name = \"testing\"\n",
+			path:     "name",
+			newValue: "new name",
+			want:     "name = \"new name\"\n",
+		},
+		{
+			name:     "key in table",
+			source:   "# The server\n[server]\nhost = \"localhost\"\nport = 8080 # the port\n",
+			path:     "server.port",
+			newValue: 9090,
+			want:     "# The server\n[server]\nhost = \"localhost\"\nport = 9090 # the port\n",
+		},
+		{
+			name:     "literal string",
+			source:   "[image]\nname   =   'example/app:v1'\n",
+			path:     "image.name",
+			newValue: "example/app:v2",
+			want:     "[image]\nname   =   'example/app:v2'\n",
+		},
+		{
+			name:     "quoted table and key",
+			source:   "[\"example.com\"]\n\"my key\" = 1\n",
+			path:     "example\\.com.my key",
+			newValue: 2,
+			want:     "[\"example.com\"]\n\"my key\" = 2\n",
+		},
+		{
+			name:     "float",
+			source:   "ratio = 0.5\n",
+			path:     "ratio",
+			newValue: 1.0,
+			want:     "ratio = 1.0\n",
+		},
+		{
+			name:     "array",
+			source:   "ports = [80, 443]\n",
+			path:     "ports",
+			newValue: []int{8080, 8443},
+			want:     "ports = [8080, 8443]\n",
+		},
+		{
+			name:     "inline table",
+			source:   "[app]\nlimits = {}\n",
+			path:     "app.limits",
+			newValue: map[string]interface{}{"cpu": "1", "memory": "1Gi"},
+			want:     "[app]\nlimits = { cpu = \"1\", memory = \"1Gi\" }\n",
+		},
+		{
+			name:     "new key in existing table",
+			source:   "title = \"test\"\n\n[server]\nhost = \"localhost\"\n\n[client]\nretries = 3\n",
+			path:     "server.port",
+			newValue: 8080,
+			want:     "title = \"test\"\n\n[server]\nhost = \"localhost\"\nport = 8080\n\n[client]\nretries = 3\n",
+		},
+		{
+			name:     "new top-level key",
+			source:   "title = \"test\"\n\n[server]\nhost = \"localhost\"\n",
+			path:     "owner",
+			newValue: "me",
+			want:     "title = \"test\"\nowner = \"me\"\n\n[server]\nhost = \"localhost\"\n",
+		},
+		{
+			name:     "new table",
+			source:   "title = \"test\"\n",
+			path:     "server.host",
+			newValue: "localhost",
+			want:     "title = \"test\"\n\n[server]\nhost = \"localhost\"\n",
+		},
+		{
+			name:     "windows line endings",
+			source:   "[server]\r\nport = 8080\r\n",
+			path:     "server.port",
+			newValue: 9090,
+			want:     "[server]\r\nport = 9090\r\n",
+		},
+		{
+			name:     "keys in arrays of tables are ignored",
+			source:   "[[servers]]\nport = 8080\n\n[app]\nport = 80\n",
+			path:     "app.port",
+			newValue: 81,
+			want:     "[[servers]]\nport = 8080\n\n[app]\nport = 81\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFailures(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		wantErr  string
+	}{
+		{"invalid document", "name = \n", "name", "test", "Near line 1"},
+		{"empty path", "name = \"test\"\n", "", "test", "empty path"},
+		{"null value", "name = \"test\"\n", "name", nil, "TOML does not support null values"},
+		{"conflicting table", "[server]\nport = 80\n", "server.port.number", 8080, "updated document is invalid"},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	source := []byte("[server]\nhost = \"localhost\"\nport = 8080\n")
+
+	v, err := GetBytes(source, "server.port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(int64(8080), v); diff != "" {
+		t.Errorf("failed to get:\n%s", diff)
+	}
+
+	_, err = GetBytes(source, "server.missing")
+	if !test.MatchError(t, "key not found: server.missing", err) {
+		t.Errorf("error got %s", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	source := []byte("[server]\nhost = \"localhost\" # the host\nport = 8080\n")
+
+	updated, err := DeleteBytes(source, "server.host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[server]\nport = 8080\n", string(updated)); diff != "" {
+		t.Errorf("failed to delete:\n%s", diff)
+	}
+
+	_, err = DeleteBytes(source, "server.missing")
+	if !test.MatchError(t, "key not found: server.missing", err) {
+		t.Errorf("error got %s", err)
+	}
+}
@@ -0,0 +1,368 @@
+package sxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/agill17/pkg/internal/dotpath"
+)
+
+// ErrKeyNotFound is returned when the element or attribute being read doesn't
+// exist in the document.
+var ErrKeyNotFound = errors.New("key not found")
+
+var errEmptyPath = errors.New("empty path")
+
+// SetBytes accepts an XML document, a path and a new value, and updates the
+// text of the element, or the value of the attribute, in the document.
+//
+// The path starts with the root element, followed by the child elements, a
+// numeric segment selects one of several elements with the same name, and
+// a last segment starting with "@" is an attribute, e.g.
+// "project.dependencies.dependency.1.version" would update the version of the
+// second dependency in a Maven pom.xml, and "Project.PropertyGroup.@Condition"
+// the Condition of a PropertyGroup.
+//
+// Comments and formatting are preserved, if the last element or attribute
+// doesn't exist, it's added to its parent, which must exist.
+func SetBytes(b []byte, path string, value interface{}) ([]byte, error) {
+	doc, err := parse(b)
+	if err != nil {
+		return nil, err
+	}
+	segments := dotpath.Split(path)
+	if len(segments) == 0 {
+		return nil, errEmptyPath
+	}
+	v := escape(fmt.Sprint(value))
+	last := segments[len(segments)-1]
+	if strings.HasPrefix(last, "@") {
+		el, err := doc.find(segments[:len(segments)-1], path)
+		if err != nil {
+			return nil, err
+		}
+		if a := el.attr(last[1:]); a != nil {
+			return splice(b, a.valueStart, a.valueEnd, v), nil
+		}
+		at := el.tagEnd - 1
+		if el.selfClosing {
+			at = el.tagEnd - 2
+		}
+		for at > el.start && isSpace(b[at-1]) {
+			at--
+		}
+		return splice(b, at, at, fmt.Sprintf(` %s="%s"`, last[1:], v)), nil
+	}
+	el, err := doc.find(segments, path)
+	if errors.Is(err, ErrKeyNotFound) && !isIndex(last) {
+		parent, perr := doc.find(segments[:len(segments)-1], path)
+		if perr != nil || len(parent.children) == 0 {
+			return nil, err
+		}
+		return parent.insert(b, last, v), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(el.children) > 0 {
+		return nil, fmt.Errorf("element %s has child elements", path)
+	}
+	if el.selfClosing {
+		tag := bytes.TrimRightFunc(b[el.start:el.tagEnd-2], func(r rune) bool { return isSpace(byte(r)) })
+		return splice(b, el.start, el.end, fmt.Sprintf("%s>%s</%s>", tag, v, el.name)), nil
+	}
+	return splice(b, el.tagEnd, el.contentEnd, v), nil
+}
+
+// GetBytes returns the text of the element, or the value of the attribute, at
+// the path in the document, surrounding whitespace is trimmed from the text.
+func GetBytes(b []byte, path string) (string, error) {
+	doc, err := parse(b)
+	if err != nil {
+		return "", err
+	}
+	segments := dotpath.Split(path)
+	if len(segments) == 0 {
+		return "", errEmptyPath
+	}
+	last := segments[len(segments)-1]
+	if strings.HasPrefix(last, "@") {
+		el, err := doc.find(segments[:len(segments)-1], path)
+		if err != nil {
+			return "", err
+		}
+		if a := el.attr(last[1:]); a != nil {
+			return a.value, nil
+		}
+		return "", fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	el, err := doc.find(segments, path)
+	if err != nil {
+		return "", err
+	}
+	if len(el.children) > 0 {
+		return "", fmt.Errorf("element %s has child elements", path)
+	}
+	return strings.TrimSpace(el.text), nil
+}
+
+// DeleteBytes removes the element, or the attribute, at the path from the
+// document, the document is returned unchanged if it doesn't exist.
+//
+// Elements are removed along with their line, if nothing else is on it.
+func DeleteBytes(b []byte, path string) ([]byte, error) {
+	doc, err := parse(b)
+	if err != nil {
+		return nil, err
+	}
+	segments := dotpath.Split(path)
+	if len(segments) < 2 {
+		return nil, errors.New("the root element can't be deleted")
+	}
+	last := segments[len(segments)-1]
+	if strings.HasPrefix(last, "@") {
+		el, err := doc.find(segments[:len(segments)-1], path)
+		if errors.Is(err, ErrKeyNotFound) {
+			return b, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		a := el.attr(last[1:])
+		if a == nil {
+			return b, nil
+		}
+		start := a.start
+		for start > el.start && isSpace(b[start-1]) {
+			start--
+		}
+		return splice(b, start, a.valueEnd+1, ""), nil
+	}
+	el, err := doc.find(segments, path)
+	if errors.Is(err, ErrKeyNotFound) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	start, end := el.start, el.end
+	lineStart := bytes.LastIndexByte(b[:start], '\n') + 1
+	lineEnd := len(b)
+	if i := bytes.IndexByte(b[end:], '\n'); i >= 0 {
+		lineEnd = end + i + 1
+	}
+	if isBlank(b[lineStart:start]) && isBlank(b[end:lineEnd]) {
+		start, end = lineStart, lineEnd
+	}
+	return splice(b, start, end, ""), nil
+}
+
+type document struct {
+	root *element
+}
+
+type element struct {
+	name        string
+	start       int // the offset of the start tag
+	tagEnd      int // the offset after the start tag
+	contentEnd  int // the offset of the end tag
+	end         int // the offset after the end tag
+	selfClosing bool
+	text        string
+	attrs       []*attribute
+	children    []*element
+}
+
+type attribute struct {
+	name       string
+	value      string
+	start      int
+	valueStart int
+	valueEnd   int
+}
+
+func parse(b []byte) (*document, error) {
+	d := xml.NewDecoder(bytes.NewReader(b))
+	doc := &document{}
+	stack := []*element{}
+	for {
+		start := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the XML document: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			el := &element{name: qualifiedName(t.Name), start: start, tagEnd: int(d.InputOffset())}
+			el.selfClosing = bytes.HasSuffix(b[el.start:el.tagEnd], []byte("/>"))
+			el.attrs = parseAttrs(b, el)
+			if len(stack) == 0 {
+				if doc.root != nil {
+					return nil, errors.New("failed to parse the XML document: more than one root element")
+				}
+				doc.root = el
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, el)
+			}
+			stack = append(stack, el)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("failed to parse the XML document: unexpected end element %s", qualifiedName(t.Name))
+			}
+			el := stack[len(stack)-1]
+			el.contentEnd, el.end = start, int(d.InputOffset())
+			if el.selfClosing {
+				el.contentEnd = el.tagEnd
+			}
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if doc.root == nil || len(stack) > 0 {
+		return nil, errors.New("failed to parse the XML document: no complete root element")
+	}
+	return doc, nil
+}
+
+// parseAttrs scans the start tag of the element for the offsets of its
+// attributes.
+func parseAttrs(b []byte, el *element) []*attribute {
+	attrs := []*attribute{}
+	i := el.start + 1 + len(el.name)
+	for i < el.tagEnd {
+		for i < el.tagEnd && isSpace(b[i]) {
+			i++
+		}
+		nameStart := i
+		for i < el.tagEnd && !isSpace(b[i]) && b[i] != '=' && b[i] != '>' && b[i] != '/' {
+			i++
+		}
+		if i == nameStart {
+			break
+		}
+		a := &attribute{name: string(b[nameStart:i]), start: nameStart}
+		for i < el.tagEnd && (isSpace(b[i]) || b[i] == '=') {
+			i++
+		}
+		if i >= el.tagEnd || (b[i] != '"' && b[i] != '\'') {
+			break
+		}
+		quote := b[i]
+		a.valueStart = i + 1
+		end := bytes.IndexByte(b[a.valueStart:el.tagEnd], quote)
+		if end < 0 {
+			break
+		}
+		a.valueEnd = a.valueStart + end
+		a.value = unescape(b[a.valueStart:a.valueEnd])
+		attrs = append(attrs, a)
+		i = a.valueEnd + 1
+	}
+	return attrs
+}
+
+// find returns the element matching the segments, starting with the root.
+func (d *document) find(segments []string, path string) (*element, error) {
+	if len(segments) == 0 || segments[0] != d.root.name {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+	}
+	el := d.root
+	for i := 1; i < len(segments); i++ {
+		index := 0
+		if i+1 < len(segments) && isIndex(segments[i+1]) {
+			index, _ = strconv.Atoi(segments[i+1])
+		}
+		matches := []*element{}
+		for _, c := range el.children {
+			if c.name == segments[i] {
+				matches = append(matches, c)
+			}
+		}
+		if isIndex(segments[i]) || index < 0 || index >= len(matches) {
+			return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, path)
+		}
+		el = matches[index]
+		if i+1 < len(segments) && isIndex(segments[i+1]) {
+			i++
+		}
+	}
+	return el, nil
+}
+
+func (el *element) attr(name string) *attribute {
+	for _, a := range el.attrs {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// insert adds a new child element after the last child, with the same
+// indentation.
+func (el *element) insert(b []byte, name, value string) []byte {
+	last := el.children[len(el.children)-1]
+	lineStart := bytes.LastIndexByte(b[:last.start], '\n') + 1
+	separator := " "
+	if indent := b[lineStart:last.start]; isBlank(indent) {
+		separator = "\n" + string(indent)
+		if lineStart > 0 && b[lineStart-1] == '\n' && lineStart > 1 && b[lineStart-2] == '\r' {
+			separator = "\r" + separator
+		}
+	}
+	return splice(b, last.end, last.end, fmt.Sprintf("%s<%s>%s</%s>", separator, name, value, name))
+}
+
+func qualifiedName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+func isIndex(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isBlank(b []byte) bool {
+	return len(bytes.TrimLeft(b, " \t\r\n")) == 0
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	// EscapeText only fails if the writer does.
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func unescape(b []byte) string {
+	var s string
+	if err := xml.Unmarshal(append(append([]byte("<v>"), b...), "</v>"...), &s); err != nil {
+		return string(b)
+	}
+	return s
+}
+
+func splice(b []byte, start, end int, s string) []byte {
+	updated := make([]byte, 0, len(b)-(end-start)+len(s))
+	updated = append(updated, b[:start]...)
+	updated = append(updated, s...)
+	return append(updated, b[end:]...)
+}
@@ -0,0 +1,213 @@
+package sxml
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testPom = `<?xml version="1.0" encoding="UTF-8"?>
+<project>
+  <!-- The dependencies -->
+  <dependencies>
+    <dependency scope="compile">
+      <artifactId>app</artifactId>
+      <version>1.0.0</version>
+    </dependency>
+    <dependency>
+      <artifactId>lib</artifactId>
+      <version>2.0.0</version>
+      <optional/>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+func TestSet(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "element text",
+			source:   "<project>\n  <version>1.0.0</version>\n</project>\n",
+			path:     "project.version",
+			newValue: "1.1.0",
+			want:     "<project>\n  <version>1.1.0</version>\n</project>\n",
+		},
+		{
+			name:     "indexed element",
+			source:   testPom,
+			path:     "project.dependencies.dependency.1.version",
+			newValue: "2.1.0",
+			want:     "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<project>\n  <!-- The dependencies -->\n  <dependencies>\n    <dependency scope=\"compile\">\n      <artifactId>app</artifactId>\n      <version>1.0.0</version>\n    </dependency>\n    <dependency>\n      <artifactId>lib</artifactId>\n      <version>2.1.0</version>\n      <optional/>\n    </dependency>\n  </dependencies>\n</project>\n",
+		},
+		{
+			name:     "escaped value",
+			source:   "<project><name>app</name></project>",
+			path:     "project.name",
+			newValue: "a & b",
+			want:     "<project><name>a &amp; b</name></project>",
+		},
+		{
+			name:     "self-closing element",
+			source:   "<project>\n  <optional />\n</project>\n",
+			path:     "project.optional",
+			newValue: true,
+			want:     "<project>\n  <optional>true</optional>\n</project>\n",
+		},
+		{
+			name:     "attribute",
+			source:   "<project>\n  <dependency scope='compile'/>\n</project>\n",
+			path:     "project.dependency.@scope",
+			newValue: "test",
+			want:     "<project>\n  <dependency scope='test'/>\n</project>\n",
+		},
+		{
+			name:     "new attribute",
+			source:   "<project>\n  <dependency scope=\"compile\">app</dependency>\n</project>\n",
+			path:     "project.dependency.@type",
+			newValue: "jar",
+			want:     "<project>\n  <dependency scope=\"compile\" type=\"jar\">app</dependency>\n</project>\n",
+		},
+		{
+			name:     "new element",
+			source:   "<project>\n  <groupId>example</groupId>\n  <artifactId>app</artifactId>\n</project>\n",
+			path:     "project.version",
+			newValue: "1.0.0",
+			want:     "<project>\n  <groupId>example</groupId>\n  <artifactId>app</artifactId>\n  <version>1.0.0</version>\n</project>\n",
+		},
+		{
+			name:     "namespaced element",
+			source:   "<a:config xmlns:a=\"urn:a\"><a:port>80</a:port></a:config>",
+			path:     "a:config.a:port",
+			newValue: 8080,
+			want:     "<a:config xmlns:a=\"urn:a\"><a:port>8080</a:port></a:config>",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFailures(t *testing.T) {
+	setTests := []struct {
+		name    string
+		source  string
+		path    string
+		wantErr string
+	}{
+		{"invalid document", "<project>", "project.version", "failed to parse the XML document"},
+		{"empty path", testPom, "", "empty path"},
+		{"wrong root", testPom, "pom.version", "key not found: pom.version"},
+		{"missing parent", testPom, "project.build.version", "key not found: project.build.version"},
+		{"missing index", testPom, "project.dependencies.dependency.2.version", "key not found"},
+		{"child elements", testPom, "project.dependencies", "element project.dependencies has child elements"},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(tt.source), tt.path, "test")
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	getTests := []struct {
+		path string
+		want string
+	}{
+		{"project.dependencies.dependency.artifactId", "app"},
+		{"project.dependencies.dependency.1.version", "2.0.0"},
+		{"project.dependencies.dependency.0.@scope", "compile"},
+		{"project.dependencies.dependency.1.optional", ""},
+	}
+
+	for _, tt := range getTests {
+		got, err := GetBytes([]byte(testPom), tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("GetBytes(%s) got %#v, want %#v", tt.path, got, tt.want)
+		}
+	}
+
+	got, err := GetBytes([]byte("<a b=\"x &amp; y\">1 &lt; 2</a>"), "a")
+	if err != nil || got != "1 < 2" {
+		t.Errorf("GetBytes(a) got %#v, %v", got, err)
+	}
+	_, err = GetBytes([]byte(testPom), "project.dependencies.dependency.1.@scope")
+	if !errors.Is(err, ErrKeyNotFound) {
+		t.Fatalf("got %v, want ErrKeyNotFound", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	deleteTests := []struct {
+		name   string
+		source string
+		path   string
+		want   string
+	}{
+		{
+			name:   "element line",
+			source: "<project>\n  <name>app</name>\n  <version>1.0.0</version>\n</project>\n",
+			path:   "project.name",
+			want:   "<project>\n  <version>1.0.0</version>\n</project>\n",
+		},
+		{
+			name:   "inline element",
+			source: "<project><name>app</name><version>1.0.0</version></project>",
+			path:   "project.version",
+			want:   "<project><name>app</name></project>",
+		},
+		{
+			name:   "attribute",
+			source: "<project>\n  <dependency scope=\"compile\" type=\"jar\"/>\n</project>\n",
+			path:   "project.dependency.@scope",
+			want:   "<project>\n  <dependency type=\"jar\"/>\n</project>\n",
+		},
+		{
+			name:   "missing element",
+			source: "<project><name>app</name></project>",
+			path:   "project.version",
+			want:   "<project><name>app</name></project>",
+		},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := DeleteBytes([]byte(tt.source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+
+	_, err := DeleteBytes([]byte(testPom), "project")
+	if !test.MatchError(t, "the root element can't be deleted", err) {
+		t.Errorf("error got %s", err)
+	}
+}
@@ -0,0 +1,112 @@
+package syaml
+
+import (
+	"bytes"
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// DeleteBytes removes the key at the path from the YAML body, the body is
+// returned unchanged if the key doesn't exist.
+//
+// This differs from setting the value to nil with SetBytes, which leaves the
+// key with a null value.
+//
+// Removing the last key from a mapping leaves an empty mapping, including the
+// root, where the comments and document markers around the key are kept, and
+// paths that end in a sequence index remove the item.
+func DeleteBytes(y []byte, path string, opts ...Option) ([]byte, error) {
+	o := makeOptions(opts)
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseRoot(y)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.checkCurrent(root, segments, path); err != nil {
+		return nil, err
+	}
+	if root == nil {
+		return y, nil
+	}
+	node, parent := findNode(root, segments)
+	if node == nil {
+		return y, nil
+	}
+	last := segments[len(segments)-1]
+	parentPath := joinPath(segments[:len(segments)-1])
+	if parent.Kind == yaml3.SequenceNode {
+		index, _ := sequenceIndex(last)
+		return RemoveBytes(y, parentPath, Index(index), opts...)
+	}
+	index := mappingIndex(parent, last)
+	if len(parent.Content) == 2 {
+		if parent == root {
+			updated, err := clearRoot(y, root)
+			if err != nil {
+				return nil, err
+			}
+			return o.validated(updated)
+		}
+		return SetBytes(y, parentPath, map[string]interface{}{}, opts...)
+	}
+	var updated []byte
+	if parent.Style&yaml3.FlowStyle != 0 {
+		start, end, err := flowSpan(y, parent)
+		if err != nil {
+			return nil, err
+		}
+		parent.Content = append(parent.Content[:index-1], parent.Content[index+1:]...)
+		if updated, err = renderFlow(y, parent, start, end); err != nil {
+			return nil, err
+		}
+	} else if updated, err = removeEntry(y, parent, index); err != nil {
+		return nil, err
+	}
+	return o.validated(updated)
+}
+
+// clearRoot replaces the only entry of the root mapping with an empty mapping,
+// the rest of the document is unchanged.
+func clearRoot(y []byte, root *yaml3.Node) ([]byte, error) {
+	if root.Style&yaml3.FlowStyle != 0 {
+		start, end, err := flowSpan(y, root)
+		if err != nil {
+			return nil, err
+		}
+		return splice(y, start, end, []byte("{}")), nil
+	}
+	start, end, err := entrySpan(y, root.Content[0], root.Content[1])
+	if err != nil {
+		return nil, err
+	}
+	return splice(y, start, end, []byte("{}")), nil
+}
+
+// removeEntry removes the lines of the block mapping entry with the value at
+// the index, where the key follows the "- " indicator of a sequence item, the
+// entry is removed up to the next key.
+func removeEntry(y []byte, mapping *yaml3.Node, index int) ([]byte, error) {
+	start, end, err := entrySpan(y, mapping.Content[index-1], mapping.Content[index])
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimLeft(y[lineStart(y, start):start], " ")) > 0 {
+		next := mapping.Content[index+1]
+		nextStart, ok := offsetOf(y, next.Line, next.Column)
+		if !ok {
+			return nil, fmt.Errorf("failed to locate the key %s at line %d", next.Value, next.Line)
+		}
+		return splice(y, start, nextStart, nil), nil
+	}
+	start = lineStart(y, start)
+	if i := bytes.IndexByte(y[end:], '\n'); i != -1 {
+		end += i + 1
+	} else if start > 0 {
+		start = lineEnd(y, start-1)
+	}
+	return splice(y, start, end, nil), nil
+}
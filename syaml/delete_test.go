@@ -0,0 +1,65 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestDeleteBytes(t *testing.T) {
+	deleteTests := []struct {
+		name   string
+		source string
+		path   string
+		want   string
+	}{
+		{"top-level key", "name: test\n# the replicas\nreplicas: 1\nimage: app\n", "replicas", "name: test\n# the replicas\nimage: app\n"},
+		{"nested mapping", "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n", "spec.resources", "spec:\n  replicas: 1\n"},
+		{"last key without a trailing newline", "name: test\nreplicas: 1", "replicas", "name: test"},
+		{"first key of a sequence item", "containers:\n- name: app\n  image: app:v1\n", "containers.0.name", "containers:\n- image: app:v1\n"},
+		{"last key of a mapping", "metadata:\n  labels:\n    app: web\n  name: test\n", "metadata.labels.app", "metadata:\n  labels: {}\n  name: test\n"},
+		{"only key in the document", "name: test\n", "name", "{}\n"},
+		{"only key without a trailing newline", "name: test", "name", "{}"},
+		{"only key with comments and markers", "# the service\n---\n# the name\nname: test # generated\n# the end\n...\n", "name", "# the service\n---\n# the name\n{}\n# the end\n...\n"},
+		{"only nested key in the document", "spec:\n  replicas: 1\n# the end\n", "spec", "{}\n# the end\n"},
+		{"only key in a flow mapping document", "--- {name: test}\n", "name", "--- {}\n"},
+		{"only key with CRLF line endings", "---\r\nname: test\r\n", "name", "---\r\n{}\r\n"},
+		{"flow mapping", "labels: {app: web, tier: backend}\n", "labels.tier", "labels: {app: web}\n"},
+		{"sequence item", "args:\n- one\n- two\n", "args.0", "args:\n- two\n"},
+		{"missing key", "name: test\n", "spec.replicas", "name: test\n"},
+		{"null value", "name: test\nimage:\nreplicas: 1\n", "image", "name: test\nreplicas: 1\n"},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := DeleteBytes([]byte(tt.source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to delete:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeleteBytesWithExpectedValue(t *testing.T) {
+	_, err := DeleteBytes([]byte("image: app:v2\n"), "image", ExpectValue("app:v1"))
+
+	if !test.MatchError(t, `unexpected current value: image is "app:v2", expected "app:v1"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestSetNullKeepsTheKey(t *testing.T) {
+	updated, err := SetBytes([]byte("name: test\nimage: app\n"), "image", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(updated); s != "name: test\nimage: null\n" {
+		t.Fatalf("got %#v", s)
+	}
+}
@@ -0,0 +1,235 @@
+package syaml
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 3
+
+// UnifiedDiff returns a unified diff between the original and updated content,
+// with the name used in the file headers.
+//
+// If there are no differences, an empty string is returned.
+func UnifiedDiff(name string, original, updated []byte) string {
+	a, b := splitLines(string(original)), splitLines(string(updated))
+	edits := diffLines(a, b)
+	hunks := groupHunks(edits)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", name, name)
+	for _, h := range hunks {
+		h.writeTo(&sb)
+	}
+	return sb.String()
+}
+
+type editOp int
+
+const (
+	opEqual editOp = iota
+	opDelete
+	opInsert
+)
+
+type edit struct {
+	op   editOp
+	line string
+	a, b int // line indexes in the original and updated content
+}
+
+type hunk []edit
+
+func (h hunk) writeTo(sb *strings.Builder) {
+	aStart, bStart, aLen, bLen := -1, -1, 0, 0
+	for _, e := range h {
+		if e.op != opInsert {
+			if aStart == -1 {
+				aStart = e.a
+			}
+			aLen++
+		}
+		if e.op != opDelete {
+			if bStart == -1 {
+				bStart = e.b
+			}
+			bLen++
+		}
+	}
+	// Empty ranges are reported at the line before, see GNU diff.
+	if aStart == -1 {
+		aStart = h[0].a - 1
+	}
+	if bStart == -1 {
+		bStart = h[0].b - 1
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", hunkRange(aStart, aLen), hunkRange(bStart, bLen))
+	for _, e := range h {
+		switch e.op {
+		case opEqual:
+			sb.WriteString(" ")
+		case opDelete:
+			sb.WriteString("-")
+		case opInsert:
+			sb.WriteString("+")
+		}
+		sb.WriteString(e.line)
+		if !strings.HasSuffix(e.line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+func hunkRange(start, length int) string {
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// groupHunks splits the edits into hunks of changes surrounded by up to
+// diffContext lines of unchanged content.
+func groupHunks(edits []edit) []hunk {
+	hunks := []hunk{}
+	var current hunk
+	lastChange := -1
+	for i, e := range edits {
+		if e.op == opEqual {
+			continue
+		}
+		start := i - diffContext
+		if start < 0 {
+			start = 0
+		}
+		if current != nil && start <= lastChange+diffContext+1 {
+			current = append(current, edits[lastChange+1:i+1]...)
+		} else {
+			if current != nil {
+				hunks = append(hunks, closeHunk(current, edits, lastChange))
+			}
+			current = append(hunk{}, edits[start:i+1]...)
+		}
+		lastChange = i
+	}
+	if current != nil {
+		hunks = append(hunks, closeHunk(current, edits, lastChange))
+	}
+	return hunks
+}
+
+func closeHunk(h hunk, edits []edit, lastChange int) hunk {
+	end := lastChange + diffContext + 1
+	if end > len(edits) {
+		end = len(edits)
+	}
+	return append(h, edits[lastChange+1:end]...)
+}
+
+// diffLines calculates the shortest edit script between two sets of lines
+// using the Myers diff algorithm.
+//
+// Common prefixes and suffixes are trimmed first, YAML updates typically
+// only affect a few lines in a larger file.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := []edit{}
+	for i := 0; i < prefix; i++ {
+		edits = append(edits, edit{op: opEqual, line: a[i], a: i, b: i})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := suffix; i > 0; i-- {
+		edits = append(edits, edit{op: opEqual, line: a[len(a)-i], a: len(a) - i, b: len(b) - i})
+	}
+	return edits
+}
+
+func myers(a, b []string, offset int) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	v := make([]int, 2*max+2)
+	trace := [][]int{}
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+				x = v[max+k+1]
+			} else {
+				x = v[max+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[max+k] = x
+			if x >= n && y >= m {
+				return backtrack(trace, a, b, max, offset)
+			}
+		}
+	}
+	return nil
+}
+
+func backtrack(trace [][]int, a, b []string, max, offset int) []edit {
+	x, y := len(a), len(b)
+	reversed := []edit{}
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[max+k-1] < v[max+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[max+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, edit{op: opEqual, line: a[x], a: x + offset, b: y + offset})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				reversed = append(reversed, edit{op: opInsert, line: b[y], a: x + offset, b: y + offset})
+			} else {
+				x--
+				reversed = append(reversed, edit{op: opDelete, line: a[x], a: x + offset, b: y + offset})
+			}
+		}
+	}
+	edits := make([]edit, len(reversed))
+	for i := range reversed {
+		edits[i] = reversed[len(reversed)-1-i]
+	}
+	return edits
+}
+
+// splitLines splits the string into lines, retaining the line endings.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,75 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	diffTests := []struct {
+		name     string
+		original string
+		updated  string
+		want     string
+	}{
+		{
+			name:     "no changes",
+			original: "name: testing\n",
+			updated:  "name: testing\n",
+			want:     "",
+		},
+		{
+			name:     "single line change",
+			original: "name: testing\n",
+			updated:  "name: new name\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -1 +1 @@\n-name: testing\n+name: new name\n",
+		},
+		{
+			name:     "change with context",
+			original: "a: 1\nb: 2\nc: 3\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\n",
+			updated:  "a: 1\nb: 2\nc: 3\nd: 4\ne: 50\nf: 6\ng: 7\nh: 8\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -2,7 +2,7 @@\n b: 2\n c: 3\n d: 4\n-e: 5\n+e: 50\n f: 6\n g: 7\n h: 8\n",
+		},
+		{
+			name:     "separate hunks",
+			original: "a: 1\nb: 2\nc: 3\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\ni: 9\nj: 10\n",
+			updated:  "a: 10\nb: 2\nc: 3\nd: 4\ne: 5\nf: 6\ng: 7\nh: 8\ni: 9\nj: 1\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -1,4 +1,4 @@\n-a: 1\n+a: 10\n b: 2\n c: 3\n d: 4\n@@ -7,4 +7,4 @@\n g: 7\n h: 8\n i: 9\n-j: 10\n+j: 1\n",
+		},
+		{
+			name:     "added lines",
+			original: "a: 1\nb: 2\n",
+			updated:  "a: 1\nc: 3\nb: 2\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -1,2 +1,3 @@\n a: 1\n+c: 3\n b: 2\n",
+		},
+		{
+			name:     "removed lines",
+			original: "a: 1\nb: 2\nc: 3\n",
+			updated:  "a: 1\nc: 3\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -1,3 +1,2 @@\n a: 1\n-b: 2\n c: 3\n",
+		},
+		{
+			name:     "new file",
+			original: "",
+			updated:  "a: 1\n",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -0,0 +1 @@\n+a: 1\n",
+		},
+		{
+			name:     "missing trailing newline",
+			original: "a: 1",
+			updated:  "a: 2",
+			want:     "--- a/test.yaml\n+++ b/test.yaml\n@@ -1 +1 @@\n-a: 1\n\\ No newline at end of file\n+a: 2\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range diffTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			got := UnifiedDiff("test.yaml", []byte(tt.original), []byte(tt.updated))
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				rt.Errorf("diff failed:\n%s", diff)
+			}
+		})
+	}
+}
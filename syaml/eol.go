@@ -0,0 +1,30 @@
+package syaml
+
+import (
+	"bytes"
+)
+
+// lineEndings records the end-of-line convention of a document, so that it can
+// be restored after the document is re-encoded.
+type lineEndings struct {
+	crlf            bool
+	trailingNewline bool
+}
+
+func detectLineEndings(b []byte) lineEndings {
+	return lineEndings{
+		crlf:            bytes.Contains(b, []byte("\r\n")),
+		trailingNewline: len(b) == 0 || bytes.HasSuffix(b, []byte("\n")),
+	}
+}
+
+// apply converts a document with LF line endings to the recorded convention.
+func (l lineEndings) apply(b []byte) []byte {
+	if !l.trailingNewline {
+		b = bytes.TrimRight(b, "\n")
+	}
+	if l.crlf {
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte("\r\n"))
+	}
+	return b
+}
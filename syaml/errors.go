@@ -0,0 +1,96 @@
+package syaml
+
+import (
+	"fmt"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// IndexOutOfRangeError is returned when a path indexes beyond the end of a
+// sequence.
+type IndexOutOfRangeError struct {
+	// Path is the path up to and including the failing segment.
+	Path   string
+	Index  int
+	Length int
+}
+
+func (e *IndexOutOfRangeError) Error() string {
+	return fmt.Sprintf("index %d out of range at %s, the sequence has %d items", e.Index, e.Path, e.Length)
+}
+
+// TypeMismatchError is returned when a path segment can't be applied to the
+// node it addresses, e.g. a key in a scalar, or a non-numeric segment in a
+// sequence.
+type TypeMismatchError struct {
+	// Path is the path up to and including the failing segment.
+	Path    string
+	Segment string
+	// Kind is the kind of the node the segment was applied to, one of
+	// "mapping", "sequence" or "scalar".
+	Kind string
+}
+
+func (e *TypeMismatchError) Error() string {
+	parent := strings.TrimSuffix(strings.TrimSuffix(e.Path, e.Segment), ".")
+	if parent == "" {
+		return fmt.Sprintf("cannot use %q in path %s, the document is a %s", e.Segment, e.Path, e.Kind)
+	}
+	return fmt.Sprintf("cannot use %q in path %s, %s is a %s", e.Segment, e.Path, parent, e.Kind)
+}
+
+// checkPath walks the path through the document, and returns an error if a
+// segment can't be applied to the existing nodes.
+//
+// Missing keys in mappings are created by the update, unless the parents must
+// exist.
+//
+// The root is nil for an empty document.
+func checkPath(root *yaml3.Node, segments []string, parentsMustExist bool) error {
+	if root == nil {
+		if parentsMustExist && len(segments) > 1 {
+			return fmt.Errorf("%w: %s", ErrMissingParent, segments[0])
+		}
+		return nil
+	}
+	node := root
+	for i, s := range segments {
+		for node.Kind == yaml3.AliasNode {
+			node = node.Alias
+		}
+		current := strings.Join(segments[:i+1], ".")
+		switch {
+		case node.Kind == yaml3.SequenceNode:
+			index, ok := sequenceIndex(s)
+			if !ok {
+				return &TypeMismatchError{Path: current, Segment: s, Kind: "sequence"}
+			}
+			if index > len(node.Content) {
+				return &IndexOutOfRangeError{Path: current, Index: index, Length: len(node.Content)}
+			}
+			if index == len(node.Content) {
+				return missingParent(segments, i, parentsMustExist)
+			}
+			node = node.Content[index]
+		case node.Kind == yaml3.ScalarNode && node.Tag != "!!null":
+			return &TypeMismatchError{Path: current, Segment: s, Kind: "scalar"}
+		case node.Kind == yaml3.MappingNode:
+			child := childNode(node, s)
+			if child == nil {
+				return missingParent(segments, i, parentsMustExist)
+			}
+			node = child
+		default:
+			return missingParent(segments, i, parentsMustExist)
+		}
+	}
+	return nil
+}
+
+func missingParent(segments []string, i int, parentsMustExist bool) error {
+	if parentsMustExist && i < len(segments)-1 {
+		return fmt.Errorf("%w: %s", ErrMissingParent, strings.Join(segments[:i+1], "."))
+	}
+	return nil
+}
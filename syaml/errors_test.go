@@ -0,0 +1,85 @@
+package syaml
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetPathErrors(t *testing.T) {
+	setTests := []struct {
+		name   string
+		source string
+		path   string
+		want   error
+	}{
+		{
+			name:   "index beyond the end of a sequence",
+			source: "items:\n- name: one\n- name: two\n",
+			path:   "items.5.name",
+			want:   &IndexOutOfRangeError{Path: "items.5", Index: 5, Length: 2},
+		},
+		{
+			name:   "key in a scalar",
+			source: "name: testing\n",
+			path:   "name.first",
+			want:   &TypeMismatchError{Path: "name.first", Segment: "first", Kind: "scalar"},
+		},
+		{
+			name:   "key in a sequence",
+			source: "items:\n- one\n",
+			path:   "items.name",
+			want:   &TypeMismatchError{Path: "items.name", Segment: "name", Kind: "sequence"},
+		},
+		{
+			name:   "key in a nested scalar",
+			source: "items:\n- one\n",
+			path:   "items.0.name",
+			want:   &TypeMismatchError{Path: "items.0.name", Segment: "name", Kind: "scalar"},
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(tt.source), tt.path, map[string]interface{}{"new": "value"})
+			if diff := cmp.Diff(tt.want, err); diff != "" {
+				rt.Errorf("incorrect error:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetPathErrorMessages(t *testing.T) {
+	_, err := SetBytes([]byte("name: testing\n"), "name.first", "value")
+	want := `cannot use "first" in path name.first, name is a scalar`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+	var mismatch *TypeMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Errorf("got %T, want a *TypeMismatchError", err)
+	}
+
+	_, err = SetBytes([]byte("- one\n"), "0.name", "value")
+	want = `cannot use "name" in path 0.name, 0 is a scalar`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+
+	_, err = SetBytes([]byte("items:\n- one\n"), "items.3", "value")
+	want = "index 3 out of range at items.3, the sequence has 1 items"
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+}
+
+func TestSetAppendsToSequence(t *testing.T) {
+	updated, err := SetBytes([]byte("items:\n- one\n"), "items.1", "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("items:\n- one\n- two\n", string(updated)); diff != "" {
+		t.Errorf("failed to append:\n%s", diff)
+	}
+}
@@ -0,0 +1,56 @@
+package syaml
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNoFrontMatter is returned when a document doesn't start with a YAML
+// front matter block.
+var ErrNoFrontMatter = errors.New("no front matter found")
+
+// SetFrontMatter updates a key in the YAML front matter of a document, e.g. a
+// Markdown file, the rest of the document is left untouched.
+//
+// The front matter must start on the first line with "---", and is closed by a
+// line with "---" or "...".
+func SetFrontMatter(b []byte, path string, value interface{}, opts ...Option) ([]byte, error) {
+	start, end, ok := frontMatter(b)
+	if !ok {
+		return nil, ErrNoFrontMatter
+	}
+	updated, err := SetBytes(b[start:end], path, value, opts...)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, 0, len(b)-(end-start)+len(updated))
+	result = append(result, b[:start]...)
+	result = append(result, updated...)
+	return append(result, b[end:]...), nil
+}
+
+// frontMatter returns the start and end offsets of the YAML between the
+// front matter delimiters.
+func frontMatter(b []byte) (int, int, bool) {
+	first := bytes.IndexByte(b, '\n')
+	if first == -1 || string(bytes.TrimRight(b[:first], "\r")) != "---" {
+		return 0, 0, false
+	}
+	start := first + 1
+	for offset := start; offset < len(b); {
+		lineEnd := bytes.IndexByte(b[offset:], '\n')
+		line := b[offset:]
+		if lineEnd != -1 {
+			line = b[offset : offset+lineEnd]
+		}
+		switch string(bytes.TrimRight(line, "\r")) {
+		case "---", "...":
+			return start, offset, true
+		}
+		if lineEnd == -1 {
+			break
+		}
+		offset += lineEnd + 1
+	}
+	return 0, 0, false
+}
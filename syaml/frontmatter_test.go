@@ -0,0 +1,68 @@
+package syaml
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetFrontMatter(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "existing key",
+			source:   "---\ntitle: Install # the page title\nversion: 1.0.0\n---\n# Install\n\nversion: 1.0.0 is in the body\n",
+			path:     "version",
+			newValue: "1.1.0",
+			want:     "---\ntitle: Install # the page title\nversion: 1.1.0\n---\n# Install\n\nversion: 1.0.0 is in the body\n",
+		},
+		{
+			name:     "new key",
+			source:   "---\ntitle: Install\n...\nThe body\n",
+			path:     "version",
+			newValue: "1.1.0",
+			want:     "---\ntitle: Install\nversion: 1.1.0\n...\nThe body\n",
+		},
+		{
+			name:     "windows line endings",
+			source:   "---\r\nversion: 1.0.0\r\n---\r\nThe body\r\n",
+			path:     "version",
+			newValue: "1.1.0",
+			want:     "---\r\nversion: 1.1.0\r\n---\r\nThe body\r\n",
+		},
+		{
+			name:     "front matter at the end of the file",
+			source:   "---\nversion: 1.0.0\n---",
+			path:     "version",
+			newValue: "1.1.0",
+			want:     "---\nversion: 1.1.0\n---",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetFrontMatter([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetFrontMatterWithNoFrontMatter(t *testing.T) {
+	for _, source := range []string{"# Install\n", "---\ntitle: Install\n", "\n---\ntitle: Install\n---\n"} {
+		_, err := SetFrontMatter([]byte(source), "version", "1.1.0")
+		if !errors.Is(err, ErrNoFrontMatter) {
+			t.Errorf("got %v, want ErrNoFrontMatter for %#v", err, source)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package syaml
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// setInPlace replaces an existing scalar value in the YAML body, leaving the
+// rest of the document byte-for-byte untouched, this preserves the
+// indentation, quoting and comments of the original document.
+//
+// The quoting style of the replaced scalar is retained where the new value is
+// a string.
+//
+// Returns false if the change can't be made in place, e.g. the value doesn't
+// exist, or is not a scalar.
+func setInPlace(y []byte, root *yaml3.Node, segments []string, value interface{}) ([]byte, bool) {
+	node, parent := findNode(root, segments)
+	if node == nil || node.Kind != yaml3.ScalarNode || node.Anchor != "" || node.Style&(yaml3.TaggedStyle|yaml3.LiteralStyle|yaml3.FoldedStyle) != 0 {
+		return nil, false
+	}
+	rendered, ok := renderScalar(value, node.Style)
+	if !ok {
+		return nil, false
+	}
+	start, end, ok := scalarSpan(y, node, parent.Style&yaml3.FlowStyle != 0)
+	if !ok {
+		return nil, false
+	}
+	updated := make([]byte, 0, len(y)-(end-start)+len(rendered))
+	updated = append(updated, y[:start]...)
+	updated = append(updated, rendered...)
+	return append(updated, y[end:]...), true
+}
+
+// findNode walks the path segments from the node, and returns the node at
+// the path, and its parent node.
+func findNode(node *yaml3.Node, segments []string) (*yaml3.Node, *yaml3.Node) {
+	var parent *yaml3.Node
+	for _, s := range segments {
+		parent = node
+		node = childNode(node, s)
+		if node == nil {
+			return nil, nil
+		}
+	}
+	return node, parent
+}
+
+func childNode(node *yaml3.Node, segment string) *yaml3.Node {
+	switch node.Kind {
+	case yaml3.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if k := node.Content[i]; k.Kind == yaml3.ScalarNode && k.Value == segment {
+				return node.Content[i+1]
+			}
+		}
+	case yaml3.SequenceNode:
+		if i, ok := sequenceIndex(segment); ok && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// renderScalar renders a scalar value as YAML, strings are rendered in the
+// provided style if possible.
+func renderScalar(value interface{}, style yaml3.Style) ([]byte, bool) {
+	n := &yaml3.Node{}
+	switch v := value.(type) {
+	case string:
+		if err := n.Encode(v); err != nil {
+			return nil, false
+		}
+		if style&(yaml3.SingleQuotedStyle|yaml3.DoubleQuotedStyle) != 0 {
+			n.Style = style
+		}
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		if err := n.Encode(v); err != nil {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	b, err := yaml3.Marshal(n)
+	if err != nil {
+		return nil, false
+	}
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	if bytes.ContainsAny(b, "\r\n") {
+		return nil, false
+	}
+	return b, true
+}
+
+// scalarSpan returns the start and end byte offsets of a single-line scalar
+// node within the source.
+func scalarSpan(y []byte, node *yaml3.Node, flow bool) (int, int, bool) {
+	start, ok := offsetOf(y, node.Line, node.Column)
+	if !ok {
+		return 0, 0, false
+	}
+	lineEnd := bytes.IndexAny(y[start:], "\r\n")
+	if lineEnd == -1 {
+		lineEnd = len(y)
+	} else {
+		lineEnd += start
+	}
+	end := -1
+	switch {
+	case node.Style&yaml3.DoubleQuotedStyle != 0:
+		for i := start + 1; i < lineEnd; i++ {
+			if y[i] == '\\' {
+				i++
+				continue
+			}
+			if y[i] == '"' {
+				end = i + 1
+				break
+			}
+		}
+	case node.Style&yaml3.SingleQuotedStyle != 0:
+		for i := start + 1; i < lineEnd; i++ {
+			if y[i] == '\'' {
+				if i+1 < lineEnd && y[i+1] == '\'' {
+					i++
+					continue
+				}
+				end = i + 1
+				break
+			}
+		}
+	default:
+		end = plainScalarEnd(y, start, lineEnd, flow)
+	}
+	if end == -1 {
+		return 0, 0, false
+	}
+	// Check that the span really is the scalar, e.g. multi-line scalars would
+	// not match.
+	var check yaml3.Node
+	if err := yaml3.Unmarshal(y[start:end], &check); err != nil || len(check.Content) != 1 || check.Content[0].Value != node.Value {
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+func plainScalarEnd(y []byte, start, lineEnd int, flow bool) int {
+	end := lineEnd
+	for i := start; i < lineEnd; i++ {
+		if y[i] == '#' && i > start && (y[i-1] == ' ' || y[i-1] == '\t') {
+			end = i
+			break
+		}
+		if flow && (y[i] == ',' || y[i] == ']' || y[i] == '}') {
+			end = i
+			break
+		}
+	}
+	return len(bytes.TrimRight(y[:end], " \t"))
+}
+
+// offsetOf converts a 1-based line and column (in characters) to a byte
+// offset.
+func offsetOf(y []byte, line, column int) (int, bool) {
+	offset := 0
+	for l := 1; l < line; l++ {
+		i := bytes.IndexByte(y[offset:], '\n')
+		if i == -1 {
+			return 0, false
+		}
+		offset += i + 1
+	}
+	for c := 1; c < column; c++ {
+		if offset >= len(y) {
+			return 0, false
+		}
+		_, size := utf8.DecodeRune(y[offset:])
+		offset += size
+	}
+	return offset, true
+}
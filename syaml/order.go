@@ -0,0 +1,82 @@
+package syaml
+
+import (
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// orderedValueNode converts the value to a node, and orders the keys of maps,
+// which JSON sorts, to follow the key order of the template, the node being
+// replaced, or a sibling of a new sequence item.
+//
+// RawYAML fragments are written in the order of the fragment.
+func orderedValueNode(value interface{}, template *yaml3.Node) (*yaml3.Node, error) {
+	node, err := valueNode(value)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := value.(RawYAML); !ok {
+		orderKeys(node, template)
+	}
+	return node, nil
+}
+
+// orderTemplate returns the node at the path, or where the path appends to a
+// sequence, the last item of the sequence.
+func orderTemplate(root *yaml3.Node, segments []string) *yaml3.Node {
+	if root == nil {
+		return nil
+	}
+	if node, _ := findNode(root, segments); node != nil {
+		return node
+	}
+	parent, _ := findNode(root, segments[:len(segments)-1])
+	if len(segments) == 1 {
+		parent = root
+	}
+	if parent == nil || parent.Kind != yaml3.SequenceNode || len(parent.Content) == 0 {
+		return nil
+	}
+	if index, ok := sequenceIndex(segments[len(segments)-1]); !ok || index != len(parent.Content) {
+		return nil
+	}
+	return parent.Content[len(parent.Content)-1]
+}
+
+// orderKeys orders the keys of the mappings in the node to follow the order
+// of the keys in the template, keys that aren't in the template follow in
+// their existing order, sequence items are ordered by the item at the same
+// index.
+func orderKeys(node, template *yaml3.Node) {
+	for template != nil && template.Kind == yaml3.AliasNode {
+		template = template.Alias
+	}
+	if node == nil || template == nil || node.Kind != template.Kind {
+		return
+	}
+	switch node.Kind {
+	case yaml3.MappingNode:
+		content := make([]*yaml3.Node, 0, len(node.Content))
+		used := map[int]bool{}
+		for i := 0; i+1 < len(template.Content); i += 2 {
+			j := mappingIndex(node, template.Content[i].Value)
+			if j == -1 || used[j] {
+				continue
+			}
+			used[j] = true
+			orderKeys(node.Content[j], template.Content[i+1])
+			content = append(content, node.Content[j-1], node.Content[j])
+		}
+		for j := 1; j < len(node.Content); j += 2 {
+			if !used[j] {
+				content = append(content, node.Content[j-1], node.Content[j])
+			}
+		}
+		node.Content = content
+	case yaml3.SequenceNode:
+		for i, item := range node.Content {
+			if i < len(template.Content) {
+				orderKeys(item, template.Content[i])
+			}
+		}
+	}
+}
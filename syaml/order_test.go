@@ -0,0 +1,90 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestKeyOrdering(t *testing.T) {
+	source := "kind: Deployment\nmetadata:\n  name: test\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v1\n        resources:\n          requests:\n            memory: 64Mi\n            cpu: 250m\n"
+	orderTests := []struct {
+		name     string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "replaced mapping",
+			path:     "spec.template.spec.containers.0.resources",
+			newValue: map[string]interface{}{"requests": map[string]string{"cpu": "500m", "memory": "128Mi"}, "limits": map[string]string{"memory": "256Mi"}},
+			want:     "kind: Deployment\nmetadata:\n  name: test\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v1\n        resources:\n          requests:\n            memory: 128Mi\n            cpu: 500m\n          limits:\n            memory: 256Mi\n",
+		},
+		{
+			name:     "replaced sequence",
+			path:     "spec.template.spec.containers",
+			newValue: []map[string]string{{"image": "app:v2", "name": "app"}, {"image": "sidecar:v1", "name": "sidecar"}},
+			want:     "kind: Deployment\nmetadata:\n  name: test\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v2\n      - image: sidecar:v1\n        name: sidecar\n",
+		},
+		{
+			name:     "appended item",
+			path:     "spec.template.spec.containers.1",
+			newValue: map[string]string{"image": "sidecar:v1", "name": "sidecar"},
+			want:     "kind: Deployment\nmetadata:\n  name: test\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v1\n        resources:\n          requests:\n            memory: 64Mi\n            cpu: 250m\n      - name: sidecar\n        image: sidecar:v1\n",
+		},
+		{
+			name:     "replaced document",
+			path:     "metadata",
+			newValue: map[string]interface{}{"namespace": "default", "name": "test"},
+			want:     "kind: Deployment\nmetadata:\n  name: test\n  namespace: default\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v1\n        resources:\n          requests:\n            memory: 64Mi\n            cpu: 250m\n",
+		},
+		{
+			name:     "YAML fragments are not reordered",
+			path:     "spec.template.spec.containers.0.resources",
+			newValue: RawYAML("requests:\n  cpu: 500m\n  memory: 128Mi\n"),
+			want:     "kind: Deployment\nmetadata:\n  name: test\nspec:\n  template:\n    spec:\n      containers:\n      - name: app\n        image: app:v1\n        resources:\n          requests:\n            cpu: 500m\n            memory: 128Mi\n",
+		},
+	}
+
+	for _, tt := range orderTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestKeyOrderingIsStable(t *testing.T) {
+	source := []byte("zone: b\nname: test\nlabels:\n  tier: web\n  app: test\n")
+	updated := source
+	for i := 0; i < 3; i++ {
+		var err error
+		updated, err = SetBytes(updated, "labels", map[string]string{"app": "test", "tier": "web"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if diff := cmp.Diff(string(source), string(updated)); diff != "" {
+		t.Fatalf("keys were reordered:\n%s", diff)
+	}
+}
+
+func TestInsertBytesKeyOrdering(t *testing.T) {
+	source := "env:\n- value: \"1\"\n  name: DEBUG\n"
+
+	updated, err := InsertBytes([]byte(source), "env", 0, map[string]string{"name": "MODE", "value": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "env:\n- value: test\n  name: MODE\n- value: \"1\"\n  name: DEBUG\n"
+	if diff := cmp.Diff(want, string(updated)); diff != "" {
+		t.Fatalf("failed to insert:\n%s", diff)
+	}
+}
@@ -0,0 +1,30 @@
+package syaml
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/agill17/pkg/internal/dotpath"
+)
+
+var errEmptyPath = errors.New("path cannot be empty")
+
+// splitPath splits a dotted path into the individual segments, dots can be
+// escaped with a backslash e.g. "metadata.annotations.example\.com/name".
+//
+// All other characters are literal, so keys can contain e.g. "*" or "@".
+func splitPath(path string) ([]string, error) {
+	if path == "" {
+		return nil, errEmptyPath
+	}
+	return dotpath.Split(path), nil
+}
+
+// sequenceIndex parses a path segment as an index into a sequence.
+func sequenceIndex(s string) (int, bool) {
+	i, err := strconv.Atoi(s)
+	if err != nil || i < 0 {
+		return 0, false
+	}
+	return i, true
+}
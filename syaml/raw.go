@@ -0,0 +1,45 @@
+package syaml
+
+import (
+	"errors"
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// RawYAML is a YAML fragment that can be used as the value in SetBytes, it's
+// parsed and inserted as a subtree, rather than converted via JSON, so the
+// scalars are written as they are in the fragment.
+//
+// SetBytes(b, "spec.resources", RawYAML("limits:\n  cpu: 500m\n  memory: 128Mi\n"))
+type RawYAML string
+
+func (r RawYAML) node() (*yaml3.Node, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal([]byte(r), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the YAML fragment: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+	node := doc.Content[0]
+	if err := checkFragment(node); err != nil {
+		return nil, err
+	}
+	clearStyle(node)
+	return node, nil
+}
+
+// checkFragment returns an error if the fragment uses anchors or aliases, as
+// they can't be spliced into another document.
+func checkFragment(node *yaml3.Node) error {
+	if node.Kind == yaml3.AliasNode || node.Anchor != "" {
+		return errors.New("anchors and aliases are not supported in YAML fragments")
+	}
+	for _, n := range node.Content {
+		if err := checkFragment(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestSetStructuredValues(t *testing.T) {
+	source := "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n"
+	setTests := []struct {
+		name     string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "map",
+			path:     "spec.resources",
+			newValue: map[string]interface{}{"limits": map[string]string{"cpu": "500m", "memory": "128Mi"}},
+			want:     "spec:\n  resources:\n    limits:\n      cpu: 500m\n      memory: 128Mi\n  replicas: 1\n",
+		},
+		{
+			name:     "slice of maps",
+			path:     "spec.tolerations",
+			newValue: []map[string]string{{"key": "dedicated", "operator": "Exists"}},
+			want:     "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n  tolerations:\n  - key: dedicated\n    operator: Exists\n",
+		},
+		{
+			name:     "YAML fragment",
+			path:     "spec.resources",
+			newValue: RawYAML("requests:\n  cpu: 250m\n  memory: 64Mi\nlimits:\n  memory: 128Mi # hard limit\n"),
+			want:     "spec:\n  resources:\n    requests:\n      cpu: 250m\n      memory: 64Mi\n    limits:\n      memory: 128Mi\n  replicas: 1\n",
+		},
+		{
+			name:     "YAML fragment scalars are retained",
+			path:     "spec.settings",
+			newValue: RawYAML("mode: 0755\nid: 12345678901234567890\nenabled: yes\nname: 'quoted'\ndate: !!str 2001-12-14\n"),
+			want:     "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n  settings:\n    mode: 0755\n    id: 12345678901234567890\n    enabled: yes\n    name: quoted\n    date: \"2001-12-14\"\n",
+		},
+		{
+			name:     "YAML fragment sequence",
+			path:     "spec.args",
+			newValue: RawYAML("- --verbose\n- --port=8080\n"),
+			want:     "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n  args:\n  - --verbose\n  - --port=8080\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetRawYAMLErrors(t *testing.T) {
+	errorTests := []struct {
+		name     string
+		fragment RawYAML
+		wantErr  string
+	}{
+		{"invalid YAML", RawYAML("key: [unclosed\n"), "failed to parse the YAML fragment"},
+		{"aliases", RawYAML("a: &a 1\nb: *a\n"), "anchors and aliases are not supported in YAML fragments"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte("name: test\n"), "spec", tt.fragment)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
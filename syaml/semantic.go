@@ -0,0 +1,142 @@
+package syaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ChangeKind is the kind of change made to a key.
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Changed ChangeKind = "changed"
+	Removed ChangeKind = "removed"
+)
+
+// Change is a key-level change between two documents.
+type Change struct {
+	// Path is the dotted path to the key, dots in keys are escaped with a
+	// backslash.
+	Path string
+	Kind ChangeKind
+	Old  interface{} `json:",omitempty"`
+	New  interface{} `json:",omitempty"`
+}
+
+// SemanticDiff compares two YAML documents and returns the keys that were
+// added, changed or removed, ignoring formatting, comments and key order.
+//
+// The changes are sorted by path.
+func SemanticDiff(original, updated []byte) ([]Change, error) {
+	var before, after interface{}
+	if err := yaml.Unmarshal(original, &before); err != nil {
+		return nil, fmt.Errorf("failed to parse the original document: %w", err)
+	}
+	if err := yaml.Unmarshal(updated, &after); err != nil {
+		return nil, fmt.Errorf("failed to parse the updated document: %w", err)
+	}
+	changes := []Change{}
+	diffValues(nil, before, after, &changes)
+	return changes, nil
+}
+
+func diffValues(path []string, before, after interface{}, changes *[]Change) {
+	switch b := before.(type) {
+	case map[string]interface{}:
+		if a, ok := after.(map[string]interface{}); ok {
+			for _, k := range unionKeys(b, a) {
+				bv, inBefore := b[k]
+				av, inAfter := a[k]
+				p := append(append([]string{}, path...), k)
+				switch {
+				case !inAfter:
+					*changes = append(*changes, Change{Path: joinPath(p), Kind: Removed, Old: bv})
+				case !inBefore:
+					*changes = append(*changes, Change{Path: joinPath(p), Kind: Added, New: av})
+				default:
+					diffValues(p, bv, av, changes)
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if a, ok := after.([]interface{}); ok {
+			for i := 0; i < len(b) || i < len(a); i++ {
+				p := append(append([]string{}, path...), strconv.Itoa(i))
+				switch {
+				case i >= len(a):
+					*changes = append(*changes, Change{Path: joinPath(p), Kind: Removed, Old: b[i]})
+				case i >= len(b):
+					*changes = append(*changes, Change{Path: joinPath(p), Kind: Added, New: a[i]})
+				default:
+					diffValues(p, b[i], a[i], changes)
+				}
+			}
+			return
+		}
+	}
+	if !reflect.DeepEqual(before, after) {
+		*changes = append(*changes, Change{Path: joinPath(path), Kind: Changed, Old: before, New: after})
+	}
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinPath(segments []string) string {
+	escaped := make([]string, len(segments))
+	for i, s := range segments {
+		escaped[i] = strings.ReplaceAll(s, ".", `\.`)
+	}
+	return strings.Join(escaped, ".")
+}
+
+// FormatChanges renders the changes one per line, prefixed with "+" for
+// added, "~" for changed and "-" for removed keys, values are rendered as
+// JSON.
+//
+//	~ test.image: "old-image" -> "new-image"
+func FormatChanges(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "."
+		}
+		switch c.Kind {
+		case Added:
+			fmt.Fprintf(&b, "+ %s: %s\n", path, formatValue(c.New))
+		case Changed:
+			fmt.Fprintf(&b, "~ %s: %s -> %s\n", path, formatValue(c.Old), formatValue(c.New))
+		case Removed:
+			fmt.Fprintf(&b, "- %s: %s\n", path, formatValue(c.Old))
+		}
+	}
+	return b.String()
+}
+
+func formatValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
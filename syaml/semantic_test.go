@@ -0,0 +1,99 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestSemanticDiff(t *testing.T) {
+	diffTests := []struct {
+		name     string
+		original string
+		updated  string
+		want     []Change
+	}{
+		{
+			name:     "no changes",
+			original: "name: testing\n",
+			updated:  "name: testing\n",
+			want:     []Change{},
+		},
+		{
+			name:     "formatting is ignored",
+			original: "# A comment\nperson: {name: John, age: 30}\n",
+			updated:  "person:\n  age: 30\n  name: \"John\"\n",
+			want:     []Change{},
+		},
+		{
+			name:     "changed, added and removed keys",
+			original: "test:\n  image: old-image\n  replicas: 1\n",
+			updated:  "test:\n  image: new-image\n  port: 8080\n",
+			want: []Change{
+				{Path: "test.image", Kind: Changed, Old: "old-image", New: "new-image"},
+				{Path: "test.port", Kind: Added, New: float64(8080)},
+				{Path: "test.replicas", Kind: Removed, Old: float64(1)},
+			},
+		},
+		{
+			name:     "sequences",
+			original: "items:\n- one\n- two\n",
+			updated:  "items:\n- one\n- three\n- four\n",
+			want: []Change{
+				{Path: "items.1", Kind: Changed, Old: "two", New: "three"},
+				{Path: "items.2", Kind: Added, New: "four"},
+			},
+		},
+		{
+			name:     "type changes",
+			original: "metadata:\n  annotations: null\n",
+			updated:  "metadata:\n  annotations:\n    example.com/name: test\n",
+			want: []Change{
+				{Path: "metadata.annotations", Kind: Changed, Old: nil, New: map[string]interface{}{"example.com/name": "test"}},
+			},
+		},
+		{
+			name:     "keys with dots",
+			original: "annotations:\n  example.com/name: old\n",
+			updated:  "annotations:\n  example.com/name: new\n",
+			want: []Change{
+				{Path: `annotations.example\.com/name`, Kind: Changed, Old: "old", New: "new"},
+			},
+		},
+	}
+
+	for _, tt := range diffTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			changes, err := SemanticDiff([]byte(tt.original), []byte(tt.updated))
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, changes); diff != "" {
+				rt.Errorf("incorrect changes:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSemanticDiffWithInvalidDocument(t *testing.T) {
+	_, err := SemanticDiff([]byte("name: testing\n"), []byte(": testing\n"))
+
+	if !test.MatchError(t, "failed to parse the updated document", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestFormatChanges(t *testing.T) {
+	changes := []Change{
+		{Path: "test.image", Kind: Changed, Old: "old-image", New: "new-image"},
+		{Path: "test.port", Kind: Added, New: float64(8080)},
+		{Path: "test.replicas", Kind: Removed, Old: float64(1)},
+	}
+
+	want := "~ test.image: \"old-image\" -> \"new-image\"\n+ test.port: 8080\n- test.replicas: 1\n"
+	if diff := cmp.Diff(want, FormatChanges(changes)); diff != "" {
+		t.Errorf("incorrect format:\n%s", diff)
+	}
+}
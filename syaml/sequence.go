@@ -0,0 +1,273 @@
+package syaml
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Selector selects the items of a sequence to remove with RemoveBytes, it's
+// called with the index and the decoded value of each item.
+type Selector func(index int, item interface{}) bool
+
+// Index selects the item at the index.
+func Index(i int) Selector {
+	return func(index int, _ interface{}) bool {
+		return index == i
+	}
+}
+
+// Matching selects mapping items where the key has the value, values are
+// compared in their YAML form.
+//
+// RemoveBytes(b, "spec.volumes", Matching("name", "cache"))
+func Matching(key, value string) Selector {
+	return func(_ int, item interface{}) bool {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		v, ok := m[key]
+		return ok && fmt.Sprint(v) == value
+	}
+}
+
+// AppendBytes appends the value to the sequence at the path in the YAML
+// body, creating the sequence if it doesn't exist.
+//
+// AppendBytes(b, "spec.containers.0.env", map[string]string{"name": "DEBUG", "value": "1"})
+//
+// For the sequence operations, ExpectValue and ExpectMatch are compared with
+// the current sequence, in its YAML form, and with Strict, the parents of the
+// sequence must exist.
+func AppendBytes(y []byte, path string, value interface{}, opts ...Option) ([]byte, error) {
+	root, seq, err := findSequence(y, path)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = sequenceOptions(root, path, opts); err != nil {
+		return nil, err
+	}
+	if seq == nil {
+		return SetBytes(y, path, []interface{}{value}, opts...)
+	}
+	return SetBytes(y, path+"."+strconv.Itoa(len(seq.Content)), value, opts...)
+}
+
+// InsertBytes inserts the value into the sequence at the path in the YAML
+// body, before the item at the index, an index equal to the length of the
+// sequence appends to it.
+func InsertBytes(y []byte, path string, index int, value interface{}, opts ...Option) ([]byte, error) {
+	root, seq, err := findSequence(y, path)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = sequenceOptions(root, path, opts); err != nil {
+		return nil, err
+	}
+	length := 0
+	if seq != nil {
+		length = len(seq.Content)
+	}
+	if index < 0 || index > length {
+		return nil, &IndexOutOfRangeError{Path: path, Index: index, Length: length}
+	}
+	if index == length {
+		return AppendBytes(y, path, value, opts...)
+	}
+	newValue, err := orderedValueNode(value, seq.Content[index])
+	if err != nil {
+		return nil, err
+	}
+	var updated []byte
+	if seq.Style&yaml3.FlowStyle != 0 {
+		start, end, err := flowSpan(y, seq)
+		if err != nil {
+			return nil, err
+		}
+		seq.Content = append(seq.Content[:index], append([]*yaml3.Node{newValue}, seq.Content[index:]...)...)
+		if updated, err = renderFlow(y, seq, start, end); err != nil {
+			return nil, err
+		}
+	} else {
+		items, err := itemOffsets(y, seq)
+		if err != nil {
+			return nil, err
+		}
+		s := detectStyle(y, root)
+		e := &emitter{style: s}
+		e.sequence(&yaml3.Node{Kind: yaml3.SequenceNode, Content: []*yaml3.Node{newValue}}, seq.Column-1)
+		// The new item is inserted before the head comment of the item at
+		// the index, which belongs to that item.
+		rendered := append(bytes.TrimLeft(e.bytes(), " "), s.eol...)
+		rendered = append(rendered, bytes.Repeat([]byte(" "), seq.Column-1)...)
+		start := headStart(y, items[index], seq.Column-1)
+		updated = splice(y, start, start, rendered)
+	}
+	return makeOptions(opts).validated(updated)
+}
+
+// RemoveBytes removes the items selected by the selector from the sequence at
+// the path in the YAML body, the body is returned unchanged if the sequence
+// doesn't exist, or no items are selected.
+//
+// Removing all the items leaves an empty sequence.
+func RemoveBytes(y []byte, path string, selector Selector, opts ...Option) ([]byte, error) {
+	root, seq, err := findSequence(y, path)
+	if err != nil {
+		return nil, err
+	}
+	if opts, err = sequenceOptions(root, path, opts); err != nil {
+		return nil, err
+	}
+	if seq == nil {
+		return y, nil
+	}
+	selected := map[int]bool{}
+	kept := -1
+	for i, item := range seq.Content {
+		var v interface{}
+		if err := item.Decode(&v); err != nil {
+			return nil, fmt.Errorf("failed to decode item %d of %s: %w", i, path, err)
+		}
+		if selector(i, v) {
+			selected[i] = true
+			continue
+		}
+		kept = i
+	}
+	switch {
+	case len(selected) == 0:
+		return y, nil
+	case kept == -1:
+		return SetBytes(y, path, []interface{}{}, opts...)
+	}
+	var updated []byte
+	if seq.Style&yaml3.FlowStyle != 0 {
+		start, end, err := flowSpan(y, seq)
+		if err != nil {
+			return nil, err
+		}
+		content := []*yaml3.Node{}
+		for i, item := range seq.Content {
+			if !selected[i] {
+				content = append(content, item)
+			}
+		}
+		seq.Content = content
+		if updated, err = renderFlow(y, seq, start, end); err != nil {
+			return nil, err
+		}
+	} else if updated, err = removeItems(y, seq, selected, kept); err != nil {
+		return nil, err
+	}
+	return makeOptions(opts).validated(updated)
+}
+
+// removeItems removes the selected items from a block sequence, each item is
+// removed with its head comment up to the head comment of the next, and
+// trailing items are removed from the end of the line before the head comment
+// of the first of them.
+func removeItems(y []byte, seq *yaml3.Node, selected map[int]bool, kept int) ([]byte, error) {
+	items, err := itemOffsets(y, seq)
+	if err != nil {
+		return nil, err
+	}
+	itemEnd := func(i int) (int, error) {
+		return nodeEnd(y, lineStart(y, items[i]), seq.Column-1, false, seq.Content[i])
+	}
+	updated := y
+	if kept < len(items)-1 {
+		// Comments indented within the kept item are kept with it.
+		head := headStart(y, items[kept+1], seq.Column-1)
+		start := lineEnd(y, lineStart(y, head)-1)
+		end, err := itemEnd(len(items) - 1)
+		if err != nil {
+			return nil, err
+		}
+		updated = splice(updated, start, end, nil)
+	}
+	for i := kept - 1; i >= 0; i-- {
+		if selected[i] {
+			updated = splice(updated, headStart(y, items[i], seq.Column-1), headStart(y, items[i+1], seq.Column-1), nil)
+		}
+	}
+	return updated, nil
+}
+
+// headStart returns the offset of the comment lines, at the indent of the
+// items, directly above the item at the offset, or the item's offset if there
+// are none, or it's not the first item on its line.
+func headStart(y []byte, item, indent int) int {
+	start := lineStart(y, item)
+	if start+indent != item {
+		return item
+	}
+	for start > 0 {
+		previous := lineStart(y, start-1)
+		line := y[previous:lineEnd(y, previous)]
+		content := bytes.TrimLeft(line, " ")
+		if len(content) == 0 || content[0] != '#' || len(line)-len(content) != indent {
+			break
+		}
+		start = previous
+	}
+	return start + indent
+}
+
+// sequenceOptions checks the current value of the sequence at the path
+// against the expected value of the options, and returns the options for
+// updating it, without the expectation, which doesn't apply to the item.
+//
+// With Strict, the parents of a missing sequence must exist.
+func sequenceOptions(root *yaml3.Node, path string, opts []Option) ([]Option, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	o := makeOptions(opts)
+	if err := o.checkCurrent(root, segments, path); err != nil {
+		return nil, err
+	}
+	if err := checkPath(root, segments, o.strict && !o.ensurePath); err != nil {
+		return nil, err
+	}
+	return append(append([]Option{}, opts...), func(o *options) {
+		o.expected, o.expectedPattern = nil, ""
+	}), nil
+}
+
+// findSequence returns the root of the document, and the sequence at the
+// path, or nil if there is no value at the path.
+func findSequence(y []byte, path string) (*yaml3.Node, *yaml3.Node, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := parseRoot(y)
+	if err != nil || root == nil {
+		return root, nil, err
+	}
+	node, _ := findNode(root, segments)
+	switch {
+	case node == nil || isNull(node):
+		return root, nil, nil
+	case node.Kind == yaml3.AliasNode:
+		return nil, nil, fmt.Errorf("cannot update %s, it is an alias", path)
+	case node.Kind != yaml3.SequenceNode:
+		return nil, nil, fmt.Errorf("cannot update %s, it is a %s, not a sequence", path, kindOf(node))
+	}
+	return root, node, nil
+}
+
+func kindOf(node *yaml3.Node) string {
+	switch node.Kind {
+	case yaml3.MappingNode:
+		return "mapping"
+	case yaml3.SequenceNode:
+		return "sequence"
+	}
+	return "scalar"
+}
@@ -0,0 +1,223 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestAppendBytes(t *testing.T) {
+	appendTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+		wantErr  string
+	}{
+		{
+			name:     "block sequence",
+			source:   "env:\n- name: A\n  value: \"1\"\nname: test\n",
+			path:     "env",
+			newValue: map[string]string{"name": "DEBUG", "value": "1"},
+			want:     "env:\n- name: A\n  value: \"1\"\n- name: DEBUG\n  value: \"1\"\nname: test\n",
+		},
+		{
+			name:     "flow sequence",
+			source:   "args: [--verbose]\n",
+			path:     "args",
+			newValue: "--port=8080",
+			want:     "args: [--verbose, --port=8080]\n",
+		},
+		{
+			name:     "missing sequence",
+			source:   "spec:\n  name: test\n",
+			path:     "spec.volumes",
+			newValue: map[string]string{"name": "cache"},
+			want:     "spec:\n  name: test\n  volumes:\n  - name: cache\n",
+		},
+		{
+			name:     "not a sequence",
+			source:   "spec:\n  name: test\n",
+			path:     "spec",
+			newValue: "test",
+			wantErr:  "cannot update spec, it is a mapping, not a sequence",
+		},
+	}
+
+	for _, tt := range appendTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := AppendBytes([]byte(tt.source), tt.path, tt.newValue)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to append:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInsertBytes(t *testing.T) {
+	insertTests := []struct {
+		name    string
+		source  string
+		path    string
+		index   int
+		want    string
+		wantErr string
+	}{
+		{"first item", "items:\n- one\n- two\n", "items", 0, "items:\n- new\n- one\n- two\n", ""},
+		{"middle item", "items:\n  - one # first\n  - two\n", "items", 1, "items:\n  - one # first\n  - new\n  - two\n", ""},
+		{"nested sequence", "- - one\n- two\n", "0", 0, "- - new\n  - one\n- two\n", ""},
+		{"before head comment", "items:\n  - one\n  # the second\n  - two\n", "items", 1, "items:\n  - one\n  - new\n  # the second\n  - two\n", ""},
+		{"before first head comment", "items:\n# the first\n- one\n", "items", 0, "items:\n- new\n# the first\n- one\n", ""},
+		{"flow sequence", "items: [one, two]\n", "items", 1, "items: [one, new, two]\n", ""},
+		{"append", "items:\n- one\n", "items", 1, "items:\n- one\n- new\n", ""},
+		{"missing sequence", "name: test\n", "items", 0, "name: test\nitems:\n- new\n", ""},
+		{"out of range", "items:\n- one\n", "items", 2, "", "index 2 out of range at items, the sequence has 1 items"},
+	}
+
+	for _, tt := range insertTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := InsertBytes([]byte(tt.source), tt.path, tt.index, "new")
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to insert:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRemoveBytes(t *testing.T) {
+	volumes := "volumes:\n- name: cache\n  emptyDir: {}\n- name: config\n  configMap:\n    name: app\n- name: tmp\nname: test\n"
+	removeTests := []struct {
+		name     string
+		source   string
+		path     string
+		selector Selector
+		want     string
+	}{
+		{"first item", volumes, "volumes", Index(0), "volumes:\n- name: config\n  configMap:\n    name: app\n- name: tmp\nname: test\n"},
+		{"last item", volumes, "volumes", Index(2), "volumes:\n- name: cache\n  emptyDir: {}\n- name: config\n  configMap:\n    name: app\nname: test\n"},
+		{"matching item", volumes, "volumes", Matching("name", "config"), "volumes:\n- name: cache\n  emptyDir: {}\n- name: tmp\nname: test\n"},
+		{"several items", volumes, "volumes", func(i int, _ interface{}) bool { return i > 0 }, "volumes:\n- name: cache\n  emptyDir: {}\nname: test\n"},
+		{"head comments", "volumes:\n# cache\n- name: cache\n# config\n# mounted\n- name: config\n", "volumes", Index(0), "volumes:\n# config\n# mounted\n- name: config\n"},
+		{"head comment of a removed item", "volumes:\n- name: cache\n# config\n- name: config\n# tmp\n- name: tmp\n", "volumes", Index(1), "volumes:\n- name: cache\n# tmp\n- name: tmp\n"},
+		{"head comment of the last item", "volumes:\n- name: cache\n# tmp\n- name: tmp\nname: test\n", "volumes", Index(1), "volumes:\n- name: cache\nname: test\n"},
+		{"indented comment", "volumes:\n- name: cache\n  # cache only\n- name: config\n", "volumes", Index(1), "volumes:\n- name: cache\n  # cache only\n"},
+		{"all items", volumes, "volumes", func(int, interface{}) bool { return true }, "volumes: []\nname: test\n"},
+		{"no matching items", volumes, "volumes", Matching("name", "logs"), volumes},
+		{"missing sequence", "name: test\n", "volumes", Index(0), "name: test\n"},
+		{"flow sequence", "ports: [80, 443, 8080]\n", "ports", Matching("", ""), "ports: [80, 443, 8080]\n"},
+		{"flow sequence by index", "ports: [80, 443, 8080] # public\n", "ports", Index(1), "ports: [80, 8080] # public\n"},
+	}
+
+	for _, tt := range removeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := RemoveBytes([]byte(tt.source), tt.path, tt.selector)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to remove:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSequenceOptions(t *testing.T) {
+	source := "spec:\n  args:\n  - --verbose\n"
+	optionTests := []struct {
+		name    string
+		update  func([]byte, ...Option) ([]byte, error)
+		opts    []Option
+		want    string
+		wantErr string
+	}{
+		{
+			name: "append with the expected value",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return AppendBytes(y, "spec.args", "--port=8080", opts...)
+			},
+			opts: []Option{ExpectValue("- --verbose")},
+			want: "spec:\n  args:\n  - --verbose\n  - --port=8080\n",
+		},
+		{
+			name: "append with an unexpected value",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return AppendBytes(y, "spec.args", "--port=8080", opts...)
+			},
+			opts:    []Option{ExpectValue("- --debug")},
+			wantErr: `unexpected current value: spec.args is "- --verbose", expected "- --debug"`,
+		},
+		{
+			name: "insert with a matching value",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return InsertBytes(y, "spec.args", 0, "--port=8080", opts...)
+			},
+			opts: []Option{ExpectMatch("--verbose")},
+			want: "spec:\n  args:\n  - --port=8080\n  - --verbose\n",
+		},
+		{
+			name: "insert with an unexpected value",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return InsertBytes(y, "spec.args", 0, "--port=8080", opts...)
+			},
+			opts:    []Option{ExpectValue("- --debug")},
+			wantErr: `unexpected current value: spec.args is "- --verbose", expected "- --debug"`,
+		},
+		{
+			name:    "remove with an unexpected value",
+			update:  func(y []byte, opts ...Option) ([]byte, error) { return RemoveBytes(y, "spec.args", Index(0), opts...) },
+			opts:    []Option{ExpectValue("- --debug")},
+			wantErr: `unexpected current value: spec.args is "- --verbose", expected "- --debug"`,
+		},
+		{
+			name: "strict append with a missing parent",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return AppendBytes(y, "metadata.finalizers", "test", opts...)
+			},
+			opts:    []Option{Strict()},
+			wantErr: "parent does not exist: metadata",
+		},
+		{
+			name: "strict insert with a missing parent",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return InsertBytes(y, "metadata.finalizers", 0, "test", opts...)
+			},
+			opts:    []Option{Strict()},
+			wantErr: "parent does not exist: metadata",
+		},
+		{
+			name: "strict remove with a missing parent",
+			update: func(y []byte, opts ...Option) ([]byte, error) {
+				return RemoveBytes(y, "metadata.finalizers", Index(0), opts...)
+			},
+			opts:    []Option{Strict()},
+			wantErr: "parent does not exist: metadata",
+		},
+		{
+			name:   "strict append to a missing sequence",
+			update: func(y []byte, opts ...Option) ([]byte, error) { return AppendBytes(y, "spec.env", "test", opts...) },
+			opts:   []Option{Strict()},
+			want:   "spec:\n  args:\n  - --verbose\n  env:\n  - test\n",
+		},
+	}
+
+	for _, tt := range optionTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := tt.update([]byte(source), tt.opts...)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
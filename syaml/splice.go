@@ -0,0 +1,694 @@
+package syaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// setNode sets the value at the path by editing the YAML directly, only the
+// bytes of the replaced or added node are rendered, so the rest of the
+// document, including values that JSON can't represent, is left untouched.
+//
+// The keys of replaced mappings keep the order of the source document.
+//
+// Missing keys are added after the last entry of the closest existing mapping
+// on the path, creating any missing parents, numeric segments create
+// sequences. Changes within flow collections re-render the outermost flow
+// collection on the path.
+//
+// The path must have been checked with checkPath.
+func setNode(y []byte, root *yaml3.Node, segments []string, value interface{}) ([]byte, error) {
+	newValue, err := orderedValueNode(value, orderTemplate(root, segments))
+	if err != nil {
+		return nil, err
+	}
+	s := detectStyle(y, root)
+	if root == nil || isNull(root) {
+		return setDocument(y, root, segments, newValue, s)
+	}
+	node := root
+	for i, seg := range segments {
+		if node.Kind == yaml3.AliasNode {
+			return nil, aliasError(segments, i)
+		}
+		if node.Style&yaml3.FlowStyle != 0 {
+			return setFlow(y, node, segments, i, newValue)
+		}
+		remaining := segments[i+1:]
+		switch node.Kind {
+		case yaml3.MappingNode:
+			index := mappingIndex(node, seg)
+			if index == -1 {
+				if len(remaining) > 0 && isMerged(node, seg) {
+					return nil, fmt.Errorf("cannot set %s, %s is merged from an alias", joinPath(segments), joinPath(segments[:i+1]))
+				}
+				return insertEntry(y, node, seg, nested(remaining, newValue), s)
+			}
+			key, child := node.Content[index-1], node.Content[index]
+			if len(remaining) == 0 || isNull(child) {
+				return replaceEntry(y, key, child, nested(remaining, newValue), s)
+			}
+			node = child
+		case yaml3.SequenceNode:
+			index, _ := sequenceIndex(seg)
+			if index == len(node.Content) {
+				return appendItem(y, node, nested(remaining, newValue), s)
+			}
+			item := node.Content[index]
+			if len(remaining) == 0 || isNull(item) {
+				return replaceItem(y, node, index, nested(remaining, newValue), s)
+			}
+			node = item
+		default:
+			return nil, &TypeMismatchError{Path: joinPath(segments[:i+1]), Segment: seg, Kind: "scalar"}
+		}
+	}
+	return nil, fmt.Errorf("cannot set %s", joinPath(segments))
+}
+
+// valueNode converts the value to a node via JSON, as SetBytes has always
+// done, so that types with JSON tags are rendered consistently, RawYAML is
+// parsed directly.
+func valueNode(value interface{}) (*yaml3.Node, error) {
+	if raw, ok := value.(RawYAML); ok {
+		return raw.node()
+	}
+	j, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(j, &doc); err != nil {
+		return nil, err
+	}
+	node := doc.Content[0]
+	clearStyle(node)
+	return node, nil
+}
+
+// clearStyle removes the quoting and flow styles, so the node is rendered in
+// the style of the document, explicit tags are retained.
+func clearStyle(node *yaml3.Node) {
+	node.Style &= yaml3.TaggedStyle
+	for _, n := range node.Content {
+		clearStyle(n)
+	}
+}
+
+// nested returns the value nested under the remaining path segments, numeric
+// segments create sequences, padded with nulls.
+func nested(remaining []string, value *yaml3.Node) *yaml3.Node {
+	for i := len(remaining) - 1; i >= 0; i-- {
+		index, ok := sequenceIndex(remaining[i])
+		if !ok {
+			value = &yaml3.Node{Kind: yaml3.MappingNode, Tag: "!!map", Content: []*yaml3.Node{stringNode(remaining[i]), value}}
+			continue
+		}
+		seq := &yaml3.Node{Kind: yaml3.SequenceNode, Tag: "!!seq"}
+		for j := 0; j < index; j++ {
+			seq.Content = append(seq.Content, &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!null", Value: "null"})
+		}
+		seq.Content = append(seq.Content, value)
+		value = seq
+	}
+	return value
+}
+
+func isNull(node *yaml3.Node) bool {
+	return node.Kind == yaml3.ScalarNode && node.Tag == "!!null"
+}
+
+func aliasError(segments []string, i int) error {
+	return fmt.Errorf("cannot set %s, %s is an alias", joinPath(segments), joinPath(segments[:i]))
+}
+
+// mappingIndex returns the index of the value for the key in the mapping's
+// content, or -1 if the key doesn't exist.
+func mappingIndex(node *yaml3.Node, segment string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if k := node.Content[i]; k.Kind == yaml3.ScalarNode && k.Value == segment {
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// isMerged returns true if the key is only present in the mapping through a
+// merge key, setting a path below it would drop the merged values.
+func isMerged(node *yaml3.Node, segment string) bool {
+	index := mappingIndex(node, "<<")
+	if index == -1 {
+		return false
+	}
+	merged := []*yaml3.Node{node.Content[index]}
+	if merged[0].Kind == yaml3.SequenceNode {
+		merged = merged[0].Content
+	}
+	for _, m := range merged {
+		if m.Kind == yaml3.AliasNode {
+			m = m.Alias
+		}
+		if m != nil && m.Kind == yaml3.MappingNode && mappingIndex(m, segment) != -1 {
+			return true
+		}
+	}
+	return false
+}
+
+// setDocument sets the value in an empty or null document.
+func setDocument(y []byte, root *yaml3.Node, segments []string, value *yaml3.Node, s style) ([]byte, error) {
+	doc := nested(segments, value)
+	e := &emitter{style: s}
+	if doc.Kind == yaml3.MappingNode {
+		e.mapping(doc, 0)
+	} else {
+		e.sequence(doc, 0)
+	}
+	rendered := e.bytes()
+	if root != nil && root.Value != "" {
+		start, end, ok := scalarSpan(y, root, false)
+		if !ok {
+			return nil, fmt.Errorf("failed to locate the document at line %d", root.Line)
+		}
+		return splice(y, start, end, rendered), nil
+	}
+	switch {
+	case len(y) == 0:
+		return append(rendered, s.eol...), nil
+	case y[len(y)-1] == '\n':
+		return append(append(y[:len(y):len(y)], rendered...), s.eol...), nil
+	}
+	return splice(y, len(y), len(y), append(append([]byte{}, s.eol...), rendered...)), nil
+}
+
+// setFlow sets the value within a flow collection, by updating the parsed
+// nodes, and re-rendering the collection.
+func setFlow(y []byte, node *yaml3.Node, segments []string, i int, value *yaml3.Node) ([]byte, error) {
+	start, end, err := flowSpan(y, node)
+	if err != nil {
+		return nil, err
+	}
+	if err := setWithin(node, segments, i, value); err != nil {
+		return nil, err
+	}
+	return renderFlow(y, node, start, end)
+}
+
+// flowSpan returns the start and end offsets of a flow collection.
+func flowSpan(y []byte, node *yaml3.Node) (int, int, error) {
+	start, ok := offsetOf(y, node.Line, node.Column)
+	if !ok || start >= len(y) || y[start] != '{' && y[start] != '[' {
+		return 0, 0, fmt.Errorf("failed to locate the flow collection at line %d", node.Line)
+	}
+	end, ok := flowEnd(y, start)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to locate the end of the flow collection at line %d", node.Line)
+	}
+	return start, end, nil
+}
+
+// renderFlow replaces the span of the flow collection with the rendered node,
+// comments within the collection are not retained.
+func renderFlow(y []byte, node *yaml3.Node, start, end int) ([]byte, error) {
+	node.Anchor = ""
+	clearComments(node)
+	b, err := yaml3.Marshal(node)
+	if err != nil {
+		return nil, err
+	}
+	rendered := bytes.TrimSuffix(b, []byte("\n"))
+	return splice(y, start, end, bytes.ReplaceAll(rendered, []byte("\n"), eolOf(y))), nil
+}
+
+// setWithin sets the value at the path segments from i in the parsed nodes.
+func setWithin(node *yaml3.Node, segments []string, i int, value *yaml3.Node) error {
+	for ; i < len(segments); i++ {
+		remaining := segments[i+1:]
+		switch node.Kind {
+		case yaml3.MappingNode:
+			index := mappingIndex(node, segments[i])
+			if index == -1 {
+				node.Content = append(node.Content, stringNode(segments[i]), nested(remaining, value))
+				return nil
+			}
+			if len(remaining) == 0 || isNull(node.Content[index]) {
+				node.Content[index] = withAnchor(nested(remaining, value), node.Content[index])
+				return nil
+			}
+			node = node.Content[index]
+		case yaml3.SequenceNode:
+			index, _ := sequenceIndex(segments[i])
+			if index == len(node.Content) {
+				node.Content = append(node.Content, nested(remaining, value))
+				return nil
+			}
+			if len(remaining) == 0 || isNull(node.Content[index]) {
+				node.Content[index] = withAnchor(nested(remaining, value), node.Content[index])
+				return nil
+			}
+			node = node.Content[index]
+		case yaml3.AliasNode:
+			return aliasError(segments, i)
+		default:
+			return &TypeMismatchError{Path: joinPath(segments[:i+1]), Segment: segments[i], Kind: "scalar"}
+		}
+	}
+	return nil
+}
+
+// withAnchor copies the anchor of the replaced node, so aliases still refer
+// to the value.
+func withAnchor(node, replaced *yaml3.Node) *yaml3.Node {
+	node.Anchor = replaced.Anchor
+	return node
+}
+
+func clearComments(node *yaml3.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	for _, n := range node.Content {
+		clearComments(n)
+	}
+}
+
+// flowEnd returns the offset after the bracket that closes the flow
+// collection starting at the offset.
+func flowEnd(y []byte, start int) (int, bool) {
+	depth := 0
+	for i := start; i < len(y); i++ {
+		switch c := y[i]; c {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		case '"', '\'':
+			// Quotes only start a scalar at the start of a flow entry.
+			if before := bytes.TrimRight(y[start:i], " \t\r\n"); !bytes.ContainsAny(before[len(before)-1:], "[{,:") {
+				continue
+			}
+			for i++; i < len(y); i++ {
+				if c == '"' && y[i] == '\\' {
+					i++
+					continue
+				}
+				if y[i] == c {
+					if c == '\'' && i+1 < len(y) && y[i+1] == '\'' {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case '#':
+			if y[i-1] == ' ' || y[i-1] == '\t' || y[i-1] == '\n' {
+				i = lineEnd(y, i)
+			}
+		}
+	}
+	return 0, false
+}
+
+// replaceEntry replaces the mapping entry for the key with a new entry for
+// the value, the key is retained as written.
+func replaceEntry(y []byte, key, old, value *yaml3.Node, s style) ([]byte, error) {
+	start, end, err := entrySpan(y, key, old)
+	if err != nil {
+		return nil, err
+	}
+	k, ok := renderKey(y, key)
+	if !ok {
+		return nil, fmt.Errorf("failed to render the key %s", key.Value)
+	}
+	e := &emitter{style: s}
+	e.entry(k, withAnchor(value, old), key.Column-1)
+	return splice(y, start, end, bytes.TrimLeft(e.bytes(), " ")), nil
+}
+
+// insertEntry adds a new entry for the key to the end of the mapping.
+func insertEntry(y []byte, mapping *yaml3.Node, key string, value *yaml3.Node, s style) ([]byte, error) {
+	lastKey, lastValue := mapping.Content[len(mapping.Content)-2], mapping.Content[len(mapping.Content)-1]
+	_, end, err := entrySpan(y, lastKey, lastValue)
+	if err != nil {
+		return nil, err
+	}
+	k, ok := renderScalar(key, 0)
+	if !ok {
+		return nil, fmt.Errorf("failed to render the key %s", key)
+	}
+	e := &emitter{style: s}
+	e.entry(k, value, lastKey.Column-1)
+	return splice(y, end, end, append(append([]byte{}, s.eol...), e.bytes()...)), nil
+}
+
+// entrySpan returns the offset of the key of a block mapping entry, and the
+// end of the last line of the entry.
+func entrySpan(y []byte, key, value *yaml3.Node) (int, int, error) {
+	start, ok := offsetOf(y, key.Line, key.Column)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to locate the key %s at line %d", key.Value, key.Line)
+	}
+	end, err := nodeEnd(y, lineStart(y, start), key.Column-1, value.Kind == yaml3.SequenceNode, value)
+	return start, end, err
+}
+
+// replaceItem replaces the sequence item at the index.
+func replaceItem(y []byte, seq *yaml3.Node, index int, value *yaml3.Node, s style) ([]byte, error) {
+	items, err := itemOffsets(y, seq)
+	if err != nil {
+		return nil, err
+	}
+	item := seq.Content[index]
+	end, err := nodeEnd(y, lineStart(y, items[index]), seq.Column-1, false, item)
+	if err != nil {
+		return nil, err
+	}
+	e := &emitter{style: s}
+	e.sequence(&yaml3.Node{Kind: yaml3.SequenceNode, Content: []*yaml3.Node{withAnchor(value, item)}}, seq.Column-1)
+	return splice(y, items[index], end, bytes.TrimLeft(e.bytes(), " ")), nil
+}
+
+// appendItem adds an item to the end of the sequence.
+func appendItem(y []byte, seq *yaml3.Node, value *yaml3.Node, s style) ([]byte, error) {
+	items, err := itemOffsets(y, seq)
+	if err != nil {
+		return nil, err
+	}
+	last := len(items) - 1
+	end, err := nodeEnd(y, lineStart(y, items[last]), seq.Column-1, false, seq.Content[last])
+	if err != nil {
+		return nil, err
+	}
+	e := &emitter{style: s}
+	e.sequence(&yaml3.Node{Kind: yaml3.SequenceNode, Content: []*yaml3.Node{value}}, seq.Column-1)
+	return splice(y, end, end, append(append([]byte{}, s.eol...), e.bytes()...)), nil
+}
+
+// itemOffsets returns the offsets of the "-" indicators of the items of a
+// block sequence, these are the first at the start of the sequence, and those
+// that follow at the same indentation.
+func itemOffsets(y []byte, seq *yaml3.Node) ([]int, error) {
+	start, ok := offsetOf(y, seq.Line, seq.Column)
+	if !ok || start >= len(y) || y[start] != '-' {
+		return nil, fmt.Errorf("failed to locate the sequence at line %d", seq.Line)
+	}
+	items := []int{start}
+	indent := seq.Column - 1
+	for next := start; ; {
+		i := bytes.IndexByte(y[next:], '\n')
+		if i == -1 {
+			break
+		}
+		next += i + 1
+		line := y[next:lineEnd(y, next)]
+		content := bytes.TrimLeft(line, " ")
+		if len(content) == 0 || content[0] == '#' {
+			continue
+		}
+		lineIndent := len(line) - len(content)
+		if lineIndent > indent {
+			continue
+		}
+		if lineIndent < indent || !isItem(content) {
+			break
+		}
+		items = append(items, next+lineIndent)
+	}
+	if len(items) != len(seq.Content) {
+		return nil, fmt.Errorf("failed to locate the items of the sequence at line %d", seq.Line)
+	}
+	return items, nil
+}
+
+func isItem(content []byte) bool {
+	return content[0] == '-' && (len(content) == 1 || content[1] == ' ' || content[1] == '\r')
+}
+
+// nodeEnd returns the offset of the end of the last line of a block mapping
+// entry or sequence item, excluding the line ending, and any trailing
+// comments.
+//
+// The lines are those following the line start that are indented beyond the
+// indent, or are items of a block sequence at the same indentation if
+// sequence items are included.
+func nodeEnd(y []byte, start, indent int, sequence bool, value *yaml3.Node) (int, error) {
+	if value.Kind != yaml3.ScalarNode && value.Style&yaml3.FlowStyle != 0 {
+		offset, ok := offsetOf(y, value.Line, value.Column)
+		if ok {
+			if end, ok := flowEnd(y, offset); ok {
+				return end, nil
+			}
+		}
+		return 0, fmt.Errorf("failed to locate the end of the flow collection at line %d", value.Line)
+	}
+	end := lineEnd(y, start)
+	for next := end; ; {
+		i := bytes.IndexByte(y[next:], '\n')
+		if i == -1 {
+			break
+		}
+		next += i + 1
+		line := y[next:lineEnd(y, next)]
+		content := bytes.TrimLeft(line, " ")
+		if len(content) == 0 || content[0] == '#' {
+			continue
+		}
+		lineIndent := len(line) - len(content)
+		if lineIndent <= indent && !(sequence && lineIndent == indent && isItem(content)) {
+			break
+		}
+		end = next + len(line)
+	}
+	return end, nil
+}
+
+// renderKey returns the key as written in the document, or rendered if it
+// can't be located.
+func renderKey(y []byte, key *yaml3.Node) ([]byte, bool) {
+	if key.Style&(yaml3.TaggedStyle|yaml3.LiteralStyle|yaml3.FoldedStyle) == 0 && key.Anchor == "" {
+		if start, end, ok := scalarSpan(y, key, false); ok {
+			return y[start:end], true
+		}
+	}
+	return renderScalar(key.Value, 0)
+}
+
+func lineStart(y []byte, offset int) int {
+	return bytes.LastIndexByte(y[:offset], '\n') + 1
+}
+
+// lineEnd returns the offset of the end of the line containing the offset,
+// excluding a carriage return.
+func lineEnd(y []byte, offset int) int {
+	i := bytes.IndexByte(y[offset:], '\n')
+	if i == -1 {
+		return len(y)
+	}
+	end := offset + i
+	if end > offset && y[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+// splice returns a copy of the document with the bytes between start and end
+// replaced.
+func splice(y []byte, start, end int, replacement []byte) []byte {
+	updated := make([]byte, 0, len(y)-(end-start)+len(replacement))
+	updated = append(updated, y[:start]...)
+	updated = append(updated, replacement...)
+	return append(updated, y[end:]...)
+}
+
+// style records the formatting conventions of a document.
+type style struct {
+	width int
+	// compactSequences is true when sequence items are at the same
+	// indentation as their key, rather than indented beneath it.
+	compactSequences bool
+	eol              []byte
+}
+
+// detectStyle returns the formatting conventions of the document.
+//
+// Sequences are compact unless the first block sequence in a mapping is
+// indented beneath its key.
+func detectStyle(y []byte, root *yaml3.Node) style {
+	s := style{width: detectIndent(y), compactSequences: true, eol: eolOf(y)}
+	if root == nil {
+		return s
+	}
+	if key, seq := firstSequence(root); seq != nil {
+		s.compactSequences = seq.Column == key.Column
+	}
+	return s
+}
+
+// firstSequence returns the first non-empty block sequence that is a value in
+// a mapping, and its key.
+func firstSequence(node *yaml3.Node) (*yaml3.Node, *yaml3.Node) {
+	if node.Kind == yaml3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if v := node.Content[i+1]; v.Kind == yaml3.SequenceNode && v.Style&yaml3.FlowStyle == 0 && len(v.Content) > 0 {
+				return node.Content[i], v
+			}
+		}
+	}
+	for _, n := range node.Content {
+		if key, seq := firstSequence(n); seq != nil {
+			return key, seq
+		}
+	}
+	return nil, nil
+}
+
+// emitter renders nodes decoded from JSON as lines of block YAML.
+type emitter struct {
+	style style
+	lines [][]byte
+}
+
+func (e *emitter) add(indent int, parts ...[]byte) {
+	line := bytes.Repeat([]byte(" "), indent)
+	for _, p := range parts {
+		line = append(line, p...)
+	}
+	e.lines = append(e.lines, line)
+}
+
+func (e *emitter) bytes() []byte {
+	return bytes.Join(e.lines, e.style.eol)
+}
+
+func (e *emitter) entry(k []byte, value *yaml3.Node, indent int) {
+	switch {
+	case value.Kind == yaml3.MappingNode && len(value.Content) > 0:
+		e.add(indent, k, []byte(":"), anchor(value, " "))
+		e.mapping(value, indent+e.style.width)
+	case value.Kind == yaml3.SequenceNode && len(value.Content) > 0:
+		e.add(indent, k, []byte(":"), anchor(value, " "))
+		if e.style.compactSequences {
+			e.sequence(value, indent)
+			return
+		}
+		e.sequence(value, indent+e.style.width)
+	default:
+		e.scalar(value, indent, k, []byte(": "), anchor(value, ""))
+	}
+}
+
+func (e *emitter) mapping(node *yaml3.Node, indent int) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		k, _ := renderScalar(node.Content[i].Value, 0)
+		e.entry(k, node.Content[i+1], indent)
+	}
+}
+
+// sequence renders the items, collections are rendered at the indentation of
+// the item content, and the "- " indicator replaces the first indent.
+func (e *emitter) sequence(node *yaml3.Node, indent int) {
+	for _, item := range node.Content {
+		first := len(e.lines)
+		isCollection := (item.Kind == yaml3.MappingNode || item.Kind == yaml3.SequenceNode) && len(item.Content) > 0
+		switch {
+		case isCollection && item.Anchor != "":
+			e.add(indent, []byte("-"), anchor(item, " "))
+			first = -1
+		case !isCollection:
+			e.scalar(item, indent, []byte("- "), anchor(item, ""))
+			continue
+		}
+		if item.Kind == yaml3.MappingNode {
+			e.mapping(item, indent+2)
+		} else {
+			e.sequence(item, indent+2)
+		}
+		if first != -1 {
+			e.lines[first] = append(append(bytes.Repeat([]byte(" "), indent), "- "...), bytes.TrimLeft(e.lines[first], " ")...)
+		}
+	}
+}
+
+// scalar renders a scalar, or an empty collection, after the prefix, the
+// content of block scalars for multi-line strings is indented.
+func (e *emitter) scalar(node *yaml3.Node, indent int, prefix ...[]byte) {
+	rendered := []byte(node.Value)
+	switch {
+	case node.Kind == yaml3.MappingNode:
+		rendered = []byte("{}")
+	case node.Kind == yaml3.SequenceNode:
+		rendered = []byte("[]")
+	case node.Tag == "!!str":
+		// Strings are always encodable.
+		b, _ := yaml3.Marshal(stringNode(node.Value))
+		rendered = bytes.TrimSuffix(b, []byte("\n"))
+	case node.Style&yaml3.TaggedStyle != 0:
+		rendered = []byte(node.Tag + " " + node.Value)
+	}
+	lines := bytes.Split(rendered, []byte("\n"))
+	e.add(indent, append(prefix, lines[0])...)
+	for _, l := range lines[1:] {
+		if len(l) == 0 {
+			e.lines = append(e.lines, l)
+			continue
+		}
+		// yaml.v3 indents the content of block scalars by four spaces.
+		e.add(indent+e.style.width, bytes.TrimPrefix(l, []byte("    ")))
+	}
+}
+
+// anchor renders the anchor of the node with the separator, before or after
+// it, as the anchor precedes scalars, and follows the key of collections.
+func anchor(node *yaml3.Node, before string) []byte {
+	if node.Anchor == "" {
+		return nil
+	}
+	if before != "" {
+		return []byte(before + "&" + node.Anchor)
+	}
+	return []byte("&" + node.Anchor + " ")
+}
+
+func stringNode(s string) *yaml3.Node {
+	n := &yaml3.Node{}
+	n.SetString(s)
+	return n
+}
+
+func eolOf(y []byte) []byte {
+	if detectLineEndings(y).crlf {
+		return []byte("\r\n")
+	}
+	return []byte("\n")
+}
+
+// detectIndent returns the indentation of the first nested block mapping in
+// the document, defaulting to two spaces.
+func detectIndent(y []byte) int {
+	var previous []byte
+	for offset := 0; offset < len(y); {
+		end := lineEnd(y, offset)
+		line := y[offset:end]
+		if content := bytes.TrimLeft(line, " "); len(content) > 0 && content[0] != '#' {
+			if previous != nil && content[0] != '-' {
+				if d := (len(line) - len(content)) - (len(previous) - len(bytes.TrimLeft(previous, " "))); d > 0 {
+					return d
+				}
+			}
+			previous = nil
+			if content[0] != '-' && bytes.HasSuffix(bytes.TrimRight(content, " "), []byte(":")) {
+				previous = line
+			}
+		}
+		i := bytes.IndexByte(y[offset:], '\n')
+		if i == -1 {
+			break
+		}
+		offset += i + 1
+	}
+	return 2
+}
@@ -0,0 +1,272 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestSetSubtree(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "new key after comments",
+			source:   "# config\nspec:\n  # replicas\n  replicas: 1 # one\n\n# other\nother: true\n",
+			path:     "spec.image",
+			newValue: "nginx",
+			want:     "# config\nspec:\n  # replicas\n  replicas: 1 # one\n  image: nginx\n\n# other\nother: true\n",
+		},
+		{
+			name:     "new key after a nested mapping",
+			source:   "spec:\n  template:\n    name: test\n  # trailing\nstatus: {}\n",
+			path:     "spec.replicas",
+			newValue: 2,
+			want:     "spec:\n  template:\n    name: test\n  replicas: 2\n  # trailing\nstatus: {}\n",
+		},
+		{
+			name:     "missing parents",
+			source:   "metadata:\n  name: test\n",
+			path:     "metadata.annotations.example\\.com/reviewed",
+			newValue: "true",
+			want:     "metadata:\n  name: test\n  annotations:\n    example.com/reviewed: \"true\"\n",
+		},
+		{
+			name:     "replace a mapping",
+			source:   "spec:\n  resources:\n    limits:\n      cpu: 1\n  replicas: 1\n",
+			path:     "spec.resources",
+			newValue: map[string]interface{}{"requests": map[string]interface{}{"cpu": "500m"}},
+			want:     "spec:\n  resources:\n    requests:\n      cpu: 500m\n  replicas: 1\n",
+		},
+		{
+			name:     "replace a scalar with a sequence",
+			source:   "args: none\nname: test\n",
+			path:     "args",
+			newValue: []string{"--verbose", "--port=8080"},
+			want:     "args:\n- --verbose\n- --port=8080\nname: test\n",
+		},
+		{
+			name:     "indented sequences",
+			source:   "ports:\n    - 80\nspec:\n    name: test\n",
+			path:     "spec.args",
+			newValue: []interface{}{map[string]interface{}{"name": "a", "value": 1}},
+			want:     "ports:\n    - 80\nspec:\n    name: test\n    args:\n        - name: a\n          value: 1\n",
+		},
+		{
+			name:     "replace a sequence",
+			source:   "args:\n- one\n- two\nname: test\n",
+			path:     "args",
+			newValue: []string{"three"},
+			want:     "args:\n- three\nname: test\n",
+		},
+		{
+			name:     "replace a literal block",
+			source:   "script: |\n  echo one\n  echo two\nname: test\n",
+			path:     "script",
+			newValue: "echo three\necho four",
+			want:     "script: |-\n  echo three\n  echo four\nname: test\n",
+		},
+		{
+			name:     "set below a null",
+			source:   "metadata:\nname: test\n",
+			path:     "metadata.labels.app",
+			newValue: "web",
+			want:     "metadata:\n  labels:\n    app: web\nname: test\n",
+		},
+		{
+			name:     "mapping in a sequence",
+			source:   "containers:\n- name: app\n  image: app:v1\n- name: sidecar\n",
+			path:     "containers.0.env",
+			newValue: []map[string]string{{"name": "DEBUG", "value": "1"}},
+			want:     "containers:\n- name: app\n  image: app:v1\n  env:\n  - name: DEBUG\n    value: \"1\"\n- name: sidecar\n",
+		},
+		{
+			name:     "last key without a trailing newline",
+			source:   "name: test",
+			path:     "labels",
+			newValue: map[string]string{"app": "web"},
+			want:     "name: test\nlabels:\n  app: web",
+		},
+		{
+			name:     "CRLF line endings",
+			source:   "spec:\r\n  name: test\r\n",
+			path:     "spec.labels.app",
+			newValue: "web",
+			want:     "spec:\r\n  name: test\r\n  labels:\r\n    app: web\r\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetNode(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "values JSON can't represent are untouched",
+			source:   "id: 12345678901234567890\nmode: 0755\ndate: 2001-12-14\ncountry: no\ntime: 12:30:45\nimage: old\nlabels:\n  app: web\n",
+			path:     "labels.tier",
+			newValue: "backend",
+			want:     "id: 12345678901234567890\nmode: 0755\ndate: 2001-12-14\ncountry: no\ntime: 12:30:45\nimage: old\nlabels:\n  app: web\n  tier: backend\n",
+		},
+		{
+			name:     "strings are quoted for YAML 1.1 parsers",
+			source:   "country: gb\n",
+			path:     "country",
+			newValue: "no",
+			want:     "country: \"no\"\n",
+		},
+		{
+			name:     "flow mapping",
+			source:   "spec: {name: test, port: '80'} # inline\nother: 1\n",
+			path:     "spec.image",
+			newValue: "nginx",
+			want:     "spec: {name: test, port: '80', image: nginx} # inline\nother: 1\n",
+		},
+		{
+			name:     "append to a flow sequence",
+			source:   "args: [one, \"t,o\"]\n",
+			path:     "args.2",
+			newValue: "three",
+			want:     "args: [one, \"t,o\", three]\n",
+		},
+		{
+			name:     "replace a flow mapping",
+			source:   "spec: {name: test}\nother: 1\n",
+			path:     "spec",
+			newValue: map[string]string{"name": "new"},
+			want:     "spec:\n  name: new\nother: 1\n",
+		},
+		{
+			name:     "append to a sequence",
+			source:   "args:\n- one\n- name: two\n  value: 2\nname: test\n",
+			path:     "args.2",
+			newValue: "three",
+			want:     "args:\n- one\n- name: two\n  value: 2\n- three\nname: test\n",
+		},
+		{
+			name:     "replace a sequence item",
+			source:   "items:\n  - name: one\n    value: 1\n  - name: two\n",
+			path:     "items.0",
+			newValue: map[string]string{"name": "three"},
+			want:     "items:\n  - name: three\n  - name: two\n",
+		},
+		{
+			name:     "replace an item in a nested sequence",
+			source:   "- - a\n  - b\n- c\n",
+			path:     "0.1",
+			newValue: []string{"d"},
+			want:     "- - a\n  - - d\n- c\n",
+		},
+		{
+			name:     "numeric missing parent",
+			source:   "name: test\n",
+			path:     "items.1",
+			newValue: "a",
+			want:     "name: test\nitems:\n- null\n- a\n",
+		},
+		{
+			name:     "anchors are retained",
+			source:   "base: &base\n  a: 1\nspec:\n  <<: *base\n",
+			path:     "base",
+			newValue: map[string]int{"b": 2},
+			want:     "base: &base\n  b: 2\nspec:\n  <<: *base\n",
+		},
+		{
+			name:     "new key beside a merge key",
+			source:   "base: &base\n  a: 1\nspec:\n  <<: *base\n",
+			path:     "spec.b",
+			newValue: 2,
+			want:     "base: &base\n  a: 1\nspec:\n  <<: *base\n  b: 2\n",
+		},
+		{
+			name:     "quoted keys are retained",
+			source:   "\"on\": push\n",
+			path:     "on",
+			newValue: map[string]string{"push": "main"},
+			want:     "\"on\":\n  push: main\n",
+		},
+		{
+			name:     "special characters in keys",
+			source:   "annotations:\n  \"example.com/*\": old\n",
+			path:     "annotations.example\\.com/*",
+			newValue: "new",
+			want:     "annotations:\n  \"example.com/*\": new\n",
+		},
+		{
+			name:     "empty document",
+			source:   "",
+			path:     "metadata.name",
+			newValue: "test",
+			want:     "metadata:\n  name: test\n",
+		},
+		{
+			name:     "document with only comments",
+			source:   "# empty\n",
+			path:     "name",
+			newValue: "test",
+			want:     "# empty\nname: test\n",
+		},
+		{
+			name:     "null document",
+			source:   "null\n",
+			path:     "name",
+			newValue: "test",
+			want:     "name: test\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetNodeFailures(t *testing.T) {
+	failureTests := []struct {
+		name    string
+		source  string
+		path    string
+		wantErr string
+	}{
+		{"through an alias", "base: &base\n  a: 1\nspec: *base\n", "spec.b", "cannot set spec.b, spec is an alias"},
+		{"below a merged key", "base: &base\n  a:\n    b: 1\nspec:\n  <<: *base\n", "spec.a.c", "cannot set spec.a.c, spec.a is merged from an alias"},
+	}
+
+	for _, tt := range failureTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := SetBytes([]byte(tt.source), tt.path, 2)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Errorf("error got %s, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
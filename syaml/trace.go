@@ -0,0 +1,142 @@
+package syaml
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the values of redacted keys in traced documents.
+const Redacted = "REDACTED"
+
+// Tracer receives the updated document before it's validated, for debugging
+// updates, the values of keys configured with RedactKeys are replaced.
+type Tracer func(doc []byte)
+
+// WithTracer configures the updates to call the tracer with the updated
+// document.
+func WithTracer(t Tracer) Option {
+	return func(o *options) {
+		o.tracer = t
+	}
+}
+
+// RedactKeys configures the values of keys with paths matching any of the
+// regular expressions to be redacted in traced documents, the paths are
+// matched in the same form as SetBytes paths, e.g. "data.password".
+//
+// Redacting a key redacts the whole value, including nested keys.
+func RedactKeys(patterns ...string) Option {
+	return func(o *options) {
+		o.redactPatterns = append(o.redactPatterns, patterns...)
+	}
+}
+
+// trace calls the tracer with the redacted document.
+func (o *options) trace(doc []byte) error {
+	if o.tracer == nil {
+		return nil
+	}
+	if len(o.redactPatterns) == 0 {
+		o.tracer(doc)
+		return nil
+	}
+	res, err := compilePatterns(o.redactPatterns)
+	if err != nil {
+		return err
+	}
+	redacted, err := redact(doc, res)
+	if err != nil {
+		return fmt.Errorf("failed to redact the traced document: %w", err)
+	}
+	o.tracer(redacted)
+	return nil
+}
+
+// RedactBytes replaces the values of keys with paths matching any of the
+// regular expressions with Redacted, as RedactKeys does for traced documents,
+// the formatting of the rest of the document is preserved.
+func RedactBytes(y []byte, patterns ...string) ([]byte, error) {
+	res, err := compilePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseRoot(y)
+	if err != nil || root == nil {
+		return y, err
+	}
+	paths := []string{}
+	redactedKeys(root, res, func(_ *yaml3.Node, _ int, path string) {
+		paths = append(paths, path)
+	})
+	for _, p := range paths {
+		if y, err = SetBytes(y, p, Redacted); err != nil {
+			return nil, fmt.Errorf("failed to redact %s: %w", p, err)
+		}
+	}
+	return y, nil
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	res := []*regexp.Regexp{}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+func redact(doc []byte, res []*regexp.Regexp) ([]byte, error) {
+	root, err := parseRoot(doc)
+	if err != nil || root == nil {
+		return doc, err
+	}
+	redactedKeys(root, res, func(mapping *yaml3.Node, i int, _ string) {
+		mapping.Content[i] = &yaml3.Node{Kind: yaml3.ScalarNode, Tag: "!!str", Value: Redacted}
+	})
+	return yaml3.Marshal(root)
+}
+
+// redactedKeys calls f with the mapping, index of the value, and path of each
+// key matching the patterns, the values of matching keys aren't walked.
+func redactedKeys(root *yaml3.Node, res []*regexp.Regexp, f func(mapping *yaml3.Node, i int, path string)) {
+	var walk func(n *yaml3.Node, path []string)
+	walk = func(n *yaml3.Node, path []string) {
+		switch n.Kind {
+		case yaml3.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				keyPath := append(path[:len(path):len(path)], escapeSegment(n.Content[i].Value))
+				if matchesAny(res, strings.Join(keyPath, ".")) {
+					f(n, i+1, strings.Join(keyPath, "."))
+					continue
+				}
+				walk(n.Content[i+1], keyPath)
+			}
+		case yaml3.SequenceNode:
+			for i, item := range n.Content {
+				walk(item, append(path[:len(path):len(path)], fmt.Sprint(i)))
+			}
+		}
+	}
+	walk(root, nil)
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeSegment escapes the dots in a key, for matching in the form of
+// SetBytes paths.
+func escapeSegment(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), ".", `\.`)
+}
@@ -0,0 +1,105 @@
+package syaml
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestWithTracer(t *testing.T) {
+	source := "data:\n  username: admin\n  password: secret\n  example.com/token: abc123\nusers:\n- name: test\n  apiKey: def456\n"
+	traceTests := []struct {
+		name     string
+		patterns []string
+		want     string
+	}{
+		{
+			name: "no redaction",
+			want: "data:\n  username: admin\n  password: secret\n  example.com/token: abc123\nusers:\n- name: test\n  apiKey: def456\nreplicas: 2\n",
+		},
+		{
+			name:     "redacted keys",
+			patterns: []string{`password$`, `^data\.example\\\.com/token$`, `^users\.\d+\.apiKey$`},
+			want:     "data:\n    username: admin\n    password: REDACTED\n    example.com/token: REDACTED\nusers:\n    - name: test\n      apiKey: REDACTED\nreplicas: 2\n",
+		},
+		{
+			name:     "redacted subtree",
+			patterns: []string{`^data$`},
+			want:     "data: REDACTED\nusers:\n    - name: test\n      apiKey: def456\nreplicas: 2\n",
+		},
+	}
+
+	for _, tt := range traceTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			var traced []byte
+			updated, err := SetBytes([]byte(source), "replicas", 2, WithTracer(func(doc []byte) {
+				traced = doc
+			}), RedactKeys(tt.patterns...))
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(traced)); diff != "" {
+				rt.Errorf("incorrect trace:\n%s", diff)
+			}
+			if diff := cmp.Diff(source+"replicas: 2\n", string(updated)); diff != "" {
+				rt.Errorf("redaction changed the update:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithTracerInvalidPattern(t *testing.T) {
+	_, err := SetBytes([]byte("name: test\n"), "name", "new", WithTracer(func([]byte) {}), RedactKeys("["))
+
+	if !test.MatchError(t, `invalid redaction pattern "\["`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
+
+func TestRedactBytes(t *testing.T) {
+	source := "# Credentials\ndata:\n  username: admin\n  password: secret # rotated\n  example.com/token: abc123\nusers:\n- name: test\n  apiKey: def456\n"
+	redactTests := []struct {
+		name     string
+		patterns []string
+		want     string
+	}{
+		{
+			name: "no patterns",
+			want: source,
+		},
+		{
+			name:     "redacted keys",
+			patterns: []string{`password$`, `^data\.example\\\.com/token$`, `^users\.\d+\.apiKey$`},
+			want:     "# Credentials\ndata:\n  username: admin\n  password: REDACTED # rotated\n  example.com/token: REDACTED\nusers:\n- name: test\n  apiKey: REDACTED\n",
+		},
+		{
+			name:     "redacted subtree",
+			patterns: []string{`^data$`},
+			want:     "# Credentials\ndata: REDACTED\nusers:\n- name: test\n  apiKey: def456\n",
+		},
+	}
+
+	for _, tt := range redactTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			redacted, err := RedactBytes([]byte(source), tt.patterns...)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(redacted)); diff != "" {
+				rt.Errorf("incorrect redaction:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRedactBytesInvalidPattern(t *testing.T) {
+	_, err := RedactBytes([]byte("name: test\n"), "[")
+
+	if !test.MatchError(t, `invalid redaction pattern "\["`, err) {
+		t.Fatalf("got error %v", err)
+	}
+}
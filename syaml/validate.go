@@ -0,0 +1,71 @@
+package syaml
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"sigs.k8s.io/yaml"
+)
+
+// Validator is implemented by values that can validate an updated document.
+//
+// The document is provided in its generic JSON-compatible form, i.e. maps of
+// string keys, slices, and scalar values.
+type Validator interface {
+	Validate(doc interface{}) error
+}
+
+// ValidationError is returned when an updated document fails validation
+// against a schema.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("document failed validation: %s", strings.Join(e.Errors, "; "))
+}
+
+// SchemaValidator validates documents against a JSON Schema.
+type SchemaValidator struct {
+	schema *gojsonschema.Schema
+}
+
+// NewSchemaValidator parses a JSON Schema and returns a SchemaValidator.
+//
+// The schema can be provided as either JSON or YAML, OpenAPI v3 schemas can be
+// used as long as they are valid JSON Schemas e.g. those in CRD definitions.
+func NewSchemaValidator(schema []byte) (*SchemaValidator, error) {
+	j, err := yaml.YAMLToJSON(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %w", err)
+	}
+	s, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(j))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	return &SchemaValidator{schema: s}, nil
+}
+
+// ValidateBytes traces the document, and validates it with the validators
+// configured with WithValidator, as SetBytes does for the updated document.
+func ValidateBytes(y []byte, opts ...Option) error {
+	_, err := makeOptions(opts).validated(y)
+	return err
+}
+
+// Validate implements the Validator interface.
+func (s *SchemaValidator) Validate(doc interface{}) error {
+	result, err := s.schema.Validate(gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return err
+	}
+	if result.Valid() {
+		return nil
+	}
+	errs := []string{}
+	for _, e := range result.Errors() {
+		errs = append(errs, e.String())
+	}
+	return ValidationError{Errors: errs}
+}
@@ -0,0 +1,93 @@
+package syaml
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testSchema = `
+type: object
+properties:
+  spec:
+    type: object
+    properties:
+      replicas:
+        type: integer
+        minimum: 1
+    required:
+      - replicas
+`
+
+func TestSetBytesWithValidator(t *testing.T) {
+	v, err := NewSchemaValidator([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := SetBytes([]byte("spec:\n  replicas: 1\n"), "spec.replicas", 3, WithValidator(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(updated); s != "spec:\n  replicas: 3\n" {
+		t.Fatalf("got %#v, want %#v", s, "spec:\n  replicas: 3\n")
+	}
+}
+
+func TestSetBytesWithFailingValidator(t *testing.T) {
+	v, err := NewSchemaValidator([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = SetBytes([]byte("spec:\n  replicas: 1\n"), "spec.replicas", "three", WithValidator(v))
+
+	var ve ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("got %#v, want a ValidationError", err)
+	}
+	want := []string{"spec.replicas: Invalid type. Expected: integer, given: string"}
+	if diff := cmp.Diff(want, ve.Errors); diff != "" {
+		t.Fatalf("validation errors differ:\n%s", diff)
+	}
+}
+
+func TestValidateBytes(t *testing.T) {
+	v, err := NewSchemaValidator([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validateTests := []struct {
+		name    string
+		doc     string
+		wantErr bool
+	}{
+		{"valid", "spec:\n  replicas: 3\n", false},
+		{"invalid", "spec:\n  replicas: three\n", true},
+	}
+
+	for _, tt := range validateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			var traced []byte
+			err := ValidateBytes([]byte(tt.doc), WithValidator(v), WithTracer(func(doc []byte) { traced = doc }))
+
+			var ve ValidationError
+			if tt.wantErr != errors.As(err, &ve) {
+				rt.Fatalf("got error %v, want a ValidationError %v", err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.doc, string(traced)); diff != "" {
+				rt.Fatalf("traced document differs:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewSchemaValidatorWithInvalidSchema(t *testing.T) {
+	_, err := NewSchemaValidator([]byte(`type: 5`))
+
+	if err == nil {
+		t.Fatal("expected an error loading the schema")
+	}
+}
@@ -1,23 +1,312 @@
 package syaml
 
 import (
-	"github.com/tidwall/sjson"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	yaml3 "gopkg.in/yaml.v3"
 	"sigs.k8s.io/yaml"
 )
 
+// Option configures the behaviour of SetBytes.
+type Option func(*options)
+
+type options struct {
+	validators      []Validator
+	strict          bool
+	ensurePath      bool
+	expected        *string
+	expectedPattern string
+	tracer          Tracer
+	redactPatterns  []string
+}
+
+// ErrMissingParent is returned in strict mode when the parent of the key being
+// set doesn't exist.
+var ErrMissingParent = errors.New("parent does not exist")
+
+// ErrUnexpectedValue is returned when the current value of the key being set
+// doesn't match the value expected with ExpectValue or ExpectMatch.
+var ErrUnexpectedValue = errors.New("unexpected current value")
+
+// WithValidator configures SetBytes to validate the updated document before
+// returning it, if the validation fails, the error is returned.
+func WithValidator(v Validator) Option {
+	return func(o *options) {
+		o.validators = append(o.validators, v)
+	}
+}
+
+// Strict configures SetBytes to return an error if the parents of the path
+// don't exist, rather than creating them, this catches typos in paths.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// EnsurePath configures SetBytes to create missing parents of the path, this
+// overrides Strict when creating the parents is intended.
+func EnsurePath() Option {
+	return func(o *options) {
+		o.ensurePath = true
+	}
+}
+
+// ExpectValue configures SetBytes to only replace the value if the current
+// value is the string, numbers and booleans are compared in their YAML form.
+//
+// This prevents racing automations from overwriting each other's changes.
+func ExpectValue(v string) Option {
+	return func(o *options) {
+		o.expected = &v
+	}
+}
+
+// ExpectMatch configures SetBytes to only replace the value if the current
+// value matches the regular expression.
+func ExpectMatch(pattern string) Option {
+	return func(o *options) {
+		o.expectedPattern = pattern
+	}
+}
+
 // SetBytes accepts a YAML body, a path and a new value, and updates the
 // specific key in the YAML body using the path.
 //
 // e.g. SetBytes([]byte("name: testing\n"), "name", "new name") would would
 // return "name: newname\n"
-func SetBytes(y []byte, path string, value interface{}) ([]byte, error) {
-	j, err := yaml.YAMLToJSON(y)
+//
+// The document is edited directly, rather than converted to JSON, so nodes
+// that aren't changed are left byte-for-byte untouched, including large
+// integers, dates, and strings such as "0755" or "no" that JSON, or YAML 1.1,
+// would reinterpret.
+//
+// The value can be a scalar, or a map, slice or struct, which is converted via
+// JSON, or a RawYAML fragment, these are inserted as a subtree. The keys of
+// maps, which JSON sorts, follow the order of the keys in the mapping they
+// replace, or of the previous item when appending to a sequence.
+//
+// Where an existing scalar value is replaced with a scalar, the change is made
+// in place, preserving the quoting style of the replaced value. Other changes,
+// e.g. new keys, sequence items or replaced subtrees, are spliced into the
+// document, only the lines of the changed node are rendered, following the
+// indentation and line endings of the document.
+//
+// Paths that index beyond the end of a sequence, or traverse a scalar, return
+// an *IndexOutOfRangeError or *TypeMismatchError, an index equal to the length
+// of a sequence appends to it.
+func SetBytes(y []byte, path string, value interface{}, opts ...Option) ([]byte, error) {
+	updated, _, err := SetBytesWithOld(y, path, value, opts...)
+	return updated, err
+}
+
+// SetBytesWithOld sets the value as SetBytes does, and returns the value that
+// was replaced, in its YAML form as compared by ExpectValue, or nil if there
+// was no value.
+func SetBytesWithOld(y []byte, path string, value interface{}, opts ...Option) ([]byte, *string, error) {
+	o := makeOptions(opts)
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, err := parseRoot(y)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := o.checkCurrent(root, segments, path); err != nil {
+		return nil, nil, err
+	}
+	var old *string
+	current, ok, err := currentValue(root, segments)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		old = &current
+	}
+	updated, ok := []byte(nil), false
+	if root != nil {
+		updated, ok = setInPlace(y, root, segments, value)
+	}
+	if !ok {
+		if err := checkPath(root, segments, o.strict && !o.ensurePath); err != nil {
+			return nil, nil, err
+		}
+		if updated, err = setNode(y, root, segments, value); err != nil {
+			return nil, nil, err
+		}
+	}
+	if updated, err = o.validated(updated); err != nil {
+		return nil, nil, err
+	}
+	return updated, old, nil
+}
+
+// GetBytes returns the value at the path in the YAML body, in its YAML form
+// as compared by ExpectValue, or nil if there is no value.
+func GetBytes(y []byte, path string) (*string, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseRoot(y)
+	if err != nil {
+		return nil, err
+	}
+	current, ok, err := currentValue(root, segments)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &current, nil
+}
+
+// GetRawBytes returns the value at the path in the YAML body as a RawYAML
+// fragment, which restores the value and its type when it's set with
+// SetBytes, or nil if there is no key at the path.
+//
+// Aliases are resolved, as fragments can't contain them.
+func GetRawBytes(y []byte, path string) (*RawYAML, error) {
+	segments, err := splitPath(path)
 	if err != nil {
 		return nil, err
 	}
-	updated, err := sjson.SetBytes(j, path, value)
+	root, err := parseRoot(y)
+	if err != nil || root == nil {
+		return nil, err
+	}
+	node, _ := findNode(root, segments)
+	for node != nil && node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	if node == nil {
+		return nil, nil
+	}
+	b, err := yaml3.Marshal(resolveAliases(node))
 	if err != nil {
 		return nil, err
 	}
-	return yaml.JSONToYAML(updated)
+	raw := RawYAML(b)
+	return &raw, nil
+}
+
+// resolveAliases returns a copy of the node, with the aliases replaced by
+// copies of the nodes they refer to, and without anchors.
+func resolveAliases(node *yaml3.Node) *yaml3.Node {
+	for node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	c := *node
+	c.Anchor = ""
+	c.Content = nil
+	for _, n := range node.Content {
+		c.Content = append(c.Content, resolveAliases(n))
+	}
+	return &c
+}
+
+// parseRoot parses the YAML body, and returns the root node of the document,
+// or nil if the document is empty.
+func parseRoot(y []byte) (*yaml3.Node, error) {
+	var doc yaml3.Node
+	if err := yaml3.Unmarshal(y, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return doc.Content[0], nil
+}
+
+// validated traces the updated document, and returns it if it passes the
+// validators.
+func (o *options) validated(updated []byte) ([]byte, error) {
+	if err := o.trace(updated); err != nil {
+		return nil, err
+	}
+	if len(o.validators) == 0 {
+		return updated, nil
+	}
+	j, err := yaml.YAMLToJSON(updated)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.validate(j); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func makeOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+func (o *options) validate(j []byte) error {
+	if len(o.validators) == 0 {
+		return nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(j, &doc); err != nil {
+		return err
+	}
+	for _, v := range o.validators {
+		if err := v.Validate(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCurrent returns an error if the current value at the path doesn't
+// match the expected value, a missing or null value never matches.
+func (o *options) checkCurrent(root *yaml3.Node, segments []string, path string) error {
+	if o.expected == nil && o.expectedPattern == "" {
+		return nil
+	}
+	var re *regexp.Regexp
+	if o.expectedPattern != "" {
+		var err error
+		if re, err = regexp.Compile(o.expectedPattern); err != nil {
+			return fmt.Errorf("invalid expected pattern %q: %w", o.expectedPattern, err)
+		}
+	}
+	current, ok, err := currentValue(root, segments)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s has no value", ErrUnexpectedValue, path)
+	}
+	if o.expected != nil && current != *o.expected {
+		return fmt.Errorf("%w: %s is %q, expected %q", ErrUnexpectedValue, path, current, *o.expected)
+	}
+	if re != nil && !re.MatchString(current) {
+		return fmt.Errorf("%w: %s is %q, expected a match for %q", ErrUnexpectedValue, path, current, o.expectedPattern)
+	}
+	return nil
+}
+
+func currentValue(root *yaml3.Node, segments []string) (string, bool, error) {
+	if root == nil {
+		return "", false, nil
+	}
+	node, _ := findNode(root, segments)
+	for node != nil && node.Kind == yaml3.AliasNode {
+		node = node.Alias
+	}
+	if node == nil || isNull(node) {
+		return "", false, nil
+	}
+	if node.Kind != yaml3.ScalarNode {
+		b, err := yaml3.Marshal(node)
+		return string(bytes.TrimSuffix(b, []byte("\n"))), true, err
+	}
+	return node.Value, true, nil
 }
@@ -1,7 +1,13 @@
 package syaml
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
 )
 
 func TestSet(t *testing.T) {
@@ -69,3 +75,368 @@ func TestSetFailures(t *testing.T) {
 		}
 	}
 }
+
+func TestSetStrict(t *testing.T) {
+	setTests := []struct {
+		name    string
+		source  string
+		path    string
+		opts    []Option
+		want    string
+		wantErr string
+	}{
+		{"existing parent", "person:\n  name: John\n", "person.age", []Option{Strict()}, "person:\n  name: John\n  age: 30\n", ""},
+		{"missing parent", "person:\n  name: John\n", "persn.age", []Option{Strict()}, "", "parent does not exist: persn"},
+		{"missing nested parent", "person:\n  name: John\n", "person.address.city", []Option{Strict()}, "", "parent does not exist: person.address"},
+		{"missing parent with EnsurePath", "person:\n  name: John\n", "person.address.city", []Option{Strict(), EnsurePath()}, "person:\n  name: John\n  address:\n    city: 30\n", ""},
+		{"missing parent without Strict", "person:\n  name: John\n", "persn.age", nil, "person:\n  name: John\npersn:\n  age: 30\n", ""},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, 30, tt.opts...)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if string(updated) != tt.want {
+				rt.Errorf("got %#v, want %#v", string(updated), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetExpected(t *testing.T) {
+	setTests := []struct {
+		name    string
+		source  string
+		opts    []Option
+		want    string
+		wantErr string
+	}{
+		{"matching value", "image: app:v1\n", []Option{ExpectValue("app:v1")}, "image: app:v2\n", ""},
+		{"different value", "image: app:v3\n", []Option{ExpectValue("app:v1")}, "", `unexpected current value: image is "app:v3", expected "app:v1"`},
+		{"missing value", "name: test\n", []Option{ExpectValue("")}, "", "unexpected current value: image has no value"},
+		{"matching pattern", "image: app:v1.2\n", []Option{ExpectMatch(`^app:v1\.`)}, "image: app:v2\n", ""},
+		{"different pattern", "image: other:v1.2\n", []Option{ExpectMatch(`^app:`)}, "", `image is "other:v1.2", expected a match for "\^app:"`},
+		{"invalid pattern", "image: app:v1\n", []Option{ExpectMatch(`(`)}, "", `invalid expected pattern "\("`},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), "image", "app:v2", tt.opts...)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if string(updated) != tt.want {
+				rt.Errorf("got %#v, want %#v", string(updated), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetBytesWithOld(t *testing.T) {
+	oldTests := []struct {
+		name    string
+		source  string
+		path    string
+		want    string
+		wantOld *string
+	}{
+		{"scalar", "image: app:v1\n", "image", "image: app:v2\n", stringPtr("app:v1")},
+		{"quoted scalar", "image: 'app:v1'\n", "image", "image: 'app:v2'\n", stringPtr("app:v1")},
+		{"missing key", "name: test\n", "image", "name: test\nimage: app:v2\n", nil},
+		{"null value", "image: null\n", "image", "image: app:v2\n", nil},
+		{"empty document", "", "image", "image: app:v2\n", nil},
+		{"mapping", "image:\n  name: app\n  tag: v1\n", "image", "image: app:v2\n", stringPtr("name: app\ntag: v1")},
+	}
+
+	for _, tt := range oldTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, old, err := SetBytesWithOld([]byte(tt.source), tt.path, "app:v2")
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(updated)); diff != "" {
+				rt.Errorf("failed to update:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantOld, old); diff != "" {
+				rt.Errorf("incorrect old value:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSetBytesWithOldFailure(t *testing.T) {
+	_, old, err := SetBytesWithOld([]byte("image: app:v3\n"), "image", "app:v2", ExpectValue("app:v1"))
+
+	if !test.MatchError(t, "unexpected current value", err) {
+		t.Fatalf("got error %v", err)
+	}
+	if old != nil {
+		t.Fatalf("got old value %q, want nil", *old)
+	}
+}
+
+func TestGetBytes(t *testing.T) {
+	source := "image: app:v1\nreplicas: 2\nempty: null\nlabels:\n  app: test\n"
+	getTests := []struct {
+		path string
+		want *string
+	}{
+		{"image", stringPtr("app:v1")},
+		{"replicas", stringPtr("2")},
+		{"labels.app", stringPtr("test")},
+		{"labels", stringPtr("app: test")},
+		{"empty", nil},
+		{"missing", nil},
+		{"image.tag", nil},
+	}
+
+	for _, tt := range getTests {
+		t.Run(tt.path, func(rt *testing.T) {
+			got, err := GetBytes([]byte(source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				rt.Errorf("incorrect value:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetRawBytes(t *testing.T) {
+	source := "base: &base\n  app: test\nimage: app:v1\ntag: \"1.10\"\nempty: null\nlabels: *base\n"
+	getTests := []struct {
+		path string
+		want *RawYAML
+	}{
+		{"image", rawPtr("app:v1\n")},
+		{"tag", rawPtr("\"1.10\"\n")},
+		{"empty", rawPtr("null\n")},
+		{"labels", rawPtr("app: test\n")},
+		{"missing", nil},
+	}
+
+	for _, tt := range getTests {
+		t.Run(tt.path, func(rt *testing.T) {
+			got, err := GetRawBytes([]byte(source), tt.path)
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				rt.Errorf("incorrect value:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetRawBytesRestoresValue(t *testing.T) {
+	original := []byte("spec:\n  tag: \"1.10\"\n")
+	raw, err := GetRawBytes(original, "spec.tag")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := SetBytes(original, "spec.tag", "1.11")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := SetBytes(updated, "spec.tag", *raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(string(original), string(restored)); diff != "" {
+		t.Fatalf("value not restored:\n%s", diff)
+	}
+}
+
+func rawPtr(s string) *RawYAML {
+	r := RawYAML(s)
+	return &r
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestSetPreservesFormatting(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "four space indentation",
+			source:   "spec:\n    template:\n        image: old\n    replicas: 1\n",
+			path:     "spec.template.image",
+			newValue: "new",
+			want:     "spec:\n    template:\n        image: new\n    replicas: 1\n",
+		},
+		{
+			name:     "single quoted value",
+			source:   "image: 'quay.io/test/old:v1'\n",
+			path:     "image",
+			newValue: "quay.io/test/new:v2",
+			want:     "image: 'quay.io/test/new:v2'\n",
+		},
+		{
+			name:     "single quoted value with quotes",
+			source:   "name: 'old'\n",
+			path:     "name",
+			newValue: "it's new",
+			want:     "name: 'it''s new'\n",
+		},
+		{
+			name:     "double quoted value",
+			source:   "image: \"quay.io/test/old:v1\"\n",
+			path:     "image",
+			newValue: "quay.io/test/new:v2",
+			want:     "image: \"quay.io/test/new:v2\"\n",
+		},
+		{
+			name:     "quoted value replaced with a number",
+			source:   "replicas: '1'\n",
+			path:     "replicas",
+			newValue: 3,
+			want:     "replicas: 3\n",
+		},
+		{
+			name:     "plain value replaced with string that needs quoting",
+			source:   "version: v1\n",
+			path:     "version",
+			newValue: "1.2",
+			want:     "version: \"1.2\"\n",
+		},
+		{
+			name:     "comments are preserved",
+			source:   "# the service\nservice:\n  image: old # the image\n  port: 8080\n",
+			path:     "service.image",
+			newValue: "new",
+			want:     "# the service\nservice:\n  image: new # the image\n  port: 8080\n",
+		},
+		{
+			name:     "flow style sequences",
+			source:   "args: [one, two, three]\n",
+			path:     "args.1",
+			newValue: "four",
+			want:     "args: [one, four, three]\n",
+		},
+		{
+			name:     "escaped dots in keys",
+			source:   "annotations:\n  example.com/name: old\n",
+			path:     "annotations.example\\.com/name",
+			newValue: "new",
+			want:     "annotations:\n  example.com/name: new\n",
+		},
+		{
+			name:     "key order is preserved",
+			source:   "b: 1\na: 2\n",
+			path:     "b",
+			newValue: 3,
+			want:     "b: 3\na: 2\n",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if string(updated) != tt.want {
+				rt.Errorf("got %#v, want %#v", string(updated), tt.want)
+			}
+		})
+	}
+}
+
+func TestSetPreservesLineEndings(t *testing.T) {
+	setTests := []struct {
+		name     string
+		source   string
+		path     string
+		newValue interface{}
+		want     string
+	}{
+		{
+			name:     "CRLF line endings",
+			source:   "person:\r\n  name: John\r\n  age: 30\r\n",
+			path:     "person.name",
+			newValue: "Anderson",
+			want:     "person:\r\n  name: Anderson\r\n  age: 30\r\n",
+		},
+		{
+			name:     "CRLF line endings with a new key",
+			source:   "person:\r\n  name: John\r\n",
+			path:     "person.age",
+			newValue: 30,
+			want:     "person:\r\n  name: John\r\n  age: 30\r\n",
+		},
+		{
+			name:     "no trailing newline",
+			source:   "person:\n  name: John",
+			path:     "person.name",
+			newValue: "Anderson",
+			want:     "person:\n  name: Anderson",
+		},
+		{
+			name:     "no trailing newline with a new key",
+			source:   "person:\n  name: John",
+			path:     "person.age",
+			newValue: 30,
+			want:     "person:\n  name: John\n  age: 30",
+		},
+	}
+
+	for _, tt := range setTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			updated, err := SetBytes([]byte(tt.source), tt.path, tt.newValue)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if string(updated) != tt.want {
+				rt.Errorf("got %#v, want %#v", string(updated), tt.want)
+			}
+		})
+	}
+}
+
+// largeManifest generates a document of roughly 2MB.
+func largeManifest() []byte {
+	var b strings.Builder
+	b.WriteString("apiVersion: v1\nkind: List\nmetadata:\n  name: generated\nitems:\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "- name: service-%d\n  image: registry.example.com/service-%d:v1.0.0 # pinned\n  replicas: 3\n  env:\n  - name: INDEX\n    value: \"%d\"\n", i, i, i)
+	}
+	return []byte(b.String())
+}
+
+func BenchmarkSetBytes(b *testing.B) {
+	manifest := largeManifest()
+	benchmarks := []struct {
+		name     string
+		path     string
+		newValue interface{}
+	}{
+		{"in place", "items.10000.image", "registry.example.com/service-10000:v1.1.0"},
+		{"new key", "metadata.labels", map[string]string{"app": "generated"}},
+		{"replace a subtree", "items.10000.env", []map[string]string{{"name": "DEBUG", "value": "1"}}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := SetBytes(manifest, bm.path, bm.newValue); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package updater
+
+import (
+	"context"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// Handle tracks an update started with SubmitUpdate.
+type Handle struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+	pr     *scm.PullRequest
+	err    error
+}
+
+// SubmitUpdate starts UpdateYAML in the background and returns a Handle to
+// await the result.
+//
+// Updates run concurrently, so the GitClient must be safe for concurrent use.
+func (u *Updater) SubmitUpdate(ctx context.Context, input *Input) *Handle {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &Handle{done: make(chan struct{}), cancel: cancel}
+	go func() {
+		defer cancel()
+		defer close(h.done)
+		h.pr, h.err = u.UpdateYAML(ctx, input)
+	}()
+	return h
+}
+
+// Done returns a channel that's closed when the update completes.
+func (h *Handle) Done() <-chan struct{} {
+	return h.done
+}
+
+// Result waits for the update to complete and returns the PullRequest and
+// error from UpdateYAML.
+func (h *Handle) Result() (*scm.PullRequest, error) {
+	<-h.done
+	return h.pr, h.err
+}
+
+// Cancel cancels the context of the update, this doesn't wait for the update
+// to complete, changes already made to the repo are not reverted.
+func (h *Handle) Cancel() {
+	h.cancel()
+}
+
+// Wait waits for all the updates to complete, or for the context to be done,
+// returning the first error in the order of the handles.
+//
+// Pending updates are not cancelled when the context is done.
+func Wait(ctx context.Context, handles ...*Handle) error {
+	for _, h := range handles {
+		select {
+		case <-h.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, h := range handles {
+		if h.err != nil {
+			return h.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestSubmitUpdate(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	h := updater.SubmitUpdate(context.Background(), makeInput())
+
+	select {
+	case <-h.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the update")
+	}
+	pr, err := h.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr.Link != "https://example.com/pull-request/1" {
+		t.Fatalf("link to PR is incorrect: got %#v, want %#v", pr.Link, "https://example.com/pull-request/1")
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+}
+
+func TestSubmitUpdateWithError(t *testing.T) {
+	m := mock.New(t)
+	testErr := errors.New("missing file")
+	m.GetFileErr = testErr
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	h := updater.SubmitUpdate(context.Background(), makeInput())
+
+	if err := Wait(context.Background(), h); !errors.Is(err, testErr) {
+		t.Fatalf("got %v, want %v", err, testErr)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestHandleCancel(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), &blockingClient{MockClient: m}, NameGenerator(stubNameGenerator{"a"}))
+
+	h := updater.SubmitUpdate(context.Background(), makeInput())
+	h.Cancel()
+
+	if _, err := h.Result(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want %v", err, context.Canceled)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestWaitWithTimeout(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), &blockingClient{MockClient: m}, NameGenerator(stubNameGenerator{"a"}))
+	h := updater.SubmitUpdate(context.Background(), makeInput())
+	defer h.Cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := Wait(ctx, h); err != context.DeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+// blockingClient blocks fetching files until the context is done.
+type blockingClient struct {
+	*mock.MockClient
+}
+
+func (b *blockingClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/buildinfo"
+)
+
+// listConcurrency is the number of repos that ListAutomationPRs lists at
+// once.
+const listConcurrency = 4
+
+// AutomationSelector identifies the open PullRequests opened by updaters.
+//
+// A PullRequest is selected if it's from a branch with the BranchPrefix, or
+// with Trailer, if its body has the BuildInfoTrailer.
+type AutomationSelector struct {
+	BranchPrefix string // e.g. update-image-
+	Trailer      bool   // Select the PullRequests with a BuildInfoTrailer
+	Target       string // Only select PullRequests into this branch, e.g. main
+}
+
+// AutomationPR is an open PullRequest opened by an updater.
+type AutomationPR struct {
+	Repo        string
+	PullRequest *scm.PullRequest
+}
+
+// matches returns true if the PullRequest is selected.
+func (s AutomationSelector) matches(pr *scm.PullRequest) bool {
+	if s.Target != "" && pr.Target != s.Target {
+		return false
+	}
+	return s.BranchPrefix != "" && strings.HasPrefix(pr.Source, s.BranchPrefix) ||
+		s.Trailer && strings.Contains(pr.Body, "Updated-By: "+buildinfo.Name+"/")
+}
+
+// ListAutomationPRs returns the open PullRequests in the repos that are
+// selected, in the order of the repos, and then the PullRequest numbers.
+//
+// The repos are listed concurrently, if listing a repo fails, the
+// PullRequests from the other repos are returned with the error.
+func (u *Updater) ListAutomationPRs(ctx context.Context, repos []string, selector AutomationSelector) ([]AutomationPR, error) {
+	if selector.BranchPrefix == "" && !selector.Trailer {
+		return nil, errors.New("the selector needs a BranchPrefix or Trailer")
+	}
+	found := make([][]*scm.PullRequest, len(repos))
+	errs := make([]error, len(repos))
+	sem := make(chan struct{}, listConcurrency)
+	var wg sync.WaitGroup
+	for n, repo := range repos {
+		wg.Add(1)
+		go func(n int, repo string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			found[n], errs[n] = u.automationPRs(ctx, repo, selector)
+		}(n, repo)
+	}
+	wg.Wait()
+	prs := []AutomationPR{}
+	var err error
+	for n, repo := range repos {
+		if errs[n] != nil && err == nil {
+			err = errs[n]
+		}
+		for _, pr := range found[n] {
+			prs = append(prs, AutomationPR{Repo: repo, PullRequest: pr})
+		}
+	}
+	return prs, err
+}
+
+// automationPRs returns the open PullRequests in the repo that are selected.
+func (u *Updater) automationPRs(ctx context.Context, repo string, selector AutomationSelector) ([]*scm.PullRequest, error) {
+	prs, err := u.gitClient.ListPullRequests(ctx, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pull requests in repo %s: %w", repo, err)
+	}
+	selected := []*scm.PullRequest{}
+	for _, pr := range prs {
+		if selector.matches(pr) {
+			selected = append(selected, pr)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Number < selected[j].Number })
+	return selected, nil
+}
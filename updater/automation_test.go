@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestListAutomationPRs(t *testing.T) {
+	trailer := "Updating.\n\n" + buildInfoTrailer()
+	m := mock.New(t)
+	m.AddOpenPullRequest("my-org/repo-a", &scm.PullRequest{Number: 3, Source: "update-image-b", Target: "main"})
+	m.AddOpenPullRequest("my-org/repo-a", &scm.PullRequest{Number: 1, Source: "update-image-a", Target: "main"})
+	m.AddOpenPullRequest("my-org/repo-a", &scm.PullRequest{Number: 2, Source: "feature", Target: "main"})
+	m.AddOpenPullRequest("my-org/repo-b", &scm.PullRequest{Number: 4, Source: "other-branch", Target: "main", Body: trailer})
+	m.AddOpenPullRequest("my-org/repo-b", &scm.PullRequest{Number: 5, Source: "update-image-c", Target: "staging"})
+	updater := New(zap.New(), m)
+	repos := []string{"my-org/repo-b", "my-org/repo-a", "my-org/repo-c"}
+
+	selectorTests := []struct {
+		name     string
+		selector AutomationSelector
+		want     []string
+	}{
+		{"prefix", AutomationSelector{BranchPrefix: "update-image-"}, []string{"my-org/repo-b#5", "my-org/repo-a#1", "my-org/repo-a#3"}},
+		{"trailer", AutomationSelector{Trailer: true}, []string{"my-org/repo-b#4"}},
+		{"prefix or trailer", AutomationSelector{BranchPrefix: "update-image-", Trailer: true}, []string{"my-org/repo-b#4", "my-org/repo-b#5", "my-org/repo-a#1", "my-org/repo-a#3"}},
+		{"target", AutomationSelector{BranchPrefix: "update-image-", Target: "main"}, []string{"my-org/repo-a#1", "my-org/repo-a#3"}},
+		{"no match", AutomationSelector{BranchPrefix: "release-"}, []string{}},
+	}
+
+	for _, tt := range selectorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			prs, err := updater.ListAutomationPRs(context.Background(), repos, tt.selector)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, automationRefs(prs)); diff != "" {
+				rt.Fatalf("failed to list the PullRequests:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestListAutomationPRsWithNoSelector(t *testing.T) {
+	updater := New(zap.New(), mock.New(t))
+
+	_, err := updater.ListAutomationPRs(context.Background(), []string{"my-org/repo-a"}, AutomationSelector{Target: "main"})
+
+	if !test.MatchError(t, "the selector needs a BranchPrefix or Trailer", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestListAutomationPRsWithFailingRepo(t *testing.T) {
+	m := mock.New(t)
+	m.AddOpenPullRequest("my-org/repo-a", &scm.PullRequest{Number: 1, Source: "update-image-a", Target: "main"})
+	updater := New(zap.New(), &failingListClient{MockClient: m, repo: "my-org/repo-b"})
+
+	prs, err := updater.ListAutomationPRs(context.Background(), []string{"my-org/repo-a", "my-org/repo-b"}, AutomationSelector{BranchPrefix: "update-image-"})
+
+	if !test.MatchError(t, "failed to list the pull requests in repo my-org/repo-b: not available", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if diff := cmp.Diff([]string{"my-org/repo-a#1"}, automationRefs(prs)); diff != "" {
+		t.Fatalf("failed to list the PullRequests:\n%s", diff)
+	}
+}
+
+type failingListClient struct {
+	*mock.MockClient
+	repo string
+}
+
+func (c *failingListClient) ListPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error) {
+	if repo == c.repo {
+		return nil, errors.New("not available")
+	}
+	return c.MockClient.ListPullRequests(ctx, repo)
+}
+
+func automationRefs(prs []AutomationPR) []string {
+	refs := []string{}
+	for _, pr := range prs {
+		refs = append(refs, pr.Repo+"#"+fmt.Sprint(pr.PullRequest.Number))
+	}
+	return refs
+}
@@ -0,0 +1,529 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// Batch configures several updates that are applied together.
+type Batch struct {
+	Inputs []*Input
+	// GroupByRepo combines the changes for each repo and branch into a single
+	// branch and PullRequest, rather than one per Input.
+	GroupByRepo bool
+	// These are used for the grouped branches and PullRequests, if the
+	// BranchGenerateName is empty, the first Input's is used, if the
+	// PullRequest Title is empty, the Input titles are combined.
+	BranchGenerateName string
+	PullRequest        PullRequestInput
+	// These are used for the grouped branches when there's no
+	// BranchGenerateName, if they're empty, the first Input's are used.
+	NewBranchName string
+	ResetBranch   bool
+	// ReusePullRequest commits the grouped changes to the branch of an open
+	// PullRequest from the branches, if there is one.
+	ReusePullRequest bool
+	// Supersede configures what happens to the open PullRequests from the
+	// branches, when a grouped PullRequest is opened.
+	Supersede SupersedePolicy
+	// PostActions are applied to the grouped PullRequests, if there are none,
+	// the first Input's are used.
+	PostActions []PostAction
+	// ContinueOnError records the errors of failing updates as Failed
+	// results, and continues with the rest of the batch, rather than stopping
+	// at the first error, see NewReleaseReport.
+	ContinueOnError bool
+}
+
+// BatchResult records the outcome of the updates in a Batch for a repo and
+// branch, i.e. an Input, or a group of Inputs when grouping by repo.
+type BatchResult struct {
+	Repo   string
+	Branch string
+	Inputs []*Input
+	*UpdateResult
+	// Err is why the updates were Skipped, e.g. a ReadOnlyRepoError, or why
+	// they Failed.
+	Err error
+}
+
+// UpdateBatch applies all the updates in the batch, returning the
+// PullRequests that were created.
+//
+// When grouping by repo, each file is committed separately to the new branch,
+// and if no files in the group need changing, no branch is created.
+//
+// Updates to archived or disabled repos are skipped, use UpdateBatchResults
+// to report them.
+func (u *Updater) UpdateBatch(ctx context.Context, b *Batch) ([]*scm.PullRequest, error) {
+	results, err := u.UpdateBatchResults(ctx, b)
+	prs := []*scm.PullRequest{}
+	for _, r := range results {
+		if r.PullRequest != nil {
+			prs = append(prs, r.PullRequest)
+		}
+	}
+	return prs, err
+}
+
+// UpdateBatchResults applies all the updates in the batch as UpdateBatch does,
+// returning a result for each Input, or each group when grouping by repo.
+//
+// Updates to archived or disabled repos are Skipped, with the
+// ReadOnlyRepoError, rather than failing the batch, so that runs across many
+// repos report the repos that need to be removed from the configuration.
+func (u *Updater) UpdateBatchResults(ctx context.Context, b *Batch) ([]*BatchResult, error) {
+	groups := groupInputs(b.Inputs)
+	if !b.GroupByRepo {
+		groups = [][]*Input{}
+		for _, input := range b.Inputs {
+			groups = append(groups, []*Input{input})
+		}
+	}
+	results := []*BatchResult{}
+	for _, group := range groups {
+		var r *UpdateResult
+		var err error
+		if b.GroupByRepo {
+			r, err = u.lockedUpdateGroup(ctx, b, group)
+			for _, input := range group {
+				u.decide(input, r, err)
+			}
+		} else {
+			r, err = u.Update(ctx, group[0])
+		}
+		result := &BatchResult{Repo: group[0].Repo, Branch: group[0].Branch, Inputs: group, UpdateResult: r}
+		if errors.Is(err, ErrRepoReadOnly) {
+			u.log.Info("skipping updates to read-only repository", "repo", result.Repo, "reason", err.Error())
+			result.UpdateResult, result.Err = &UpdateResult{State: Skipped}, err
+			err = nil
+		}
+		if err != nil && b.ContinueOnError {
+			u.log.Error(err, "failed to update repository", "repo", result.Repo, "branch", result.Branch)
+			result.UpdateResult, result.Err = &UpdateResult{State: Failed}, err
+			err = nil
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (u *Updater) lockedUpdateGroup(ctx context.Context, b *Batch, group []*Input) (*UpdateResult, error) {
+	unlock, err := u.lockRepo(ctx, group[0].Repo)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	return u.updateGroup(ctx, b, group)
+}
+
+func (u *Updater) updateGroup(ctx context.Context, b *Batch, group []*Input) (*UpdateResult, error) {
+	first, original := group[0], group
+	commit := CommitInput{
+		Repo:               first.Repo,
+		Branch:             first.Branch,
+		BaseBranch:         first.BaseBranch,
+		Fork:               first.Fork,
+		BranchGenerateName: b.BranchGenerateName,
+	}
+	reuse, supersede := b.ReusePullRequest, b.Supersede
+	if commit.BranchGenerateName == "" && b.NewBranchName == "" {
+		commit.BranchGenerateName = first.BranchGenerateName
+		commit.NewBranchName, commit.ResetBranch = first.NewBranchName, first.ResetBranch
+		reuse, supersede = first.ReusePullRequest, first.Supersede
+	} else {
+		commit.NewBranchName, commit.ResetBranch = b.NewBranchName, b.ResetBranch
+	}
+	prefix, err := branchPrefix(commit.BranchGenerateName, first.branchValues(u.now()))
+	if err != nil {
+		return nil, err
+	}
+	commit.BranchGenerateName = prefix
+	if hashed, ok := u.hashedCommit(commit, group); ok {
+		commit, reuse = hashed, true
+	}
+	if err := u.checkPolicy(commit); err != nil {
+		return nil, err
+	}
+	if err := u.checkWritable(ctx, commit.Repo); err != nil {
+		return nil, err
+	}
+	if commit, err = u.withFork(ctx, commit); err != nil {
+		return nil, err
+	}
+	targets := changeTargets(group)
+	reused, err := u.reusablePullRequest(ctx, commit, reuse, targets)
+	if err != nil {
+		return nil, err
+	}
+	if reused != nil {
+		commit = CommitInput{Repo: commit.Repo, Branch: reused.Source}
+		onBranch := []*Input{}
+		for _, input := range group {
+			onBranch = append(onBranch, input.onBranch(reused.Source))
+		}
+		group = onBranch
+	}
+	changes, group, err := u.groupChanges(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		u.log.Info("no change required", "repo", first.Repo, "branch", first.Branch)
+		return &UpdateResult{State: Unchanged}, nil
+	}
+	title, body := groupMessages(b.PullRequest, group)
+	requests := groupRequests(b.PullRequest, group)
+	if requests.IncludeDiff {
+		diff, err := groupDiff(changes)
+		if err != nil {
+			return nil, err
+		}
+		body = appendParagraph(body, diff)
+	}
+	body = u.withProvenance(body, groupTriggers(group)...)
+	body = withTargets(body, targets, reuse || supersede != SupersedeNone)
+	r, err := u.commitChangesTo(ctx, commit, changes, PullRequestInput{Title: title, Body: body, Draft: requests.Draft})
+	if u.fallback(commit, err) {
+		fallback := *b
+		fallback.BranchGenerateName, fallback.NewBranchName, fallback.ResetBranch = u.fallbackPrefix, "", false
+		fallback.ReusePullRequest, fallback.Supersede = true, supersede
+		return u.updateGroup(ctx, &fallback, original)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if r.State == PullRequestCreated {
+		r.Superseded = u.supersede(ctx, commit, supersede, r.PullRequest, r.Branch, targets)
+		u.cleanup(ctx, commit)
+		actions := b.PostActions
+		if len(actions) == 0 {
+			actions = first.PostActions
+		}
+		r.PostActionErrors = u.applyPostActions(ctx, commit.Repo, r.PullRequest, withRequests(requests, commit.base(), changedFiles(changes), actions))
+	}
+	if reused == nil {
+		return r, nil
+	}
+	if r.PullRequest, err = u.updatePullRequest(ctx, commit.Repo, reused, title, body); err != nil {
+		return nil, err
+	}
+	r.State = PullRequestUpdated
+	return r, nil
+}
+
+// groupMessages returns the PullRequest title and body for the group, if
+// they're empty, the titles and bodies of the Inputs are combined.
+func groupMessages(pr PullRequestInput, group []*Input) (string, string) {
+	title, body := pr.Title, pr.Body
+	if title == "" {
+		titles := []string{}
+		for _, input := range group {
+			if input.PullRequest.Title != "" {
+				titles = append(titles, input.PullRequest.Title)
+			}
+		}
+		title = strings.Join(titles, ", ")
+	}
+	if body == "" {
+		bodies := []string{}
+		for _, input := range group {
+			if input.PullRequest.Body != "" {
+				bodies = append(bodies, input.PullRequest.Body)
+			}
+		}
+		body = strings.Join(bodies, "\n\n")
+	}
+	return title, body
+}
+
+// groupRequests returns a PullRequestInput with the Labels, Reviewers,
+// TeamReviewers, Assignees and Comment for the group, each that the
+// PullRequest doesn't have is combined from the Inputs, it's a Draft if any of
+// them are, and requests reviews from the CodeOwners, or includes the diffs, if
+// any of them do.
+//
+// Without an AutoMerge, Milestone or Project for the PullRequest, each is only
+// used if all the Inputs have the same value.
+func groupRequests(pr PullRequestInput, group []*Input) PullRequestInput {
+	combine := func(configured []string, values func(PullRequestInput) []string) []string {
+		if len(configured) > 0 {
+			return configured
+		}
+		seen := map[string]bool{}
+		var combined []string
+		for _, input := range group {
+			for _, v := range values(input.PullRequest) {
+				if !seen[v] {
+					seen[v] = true
+					combined = append(combined, v)
+				}
+			}
+		}
+		return combined
+	}
+	draft, codeOwners, includeDiff := pr.Draft, pr.CodeOwners, pr.IncludeDiff
+	for _, input := range group {
+		draft = draft || input.PullRequest.Draft
+		codeOwners = codeOwners || input.PullRequest.CodeOwners
+		includeDiff = includeDiff || input.PullRequest.IncludeDiff
+	}
+	same := func(configured string, value func(PullRequestInput) string) string {
+		if configured != "" || len(group) == 0 {
+			return configured
+		}
+		v := value(group[0].PullRequest)
+		for _, input := range group[1:] {
+			if value(input.PullRequest) != v {
+				return ""
+			}
+		}
+		return v
+	}
+	project := same(pr.Project, func(p PullRequestInput) string { return p.Project })
+	comment := pr.Comment
+	if comment == "" {
+		comments := combine(nil, func(p PullRequestInput) []string {
+			if p.Comment == "" {
+				return nil
+			}
+			return []string{p.Comment}
+		})
+		comment = strings.Join(comments, "\n\n")
+	}
+	// The column belongs to the project it's configured with.
+	column := pr.ProjectColumn
+	if pr.Project == "" {
+		column = same(pr.ProjectColumn, func(p PullRequestInput) string { return p.ProjectColumn })
+	}
+	if project == "" {
+		column = ""
+	}
+	return PullRequestInput{
+		Draft:         draft,
+		AutoMerge:     same(pr.AutoMerge, func(p PullRequestInput) string { return p.AutoMerge }),
+		Milestone:     same(pr.Milestone, func(p PullRequestInput) string { return p.Milestone }),
+		Project:       project,
+		ProjectColumn: column,
+		Comment:       comment,
+		CodeOwners:    codeOwners,
+		IncludeDiff:   includeDiff,
+		Labels:        combine(pr.Labels, func(p PullRequestInput) []string { return p.Labels }),
+		Reviewers:     combine(pr.Reviewers, func(p PullRequestInput) []string { return p.Reviewers }),
+		TeamReviewers: combine(pr.TeamReviewers, func(p PullRequestInput) []string { return p.TeamReviewers }),
+		Assignees:     combine(pr.Assignees, func(p PullRequestInput) []string { return p.Assignees }),
+	}
+}
+
+// commitChanges commits the changes to a new branch, and opens a PullRequest
+// with the Title, Body and Draft of the input, or if the commit has no
+// BranchGenerateName or NewBranchName, commits the changes directly to the
+// branch.
+//
+// If the direct commit is rejected as the branch is protected, and there is a
+// ProtectedBranchFallback, the changes are committed to a new branch.
+func (u *Updater) commitChanges(ctx context.Context, commit CommitInput, changes []*fileChange, input PullRequestInput) (*UpdateResult, error) {
+	r, err := u.commitChangesTo(ctx, commit, changes, input)
+	if fallback, ok := u.fallbackCommit(commit, err); ok {
+		return u.commitChangesTo(ctx, fallback, changes, input)
+	}
+	return r, err
+}
+
+func (u *Updater) commitChangesTo(ctx context.Context, commit CommitInput, changes []*fileChange, input PullRequestInput) (*UpdateResult, error) {
+	branchRef, err := u.gitClient.GetBranchHead(ctx, commit.Repo, commit.base())
+	if err != nil {
+		return nil, scmError(err, commit.Repo, "get branch head", nil)
+	}
+	if commit.newBranch() {
+		if err := u.checkDrafts(ctx, input.Draft); err != nil {
+			return nil, err
+		}
+	}
+	newBranchName, err := u.createBranchIfNecessary(ctx, commit, branchRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range changes {
+		sha, err := u.baseFileSHA(ctx, commit, c.filename, c.sha)
+		if err != nil {
+			return nil, err
+		}
+		err = u.gitClient.UpdateFile(ctx, commit.headRepo(), newBranchName, c.filename, u.sanitize(TextCommitMessage, c.message), sha, c.updated)
+		if err != nil {
+			return nil, scmError(err, commit.Repo, "update file", commitKinds(commit, err))
+		}
+		u.log.Info("updated file", "filename", c.filename)
+	}
+	sha := u.headCommit(ctx, commit.headRepo(), newBranchName)
+	if commit.Fork == "" {
+		u.reportStatus(ctx, commit.Repo, sha)
+	}
+	if !commit.newBranch() {
+		return &UpdateResult{State: Committed, Branch: newBranchName, Commit: sha, Base: branchRef}, nil
+	}
+	pr, state, err := u.openPullRequest(ctx, commit, PullRequestInput{
+		SourceBranch: commit.base(),
+		NewBranch:    newBranchName,
+		Repo:         commit.Repo,
+		Title:        input.Title,
+		Body:         input.Body,
+		Draft:        input.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if commit.Fork != "" {
+		u.reportStatus(ctx, commit.Repo, sha)
+	}
+	return &UpdateResult{State: state, Branch: newBranchName, Commit: sha, Base: branchRef, PullRequest: pr}, nil
+}
+
+// diff renders the change for a PullRequest body or comment, the changes to
+// encrypted files, and files with secret values, are not shown.
+func (c *fileChange) diff() (string, error) {
+	return c.secrets.diffFile(c.filename, c.original, c.updated, c.input.Encryption != nil).render(c.input.PullRequest)
+}
+
+// groupDiff renders the changes for a PullRequest body.
+func groupDiff(changes []*fileChange) (string, error) {
+	diffs := []string{}
+	for _, c := range changes {
+		diff, err := c.diff()
+		if err != nil {
+			return "", err
+		}
+		diffs = append(diffs, diff)
+	}
+	return strings.Join(diffs, "\n\n"), nil
+}
+
+// changedFiles returns the filenames of the changes.
+func changedFiles(changes []*fileChange) []string {
+	filenames := []string{}
+	for _, c := range changes {
+		filenames = append(filenames, c.filename)
+	}
+	return filenames
+}
+
+type fileChange struct {
+	filename  string
+	message   string
+	messages  []string   // The distinct commit messages of the Inputs that changed the file
+	triggers  []*Trigger // The Triggers of the Inputs that changed the file
+	sha       string
+	encrypted []byte
+	original  []byte
+	updated   []byte
+	input     *Input
+	secrets   *secrets // The secret keys of all the Inputs for the file
+}
+
+// groupChanges applies the updates in the group to the files in memory, where
+// several Inputs update the same file, they're applied in order, and the file
+// is committed once, the first Input for a file determines its Source.
+//
+// The commit message for a file combines the distinct messages of the Inputs
+// that changed it, in order.
+//
+// Inputs with a glob Filename are applied to each matching file, and the group
+// is returned with the messages of the Inputs rendered.
+func (u *Updater) groupChanges(ctx context.Context, group []*Input) ([]*fileChange, []*Input, error) {
+	group, err := u.expandGlobs(ctx, group)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := map[string]*fileChange{}
+	ordered := []*fileChange{}
+	rendered := []*Input{}
+	for _, input := range group {
+		secrets, err := u.secrets(input)
+		if err != nil {
+			return nil, nil, err
+		}
+		c, ok := files[input.Filename]
+		if !ok {
+			current, plaintext, base, err := u.readFiles(ctx, input)
+			if err != nil {
+				return nil, nil, err
+			}
+			c = &fileChange{filename: input.Filename, sha: current.Sha, encrypted: current.Data, original: plaintext, updated: base, input: input, secrets: secrets}
+			files[input.Filename] = c
+			ordered = append(ordered, c)
+		} else {
+			c.secrets = c.secrets.with(secrets)
+		}
+		updated, previous, err := input.apply(c.updated, secrets.options(u.traceOptions)...)
+		err = secrets.maskError(input.Key, err)
+		skipped := input.skipMismatch(err)
+		if skipped {
+			u.log.Info("skipping update, the current value is not expected", "filename", input.Filename, "key", input.Key, "reason", err.Error())
+		} else if err != nil {
+			return nil, nil, input.applyError(err)
+		}
+		values := secrets.maskValues(input.messageValues(previous))
+		if !skipped {
+			values.Diff = c.secrets.diffFile(input.Filename, c.updated, updated, input.Encryption != nil).unified
+		}
+		r, err := input.withMessages(values)
+		if err != nil {
+			return nil, nil, err
+		}
+		rendered = append(rendered, r)
+		if !skipped && !bytes.Equal(c.updated, updated) {
+			c.updated = updated
+			c.messages = appendDistinct(c.messages, r.CommitMessage)
+			c.triggers = append(c.triggers, input.Trigger)
+		}
+	}
+	changes := []*fileChange{}
+	for _, c := range ordered {
+		if bytes.Equal(c.original, c.updated) {
+			continue
+		}
+		c.message = u.withProvenance(strings.Join(c.messages, "\n\n"), c.triggers...)
+		// The Input that first loaded the file determines the encryption.
+		updated, err := c.input.encrypt(ctx, c.updated, c.encrypted)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.updated = updated
+		changes = append(changes, c)
+	}
+	return changes, rendered, nil
+}
+
+func appendDistinct(values []string, v string) []string {
+	for _, existing := range values {
+		if existing == v {
+			return values
+		}
+	}
+	return append(values, v)
+}
+
+// groupInputs groups the inputs by repo, branch and base branch, retaining the
+// order in which they first appear.
+func groupInputs(inputs []*Input) [][]*Input {
+	indexes := map[string]int{}
+	groups := [][]*Input{}
+	for _, input := range inputs {
+		k := input.Repo + ":" + input.Branch + ":" + input.base() + ":" + input.Fork
+		i, ok := indexes[k]
+		if !ok {
+			i = len(groups)
+			indexes[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], input)
+	}
+	return groups
+}
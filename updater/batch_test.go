@@ -0,0 +1,423 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+const (
+	testSecondFilePath = "environments/test/services/service-b/test.yaml"
+	testOtherRepo      = "testorg/otherrepo"
+)
+
+func TestUpdateBatchGroupedByRepo(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testOtherRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddBranchHead(testOtherRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second, third, fourth := makeInput(), makeInput(), makeInput(), makeInput()
+	second.Key, second.NewValue, second.PullRequest.Title = "test.tag", "v2", "Bump tag"
+	third.Filename = testSecondFilePath
+	fourth.Repo = testOtherRepo
+
+	prs, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:             []*Input{first, second, third, fourth},
+		GroupByRepo:        true,
+		BranchGenerateName: "batch-",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(prs); l != 2 {
+		t.Fatalf("got %d PullRequests, want 2", l)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "batch-a")); s != "test:\n  image: new-image\n  tag: v2\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testSecondFilePath, "batch-a")); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "batch-a", "just a test commit")
+	m.AssertBranchCreated(testGitHubRepo, "batch-a", testSHA)
+	m.AssertBranchCreated(testOtherRepo, "batch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR, Bump tag, This is a test PR",
+		Body:  "This is the body\n\nThis is the body\n\nThis is the body",
+		Head:  "batch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateBatchGroupedWithNoChanges(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	prs, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{makeInput(), makeInput()},
+		GroupByRepo: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(prs); l != 0 {
+		t.Fatalf("got %d PullRequests, want 0", l)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateBatchGroupedSkipsUnexpectedValues(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	expected := "v3"
+	second.Key, second.NewValue, second.ExpectedValue, second.SkipOnMismatch = "test.tag", "v4", &expected, true
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:             []*Input{first, second},
+		GroupByRepo:        true,
+		BranchGenerateName: "batch-",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "batch-a")); s != "test:\n  image: new-image\n  tag: v1\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestUpdateBatchGroupedCombinesCommitMessages(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n  name: app\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second, third := makeInput(), makeInput(), makeInput()
+	first.CommitMessage = "Update the image"
+	second.Key, second.NewValue, second.CommitMessage = "test.tag", "v2", "Update the tag"
+	third.Key, third.NewValue, third.CommitMessage = "test.name", "app", "Rename the app"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:             []*Input{first, second, third},
+		GroupByRepo:        true,
+		BranchGenerateName: "batch-",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "batch-a", "Update the image\n\nUpdate the tag")
+}
+
+func TestUpdateBatchWithoutGrouping(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	second.Filename = testSecondFilePath
+	second.BranchGenerateName = "other-branch-"
+
+	prs, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(prs); l != 2 {
+		t.Fatalf("got %d PullRequests, want 2", l)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertBranchCreated(testGitHubRepo, "other-branch-a", testSHA)
+}
+
+func TestUpdateBatchResultsSkipsReadOnlyRepos(t *testing.T) {
+	for _, grouped := range []bool{true, false} {
+		m := mock.New(t)
+		m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+		m.AddFileContents(testOtherRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+		m.AddBranchHead(testOtherRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+		m.AddRepoStatus(testGitHubRepo, &client.RepoStatus{Archived: true})
+		updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+		first, second := makeInput(), makeInput()
+		second.Repo = testOtherRepo
+
+		results, err := updater.UpdateBatchResults(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: grouped})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l := len(results); l != 2 {
+			t.Fatalf("got %d results, want 2", l)
+		}
+		if r := results[0]; r.Repo != testGitHubRepo || r.State != Skipped || !errors.Is(r.Err, ErrRepoReadOnly) {
+			t.Fatalf("got %#v, want the archived repo skipped", r)
+		}
+		if r := results[1]; r.Repo != testOtherRepo || r.State != PullRequestCreated || r.Err != nil {
+			t.Fatalf("got %#v, want a PullRequest for the other repo", r)
+		}
+		if updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"); updated != nil {
+			t.Fatalf("got updated file %q in the archived repo", updated)
+		}
+	}
+}
+
+func TestUpdateBatchGroupedCombinesLabels(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.PullRequest.Labels = []string{"automated", "image-update"}
+	second.Filename = testSecondFilePath
+	second.PullRequest.Labels = []string{"automated", "env/prod"}
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertLabelsAdded(testGitHubRepo, 1, "automated", "image-update", "env/prod")
+}
+
+func TestUpdateBatchGroupedDraft(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	production.Filename = testSecondFilePath
+	production.PullRequest.Draft = true
+
+	prs, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{staging, production}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(prs); l != 1 {
+		t.Fatalf("got %d PullRequests, want 1", l)
+	}
+	m.AssertDraftPullRequest(testGitHubRepo, 1)
+}
+
+func TestUpdateBatchGroupedDraftUnsupported(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.Caps.DraftPullRequests = false
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Draft = true
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{input}, GroupByRepo: true})
+
+	if !errors.Is(err, ErrDraftsUnsupported) {
+		t.Fatalf("got %v, want ErrDraftsUnsupported", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateBatchGroupedAutoMerge(t *testing.T) {
+	autoMergeTests := []struct {
+		name    string
+		batch   string
+		staging string
+		want    string
+	}{
+		{"same method", "", client.Squash, client.Squash},
+		{"different methods", "", client.MergeCommit, ""},
+		{"batch method", client.Rebase, "", client.Rebase},
+	}
+
+	for _, tt := range autoMergeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			staging, production := makeInput(), makeInput()
+			staging.PullRequest.AutoMerge = tt.staging
+			production.Filename = testSecondFilePath
+			production.PullRequest.AutoMerge = client.Squash
+
+			_, err := updater.UpdateBatch(context.Background(), &Batch{
+				Inputs:      []*Input{staging, production},
+				GroupByRepo: true,
+				PullRequest: PullRequestInput{AutoMerge: tt.batch},
+			})
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if tt.want == "" {
+				m.RefuteAutoMergeEnabled(testGitHubRepo, 1)
+				return
+			}
+			m.AssertAutoMergeEnabled(testGitHubRepo, 1, tt.want)
+		})
+	}
+}
+
+func TestUpdateBatchGroupedComments(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	staging.PullRequest.Comment = "Updated {{ .Filename }}"
+	production.Filename = testSecondFilePath
+	production.PullRequest.Comment = "Updated {{ .Filename }}"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommentCreated(testGitHubRepo, 1, "Updated "+testFilePath+"\n\nUpdated "+testSecondFilePath)
+}
+
+func TestUpdateBatchGroupedByBaseBranch(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddMissingFile(testGitHubRepo, testSecondFilePath, "release-1.2")
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, "release-1.2", "3b1f0a4d8c9e7f6a5b4c3d2e1f0a9b8c7d6e5f4a")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	main, release := makeInput(), makeInput()
+	release.Filename, release.BaseBranch = testSecondFilePath, "release-1.2"
+
+	prs, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{main, release}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(prs); l != 2 {
+		t.Fatalf("got %d PullRequests, want 2", l)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", "3b1f0a4d8c9e7f6a5b4c3d2e1f0a9b8c7d6e5f4a")
+}
+
+func TestUpdateBatchGroupedWithDiff(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	production.Filename = testSecondFilePath
+	production.PullRequest.IncludeDiff, production.PullRequest.DiffStyle = true, SemanticDiff
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+		PullRequest: PullRequestInput{Title: "Update the images", Body: "Updating the images."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Update the images",
+		Body: "Updating the images.\n\n" +
+			"```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,2 +1,2 @@\n test:\n-  image: old-image\n+  image: new-image\n```\n\n" +
+			"```\n~ test.image: \"old-image\" -> \"new-image\"\n```",
+		Head: "test-branch-a",
+		Base: testBranch,
+	})
+}
+
+func TestUpdateBatchGroupedProject(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	staging.PullRequest.Project, staging.PullRequest.ProjectColumn = "PVT_kwDOA", "In review"
+	production.Filename = testSecondFilePath
+	production.PullRequest.Project = "PVT_kwDOA"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+		PullRequest: PullRequestInput{Milestone: "v1.2.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertMilestoneSet(testGitHubRepo, 1, "v1.2.0")
+	m.AssertAddedToProject(testGitHubRepo, 1, "PVT_kwDOA", "")
+}
+
+func TestUpdateBatchDraftPerInput(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testOtherRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testOtherRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	production.Repo = testOtherRepo
+	production.PullRequest.Draft = true
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{staging, production}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.RefuteDraftPullRequest(testGitHubRepo, 1)
+	m.AssertDraftPullRequest(testOtherRepo, 1)
+}
+
+func TestUpdateBatchGroupedReviewers(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.PullRequest.Reviewers = []string{"octocat"}
+	first.PullRequest.Assignees = []string{"octocat"}
+	second.Filename = testSecondFilePath
+	second.PullRequest.Reviewers = []string{"hubot", "octocat"}
+	second.PullRequest.Assignees = []string{"hubot"}
+	b := &Batch{Inputs: []*Input{first, second}, GroupByRepo: true}
+	// The Batch's are used rather than combining the Inputs'.
+	b.PullRequest.TeamReviewers = []string{"my-org/platform"}
+	b.PullRequest.Assignees = []string{"release-bot"}
+
+	_, err := updater.UpdateBatch(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat", "hubot")
+	m.AssertTeamReviewersRequested(testGitHubRepo, 1, "my-org/platform")
+	m.AssertAssigneesAdded(testGitHubRepo, 1, "release-bot")
+}
@@ -0,0 +1,106 @@
+package updater
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// BranchValues are available as template values in the BranchGenerateName of
+// an Input, the rendered prefix is made safe for branch names.
+//
+//	BranchGenerateName: "update-{{ .Service }}-to-{{ .Tag }}-"
+type BranchValues struct {
+	Key      string
+	NewValue interface{}
+	Service  string // The name of the image in the Trigger, or NewValue, e.g. service-a for quay.io/my-org/service-a:v1.2.0
+	Tag      string // The tag of the image, or the NewValue if it's not an image, e.g. v1.2.0
+	Date     string // The date of the update, e.g. 20240131
+	File     string // The base name of the Filename, without the extension, e.g. deployment
+}
+
+func (i *Input) branchValues(now time.Time) BranchValues {
+	v := BranchValues{Key: i.Key, NewValue: i.NewValue, Date: now.UTC().Format("20060102")}
+	if i.Filename != "" {
+		base := path.Base(i.Filename)
+		v.File = strings.TrimSuffix(base, path.Ext(base))
+	}
+	image := fmt.Sprint(i.NewValue)
+	if i.NewValue == nil {
+		image = ""
+	}
+	if i.Trigger != nil && i.Trigger.Image != "" {
+		image = i.Trigger.Image
+		if i.Trigger.Tag != "" {
+			image = image + ":" + i.Trigger.Tag
+		}
+	}
+	v.Service, v.Tag = splitImage(image)
+	return v
+}
+
+// splitImage returns the name and tag of an image reference, without the
+// registry or digest, a value that isn't an image reference is the tag.
+func splitImage(image string) (string, string) {
+	if n := strings.Index(image, "@"); n >= 0 {
+		image = image[:n]
+	}
+	if !strings.ContainsAny(image, ":/") {
+		return "", image
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	if n := strings.LastIndex(name, ":"); n >= 0 {
+		return name[:n], name[n+1:]
+	}
+	return name, ""
+}
+
+// branchPrefix renders the BranchGenerateName template with the values, and
+// replaces the characters that aren't allowed in branch names, prefixes
+// without actions are returned unchanged.
+func branchPrefix(prefix string, v BranchValues) (string, error) {
+	if !strings.Contains(prefix, "{{") {
+		return prefix, nil
+	}
+	tmpl, err := parseTemplate("BranchGenerateName", prefix)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, v); err != nil {
+		return "", fmt.Errorf("failed to render the BranchGenerateName: %w", err)
+	}
+	return sanitizeBranchName(b.String()), nil
+}
+
+// sanitizeBranchName replaces the characters that aren't allowed in branch
+// names, and repeated separators, with a "-".
+func sanitizeBranchName(s string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == '/':
+			return r
+		}
+		return '-'
+	}, s)
+	for _, repeated := range []string{"..", "//", "--"} {
+		for strings.Contains(safe, repeated) {
+			safe = strings.Replace(safe, repeated, repeated[:1], -1)
+		}
+	}
+	safe = strings.Replace(safe, "/.", "/", -1)
+	return strings.TrimLeft(safe, "./-")
+}
+
+// withBranchPrefix returns a copy of the Input with the BranchGenerateName
+// rendered.
+func (i *Input) withBranchPrefix(now time.Time) (*Input, error) {
+	prefix, err := branchPrefix(i.BranchGenerateName, i.branchValues(now))
+	if err != nil {
+		return nil, err
+	}
+	rendered := *i
+	rendered.BranchGenerateName = prefix
+	return &rendered, nil
+}
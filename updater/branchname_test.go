@@ -0,0 +1,120 @@
+package updater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestBranchValues(t *testing.T) {
+	now := time.Date(2024, time.January, 31, 23, 0, 0, 0, time.FixedZone("test", -3600))
+	valueTests := []struct {
+		name  string
+		input func(*Input)
+		want  BranchValues
+	}{
+		{"image", func(i *Input) { i.NewValue = "quay.io/my-org/service-a:v1.2.0" },
+			BranchValues{NewValue: "quay.io/my-org/service-a:v1.2.0", Service: "service-a", Tag: "v1.2.0"}},
+		{"image with a digest", func(i *Input) { i.NewValue = "my-org/service-a:v1.2.0@sha256:abc" },
+			BranchValues{NewValue: "my-org/service-a:v1.2.0@sha256:abc", Service: "service-a", Tag: "v1.2.0"}},
+		{"registry with a port", func(i *Input) { i.NewValue = "localhost:5000/service-a" },
+			BranchValues{NewValue: "localhost:5000/service-a", Service: "service-a"}},
+		{"version", func(i *Input) { i.NewValue = "v1.2.0" }, BranchValues{NewValue: "v1.2.0", Tag: "v1.2.0"}},
+		{"nil value", func(i *Input) { i.NewValue = nil }, BranchValues{}},
+		{"trigger", func(i *Input) { i.Trigger = &Trigger{Image: "quay.io/my-org/service-b", Tag: "v2"} },
+			BranchValues{NewValue: "new-image", Service: "service-b", Tag: "v2"}},
+	}
+
+	for _, tt := range valueTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			input := makeInput()
+			tt.input(input)
+
+			v := input.branchValues(now)
+
+			want := tt.want
+			want.Key, want.Date, want.File = "test.image", "20240201", "test"
+			if diff := cmp.Diff(want, v); diff != "" {
+				rt.Fatalf("failed to get the values:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBranchPrefix(t *testing.T) {
+	values := BranchValues{Service: "service-a", Tag: "v1.2.0", Date: "20240131", File: "deployment", NewValue: "my image"}
+	prefixTests := []struct {
+		prefix  string
+		want    string
+		wantErr string
+	}{
+		{"update-image-", "update-image-", ""},
+		{"update-{{ .Service }}-to-{{ .Tag }}-", "update-service-a-to-v1.2.0-", ""},
+		{"{{ .Date }}/{{ .File }}-", "20240131/deployment-", ""},
+		{"update-{{ .NewValue }}-", "update-my-image-", ""},
+		{"update-{{ .Service }}-{{ .Key }}-", "update-service-a-", ""},
+		{"-{{ .Tag }}..{{ .Tag }}//", "v1.2.0.v1.2.0/", ""},
+		{"update-{{ .Unknown }}-", "", "failed to render the BranchGenerateName"},
+		{"update-{{ .Service -", "", "failed to parse the BranchGenerateName"},
+	}
+
+	for _, tt := range prefixTests {
+		t.Run(tt.prefix, func(rt *testing.T) {
+			p, err := branchPrefix(tt.prefix, values)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			if p != tt.want {
+				rt.Errorf("got %q, want %q", p, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWithTemplatedBranchName(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	updater.now = func() time.Time { return time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC) }
+	input := makeInput()
+	input.NewValue = "quay.io/my-org/service-a:v1.2.0"
+	input.BranchGenerateName = "update-{{ .Service }}-to-{{ .Tag }}-{{ .Date }}-"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "update-service-a-to-v1.2.0-20240131-a"; r.Branch != want {
+		t.Fatalf("got branch %q, want %q", r.Branch, want)
+	}
+}
+
+func TestUpdateFilesWithTemplatedBranchName(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "other.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.NewValue = "quay.io/my-org/service-a:v1.2.0"
+	input.BranchGenerateName = "update-{{ .File }}-to-{{ .Tag }}-"
+	input.Files = []FileChange{{Filename: "other.yaml", Key: "test.image", NewValue: "new-image"}}
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "update-test-to-v1.2.0-a"; r.Branch != want {
+		t.Fatalf("got branch %q, want %q", r.Branch, want)
+	}
+}
@@ -0,0 +1,435 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/buildinfo"
+	"github.com/agill17/pkg/client"
+)
+
+const redacted = "<redacted>"
+
+// SupportBundle records the context of a failed update, for inclusion in bug
+// reports.
+type SupportBundle struct {
+	Time     time.Time
+	Duration string
+	Error    string
+	Input    Input
+	Calls    []ProviderCall
+	Versions map[string]string
+	Build    buildinfo.Info
+}
+
+// ProviderCall records a call to the git provider, and the outcome.
+type ProviderCall struct {
+	Method   string
+	Args     []string
+	Duration string
+	Error    string `json:",omitempty"`
+}
+
+// SupportBundles is an option func for the Updater creation function, when
+// configured, failed updates write a JSON encoded SupportBundle to the writer.
+//
+// The NewValues in the Input, and its Values and Files, are redacted, as they
+// may be sensitive, as are the ExpectedValue and ExpectedPattern of a secret
+// Key, and the values of secret keys in the CommitMessage and PullRequest.
+func SupportBundles(w io.Writer) UpdaterFunc {
+	return func(u *Updater) {
+		u.bundleWriter = w
+	}
+}
+
+func (u *Updater) writeSupportBundle(input *Input, calls []ProviderCall, start time.Time, updateErr error) {
+	// Invalid secret key patterns make every key secret.
+	secrets, secretsErr := u.secrets(input)
+	secret := func(key string) bool {
+		return secretsErr != nil || secrets.matches(key)
+	}
+	redactedInput := *input
+	redactedInput.NewValue = redacted
+	if secret(input.Key) {
+		if input.ExpectedValue != nil {
+			v := redacted
+			redactedInput.ExpectedValue = &v
+		}
+		if input.ExpectedPattern != "" {
+			redactedInput.ExpectedPattern = redacted
+		}
+	}
+	redactedInput.Validator = nil
+	redactedInput.Encryption = nil
+	redactedInput.Values = nil
+	for _, v := range input.Values {
+		redactedInput.Values = append(redactedInput.Values, KeyValue{Key: v.Key, NewValue: redacted})
+	}
+	redactedInput.Files = nil
+	for _, f := range input.Files {
+		f.NewValue = redacted
+		redactedInput.Files = append(redactedInput.Files, f)
+	}
+	values := secretValues(input, secret)
+	redactedInput.CommitMessage = redactText(input.CommitMessage, values)
+	redactedInput.PullRequest.Title = redactText(input.PullRequest.Title, values)
+	redactedInput.PullRequest.Body = redactText(input.PullRequest.Body, values)
+	redactedInput.PullRequest.Comment = redactText(input.PullRequest.Comment, values)
+	b := SupportBundle{
+		Time:     start.UTC(),
+		Duration: time.Since(start).String(),
+		Error:    updateErr.Error(),
+		Input:    redactedInput,
+		Calls:    calls,
+		Versions: versions(),
+		Build:    buildinfo.Get(),
+	}
+	enc := json.NewEncoder(u.bundleWriter)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		u.log.Info("failed to write support bundle", "err", err)
+	}
+}
+
+// secretValues returns the values the Input sets or expects for secret keys,
+// longest first, so that values containing others are redacted whole.
+func secretValues(input *Input, secret func(string) bool) []string {
+	values := []string{}
+	add := func(key string, v interface{}) {
+		if v == nil || !secret(key) {
+			return
+		}
+		if s := fmt.Sprint(v); s != "" {
+			values = append(values, s)
+		}
+	}
+	add(input.Key, input.NewValue)
+	if input.ExpectedValue != nil {
+		add(input.Key, *input.ExpectedValue)
+	}
+	for _, v := range input.Values {
+		add(v.Key, v.NewValue)
+	}
+	for _, f := range input.Files {
+		add(f.Key, f.NewValue)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	return values
+}
+
+// redactText returns the text with the values redacted.
+func redactText(text string, values []string) string {
+	for _, v := range values {
+		text = strings.ReplaceAll(text, v, redacted)
+	}
+	return text
+}
+
+func versions() map[string]string {
+	v := map[string]string{"go": runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return v
+	}
+	for _, dep := range info.Deps {
+		switch dep.Path {
+		case "github.com/agill17/pkg", "github.com/jenkins-x/go-scm":
+			v[dep.Path] = dep.Version
+		}
+	}
+	return v
+}
+
+// recordingClient wraps a GitClient and records the calls made.
+type recordingClient struct {
+	client.GitClient
+	calls []ProviderCall
+}
+
+func (r *recordingClient) record(method string, start time.Time, err error, args ...interface{}) {
+	c := ProviderCall{Method: method, Duration: time.Since(start).String()}
+	for _, a := range args {
+		c.Args = append(c.Args, fmt.Sprint(a))
+	}
+	if err != nil {
+		c.Error = err.Error()
+	}
+	r.calls = append(r.calls, c)
+}
+
+func (r *recordingClient) GetFile(ctx context.Context, repo, ref, path string) (*scm.Content, error) {
+	start := time.Now()
+	c, err := r.GitClient.GetFile(ctx, repo, ref, path)
+	r.record("GetFile", start, err, repo, ref, path)
+	return c, err
+}
+
+func (r *recordingClient) ListFiles(ctx context.Context, repo, ref, dir string) ([]string, error) {
+	start := time.Now()
+	files, err := r.GitClient.ListFiles(ctx, repo, ref, dir)
+	r.record("ListFiles", start, err, repo, ref, dir)
+	return files, err
+}
+
+func (r *recordingClient) UpdateFile(ctx context.Context, repo, branch, path, message, previousSHA string, content []byte) error {
+	start := time.Now()
+	err := r.GitClient.UpdateFile(ctx, repo, branch, path, message, previousSHA, content)
+	r.record("UpdateFile", start, err, repo, branch, path, previousSHA)
+	return err
+}
+
+func (r *recordingClient) CreatePullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	start := time.Now()
+	pr, err := r.GitClient.CreatePullRequest(ctx, repo, inp)
+	r.record("CreatePullRequest", start, err, repo, inp.Head, inp.Base)
+	return pr, err
+}
+
+func (r *recordingClient) CreateDraftPullRequest(ctx context.Context, repo string, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	start := time.Now()
+	pr, err := r.GitClient.CreateDraftPullRequest(ctx, repo, inp)
+	r.record("CreateDraftPullRequest", start, err, repo, inp.Head, inp.Base)
+	return pr, err
+}
+
+func (r *recordingClient) CreateBranch(ctx context.Context, repo, branch, sha string) error {
+	start := time.Now()
+	err := r.GitClient.CreateBranch(ctx, repo, branch, sha)
+	r.record("CreateBranch", start, err, repo, branch, sha)
+	return err
+}
+
+func (r *recordingClient) ResetBranch(ctx context.Context, repo, branch, sha string) error {
+	start := time.Now()
+	err := r.GitClient.ResetBranch(ctx, repo, branch, sha)
+	r.record("ResetBranch", start, err, repo, branch, sha)
+	return err
+}
+
+func (r *recordingClient) FindPullRequest(ctx context.Context, repo, head string) (*scm.PullRequest, error) {
+	start := time.Now()
+	pr, err := r.GitClient.FindPullRequest(ctx, repo, head)
+	r.record("FindPullRequest", start, err, repo, head)
+	return pr, err
+}
+
+func (r *recordingClient) ListPullRequests(ctx context.Context, repo string) ([]*scm.PullRequest, error) {
+	start := time.Now()
+	prs, err := r.GitClient.ListPullRequests(ctx, repo)
+	r.record("ListPullRequests", start, err, repo)
+	return prs, err
+}
+
+func (r *recordingClient) UpdatePullRequest(ctx context.Context, repo string, number int, inp *scm.PullRequestInput) (*scm.PullRequest, error) {
+	start := time.Now()
+	pr, err := r.GitClient.UpdatePullRequest(ctx, repo, number, inp)
+	r.record("UpdatePullRequest", start, err, repo, number)
+	return pr, err
+}
+
+func (r *recordingClient) ClosePullRequest(ctx context.Context, repo string, number int) error {
+	start := time.Now()
+	err := r.GitClient.ClosePullRequest(ctx, repo, number)
+	r.record("ClosePullRequest", start, err, repo, number)
+	return err
+}
+
+func (r *recordingClient) DeleteBranch(ctx context.Context, repo, branch string) error {
+	start := time.Now()
+	err := r.GitClient.DeleteBranch(ctx, repo, branch)
+	r.record("DeleteBranch", start, err, repo, branch)
+	return err
+}
+
+func (r *recordingClient) GetBranchHead(ctx context.Context, repo, branch string) (string, error) {
+	start := time.Now()
+	sha, err := r.GitClient.GetBranchHead(ctx, repo, branch)
+	r.record("GetBranchHead", start, err, repo, branch)
+	return sha, err
+}
+
+func (r *recordingClient) ResolveRef(ctx context.Context, repo, ref string) (string, error) {
+	start := time.Now()
+	sha, err := r.GitClient.ResolveRef(ctx, repo, ref)
+	r.record("ResolveRef", start, err, repo, ref)
+	return sha, err
+}
+
+func (r *recordingClient) GetCommit(ctx context.Context, repo, ref string) (*scm.Commit, error) {
+	start := time.Now()
+	commit, err := r.GitClient.GetCommit(ctx, repo, ref)
+	r.record("GetCommit", start, err, repo, ref)
+	return commit, err
+}
+
+func (r *recordingClient) CompareCommits(ctx context.Context, repo, base, head string) ([]*scm.Commit, error) {
+	start := time.Now()
+	commits, err := r.GitClient.CompareCommits(ctx, repo, base, head)
+	r.record("CompareCommits", start, err, repo, base, head)
+	return commits, err
+}
+
+func (r *recordingClient) CreateComment(ctx context.Context, repo string, number int, body string) error {
+	start := time.Now()
+	err := r.GitClient.CreateComment(ctx, repo, number, body)
+	r.record("CreateComment", start, err, repo, number)
+	return err
+}
+
+func (r *recordingClient) GetRepoPermissions(ctx context.Context, repo string) (*scm.Perm, error) {
+	start := time.Now()
+	perm, err := r.GitClient.GetRepoPermissions(ctx, repo)
+	r.record("GetRepoPermissions", start, err, repo)
+	return perm, err
+}
+
+func (r *recordingClient) GetRepoStatus(ctx context.Context, repo string) (*client.RepoStatus, error) {
+	start := time.Now()
+	status, err := r.GitClient.GetRepoStatus(ctx, repo)
+	r.record("GetRepoStatus", start, err, repo)
+	return status, err
+}
+
+func (r *recordingClient) ListClosedPullRequests(ctx context.Context, repo string, limit int) ([]*scm.PullRequest, error) {
+	start := time.Now()
+	prs, err := r.GitClient.ListClosedPullRequests(ctx, repo, limit)
+	r.record("ListClosedPullRequests", start, err, repo, limit)
+	return prs, err
+}
+
+func (r *recordingClient) CreateFork(ctx context.Context, repo, organization string) (string, error) {
+	start := time.Now()
+	fork, err := r.GitClient.CreateFork(ctx, repo, organization)
+	r.record("CreateFork", start, err, repo, organization)
+	return fork, err
+}
+
+func (r *recordingClient) Capabilities(ctx context.Context) (client.Capabilities, error) {
+	start := time.Now()
+	caps, err := r.GitClient.Capabilities(ctx)
+	r.record("Capabilities", start, err)
+	return caps, err
+}
+
+func (r *recordingClient) GetVariable(ctx context.Context, repo, name string) (string, error) {
+	start := time.Now()
+	value, err := r.GitClient.GetVariable(ctx, repo, name)
+	r.record("GetVariable", start, err, repo, name)
+	return value, err
+}
+
+func (r *recordingClient) SetVariable(ctx context.Context, repo, name, value string) error {
+	start := time.Now()
+	err := r.GitClient.SetVariable(ctx, repo, name, value)
+	r.record("SetVariable", start, err, repo, name)
+	return err
+}
+
+func (r *recordingClient) CreateTag(ctx context.Context, repo, name, sha, message string) error {
+	start := time.Now()
+	err := r.GitClient.CreateTag(ctx, repo, name, sha, message)
+	r.record("CreateTag", start, err, repo, name, sha)
+	return err
+}
+
+func (r *recordingClient) CreateRelease(ctx context.Context, repo string, input *client.ReleaseInput) error {
+	start := time.Now()
+	err := r.GitClient.CreateRelease(ctx, repo, input)
+	r.record("CreateRelease", start, err, repo, input.Tag)
+	return err
+}
+
+func (r *recordingClient) GetLatestRelease(ctx context.Context, repo string) (string, error) {
+	start := time.Now()
+	tag, err := r.GitClient.GetLatestRelease(ctx, repo)
+	r.record("GetLatestRelease", start, err, repo)
+	return tag, err
+}
+
+func (r *recordingClient) AddLabels(ctx context.Context, repo string, number int, labels []string) error {
+	start := time.Now()
+	err := r.GitClient.AddLabels(ctx, repo, number, labels)
+	r.record("AddLabels", start, err, repo, number, labels)
+	return err
+}
+
+func (r *recordingClient) RequestReviewers(ctx context.Context, repo string, number int, logins []string) error {
+	start := time.Now()
+	err := r.GitClient.RequestReviewers(ctx, repo, number, logins)
+	r.record("RequestReviewers", start, err, repo, number, logins)
+	return err
+}
+
+func (r *recordingClient) RequestTeamReviewers(ctx context.Context, repo string, number int, teams []string) error {
+	start := time.Now()
+	err := r.GitClient.RequestTeamReviewers(ctx, repo, number, teams)
+	r.record("RequestTeamReviewers", start, err, repo, number, teams)
+	return err
+}
+
+func (r *recordingClient) AddAssignees(ctx context.Context, repo string, number int, logins []string) error {
+	start := time.Now()
+	err := r.GitClient.AddAssignees(ctx, repo, number, logins)
+	r.record("AddAssignees", start, err, repo, number, logins)
+	return err
+}
+
+func (r *recordingClient) EnableAutoMerge(ctx context.Context, repo string, number int, method string) error {
+	start := time.Now()
+	err := r.GitClient.EnableAutoMerge(ctx, repo, number, method)
+	r.record("EnableAutoMerge", start, err, repo, number, method)
+	return err
+}
+
+func (r *recordingClient) SetMilestone(ctx context.Context, repo string, number int, title string) error {
+	start := time.Now()
+	err := r.GitClient.SetMilestone(ctx, repo, number, title)
+	r.record("SetMilestone", start, err, repo, number, title)
+	return err
+}
+
+func (r *recordingClient) AddToProject(ctx context.Context, repo string, number int, project, column string) error {
+	start := time.Now()
+	err := r.GitClient.AddToProject(ctx, repo, number, project, column)
+	r.record("AddToProject", start, err, repo, number, project, column)
+	return err
+}
+
+func (r *recordingClient) MergePullRequest(ctx context.Context, repo string, number int, method string) error {
+	start := time.Now()
+	err := r.GitClient.MergePullRequest(ctx, repo, number, method)
+	r.record("MergePullRequest", start, err, repo, number, method)
+	return err
+}
+
+func (r *recordingClient) GetPullRequest(ctx context.Context, repo string, number int) (*scm.PullRequest, error) {
+	start := time.Now()
+	pr, err := r.GitClient.GetPullRequest(ctx, repo, number)
+	r.record("GetPullRequest", start, err, repo, number)
+	return pr, err
+}
+
+func (r *recordingClient) CreateStatus(ctx context.Context, repo, sha string, status *scm.StatusInput) error {
+	start := time.Now()
+	err := r.GitClient.CreateStatus(ctx, repo, sha, status)
+	r.record("CreateStatus", start, err, repo, sha, status.Label)
+	return err
+}
+
+func (r *recordingClient) GetCombinedStatus(ctx context.Context, repo, ref string) (*scm.CombinedStatus, error) {
+	start := time.Now()
+	status, err := r.GitClient.GetCombinedStatus(ctx, repo, ref)
+	r.record("GetCombinedStatus", start, err, repo, ref)
+	return status, err
+}
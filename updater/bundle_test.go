@@ -0,0 +1,188 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestUpdateYAMLWritesSupportBundleOnFailure(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.CreateBranchErr = errors.New("can't create branch")
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SupportBundles(&buf))
+
+	_, err := updater.UpdateYAML(context.Background(), makeInput())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	bundle := SupportBundle{}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Error != "failed to create branch: can't create branch" {
+		t.Fatalf("got error %#v", bundle.Error)
+	}
+	if bundle.Input.NewValue != redacted {
+		t.Fatalf("NewValue was not redacted, got %#v", bundle.Input.NewValue)
+	}
+	if bundle.Build.Version == "" {
+		t.Fatalf("build info was not recorded, got %#v", bundle.Build)
+	}
+	if bundle.Versions["go"] == "" {
+		t.Fatal("go version not recorded")
+	}
+	methods := []string{}
+	for _, c := range bundle.Calls {
+		methods = append(methods, c.Method)
+	}
+	if diff := cmp.Diff([]string{"GetRepoStatus", "GetFile", "GetBranchHead", "CreateBranch"}, methods); diff != "" {
+		t.Fatalf("recorded calls differ:\n%s", diff)
+	}
+	if bundle.Calls[3].Error != "can't create branch" {
+		t.Fatalf("got call error %#v", bundle.Calls[3].Error)
+	}
+}
+
+func TestUpdateYAMLWithSupportBundleAndNoFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SupportBundles(&buf))
+
+	_, err := updater.UpdateYAML(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if buf.Len() != 0 {
+		t.Fatalf("support bundle written: %s", buf.String())
+	}
+}
+
+func TestSupportBundleRedactsValuesAndFiles(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.CreateBranchErr = errors.New("can't create branch")
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SupportBundles(&buf))
+	input := makeInput()
+	input.Values = []KeyValue{{Key: "test.password", NewValue: "secret"}}
+	input.Files = []FileChange{{Filename: "other.yaml", Key: "token", NewValue: "secret"}}
+
+	if _, err := updater.Update(context.Background(), input); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	bundle := SupportBundle{}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]KeyValue{{Key: "test.password", NewValue: redacted}}, bundle.Input.Values); diff != "" {
+		t.Fatalf("Values were not redacted:\n%s", diff)
+	}
+	if diff := cmp.Diff([]FileChange{{Filename: "other.yaml", Key: "token", NewValue: redacted}}, bundle.Input.Files, cmpopts.IgnoreFields(FileChange{}, "ContentUpdater")); diff != "" {
+		t.Fatalf("Files were not redacted:\n%s", diff)
+	}
+	if input.Values[0].NewValue != "secret" || input.Files[0].NewValue != "secret" {
+		t.Fatal("the Input was modified")
+	}
+}
+
+func TestSupportBundleRedactsExpectedValuesOfSecretKeys(t *testing.T) {
+	bundleTests := []struct {
+		name        string
+		secretKeys  []string
+		wantValue   string
+		wantPattern string
+	}{
+		{"secret key", []string{`^test\.image$`}, redacted, redacted},
+		{"other secret key", []string{`^test\.password$`}, "old-image", "^old-"},
+		{"invalid secret key pattern", []string{`[`}, redacted, redacted},
+	}
+
+	for _, tt := range bundleTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.CreateBranchErr = errors.New("can't create branch")
+			var buf bytes.Buffer
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SupportBundles(&buf))
+			input := makeInput()
+			expected := "old-image"
+			input.ExpectedValue = &expected
+			input.ExpectedPattern = "^old-"
+			input.SecretKeys = tt.secretKeys
+
+			if _, err := updater.Update(context.Background(), input); err == nil {
+				rt.Fatal("expected an error")
+			}
+
+			bundle := SupportBundle{}
+			if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+				rt.Fatal(err)
+			}
+			if bundle.Input.ExpectedValue == nil || *bundle.Input.ExpectedValue != tt.wantValue {
+				rt.Fatalf("got ExpectedValue %v, want %q", bundle.Input.ExpectedValue, tt.wantValue)
+			}
+			if bundle.Input.ExpectedPattern != tt.wantPattern {
+				rt.Fatalf("got ExpectedPattern %q, want %q", bundle.Input.ExpectedPattern, tt.wantPattern)
+			}
+			if *input.ExpectedValue != "old-image" {
+				rt.Fatal("the Input was modified")
+			}
+		})
+	}
+}
+
+func TestSupportBundleRedactsSecretValuesInMessages(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  password: old-password\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.CreateBranchErr = errors.New("can't create branch")
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SupportBundles(&buf))
+	input := makeInput()
+	input.Values = []KeyValue{{Key: "test.password", NewValue: "new-password"}, {Key: "test.replicas", NewValue: 3}}
+	input.SecretKeys = []string{`password$`}
+	input.CommitMessage = "Set the password to new-password"
+	input.PullRequest.Title = "Rotate new-password"
+	input.PullRequest.Body = "The password is new-password, with 3 replicas."
+	input.PullRequest.Comment = "Rotated new-password"
+
+	if _, err := updater.Update(context.Background(), input); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	bundle := SupportBundle{}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Set the password to " + redacted,
+		"Rotate " + redacted,
+		"The password is " + redacted + ", with 3 replicas.",
+		"Rotated " + redacted,
+	}
+	got := []string{bundle.Input.CommitMessage, bundle.Input.PullRequest.Title, bundle.Input.PullRequest.Body, bundle.Input.PullRequest.Comment}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("messages were not redacted:\n%s", diff)
+	}
+	if input.CommitMessage != "Set the password to new-password" {
+		t.Fatal("the Input was modified")
+	}
+}
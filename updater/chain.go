@@ -0,0 +1,64 @@
+package updater
+
+import (
+	"fmt"
+)
+
+// Middleware wraps a ContentUpdater, e.g. to check or transform the content
+// before or after it's applied.
+type Middleware func(ContentUpdater) ContentUpdater
+
+// Chain is a ContentUpdater that applies the updaters in order, each is passed
+// the content returned by the previous one, so that several transformations
+// can be made in a single commit.
+//
+// Chain(UpdateYAML("image", "nginx:1.19"), UpdateYAML("metadata.annotations.updated", "true"))
+//
+// If an updater fails, the updaters after it are not applied.
+func Chain(updaters ...ContentUpdater) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		for i, f := range updaters {
+			updated, err := f(b)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply update %d of %d: %w", i+1, len(updaters), err)
+			}
+			b = updated
+		}
+		return b, nil
+	}
+}
+
+// Pipeline returns the ContentUpdater wrapped in the middleware, the first
+// middleware is the outermost, it's the first to be passed the content, and
+// the last to see the result.
+func Pipeline(f ContentUpdater, middleware ...Middleware) ContentUpdater {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		f = middleware[i](f)
+	}
+	return f
+}
+
+// Check is Middleware that calls the check with the original and updated
+// content after the wrapped ContentUpdater is applied, the update fails if
+// the check returns an error.
+//
+//	Check(func(original, updated []byte) error {
+//		if bytes.Count(updated, []byte("\n")) < bytes.Count(original, []byte("\n")) {
+//			return errors.New("lines were removed")
+//		}
+//		return nil
+//	})
+func Check(check func(original, updated []byte) error) Middleware {
+	return func(f ContentUpdater) ContentUpdater {
+		return func(b []byte) ([]byte, error) {
+			updated, err := f(b)
+			if err != nil {
+				return nil, err
+			}
+			if err := check(b, updated); err != nil {
+				return nil, fmt.Errorf("failed to check the update: %w", err)
+			}
+			return updated, nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestChain(t *testing.T) {
+	f := Chain(
+		UpdateYAML("test.image", "new-image"),
+		UpdateYAML("metadata.annotations.updated", "true"),
+		RegexReplace(`new-image`, "newer-image", 0),
+	)
+
+	got, err := f([]byte("test:\n  image: old-image\nmetadata:\n  annotations: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test:\n  image: newer-image\nmetadata:\n  annotations: {updated: \"true\"}\n"
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("chained update failed:\n%s", diff)
+	}
+}
+
+func TestChainWithFailingUpdate(t *testing.T) {
+	applied := false
+	f := Chain(
+		UpdateYAML("test.image", "new-image"),
+		func([]byte) ([]byte, error) { return nil, errors.New("broken") },
+		func(b []byte) ([]byte, error) { applied = true; return b, nil },
+	)
+
+	_, err := f([]byte("test:\n  image: old-image\n"))
+
+	if !test.MatchError(t, "failed to apply update 2 of 3: broken", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if applied {
+		t.Fatal("the update after the failure was applied")
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	calls := []string{}
+	record := func(name string) Middleware {
+		return func(f ContentUpdater) ContentUpdater {
+			return func(b []byte) ([]byte, error) {
+				calls = append(calls, "before "+name)
+				updated, err := f(b)
+				calls = append(calls, "after "+name)
+				return updated, err
+			}
+		}
+	}
+	f := Pipeline(func(b []byte) ([]byte, error) {
+		calls = append(calls, "update")
+		return b, nil
+	}, record("first"), record("second"))
+
+	if _, err := f([]byte("test")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"before first", "before second", "update", "after second", "after first"}
+	if diff := cmp.Diff(want, calls); diff != "" {
+		t.Fatalf("middleware order failed:\n%s", diff)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	noRemovals := Check(func(original, updated []byte) error {
+		if len(updated) < len(original) {
+			return errors.New("content was removed")
+		}
+		return nil
+	})
+	checkTests := []struct {
+		name    string
+		f       ContentUpdater
+		wantErr string
+	}{
+		{"passing check", UpdateYAML("test.image", "newer-image"), ""},
+		{"failing check", DeleteYAML("test.image"), "failed to check the update: content was removed"},
+		{"failing update", func([]byte) ([]byte, error) { return nil, errors.New("broken") }, "^broken$"},
+	}
+
+	for _, tt := range checkTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := Pipeline(tt.f, noRemovals)([]byte("test:\n  image: old-image\n"))
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestApplyUpdateWithChain(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  replicas: 1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.ApplyUpdate(context.Background(), makeInput(), Chain(
+		UpdateYAML("test.image", "new-image"),
+		UpdateYAML("test.replicas", 2),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test:\n  image: new-image\n  replicas: 2\n"
+	if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"))); diff != "" {
+		t.Fatalf("updated file failed:\n%s", diff)
+	}
+}
@@ -0,0 +1,102 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+)
+
+// cleanupLimit is the number of the most recent closed PullRequests that are
+// checked after an update with CleanupBranches.
+const cleanupLimit = 100
+
+// CleanupBranches is an option func for the Updater creation function, when an
+// update opens a PullRequest, the branches with its BranchGenerateName prefix
+// are deleted from the most recent merged or closed PullRequests in the repo,
+// see DeleteClosedBranches.
+func CleanupBranches() UpdaterFunc {
+	return func(u *Updater) {
+		u.cleanupBranches = true
+	}
+}
+
+// DeletedBranch is a branch deleted by DeleteClosedBranches.
+type DeletedBranch struct {
+	Repo        string // The fork, for PullRequests from forks
+	Branch      string
+	PullRequest *scm.PullRequest // The merged or closed PullRequest from the branch
+}
+
+// DeleteClosedBranches deletes the branches with the prefix, e.g.
+// update-image-, of the merged and closed PullRequests in the repo, and
+// returns the deleted branches.
+//
+// Branches with an open PullRequest, or with no PullRequest, are kept, the
+// branches of PullRequests from forks are deleted from the fork, and branches
+// that were already deleted are ignored.
+func (u *Updater) DeleteClosedBranches(ctx context.Context, repo, prefix string) ([]DeletedBranch, error) {
+	return u.deleteClosedBranches(ctx, repo, prefix, 0)
+}
+
+func (u *Updater) deleteClosedBranches(ctx context.Context, repo, prefix string, limit int) ([]DeletedBranch, error) {
+	if prefix == "" {
+		return nil, errors.New("a branch prefix is required to delete branches")
+	}
+	closed, err := u.gitClient.ListClosedPullRequests(ctx, repo, limit)
+	if err != nil {
+		return nil, scmError(err, repo, "list the closed pull requests in repo "+repo, nil)
+	}
+	open, err := u.gitClient.ListPullRequests(ctx, repo)
+	if err != nil {
+		return nil, scmError(err, repo, "list the pull requests in repo "+repo, nil)
+	}
+	kept := map[string]bool{}
+	for _, pr := range open {
+		kept[branchRepo(repo, pr)+":"+pr.Source] = true
+	}
+	deleted := []DeletedBranch{}
+	for _, pr := range closed {
+		r := branchRepo(repo, pr)
+		if !strings.HasPrefix(pr.Source, prefix) || kept[r+":"+pr.Source] {
+			continue
+		}
+		kept[r+":"+pr.Source] = true
+		err := u.gitClient.DeleteBranch(ctx, r, pr.Source)
+		// GitHub rejects deleting a branch that doesn't exist as invalid.
+		if client.IsNotFound(err) || client.StatusCode(err) == http.StatusUnprocessableEntity {
+			continue
+		}
+		if err != nil {
+			return deleted, scmError(err, r, "delete branch "+pr.Source, nil)
+		}
+		u.log.Info("deleted branch", "repo", r, "branch", pr.Source, "number", pr.Number, "merged", pr.Merged)
+		deleted = append(deleted, DeletedBranch{Repo: r, Branch: pr.Source, PullRequest: pr})
+	}
+	return deleted, nil
+}
+
+// branchRepo returns the repo of the PullRequest's source branch, the fork for
+// PullRequests from forks.
+func branchRepo(repo string, pr *scm.PullRequest) string {
+	if pr.Fork != "" {
+		return pr.Fork
+	}
+	return repo
+}
+
+// cleanup deletes the branches of the closed PullRequests from the commit's
+// BranchGenerateName, if configured, the update has been made, so failures
+// are logged rather than returned.
+func (u *Updater) cleanup(ctx context.Context, commit CommitInput) {
+	if !u.cleanupBranches || commit.BranchGenerateName == "" {
+		return
+	}
+	if _, err := u.deleteClosedBranches(ctx, commit.Repo, commit.BranchGenerateName, cleanupLimit); err != nil {
+		u.log.Error(err, "failed to delete the branches of closed PullRequests", "repo", commit.Repo)
+	}
+}
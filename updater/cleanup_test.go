@@ -0,0 +1,124 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestDeleteClosedBranches(t *testing.T) {
+	m := mock.New(t)
+	merged := &scm.PullRequest{Number: 1, Source: "update-image-a", Merged: true, Closed: true}
+	closed := &scm.PullRequest{Number: 2, Source: "update-image-b", Closed: true}
+	fork := &scm.PullRequest{Number: 3, Source: "update-image-c", Fork: "my-bots/testrepo", Closed: true}
+	m.AddClosedPullRequest(testGitHubRepo, merged)
+	m.AddClosedPullRequest(testGitHubRepo, closed)
+	m.AddClosedPullRequest(testGitHubRepo, fork)
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 4, Source: "update-image-d", Closed: true})
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 5, Source: "feature", Merged: true, Closed: true})
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 6, Source: "update-image-a", Closed: true})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: "update-image-d"})
+	updater := New(zap.New(), m)
+
+	deleted, err := updater.DeleteClosedBranches(context.Background(), testGitHubRepo, "update-image-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []DeletedBranch{
+		{Repo: testGitHubRepo, Branch: "update-image-a", PullRequest: merged},
+		{Repo: testGitHubRepo, Branch: "update-image-b", PullRequest: closed},
+		{Repo: "my-bots/testrepo", Branch: "update-image-c", PullRequest: fork},
+	}
+	if diff := cmp.Diff(want, deleted); diff != "" {
+		t.Fatalf("deleted branches differ:\n%s", diff)
+	}
+	m.AssertBranchDeleted("my-bots/testrepo", "update-image-c")
+	m.RefuteBranchDeleted(testGitHubRepo, "update-image-d")
+	m.RefuteBranchDeleted(testGitHubRepo, "feature")
+}
+
+func TestDeleteClosedBranchesIgnoresDeletedBranches(t *testing.T) {
+	m := mock.New(t)
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 1, Source: "update-image-a", Merged: true, Closed: true})
+	m.DeleteBranchErr = client.NewStatusError("failed to delete branch", http.StatusUnprocessableEntity)
+	updater := New(zap.New(), m)
+
+	deleted, err := updater.DeleteClosedBranches(context.Background(), testGitHubRepo, "update-image-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(deleted) != 0 {
+		t.Fatalf("got %d deleted branches, want 0", len(deleted))
+	}
+}
+
+func TestDeleteClosedBranchesErrors(t *testing.T) {
+	cleanupTests := []struct {
+		name    string
+		prefix  string
+		err     error
+		wantErr string
+	}{
+		{"no prefix", "", nil, "a branch prefix is required to delete branches"},
+		{"delete failure", "update-image-", errors.New("permission denied"), "permission denied"},
+	}
+
+	for _, tt := range cleanupTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 1, Source: "update-image-a", Merged: true, Closed: true})
+			m.DeleteBranchErr = tt.err
+			updater := New(zap.New(), m)
+
+			_, err := updater.DeleteClosedBranches(context.Background(), testGitHubRepo, tt.prefix)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateWithCleanupBranches(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 1, Source: "test-branch-z", Merged: true, Closed: true})
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 2, Source: "other-branch", Merged: true, Closed: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CleanupBranches())
+
+	_, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertBranchDeleted(testGitHubRepo, "test-branch-z")
+	m.RefuteBranchDeleted(testGitHubRepo, "other-branch")
+	m.RefuteBranchDeleted(testGitHubRepo, "test-branch-a")
+}
+
+func TestUpdateWithoutCleanupBranches(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddClosedPullRequest(testGitHubRepo, &scm.PullRequest{Number: 1, Source: "test-branch-z", Merged: true, Closed: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.RefuteBranchDeleted(testGitHubRepo, "test-branch-z")
+}
@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/agill17/pkg/client"
+)
+
+// codeOwnersFiles are the locations of the CODEOWNERS file, in the order that
+// GitHub and GitLab look for them.
+var codeOwnersFiles = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+// RequestCodeOwners is a PostAction that requests reviews of the PullRequest
+// from the owners of the paths, in the CODEOWNERS file of the branch, e.g. main.
+//
+// Owners that are teams, e.g. @my-org/frontend, are requested as team
+// reviewers, and owners identified by email are ignored, nothing is requested
+// if the repo has no CODEOWNERS file.
+func RequestCodeOwners(branch string, paths ...string) PostAction {
+	return PostActionFunc("request-code-owners", func(ctx context.Context, t PostActionTarget) error {
+		rules, err := readCodeOwners(ctx, t.Client, t.Repo, branch)
+		if err != nil || len(rules) == 0 {
+			return err
+		}
+		var logins, teams []string
+		for _, owner := range codeOwners(rules, paths) {
+			switch {
+			case !strings.HasPrefix(owner, "@"):
+			case strings.Contains(owner, "/"):
+				teams = append(teams, strings.TrimPrefix(owner, "@"))
+			default:
+				logins = append(logins, strings.TrimPrefix(owner, "@"))
+			}
+		}
+		if len(logins) > 0 {
+			if err := t.Client.RequestReviewers(ctx, t.Repo, t.PullRequest.Number, logins); err != nil {
+				return err
+			}
+		}
+		if len(teams) > 0 {
+			return t.Client.RequestTeamReviewers(ctx, t.Repo, t.PullRequest.Number, teams)
+		}
+		return nil
+	})
+}
+
+// readCodeOwners returns the rules of the first CODEOWNERS file found in the
+// branch, or none if there isn't one.
+func readCodeOwners(ctx context.Context, c client.GitClient, repo, branch string) ([]codeOwnersRule, error) {
+	for _, filename := range codeOwnersFiles {
+		file, err := c.GetFile(ctx, repo, branch, filename)
+		if client.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, scmError(err, repo, "get file "+filename, nil)
+		}
+		return parseCodeOwners(file.Data), nil
+	}
+	return nil, nil
+}
+
+// parseCodeOwners returns the rules of a CODEOWNERS file, comments, and GitLab
+// section headers, are ignored.
+func parseCodeOwners(b []byte) []codeOwnersRule {
+	rules := []codeOwnersRule{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") || strings.HasPrefix(fields[0], "^[") {
+			continue
+		}
+		rules = append(rules, codeOwnersRule{pattern: codeOwnersPattern(fields[0]), owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeOwnersPattern returns a regular expression matching the paths that the
+// gitignore style pattern matches, e.g. "/apps/" matches all the files in the
+// apps directory at the root of the repo, and "*.yaml" matches in any
+// directory.
+func codeOwnersPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(.*/)?")
+	}
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if strings.HasSuffix(pattern, "/") {
+		re.WriteString(".*")
+	} else {
+		// A pattern matches a directory, and the files in it.
+		re.WriteString("(/.*)?")
+	}
+	re.WriteString("$")
+	return regexp.MustCompile(re.String())
+}
+
+// codeOwners returns the owners of the paths, the last matching rule for a path
+// determines its owners.
+func codeOwners(rules []codeOwnersRule, paths []string) []string {
+	seen := map[string]bool{}
+	owners := []string{}
+	for _, path := range paths {
+		path = strings.TrimPrefix(path, "/")
+		for i := len(rules) - 1; i >= 0; i-- {
+			if !rules[i].pattern.MatchString(path) {
+				continue
+			}
+			for _, owner := range rules[i].owners {
+				if !seen[owner] {
+					seen[owner] = true
+					owners = append(owners, owner)
+				}
+			}
+			break
+		}
+	}
+	return owners
+}
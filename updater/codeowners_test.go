@@ -0,0 +1,109 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+const testCodeOwners = `# Platform owns everything by default
+*                      @my-org/platform
+
+[Frontend]
+/environments/         @my-org/deployers
+**/service-a/          @octocat @my-org/service-a
+*.md                   docs@example.com
+environments/prod/     @hubot
+`
+
+func TestCodeOwners(t *testing.T) {
+	ownersTests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"docs@example.com"}},
+		{"docs/guide.md", []string{"docs@example.com"}},
+		{"Makefile", []string{"@my-org/platform"}},
+		{"environments/staging/values.yaml", []string{"@my-org/deployers"}},
+		{testFilePath, []string{"@octocat", "@my-org/service-a"}},
+		{"environments/prod/values.yaml", []string{"@hubot"}},
+		{"apps/environments/values.yaml", []string{"@my-org/platform"}},
+	}
+	rules := parseCodeOwners([]byte(testCodeOwners))
+
+	for _, tt := range ownersTests {
+		t.Run(tt.path, func(rt *testing.T) {
+			if diff := cmp.Diff(tt.want, codeOwners(rules, []string{tt.path})); diff != "" {
+				rt.Fatalf("incorrect owners:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateRequestsCodeOwners(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, ".github/CODEOWNERS", testBranch, []byte(testCodeOwners))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.CodeOwners = true
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat")
+	m.AssertTeamReviewersRequested(testGitHubRepo, 1, "my-org/service-a")
+}
+
+func TestUpdateBatchRequestsCodeOwners(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/prod/values.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddMissingFile(testGitHubRepo, ".github/CODEOWNERS", testBranch)
+	m.AddFileContents(testGitHubRepo, "CODEOWNERS", testBranch, []byte(testCodeOwners))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	staging, production := makeInput(), makeInput()
+	production.Filename = "environments/prod/values.yaml"
+	production.PullRequest.CodeOwners = true
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{staging, production}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat", "hubot")
+	m.AssertTeamReviewersRequested(testGitHubRepo, 1, "my-org/service-a")
+}
+
+func TestUpdateRequestsCodeOwnersWithoutCodeOwnersFile(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	for _, filename := range codeOwnersFiles {
+		m.AddMissingFile(testGitHubRepo, filename, testBranch)
+	}
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.CodeOwners = true
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertReviewersRequested(testGitHubRepo, 1)
+}
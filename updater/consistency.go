@@ -0,0 +1,318 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/syaml"
+)
+
+// ReleaseReport is the outcome of a Batch that updates several repos as one
+// release, which is consistent if every repo was updated, or none were.
+type ReleaseReport struct {
+	// Updated are the results that committed changes, directly or in a
+	// PullRequest.
+	Updated []*BatchResult
+	// Failed are the results of the updates that failed, when the Batch
+	// continues on errors.
+	Failed []*BatchResult
+	// Unchanged are the results where nothing needed changing.
+	Unchanged []*BatchResult
+	// Skipped are the results for read-only repos, and skipped mismatches.
+	Skipped []*BatchResult
+
+	batch *Batch
+}
+
+// NewReleaseReport creates and returns a ReleaseReport from the results of
+// UpdateBatchResults for the Batch, which should have ContinueOnError, so that
+// all the repos are attempted.
+func NewReleaseReport(b *Batch, results []*BatchResult) *ReleaseReport {
+	r := &ReleaseReport{batch: b}
+	for _, result := range results {
+		switch result.State {
+		case Committed, PullRequestCreated, PullRequestUpdated:
+			r.Updated = append(r.Updated, result)
+		case Failed:
+			r.Failed = append(r.Failed, result)
+		case Skipped:
+			r.Skipped = append(r.Skipped, result)
+		default:
+			r.Unchanged = append(r.Unchanged, result)
+		}
+	}
+	return r
+}
+
+// Consistent returns true if no updates failed, or no repos were updated.
+func (r *ReleaseReport) Consistent() bool {
+	return len(r.Failed) == 0 || len(r.Updated) == 0
+}
+
+// String returns a summary of the report, with a line for each updated and
+// failed repo.
+func (r *ReleaseReport) String() string {
+	state := "consistent"
+	if !r.Consistent() {
+		state = "inconsistent"
+	}
+	lines := []string{fmt.Sprintf("release is %s: %d updated, %d failed, %d unchanged, %d skipped",
+		state, len(r.Updated), len(r.Failed), len(r.Unchanged), len(r.Skipped))}
+	for _, u := range r.Updated {
+		line := fmt.Sprintf("updated %s %s: %s", u.Repo, u.Branch, u.State)
+		if u.PullRequest != nil {
+			line += fmt.Sprintf(" #%d", u.PullRequest.Number)
+		}
+		lines = append(lines, line)
+	}
+	for _, f := range r.Failed {
+		lines = append(lines, fmt.Sprintf("failed %s %s: %s", f.Repo, f.Branch, f.Err))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// Reverts returns the Compensations that restore a consistent state by
+// undoing the updates to the Updated repos, it's empty if the release is
+// Consistent.
+func (r *ReleaseReport) Reverts() []*Compensation {
+	if r.Consistent() {
+		return nil
+	}
+	reverts := []*Compensation{}
+	for _, u := range r.Updated {
+		reverts = append(reverts, &Compensation{Kind: RevertUpdate, Result: u, batch: r.batch})
+	}
+	return reverts
+}
+
+// Retries returns the Compensations that restore a consistent state by
+// retrying the updates to the Failed repos, it's empty if the release is
+// Consistent.
+func (r *ReleaseReport) Retries() []*Compensation {
+	if r.Consistent() {
+		return nil
+	}
+	retries := []*Compensation{}
+	for _, f := range r.Failed {
+		retries = append(retries, &Compensation{Kind: RetryUpdate, Result: f, batch: r.batch})
+	}
+	return retries
+}
+
+// CompensationKind is the action taken by a Compensation.
+type CompensationKind int
+
+const (
+	// RevertUpdate undoes an update, an open PullRequest is closed, otherwise
+	// the changed values are restored from the Base commit, in a PullRequest.
+	RevertUpdate CompensationKind = iota
+	// RetryUpdate applies a failed update again.
+	RetryUpdate
+)
+
+func (k CompensationKind) String() string {
+	switch k {
+	case RevertUpdate:
+		return "revert"
+	case RetryUpdate:
+		return "retry"
+	}
+	return "unknown"
+}
+
+// Compensation is an action that restores a consistent release, applied with
+// Compensate.
+type Compensation struct {
+	Kind   CompensationKind
+	Result *BatchResult
+
+	batch *Batch
+}
+
+func (c *Compensation) String() string {
+	return fmt.Sprintf("%s %s %s", c.Kind, c.Result.Repo, c.Result.Branch)
+}
+
+// CompensationResult records the outcome of a Compensation.
+type CompensationResult struct {
+	// ClosedPullRequest is the PullRequest of the update, if it was still
+	// open, and closed rather than reverting the change.
+	ClosedPullRequest *scm.PullRequest
+	*UpdateResult
+}
+
+// Compensate applies the Compensation.
+//
+// Changes are reverted by restoring the values of the keys from the Base
+// commit of the update, or the whole file if it wasn't updated by a Key, or
+// isn't YAML, so later changes to other keys in the files are retained.
+// Encrypted files can't be reverted.
+func (u *Updater) Compensate(ctx context.Context, c *Compensation) (*CompensationResult, error) {
+	if c.Kind == RetryUpdate {
+		b := Batch{}
+		if c.batch != nil {
+			b = *c.batch
+		}
+		b.Inputs, b.ContinueOnError = c.Result.Inputs, false
+		return u.compensateBatch(ctx, &b)
+	}
+	return u.revert(ctx, c.Result)
+}
+
+func (u *Updater) revert(ctx context.Context, r *BatchResult) (*CompensationResult, error) {
+	branch, generateName := r.Branch, "revert-"
+	if r.PullRequest != nil {
+		pr, err := u.gitClient.FindPullRequest(ctx, r.Repo, r.UpdateResult.Branch)
+		if err != nil && !client.IsNotFound(err) {
+			return nil, scmError(err, r.Repo, "find the pull request to revert", nil)
+		}
+		switch {
+		case pr != nil && r.State == PullRequestCreated:
+			if err := u.closeReverted(ctx, r.Repo, pr); err != nil {
+				return nil, err
+			}
+			return &CompensationResult{ClosedPullRequest: pr, UpdateResult: &UpdateResult{State: Unchanged}}, nil
+		case pr != nil:
+			// The reused PullRequest has other changes, so the update is
+			// reverted on its branch.
+			branch, generateName = pr.Source, ""
+		}
+	}
+	if r.Base == "" {
+		return nil, fmt.Errorf("failed to revert the update of %s: the base commit is not known", r.Repo)
+	}
+	inputs := []*Input{}
+	for _, input := range r.Inputs {
+		reverted, err := u.revertInputs(ctx, input, r.Base, branch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to revert the update of %s: %w", r.Repo, err)
+		}
+		inputs = append(inputs, reverted...)
+	}
+	title := "Revert the update of " + r.Repo
+	if t := r.Inputs[0].PullRequest.Title; t != "" {
+		title = fmt.Sprintf("Revert %q", t)
+	}
+	body := fmt.Sprintf("This restores the files changed by the update of %s from %s.", r.Repo, r.Base)
+	if r.PullRequest != nil {
+		body = fmt.Sprintf("This reverts %s, restoring the files from %s.", pullRequestRef(r.PullRequest), r.Base)
+	}
+	return u.compensateBatch(ctx, &Batch{
+		Inputs:             inputs,
+		GroupByRepo:        true,
+		BranchGenerateName: generateName,
+		PullRequest:        PullRequestInput{Title: title, Body: body},
+	})
+}
+
+// revertInputs returns Inputs that restore the files changed by the Input to
+// their content at the base commit, committed to the branch.
+func (u *Updater) revertInputs(ctx context.Context, input *Input, base, branch string) ([]*Input, error) {
+	files := []*Input{}
+	for _, f := range input.fileInputs() {
+		atBase := *f
+		atBase.Branch = base
+		files = append(files, &atBase)
+	}
+	files, err := u.expandGlobs(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	reverts := []*Input{}
+	for _, f := range files {
+		if f.Encryption != nil {
+			return nil, fmt.Errorf("encrypted file %s can't be reverted", f.Filename)
+		}
+		previous, err := u.gitClient.GetFile(ctx, f.Repo, base, f.Filename)
+		if err != nil {
+			return nil, scmError(err, f.Repo, "get file "+f.Filename+" at "+base, nil)
+		}
+		restore := restoreContent(previous.Data)
+		if f.Key != "" && f.ContentUpdater == nil && f.Source == nil && f.isYAML() {
+			keys := []string{f.Key}
+			for _, v := range f.Values {
+				keys = append(keys, v.Key)
+			}
+			restore = restoreKeys(previous.Data, keys)
+		}
+		reverts = append(reverts, &Input{
+			Repo:           f.Repo,
+			Filename:       f.Filename,
+			Branch:         branch,
+			CommitMessage:  "Revert " + f.Filename,
+			ContentUpdater: restore,
+		})
+	}
+	return reverts, nil
+}
+
+func (u *Updater) compensateBatch(ctx context.Context, b *Batch) (*CompensationResult, error) {
+	results, err := u.UpdateBatchResults(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, errors.New("no repos to update")
+	}
+	if results[0].Err != nil {
+		return nil, results[0].Err
+	}
+	return &CompensationResult{UpdateResult: results[0].UpdateResult}, nil
+}
+
+func (u *Updater) closeReverted(ctx context.Context, repo string, pr *scm.PullRequest) error {
+	comment := "Closed to restore a consistent release, as updates to other repositories failed."
+	if err := u.gitClient.CreateComment(ctx, repo, pr.Number, u.sanitize(TextComment, comment)); err != nil {
+		return fmt.Errorf("failed to comment on pull request %d: %w", pr.Number, err)
+	}
+	if err := u.gitClient.ClosePullRequest(ctx, repo, pr.Number); err != nil {
+		return fmt.Errorf("failed to close pull request %d: %w", pr.Number, err)
+	}
+	if err := u.gitClient.DeleteBranch(ctx, repo, pr.Source); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", pr.Source, err)
+	}
+	u.log.Info("closed reverted PullRequest", "number", pr.Number)
+	return nil
+}
+
+// restoreContent returns a ContentUpdater that replaces the file with the
+// previous content.
+func restoreContent(previous []byte) ContentUpdater {
+	return func([]byte) ([]byte, error) {
+		return previous, nil
+	}
+}
+
+// restoreKeys returns a ContentUpdater that sets the keys to their values in
+// the previous content, and removes the keys that it didn't have.
+func restoreKeys(previous []byte, keys []string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		for _, key := range keys {
+			value, err := syaml.GetRawBytes(previous, key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the previous value of %s: %w", key, err)
+			}
+			if value == nil {
+				b, err = syaml.DeleteBytes(b, key)
+			} else {
+				b, err = syaml.SetBytes(b, key, *value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to restore the value of %s: %w", key, err)
+			}
+		}
+		return b, nil
+	}
+}
+
+func pullRequestRef(pr *scm.PullRequest) string {
+	if pr.Link != "" {
+		return pr.Link
+	}
+	return fmt.Sprintf("#%d", pr.Number)
+}
@@ -0,0 +1,264 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testBaseSHA = "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+
+func makeRelease(t *testing.T) (*mock.MockClient, *Batch) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testBaseSHA)
+	other := makeInput()
+	// The file is missing from the other repo, so its update fails.
+	other.Repo = testOtherRepo
+	return m, &Batch{Inputs: []*Input{makeInput(), other}, ContinueOnError: true}
+}
+
+func TestUpdateBatchResultsContinueOnError(t *testing.T) {
+	m, b := makeRelease(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	results, err := updater.UpdateBatchResults(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if l := len(results); l != 2 {
+		t.Fatalf("got %d results, want 2", l)
+	}
+	if s := results[0].State; s != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", s)
+	}
+	if b := results[0].Base; b != testBaseSHA {
+		t.Fatalf("got base %q, want %q", b, testBaseSHA)
+	}
+	if s := results[1].State; s != Failed {
+		t.Fatalf("got state %s, want failed", s)
+	}
+	if !test.MatchError(t, "failed to get file", results[1].Err) {
+		t.Fatalf("failed to match error: %s", results[1].Err)
+	}
+}
+
+func TestReleaseReport(t *testing.T) {
+	m, b := makeRelease(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	results, err := updater.UpdateBatchResults(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := NewReleaseReport(b, results)
+
+	if report.Consistent() {
+		t.Fatal("got a consistent release, want inconsistent")
+	}
+	want := "release is inconsistent: 1 updated, 1 failed, 0 unchanged, 0 skipped\n" +
+		"updated testorg/testrepo main: pull-request-created #1\n" +
+		"failed testorg/otherrepo main: " + results[1].Err.Error() + "\n"
+	if diff := cmp.Diff(want, report.String()); diff != "" {
+		t.Fatalf("incorrect report:\n%s", diff)
+	}
+	got := []string{}
+	for _, c := range append(report.Reverts(), report.Retries()...) {
+		got = append(got, c.String())
+	}
+	if diff := cmp.Diff([]string{"revert testorg/testrepo main", "retry testorg/otherrepo main"}, got); diff != "" {
+		t.Fatalf("incorrect compensations:\n%s", diff)
+	}
+}
+
+func TestReleaseReportConsistent(t *testing.T) {
+	reportTests := []struct {
+		name    string
+		results []*BatchResult
+	}{
+		{"all updated", []*BatchResult{
+			{Repo: testGitHubRepo, UpdateResult: &UpdateResult{State: PullRequestCreated}},
+			{Repo: testOtherRepo, UpdateResult: &UpdateResult{State: Committed}},
+		}},
+		{"none updated", []*BatchResult{
+			{Repo: testGitHubRepo, UpdateResult: &UpdateResult{State: Failed}},
+			{Repo: testOtherRepo, UpdateResult: &UpdateResult{State: Unchanged}},
+		}},
+		{"skipped", []*BatchResult{
+			{Repo: testGitHubRepo, UpdateResult: &UpdateResult{State: PullRequestUpdated}},
+			{Repo: testOtherRepo, UpdateResult: &UpdateResult{State: Skipped}},
+		}},
+	}
+
+	for _, tt := range reportTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			report := NewReleaseReport(&Batch{}, tt.results)
+
+			if !report.Consistent() {
+				rt.Fatalf("got an inconsistent release:\n%s", report)
+			}
+			if c := append(report.Reverts(), report.Retries()...); len(c) != 0 {
+				rt.Fatalf("got compensations %v, want none", c)
+			}
+		})
+	}
+}
+
+func TestCompensateRetry(t *testing.T) {
+	m, b := makeRelease(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	results, err := updater.UpdateBatchResults(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AddFileContents(testOtherRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testOtherRepo, testBranch, testBaseSHA)
+
+	r, err := updater.Compensate(context.Background(), NewReleaseReport(b, results).Retries()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", r.State)
+	}
+	if s := string(m.GetUpdatedContents(testOtherRepo, testFilePath, "test-branch-a")); s != "test:\n  image: new-image\n" {
+		t.Fatalf("retry failed, got %#v", s)
+	}
+}
+
+func TestCompensateRetryFailing(t *testing.T) {
+	m, b := makeRelease(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	results, err := updater.UpdateBatchResults(context.Background(), b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = updater.Compensate(context.Background(), NewReleaseReport(b, results).Retries()[0])
+
+	if !test.MatchError(t, "failed to get file", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCompensateRevertClosesOpenPullRequest(t *testing.T) {
+	m := mock.New(t)
+	pr := &scm.PullRequest{Number: 3, Source: "test-branch-a", Target: testBranch}
+	m.AddOpenPullRequest(testGitHubRepo, pr)
+	updater := New(zap.New(), m)
+	c := &Compensation{Kind: RevertUpdate, Result: &BatchResult{
+		Repo: testGitHubRepo, Branch: testBranch, Inputs: []*Input{makeInput()},
+		UpdateResult: &UpdateResult{State: PullRequestCreated, Branch: "test-branch-a", Base: testBaseSHA, PullRequest: pr},
+	}}
+
+	r, err := updater.Compensate(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.ClosedPullRequest != pr {
+		t.Fatalf("got closed PullRequest %#v, want %#v", r.ClosedPullRequest, pr)
+	}
+	m.AssertPullRequestClosed(testGitHubRepo, 3)
+	m.AssertCommentCreated(testGitHubRepo, 3, "Closed to restore a consistent release, as updates to other repositories failed.")
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestCompensateRevertMergedUpdate(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBaseSHA, []byte("test:\n  image: old-image\n"))
+	// The file was changed after the update was merged.
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n  replicas: 3\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	c := &Compensation{Kind: RevertUpdate, Result: &BatchResult{
+		Repo: testGitHubRepo, Branch: testBranch, Inputs: []*Input{makeInput()},
+		UpdateResult: &UpdateResult{State: PullRequestCreated, Branch: "test-branch-a", Base: testBaseSHA, PullRequest: &scm.PullRequest{Number: 3}},
+	}}
+
+	r, err := updater.Compensate(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", r.State)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "revert-a")); s != "test:\n  image: old-image\n  replicas: 3\n" {
+		t.Fatalf("revert failed, got %#v", s)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: `Revert "This is a test PR"`,
+		Body:  "This reverts #3, restoring the files from " + testBaseSHA + ".",
+		Head:  "revert-a",
+		Base:  testBranch,
+	})
+}
+
+func TestCompensateRevertRestoresFiles(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBaseSHA, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n  tag: v2\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BranchGenerateName = ""
+	input.Values = []KeyValue{{Key: "test.tag", NewValue: "v2"}}
+	c := &Compensation{Kind: RevertUpdate, Result: &BatchResult{
+		Repo: testGitHubRepo, Branch: testBranch, Inputs: []*Input{input},
+		UpdateResult: &UpdateResult{State: Committed, Branch: testBranch, Base: testBaseSHA},
+	}}
+
+	// A direct commit is reverted in a PullRequest.
+	r, err := updater.Compensate(context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", r.State)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "revert-a")); s != "test:\n  image: old-image\n" {
+		t.Fatalf("revert failed, got %#v", s)
+	}
+}
+
+func TestCompensateRevertWithoutBase(t *testing.T) {
+	updater := New(zap.New(), mock.New(t))
+	c := &Compensation{Kind: RevertUpdate, Result: &BatchResult{
+		Repo: testGitHubRepo, Branch: testBranch, Inputs: []*Input{makeInput()},
+		UpdateResult: &UpdateResult{State: Committed, Branch: testBranch},
+	}}
+
+	_, err := updater.Compensate(context.Background(), c)
+
+	if !test.MatchError(t, "failed to revert the update of testorg/testrepo: the base commit is not known", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestCompensateRevertEncryptedFile(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBaseSHA, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+	c := &Compensation{Kind: RevertUpdate, Result: &BatchResult{
+		Repo: testGitHubRepo, Branch: testBranch, Inputs: []*Input{input},
+		UpdateResult: &UpdateResult{State: Committed, Branch: testBranch, Base: testBaseSHA},
+	}}
+
+	_, err := updater.Compensate(context.Background(), c)
+
+	if !test.MatchError(t, "encrypted file .* can't be reverted", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,132 @@
+package updater
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// DecisionReason is why a trigger did, or did not, result in a change.
+type DecisionReason string
+
+const (
+	// DecisionApplied is a change that was committed, directly or with a
+	// PullRequest.
+	DecisionApplied DecisionReason = "applied"
+	// DecisionValueUnchanged is an update where the file already had the
+	// value.
+	DecisionValueUnchanged DecisionReason = "value-unchanged"
+	// DecisionUnexpectedValue is an update that was Skipped, as the current
+	// value was not the expected value.
+	DecisionUnexpectedValue DecisionReason = "unexpected-value"
+	// DecisionPolicyDenied is an update rejected by the Updater's policy,
+	// e.g. RequirePullRequests.
+	DecisionPolicyDenied DecisionReason = "policy-denied"
+	// DecisionReadOnlyRepo is an update to an archived or disabled repo.
+	DecisionReadOnlyRepo DecisionReason = "read-only-repo"
+	// DecisionDeduplicated is a value that was already applied, and is not
+	// applied again.
+	DecisionDeduplicated DecisionReason = "deduplicated"
+	// DecisionDisabled is a trigger for a disabled rule.
+	DecisionDisabled DecisionReason = "disabled"
+	// DecisionNoRuleMatched is a trigger that no rule applies to, it's
+	// recorded by the caller that routes triggers to rules.
+	DecisionNoRuleMatched DecisionReason = "no-rule-matched"
+	// DecisionFailed is an update that failed with an error.
+	DecisionFailed DecisionReason = "failed"
+)
+
+// Decision records the outcome of a trigger, and why, so that updates which
+// did nothing can be explained.
+type Decision struct {
+	Time        time.Time
+	Reason      DecisionReason
+	Rule        string
+	Repo        string
+	Branch      string
+	Filename    string
+	Key         string
+	Trigger     *Trigger
+	State       string // The UpdateState, if the update ran
+	PullRequest int
+	Detail      string // e.g. the error
+}
+
+// Decisions is an option func for the Updater creation function, when
+// configured, a Decision is written to the writer for each update, one
+// document written by Encode per line.
+//
+// The values of the updates are not recorded.
+func Decisions(w io.Writer) UpdaterFunc {
+	return func(u *Updater) {
+		u.decisions = &decisionLog{w: w}
+	}
+}
+
+type decisionLog struct {
+	sync.Mutex
+	w io.Writer
+}
+
+// RecordDecision logs the Decision, and writes it to the Decisions writer if
+// one is configured, the Time defaults to now.
+//
+// Decisions for each update are recorded by the Updater, callers record
+// the decisions it can't make, e.g. DecisionNoRuleMatched.
+func (u *Updater) RecordDecision(d Decision) {
+	if d.Time.IsZero() {
+		d.Time = u.now()
+	}
+	d.Time = d.Time.UTC()
+	kv := []interface{}{"reason", string(d.Reason), "repo", d.Repo, "filename", d.Filename, "key", d.Key}
+	if d.Rule != "" {
+		kv = append(kv, "rule", d.Rule)
+	}
+	if d.Detail != "" {
+		kv = append(kv, "detail", d.Detail)
+	}
+	u.log.V(1).Info("update decision", append(kv, d.Trigger.keysAndValues()...)...)
+	if u.decisions == nil {
+		return
+	}
+	b, err := Encode(&d)
+	if err != nil {
+		u.log.Info("failed to encode decision", "err", err)
+		return
+	}
+	u.decisions.Lock()
+	defer u.decisions.Unlock()
+	if _, err := u.decisions.w.Write(append(b, '\n')); err != nil {
+		u.log.Info("failed to write decision", "err", err)
+	}
+}
+
+// decide records the Decision for the outcome of the update of the Input.
+func (u *Updater) decide(input *Input, r *UpdateResult, err error) {
+	d := Decision{Repo: input.Repo, Branch: input.Branch, Filename: input.Filename, Key: input.Key, Trigger: input.Trigger}
+	switch {
+	case errors.Is(err, ErrDirectCommit):
+		d.Reason, d.Detail = DecisionPolicyDenied, err.Error()
+	case errors.Is(err, ErrRepoReadOnly):
+		d.Reason, d.Detail = DecisionReadOnlyRepo, err.Error()
+	case err != nil:
+		d.Reason, d.Detail = DecisionFailed, err.Error()
+	default:
+		d.State = r.State.String()
+		switch r.State {
+		case Unchanged:
+			d.Reason = DecisionValueUnchanged
+		case Skipped:
+			d.Reason = DecisionUnexpectedValue
+		case Failed:
+			d.Reason = DecisionFailed
+		default:
+			d.Reason = DecisionApplied
+		}
+		if r.PullRequest != nil {
+			d.PullRequest = r.PullRequest.Number
+		}
+	}
+	u.RecordDecision(d)
+}
@@ -0,0 +1,148 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestUpdateRecordsDecisions(t *testing.T) {
+	trigger := &Trigger{Source: TriggerRegistry, Image: "quay.io/my-org/my-image", Tag: "v1.4.2"}
+	decisionTests := []struct {
+		name    string
+		content string
+		input   func(*Input)
+		opts    []UpdaterFunc
+		want    Decision
+	}{
+		{
+			name:    "applied",
+			content: "test:\n  image: old-image\n",
+			want:    Decision{Reason: DecisionApplied, State: "pull-request-created", PullRequest: 1},
+		},
+		{
+			name:    "value unchanged",
+			content: "test:\n  image: new-image\n",
+			want:    Decision{Reason: DecisionValueUnchanged, State: "unchanged"},
+		},
+		{
+			name:    "unexpected value",
+			content: "test:\n  image: other-image\n",
+			input: func(i *Input) {
+				expected := "old-image"
+				i.ExpectedValue, i.SkipOnMismatch = &expected, true
+			},
+			want: Decision{Reason: DecisionUnexpectedValue, State: "skipped"},
+		},
+		{
+			name:    "policy denied",
+			content: "test:\n  image: old-image\n",
+			input:   func(i *Input) { i.BranchGenerateName = "" },
+			opts:    []UpdaterFunc{RequirePullRequests()},
+			want:    Decision{Reason: DecisionPolicyDenied, Detail: "direct commits are not allowed: a BranchGenerateName is required to update branch " + testBranch + " in repo " + testGitHubRepo},
+		},
+	}
+
+	for _, tt := range decisionTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(tt.content))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			var buf bytes.Buffer
+			updater := New(zap.New(), m, append([]UpdaterFunc{NameGenerator(stubNameGenerator{"a"}), Decisions(&buf)}, tt.opts...)...)
+			updater.now = func() time.Time { return time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC) }
+			input := makeInput()
+			input.Trigger = trigger
+			if tt.input != nil {
+				tt.input(input)
+			}
+
+			// The errors are recorded in the Decision.
+			_, _ = updater.Update(context.Background(), input)
+
+			want := tt.want
+			want.Time = time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+			want.Repo, want.Branch, want.Filename, want.Key, want.Trigger = testGitHubRepo, testBranch, testFilePath, "test.image", trigger
+			if diff := cmp.Diff([]Decision{want}, readDecisions(rt, &buf)); diff != "" {
+				rt.Fatalf("incorrect decisions:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateBatchRecordsDecisions(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddRepoStatus("my-org/archived", &client.RepoStatus{Archived: true})
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Decisions(&buf))
+	second := makeInput()
+	second.Filename = testSecondFilePath
+	archived := makeInput()
+	archived.Repo = "my-org/archived"
+
+	_, err := updater.UpdateBatchResults(context.Background(), &Batch{Inputs: []*Input{makeInput(), second, archived}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, d := range readDecisions(t, &buf) {
+		got = append(got, d.Repo+" "+d.Filename+" "+string(d.Reason))
+	}
+	want := []string{
+		testGitHubRepo + " " + testFilePath + " applied",
+		testGitHubRepo + " " + testSecondFilePath + " applied",
+		"my-org/archived " + testFilePath + " read-only-repo",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("incorrect decisions:\n%s", diff)
+	}
+}
+
+func TestRecordDecision(t *testing.T) {
+	var buf bytes.Buffer
+	updater := New(zap.New(), mock.New(t), Decisions(&buf))
+	updater.now = func() time.Time { return time.Date(2024, time.January, 31, 12, 0, 0, 0, time.FixedZone("CET", 3600)) }
+
+	updater.RecordDecision(Decision{Reason: DecisionNoRuleMatched, Trigger: &Trigger{Source: TriggerWebhook, PayloadRef: "72d3162e"}})
+
+	want := `{"version":1,"kind":"Decision","data":{"time":"2024-01-31T11:00:00Z","reason":"no-rule-matched","trigger":{"source":"webhook","payloadRef":"72d3162e"}}}` + "\n"
+	if buf.String() != want {
+		t.Fatalf("got %s, want %s", buf.String(), want)
+	}
+}
+
+func TestRecordDecisionWithoutWriter(t *testing.T) {
+	updater := New(zap.New(), mock.New(t))
+
+	updater.RecordDecision(Decision{Reason: DecisionNoRuleMatched})
+}
+
+func readDecisions(t *testing.T, buf *bytes.Buffer) []Decision {
+	t.Helper()
+	decisions := []Decision{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var b json.RawMessage
+		if err := dec.Decode(&b); err != nil {
+			t.Fatal(err)
+		}
+		var d Decision
+		if err := Decode(b, &d); err != nil {
+			t.Fatal(err)
+		}
+		decisions = append(decisions, d)
+	}
+	return decisions
+}
@@ -0,0 +1,198 @@
+package updater
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DelayedUpdate is an Input scheduled to be applied at, or after, a time, e.g.
+// to promote a change to production after it has soaked in staging.
+type DelayedUpdate struct {
+	ID        string    `json:"id"`
+	NotBefore time.Time `json:"notBefore"`
+	Input     *Input    `json:"input"`
+}
+
+// Store persists DelayedUpdates, so that they survive restarts.
+type Store interface {
+	Save(ctx context.Context, d *DelayedUpdate) error
+	// List returns the stored updates, ordered by the time they're due.
+	List(ctx context.Context) ([]*DelayedUpdate, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// WithStore is an option func for the Updater creation function, it
+// configures the Store used for delayed updates.
+func WithStore(s Store) UpdaterFunc {
+	return func(u *Updater) {
+		u.store = s
+	}
+}
+
+var errNoStore = errors.New("no Store is configured for delayed updates")
+
+// ScheduleUpdate stores the Input, to be applied by RunDueUpdates at, or
+// after, the time.
+func (u *Updater) ScheduleUpdate(ctx context.Context, input *Input, notBefore time.Time) (*DelayedUpdate, error) {
+	if u.store == nil {
+		return nil, errNoStore
+	}
+	id, err := newUpdateID()
+	if err != nil {
+		return nil, err
+	}
+	d := &DelayedUpdate{ID: id, NotBefore: notBefore.UTC(), Input: input}
+	if err := u.store.Save(ctx, d); err != nil {
+		return nil, fmt.Errorf("failed to save delayed update: %w", err)
+	}
+	u.log.Info("scheduled update", "id", d.ID, "notBefore", d.NotBefore, "repo", input.Repo, "filename", input.Filename)
+	return d, nil
+}
+
+// newUpdateID returns a unique ID for a DelayedUpdate, from 16 random bytes.
+func newUpdateID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate an update ID: %w", err)
+	}
+	return "update-" + hex.EncodeToString(b), nil
+}
+
+// RunDueUpdates applies the stored updates that are due at the time, and
+// removes them from the Store, it returns the results of the updates that
+// were applied.
+//
+// Updates that fail are left in the Store to be retried, and the first error
+// is returned once the other due updates have been applied.
+func (u *Updater) RunDueUpdates(ctx context.Context, now time.Time) ([]*UpdateResult, error) {
+	if u.store == nil {
+		return nil, errNoStore
+	}
+	delayed, err := u.store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list delayed updates: %w", err)
+	}
+	results := []*UpdateResult{}
+	var firstErr error
+	for _, d := range delayed {
+		if d.NotBefore.After(now) {
+			continue
+		}
+		r, err := u.Update(ctx, d.Input)
+		if err == nil {
+			err = u.store.Delete(ctx, d.ID)
+			results = append(results, r)
+		}
+		if err != nil {
+			u.log.Info("failed to apply delayed update", "id", d.ID, "err", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to apply delayed update %s: %w", d.ID, err)
+			}
+		}
+	}
+	return results, firstErr
+}
+
+// FileStore is a Store that persists each DelayedUpdate as a file written by
+// Encode in a directory.
+//
+// Inputs with a Validator, Encryption or ContentUpdater can't be persisted,
+// the NewValue is restored as decoded from JSON, retaining RawYAML values, and
+// only files written by Encode are read.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates and returns a FileStore that uses the directory, which
+// must exist.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Save implements the Store interface, an existing update with the same ID is
+// not replaced.
+func (s *FileStore) Save(ctx context.Context, d *DelayedUpdate) error {
+	if d.Input.Validator != nil || d.Input.Encryption != nil {
+		return fmt.Errorf("update %s has a Validator or Encryption, which can't be persisted", d.ID)
+	}
+	if len(d.Input.PostActions) > 0 {
+		return fmt.Errorf("update %s has PostActions, which can't be persisted", d.ID)
+	}
+	if hasContentUpdater(d.Input) {
+		return fmt.Errorf("update %s has a ContentUpdater, which can't be persisted", d.ID)
+	}
+	b, err := Encode(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode update %s: %w", d.ID, err)
+	}
+	// Writing to a temporary file and renaming it avoids partial files.
+	tmp := filepath.Join(s.dir, "."+d.ID+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	// Unlike renaming, linking fails if the file exists.
+	if err := os.Link(tmp, s.path(d.ID)); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("update %s already exists", d.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// List implements the Store interface.
+func (s *FileStore) List(ctx context.Context) ([]*DelayedUpdate, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	delayed := []*DelayedUpdate{}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		d := &DelayedUpdate{}
+		if err := Decode(b, d); err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", filepath.Base(f), err)
+		}
+		delayed = append(delayed, d)
+	}
+	sort.SliceStable(delayed, func(i, j int) bool {
+		return delayed[i].NotBefore.Before(delayed[j].NotBefore)
+	})
+	return delayed, nil
+}
+
+// Delete implements the Store interface.
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func hasContentUpdater(input *Input) bool {
+	if input.ContentUpdater != nil {
+		return true
+	}
+	for _, f := range input.Files {
+		if f.ContentUpdater != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/syaml"
+	"github.com/agill17/pkg/test"
+)
+
+func makeFileStore(t *testing.T) *FileStore {
+	dir, err := ioutil.TempDir("", "delayed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return NewFileStore(dir)
+}
+
+func TestRunDueUpdates(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	store := makeFileStore(t)
+	now := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), WithStore(store))
+	later := makeInput()
+	later.Filename = testSecondFilePath
+	scheduled, err := updater.ScheduleUpdate(context.Background(), later, now.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := updater.ScheduleUpdate(context.Background(), makeInput(), now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new Updater, as if the service had restarted.
+	results, err := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), WithStore(store)).RunDueUpdates(context.Background(), now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(results); l != 1 {
+		t.Fatalf("got %d results, want 1", l)
+	}
+	if results[0].State != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", results[0].State)
+	}
+	remaining, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{scheduled.ID}, updateIDs(remaining)); diff != "" {
+		t.Fatalf("remaining updates:\n%s", diff)
+	}
+}
+
+func TestRunDueUpdatesKeepsFailedUpdates(t *testing.T) {
+	m := mock.New(t)
+	m.GetFileErr = errors.New("unavailable")
+	store := makeFileStore(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), WithStore(store))
+	scheduled, err := updater.ScheduleUpdate(context.Background(), makeInput(), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = updater.RunDueUpdates(context.Background(), time.Now())
+
+	if !test.MatchError(t, "failed to apply delayed update "+scheduled.ID+": failed to get file .* from branch main: unavailable", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	remaining, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(remaining); l != 1 {
+		t.Fatalf("got %d remaining updates, want 1", l)
+	}
+}
+
+func TestScheduleUpdateWithoutStore(t *testing.T) {
+	updater := New(zap.New(), mock.New(t))
+
+	_, err := updater.ScheduleUpdate(context.Background(), makeInput(), time.Now())
+
+	if !test.MatchError(t, "no Store is configured for delayed updates", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := makeFileStore(t)
+	input := makeInput()
+	input.NewValue = syaml.RawYAML("limits:\n  cpu: 500m\n")
+	input.Trigger = &Trigger{Source: TriggerManual, Actor: "testuser"}
+	first := &DelayedUpdate{ID: "update-a", NotBefore: time.Date(2020, time.June, 2, 0, 0, 0, 0, time.UTC), Input: input}
+	second := &DelayedUpdate{ID: "update-b", NotBefore: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC), Input: makeInput()}
+	second.Input.NewValue = 12345678901234567
+
+	for _, d := range []*DelayedUpdate{first, second} {
+		if err := store.Save(context.Background(), d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listed, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"update-b", "update-a"}, updateIDs(listed)); diff != "" {
+		t.Fatalf("listed updates:\n%s", diff)
+	}
+	if diff := cmp.Diff(first, listed[1]); diff != "" {
+		t.Fatalf("restored update:\n%s", diff)
+	}
+	if v := listed[0].Input.NewValue; v != json.Number("12345678901234567") {
+		t.Fatalf("got NewValue %#v", v)
+	}
+	if err := store.Delete(context.Background(), "update-a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(context.Background(), "update-a"); err != nil {
+		t.Fatalf("failed to delete a missing update: %s", err)
+	}
+}
+
+func TestFileStoreRejectsUnversionedUpdates(t *testing.T) {
+	store := makeFileStore(t)
+	unversioned := `{"ID":"update-a","NotBefore":"2020-06-01T00:00:00Z","Input":{"Repo":"testorg/testrepo","Filename":"test.yaml","Branch":"main","Key":"test.image","NewValue":"new-image"}}`
+	if err := ioutil.WriteFile(filepath.Join(store.dir, "update-a.json"), []byte(unversioned), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := store.List(context.Background())
+
+	if !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Fatalf("got %v, want ErrUnsupportedEncoding", err)
+	}
+}
+
+func TestFileStoreRejectsValidators(t *testing.T) {
+	input := makeInput()
+	input.Validator = acceptValidator{}
+
+	err := makeFileStore(t).Save(context.Background(), &DelayedUpdate{ID: "update-a", Input: input})
+
+	if !test.MatchError(t, "update update-a has a Validator or Encryption, which can't be persisted", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestFileStoreRejectsContentUpdaters(t *testing.T) {
+	input := makeInput()
+	input.Files = []FileChange{{Filename: testSecondFilePath, ContentUpdater: ReplaceContents([]byte("test"))}}
+
+	err := makeFileStore(t).Save(context.Background(), &DelayedUpdate{ID: "update-a", Input: input})
+
+	if !test.MatchError(t, "update update-a has a ContentUpdater, which can't be persisted", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestScheduleUpdateGeneratesUniqueIDs(t *testing.T) {
+	updater := New(zap.New(), mock.New(t), NameGenerator(stubNameGenerator{"a"}), WithStore(makeFileStore(t)))
+	ids := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		d, err := updater.ScheduleUpdate(context.Background(), makeInput(), time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !regexp.MustCompile(`^update-[0-9a-f]{32}$`).MatchString(d.ID) {
+			t.Fatalf("got ID %q", d.ID)
+		}
+		ids[d.ID] = true
+	}
+	if l := len(ids); l != 10 {
+		t.Fatalf("got %d unique IDs, want 10", l)
+	}
+}
+
+func TestFileStoreSaveDoesNotReplaceExistingUpdate(t *testing.T) {
+	store := makeFileStore(t)
+	first := &DelayedUpdate{ID: "update-a", Input: makeInput()}
+	if err := store.Save(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	second := makeInput()
+	second.Filename = testSecondFilePath
+
+	err := store.Save(context.Background(), &DelayedUpdate{ID: "update-a", Input: second})
+
+	if !test.MatchError(t, "update update-a already exists", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	listed, err := store.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(listed); l != 1 || listed[0].Input.Filename != testFilePath {
+		t.Fatalf("the existing update was replaced: %#v", listed)
+	}
+	tmps, err := filepath.Glob(filepath.Join(store.dir, ".*.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tmps) != 0 {
+		t.Fatalf("temporary files were left: %v", tmps)
+	}
+}
+
+func updateIDs(delayed []*DelayedUpdate) []string {
+	ids := []string{}
+	for _, d := range delayed {
+		ids = append(ids, d.ID)
+	}
+	return ids
+}
+
+type acceptValidator struct{}
+
+func (acceptValidator) Validate(interface{}) error {
+	return nil
+}
@@ -0,0 +1,573 @@
+package updater
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/syaml"
+)
+
+// EncodingVersion is the version of the documents written by Encode, it's
+// incremented when a change to the encoding can't be decoded by the previous
+// version, adding fields doesn't change the version, as unknown fields are
+// ignored.
+const EncodingVersion = 1
+
+// ErrUnsupportedEncoding is matched by the errors decoding documents with a
+// newer EncodingVersion, or of a different kind.
+var ErrUnsupportedEncoding = errors.New("unsupported encoding")
+
+// The kinds of documents written by Encode.
+const (
+	KindInput         = "Input"
+	KindUpdateResult  = "UpdateResult"
+	KindTrigger       = "Trigger"
+	KindDecision      = "Decision"
+	KindDelayedUpdate = "DelayedUpdate"
+)
+
+type document struct {
+	Version int             `json:"version"`
+	Kind    string          `json:"kind"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Encode returns the versioned JSON encoding of an *Input, *UpdateResult,
+// *Trigger, *Decision or *DelayedUpdate, for storing or sending to other
+// processes, e.g.
+//
+//	{"version":1,"kind":"Trigger","data":{"source":"manual","actor":"octocat"}}
+//
+// The fields are encoded with stable lowerCamelCase names, and enumerations
+// as strings, Inputs with a Validator, Encryption, ContentUpdater or
+// PostActions can't be encoded.
+//
+// Values are not masked with the SecretKeys, an encoded Input carries its
+// secret values, see UpdateResult.MarshalJSON for results.
+func Encode(v interface{}) ([]byte, error) {
+	kind, err := kindOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := encodable(v); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", kind, err)
+	}
+	return json.Marshal(document{Version: EncodingVersion, Kind: kind, Data: data})
+}
+
+// Decode decodes a document written by Encode, with this or an earlier
+// EncodingVersion, into v, which must be a pointer of the kind that was
+// encoded.
+//
+// Numbers in values are decoded as json.Number, so large integers are not
+// rounded.
+func Decode(b []byte, v interface{}) error {
+	kind, err := kindOf(v)
+	if err != nil {
+		return err
+	}
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", kind, err)
+	}
+	if doc.Version < 1 || doc.Version > EncodingVersion {
+		return fmt.Errorf("%w: version %d, the supported versions are 1 to %d", ErrUnsupportedEncoding, doc.Version, EncodingVersion)
+	}
+	if doc.Kind != kind {
+		return fmt.Errorf("%w: got kind %s, want %s", ErrUnsupportedEncoding, doc.Kind, kind)
+	}
+	if err := json.Unmarshal(doc.Data, v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", kind, err)
+	}
+	return nil
+}
+
+func kindOf(v interface{}) (string, error) {
+	switch v.(type) {
+	case *Input:
+		return KindInput, nil
+	case *UpdateResult:
+		return KindUpdateResult, nil
+	case *Trigger:
+		return KindTrigger, nil
+	case *Decision:
+		return KindDecision, nil
+	case *DelayedUpdate:
+		return KindDelayedUpdate, nil
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedEncoding, v)
+}
+
+// encodable returns an error if the Input, or the Input of a DelayedUpdate,
+// has fields that can't be encoded.
+func encodable(v interface{}) error {
+	input, ok := v.(*Input)
+	if d, isDelayed := v.(*DelayedUpdate); isDelayed {
+		input, ok = d.Input, true
+	}
+	if !ok || input == nil {
+		return nil
+	}
+	switch {
+	case input.Validator != nil || input.Encryption != nil:
+		return errors.New("an Input with a Validator or Encryption can't be encoded")
+	case len(input.PostActions) > 0:
+		return errors.New("an Input with PostActions can't be encoded")
+	case hasContentUpdater(input):
+		return errors.New("an Input with a ContentUpdater can't be encoded")
+	}
+	return nil
+}
+
+// These are the names of the enumerations, in the order of their values.
+var (
+	supersedePolicyNames = []string{"none", "close", "close-with-comment"}
+	noChangePolicyNames  = []string{"skip", "pull-request", "comment"}
+	diffStyleNames       = []string{"text", "semantic"}
+	updateStateNames     = []string{"unchanged", "committed", "pull-request-created", "skipped", "pull-request-updated", "failed"}
+)
+
+func encodeEnum(names []string, v int) string {
+	if v < 0 || v >= len(names) {
+		return fmt.Sprint(v)
+	}
+	return names[v]
+}
+
+// decodeEnum returns the value of the name, an empty name is the zero value.
+func decodeEnum(names []string, name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for i, n := range names {
+		if n == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown value %q, must be one of %q", name, names)
+}
+
+// unmarshalNumbers decodes the JSON into v, with numbers decoded as
+// json.Number.
+func unmarshalNumbers(b []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// rawYAML returns true if the value is a syaml.RawYAML, which is encoded as a
+// string.
+func rawYAML(v interface{}) bool {
+	_, ok := v.(syaml.RawYAML)
+	return ok
+}
+
+// decodeValue restores a value encoded as a string from a syaml.RawYAML.
+func decodeValue(v interface{}, raw bool) interface{} {
+	if s, ok := v.(string); ok && raw {
+		return syaml.RawYAML(s)
+	}
+	return v
+}
+
+type encodedTrigger struct {
+	Source     TriggerSource `json:"source"`
+	Image      string        `json:"image,omitempty"`
+	Tag        string        `json:"tag,omitempty"`
+	Digest     string        `json:"digest,omitempty"`
+	Commit     string        `json:"commit,omitempty"`
+	Actor      string        `json:"actor,omitempty"`
+	PayloadRef string        `json:"payloadRef,omitempty"`
+	RunURL     string        `json:"runURL,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t Trigger) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodedTrigger(t))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *Trigger) UnmarshalJSON(b []byte) error {
+	var e encodedTrigger
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	*t = Trigger(e)
+	return nil
+}
+
+type encodedSource struct {
+	Repo     string `json:"repo"`
+	Branch   string `json:"branch"`
+	Filename string `json:"filename"`
+}
+
+type encodedKeyValue struct {
+	Key      string      `json:"key"`
+	NewValue interface{} `json:"newValue"`
+	RawYAML  bool        `json:"rawYAML,omitempty"`
+}
+
+type encodedFileChange struct {
+	Filename string      `json:"filename"`
+	Key      string      `json:"key,omitempty"`
+	NewValue interface{} `json:"newValue,omitempty"`
+	RawYAML  bool        `json:"rawYAML,omitempty"`
+	Format   string      `json:"format,omitempty"`
+}
+
+type encodedPullRequestInput struct {
+	SourceBranch  string   `json:"sourceBranch,omitempty"`
+	NewBranch     string   `json:"newBranch,omitempty"`
+	Repo          string   `json:"repo,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	Body          string   `json:"body,omitempty"`
+	IncludeDiff   bool     `json:"includeDiff,omitempty"`
+	DiffStyle     string   `json:"diffStyle,omitempty"`
+	MaxDiffLines  int      `json:"maxDiffLines,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	Reviewers     []string `json:"reviewers,omitempty"`
+	TeamReviewers []string `json:"teamReviewers,omitempty"`
+	Assignees     []string `json:"assignees,omitempty"`
+	Draft         bool     `json:"draft,omitempty"`
+	AutoMerge     string   `json:"autoMerge,omitempty"`
+	Milestone     string   `json:"milestone,omitempty"`
+	Project       string   `json:"project,omitempty"`
+	ProjectColumn string   `json:"projectColumn,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+	CodeOwners    bool     `json:"codeOwners,omitempty"`
+}
+
+type encodedInput struct {
+	Repo               string                  `json:"repo"`
+	Filename           string                  `json:"filename,omitempty"`
+	Branch             string                  `json:"branch"`
+	BaseBranch         string                  `json:"baseBranch,omitempty"`
+	Fork               string                  `json:"fork,omitempty"`
+	Key                string                  `json:"key,omitempty"`
+	Format             string                  `json:"format,omitempty"`
+	NewValue           interface{}             `json:"newValue"`
+	RawYAML            bool                    `json:"rawYAML,omitempty"`
+	BranchGenerateName string                  `json:"branchGenerateName,omitempty"`
+	NewBranchName      string                  `json:"newBranchName,omitempty"`
+	ResetBranch        bool                    `json:"resetBranch,omitempty"`
+	ReusePullRequest   bool                    `json:"reusePullRequest,omitempty"`
+	Supersede          string                  `json:"supersede,omitempty"`
+	CommitMessage      string                  `json:"commitMessage,omitempty"`
+	PullRequest        encodedPullRequestInput `json:"pullRequest"`
+	NoChange           string                  `json:"noChange,omitempty"`
+	TrackingIssue      int                     `json:"trackingIssue,omitempty"`
+	Trigger            *Trigger                `json:"trigger,omitempty"`
+	StrictPaths        bool                    `json:"strictPaths,omitempty"`
+	EnsurePath         bool                    `json:"ensurePath,omitempty"`
+	ExpectedValue      *string                 `json:"expectedValue,omitempty"`
+	ExpectedPattern    string                  `json:"expectedPattern,omitempty"`
+	SkipOnMismatch     bool                    `json:"skipOnMismatch,omitempty"`
+	Source             *encodedSource          `json:"source,omitempty"`
+	Values             []encodedKeyValue       `json:"values,omitempty"`
+	Files              []encodedFileChange     `json:"files,omitempty"`
+	Delete             bool                    `json:"delete,omitempty"`
+	SecretKeys         []string                `json:"secretKeys,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, the Validator,
+// Encryption, ContentUpdaters and PostActions are not encoded.
+func (i Input) MarshalJSON() ([]byte, error) {
+	pr := i.PullRequest
+	e := encodedInput{
+		Repo:               i.Repo,
+		Filename:           i.Filename,
+		Branch:             i.Branch,
+		BaseBranch:         i.BaseBranch,
+		Fork:               i.Fork,
+		Key:                i.Key,
+		Format:             i.Format,
+		NewValue:           i.NewValue,
+		RawYAML:            rawYAML(i.NewValue),
+		BranchGenerateName: i.BranchGenerateName,
+		NewBranchName:      i.NewBranchName,
+		ResetBranch:        i.ResetBranch,
+		ReusePullRequest:   i.ReusePullRequest,
+		Supersede:          encodeEnum(supersedePolicyNames, int(i.Supersede)),
+		CommitMessage:      i.CommitMessage,
+		PullRequest: encodedPullRequestInput{
+			SourceBranch:  pr.SourceBranch,
+			NewBranch:     pr.NewBranch,
+			Repo:          pr.Repo,
+			Title:         pr.Title,
+			Body:          pr.Body,
+			IncludeDiff:   pr.IncludeDiff,
+			DiffStyle:     encodeEnum(diffStyleNames, int(pr.DiffStyle)),
+			MaxDiffLines:  pr.MaxDiffLines,
+			Labels:        pr.Labels,
+			Reviewers:     pr.Reviewers,
+			TeamReviewers: pr.TeamReviewers,
+			Assignees:     pr.Assignees,
+			Draft:         pr.Draft,
+			AutoMerge:     pr.AutoMerge,
+			Milestone:     pr.Milestone,
+			Project:       pr.Project,
+			ProjectColumn: pr.ProjectColumn,
+			Comment:       pr.Comment,
+			CodeOwners:    pr.CodeOwners,
+		},
+		NoChange:        encodeEnum(noChangePolicyNames, int(i.NoChange)),
+		TrackingIssue:   i.TrackingIssue,
+		Trigger:         i.Trigger,
+		StrictPaths:     i.StrictPaths,
+		EnsurePath:      i.EnsurePath,
+		ExpectedValue:   i.ExpectedValue,
+		ExpectedPattern: i.ExpectedPattern,
+		SkipOnMismatch:  i.SkipOnMismatch,
+		Delete:          i.Delete,
+		SecretKeys:      i.SecretKeys,
+	}
+	if i.Source != nil {
+		s := encodedSource(*i.Source)
+		e.Source = &s
+	}
+	for _, v := range i.Values {
+		e.Values = append(e.Values, encodedKeyValue{Key: v.Key, NewValue: v.NewValue, RawYAML: rawYAML(v.NewValue)})
+	}
+	for _, f := range i.Files {
+		e.Files = append(e.Files, encodedFileChange{Filename: f.Filename, Key: f.Key, NewValue: f.NewValue, RawYAML: rawYAML(f.NewValue), Format: f.Format})
+	}
+	return json.Marshal(e)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (i *Input) UnmarshalJSON(b []byte) error {
+	var e encodedInput
+	if err := unmarshalNumbers(b, &e); err != nil {
+		return err
+	}
+	supersede, err := decodeEnum(supersedePolicyNames, e.Supersede)
+	if err != nil {
+		return fmt.Errorf("failed to decode supersede: %w", err)
+	}
+	noChange, err := decodeEnum(noChangePolicyNames, e.NoChange)
+	if err != nil {
+		return fmt.Errorf("failed to decode noChange: %w", err)
+	}
+	diffStyle, err := decodeEnum(diffStyleNames, e.PullRequest.DiffStyle)
+	if err != nil {
+		return fmt.Errorf("failed to decode diffStyle: %w", err)
+	}
+	pr := e.PullRequest
+	*i = Input{
+		Repo:               e.Repo,
+		Filename:           e.Filename,
+		Branch:             e.Branch,
+		BaseBranch:         e.BaseBranch,
+		Fork:               e.Fork,
+		Key:                e.Key,
+		Format:             e.Format,
+		NewValue:           decodeValue(e.NewValue, e.RawYAML),
+		BranchGenerateName: e.BranchGenerateName,
+		NewBranchName:      e.NewBranchName,
+		ResetBranch:        e.ResetBranch,
+		ReusePullRequest:   e.ReusePullRequest,
+		Supersede:          SupersedePolicy(supersede),
+		CommitMessage:      e.CommitMessage,
+		PullRequest: PullRequestInput{
+			SourceBranch:  pr.SourceBranch,
+			NewBranch:     pr.NewBranch,
+			Repo:          pr.Repo,
+			Title:         pr.Title,
+			Body:          pr.Body,
+			IncludeDiff:   pr.IncludeDiff,
+			DiffStyle:     DiffStyle(diffStyle),
+			MaxDiffLines:  pr.MaxDiffLines,
+			Labels:        pr.Labels,
+			Reviewers:     pr.Reviewers,
+			TeamReviewers: pr.TeamReviewers,
+			Assignees:     pr.Assignees,
+			Draft:         pr.Draft,
+			AutoMerge:     pr.AutoMerge,
+			Milestone:     pr.Milestone,
+			Project:       pr.Project,
+			ProjectColumn: pr.ProjectColumn,
+			Comment:       pr.Comment,
+			CodeOwners:    pr.CodeOwners,
+		},
+		NoChange:        NoChangePolicy(noChange),
+		TrackingIssue:   e.TrackingIssue,
+		Trigger:         e.Trigger,
+		StrictPaths:     e.StrictPaths,
+		EnsurePath:      e.EnsurePath,
+		ExpectedValue:   e.ExpectedValue,
+		ExpectedPattern: e.ExpectedPattern,
+		SkipOnMismatch:  e.SkipOnMismatch,
+		Delete:          e.Delete,
+		SecretKeys:      e.SecretKeys,
+	}
+	if e.Source != nil {
+		s := SourceFile(*e.Source)
+		i.Source = &s
+	}
+	for _, v := range e.Values {
+		i.Values = append(i.Values, KeyValue{Key: v.Key, NewValue: decodeValue(v.NewValue, v.RawYAML)})
+	}
+	for _, f := range e.Files {
+		i.Files = append(i.Files, FileChange{Filename: f.Filename, Key: f.Key, NewValue: decodeValue(f.NewValue, f.RawYAML), Format: f.Format})
+	}
+	return nil
+}
+
+// encodedPullRequest is the identifying subset of an scm.PullRequest.
+type encodedPullRequest struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title,omitempty"`
+	Link     string `json:"link,omitempty"`
+	Source   string `json:"source,omitempty"`
+	Target   string `json:"target,omitempty"`
+	Sha      string `json:"sha,omitempty"`
+	Draft    bool   `json:"draft,omitempty"`
+	Closed   bool   `json:"closed,omitempty"`
+	Merged   bool   `json:"merged,omitempty"`
+	MergeSha string `json:"mergeSha,omitempty"`
+}
+
+func encodePullRequest(pr *scm.PullRequest) *encodedPullRequest {
+	if pr == nil {
+		return nil
+	}
+	return &encodedPullRequest{Number: pr.Number, Title: pr.Title, Link: pr.Link, Source: pr.Source, Target: pr.Target, Sha: pr.Sha, Draft: pr.Draft, Closed: pr.Closed, Merged: pr.Merged, MergeSha: pr.MergeSha}
+}
+
+func (e *encodedPullRequest) pullRequest() *scm.PullRequest {
+	if e == nil {
+		return nil
+	}
+	return &scm.PullRequest{Number: e.Number, Title: e.Title, Link: e.Link, Source: e.Source, Target: e.Target, Sha: e.Sha, Draft: e.Draft, Closed: e.Closed, Merged: e.Merged, MergeSha: e.MergeSha}
+}
+
+type encodedPostActionError struct {
+	Action string `json:"action,omitempty"`
+	Error  string `json:"error"`
+}
+
+type encodedResult struct {
+	State            string                   `json:"state"`
+	Branch           string                   `json:"branch,omitempty"`
+	PullRequest      *encodedPullRequest      `json:"pullRequest,omitempty"`
+	Commit           string                   `json:"commit,omitempty"`
+	Base             string                   `json:"base,omitempty"`
+	Previous         *string                  `json:"previous,omitempty"`
+	NewValue         interface{}              `json:"newValue,omitempty"`
+	RawYAML          bool                     `json:"rawYAML,omitempty"`
+	Content          []byte                   `json:"content,omitempty"`
+	Source           *encodedSource           `json:"source,omitempty"`
+	Superseded       []*encodedPullRequest    `json:"superseded,omitempty"`
+	PostActionErrors []encodedPostActionError `json:"postActionErrors,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface, only the identifying
+// fields of the PullRequests are encoded, and the PostActionErrors are
+// encoded as their messages.
+//
+// The NewValue, Previous and Content are encoded as they are in the result,
+// without SecretKeys masking, the results of the Updater are already masked,
+// with the NewValue redacted, the Previous value hashed, and no Content for
+// files with secret values, but results built elsewhere carry any secrets.
+func (r UpdateResult) MarshalJSON() ([]byte, error) {
+	e := encodedResult{
+		State:       encodeEnum(updateStateNames, int(r.State)),
+		Branch:      r.Branch,
+		PullRequest: encodePullRequest(r.PullRequest),
+		Commit:      r.Commit,
+		Base:        r.Base,
+		Previous:    r.Previous,
+		NewValue:    r.NewValue,
+		RawYAML:     rawYAML(r.NewValue),
+		Content:     r.Content,
+	}
+	if r.Source != nil {
+		s := encodedSource(*r.Source)
+		e.Source = &s
+	}
+	for _, pr := range r.Superseded {
+		e.Superseded = append(e.Superseded, encodePullRequest(pr))
+	}
+	for _, err := range r.PostActionErrors {
+		encoded := encodedPostActionError{Error: err.Error()}
+		var actionErr *PostActionError
+		if errors.As(err, &actionErr) {
+			encoded = encodedPostActionError{Action: actionErr.Action, Error: actionErr.Err.Error()}
+		}
+		e.PostActionErrors = append(e.PostActionErrors, encoded)
+	}
+	return json.Marshal(e)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, PostActionErrors
+// are decoded as PostActionErrors with the messages.
+func (r *UpdateResult) UnmarshalJSON(b []byte) error {
+	var e encodedResult
+	if err := unmarshalNumbers(b, &e); err != nil {
+		return err
+	}
+	state, err := decodeEnum(updateStateNames, e.State)
+	if err != nil {
+		return fmt.Errorf("failed to decode state: %w", err)
+	}
+	*r = UpdateResult{
+		State:       UpdateState(state),
+		Branch:      e.Branch,
+		PullRequest: e.PullRequest.pullRequest(),
+		Commit:      e.Commit,
+		Base:        e.Base,
+		Previous:    e.Previous,
+		NewValue:    decodeValue(e.NewValue, e.RawYAML),
+		Content:     e.Content,
+	}
+	if e.Source != nil {
+		s := SourceFile(*e.Source)
+		r.Source = &s
+	}
+	for _, pr := range e.Superseded {
+		r.Superseded = append(r.Superseded, pr.pullRequest())
+	}
+	for _, pe := range e.PostActionErrors {
+		r.PostActionErrors = append(r.PostActionErrors, &PostActionError{Action: pe.Action, Err: errors.New(pe.Error)})
+	}
+	return nil
+}
+
+type encodedDecision struct {
+	Time        time.Time      `json:"time"`
+	Reason      DecisionReason `json:"reason"`
+	Rule        string         `json:"rule,omitempty"`
+	Repo        string         `json:"repo,omitempty"`
+	Branch      string         `json:"branch,omitempty"`
+	Filename    string         `json:"filename,omitempty"`
+	Key         string         `json:"key,omitempty"`
+	Trigger     *Trigger       `json:"trigger,omitempty"`
+	State       string         `json:"state,omitempty"`
+	PullRequest int            `json:"pullRequest,omitempty"`
+	Detail      string         `json:"detail,omitempty"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Decision) MarshalJSON() ([]byte, error) {
+	return json.Marshal(encodedDecision(d))
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Decision) UnmarshalJSON(b []byte) error {
+	var e encodedDecision
+	if err := json.Unmarshal(b, &e); err != nil {
+		return err
+	}
+	*d = Decision(e)
+	return nil
+}
@@ -0,0 +1,182 @@
+package updater
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/syaml"
+	"github.com/agill17/pkg/test"
+)
+
+func TestEncodeInput(t *testing.T) {
+	expected := "old-image"
+	input := makeInput()
+	input.NewValue = syaml.RawYAML("limits:\n  cpu: 500m\n")
+	input.Supersede = SupersedeCloseWithComment
+	input.NoChange = NoChangeComment
+	input.TrackingIssue = 12
+	input.ExpectedValue = &expected
+	input.Trigger = &Trigger{Source: TriggerRegistry, Image: "quay.io/my-org/my-image", Tag: "v1.4.2", RunURL: "https://ci.example.com/runs/1234"}
+	input.Source = &SourceFile{Repo: "my-org/templates", Branch: "main", Filename: "base.yaml"}
+	input.Values = []KeyValue{{Key: "test.replicas", NewValue: json.Number("12345678901234567")}}
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+	input.PullRequest.IncludeDiff, input.PullRequest.DiffStyle = true, SemanticDiff
+	input.PullRequest.Labels = []string{"automated"}
+
+	b, err := Encode(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Input{}
+	if err := Decode(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(input, decoded); diff != "" {
+		t.Fatalf("decoded input:\n%s", diff)
+	}
+}
+
+func TestEncodeTrigger(t *testing.T) {
+	b, err := Encode(&Trigger{Source: TriggerManual, Actor: "octocat"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"version":1,"kind":"Trigger","data":{"source":"manual","actor":"octocat"}}`
+	if string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+}
+
+func TestEncodeUpdateResult(t *testing.T) {
+	previous := "old-image"
+	r := &UpdateResult{
+		State:            PullRequestCreated,
+		Branch:           "test-branch-a",
+		PullRequest:      &scm.PullRequest{Number: 1, Title: "This is a test PR", Link: "https://github.com/testorg/testrepo/pull/1", Source: "test-branch-a", Target: testBranch},
+		Commit:           "980a0d5f19a64b4b30a87d4206aade58726b60e3",
+		Previous:         &previous,
+		NewValue:         "new-image",
+		Content:          []byte("test:\n  image: new-image\n"),
+		Superseded:       []*scm.PullRequest{{Number: 3, Closed: true}},
+		PostActionErrors: []error{&PostActionError{Action: "add-labels", Err: errors.New("failed to add labels")}},
+	}
+
+	b, err := Encode(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &UpdateResult{}
+	if err := Decode(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(r, decoded, cmp.Comparer(func(x, y error) bool { return x.Error() == y.Error() })); diff != "" {
+		t.Fatalf("decoded result:\n%s", diff)
+	}
+}
+
+func TestEncodeUpdateResultWithSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.SecretKeys = []string{`^test\.image$`}
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Encode(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"old-image", "new-image"} {
+		if strings.Contains(string(b), secret) {
+			t.Fatalf("encoded result contains %q: %s", secret, b)
+		}
+	}
+	decoded := &UpdateResult{}
+	if err := Decode(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.NewValue != syaml.Redacted || decoded.Content != nil {
+		t.Fatalf("got NewValue %#v and Content %q", decoded.NewValue, decoded.Content)
+	}
+}
+
+func TestEncodeDecision(t *testing.T) {
+	d := &Decision{Time: time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC), Reason: DecisionApplied, Repo: testGitHubRepo, State: "committed"}
+
+	b, err := Encode(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := &Decision{}
+	if err := Decode(b, decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(d, decoded); diff != "" {
+		t.Fatalf("decoded decision:\n%s", diff)
+	}
+}
+
+func TestEncodeRejectsPostActions(t *testing.T) {
+	input := makeInput()
+	input.PostActions = []PostAction{AddLabels("automated")}
+
+	_, err := Encode(input)
+
+	if !test.MatchError(t, "an Input with PostActions can't be encoded", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	decodeTests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"newer version", `{"version":2,"kind":"Input","data":{}}`, "unsupported encoding: version 2, the supported versions are 1 to 1"},
+		{"unversioned", `{"Repo":"testorg/testrepo"}`, "unsupported encoding: version 0"},
+		{"different kind", `{"version":1,"kind":"Trigger","data":{}}`, "unsupported encoding: got kind Trigger, want Input"},
+		{"unknown enumeration", `{"version":1,"kind":"Input","data":{"supersede":"reopen"}}`, `failed to decode supersede: unknown value "reopen"`},
+	}
+
+	for _, tt := range decodeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := Decode([]byte(tt.doc), &Input{})
+
+			if !test.MatchError(rt, tt.want, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestDecodeIgnoresUnknownFields(t *testing.T) {
+	decoded := &Trigger{}
+
+	err := Decode([]byte(`{"version":1,"kind":"Trigger","data":{"source":"poll","interval":"5m"},"signature":"abc"}`), decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(&Trigger{Source: TriggerPoll}, decoded); diff != "" {
+		t.Fatalf("decoded trigger:\n%s", diff)
+	}
+}
@@ -0,0 +1,50 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Encryption decrypts and re-encrypts files that are encrypted in the repo,
+// e.g. with SOPS, so that keys can be updated in the plaintext.
+//
+// Implementations can wrap the sops library or CLI.
+type Encryption interface {
+	// Decrypt returns the plaintext of the encrypted file.
+	Decrypt(ctx context.Context, filename string, encrypted []byte) ([]byte, error)
+	// Encrypt encrypts the updated plaintext, with the same recipients as the
+	// original encrypted file.
+	Encrypt(ctx context.Context, filename string, plaintext, original []byte) ([]byte, error)
+}
+
+var errUnencrypted = errors.New("encryption returned the plaintext")
+
+// decrypt returns the plaintext of the file if the Input is encrypted.
+func (i *Input) decrypt(ctx context.Context, b []byte) ([]byte, error) {
+	if i.Encryption == nil {
+		return b, nil
+	}
+	plaintext, err := i.Encryption.Decrypt(ctx, i.Filename, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt file %s: %w", i.Filename, err)
+	}
+	return plaintext, nil
+}
+
+// encrypt re-encrypts the updated plaintext if the Input is encrypted, the
+// result is checked so that plaintext is never committed.
+func (i *Input) encrypt(ctx context.Context, plaintext, original []byte) ([]byte, error) {
+	if i.Encryption == nil {
+		return plaintext, nil
+	}
+	encrypted, err := i.Encryption.Encrypt(ctx, i.Filename, plaintext, original)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt file %s: %w", i.Filename, err)
+	}
+	if bytes.Equal(encrypted, plaintext) {
+		return nil, fmt.Errorf("failed to encrypt file %s: %w", i.Filename, errUnencrypted)
+	}
+	return encrypted, nil
+}
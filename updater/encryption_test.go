@@ -0,0 +1,202 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+// fakeEncryption "encrypts" files by base64 encoding them with a recipient
+// prefix.
+type fakeEncryption struct {
+	plaintext bool
+}
+
+func (f fakeEncryption) Decrypt(ctx context.Context, filename string, encrypted []byte) ([]byte, error) {
+	parts := bytes.SplitN(encrypted, []byte(":"), 2)
+	if len(parts) != 2 {
+		return nil, errors.New("file is not encrypted")
+	}
+	return base64.StdEncoding.DecodeString(string(parts[1]))
+}
+
+func (f fakeEncryption) Encrypt(ctx context.Context, filename string, plaintext, original []byte) ([]byte, error) {
+	if f.plaintext {
+		return plaintext, nil
+	}
+	recipient := bytes.SplitN(original, []byte(":"), 2)[0]
+	return []byte(string(recipient) + ":" + base64.StdEncoding.EncodeToString(plaintext)), nil
+}
+
+func encrypt(recipient, s string) []byte {
+	return []byte(recipient + ":" + base64.StdEncoding.EncodeToString([]byte(s)))
+}
+
+func TestUpdateYAMLWithEncryption(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+	input.PullRequest.IncludeDiff = true
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := encrypt("age1test", "test:\n  image: new-image\n")
+	if updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"); !bytes.Equal(updated, want) {
+		t.Fatalf("update failed, got %s, want %s", updated, want)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body + "\n\n`" + testFilePath + "` is encrypted, the change is not shown.",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateYAMLWithEncryptionAndNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: new-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+
+	pr, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr != nil {
+		t.Fatalf("got PullRequest %v, want nil", pr)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateYAMLWithEncryptionErrors(t *testing.T) {
+	encryptionTests := []struct {
+		name       string
+		contents   []byte
+		encryption Encryption
+		wantErr    string
+	}{
+		{"decryption fails", []byte("plaintext"), fakeEncryption{}, "failed to decrypt file .*: file is not encrypted"},
+		{"encryption returns plaintext", encrypt("age1test", "test:\n  image: old-image\n"), fakeEncryption{plaintext: true}, "failed to encrypt file .*: encryption returned the plaintext"},
+	}
+
+	for _, tt := range encryptionTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, tt.contents)
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.Encryption = tt.encryption
+
+			_, err := updater.UpdateYAML(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestUpdateBatchWithEncryption(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.Encryption, second.Encryption = fakeEncryption{}, fakeEncryption{}
+	second.Key, second.NewValue = "test.tag", "v2"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := encrypt("age1test", "test:\n  image: new-image\n  tag: v2\n")
+	if updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"); !bytes.Equal(updated, want) {
+		t.Fatalf("update failed, got %s, want %s", updated, want)
+	}
+}
+
+func TestUpdateYAMLWithEncryptionMasksValues(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+	input.CommitMessage = "Update {{ .Key }} from {{ .Previous }} to {{ .NewValue }}"
+	input.PullRequest.Body = "Updated from {{ .Previous }}"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Update test.image from REDACTED to REDACTED")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  "Updated from REDACTED",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if r.Previous == nil || *r.Previous == "old-image" {
+		t.Fatalf("got Previous %v, want a hash", r.Previous)
+	}
+}
+
+func TestUpdateYAMLWithEncryptionMasksErrors(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+	expected := "other-image"
+	input.ExpectedValue = &expected
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "test.image is a secret, the values are not shown", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if strings.Contains(err.Error(), "old-image") {
+		t.Fatalf("error %q shows the decrypted value", err)
+	}
+}
+
+func TestUpdateYAMLWithEncryptionAndNoChangeComment(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, encrypt("age1test", "test:\n  image: new-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Encryption = fakeEncryption{}
+	input.NoChange, input.TrackingIssue = NoChangeComment, 12
+
+	_, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommentCreated(testGitHubRepo, 12, "No change required, test.image in "+testFilePath+" already has the value REDACTED.")
+}
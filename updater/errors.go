@@ -0,0 +1,94 @@
+package updater
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/agill17/pkg/client"
+)
+
+var (
+	// ErrFileNotFound is matched by the errors when a file to update, or
+	// read from, doesn't exist in the repo.
+	ErrFileNotFound = errors.New("file not found")
+	// ErrBranchExists is matched by the errors when the branch to create
+	// already exists in the repo.
+	ErrBranchExists = errors.New("branch already exists")
+	// ErrPermissionDenied is matched by the errors when the git provider
+	// rejects a request as unauthorized or forbidden.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrBranchProtected is matched by the errors when the git provider
+	// rejects a direct commit to a branch, e.g. as its protection rules
+	// require PullRequests.
+	ErrBranchProtected = errors.New("branch is protected")
+	// ErrNoChange is matched by the errors when an update leaves the file
+	// unchanged, and no branch or commit is created.
+	ErrNoChange = errors.New("no change required")
+)
+
+// SCMError is returned when a request to the git provider fails.
+//
+// It unwraps to the error from the GitClient, and where the response status,
+// or for protected branches its message, identifies the failure, it matches
+// ErrFileNotFound, ErrBranchExists, ErrBranchProtected or ErrPermissionDenied
+// with errors.Is.
+type SCMError struct {
+	Op     string // e.g. create branch update-image-abcde
+	Repo   string
+	Status int // The status code of the response, or 0 if there wasn't one
+	Err    error
+	kind   error
+}
+
+func (e *SCMError) Error() string {
+	return "failed to " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error from the GitClient.
+func (e *SCMError) Unwrap() error {
+	return e.Err
+}
+
+// Is returns true if the target is the sentinel error for the failure.
+func (e *SCMError) Is(target error) bool {
+	return e.kind != nil && target == e.kind
+}
+
+// scmError wraps the error from the GitClient in an SCMError, the kinds map
+// the response status codes of the operation to sentinel errors, a nil error
+// is returned as nil.
+func scmError(err error, repo, op string, kinds map[int]error) error {
+	if err == nil {
+		return nil
+	}
+	e := &SCMError{Op: op, Repo: repo, Status: client.StatusCode(err), Err: err}
+	switch e.Status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		e.kind = ErrPermissionDenied
+	default:
+		e.kind = kinds[e.Status]
+	}
+	return e
+}
+
+var (
+	fileKinds   = map[int]error{http.StatusNotFound: ErrFileNotFound}
+	branchKinds = map[int]error{http.StatusConflict: ErrBranchExists, http.StatusUnprocessableEntity: ErrBranchExists}
+	// GitHub rejects commits to protected branches with a conflict, or with
+	// a validation failure for repository rules.
+	protectedKinds = map[int]error{http.StatusConflict: ErrBranchProtected, http.StatusUnprocessableEntity: ErrBranchProtected}
+)
+
+// GitHub also rejects commits with a conflict when the SHA of the file is
+// stale, protection is identified by the message in the response.
+var protectedMessage = regexp.MustCompile(`(?i)protected branch|through a pull request|repository rule violations`)
+
+// commitKinds returns the kinds of the failures to commit a file, only direct
+// commits to the branch can be rejected by its protection.
+func commitKinds(commit CommitInput, err error) map[int]error {
+	if commit.newBranch() || !protectedMessage.MatchString(client.ResponseMessage(err)) {
+		return nil
+	}
+	return protectedKinds
+}
@@ -0,0 +1,131 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestSCMErrorMatches(t *testing.T) {
+	errorTests := []struct {
+		name  string
+		err   error
+		kinds map[int]error
+		want  error
+	}{
+		{"file not found", client.NewNotFoundError("not found"), fileKinds, ErrFileNotFound},
+		{"branch exists", client.NewStatusError("exists", http.StatusUnprocessableEntity), branchKinds, ErrBranchExists},
+		{"branch conflict", client.NewStatusError("exists", http.StatusConflict), branchKinds, ErrBranchExists},
+		{"branch protected", client.NewStatusError("protected", http.StatusConflict), protectedKinds, ErrBranchProtected},
+		{"unauthorized", client.NewStatusError("unauthorized", http.StatusUnauthorized), nil, ErrPermissionDenied},
+		{"forbidden", client.NewStatusError("forbidden", http.StatusForbidden), fileKinds, ErrPermissionDenied},
+		{"other status", client.NewStatusError("failed", http.StatusInternalServerError), fileKinds, nil},
+		{"no status", errors.New("unavailable"), fileKinds, nil},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := scmError(tt.err, testGitHubRepo, "do something", tt.kinds)
+
+			for _, sentinel := range []error{ErrFileNotFound, ErrBranchExists, ErrBranchProtected, ErrPermissionDenied} {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					rt.Errorf("errors.Is(%v) got %v", sentinel, got)
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				rt.Errorf("failed to unwrap %v", tt.err)
+			}
+			if !test.MatchError(rt, "failed to do something: ", err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestCommitKinds(t *testing.T) {
+	direct := CommitInput{Repo: testGitHubRepo, Branch: testBranch}
+	kindTests := []struct {
+		name   string
+		commit CommitInput
+		err    error
+		want   error
+	}{
+		{"protected branch", direct, client.NewResponseError("failed", http.StatusConflict, "Changes must be made through a pull request."), ErrBranchProtected},
+		{"repository rules", direct, client.NewResponseError("failed", http.StatusUnprocessableEntity, "Repository rule violations found"), ErrBranchProtected},
+		{"stale file", direct, client.NewResponseError("failed", http.StatusConflict, "config/test.yaml does not match 980a0d5f"), nil},
+		{"no message", direct, client.NewStatusError("failed", http.StatusConflict), nil},
+		{"new branch", CommitInput{Repo: testGitHubRepo, Branch: testBranch, BranchGenerateName: "test-"}, client.NewResponseError("failed", http.StatusConflict, "Changes must be made through a pull request."), nil},
+	}
+
+	for _, tt := range kindTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := scmError(tt.err, testGitHubRepo, "update file", commitKinds(tt.commit, tt.err))
+
+			if got := errors.Is(err, ErrBranchProtected); got != (tt.want != nil) {
+				rt.Fatalf("errors.Is(%v) got %v", ErrBranchProtected, got)
+			}
+		})
+	}
+}
+
+func TestSCMErrorWithNoError(t *testing.T) {
+	if err := scmError(nil, testGitHubRepo, "do something", nil); err != nil {
+		t.Fatalf("got %v, want nil", err)
+	}
+}
+
+func TestUpdateWithMissingFile(t *testing.T) {
+	m := mock.New(t)
+	m.AddMissingFile(testGitHubRepo, testFilePath, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.Update(context.Background(), makeInput())
+
+	if !errors.Is(err, ErrFileNotFound) {
+		t.Fatalf("got %v, want %v", err, ErrFileNotFound)
+	}
+	var scmErr *SCMError
+	if !errors.As(err, &scmErr) {
+		t.Fatalf("got %T, want an SCMError", err)
+	}
+	if scmErr.Repo != testGitHubRepo || scmErr.Status != http.StatusNotFound {
+		t.Fatalf("got repo %s and status %d, want %s and %d", scmErr.Repo, scmErr.Status, testGitHubRepo, http.StatusNotFound)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestUpdateWithExistingBranch(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.CreateBranchErr = client.NewStatusError("Reference already exists", http.StatusUnprocessableEntity)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.Update(context.Background(), makeInput())
+
+	if !errors.Is(err, ErrBranchExists) {
+		t.Fatalf("got %v, want %v", err, ErrBranchExists)
+	}
+	if !test.MatchError(t, "failed to create branch: Reference already exists", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUpdateWithPermissionDenied(t *testing.T) {
+	m := mock.New(t)
+	m.GetFileErr = client.NewStatusError("forbidden", http.StatusForbidden)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	_, err := updater.Update(context.Background(), makeInput())
+
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("got %v, want %v", err, ErrPermissionDenied)
+	}
+}
@@ -0,0 +1,31 @@
+package updater
+
+import (
+	"github.com/agill17/pkg/features"
+)
+
+// HashedBranchNamesFeature gates the behaviour of the HashedBranchNames
+// option, so it can be enabled per deployment.
+const HashedBranchNamesFeature features.Feature = "HashedBranchNames"
+
+// KnownFeatures returns the experimental features of the Updater, and their
+// defaults, for creating the Gates.
+func KnownFeatures() map[features.Feature]bool {
+	return map[features.Feature]bool{
+		HashedBranchNamesFeature: false,
+	}
+}
+
+// FeatureGates is an option func for the Updater creation function, the
+// experimental features enabled in the Gates are used, as if configured with
+// their options.
+func FeatureGates(g *features.Gates) UpdaterFunc {
+	return func(u *Updater) {
+		u.gates = g
+	}
+}
+
+// enabled returns true if the feature is enabled in the Updater's Gates.
+func (u *Updater) enabled(f features.Feature) bool {
+	return u.gates.Enabled(f)
+}
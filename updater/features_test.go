@@ -0,0 +1,44 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/features"
+)
+
+func TestUpdateWithFeatureGates(t *testing.T) {
+	gateTests := []struct {
+		name       string
+		gates      string
+		wantBranch string
+	}{
+		{"disabled", "", "test-branch-a"},
+		{"hashed branch names", "HashedBranchNames=true", testHashedBranch},
+	}
+
+	for _, tt := range gateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			gates := features.New(KnownFeatures())
+			if err := gates.Parse(tt.gates); err != nil {
+				rt.Fatal(err)
+			}
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), FeatureGates(gates))
+
+			r, err := updater.Update(context.Background(), makeInput())
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r.Branch != tt.wantBranch {
+				rt.Errorf("got branch %q, want %q", r.Branch, tt.wantBranch)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/agill17/pkg/client"
+)
+
+// KeyValue is a further key to set in the Input's file.
+type KeyValue struct {
+	Key      string      // e.g. metadata.annotations.checksum
+	NewValue interface{} // e.g. 7e5f8c1
+}
+
+// FileChange is a change to a further file in the Input's repo, committed to
+// the same branch, and included in the same PullRequest as the Input.
+type FileChange struct {
+	Filename string      // relative path to the file in the repository
+	Key      string      // e.g. spec.template.spec.containers.0.image
+	NewValue interface{} // e.g. my-org/my-image:v2
+	Format   string      // The format of the file, this defaults to the Format of the Input
+	// ContentUpdater transforms the file, rather than setting the Key, e.g.
+	// a TransformUpdater.
+	ContentUpdater ContentUpdater `json:"-"`
+}
+
+// updateFiles applies the Input, and its Files, committing the changed files
+// to a single branch and opening one PullRequest, an empty Filename only
+// updates the Files, and a glob Filename updates each matching file.
+func (u *Updater) updateFiles(ctx context.Context, input *Input) (*UpdateResult, error) {
+	if input.Trigger != nil {
+		u.log.Info("update triggered", input.Trigger.keysAndValues()...)
+	}
+	group := input.fileInputs()
+	if u.preflight {
+		// Each file matching a glob is checked.
+		expanded, err := u.expandGlobs(ctx, group)
+		if err != nil {
+			return nil, err
+		}
+		group = expanded
+	}
+	if err := u.preflightChecks(ctx, group[0].commitInput(), preflightFiles(group)); err != nil {
+		return nil, err
+	}
+	return u.updateGroup(ctx, &Batch{BranchGenerateName: input.BranchGenerateName, NewBranchName: input.NewBranchName, ResetBranch: input.ResetBranch, ReusePullRequest: input.ReusePullRequest, Supersede: input.Supersede}, group)
+}
+
+// fileInputs returns an Input for each of the files, the PullRequest title
+// and body are taken from the first.
+func (i *Input) fileInputs() []*Input {
+	inputs := []*Input{}
+	if i.Filename != "" {
+		main := *i
+		main.Files = nil
+		inputs = append(inputs, &main)
+	}
+	for _, f := range i.Files {
+		file := *i
+		file.Filename, file.Key, file.NewValue, file.ContentUpdater = f.Filename, f.Key, f.NewValue, f.ContentUpdater
+		file.Files, file.Source, file.Values = nil, nil, nil
+		if f.Format != "" {
+			file.Format = f.Format
+		}
+		if len(inputs) > 0 {
+			file.PullRequest.Title, file.PullRequest.Body = "", ""
+		}
+		inputs = append(inputs, &file)
+	}
+	return inputs
+}
+
+// isGlob returns true if the filename is a pattern matching several files.
+func isGlob(filename string) bool {
+	return strings.ContainsAny(filename, "*?[")
+}
+
+// expandGlobs replaces each Input with a glob Filename, with an Input for
+// each of the matching files in the repo, the PullRequest title and body are
+// taken from the first.
+func (u *Updater) expandGlobs(ctx context.Context, inputs []*Input) ([]*Input, error) {
+	expanded := []*Input{}
+	for _, input := range inputs {
+		if !isGlob(input.Filename) {
+			expanded = append(expanded, input)
+			continue
+		}
+		matches, err := GlobFiles(ctx, u.gitClient, input.Repo, input.Branch, input.Filename)
+		if err != nil {
+			return nil, err
+		}
+		u.log.Info("matched files", "filename", input.Filename, "count", len(matches))
+		for n, m := range matches {
+			file := *input
+			file.Filename = m
+			if n > 0 {
+				file.PullRequest.Title, file.PullRequest.Body = "", ""
+			}
+			expanded = append(expanded, &file)
+		}
+	}
+	return expanded, nil
+}
+
+// GlobFiles returns the files in the repo branch matching the glob, in path
+// order, it's an error if no files match, a filename that isn't a glob is
+// returned without listing the files.
+//
+// The glob is matched with path.Match, so a * doesn't match a /.
+func GlobFiles(ctx context.Context, c client.GitClient, repo, branch, glob string) ([]string, error) {
+	if !isGlob(glob) {
+		return []string{glob}, nil
+	}
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+	}
+	dir := globDir(glob)
+	files, err := c.ListFiles(ctx, repo, branch, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files in %s: %w", dir, err)
+	}
+	matches := []string{}
+	for _, f := range files {
+		if ok, _ := path.Match(glob, f); ok {
+			matches = append(matches, f)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no files in %s match %s", repo, glob)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// globDir returns the directory containing all the files the glob can match.
+func globDir(glob string) string {
+	segments := strings.Split(glob, "/")
+	for i, s := range segments {
+		if isGlob(s) {
+			return strings.Join(segments[:i], "/")
+		}
+	}
+	return path.Dir(glob)
+}
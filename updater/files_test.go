@@ -0,0 +1,247 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testThirdFilePath = "environments/test/services/service-c/test.yaml"
+
+func TestUpdateWithFiles(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testThirdFilePath, testBranch, []byte("build: 1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.CommitMessage = "Update {{ .Key }} from {{ .Previous }}"
+	input.Files = []FileChange{
+		{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"},
+		{Filename: testThirdFilePath, ContentUpdater: TransformUpdater("build", "{{ add . 1 }}")},
+	}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	for path, want := range map[string]string{
+		testFilePath:       "test:\n  image: new-image\n",
+		testSecondFilePath: "test:\n  image: new-image\n",
+		testThirdFilePath:  "build: 2\n",
+	} {
+		if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, path, "test-branch-a"))); diff != "" {
+			t.Errorf("incorrect update of %s:\n%s", path, diff)
+		}
+	}
+	m.AssertCommitMessage(testGitHubRepo, testSecondFilePath, "test-branch-a", "Update test.image from old-image")
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithFilesInOtherFormats(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "chart/values.tfvars", testBranch, []byte("image = \"old-image\"\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Files = []FileChange{{Filename: "chart/values.tfvars", Key: "image", NewValue: "new-image", Format: "hcl"}}
+
+	_, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		testFilePath:          "test:\n  image: new-image\n",
+		"chart/values.tfvars": "image = \"new-image\"\n",
+	} {
+		if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, path, "test-branch-a"))); diff != "" {
+			t.Errorf("incorrect update of %s:\n%s", path, diff)
+		}
+	}
+}
+
+func TestUpdateWithOnlyFiles(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Filename = ""
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+
+	pr, err := updater.UpdateYAML(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if pr == nil {
+		t.Fatal("no PullRequest was created")
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testSecondFilePath, "test-branch-a")); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestUpdateWithFilesAndNoChanges(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != Unchanged {
+		t.Fatalf("got state %s, want %s", result.State, Unchanged)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateWithFilesCommitted(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BranchGenerateName = ""
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := m.GetBranchHead(context.Background(), testGitHubRepo, testBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&UpdateResult{State: Committed, Branch: testBranch, Commit: head, Base: "980a0d5f19a64b4b30a87d4206aade58726b60e3"}, result); diff != "" {
+		t.Fatalf("incorrect result:\n%s", diff)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testSecondFilePath, testBranch)); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateWithGlobFilename(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "environments/production/services/service-a/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/staging/services/service-a/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/test/services/service-a/app.yaml", testBranch, []byte("test:\n  image: new-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/test/services/service-b/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/test/services/service-a/nested/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Filename = "environments/*/services/service-a/*.yaml"
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	for path, want := range map[string]string{
+		"environments/production/services/service-a/app.yaml":  "test:\n  image: new-image\n",
+		"environments/staging/services/service-a/app.yaml":     "test:\n  image: new-image\n",
+		"environments/test/services/service-a/app.yaml":        "",
+		"environments/test/services/service-b/app.yaml":        "",
+		"environments/test/services/service-a/nested/app.yaml": "",
+	} {
+		if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, path, "test-branch-a"))); diff != "" {
+			t.Errorf("incorrect update of %s:\n%s", path, diff)
+		}
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithGlobFilenameErrors(t *testing.T) {
+	globTests := []struct {
+		name    string
+		glob    string
+		wantErr string
+	}{
+		{"no matching files", "environments/*/services/service-z/*.yaml", "no files in testorg/testrepo match environments/\\*/services/service-z/\\*.yaml"},
+		{"invalid glob", "environments/[/*.yaml", `invalid glob "environments/\[/\*.yaml": syntax error in pattern`},
+	}
+
+	for _, tt := range globTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			updater := New(zap.New(), m)
+			input := makeInput()
+			input.Filename = tt.glob
+
+			_, err := updater.Update(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoBranchesCreated()
+		})
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "values.yaml", testBranch, []byte("a: 1\n"))
+	m.AddFileContents(testGitHubRepo, "values-test.yaml", testBranch, []byte("a: 1\n"))
+	m.AddFileContents(testGitHubRepo, "charts/values.yaml", testBranch, []byte("a: 1\n"))
+	globTests := []struct {
+		glob string
+		want []string
+	}{
+		{"values*.yaml", []string{"values-test.yaml", "values.yaml"}},
+		{"*/values.yaml", []string{"charts/values.yaml"}},
+		{"charts/values.yaml", []string{"charts/values.yaml"}},
+	}
+
+	for _, tt := range globTests {
+		t.Run(tt.glob, func(rt *testing.T) {
+			files, err := GlobFiles(context.Background(), m, testGitHubRepo, testBranch, tt.glob)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, files); diff != "" {
+				rt.Fatalf("incorrect files:\n%s", diff)
+			}
+		})
+	}
+}
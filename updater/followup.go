@@ -0,0 +1,102 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// ErrFollowUpPending is returned when the gates of a FollowUp are not yet
+// satisfied, the follow-up should be retried later.
+var ErrFollowUpPending = errors.New("the follow-up is pending")
+
+// Gate is checked before a follow-up is applied, it returns false, and the
+// reason, to hold the follow-up e.g. until the change has been approved.
+type Gate func(ctx context.Context, pr *scm.PullRequest) (bool, string, error)
+
+// FollowUp configures the updates that are applied when an automation
+// PullRequest is merged, e.g. to promote a change from a staging environment
+// repo to production.
+type FollowUp struct {
+	Repo         string        // The repo of the merged PullRequests e.g. my-org/staging
+	BranchPrefix string        // Only PullRequests from branches with this prefix are followed up e.g. update-image-
+	Delay        time.Duration // How long after the merge before the follow-up is applied
+	Gates        []Gate        // Checks that must pass before the follow-up is applied
+	// Plan returns the updates to apply for the merged PullRequest e.g. the
+	// same change to the production environment.
+	Plan func(pr *scm.PullRequest) (*Batch, error)
+}
+
+// LabelGate holds a follow-up until the merged PullRequest has the label, e.g.
+// an "approved-for-production" label applied by a reviewer.
+func LabelGate(label string) Gate {
+	return func(ctx context.Context, pr *scm.PullRequest) (bool, string, error) {
+		for _, l := range pr.Labels {
+			if l.Name == label {
+				return true, "", nil
+			}
+		}
+		return false, fmt.Sprintf("the PullRequest does not have the label %s", label), nil
+	}
+}
+
+// FollowUpMerged applies the FollowUp for a merged PullRequest, e.g. from a
+// webhook, and returns the PullRequests that were created.
+//
+// PullRequests in other repos, or from other branches, are ignored. If the
+// delay has not passed since the merge commit was committed, or a gate does not
+// pass, an error wrapping ErrFollowUpPending is returned.
+//
+// Inputs without a Trigger are recorded as triggered by the merge.
+func (u *Updater) FollowUpMerged(ctx context.Context, pr *scm.PullRequest, f *FollowUp) ([]*scm.PullRequest, error) {
+	if !pr.Merged {
+		return nil, ErrNotMerged
+	}
+	if pr.Base.Repo.FullName != f.Repo || !strings.HasPrefix(pr.Head.Ref, f.BranchPrefix) {
+		u.log.Info("no follow-up for the PullRequest", "repo", pr.Base.Repo.FullName, "branch", pr.Head.Ref, "number", pr.Number)
+		return nil, nil
+	}
+	if f.Delay > 0 {
+		mergedAt, err := u.mergedAt(ctx, pr)
+		if err != nil {
+			return nil, err
+		}
+		if wait := f.Delay - u.now().Sub(mergedAt); wait > 0 {
+			return nil, fmt.Errorf("%w: %s of the delay remaining", ErrFollowUpPending, wait.Round(time.Second))
+		}
+	}
+	for _, g := range f.Gates {
+		ok, reason, err := g(ctx, pr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the follow-up gate: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrFollowUpPending, reason)
+		}
+	}
+	b, err := f.Plan(pr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan the follow-up for PullRequest %d: %w", pr.Number, err)
+	}
+	for _, input := range b.Inputs {
+		if input.Trigger == nil {
+			input.Trigger = &Trigger{Source: TriggerWebhook, Commit: pr.MergeSha, PayloadRef: pr.Link}
+		}
+	}
+	u.log.Info("following up merged PullRequest", "repo", f.Repo, "number", pr.Number, "updates", len(b.Inputs))
+	return u.UpdateBatch(ctx, b)
+}
+
+// mergedAt returns when the PullRequest was merged, from the merge commit, the
+// PullRequest is updated by later comments and labels.
+func (u *Updater) mergedAt(ctx context.Context, pr *scm.PullRequest) (time.Time, error) {
+	commit, err := u.gitClient.GetCommit(ctx, pr.Base.Repo.FullName, pr.MergeSha)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get the merge commit of PullRequest %d: %w", pr.Number, err)
+	}
+	return commit.Committer.Date, nil
+}
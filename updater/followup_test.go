@@ -0,0 +1,184 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testStagingRepo = "testorg/staging"
+
+func makeFollowUp() *FollowUp {
+	return &FollowUp{
+		Repo:         testStagingRepo,
+		BranchPrefix: "update-image-",
+		Plan: func(pr *scm.PullRequest) (*Batch, error) {
+			input := makeInput()
+			input.PullRequest.Title = "Production: " + pr.Title
+			return &Batch{Inputs: []*Input{input}}, nil
+		},
+	}
+}
+
+func makeStagingPullRequest() *scm.PullRequest {
+	pr := makeMergedPullRequest()
+	pr.Base.Repo = scm.Repository{FullName: testStagingRepo}
+	pr.Head = scm.PullRequestBranch{Ref: "update-image-abcde"}
+	return pr
+}
+
+func addMergeCommit(m *mock.MockClient, mergedAt time.Time) {
+	m.AddCommit(testStagingRepo, &scm.Commit{Sha: testMergeSHA, Committer: scm.Signature{Date: mergedAt}})
+}
+
+func TestFollowUpMerged(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	f := makeFollowUp()
+	f.Delay = 30 * time.Minute
+	f.Gates = []Gate{LabelGate("approved")}
+	addMergeCommit(m, time.Now().Add(-time.Hour))
+	pr := makeStagingPullRequest()
+	pr.Labels = []*scm.Label{{Name: "approved"}}
+	pr.Updated = time.Now()
+
+	prs, err := updater.FollowUpMerged(context.Background(), pr, f)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := len(prs); l != 1 {
+		t.Fatalf("got %d PullRequests, want 1", l)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Production: Promote new-image",
+		Body:  "This is the body",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestFollowUpMergedIgnoresOtherPullRequests(t *testing.T) {
+	ignoreTests := []struct {
+		name   string
+		modify func(pr *scm.PullRequest)
+	}{
+		{"other repo", func(pr *scm.PullRequest) { pr.Base.Repo.FullName = testOtherRepo }},
+		{"other branch", func(pr *scm.PullRequest) { pr.Head.Ref = "feature" }},
+	}
+
+	for _, tt := range ignoreTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			updater := New(zap.New(), m)
+			pr := makeStagingPullRequest()
+			tt.modify(pr)
+
+			prs, err := updater.FollowUpMerged(context.Background(), pr, makeFollowUp())
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if prs != nil {
+				rt.Fatalf("got %v, want no PullRequests", prs)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestFollowUpMergedErrors(t *testing.T) {
+	errorTests := []struct {
+		name    string
+		modify  func(pr *scm.PullRequest, f *FollowUp)
+		wantErr string
+	}{
+		{"not merged", func(pr *scm.PullRequest, f *FollowUp) { pr.Merged = false }, "the PullRequest has not been merged"},
+		{"delay", func(pr *scm.PullRequest, f *FollowUp) { f.Delay = 2 * time.Hour }, "the follow-up is pending: 1h0m0s of the delay remaining"},
+		{"gate", func(pr *scm.PullRequest, f *FollowUp) { f.Gates = []Gate{LabelGate("approved")} }, "the follow-up is pending: the PullRequest does not have the label approved"},
+		{"gate error", func(pr *scm.PullRequest, f *FollowUp) {
+			f.Gates = []Gate{func(context.Context, *scm.PullRequest) (bool, string, error) {
+				return false, "", errors.New("unavailable")
+			}}
+		}, "failed to check the follow-up gate: unavailable"},
+		{"plan error", func(pr *scm.PullRequest, f *FollowUp) {
+			f.Plan = func(*scm.PullRequest) (*Batch, error) { return nil, errors.New("no production file") }
+		}, "failed to plan the follow-up for PullRequest 12: no production file"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			addMergeCommit(m, time.Now().Add(-time.Hour))
+			updater := New(zap.New(), m)
+			pr, f := makeStagingPullRequest(), makeFollowUp()
+			tt.modify(pr, f)
+
+			_, err := updater.FollowUpMerged(context.Background(), pr, f)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestFollowUpMergedDelayUsesTheClock(t *testing.T) {
+	mergedAt := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+	delayTests := []struct {
+		name    string
+		now     time.Time
+		wantPRs int
+		wantErr string
+	}{
+		{"delay remaining", mergedAt.Add(20 * time.Minute), 0, "the follow-up is pending: 10m0s of the delay remaining"},
+		{"delay passed", mergedAt.Add(31 * time.Minute), 1, ""},
+	}
+
+	for _, tt := range delayTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			addMergeCommit(m, mergedAt)
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			updater.now = func() time.Time { return tt.now }
+			f := makeFollowUp()
+			f.Delay = 30 * time.Minute
+
+			prs, err := updater.FollowUpMerged(context.Background(), makeStagingPullRequest(), f)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("error got %s, want %s", err, tt.wantErr)
+			}
+			if l := len(prs); l != tt.wantPRs {
+				rt.Fatalf("got %d PullRequests, want %d", l, tt.wantPRs)
+			}
+		})
+	}
+}
+
+func TestFollowUpMergedWithMissingMergeCommit(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+	f := makeFollowUp()
+	f.Delay = 30 * time.Minute
+
+	_, err := updater.FollowUpMerged(context.Background(), makeStagingPullRequest(), f)
+
+	if !test.MatchError(t, "failed to get the merge commit of PullRequest 12: commit not found", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
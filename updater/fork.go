@@ -0,0 +1,127 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+)
+
+// ForkCleanup configures what is done with the update branches pushed to
+// forks.
+type ForkCleanup int
+
+const (
+	// ForkKeepBranch leaves the update branch in the fork.
+	ForkKeepBranch ForkCleanup = iota
+	// ForkDeleteBranchOnFailure deletes the update branch from the fork if
+	// the PullRequest can't be opened, rather than leaving it behind.
+	ForkDeleteBranchOnFailure
+)
+
+type forkOptions struct {
+	organization string
+	cleanup      ForkCleanup
+}
+
+// Forks is an option func for the Updater creation function, when the bot
+// can't push to a repo, the repo is forked to the organization, or the
+// authenticated user if it's empty, and the update branch is pushed to the
+// fork, with a cross-repo PullRequest.
+//
+// An existing fork is used rather than creating another, and the Fork of an
+// Input is used regardless of the permissions.
+//
+// PullRequests from forks aren't reused, but reset branches are, so with
+// HashedBranchNames a repeated update resets the branch in the fork, and the
+// PullRequest from it is updated, rather than another being opened.
+func Forks(organization string, cleanup ForkCleanup) UpdaterFunc {
+	return func(u *Updater) {
+		u.forks = &forkOptions{organization: organization, cleanup: cleanup}
+	}
+}
+
+// withFork returns the commit with the fork that the branch is pushed to, if
+// the bot can't push to the repo, and Forks are configured.
+//
+// The fork must be owner/repo, and changes are only pushed to new or reset
+// branches in forks.
+func (u *Updater) withFork(ctx context.Context, commit CommitInput) (CommitInput, error) {
+	if commit.Fork == "" {
+		if u.forks == nil {
+			return commit, nil
+		}
+		perm, err := u.gitClient.GetRepoPermissions(ctx, commit.Repo)
+		if err != nil {
+			return commit, scmError(err, commit.Repo, "get the permissions of repo "+commit.Repo, nil)
+		}
+		if perm.Push {
+			return commit, nil
+		}
+		fork, err := u.gitClient.CreateFork(ctx, commit.Repo, u.forks.organization)
+		if err != nil {
+			return commit, scmError(err, commit.Repo, "fork repo "+commit.Repo, nil)
+		}
+		u.log.Info("pushing to fork", "repo", commit.Repo, "fork", fork)
+		commit.Fork = fork
+	}
+	if n := strings.Index(commit.Fork, "/"); n <= 0 || n == len(commit.Fork)-1 {
+		return commit, fmt.Errorf("invalid fork %q, the fork must be owner/repo", commit.Fork)
+	}
+	if !commit.newBranch() {
+		return commit, fmt.Errorf("a BranchGenerateName or NewBranchName is required to push to fork %s", commit.Fork)
+	}
+	return commit, nil
+}
+
+// headRepo returns the repo that the branch is pushed to, the Fork if there is
+// one, or the Repo.
+func (c CommitInput) headRepo() string {
+	if c.Fork != "" {
+		return c.Fork
+	}
+	return c.Repo
+}
+
+// head returns the head of a PullRequest from the branch, which is prefixed
+// with the owner of the Fork, if there is one, e.g. my-bot:update-image-a.
+func (c CommitInput) head(branch string) string {
+	if c.Fork == "" {
+		return branch
+	}
+	return c.Fork[:strings.Index(c.Fork, "/")] + ":" + branch
+}
+
+// findPullRequest returns the open PullRequest in the repo from the branch,
+// in the commit's Fork if there is one.
+func (u *Updater) findPullRequest(ctx context.Context, commit CommitInput, branch string) (*scm.PullRequest, error) {
+	if commit.Fork == "" {
+		return u.gitClient.FindPullRequest(ctx, commit.Repo, branch)
+	}
+	prs, err := u.gitClient.ListPullRequests(ctx, commit.Repo)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range prs {
+		if pr.Source == branch && pr.Fork == commit.Fork {
+			return pr, nil
+		}
+	}
+	return nil, client.NewNotFoundError(fmt.Sprintf("no open pull request from %s in repo %s", commit.head(branch), commit.Repo))
+}
+
+// cleanupFork deletes the branch from the fork, if configured, the update
+// has already failed, so failures are logged rather than returned.
+func (u *Updater) cleanupFork(ctx context.Context, commit CommitInput, branch string) {
+	if commit.Fork == "" || u.forks == nil || u.forks.cleanup != ForkDeleteBranchOnFailure {
+		return
+	}
+	if err := u.gitClient.DeleteBranch(ctx, commit.Fork, branch); err != nil {
+		u.log.Error(err, "failed to delete branch from fork", "fork", commit.Fork, "branch", branch)
+		return
+	}
+	u.log.Info("deleted branch from fork", "fork", commit.Fork, "branch", branch)
+}
@@ -0,0 +1,200 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateWithForks(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Forks("my-bots", ForkKeepBranch))
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Branch != "test-branch-a" {
+		t.Fatalf("got branch %q, want test-branch-a", r.Branch)
+	}
+	m.AssertForked(testGitHubRepo, "my-bots/testrepo")
+	m.AssertBranchCreated("my-bots/testrepo", "test-branch-a", testSHA)
+	if b := m.GetUpdatedContents("my-bots/testrepo", testFilePath, "test-branch-a"); string(b) != "test:\n  image: new-image\n" {
+		t.Fatalf("got updated contents %q in the fork", b)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body",
+		Head:  "my-bots:test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithForksAndPushPermission(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true, Push: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Forks("", ForkKeepBranch))
+
+	_, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.RefuteForked(testGitHubRepo)
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+}
+
+func TestUpdateWithExistingFork(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Fork = "octocat/testrepo-fork"
+
+	_, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.RefuteForked(testGitHubRepo)
+	m.AssertBranchCreated("octocat/testrepo-fork", "test-branch-a", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body",
+		Head:  "octocat:test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithForkErrors(t *testing.T) {
+	forkTests := []struct {
+		name    string
+		modify  func(*Input)
+		wantErr string
+	}{
+		{"direct commit", func(i *Input) { i.BranchGenerateName = "" }, "a BranchGenerateName or NewBranchName is required to push to fork my-bots/testrepo"},
+		{"reuse", func(i *Input) { i.ReusePullRequest = true }, "pull requests from forks can't be reused"},
+		{"fork without a repo", func(i *Input) { i.Fork = "octocat" }, `invalid fork "octocat", the fork must be owner/repo`},
+		{"fork without an owner", func(i *Input) { i.Fork = "/testrepo" }, `invalid fork "/testrepo", the fork must be owner/repo`},
+	}
+
+	for _, tt := range forkTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Forks("my-bots", ForkKeepBranch))
+			input := makeInput()
+			tt.modify(input)
+
+			_, err := updater.Update(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoBranchesCreated()
+		})
+	}
+}
+
+func TestUpdateWithForksAndHashedBranchNames(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	hashedTests := []struct {
+		name      string
+		openPR    *scm.PullRequest
+		wantState UpdateState
+	}{
+		{"no open PullRequest", nil, PullRequestCreated},
+		{"open PullRequest from the fork", &scm.PullRequest{Number: 7, Source: testHashedBranch, Target: testBranch, Fork: "my-bots/testrepo"}, PullRequestUpdated},
+		{"open PullRequest from the repo", &scm.PullRequest{Number: 7, Source: testHashedBranch, Target: testBranch}, PullRequestCreated},
+	}
+
+	for _, tt := range hashedTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+			m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+			if tt.openPR != nil {
+				m.AddOpenPullRequest(testGitHubRepo, tt.openPR)
+			}
+			updater := New(zap.New(), m, Forks("my-bots", ForkKeepBranch), HashedBranchNames())
+
+			r, err := updater.Update(context.Background(), makeInput())
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r.State != tt.wantState {
+				rt.Fatalf("got state %s, want %s", r.State, tt.wantState)
+			}
+			m.AssertBranchReset("my-bots/testrepo", testHashedBranch, testSHA)
+			if tt.wantState == PullRequestUpdated {
+				m.AssertNoPullRequestsCreated()
+			}
+		})
+	}
+}
+
+func TestUpdateWithForkDeletesBranchOnFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+	m.CreatePullRequestErr = errors.New("pull requests are disabled")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Forks("my-bots", ForkDeleteBranchOnFailure))
+
+	_, err := updater.Update(context.Background(), makeInput())
+
+	if !test.MatchError(t, "pull requests are disabled", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertBranchDeleted("my-bots/testrepo", "test-branch-a")
+}
+
+func TestUpdateBatchGroupedWithForks(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Forks("", ForkKeepBranch))
+	staging, production := makeInput(), makeInput()
+	production.Filename = testSecondFilePath
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+		PullRequest: PullRequestInput{Title: "Update the images", Body: "Updating the images."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{testFilePath, testSecondFilePath} {
+		if b := m.GetUpdatedContents("mock-user/testrepo", filename, "test-branch-a"); string(b) != "test:\n  image: new-image\n" {
+			t.Fatalf("got updated contents %q of %s in the fork", b, filename)
+		}
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Update the images",
+		Body:  "Updating the images.",
+		Head:  "mock-user:test-branch-a",
+		Base:  testBranch,
+	})
+}
@@ -1,6 +1,13 @@
 package updater
 
 import (
+	"fmt"
+	"regexp"
+
+	"github.com/agill17/pkg/dockerfile"
+	"github.com/agill17/pkg/formats"
+	"github.com/agill17/pkg/helm"
+	"github.com/agill17/pkg/kustomize"
 	"github.com/agill17/pkg/syaml"
 )
 
@@ -16,9 +23,194 @@ func ReplaceContents(b []byte) ContentUpdater {
 // value, they key can be a dotted path.
 //
 // UpdateYAML("test.value", []string{"test", "value"})
-func UpdateYAML(key string, newValue interface{}) ContentUpdater {
+//
+// Options are passed through to syaml.SetBytes e.g. to validate the updated
+// document.
+func UpdateYAML(key string, newValue interface{}, opts ...syaml.Option) ContentUpdater {
 	return func(b []byte) ([]byte, error) {
-		data, err := syaml.SetBytes(b, key, newValue)
+		data, err := syaml.SetBytes(b, key, newValue, opts...)
 		return data, err
 	}
 }
+
+// DeleteYAML is a ContentUpdater that removes a key from a YAML file, the key
+// can be a dotted path, use UpdateYAML with a nil value to set the key to null.
+func DeleteYAML(key string, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return syaml.DeleteBytes(b, key, opts...)
+	}
+}
+
+// AppendYAML is a ContentUpdater that appends a value to the sequence at a
+// dotted path in a YAML file, creating the sequence if it doesn't exist.
+//
+// AppendYAML("spec.template.spec.containers.0.env", map[string]string{"name": "DEBUG", "value": "1"})
+func AppendYAML(key string, value interface{}, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return syaml.AppendBytes(b, key, value, opts...)
+	}
+}
+
+// InsertYAML is a ContentUpdater that inserts a value into the sequence at a
+// dotted path in a YAML file, before the item at the index.
+func InsertYAML(key string, index int, value interface{}, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return syaml.InsertBytes(b, key, index, value, opts...)
+	}
+}
+
+// RemoveYAML is a ContentUpdater that removes the selected items from the
+// sequence at a dotted path in a YAML file.
+//
+// RemoveYAML("spec.template.spec.volumes", syaml.Matching("name", "cache"))
+func RemoveYAML(key string, selector syaml.Selector, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return syaml.RemoveBytes(b, key, selector, opts...)
+	}
+}
+
+// UpdateFrontMatter is a ContentUpdater that updates a key in the YAML front
+// matter of a file, e.g. a Markdown file, leaving the body untouched.
+func UpdateFrontMatter(key string, newValue interface{}, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return syaml.SetFrontMatter(b, key, newValue, opts...)
+	}
+}
+
+// UpdateFormat is a ContentUpdater that updates a file in a format from the
+// formats registry, using a key and new value.
+//
+// UpdateFormat("xml", "project.version", "1.2.0")
+func UpdateFormat(format, key string, newValue interface{}) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		f, err := formats.Lookup(format)
+		if err != nil {
+			return nil, err
+		}
+		return f.Set(b, key, newValue)
+	}
+}
+
+// DeleteFormat is a ContentUpdater that removes a key from a file in a format
+// from the formats registry.
+func DeleteFormat(format, key string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		f, err := formats.Lookup(format)
+		if err != nil {
+			return nil, err
+		}
+		return f.Delete(b, key)
+	}
+}
+
+// UpdateJSON is a ContentUpdater that updates a JSON file using a key and new
+// value, the key can be a dotted path.
+//
+// The formatting of the original file is preserved.
+func UpdateJSON(key string, newValue interface{}) ContentUpdater {
+	return UpdateFormat("json", key, newValue)
+}
+
+// UpdateTOML is a ContentUpdater that updates a TOML file using a key and new
+// value, the key can be a dotted path.
+//
+// The formatting and comments of the original file are preserved.
+func UpdateTOML(key string, newValue interface{}) ContentUpdater {
+	return UpdateFormat("toml", key, newValue)
+}
+
+// UpdateHCL is a ContentUpdater that updates an HCL file e.g. Terraform .tf
+// and .tfvars files, using a key and new value.
+//
+// UpdateHCL("module.vpc.version", "2.1.0")
+//
+// The formatting and comments of the original file are preserved.
+func UpdateHCL(key string, newValue interface{}) ContentUpdater {
+	return UpdateFormat("hcl", key, newValue)
+}
+
+// UpdateKeyValue is a ContentUpdater that updates a key=value file e.g. a .env
+// or Java .properties file, using a key and new value.
+//
+// The comments and ordering of the original file are preserved.
+func UpdateKeyValue(key string, newValue interface{}) ContentUpdater {
+	return UpdateFormat("kv", key, newValue)
+}
+
+// UpdateDockerfileFrom is a ContentUpdater that updates the FROM instructions
+// for an image in a Dockerfile to the new image reference, which can include a
+// digest.
+//
+// UpdateDockerfileFrom("golang:1.15@sha256:4d4d...")
+func UpdateDockerfileFrom(ref string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return dockerfile.SetFrom(b, ref)
+	}
+}
+
+// UpdateDockerfileArg is a ContentUpdater that updates the default value of a
+// build argument in a Dockerfile.
+func UpdateDockerfileArg(name, value string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return dockerfile.SetArg(b, name, value)
+	}
+}
+
+// UpdateKustomizeImage is a ContentUpdater that sets the newName, newTag or
+// digest for an image in the images list of a kustomization.yaml, creating
+// the entry if it doesn't exist.
+//
+// UpdateKustomizeImage(kustomize.Image{Name: "nginx", NewTag: "1.19"})
+func UpdateKustomizeImage(image kustomize.Image) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return kustomize.SetImage(b, image)
+	}
+}
+
+// UpdateHelmImageTag is a ContentUpdater that sets the tag of the image at the
+// path in a Helm values.yaml.
+//
+// UpdateHelmImageTag("image", "1.19")
+func UpdateHelmImageTag(path, tag string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return helm.SetImageTag(b, path, tag)
+	}
+}
+
+// BumpChartVersion is a ContentUpdater that increments the version in a Helm
+// Chart.yaml, and if the appVersion is not empty, sets the appVersion.
+//
+// BumpChartVersion(helm.Patch, "1.19")
+func BumpChartVersion(bump helm.Bump, appVersion string) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		return helm.BumpChart(b, bump, appVersion)
+	}
+}
+
+// RegexReplace is a ContentUpdater that replaces matches of the pattern with
+// the replacement, for files that aren't structured data, e.g. scripts.
+//
+// RegexReplace(`version: v\d+\.\d+\.\d+`, "version: v1.2.0", 1)
+//
+// The replacement can refer to submatches e.g. ${1}, and if max is greater
+// than zero, at most max matches are replaced.
+func RegexReplace(pattern, replacement string, max int) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile pattern %q: %w", pattern, err)
+		}
+		n := -1
+		if max > 0 {
+			n = max
+		}
+		updated := []byte{}
+		last := 0
+		for _, m := range re.FindAllSubmatchIndex(b, n) {
+			updated = append(updated, b[last:m[0]]...)
+			updated = re.Expand(updated, []byte(replacement), b, m)
+			last = m[1]
+		}
+		return append(updated, b[last:]...), nil
+	}
+}
@@ -4,6 +4,11 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/helm"
+	"github.com/agill17/pkg/kustomize"
+	"github.com/agill17/pkg/syaml"
+	"github.com/agill17/pkg/test"
 )
 
 func TestFunctions(t *testing.T) {
@@ -15,6 +20,24 @@ func TestFunctions(t *testing.T) {
 	}{
 		{"replace contents", []byte("input"), []byte("output"), ReplaceContents([]byte("output"))},
 		{"update yaml key", []byte("input:\n  value: test\n"), []byte("input:\n  value: new\n"), UpdateYAML("input.value", "new")},
+		{"delete yaml key", []byte("input:\n  value: test\n  other: test\n"), []byte("input:\n  other: test\n"), DeleteYAML("input.value")},
+		{"append yaml item", []byte("env:\n- name: A\n"), []byte("env:\n- name: A\n- name: B\n"), AppendYAML("env", map[string]string{"name": "B"})},
+		{"insert yaml item", []byte("args: [--a, --c]\n"), []byte("args: [--a, --b, --c]\n"), InsertYAML("args", 1, "--b")},
+		{"remove yaml item", []byte("volumes:\n- name: cache\n- name: config\n"), []byte("volumes:\n- name: config\n"), RemoveYAML("volumes", syaml.Matching("name", "cache"))},
+		{"update front matter key", []byte("---\nversion: 1.0.0\n---\nversion: 1.0.0\n"), []byte("---\nversion: 1.1.0\n---\nversion: 1.0.0\n"), UpdateFrontMatter("version", "1.1.0")},
+		{"update json key", []byte("{\n  \"input\": {\n    \"value\": \"test\"\n  }\n}\n"), []byte("{\n  \"input\": {\n    \"value\": \"new\"\n  }\n}\n"), UpdateJSON("input.value", "new")},
+		{"update toml key", []byte("# settings\n[input]\nvalue = \"test\" # current\n"), []byte("# settings\n[input]\nvalue = \"new\" # current\n"), UpdateTOML("input.value", "new")},
+		{"update hcl key", []byte("module \"vpc\" {\n  version = \"2.0.0\" # pinned\n}\n"), []byte("module \"vpc\" {\n  version = \"2.1.0\" # pinned\n}\n"), UpdateHCL("module.vpc.version", "2.1.0")},
+		{"update format key", []byte("<project>\n  <version>1.0.0</version>\n</project>\n"), []byte("<project>\n  <version>1.1.0</version>\n</project>\n"), UpdateFormat("xml", "project.version", "1.1.0")},
+		{"delete format key", []byte("[server]\nhost = localhost\nport = 80\n"), []byte("[server]\nhost = localhost\n"), DeleteFormat("ini", "server.port")},
+		{"update key=value key", []byte("# settings\nIMAGE=old\n"), []byte("# settings\nIMAGE=new\n"), UpdateKeyValue("IMAGE", "new")},
+		{"update dockerfile from", []byte("FROM golang:1.14 AS builder\n"), []byte("FROM golang:1.15 AS builder\n"), UpdateDockerfileFrom("golang:1.15")},
+		{"update dockerfile arg", []byte("ARG VERSION=1\n"), []byte("ARG VERSION=2\n"), UpdateDockerfileArg("VERSION", "2")},
+		{"update kustomize image", []byte("images:\n- name: nginx\n  newTag: \"1.18\"\n"), []byte("images:\n- name: nginx\n  newTag: \"1.19\"\n"), UpdateKustomizeImage(kustomize.Image{Name: "nginx", NewTag: "1.19"})},
+		{"update helm image tag", []byte("image:\n  repository: nginx\n  tag: \"1.18\"\n"), []byte("image:\n  repository: nginx\n  tag: \"1.19\"\n"), UpdateHelmImageTag("image", "1.19")},
+		{"bump chart version", []byte("version: 0.1.0\nappVersion: \"1.18\"\n"), []byte("version: 0.1.1\nappVersion: \"1.19\"\n"), BumpChartVersion(helm.Patch, "1.19")},
+		{"regex replace", []byte("VERSION=1.2.0\nOTHER_VERSION=1.2.0\n"), []byte("VERSION=1.3.0\nOTHER_VERSION=1.2.0\n"), RegexReplace(`(?m)^VERSION=(.*)$`, "VERSION=1.3.0", 0)},
+		{"regex replace with submatches", []byte("image: nginx:1.18\nsidecar: nginx:1.18\n"), []byte("image: nginx:1.19\nsidecar: nginx:1.18\n"), RegexReplace(`(nginx):1\.18`, "${1}:1.19", 1)},
 	}
 
 	for _, tt := range funcTests {
@@ -32,3 +55,19 @@ func TestFunctions(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateFormatWithUnknownFormat(t *testing.T) {
+	_, err := UpdateFormat("cue", "version", "1.1.0")([]byte("test"))
+
+	if !test.MatchError(t, `unknown format "cue"`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestRegexReplaceWithInvalidPattern(t *testing.T) {
+	_, err := RegexReplace("(", "", 0)([]byte("test"))
+
+	if !test.MatchError(t, `failed to compile pattern "\("`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
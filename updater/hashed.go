@@ -0,0 +1,95 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// HashedBranchNames is an option func for the Updater creation function, when
+// configured, branch names are derived from a hash of the change, rather than
+// generated with a random suffix, e.g. update-image-3f2a9c1b7e.
+//
+// The hash is of the repo, branch, files, keys and new values, so a retried or
+// duplicated update is committed to the same branch, and an open PullRequest
+// from the branch is reused rather than a new one being opened, an existing
+// branch without an open PullRequest is reset.
+//
+// Changes made by a ContentUpdater can't be hashed, and the name is generated.
+//
+// With Forks, the branch in the fork is reset, and the PullRequest from it is
+// updated.
+//
+// This is experimental, it can also be enabled with the
+// HashedBranchNamesFeature gate.
+func HashedBranchNames() UpdaterFunc {
+	return func(u *Updater) {
+		u.hashBranches = true
+	}
+}
+
+// hashedBranch returns a copy of the Input with a NewBranchName derived from
+// the change, if the Updater has HashedBranchNames, and the name would be
+// generated.
+func (u *Updater) hashedBranch(input *Input) *Input {
+	commit, ok := u.hashedCommit(input.commitInput(), []*Input{input})
+	if !ok {
+		return input
+	}
+	i := *input
+	i.NewBranchName, i.ResetBranch, i.ReusePullRequest = commit.NewBranchName, true, true
+	return &i
+}
+
+// hashedCommit returns the commit with a NewBranchName derived from the
+// inputs, and true, if the Updater has HashedBranchNames, and the name would
+// be generated.
+func (u *Updater) hashedCommit(commit CommitInput, inputs []*Input) (CommitInput, bool) {
+	if !(u.hashBranches || u.enabled(HashedBranchNamesFeature)) || commit.NewBranchName != "" || commit.BranchGenerateName == "" {
+		return commit, false
+	}
+	hash, ok := changeHash(inputs)
+	if !ok {
+		return commit, false
+	}
+	commit.NewBranchName, commit.ResetBranch = commit.BranchGenerateName+hash, true
+	u.log.Info("derived branch name from the change", "name", commit.NewBranchName)
+	return commit, true
+}
+
+// hashedChange is the part of an Input that identifies the change.
+type hashedChange struct {
+	Repo     string
+	Branch   string
+	Filename string
+	Key      string
+	NewValue interface{}
+	Delete   bool
+	Values   []KeyValue
+}
+
+// changeHash returns the first 10 hex characters of a sha256 hash of the
+// changes in the inputs, in any order, it returns false if a change can't be
+// hashed.
+func changeHash(inputs []*Input) (string, bool) {
+	changes := []string{}
+	for _, input := range inputs {
+		for _, i := range input.fileInputs() {
+			if i.ContentUpdater != nil {
+				return "", false
+			}
+			b, err := json.Marshal(hashedChange{Repo: i.Repo, Branch: i.Branch, Filename: i.Filename, Key: i.Key, NewValue: i.NewValue, Delete: i.Delete, Values: i.Values})
+			if err != nil {
+				return "", false
+			}
+			changes = append(changes, string(b))
+		}
+	}
+	sort.Strings(changes)
+	h := sha256.New()
+	for _, c := range changes {
+		fmt.Fprintln(h, c)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))[:10], true
+}
@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+const testHashedBranch = "test-branch-4beeb60205"
+
+func TestUpdateWithHashedBranchNames(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), HashedBranchNames())
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	if r.Branch != testHashedBranch {
+		t.Fatalf("got branch %q, want %q", r.Branch, testHashedBranch)
+	}
+	m.AssertBranchReset(testGitHubRepo, testHashedBranch, testSHA)
+}
+
+func TestUpdateWithHashedBranchNamesAndOpenPullRequest(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testHashedBranch, []byte("test:\n  image: new-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: testHashedBranch, Target: testBranch})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), HashedBranchNames())
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != Unchanged {
+		t.Fatalf("got state %s, want %s", r.State, Unchanged)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateFilesWithHashedBranchNames(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "other.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), HashedBranchNames())
+	input := makeInput()
+	input.Files = []FileChange{{Filename: "other.yaml", Key: "test.image", NewValue: "new-image"}}
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "test-branch-44ff06d88a"; r.Branch != want {
+		t.Fatalf("got branch %q, want %q", r.Branch, want)
+	}
+}
+
+func TestHashedBranchNamesWithContentUpdater(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), HashedBranchNames())
+
+	r, err := updater.ApplyUpdate(context.Background(), makeInput(), ReplaceContents([]byte("test: {}\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Branch != "test-branch-a" {
+		t.Fatalf("got branch %q, want %q", r.Branch, "test-branch-a")
+	}
+}
+
+func TestChangeHash(t *testing.T) {
+	input := makeInput()
+	hash, ok := changeHash([]*Input{input})
+	if !ok {
+		t.Fatal("failed to hash the change")
+	}
+
+	same := makeInput()
+	same.CommitMessage, same.PullRequest.Title = "another message", "another title"
+	if h, _ := changeHash([]*Input{same}); h != hash {
+		t.Errorf("got %q for the same change, want %q", h, hash)
+	}
+	changed := makeInput()
+	changed.NewValue = "other-image"
+	if h, _ := changeHash([]*Input{changed}); h == hash {
+		t.Errorf("got %q for a different value, want a different hash", h)
+	}
+	other := makeInput()
+	other.Filename = "other.yaml"
+	if h := mustHash(t, input, other); h != mustHash(t, other, input) {
+		t.Errorf("got %q for the changes, want the same hash in any order", h)
+	}
+}
+
+func mustHash(t *testing.T, inputs ...*Input) string {
+	t.Helper()
+	h, ok := changeHash(inputs)
+	if !ok {
+		t.Fatal("failed to hash the change")
+	}
+	return h
+}
@@ -10,4 +10,7 @@ import (
 type GitUpdater interface {
 	ApplyUpdateToFile(ctx context.Context, input CommitInput, f ContentUpdater) (string, error)
 	CreatePR(ctx context.Context, input PullRequestInput) (*scm.PullRequest, error)
+	UpdateYAML(ctx context.Context, input *Input) (*scm.PullRequest, error)
+	Update(ctx context.Context, input *Input) (*UpdateResult, error)
+	ApplyUpdate(ctx context.Context, input *Input, f ContentUpdater) (*UpdateResult, error)
 }
@@ -0,0 +1,42 @@
+package updater
+
+import (
+	"context"
+	"time"
+
+	"github.com/agill17/pkg/lock"
+)
+
+// RepoLocks is an option func for the Updater creation function, when
+// configured, the Updater holds the repo's lock for each update, so that
+// updaters sharing the Locker don't interleave their branches and commits in
+// the same repo.
+//
+// The TTL should be longer than the slowest update, as the lock is released
+// when it expires.
+func RepoLocks(l lock.Locker, ttl time.Duration) UpdaterFunc {
+	return func(u *Updater) {
+		u.locker = l
+		u.lockTTL = ttl
+		u.lockInterval = time.Second
+	}
+}
+
+// lockRepo blocks until the repo's lock is acquired, or the context is done,
+// and returns a func that releases it.
+func (u *Updater) lockRepo(ctx context.Context, repo string) (func(), error) {
+	if u.locker == nil {
+		return func() {}, nil
+	}
+	owner := lock.NewOwner()
+	if err := lock.Acquire(ctx, u.locker, repo, owner, u.lockTTL, u.lockInterval); err != nil {
+		return nil, err
+	}
+	u.log.Info("acquired repo lock", "repo", repo, "owner", owner)
+	return func() {
+		// The lock is released even if the update was cancelled.
+		if err := u.locker.Unlock(context.Background(), repo, owner); err != nil {
+			u.log.Error(err, "failed to release repo lock", "repo", repo, "owner", owner)
+		}
+	}, nil
+}
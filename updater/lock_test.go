@@ -0,0 +1,79 @@
+package updater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/lock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateWithRepoLocks(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	locks := lock.NewMemoryLocker()
+	u := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RepoLocks(locks, time.Minute))
+	input := makeInput()
+	held := false
+	input.ContentUpdater = func(b []byte) ([]byte, error) {
+		ok, err := locks.TryLock(context.Background(), testGitHubRepo, "other", time.Minute)
+		held = !ok
+		return b, err
+	}
+
+	_, err := u.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !held {
+		t.Fatal("the repo was not locked during the update")
+	}
+	if ok, _ := locks.TryLock(context.Background(), testGitHubRepo, "other", time.Minute); !ok {
+		t.Fatal("the repo lock was not released")
+	}
+}
+
+func TestUpdateWithHeldRepoLock(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	locks := lock.NewMemoryLocker()
+	if _, err := locks.TryLock(context.Background(), testGitHubRepo, "other", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	u := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RepoLocks(locks, time.Minute))
+	u.lockInterval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := u.Update(ctx, makeInput())
+
+	if !test.MatchError(t, "failed to acquire lock testorg/testrepo: context deadline exceeded", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateBatchWithRepoLocks(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	locks := lock.NewMemoryLocker()
+	u := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RepoLocks(locks, time.Minute))
+
+	_, err := u.UpdateBatch(context.Background(), &Batch{GroupByRepo: true, BranchGenerateName: "test-branch-", Inputs: []*Input{makeInput()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, _ := locks.TryLock(context.Background(), testGitHubRepo, "other", time.Minute); !ok {
+		t.Fatal("the repo lock was not released")
+	}
+}
@@ -0,0 +1,112 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// MessageValues are available as template values in the CommitMessage, and
+// the PullRequest Title, Body and Comment of an Input.
+//
+//	CommitMessage: "Update the image from {{ .Previous }} to {{ .NewValue }}"
+//	Title: "Bump {{ .Trigger.Image }} to {{ .Trigger.Tag }} in {{ .Filename }}"
+type MessageValues struct {
+	Repo     string
+	Branch   string
+	Filename string
+	Key      string
+	Previous string // The value that was replaced, empty if there was none
+	NewValue interface{}
+	Diff     string  // A unified diff of the change, empty if it would show encrypted or secret values
+	Trigger  Trigger // The event that caused the update, empty if there was none
+}
+
+func (i *Input) messageValues(previous *string) MessageValues {
+	v := MessageValues{Repo: i.Repo, Branch: i.Branch, Filename: i.Filename, Key: i.Key, NewValue: i.NewValue}
+	if previous != nil {
+		v.Previous = *previous
+	}
+	if i.Trigger != nil {
+		v.Trigger = *i.Trigger
+	}
+	return v
+}
+
+// renderMessage executes the message as a template with the values, messages
+// without actions are returned unchanged.
+func renderMessage(name, message string, v MessageValues) (string, error) {
+	if !strings.Contains(message, "{{") {
+		return message, nil
+	}
+	tmpl, err := parseTemplate(name, message)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, v); err != nil {
+		return "", fmt.Errorf("failed to render the %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// parseTemplate parses the text as a template with the transform functions,
+// missing keys are an error.
+func parseTemplate(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(transformFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the %s: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// withMessages returns a copy of the Input with the commit message, and
+// PullRequest title, body and comment rendered with the values.
+func (i *Input) withMessages(v MessageValues) (*Input, error) {
+	rendered := *i
+	var err error
+	if rendered.CommitMessage, err = renderMessage("commit message", i.CommitMessage, v); err != nil {
+		return nil, err
+	}
+	if rendered.PullRequest.Title, err = renderMessage("PullRequest title", i.PullRequest.Title, v); err != nil {
+		return nil, err
+	}
+	if rendered.PullRequest.Body, err = renderMessage("PullRequest body", i.PullRequest.Body, v); err != nil {
+		return nil, err
+	}
+	if rendered.PullRequest.Comment, err = renderMessage("PullRequest comment", i.PullRequest.Comment, v); err != nil {
+		return nil, err
+	}
+	return &rendered, nil
+}
+
+// ConventionalCommit is a commit message in the Conventional Commits format,
+// e.g. "chore(deploy): bump service-a to v1.4.2", so that changelog tools can
+// process the commits.
+//
+// The Description and Body are templates, with the MessageValues.
+type ConventionalCommit struct {
+	Type        string // e.g. chore
+	Scope       string // Optional, e.g. deploy
+	Description string // e.g. bump {{ .Key }} to {{ .NewValue }}
+	Body        string // Optional
+	Breaking    bool   // Marks the commit as a breaking change
+}
+
+// String returns the commit message, for the CommitMessage of an Input.
+func (c ConventionalCommit) String() string {
+	var b strings.Builder
+	b.WriteString(c.Type)
+	if c.Scope != "" {
+		b.WriteString("(" + c.Scope + ")")
+	}
+	if c.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": " + c.Description)
+	if c.Body != "" {
+		b.WriteString("\n\n" + c.Body)
+	}
+	return b.String()
+}
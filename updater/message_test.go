@@ -0,0 +1,216 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/syaml"
+	"github.com/agill17/pkg/test"
+)
+
+func TestRenderMessage(t *testing.T) {
+	values := MessageValues{Key: "test.image", Previous: "app:v1", NewValue: "app:v2"}
+	renderTests := []struct {
+		message string
+		want    string
+		wantErr string
+	}{
+		{"Update the image", "Update the image", ""},
+		{"Update {{ .Key }} from {{ .Previous }} to {{ .NewValue }}", "Update test.image from app:v1 to app:v2", ""},
+		{"Update to {{ .NewValue | trimPrefix \"app:\" }}", "Update to v2", ""},
+		{"Update {{ .Missing }}", "", "failed to render the commit message: .*Missing"},
+		{"Update {{ .Key", "", "failed to parse the commit message"},
+	}
+
+	for _, tt := range renderTests {
+		t.Run(tt.message, func(rt *testing.T) {
+			got, err := renderMessage("commit message", tt.message, values)
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("got error %v, want %s", err, tt.wantErr)
+			}
+			if got != tt.want {
+				rt.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWithMessageTemplates(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.CommitMessage = "Update the image from {{ .Previous }} to {{ .NewValue }}"
+	input.PullRequest.Title = "Update {{ .Key }}"
+	input.PullRequest.Body = "Previously {{ .Previous }}"
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Update the image from old-image to new-image")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Update test.image",
+		Body:  "Previously old-image",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if diff := cmp.Diff("old-image", *result.Previous); diff != "" {
+		t.Fatalf("incorrect previous value:\n%s", diff)
+	}
+}
+
+func TestUpdateWithNoPreviousValue(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test: {}\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.CommitMessage = "Set the image{{ with .Previous }}, replacing {{ . }}{{ end }}"
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Set the image")
+	if result.Previous != nil {
+		t.Fatalf("got previous value %q, want nil", *result.Previous)
+	}
+}
+
+func TestUpdateBatchWithMessageTemplates(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.CommitMessage = "Update the image from {{ .Previous }}"
+	first.PullRequest.Title = "Image {{ .NewValue }}"
+	second.Key, second.NewValue, second.PullRequest.Title = "test.tag", "v2", "Tag {{ .Previous }} to {{ .NewValue }}"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Update the image from old-image\n\njust a test commit")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Image new-image, Tag v1 to v2",
+		Body:  "This is the body\n\nThis is the body",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithUpdateContextTemplates(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Trigger = &Trigger{Image: "quay.io/my-org/service-a", Tag: "v1.4.2"}
+	input.PullRequest.Title = "Bump {{ .Trigger.Image | trimPrefix \"quay.io/my-org/\" }} to {{ .Trigger.Tag }}"
+	input.PullRequest.Body = "Updating {{ .Filename }} in {{ .Repo }} {{ .Branch }}.\n\n{{ .Diff }}"
+
+	if _, err := updater.Update(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	diff := syaml.UnifiedDiff(testFilePath, []byte("test:\n  image: old-image\n"), []byte("test:\n  image: new-image\n"))
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Bump service-a to v1.4.2",
+		Body:  "Updating " + testFilePath + " in testorg/testrepo main.\n\n" + diff,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithDiffTemplateAndSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  password: old-password\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Key, input.NewValue = "test.password", "new-password"
+	input.SecretKeys = []string{"password$"}
+	input.PullRequest.Body = "{{ .Diff }}"
+
+	if _, err := updater.Update(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the secret value changed, so the masked diff is empty.
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateFilesWithUpdateContextTemplates(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "other.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.CommitMessage = "Update {{ .Filename }}"
+	input.PullRequest.Body = "{{ .Diff }}"
+	input.Files = []FileChange{{Filename: "other.yaml", Key: "test.image", NewValue: "new-image"}}
+
+	if _, err := updater.Update(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, "other.yaml", "test-branch-a", "Update other.yaml")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  syaml.UnifiedDiff(testFilePath, []byte("test:\n  image: old-image\n"), []byte("test:\n  image: new-image\n")),
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestConventionalCommit(t *testing.T) {
+	commitTests := []struct {
+		commit ConventionalCommit
+		want   string
+	}{
+		{ConventionalCommit{Type: "chore", Description: "bump the image"}, "chore: bump the image"},
+		{ConventionalCommit{Type: "chore", Scope: "deploy", Description: "bump the image"}, "chore(deploy): bump the image"},
+		{ConventionalCommit{Type: "feat", Scope: "deploy", Description: "bump the image", Breaking: true}, "feat(deploy)!: bump the image"},
+		{ConventionalCommit{Type: "chore", Description: "bump the image", Body: "The image is v2."}, "chore: bump the image\n\nThe image is v2."},
+	}
+
+	for _, tt := range commitTests {
+		t.Run(tt.want, func(rt *testing.T) {
+			if s := tt.commit.String(); s != tt.want {
+				rt.Errorf("got %q, want %q", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateWithConventionalCommit(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Trigger = &Trigger{Image: "service-a", Tag: "v1.4.2"}
+	input.CommitMessage = ConventionalCommit{Type: "chore", Scope: "deploy", Description: "bump {{ .Trigger.Image }} to {{ .Trigger.Tag }}"}.String()
+
+	if _, err := updater.Update(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "chore(deploy): bump service-a to v1.4.2")
+}
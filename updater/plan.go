@@ -0,0 +1,150 @@
+package updater
+
+import (
+	"context"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// UpdatePlan is what an update would do, as calculated by Plan.
+type UpdatePlan struct {
+	// State is the State the update would have, Unchanged if no files would
+	// be changed.
+	State UpdateState
+	// Commit is where the changes would be committed, with the
+	// BranchGenerateName rendered, a generated branch name is only known
+	// once the branch is created.
+	Commit CommitInput
+	// Files are the files that would be changed.
+	Files []PlannedFile
+	// PullRequest is the PullRequest that would be opened, or the new title
+	// and body of the ReusedPullRequest, nil if the changes would be
+	// committed directly.
+	PullRequest *PullRequestInput
+	// ReusedPullRequest is the open PullRequest that the changes would be
+	// committed to, when the Input has ReusePullRequest.
+	ReusedPullRequest *scm.PullRequest
+}
+
+// PlannedFile is a file that an update would change.
+type PlannedFile struct {
+	Filename      string
+	CommitMessage string
+	// Diff is a unified diff of the change, with the values of secrets
+	// redacted, it's empty for encrypted files, and if only secret values
+	// changed.
+	Diff string
+	// Content is the file as it would be committed, it's nil if the file has
+	// secret values.
+	Content []byte
+}
+
+// Plan calculates what the update for the Input would do, without creating
+// branches, commits or PullRequests, e.g. to preview the automation in a
+// staging environment.
+//
+// The files are read, and the PullRequest found if it would be reused, and
+// the Updater's policy is checked, the NoChange policy of the Input is not
+// applied.
+func (u *Updater) Plan(ctx context.Context, input *Input) (*UpdatePlan, error) {
+	input, err := input.withBranchPrefix(u.now())
+	if err != nil {
+		return nil, err
+	}
+	group := input.fileInputs()
+	commit, reuse := input.commitInput(), input.ReusePullRequest
+	if hashed, ok := u.hashedCommit(commit, group); ok {
+		commit, reuse = hashed, true
+	}
+	if err := u.checkPolicy(commit); err != nil {
+		return nil, err
+	}
+	if err := u.checkWritable(ctx, commit.Repo); err != nil {
+		return nil, err
+	}
+	targets := changeTargets(group)
+	reused, err := u.reusablePullRequest(ctx, commit, reuse, targets)
+	if err != nil {
+		return nil, err
+	}
+	if reused != nil {
+		onBranch := []*Input{}
+		for _, i := range group {
+			onBranch = append(onBranch, i.onBranch(reused.Source))
+		}
+		group = onBranch
+	}
+	changes, rendered, err := u.groupChanges(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	plan := &UpdatePlan{State: Unchanged, Commit: commit}
+	if len(changes) == 0 {
+		return plan, nil
+	}
+	diffs := []string{}
+	for _, c := range changes {
+		f := PlannedFile{Filename: c.filename, CommitMessage: u.sanitize(TextCommitMessage, c.message)}
+		if !c.secrets.any() {
+			f.Content = c.updated
+		}
+		d := c.secrets.diffFile(c.filename, c.original, c.updated, c.input.Encryption != nil)
+		f.Diff = d.unified
+		diff, err := d.render(c.input.PullRequest)
+		if err != nil {
+			return nil, err
+		}
+		if diff != "" {
+			diffs = append(diffs, diff)
+		}
+		plan.Files = append(plan.Files, f)
+	}
+	plan.ReusedPullRequest = reused
+	switch {
+	case reused != nil:
+		plan.State = PullRequestUpdated
+	case commit.newBranch():
+		plan.State = PullRequestCreated
+	default:
+		plan.State = Committed
+		return plan, nil
+	}
+	title, body := groupMessages(PullRequestInput{}, rendered)
+	requests := groupRequests(PullRequestInput{}, rendered)
+	switch {
+	case len(group) == 1 && !isGlob(input.Filename):
+		if input.PullRequest.IncludeDiff && len(diffs) == 1 {
+			body = appendParagraph(body, diffs[0])
+		}
+	case requests.IncludeDiff:
+		diff, err := groupDiff(changes)
+		if err != nil {
+			return nil, err
+		}
+		body = appendParagraph(body, diff)
+	}
+	body = u.withProvenance(body, groupTriggers(rendered)...)
+	body = withTargets(body, targets, reuse || input.Supersede != SupersedeNone)
+	plan.PullRequest = &PullRequestInput{
+		SourceBranch:  input.base(),
+		NewBranch:     commit.NewBranchName,
+		Repo:          input.Repo,
+		Title:         u.sanitize(TextPullRequestTitle, title),
+		Body:          u.pullRequestBody(body),
+		Labels:        requests.Labels,
+		Reviewers:     requests.Reviewers,
+		TeamReviewers: requests.TeamReviewers,
+		Assignees:     requests.Assignees,
+		Draft:         requests.Draft,
+		AutoMerge:     requests.AutoMerge,
+		Milestone:     requests.Milestone,
+		Project:       requests.Project,
+		ProjectColumn: requests.ProjectColumn,
+		Comment:       requests.Comment,
+		CodeOwners:    requests.CodeOwners,
+	}
+	if reused != nil {
+		plan.PullRequest.NewBranch = reused.Source
+	}
+	return plan, nil
+}
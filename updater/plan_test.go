@@ -0,0 +1,186 @@
+package updater
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestPlan(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.CommitMessage = "Update to {{ .NewValue }}"
+	input.PullRequest.IncludeDiff = true
+	input.PullRequest.Labels = []string{"automated"}
+	input.PullRequest.Reviewers = []string{"octocat"}
+	input.PullRequest.TeamReviewers = []string{"my-org/frontend"}
+	input.PullRequest.Assignees = []string{"hubot"}
+	input.PullRequest.Draft = true
+	input.PullRequest.AutoMerge = client.Squash
+	input.PullRequest.Milestone = "v1.2.0"
+
+	plan, err := updater.Plan(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diff := "--- a/environments/test/services/service-a/test.yaml\n+++ b/environments/test/services/service-a/test.yaml\n@@ -1,2 +1,2 @@\n test:\n-  image: old-image\n+  image: new-image\n"
+	want := &UpdatePlan{
+		State: PullRequestCreated,
+		Commit: CommitInput{
+			Repo:               testGitHubRepo,
+			Filename:           testFilePath,
+			Branch:             testBranch,
+			BranchGenerateName: "test-branch-",
+			CommitMessage:      "Update to {{ .NewValue }}",
+		},
+		Files: []PlannedFile{
+			{Filename: testFilePath, CommitMessage: "Update to new-image", Diff: diff, Content: []byte("test:\n  image: new-image\n")},
+		},
+		PullRequest: &PullRequestInput{
+			SourceBranch:  testBranch,
+			Repo:          testGitHubRepo,
+			Title:         "This is a test PR",
+			Body:          "This is the body\n\n```diff\n" + diff + "```",
+			Labels:        []string{"automated"},
+			Reviewers:     []string{"octocat"},
+			TeamReviewers: []string{"my-org/frontend"},
+			Assignees:     []string{"hubot"},
+			Draft:         true,
+			AutoMerge:     client.Squash,
+			Milestone:     "v1.2.0",
+		},
+	}
+	if diff := cmp.Diff(want, plan); diff != "" {
+		t.Fatalf("incorrect plan:\n%s", diff)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	if updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, testBranch); updated != nil {
+		t.Fatalf("got updated file %q", updated)
+	}
+}
+
+func TestPlanStates(t *testing.T) {
+	stateTests := []struct {
+		name    string
+		content string
+		input   func(*Input)
+		openPR  *scm.PullRequest
+		want    UpdateState
+		wantPR  bool
+	}{
+		{"pull request", "test:\n  image: old-image\n", func(*Input) {}, nil, PullRequestCreated, true},
+		{"direct commit", "test:\n  image: old-image\n", func(i *Input) { i.BranchGenerateName = "" }, nil, Committed, false},
+		{"no change", "test:\n  image: new-image\n", func(*Input) {}, nil, Unchanged, false},
+		{"reused pull request", "test:\n  image: old-image\n", func(i *Input) { i.ReusePullRequest = true },
+			&scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: testTargetBody}, PullRequestUpdated, true},
+		{"files", "test:\n  image: old-image\n", func(i *Input) {
+			i.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+		}, nil, PullRequestCreated, true},
+	}
+
+	for _, tt := range stateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(tt.content))
+			m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte(tt.content))
+			m.AddFileContents(testGitHubRepo, testFilePath, testReusedBranch, []byte(tt.content))
+			if tt.openPR != nil {
+				m.AddOpenPullRequest(testGitHubRepo, tt.openPR)
+			}
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			tt.input(input)
+
+			plan, err := updater.Plan(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if plan.State != tt.want {
+				rt.Errorf("got state %s, want %s", plan.State, tt.want)
+			}
+			if (plan.PullRequest != nil) != tt.wantPR {
+				rt.Errorf("got PullRequest %#v, want a PullRequest %v", plan.PullRequest, tt.wantPR)
+			}
+			if plan.ReusedPullRequest != tt.openPR {
+				rt.Errorf("got reused PullRequest %#v, want %#v", plan.ReusedPullRequest, tt.openPR)
+			}
+			m.AssertNoBranchesCreated()
+			m.AssertNoPullRequestsCreated()
+		})
+	}
+}
+
+func TestPlanWithFilesIncludesDiffs(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+	input.PullRequest.IncludeDiff = true
+
+	plan, err := updater.Plan(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{testFilePath, testSecondFilePath} {
+		if b := plan.PullRequest.Body; !strings.Contains(b, "```diff\n--- a/"+filename+"\n") {
+			t.Fatalf("got body %q, want the diff of %s", b, filename)
+		}
+	}
+}
+
+func TestPlanWithSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  password: old-password\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Key, input.NewValue = "test.password", "new-password"
+	input.SecretKeys = []string{`\.password$`}
+	input.PullRequest.IncludeDiff = true
+
+	plan, err := updater.Plan(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := plan.Files[0]
+	if f.Content != nil {
+		t.Fatalf("got content %q for a file with secret values", f.Content)
+	}
+	if f.Diff != "" {
+		t.Fatalf("got diff %q for a change of secret values", f.Diff)
+	}
+	if b := plan.PullRequest.Body; strings.Contains(b, "password") || !strings.Contains(b, secretDiffMessage(testFilePath)) {
+		t.Fatalf("got body %q, want the change not shown", b)
+	}
+}
+
+func TestPlanWithDirectCommitPolicy(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, RequirePullRequests())
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.Plan(context.Background(), input)
+
+	if !test.MatchError(t, "direct commits are not allowed", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,69 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrDirectCommit is returned when the Updater requires PullRequests, and an
+// update would commit directly to the source branch.
+var ErrDirectCommit = errors.New("direct commits are not allowed")
+
+// RequirePullRequests is an option func for the Updater creation function, it
+// rejects updates that would commit directly to the source branch, i.e. with
+// no BranchGenerateName or NewBranchName, before any changes are made.
+func RequirePullRequests() UpdaterFunc {
+	return func(u *Updater) {
+		u.requirePRs = true
+	}
+}
+
+// ProtectedBranchFallback is an option func for the Updater creation function,
+// when a direct commit is rejected as the branch is protected, the change is
+// committed to a new branch, with a name generated from the prefix e.g.
+// update-, and a PullRequest is opened, rather than failing with an error that
+// matches ErrBranchProtected.
+//
+// An open PullRequest from the prefix for the same files and keys is reused.
+func ProtectedBranchFallback(prefix string) UpdaterFunc {
+	return func(u *Updater) {
+		u.fallbackPrefix = prefix
+	}
+}
+
+// fallback returns true if the error is the rejection of a direct commit as
+// the branch is protected, and there is a ProtectedBranchFallback.
+func (u *Updater) fallback(commit CommitInput, err error) bool {
+	if u.fallbackPrefix == "" || commit.newBranch() || !errors.Is(err, ErrBranchProtected) {
+		return false
+	}
+	u.log.Info("branch is protected, committing to a new branch", "repo", commit.Repo, "branch", commit.base(), "err", err.Error())
+	return true
+}
+
+// fallbackCommit returns the commit to a new branch, and true, if the direct
+// commit was rejected as the branch is protected.
+func (u *Updater) fallbackCommit(commit CommitInput, err error) (CommitInput, bool) {
+	if !u.fallback(commit, err) {
+		return commit, false
+	}
+	commit.BranchGenerateName = u.fallbackPrefix
+	return commit, true
+}
+
+// fallbackInput returns a copy of the Input committed to a new branch from the
+// ProtectedBranchFallback prefix, that reuses the open PullRequest for the
+// same change, so repeated updates don't open a PullRequest each, with
+// HashedBranchNames the branch name is derived from the change.
+func (u *Updater) fallbackInput(input *Input) *Input {
+	i := *input
+	i.BranchGenerateName, i.ReusePullRequest = u.fallbackPrefix, true
+	return &i
+}
+
+func (u *Updater) checkPolicy(input CommitInput) error {
+	if !u.requirePRs || input.BranchGenerateName != "" || input.NewBranchName != "" {
+		return nil
+	}
+	return fmt.Errorf("%w: a BranchGenerateName is required to update branch %s in repo %s", ErrDirectCommit, input.base(), input.Repo)
+}
@@ -0,0 +1,213 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestUpdateYAMLWithRequirePullRequests(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RequirePullRequests())
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if !errors.Is(err, ErrDirectCommit) {
+		t.Fatalf("got %v, want %v", err, ErrDirectCommit)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateYAMLWithRequirePullRequestsAndBranch(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RequirePullRequests())
+
+	pr, err := updater.UpdateYAML(context.Background(), makeInput())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr == nil {
+		t.Fatal("no PullRequest was created")
+	}
+}
+
+func TestApplyUpdateToFileWithRequirePullRequests(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RequirePullRequests())
+	input := makeCommitInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), input, ReplaceContents([]byte("test")))
+
+	if !errors.Is(err, ErrDirectCommit) {
+		t.Fatalf("got %v, want %v", err, ErrDirectCommit)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateBatchWithRequirePullRequests(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), RequirePullRequests())
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{input}, GroupByRepo: true})
+
+	if !errors.Is(err, ErrDirectCommit) {
+		t.Fatalf("got %v, want %v", err, ErrDirectCommit)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateWithProtectedBranch(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !errors.Is(err, ErrBranchProtected) {
+		t.Fatalf("got %v, want %v", err, ErrBranchProtected)
+	}
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateWithProtectedBranchFallback(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProtectedBranchFallback("update-"))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated || r.Branch != "update-a" {
+		t.Fatalf("got %s from branch %q, want a PullRequest from update-a", r.State, r.Branch)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "update-a", "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body + "\n\n" + testTargetBody,
+		Head:  "update-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithProtectedBranchFallbackAndOpenPullRequest(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, "update-z", []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, "update-z", "b1ed8e7921ad7c450e53efa19f1ab83b99232b51")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: "update-z", Target: testBranch, Body: testTargetBody})
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProtectedBranchFallback("update-"))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestUpdated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestUpdated)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	if b := m.GetUpdatedContents(testGitHubRepo, testFilePath, "update-z"); string(b) != "test:\n  image: new-image\n" {
+		t.Fatalf("got updated contents %q", b)
+	}
+}
+
+func TestUpdateWithProtectedBranchFallbackAndHashedBranchNames(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), HashedBranchNames(), ProtectedBranchFallback("update-"))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated || r.Branch != "update-4beeb60205" {
+		t.Fatalf("got %s from branch %q, want a PullRequest from update-4beeb60205", r.State, r.Branch)
+	}
+	m.AssertBranchReset(testGitHubRepo, "update-4beeb60205", testSHA)
+}
+
+func TestUpdateWithStaleFileAndProtectedBranchFallback(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.UpdateFileErr = client.NewResponseError("failed to update file", http.StatusConflict, "config/test.yaml does not match 980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProtectedBranchFallback("update-"))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	_, err := updater.Update(context.Background(), input)
+
+	if err == nil || errors.Is(err, ErrBranchProtected) {
+		t.Fatalf("got %v, want a conflict", err)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateBatchGroupedWithProtectedBranchFallback(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProtectedBranchFallback("update-"))
+	staging, production := makeInput(), makeInput()
+	staging.BranchGenerateName = ""
+	production.Filename, production.BranchGenerateName = testSecondFilePath, ""
+
+	results, err := updater.UpdateBatchResults(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+		PullRequest: PullRequestInput{Title: "Update the images", Body: "Updating the images."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := results[0].State; s != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", s, PullRequestCreated)
+	}
+	for _, filename := range []string{testFilePath, testSecondFilePath} {
+		if b := m.GetUpdatedContents(testGitHubRepo, filename, "update-a"); string(b) != "test:\n  image: new-image\n" {
+			t.Fatalf("got updated contents %q of %s", b, filename)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+)
+
+// PostAction is applied to the PullRequest opened by an update, e.g. to label
+// it, request reviews or notify a channel.
+//
+// The PostActions of an Input are applied in order, after the PullRequest is
+// created, a failing action doesn't stop the others, or fail the update, its
+// error is recorded in the UpdateResult.
+type PostAction interface {
+	// Name identifies the action in logs and errors, e.g. add-labels.
+	Name() string
+	Apply(ctx context.Context, t PostActionTarget) error
+}
+
+// PostActionTarget is the PullRequest that a PostAction is applied to.
+type PostActionTarget struct {
+	Client      client.GitClient
+	Repo        string
+	PullRequest *scm.PullRequest
+	// Sanitize applies the Updater's Sanitizers, e.g. to comments.
+	Sanitize Sanitizer
+}
+
+// PostActionError records the failure of a PostAction.
+type PostActionError struct {
+	Action string
+	Err    error
+}
+
+func (e *PostActionError) Error() string {
+	return fmt.Sprintf("failed to apply post-action %s: %s", e.Action, e.Err)
+}
+
+// Unwrap returns the error from the PostAction.
+func (e *PostActionError) Unwrap() error {
+	return e.Err
+}
+
+// PostActionFunc adapts a func to a PostAction with the name.
+func PostActionFunc(name string, f func(ctx context.Context, t PostActionTarget) error) PostAction {
+	return funcAction{name: name, f: f}
+}
+
+type funcAction struct {
+	name string
+	f    func(ctx context.Context, t PostActionTarget) error
+}
+
+func (a funcAction) Name() string {
+	return a.name
+}
+
+func (a funcAction) Apply(ctx context.Context, t PostActionTarget) error {
+	return a.f(ctx, t)
+}
+
+// AddLabels is a PostAction that adds the labels to the PullRequest.
+func AddLabels(labels ...string) PostAction {
+	return PostActionFunc("add-labels", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.AddLabels(ctx, t.Repo, t.PullRequest.Number, labels)
+	})
+}
+
+// RequestReviewers is a PostAction that requests reviews of the PullRequest
+// from the users.
+func RequestReviewers(logins ...string) PostAction {
+	return PostActionFunc("request-reviewers", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.RequestReviewers(ctx, t.Repo, t.PullRequest.Number, logins)
+	})
+}
+
+// RequestTeamReviewers is a PostAction that requests reviews of the
+// PullRequest from the teams, e.g. my-org/frontend.
+func RequestTeamReviewers(teams ...string) PostAction {
+	return PostActionFunc("request-team-reviewers", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.RequestTeamReviewers(ctx, t.Repo, t.PullRequest.Number, teams)
+	})
+}
+
+// AddAssignees is a PostAction that assigns the users to the PullRequest.
+func AddAssignees(logins ...string) PostAction {
+	return PostActionFunc("add-assignees", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.AddAssignees(ctx, t.Repo, t.PullRequest.Number, logins)
+	})
+}
+
+// Comment is a PostAction that comments on the PullRequest, e.g. with
+// instructions for reviewers.
+func Comment(body string) PostAction {
+	return PostActionFunc("comment", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.CreateComment(ctx, t.Repo, t.PullRequest.Number, t.Sanitize(TextComment, body))
+	})
+}
+
+// SetMilestone is a PostAction that adds the PullRequest to the open
+// milestone with the title.
+func SetMilestone(title string) PostAction {
+	return PostActionFunc("set-milestone", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.SetMilestone(ctx, t.Repo, t.PullRequest.Number, title)
+	})
+}
+
+// AddToProject is a PostAction that adds the PullRequest to the GitHub
+// project with the node ID, and if the column isn't empty, sets its Status.
+func AddToProject(project, column string) PostAction {
+	return PostActionFunc("add-to-project", func(ctx context.Context, t PostActionTarget) error {
+		return t.Client.AddToProject(ctx, t.Repo, t.PullRequest.Number, project, column)
+	})
+}
+
+// Notify is a PostAction that calls the func with the PullRequest, e.g. to
+// post a chat message.
+func Notify(name string, f func(ctx context.Context, repo string, pr *scm.PullRequest) error) PostAction {
+	return PostActionFunc(name, func(ctx context.Context, t PostActionTarget) error {
+		return f(ctx, t.Repo, t.PullRequest)
+	})
+}
+
+// EnableAutoMerge is a PostAction that merges the PullRequest with the
+// method, e.g. client.Squash, once its checks pass, if there is nothing to wait
+// for, e.g. the checks have passed, it's merged now.
+//
+// It fails if the git provider doesn't support auto-merge.
+func EnableAutoMerge(method string) PostAction {
+	return PostActionFunc("enable-auto-merge", func(ctx context.Context, t PostActionTarget) error {
+		switch method {
+		case client.MergeCommit, client.Squash, client.Rebase:
+		default:
+			return fmt.Errorf("unknown merge method %q", method)
+		}
+		caps, err := t.Client.Capabilities(ctx)
+		if err != nil {
+			return err
+		}
+		if !caps.AutoMerge {
+			return client.ErrAutoMergeUnsupported
+		}
+		err = t.Client.EnableAutoMerge(ctx, t.Repo, t.PullRequest.Number, method)
+		if errors.Is(err, client.ErrMergeable) {
+			return t.Client.MergePullRequest(ctx, t.Repo, t.PullRequest.Number, method)
+		}
+		return err
+	})
+}
+
+// withRequests returns the actions, preceded by the actions that add the
+// Labels, Reviewers, TeamReviewers, CodeOwners of the changed files in the
+// branch, Assignees, Milestone, Project and Comment of the PullRequestInput,
+// and followed by enabling its AutoMerge, so that it's merged last.
+func withRequests(pr PullRequestInput, branch string, changed []string, actions []PostAction) []PostAction {
+	if pr.AutoMerge != "" {
+		actions = append(append([]PostAction{}, actions...), EnableAutoMerge(pr.AutoMerge))
+	}
+	var requests []PostAction
+	if len(pr.Labels) > 0 {
+		requests = append(requests, AddLabels(pr.Labels...))
+	}
+	if len(pr.Reviewers) > 0 {
+		requests = append(requests, RequestReviewers(pr.Reviewers...))
+	}
+	if len(pr.TeamReviewers) > 0 {
+		requests = append(requests, RequestTeamReviewers(pr.TeamReviewers...))
+	}
+	if pr.CodeOwners {
+		requests = append(requests, RequestCodeOwners(branch, changed...))
+	}
+	if len(pr.Assignees) > 0 {
+		requests = append(requests, AddAssignees(pr.Assignees...))
+	}
+	if pr.Milestone != "" {
+		requests = append(requests, SetMilestone(pr.Milestone))
+	}
+	if pr.Project != "" {
+		requests = append(requests, AddToProject(pr.Project, pr.ProjectColumn))
+	}
+	if pr.Comment != "" {
+		requests = append(requests, Comment(pr.Comment))
+	}
+	if len(requests) == 0 {
+		return actions
+	}
+	return append(requests, actions...)
+}
+
+// applyPostActions applies the actions to the PullRequest, and returns the
+// errors of the actions that failed.
+func (u *Updater) applyPostActions(ctx context.Context, repo string, pr *scm.PullRequest, actions []PostAction) []error {
+	var errs []error
+	t := PostActionTarget{Client: u.gitClient, Repo: repo, PullRequest: pr, Sanitize: u.sanitize}
+	for _, a := range actions {
+		if err := a.Apply(ctx, t); err != nil {
+			u.log.Error(err, "failed to apply post-action", "action", a.Name(), "repo", repo, "number", pr.Number)
+			errs = append(errs, &PostActionError{Action: a.Name(), Err: err})
+			continue
+		}
+		u.log.Info("applied post-action", "action", a.Name(), "number", pr.Number)
+	}
+	return errs
+}
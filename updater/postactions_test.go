@@ -0,0 +1,281 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateWithPostActions(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	var notified int
+	input := makeInput()
+	input.PostActions = []PostAction{
+		AddLabels("automated", "image-update"),
+		RequestReviewers("octocat"),
+		Comment("Please review the image"),
+		Notify("notify", func(ctx context.Context, repo string, pr *scm.PullRequest) error {
+			notified = pr.Number
+			return nil
+		}),
+		EnableAutoMerge(client.Squash),
+	}
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertLabelsAdded(testGitHubRepo, 1, "automated", "image-update")
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat")
+	m.AssertCommentCreated(testGitHubRepo, 1, "Please review the image")
+	m.AssertAutoMergeEnabled(testGitHubRepo, 1, client.Squash)
+	if notified != 1 {
+		t.Fatalf("got notified for PullRequest %d, want 1", notified)
+	}
+}
+
+func TestUpdateWithFailingPostAction(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddLabelsErr = errors.New("labels are not available")
+	m.Caps.AutoMerge = false
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PostActions = []PostAction{AddLabels("automated"), RequestReviewers("octocat"), EnableAutoMerge(client.Squash)}
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want pull-request-created", r.State)
+	}
+	if l := len(r.PostActionErrors); l != 2 {
+		t.Fatalf("got %d post-action errors, want 2", l)
+	}
+	if !test.MatchError(t, "failed to apply post-action add-labels: labels are not available", r.PostActionErrors[0]) {
+		t.Fatalf("failed to match error: %s", r.PostActionErrors[0])
+	}
+	if !test.MatchError(t, "failed to apply post-action enable-auto-merge: the git provider does not support auto-merge", r.PostActionErrors[1]) {
+		t.Fatalf("failed to match error: %s", r.PostActionErrors[1])
+	}
+	var actionErr *PostActionError
+	if !errors.As(r.PostActionErrors[0], &actionErr) || !errors.Is(actionErr, m.AddLabelsErr) {
+		t.Fatalf("got %#v, want a PostActionError", r.PostActionErrors[0])
+	}
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat")
+}
+
+func TestUpdateWithComment(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Trigger = &Trigger{Source: TriggerRegistry, Image: "quay.io/my-org/my-image", Tag: "v1.4.2"}
+	input.PullRequest.Comment = "Built from {{ .Trigger.ImageRef }}, was {{ .Previous }}"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertCommentCreated(testGitHubRepo, 1, "Built from quay.io/my-org/my-image:v1.4.2, was old-image")
+}
+
+func TestUpdateWithInvalidComment(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Comment = "{{ .Unknown }}"
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "failed to render the PullRequest comment", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUpdateWithMilestoneAndProject(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Milestone = "v1.2.0"
+	input.PullRequest.Project = "PVT_kwDOA"
+	input.PullRequest.ProjectColumn = "In review"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertMilestoneSet(testGitHubRepo, 1, "v1.2.0")
+	m.AssertAddedToProject(testGitHubRepo, 1, "PVT_kwDOA", "In review")
+}
+
+func TestUpdateWithAutoMerge(t *testing.T) {
+	autoMergeTests := []struct {
+		name       string
+		enableErr  error
+		wantMerged bool
+	}{
+		{"waits for checks", nil, false},
+		{"merges now when checks have passed", fmt.Errorf("failed to enable auto-merge: %w", client.ErrMergeable), true},
+	}
+
+	for _, tt := range autoMergeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.EnableAutoMergeErr = tt.enableErr
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.PullRequest.AutoMerge = client.Rebase
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r.PostActionErrors != nil {
+				rt.Fatalf("got post-action errors %v", r.PostActionErrors)
+			}
+			if tt.wantMerged {
+				m.AssertPullRequestMerged(testGitHubRepo, 1, client.Rebase)
+			} else {
+				m.AssertAutoMergeEnabled(testGitHubRepo, 1, client.Rebase)
+				m.RefutePullRequestMerged(testGitHubRepo, 1)
+			}
+		})
+	}
+}
+
+func TestUpdateWithAutoMergeFailures(t *testing.T) {
+	autoMergeTests := []struct {
+		name    string
+		method  string
+		modify  func(*mock.MockClient)
+		wantErr string
+	}{
+		{"unknown method", "fast-forward", func(*mock.MockClient) {}, `failed to apply post-action enable-auto-merge: unknown merge method "fast-forward"`},
+		{"failing merge", client.Squash, func(m *mock.MockClient) {
+			m.EnableAutoMergeErr = client.ErrMergeable
+			m.MergePullRequestErr = errors.New("the base branch is protected")
+		}, "failed to apply post-action enable-auto-merge: the base branch is protected"},
+	}
+
+	for _, tt := range autoMergeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			tt.modify(m)
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.PullRequest.AutoMerge = tt.method
+			// Auto-merge is enabled after the other actions.
+			input.PostActions = []PostAction{Comment("Updated")}
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if l := len(r.PostActionErrors); l != 1 {
+				rt.Fatalf("got %d post-action errors, want 1", l)
+			}
+			if !test.MatchError(rt, tt.wantErr, r.PostActionErrors[0]) {
+				rt.Fatalf("failed to match error: %s", r.PostActionErrors[0])
+			}
+			m.AssertCommentCreated(testGitHubRepo, 1, "Updated")
+			m.RefutePullRequestMerged(testGitHubRepo, 1)
+		})
+	}
+}
+
+func TestUpdateWithPostActionsAndDirectCommit(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.BranchGenerateName = ""
+	input.PostActions = []PostAction{AddLabels("automated")}
+
+	if _, err := updater.Update(context.Background(), input); err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertLabelsAdded(testGitHubRepo, 1)
+}
+
+func TestUpdateBatchWithPostActions(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.PostActions = []PostAction{AddLabels("first")}
+	second.Filename = testSecondFilePath
+	second.PostActions = []PostAction{AddLabels("second")}
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertLabelsAdded(testGitHubRepo, 1, "first")
+}
+
+func TestCommentSanitizes(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, Sanitizers(StripHostnames("[redacted]", "internal.example.com")))
+	pr := &scm.PullRequest{Number: 2}
+
+	errs := updater.applyPostActions(context.Background(), testGitHubRepo, pr, []PostAction{Comment("See https://internal.example.com/build/1")})
+
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	m.AssertCommentCreated(testGitHubRepo, 2, "See https://[redacted]/build/1")
+}
+
+func TestFileStoreWithPostActions(t *testing.T) {
+	input := makeInput()
+	input.PostActions = []PostAction{AddLabels("automated")}
+
+	err := makeFileStore(t).Save(context.Background(), &DelayedUpdate{ID: "update-a", NotBefore: time.Now(), Input: input})
+
+	if !test.MatchError(t, "update update-a has PostActions, which can't be persisted", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PreflightError is returned when the preflight checks fail, it reports all of
+// the problems found, rather than just the first.
+type PreflightError struct {
+	Repo     string
+	Problems []string
+}
+
+func (e PreflightError) Error() string {
+	return fmt.Sprintf("preflight checks failed for repo %s: %s", e.Repo, strings.Join(e.Problems, "; "))
+}
+
+// Preflight is an option func for the Updater creation function, it enables
+// checking that the repository can be read from and written to before any
+// changes are made, so that updates don't fail midway leaving a partial state
+// e.g. a branch with no PullRequest.
+func Preflight() UpdaterFunc {
+	return func(u *Updater) {
+		u.preflight = true
+	}
+}
+
+// preflightChecks checks that the files can be read from the branch, and the
+// repository written to, the files must not be globs.
+func (u *Updater) preflightChecks(ctx context.Context, input CommitInput, filenames []string) error {
+	if !u.preflight {
+		return nil
+	}
+	problems := []string{}
+	for _, filename := range filenames {
+		if _, err := u.gitClient.GetFile(ctx, input.Repo, input.Branch, filename); err != nil {
+			problems = append(problems, fmt.Sprintf("can't read file %s from branch %s: %s", filename, input.Branch, err))
+		}
+	}
+	if _, err := u.gitClient.GetBranchHead(ctx, input.Repo, input.base()); err != nil {
+		problems = append(problems, fmt.Sprintf("can't get the head of branch %s: %s", input.base(), err))
+	}
+	perm, err := u.gitClient.GetRepoPermissions(ctx, input.Repo)
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("can't get the repository permissions: %s", err))
+	} else if !perm.Push && input.Fork == "" && u.forks == nil {
+		if input.BranchGenerateName == "" && input.NewBranchName == "" {
+			problems = append(problems, fmt.Sprintf("no push permission to commit to branch %s", input.base()))
+		} else {
+			problems = append(problems, "no push permission to create a branch and open a PullRequest")
+		}
+	}
+	if len(problems) > 0 {
+		u.log.Info("preflight checks failed", "repo", input.Repo, "problems", problems)
+		return PreflightError{Repo: input.Repo, Problems: problems}
+	}
+	return nil
+}
+
+// preflightFiles returns the files of the inputs that must exist, the file
+// of an Input with a Source is created if it doesn't.
+func preflightFiles(inputs []*Input) []string {
+	seen := map[string]bool{}
+	files := []string{}
+	for _, i := range inputs {
+		if i.Source != nil || seen[i.Filename] {
+			continue
+		}
+		seen[i.Filename] = true
+		files = append(files, i.Filename)
+	}
+	return files
+}
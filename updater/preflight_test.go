@@ -0,0 +1,137 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestUpdateYAMLWithPreflight(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true, Push: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+
+	_, err := updater.UpdateYAML(context.Background(), makeInput())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+}
+
+func TestUpdateYAMLWithFailingPreflight(t *testing.T) {
+	m := mock.New(t)
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+
+	_, err := updater.UpdateYAML(context.Background(), makeInput())
+
+	var pe PreflightError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got %#v, want a PreflightError", err)
+	}
+	want := []string{
+		"can't read file " + testFilePath + " from branch main: not found",
+		"can't get the head of branch main: not found",
+		"no push permission to create a branch and open a PullRequest",
+	}
+	if diff := cmp.Diff(want, pe.Problems); diff != "" {
+		t.Fatalf("preflight problems differ:\n%s", diff)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateWithPreflightAndGlobFilename(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "environments/production/services/service-a/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, "environments/staging/services/service-a/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true, Push: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+	input := makeInput()
+	input.Filename = "environments/*/services/service-a/app.yaml"
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+}
+
+func TestUpdateWithFailingPreflightAndGlobFilename(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "environments/production/services/service-a/app.yaml", testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+	input := makeInput()
+	input.Filename = "environments/*/services/service-a/app.yaml"
+
+	_, err := updater.Update(context.Background(), input)
+
+	var pe PreflightError
+	if !errors.As(err, &pe) {
+		t.Fatalf("got %#v, want a PreflightError", err)
+	}
+	want := []string{
+		"can't get the head of branch main: not found",
+		"no push permission to create a branch and open a PullRequest",
+	}
+	if diff := cmp.Diff(want, pe.Problems); diff != "" {
+		t.Fatalf("preflight problems differ:\n%s", diff)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateWithPreflightAndSourceCreatingFile(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testSourceRepo, "service/values.yaml", "release", []byte("test:\n  image: template-image\n"))
+	m.AddMissingFile(testGitHubRepo, testFilePath, testBranch)
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	m.AddRepoPermissions(testGitHubRepo, &scm.Perm{Pull: true, Push: true})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+	input := makeInput()
+	input.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	want := "test:\n  image: new-image\n"
+	if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+}
+
+func TestApplyUpdateToFileWithFailingPreflight(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Preflight())
+
+	_, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), ReplaceContents([]byte("testing")))
+
+	if !test.MatchError(t, "preflight checks failed for repo testorg/testrepo: can't get the repository permissions: not found", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
@@ -0,0 +1,54 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// PreviewTarget identifies the PullRequest in the source repo that triggered
+// the updates, e.g. the release PullRequest for an application.
+type PreviewTarget struct {
+	Repo   string // e.g. my-org/my-app
+	Number int
+}
+
+// Preview calculates the changes that the inputs would make, and posts them
+// as a comment on the target PullRequest, no branches, commits or
+// PullRequests are created in the repos being updated.
+//
+// Inputs that update the same file are applied in order, as UpdateBatch does
+// when grouping by repo, once the source PullRequest is merged, the inputs
+// can be applied with UpdateBatch.
+//
+// The body of the comment is returned.
+func (u *Updater) Preview(ctx context.Context, target PreviewTarget, inputs []*Input) (string, error) {
+	var b strings.Builder
+	b.WriteString("### Preview of the updates\n")
+	for _, group := range groupInputs(inputs) {
+		first := group[0]
+		changes, _, err := u.groupChanges(ctx, group)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("\n")
+		if len(changes) == 0 {
+			fmt.Fprintf(&b, "No change required in %s (%s).\n", first.Repo, first.Branch)
+			continue
+		}
+		fmt.Fprintf(&b, "**%s** (%s)\n", first.Repo, first.Branch)
+		for _, c := range changes {
+			rendered, err := c.diff()
+			if err != nil {
+				return "", err
+			}
+			b.WriteString("\n" + rendered + "\n")
+		}
+	}
+	body := u.sanitize(TextComment, b.String())
+	if err := u.gitClient.CreateComment(ctx, target.Repo, target.Number, body); err != nil {
+		return "", fmt.Errorf("failed to comment on the PullRequest: %w", err)
+	}
+	u.log.Info("posted a preview of the updates", "repo", target.Repo, "number", target.Number)
+	return body, nil
+}
@@ -0,0 +1,49 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+func TestPreview(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testOtherRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	updater := New(zap.New(), m)
+	first, second := makeInput(), makeInput()
+	second.Repo = testOtherRepo
+
+	body, err := updater.Preview(context.Background(), PreviewTarget{Repo: "testorg/app", Number: 5}, []*Input{first, second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "### Preview of the updates\n\n" +
+		"**" + testGitHubRepo + "** (" + testBranch + ")\n\n" +
+		"```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,2 +1,2 @@\n test:\n-  image: old-image\n+  image: new-image\n```\n\n" +
+		"No change required in " + testOtherRepo + " (" + testBranch + ").\n"
+	if body != want {
+		t.Fatalf("got %#v, want %#v", body, want)
+	}
+	m.AssertCommentCreated("testorg/app", 5, want)
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestPreviewWithFailedComment(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.CreateCommentErr = errors.New("forbidden")
+	updater := New(zap.New(), m)
+
+	_, err := updater.Preview(context.Background(), PreviewTarget{Repo: "testorg/app", Number: 5}, []*Input{makeInput()})
+
+	if !test.MatchError(t, "failed to comment on the PullRequest: forbidden", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
@@ -0,0 +1,68 @@
+package updater
+
+import "strings"
+
+// ProvenanceTrailers is an option func for the Updater creation function, when
+// configured, commit messages and PullRequest bodies end with trailers
+// recording the Trigger of the change, and the version of the updater, so that
+// automated changes can be traced back to the event that caused them, e.g.
+//
+//	Trigger-Source: registry
+//	Trigger-Image: quay.io/my-org/my-image:v1.4.2@sha256:...
+//	Trigger-Run-URL: https://ci.example.com/runs/1234
+//	Updated-By: agill17-pkg/v1.2.0 (abc1234)
+//
+// The Updated-By trailer replaces the BuildInfoTrailer, if both are configured.
+func ProvenanceTrailers() UpdaterFunc {
+	return func(u *Updater) {
+		u.provenance = true
+	}
+}
+
+// withProvenance appends the provenance trailers of the triggers to the
+// message, if configured, the lines common to the triggers are only included
+// once.
+func (u *Updater) withProvenance(message string, triggers ...*Trigger) string {
+	if !u.provenance {
+		return message
+	}
+	seen := map[string]bool{}
+	lines := []string{}
+	for _, t := range triggers {
+		for _, line := range t.trailers() {
+			if !seen[line] {
+				seen[line] = true
+				lines = append(lines, line)
+			}
+		}
+	}
+	lines = append(lines, buildInfoTrailer())
+	return appendParagraph(message, strings.Join(lines, "\n"))
+}
+
+// trailers returns the git trailers describing the Trigger.
+func (t *Trigger) trailers() []string {
+	if t == nil {
+		return nil
+	}
+	lines := []string{}
+	for _, f := range []struct {
+		k, v string
+	}{{"Source", string(t.Source)}, {"Image", t.ImageRef()}, {"Commit", t.Commit}, {"Actor", t.Actor}, {"Payload-Ref", t.PayloadRef}, {"Run-URL", t.RunURL}} {
+		if f.v != "" {
+			lines = append(lines, "Trigger-"+f.k+": "+f.v)
+		}
+	}
+	return lines
+}
+
+// groupTriggers returns the Triggers of the Inputs.
+func groupTriggers(group []*Input) []*Trigger {
+	triggers := []*Trigger{}
+	for _, input := range group {
+		if input.Trigger != nil {
+			triggers = append(triggers, input.Trigger)
+		}
+	}
+	return triggers
+}
@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/buildinfo"
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestUpdateWithProvenanceTrailers(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProvenanceTrailers(), BuildInfoTrailer())
+	input := makeInput()
+	input.Trigger = &Trigger{Source: TriggerRegistry, Image: "quay.io/my-org/my-image", Tag: "v1", Digest: "sha256:abc", RunURL: "https://ci.example.com/runs/1234"}
+
+	_, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trailers := "Trigger-Source: registry\nTrigger-Image: quay.io/my-org/my-image:v1@sha256:abc\nTrigger-Run-URL: https://ci.example.com/runs/1234\nUpdated-By: agill17-pkg/" + buildinfo.Get().String()
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "just a test commit\n\n"+trailers)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  "This is the body\n\n" + trailers,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateBatchGroupedWithProvenanceTrailers(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProvenanceTrailers())
+	trigger := &Trigger{Source: TriggerRegistry, Image: "quay.io/my-org/my-image", Tag: "v1"}
+	staging, production := makeInput(), makeInput()
+	staging.Trigger = trigger
+	production.Filename = testSecondFilePath
+	production.Trigger = trigger
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:      []*Input{staging, production},
+		GroupByRepo: true,
+		PullRequest: PullRequestInput{Title: "Update the images", Body: "Updating the images."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Update the images",
+		Body:  "Updating the images.\n\nTrigger-Source: registry\nTrigger-Image: quay.io/my-org/my-image:v1\nUpdated-By: agill17-pkg/" + buildinfo.Get().String(),
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestWithProvenance(t *testing.T) {
+	updatedBy := "Updated-By: agill17-pkg/" + buildinfo.Get().String()
+	provenanceTests := []struct {
+		name     string
+		opts     []UpdaterFunc
+		triggers []*Trigger
+		want     string
+	}{
+		{"not configured", nil, []*Trigger{{Source: TriggerManual}}, "Update"},
+		{"no trigger", []UpdaterFunc{ProvenanceTrailers()}, nil, "Update\n\n" + updatedBy},
+		{"all fields", []UpdaterFunc{ProvenanceTrailers()},
+			[]*Trigger{{Source: TriggerWebhook, Commit: "abc1234", Actor: "octocat", PayloadRef: "delivery-1", RunURL: "https://ci.example.com/runs/1"}},
+			"Update\n\nTrigger-Source: webhook\nTrigger-Commit: abc1234\nTrigger-Actor: octocat\nTrigger-Payload-Ref: delivery-1\nTrigger-Run-URL: https://ci.example.com/runs/1\n" + updatedBy},
+		{"several triggers", []UpdaterFunc{ProvenanceTrailers()},
+			[]*Trigger{{Source: TriggerRegistry, Image: "quay.io/test/image", Tag: "v1"}, {Source: TriggerRegistry, Image: "quay.io/test/image", Tag: "v2"}},
+			"Update\n\nTrigger-Source: registry\nTrigger-Image: quay.io/test/image:v1\nTrigger-Image: quay.io/test/image:v2\n" + updatedBy},
+	}
+
+	for _, tt := range provenanceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := New(zap.New(), mock.New(t), tt.opts...)
+			if diff := cmp.Diff(tt.want, u.withProvenance("Update", tt.triggers...)); diff != "" {
+				t.Fatalf("message differs:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrRepoReadOnly is matched by the errors when the repo to update is
+// archived or disabled, and can't be changed.
+var ErrRepoReadOnly = errors.New("the repository is read-only")
+
+// ReadOnlyRepoError is returned when the repo to update is archived or
+// disabled, it's detected before any changes are made.
+type ReadOnlyRepoError struct {
+	Repo     string
+	Archived bool
+	Disabled bool
+}
+
+func (e *ReadOnlyRepoError) Error() string {
+	if e.Archived {
+		return fmt.Sprintf("repository %s is archived", e.Repo)
+	}
+	return fmt.Sprintf("repository %s is disabled", e.Repo)
+}
+
+// Is returns true if the target is ErrRepoReadOnly.
+func (e *ReadOnlyRepoError) Is(target error) bool {
+	return target == ErrRepoReadOnly
+}
+
+// checkWritable returns a ReadOnlyRepoError if the repo is archived or
+// disabled.
+func (u *Updater) checkWritable(ctx context.Context, repo string) error {
+	status, err := u.gitClient.GetRepoStatus(ctx, repo)
+	if err != nil {
+		return scmError(err, repo, "get the status of repo "+repo, nil)
+	}
+	if status.ReadOnly() {
+		u.log.Info("repository is read-only", "repo", repo, "archived", status.Archived, "disabled", status.Disabled)
+		return &ReadOnlyRepoError{Repo: repo, Archived: status.Archived, Disabled: status.Disabled}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateWithReadOnlyRepo(t *testing.T) {
+	statusTests := []struct {
+		status  *client.RepoStatus
+		wantErr string
+	}{
+		{&client.RepoStatus{Archived: true}, "repository testorg/testrepo is archived"},
+		{&client.RepoStatus{Disabled: true}, "repository testorg/testrepo is disabled"},
+	}
+
+	for _, tt := range statusTests {
+		t.Run(tt.wantErr, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.AddRepoStatus(testGitHubRepo, tt.status)
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+			_, err := updater.Update(context.Background(), makeInput())
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			var readOnly *ReadOnlyRepoError
+			if !errors.Is(err, ErrRepoReadOnly) || !errors.As(err, &readOnly) || readOnly.Repo != testGitHubRepo {
+				rt.Fatalf("got %#v, want a ReadOnlyRepoError", err)
+			}
+			m.AssertNoBranchesCreated()
+			m.AssertNoPullRequestsCreated()
+		})
+	}
+}
+
+func TestUpdateWithRepoStatusError(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.GetRepoStatusErr = client.NewStatusError("forbidden", http.StatusForbidden)
+	updater := New(zap.New(), m)
+
+	_, err := updater.Update(context.Background(), makeInput())
+
+	if !test.MatchError(t, "failed to get the status of repo testorg/testrepo: forbidden", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("got %#v, want ErrPermissionDenied", err)
+	}
+}
+
+func TestPlanWithReadOnlyRepo(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddRepoStatus(testGitHubRepo, &client.RepoStatus{Archived: true})
+	updater := New(zap.New(), m)
+
+	_, err := updater.Plan(context.Background(), makeInput())
+
+	if !errors.Is(err, ErrRepoReadOnly) {
+		t.Fatalf("got %#v, want ErrRepoReadOnly", err)
+	}
+}
@@ -0,0 +1,123 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+)
+
+// ErrNotMerged is returned when a release is requested for a PullRequest that
+// has not been merged.
+var ErrNotMerged = errors.New("the PullRequest has not been merged")
+
+// ReleaseInput configures the annotated tag, and optional provider Release,
+// created when an automation PullRequest is merged.
+//
+// The names and messages are text/template templates, with the functions of
+// TransformUpdater, executed with a ReleaseData, the defaults are the already
+// rendered values.
+type ReleaseInput struct {
+	Repo          string // e.g. my-org/my-repo
+	TagName       string // e.g. "promotion-{{ .Number }}"
+	Message       string // The tag message, this defaults to the tag name
+	CreateRelease bool
+	ReleaseName   string // This defaults to the tag name
+	ReleaseBody   string // This defaults to the tag message
+}
+
+// ReleaseData is the data available to the ReleaseInput templates.
+type ReleaseData struct {
+	Number int    // The PullRequest number
+	Title  string // The PullRequest title
+	Branch string // The base branch the PullRequest was merged into
+	SHA    string // The merge commit
+	Date   time.Time
+}
+
+// ReleaseMerged tags the merge commit of a merged PullRequest, and optionally
+// creates a Release for the tag, it returns the name of the tag.
+func (u *Updater) ReleaseMerged(ctx context.Context, pr *scm.PullRequest, input *ReleaseInput) (string, error) {
+	if !pr.Merged || pr.MergeSha == "" {
+		return "", ErrNotMerged
+	}
+	caps, err := u.gitClient.Capabilities(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git provider capabilities: %w", err)
+	}
+	if !caps.Releases {
+		return "", errors.New("the git provider does not support releases")
+	}
+	data := ReleaseData{Number: pr.Number, Title: pr.Title, Branch: pr.Base.Ref, SHA: pr.MergeSha, Date: time.Now().UTC()}
+	tag, err := executeTemplate("tag name template", input.TagName, data)
+	if err != nil {
+		return "", err
+	}
+	if tag == "" {
+		return "", errors.New("the tag name is empty")
+	}
+	message, err := executeDefault("tag message template", input.Message, tag, data)
+	if err != nil {
+		return "", err
+	}
+	if err := u.gitClient.CreateTag(ctx, input.Repo, tag, pr.MergeSha, u.sanitize(TextCommitMessage, message)); err != nil {
+		return "", fmt.Errorf("failed to create tag %s: %w", tag, err)
+	}
+	u.log.Info("created tag", "tag", tag, "sha", pr.MergeSha)
+	if !input.CreateRelease {
+		return tag, nil
+	}
+	name, err := executeDefault("release name template", input.ReleaseName, tag, data)
+	if err != nil {
+		return tag, err
+	}
+	body, err := executeDefault("release body template", input.ReleaseBody, message, data)
+	if err != nil {
+		return tag, err
+	}
+	release := &client.ReleaseInput{
+		Tag:  tag,
+		Name: u.sanitize(TextPullRequestTitle, name),
+		Body: u.sanitize(TextPullRequestBody, body),
+	}
+	if err := u.gitClient.CreateRelease(ctx, input.Repo, release); err != nil {
+		return tag, fmt.Errorf("failed to create release %s: %w", tag, err)
+	}
+	u.log.Info("created release", "tag", tag)
+	return tag, nil
+}
+
+// executeTemplate executes the text as a template with the transform
+// functions and the data.
+func executeTemplate(name, text string, data interface{}) (string, error) {
+	t, err := parseTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to execute the %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// executeDefault executes the text as a template, or returns the default,
+// which is already rendered, if the text is empty.
+func executeDefault(name, text, def string, data interface{}) (string, error) {
+	if text == "" {
+		return def, nil
+	}
+	return executeTemplate(name, text, data)
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
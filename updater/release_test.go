@@ -0,0 +1,135 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testMergeSHA = "aa218f56b14c9653891f9e74264a383fa43fefbd"
+
+func makeMergedPullRequest() *scm.PullRequest {
+	return &scm.PullRequest{
+		Number:   12,
+		Title:    "Promote new-image",
+		Merged:   true,
+		MergeSha: testMergeSHA,
+		Base:     scm.PullRequestBranch{Ref: testBranch},
+	}
+}
+
+func TestReleaseMergedCreatesTag(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+
+	tag, err := updater.ReleaseMerged(context.Background(), makeMergedPullRequest(), &ReleaseInput{
+		Repo:    testGitHubRepo,
+		TagName: "promotion-{{ .Number }}",
+		Message: "{{ .Title }} into {{ .Branch }}",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tag != "promotion-12" {
+		t.Fatalf("got tag %#v, want %#v", tag, "promotion-12")
+	}
+	m.AssertTagCreated(testGitHubRepo, "promotion-12", testMergeSHA, "Promote new-image into main")
+}
+
+func TestReleaseMergedCreatesRelease(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+
+	_, err := updater.ReleaseMerged(context.Background(), makeMergedPullRequest(), &ReleaseInput{
+		Repo:          testGitHubRepo,
+		TagName:       "promotion-{{ .Number }}",
+		CreateRelease: true,
+		ReleaseBody:   "Merged as {{ .SHA }}",
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertTagCreated(testGitHubRepo, "promotion-12", testMergeSHA, "promotion-12")
+	m.AssertReleaseCreated(testGitHubRepo, &client.ReleaseInput{
+		Tag:  "promotion-12",
+		Name: "promotion-12",
+		Body: "Merged as " + testMergeSHA,
+	})
+}
+
+func TestReleaseMergedDoesNotRenderDefaultsAgain(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+	pr := makeMergedPullRequest()
+	pr.Title = "Promote {{ .Image }}"
+
+	_, err := updater.ReleaseMerged(context.Background(), pr, &ReleaseInput{
+		Repo:          testGitHubRepo,
+		TagName:       `{{ replace "Promote" "promotion" .Title | trimSuffix " {{ .Image }}" }}-{{ .Number }}`,
+		Message:       "{{ .Title }}",
+		CreateRelease: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertTagCreated(testGitHubRepo, "promotion-12", testMergeSHA, "Promote {{ .Image }}")
+	m.AssertReleaseCreated(testGitHubRepo, &client.ReleaseInput{
+		Tag:  "promotion-12",
+		Name: "promotion-12",
+		Body: "Promote {{ .Image }}",
+	})
+}
+
+func TestReleaseMergedWithUnmergedPullRequest(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+	pr := makeMergedPullRequest()
+	pr.Merged = false
+
+	_, err := updater.ReleaseMerged(context.Background(), pr, &ReleaseInput{Repo: testGitHubRepo, TagName: "v1"})
+
+	if !errors.Is(err, ErrNotMerged) {
+		t.Fatalf("got %v, want ErrNotMerged", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestReleaseMergedErrors(t *testing.T) {
+	releaseTests := []struct {
+		name    string
+		caps    client.Capabilities
+		input   *ReleaseInput
+		wantErr string
+	}{
+		{"unsupported provider", client.Capabilities{}, &ReleaseInput{TagName: "v1"}, "the git provider does not support releases"},
+		{"invalid template", client.Capabilities{Releases: true}, &ReleaseInput{TagName: "{{ .Number"}, "failed to parse the tag name template"},
+		{"unknown field", client.Capabilities{Releases: true}, &ReleaseInput{TagName: "{{ .Version }}"}, "failed to execute the tag name template"},
+		{"empty tag", client.Capabilities{Releases: true}, &ReleaseInput{}, "the tag name is empty"},
+	}
+
+	for _, tt := range releaseTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.Caps = tt.caps
+			updater := New(zap.New(), m)
+			tt.input.Repo = testGitHubRepo
+
+			_, err := updater.ReleaseMerged(context.Background(), makeMergedPullRequest(), tt.input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package updater
+
+import (
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// UpdateState describes the outcome of an update.
+type UpdateState int
+
+const (
+	// Unchanged indicates that the file already had the value, and nothing
+	// was committed.
+	Unchanged UpdateState = iota
+	// Committed indicates that the change was committed directly to the
+	// branch, without a PullRequest.
+	Committed
+	// PullRequestCreated indicates that the change was committed to a new
+	// branch, and a PullRequest was opened.
+	PullRequestCreated
+	// Skipped indicates that the current value was not the expected value,
+	// and the Input is configured to skip the update, or in a batch, that the
+	// repo is read-only.
+	Skipped
+	// PullRequestUpdated indicates that the change was recommitted to a
+	// reset branch, which already had an open PullRequest, or committed to
+	// the branch of a reused PullRequest.
+	PullRequestUpdated
+	// Failed indicates that the update failed in a batch that continues on
+	// errors, and the BatchResult records the error.
+	Failed
+)
+
+func (s UpdateState) String() string {
+	switch s {
+	case Unchanged:
+		return "unchanged"
+	case Committed:
+		return "committed"
+	case PullRequestCreated:
+		return "pull-request-created"
+	case Skipped:
+		return "skipped"
+	case PullRequestUpdated:
+		return "pull-request-updated"
+	case Failed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// UpdateResult records the outcome of an update.
+type UpdateResult struct {
+	State UpdateState
+	// Branch is the branch the change was committed to.
+	Branch string
+	// PullRequest is only set when the State is PullRequestCreated, or
+	// PullRequestUpdated.
+	PullRequest *scm.PullRequest
+	// Commit is the SHA of the last commit of the change, empty if nothing
+	// was committed, or it's not known.
+	Commit string
+	// Base is the SHA of the head of the branch that the change was
+	// committed on top of, empty if nothing was committed.
+	Base string
+	// Previous is the value of the Key before it was set, in its YAML form,
+	// nil if there was no value.
+	Previous *string
+	// NewValue is the value the Key was set to, the values of secrets are
+	// redacted, nil if the Key was deleted, or a ContentUpdater was used.
+	NewValue interface{}
+	// Content is the updated file, as committed, it's nil if nothing was
+	// committed, several files were changed, or the file has secret values.
+	Content []byte
+	// Source is the file the content was read from, if not the updated file.
+	Source *SourceFile
+	// Superseded are the open PullRequests from previous updates that were
+	// closed, when the Input has a SupersedePolicy.
+	Superseded []*scm.PullRequest
+	// PostActionErrors are the PostActionErrors of the PostActions that
+	// failed, the update itself succeeded.
+	PostActionErrors []error
+}
@@ -0,0 +1,126 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/syaml"
+)
+
+func TestUpdate(t *testing.T) {
+	expected := "other-image"
+	resultTests := []struct {
+		name       string
+		content    string
+		input      func(*Input)
+		wantState  UpdateState
+		wantBranch string
+		wantPR     bool
+	}{
+		{"pull request", "test:\n  image: old-image\n", func(*Input) {}, PullRequestCreated, "test-branch-a", true},
+		{"direct commit", "test:\n  image: old-image\n", func(i *Input) { i.BranchGenerateName = "" }, Committed, testBranch, false},
+		{"no change", "test:\n  image: new-image\n", func(*Input) {}, Unchanged, "", false},
+		{"skipped", "test:\n  image: old-image\n", func(i *Input) { i.ExpectedValue, i.SkipOnMismatch = &expected, true }, Skipped, "", false},
+	}
+
+	for _, tt := range resultTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(tt.content))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			tt.input(input)
+
+			r, err := updater.Update(context.Background(), input)
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if r.State != tt.wantState {
+				rt.Errorf("got state %s, want %s", r.State, tt.wantState)
+			}
+			if r.Branch != tt.wantBranch {
+				rt.Errorf("got branch %q, want %q", r.Branch, tt.wantBranch)
+			}
+			if (r.PullRequest != nil) != tt.wantPR {
+				rt.Errorf("got PullRequest %v, want a PullRequest %v", r.PullRequest, tt.wantPR)
+			}
+		})
+	}
+}
+
+func TestUpdateStateString(t *testing.T) {
+	stateTests := []struct {
+		state UpdateState
+		want  string
+	}{
+		{Unchanged, "unchanged"},
+		{Committed, "committed"},
+		{PullRequestCreated, "pull-request-created"},
+		{Skipped, "skipped"},
+		{PullRequestUpdated, "pull-request-updated"},
+		{UpdateState(10), "unknown"},
+	}
+
+	for _, tt := range stateTests {
+		if s := tt.state.String(); s != tt.want {
+			t.Errorf("String() got %q, want %q", s, tt.want)
+		}
+	}
+}
+
+func TestUpdateResultDetails(t *testing.T) {
+	resultTests := []struct {
+		name         string
+		content      string
+		input        func(*Input)
+		wantCommit   bool
+		wantNewValue interface{}
+		wantContent  string
+	}{
+		{"pull request", "test:\n  image: old-image\n", func(*Input) {}, true, "new-image", "test:\n  image: new-image\n"},
+		{"direct commit", "test:\n  image: old-image\n", func(i *Input) { i.BranchGenerateName = "" }, true, "new-image", "test:\n  image: new-image\n"},
+		{"no change", "test:\n  image: new-image\n", func(*Input) {}, false, "new-image", ""},
+		{"delete", "test:\n  image: old-image\n", func(i *Input) { i.Delete = true }, true, nil, "test: {}\n"},
+		{"secret", "test:\n  image: old-image\n", func(i *Input) { i.SecretKeys = []string{"image$"} }, true, syaml.Redacted, ""},
+	}
+
+	for _, tt := range resultTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(tt.content))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			tt.input(input)
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if tt.wantCommit {
+				head, err := m.GetBranchHead(context.Background(), testGitHubRepo, r.Branch)
+				if err != nil {
+					rt.Fatal(err)
+				}
+				if r.Commit != head {
+					rt.Errorf("got commit %q, want %q", r.Commit, head)
+				}
+			} else if r.Commit != "" {
+				rt.Errorf("got commit %q, want none", r.Commit)
+			}
+			if diff := cmp.Diff(tt.wantNewValue, r.NewValue); diff != "" {
+				rt.Errorf("incorrect NewValue:\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantContent, string(r.Content)); diff != "" {
+				rt.Errorf("incorrect Content:\n%s", diff)
+			}
+		})
+	}
+}
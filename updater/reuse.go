@@ -0,0 +1,63 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// reusablePullRequest returns the open PullRequest into the commit's base branch,
+// from the NewBranchName, or from a branch generated from the
+// BranchGenerateName that records the same targets, if PullRequests are
+// reused.
+func (u *Updater) reusablePullRequest(ctx context.Context, commit CommitInput, reuse bool, targets []string) (*scm.PullRequest, error) {
+	if !reuse || !commit.newBranch() {
+		return nil, nil
+	}
+	if commit.Fork != "" {
+		// The PullRequest from a reset branch is found when it's opened.
+		if commit.ResetBranch && commit.NewBranchName != "" {
+			return nil, nil
+		}
+		return nil, errors.New("pull requests from forks can't be reused")
+	}
+	prs, err := u.gitClient.ListPullRequests(ctx, commit.Repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find a pull request to reuse: %w", err)
+	}
+	for _, pr := range prs {
+		if pr.Target != commit.base() {
+			continue
+		}
+		if commit.NewBranchName != "" && pr.Source == commit.NewBranchName ||
+			commit.NewBranchName == "" && strings.HasPrefix(pr.Source, commit.BranchGenerateName) && hasTargets(pr.Body, targets) {
+			u.log.Info("reusing PullRequest", "number", pr.Number, "branch", pr.Source)
+			return pr, nil
+		}
+	}
+	return nil, nil
+}
+
+// onBranch returns a copy of the Input that is committed directly to the
+// branch.
+func (i *Input) onBranch(branch string) *Input {
+	c := *i
+	c.Branch, c.BaseBranch, c.Fork, c.BranchGenerateName, c.NewBranchName, c.ResetBranch = branch, "", "", "", "", false
+	return &c
+}
+
+// updatePullRequest replaces the title and body of the reused PullRequest.
+func (u *Updater) updatePullRequest(ctx context.Context, repo string, pr *scm.PullRequest, title, body string) (*scm.PullRequest, error) {
+	updated, err := u.gitClient.UpdatePullRequest(ctx, repo, pr.Number, &scm.PullRequestInput{
+		Title: u.sanitize(TextPullRequestTitle, title),
+		Body:  u.pullRequestBody(body),
+	})
+	if err != nil {
+		return nil, scmError(err, repo, fmt.Sprintf("update pull request %d", pr.Number), nil)
+	}
+	u.log.Info("updated PullRequest", "number", pr.Number)
+	return updated, nil
+}
@@ -0,0 +1,152 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testReusedBranch = "test-branch-xyz"
+
+func TestUpdateWithReusePullRequest(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	reuseTests := []struct {
+		name       string
+		openPR     *scm.PullRequest
+		wantState  UpdateState
+		wantBranch string
+	}{
+		{"open PullRequest", &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: testTargetBody}, PullRequestUpdated, testReusedBranch},
+		{"no open PullRequest", nil, PullRequestCreated, "test-branch-a"},
+		{"open PullRequest into another branch", &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: "staging", Body: testTargetBody}, PullRequestCreated, "test-branch-a"},
+		{"open PullRequest for another file", &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: "Update-Target: " + testSecondFilePath + "#test.image"}, PullRequestCreated, "test-branch-a"},
+		{"open PullRequest without targets", &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch}, PullRequestCreated, "test-branch-a"},
+		{"open PullRequest from another branch", &scm.PullRequest{Number: 7, Source: "other-branch-xyz", Target: testBranch}, PullRequestCreated, "test-branch-a"},
+	}
+
+	for _, tt := range reuseTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddFileContents(testGitHubRepo, testFilePath, testReusedBranch, []byte("test:\n  image: older-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+			m.AddBranchHead(testGitHubRepo, testReusedBranch, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+			if tt.openPR != nil {
+				m.AddOpenPullRequest(testGitHubRepo, tt.openPR)
+			}
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.ReusePullRequest = true
+			input.PullRequest.Body = "Updated from {{ .Previous }}"
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r.State != tt.wantState {
+				rt.Fatalf("got state %s, want %s", r.State, tt.wantState)
+			}
+			if r.Branch != tt.wantBranch {
+				rt.Fatalf("got branch %q, want %q", r.Branch, tt.wantBranch)
+			}
+			if got := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, tt.wantBranch)); got != "test:\n  image: new-image\n" {
+				rt.Fatalf("got %#v", got)
+			}
+			if tt.wantState != PullRequestUpdated {
+				m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+					Title: "This is a test PR",
+					Body:  "Updated from old-image\n\n" + testTargetBody,
+					Head:  "test-branch-a",
+					Base:  testBranch,
+				})
+				return
+			}
+			m.AssertNoBranchesCreated()
+			m.AssertNoPullRequestsCreated()
+			m.AssertPullRequestUpdated(testGitHubRepo, 7, &scm.PullRequestInput{Title: "This is a test PR", Body: "Updated from older-image\n\n" + testTargetBody})
+		})
+	}
+}
+
+func TestUpdateWithReusePullRequestAndNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testReusedBranch, []byte("test:\n  image: new-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: testTargetBody})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.ReusePullRequest = true
+	input.NoChange = NoChangePullRequest
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != Unchanged {
+		t.Fatalf("got state %s, want %s", r.State, Unchanged)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	if got := m.GetUpdatedContents(testGitHubRepo, testFilePath, testReusedBranch); got != nil {
+		t.Fatalf("got %#v, want no update", string(got))
+	}
+}
+
+func TestUpdateWithFilesAndReusePullRequest(t *testing.T) {
+	m := mock.New(t)
+	for _, branch := range []string{testBranch, testReusedBranch} {
+		m.AddFileContents(testGitHubRepo, testFilePath, branch, []byte("test:\n  image: old-image\n"))
+		m.AddFileContents(testGitHubRepo, testSecondFilePath, branch, []byte("test:\n  image: old-image\n"))
+	}
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, testReusedBranch, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	filesBody := "Update-Target: " + testFilePath + "#test.image\nUpdate-Target: " + testSecondFilePath + "#test.image"
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: filesBody})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.ReusePullRequest = true
+	input.Files = []FileChange{{Filename: testSecondFilePath, Key: "test.image", NewValue: "new-image"}}
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestUpdated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestUpdated)
+	}
+	for _, path := range []string{testFilePath, testSecondFilePath} {
+		if got := string(m.GetUpdatedContents(testGitHubRepo, path, testReusedBranch)); got != "test:\n  image: new-image\n" {
+			t.Fatalf("got %#v for %s", got, path)
+		}
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	m.AssertPullRequestUpdated(testGitHubRepo, 7, &scm.PullRequestInput{Title: "This is a test PR", Body: "This is the body\n\n" + filesBody})
+}
+
+func TestUpdateWithReusePullRequestAndUpdateFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testReusedBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testReusedBranch, "aa218f56b14c9653891f9e74264a383fa43fefbd")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: testReusedBranch, Target: testBranch, Body: testTargetBody})
+	m.UpdatePullRequestErr = errors.New("forbidden")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.ReusePullRequest = true
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "failed to update pull request 7: forbidden", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
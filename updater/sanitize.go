@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"regexp"
+	"strings"
+)
+
+// TextKind identifies the generated text being sanitized.
+type TextKind string
+
+const (
+	TextPullRequestTitle TextKind = "pull-request-title"
+	TextPullRequestBody  TextKind = "pull-request-body"
+	TextCommitMessage    TextKind = "commit-message"
+	TextComment          TextKind = "comment"
+)
+
+// Sanitizer post-processes generated text before it's submitted to the git
+// provider, it should return the text unchanged for kinds it doesn't handle.
+type Sanitizer func(kind TextKind, s string) string
+
+// Sanitizers configures the Updater to pass PullRequest titles and bodies,
+// commit messages and comments through the sanitizers, in order, before
+// submitting them.
+func Sanitizers(s ...Sanitizer) UpdaterFunc {
+	return func(u *Updater) {
+		u.sanitizers = append(u.sanitizers, s...)
+	}
+}
+
+func (u *Updater) sanitize(kind TextKind, s string) string {
+	for _, f := range u.sanitizers {
+		s = f(kind, s)
+	}
+	return s
+}
+
+// StripHostnames replaces the hosts, and any subdomains of them, in all text
+// with the replacement.
+//
+// StripHostnames("<internal>", "corp.example.com")
+func StripHostnames(replacement string, hosts ...string) Sanitizer {
+	quoted := make([]string, len(hosts))
+	for i, h := range hosts {
+		quoted[i] = regexp.QuoteMeta(h)
+	}
+	re := regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)*(?:` + strings.Join(quoted, "|") + `)\b`)
+	return func(kind TextKind, s string) string {
+		if len(hosts) == 0 {
+			return s
+		}
+		return re.ReplaceAllLiteralString(s, replacement)
+	}
+}
+
+// MaxLength truncates text of the kind to at most n characters, truncated text
+// ends with "...".
+func MaxLength(kind TextKind, n int) Sanitizer {
+	return func(k TextKind, s string) string {
+		r := []rune(s)
+		if k != kind || len(r) <= n {
+			return s
+		}
+		if n <= 3 {
+			return string(r[:n])
+		}
+		return string(r[:n-3]) + "..."
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`, `~`, `\~`, `@`, `\@`,
+)
+
+// EscapeMarkdown escapes Markdown formatting, HTML and mentions in text of
+// the kinds, so that it's rendered literally.
+func EscapeMarkdown(kinds ...TextKind) Sanitizer {
+	return func(kind TextKind, s string) string {
+		for _, k := range kinds {
+			if k == kind {
+				return markdownEscaper.Replace(s)
+			}
+		}
+		return s
+	}
+}
@@ -0,0 +1,65 @@
+package updater
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestSanitizers(t *testing.T) {
+	sanitizerTests := []struct {
+		name      string
+		sanitizer Sanitizer
+		kind      TextKind
+		text      string
+		want      string
+	}{
+		{"strip hostnames", StripHostnames("<internal>", "corp.example.com"), TextPullRequestBody, "See https://ci.corp.example.com/job/1 and corp.example.com", "See https://<internal>/job/1 and <internal>"},
+		{"strip hostnames leaves other hosts", StripHostnames("<internal>", "corp.example.com"), TextCommitMessage, "See https://example.com/", "See https://example.com/"},
+		{"strip no hostnames", StripHostnames("<internal>"), TextCommitMessage, "See https://example.com/", "See https://example.com/"},
+		{"max length", MaxLength(TextPullRequestTitle, 10), TextPullRequestTitle, "Update the image to v2", "Update ..."},
+		{"max length within the limit", MaxLength(TextPullRequestTitle, 10), TextPullRequestTitle, "Update", "Update"},
+		{"max length other kinds", MaxLength(TextPullRequestTitle, 10), TextPullRequestBody, "Update the image to v2", "Update the image to v2"},
+		{"escape markdown", EscapeMarkdown(TextComment), TextComment, "**bold** <b>@user</b>", `\*\*bold\*\* \<b\>\@user\</b\>`},
+		{"escape markdown other kinds", EscapeMarkdown(TextComment), TextPullRequestBody, "**bold**", "**bold**"},
+	}
+
+	for _, tt := range sanitizerTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			if got := tt.sanitizer(tt.kind, tt.text); got != tt.want {
+				rt.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateYAMLWithSanitizers(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}),
+		Sanitizers(StripHostnames("<internal>", "corp.example.com"), MaxLength(TextPullRequestTitle, 20)))
+	input := makeInput()
+	input.CommitMessage = "Triggered by ci.corp.example.com"
+	input.PullRequest.Title = strings.Repeat("a", 30)
+	input.PullRequest.Body = "Built on ci.corp.example.com"
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Triggered by <internal>")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: strings.Repeat("a", 17) + "...",
+		Body:  "Built on <internal>",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
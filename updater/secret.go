@@ -0,0 +1,202 @@
+package updater
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/agill17/pkg/syaml"
+)
+
+// SecretKeys is an option func for the Updater creation function, the values
+// of keys in the repo matching the regular expressions are secret, as if
+// they were in the SecretKeys of each Input for the repo.
+//
+// An empty repo applies the patterns to all repos.
+func SecretKeys(repo string, patterns ...string) UpdaterFunc {
+	return func(u *Updater) {
+		if u.secretKeys == nil {
+			u.secretKeys = map[string][]string{}
+		}
+		u.secretKeys[repo] = append(u.secretKeys[repo], patterns...)
+	}
+}
+
+// secrets matches the paths of keys with secret values.
+type secrets struct {
+	patterns []string
+	res      []*regexp.Regexp
+}
+
+// encryptedKeys matches the paths of all the keys, every value in an encrypted
+// file is secret.
+const encryptedKeys = "."
+
+// secrets returns the secret keys for the Input, from the Input and the
+// Updater, all the keys of encrypted files are secret.
+func (u *Updater) secrets(input *Input) (*secrets, error) {
+	s := &secrets{}
+	all := []string{}
+	if input.Encryption != nil {
+		all = append(all, encryptedKeys)
+	}
+	for _, patterns := range [][]string{all, u.secretKeys[""], u.secretKeys[input.Repo], input.SecretKeys} {
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid secret key pattern %q: %w", p, err)
+			}
+			s.patterns = append(s.patterns, p)
+			s.res = append(s.res, re)
+		}
+	}
+	return s, nil
+}
+
+// any returns true if there are secret keys.
+func (s *secrets) any() bool {
+	return len(s.res) > 0
+}
+
+// matches returns true if the value of the key is secret.
+func (s *secrets) matches(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, re := range s.res {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// options returns the syaml options that redact the secrets in traces.
+func (s *secrets) options(opts []syaml.Option) []syaml.Option {
+	if !s.any() {
+		return opts
+	}
+	return append(append([]syaml.Option{}, opts...), syaml.RedactKeys(s.patterns...))
+}
+
+// with returns the secrets combined with the others.
+func (s *secrets) with(o *secrets) *secrets {
+	return &secrets{
+		patterns: append(append([]string{}, s.patterns...), o.patterns...),
+		res:      append(append([]*regexp.Regexp{}, s.res...), o.res...),
+	}
+}
+
+// maskDocs returns the original and updated documents with the secret values
+// redacted for diffs, false is returned if the diff can't be shown without
+// revealing a secret, because a document can't be parsed, or only secret
+// values changed.
+func (s *secrets) maskDocs(original, updated []byte) ([]byte, []byte, bool) {
+	if !s.any() {
+		return original, updated, true
+	}
+	maskedOriginal, err := s.mask(original)
+	if err != nil {
+		return nil, nil, false
+	}
+	maskedUpdated, err := s.mask(updated)
+	if err != nil {
+		return nil, nil, false
+	}
+	if bytes.Equal(maskedOriginal, maskedUpdated) && !bytes.Equal(original, updated) {
+		return nil, nil, false
+	}
+	return maskedOriginal, maskedUpdated, true
+}
+
+func (s *secrets) mask(doc []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return doc, nil
+	}
+	return syaml.RedactBytes(doc, s.patterns...)
+}
+
+// maskValues returns the message values, with the values of secret keys
+// redacted.
+func (s *secrets) maskValues(v MessageValues) MessageValues {
+	if !s.matches(v.Key) {
+		return v
+	}
+	if v.Previous != "" {
+		v.Previous = syaml.Redacted
+	}
+	v.NewValue = syaml.Redacted
+	return v
+}
+
+// maskInput returns a copy of the Input, with the NewValue redacted if the Key
+// is secret.
+func (s *secrets) maskInput(i *Input) *Input {
+	if !s.matches(i.Key) {
+		return i
+	}
+	masked := *i
+	masked.NewValue = syaml.Redacted
+	return &masked
+}
+
+// maskPrevious returns the previous value recorded in results, secret values
+// are recorded as a sha256 hash.
+func (s *secrets) maskPrevious(key string, previous *string) *string {
+	if previous == nil || !s.matches(key) {
+		return previous
+	}
+	hashed := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(*previous)))
+	return &hashed
+}
+
+// maskError returns the error, without the current value if the key is secret
+// and the value was not expected.
+func (s *secrets) maskError(key string, err error) error {
+	if err == nil || !s.matches(key) || !errors.Is(err, syaml.ErrUnexpectedValue) {
+		return err
+	}
+	return fmt.Errorf("%w: %s is a secret, the values are not shown", syaml.ErrUnexpectedValue, key)
+}
+
+// fileDiff is the change to a file, with the secret values redacted.
+type fileDiff struct {
+	filename string
+	original []byte
+	updated  []byte
+	unified  string // The unified diff, empty if the change can't be shown
+	hidden   string // Why the change is not shown, if it can't be
+}
+
+// diffFile returns the change to the file with the secret values redacted,
+// diffs of encrypted files would reveal the plaintext, and are not shown.
+func (s *secrets) diffFile(filename string, original, updated []byte, encrypted bool) fileDiff {
+	if encrypted {
+		return fileDiff{filename: filename, hidden: fmt.Sprintf("`%s` is encrypted, the change is not shown.", filename)}
+	}
+	maskedOriginal, maskedUpdated, ok := s.maskDocs(original, updated)
+	if !ok {
+		return fileDiff{filename: filename, hidden: secretDiffMessage(filename)}
+	}
+	return fileDiff{
+		filename: filename,
+		original: maskedOriginal,
+		updated:  maskedUpdated,
+		unified:  syaml.UnifiedDiff(filename, maskedOriginal, maskedUpdated),
+	}
+}
+
+// render returns the diff for a PullRequest body, in the style of the
+// PullRequest, or why it's not shown.
+func (d fileDiff) render(pr PullRequestInput) (string, error) {
+	if d.hidden != "" || d.unified == "" {
+		return d.hidden, nil
+	}
+	return renderDiff(pr.DiffStyle, d.filename, d.original, d.updated, pr.MaxDiffLines)
+}
+
+func secretDiffMessage(filename string) string {
+	return fmt.Sprintf("`%s` has secret values, the change is not shown.", filename)
+}
@@ -0,0 +1,191 @@
+package updater
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testSecretFile = "test:\n  image: old-image\n  password: old-secret\n"
+
+func TestUpdateYAMLWithSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(testSecretFile))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeSecretInput()
+	input.PullRequest.IncludeDiff = true
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test:\n  image: new-image\n  password: new-secret\n"
+	if got := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")); got != want {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", "Update test.password from REDACTED to REDACTED")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body\n\n```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,3 +1,3 @@\n test:\n-  image: old-image\n+  image: new-image\n   password: REDACTED\n```",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("old-secret"))); r.Previous == nil || *r.Previous != want {
+		t.Fatalf("got Previous %v, want %#v", r.Previous, want)
+	}
+}
+
+func TestUpdateYAMLWithSecretKeysFromTheUpdater(t *testing.T) {
+	repoTests := []struct {
+		name     string
+		repo     string
+		wantBody string
+	}{
+		{"all repos", "", "This is the body\n\n`" + testFilePath + "` has secret values, the change is not shown."},
+		{"the updated repo", testGitHubRepo, "This is the body\n\n`" + testFilePath + "` has secret values, the change is not shown."},
+		{"another repo", testOtherRepo, "This is the body\n\n```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,3 +1,3 @@\n test:\n   image: old-image\n-  password: old-secret\n+  password: new-secret\n```"},
+	}
+
+	for _, tt := range repoTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(testSecretFile))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), SecretKeys(tt.repo, `\.password$`))
+			input := makeSecretInput()
+			input.SecretKeys = nil
+			input.Values = nil
+			input.PullRequest.IncludeDiff = true
+
+			_, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+				Title: "This is a test PR",
+				Body:  tt.wantBody,
+				Head:  "test-branch-a",
+				Base:  testBranch,
+			})
+		})
+	}
+}
+
+func TestUpdateYAMLWithSecretKeysAndUnexpectedValue(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(testSecretFile))
+	updater := New(zap.New(), m)
+	input := makeSecretInput()
+	expected := "other-secret"
+	input.ExpectedValue = &expected
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, `^failed to update key test.password in file .*: unexpected current value: test.password is a secret, the values are not shown$`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUpdateYAMLWithSecretKeysAndNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  password: new-secret\n"))
+	updater := New(zap.New(), m)
+	input := makeSecretInput()
+	input.Values = nil
+	input.NoChange = NoChangeComment
+	input.TrackingIssue = 3
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertCommentCreated(testGitHubRepo, 3, "No change required, test.password in "+testFilePath+" already has the value REDACTED.")
+	if want := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte("new-secret"))); r.Previous == nil || *r.Previous != want {
+		t.Fatalf("got Previous %v, want %#v", r.Previous, want)
+	}
+}
+
+func TestUpdateYAMLWithInvalidSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+	input := makeSecretInput()
+	input.SecretKeys = []string{"["}
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, `invalid secret key pattern "\["`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestPreviewWithSecretKeys(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte(testSecretFile))
+	updater := New(zap.New(), m)
+	image, password := makeInput(), makeSecretInput()
+	password.Values = nil
+
+	body, err := updater.Preview(context.Background(), PreviewTarget{Repo: "testorg/app", Number: 5}, []*Input{image, password})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "### Preview of the updates\n\n" +
+		"**" + testGitHubRepo + "** (" + testBranch + ")\n\n" +
+		"```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,3 +1,3 @@\n test:\n-  image: old-image\n+  image: new-image\n   password: REDACTED\n```\n"
+	if body != want {
+		t.Fatalf("got %#v, want %#v", body, want)
+	}
+}
+
+func makeSecretInput() *Input {
+	input := makeInput()
+	input.Key = "test.password"
+	input.NewValue = "new-secret"
+	input.Values = []KeyValue{{Key: "test.image", NewValue: "new-image"}}
+	input.SecretKeys = []string{`^test\.password$`}
+	input.CommitMessage = "Update {{ .Key }} from {{ .Previous }} to {{ .NewValue }}"
+	return input
+}
+
+func TestDiffFile(t *testing.T) {
+	s, err := New(zap.New(), mock.New(t)).secrets(&Input{SecretKeys: []string{`^test\.password$`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diffTests := []struct {
+		name      string
+		original  string
+		updated   string
+		encrypted bool
+		want      string
+	}{
+		{"changed", "test:\n  image: old-image\n", "test:\n  image: new-image\n", false, "```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,2 +1,2 @@\n test:\n-  image: old-image\n+  image: new-image\n```"},
+		{"unchanged", "test:\n  image: old-image\n", "test:\n  image: old-image\n", false, ""},
+		{"secret", "test:\n  password: old\n", "test:\n  password: new\n", false, "`" + testFilePath + "` has secret values, the change is not shown."},
+		{"encrypted", "test:\n  image: old-image\n", "test:\n  image: new-image\n", true, "`" + testFilePath + "` is encrypted, the change is not shown."},
+	}
+
+	for _, tt := range diffTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			got, err := s.diffFile(testFilePath, []byte(tt.original), []byte(tt.updated), tt.encrypted).render(PullRequestInput{})
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if got != tt.want {
+				rt.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x/go-scm/scm"
+
+	"github.com/agill17/pkg/client"
+)
+
+// SourceFile is a file in a repo that an update reads the content from, e.g.
+// a template or upstream chart, the updated content is committed to the
+// Input's file.
+type SourceFile struct {
+	Repo     string // e.g. my-org/templates
+	Branch   string // e.g. main
+	Filename string // relative path to the file in the repository
+}
+
+// readFiles returns the current file, its plaintext, and the plaintext that the
+// update is applied to, which is read from the Source if configured.
+//
+// When reading from a Source, the file doesn't need to exist, and the current
+// content is empty.
+func (u *Updater) readFiles(ctx context.Context, input *Input) (*scm.Content, []byte, []byte, error) {
+	current, err := u.gitClient.GetFile(ctx, input.Repo, input.Branch, input.Filename)
+	if input.Source != nil && client.IsNotFound(err) {
+		current, err = &scm.Content{}, nil
+	}
+	if err != nil {
+		u.log.Info("failed to get file from repo", "err", err)
+		return nil, nil, nil, scmError(err, input.Repo, fmt.Sprintf("get file %s from branch %s", input.Filename, input.Branch), fileKinds)
+	}
+	u.log.Info("got existing file", "sha", current.Sha)
+	plaintext := current.Data
+	if len(current.Data) > 0 {
+		if plaintext, err = input.decrypt(ctx, current.Data); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	if input.Source == nil {
+		return current, plaintext, plaintext, nil
+	}
+	s := input.Source
+	source, err := u.gitClient.GetFile(ctx, s.Repo, s.Branch, s.Filename)
+	if err != nil {
+		u.log.Info("failed to get source file from repo", "repo", s.Repo, "err", err)
+		return nil, nil, nil, scmError(err, s.Repo, fmt.Sprintf("get source file %s from branch %s", s.Filename, s.Branch), fileKinds)
+	}
+	u.log.Info("got source file", "repo", s.Repo, "branch", s.Branch, "filename", s.Filename, "sha", source.Sha)
+	base, err := input.decrypt(ctx, source.Data)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return current, plaintext, base, nil
+}
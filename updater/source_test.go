@@ -0,0 +1,118 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testSourceRepo = "testorg/templates"
+
+func TestUpdateWithSource(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testSourceRepo, "service/values.yaml", "release", []byte("test:\n  image: template-image\n  replicas: 2\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test:\n  image: new-image\n  replicas: 2\n"
+	if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+	if diff := cmp.Diff(input.Source, result.Source); diff != "" {
+		t.Fatalf("incorrect source:\n%s", diff)
+	}
+	if *result.Previous != "template-image" {
+		t.Fatalf("got previous value %q, want template-image", *result.Previous)
+	}
+}
+
+func TestUpdateWithSourceCreatesFile(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testSourceRepo, "service/values.yaml", "release", []byte("test:\n  image: template-image\n"))
+	m.AddMissingFile(testGitHubRepo, testFilePath, testBranch)
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	want := "test:\n  image: new-image\n"
+	if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+}
+
+func TestUpdateWithSourceAndNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testSourceRepo, "service/values.yaml", "release", []byte("test:\n  image: template-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+
+	result, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != Unchanged {
+		t.Fatalf("got state %s, want %s", result.State, Unchanged)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestUpdateWithMissingSource(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "failed to get source file service/values.yaml from branch release: not found", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestUpdateBatchWithSource(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testSourceRepo, "service/values.yaml", "release", []byte("test:\n  image: template-image\n  tag: v1\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	first, second := makeInput(), makeInput()
+	first.Source = &SourceFile{Repo: testSourceRepo, Branch: "release", Filename: "service/values.yaml"}
+	second.Key, second.NewValue = "test.tag", "v2"
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{first, second}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "test:\n  image: new-image\n  tag: v2\n"
+	if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"))); diff != "" {
+		t.Fatalf("incorrect update:\n%s", diff)
+	}
+}
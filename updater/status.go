@@ -0,0 +1,36 @@
+package updater
+
+import (
+	"context"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// CommitStatus is an option func for the Updater creation function, when
+// configured, a commit status with the state "success", and the label and
+// description, e.g. "gitops-updater" and "rendered manifests valid", is
+// reported for the commit of each change, before the PullRequest is opened, so
+// that branch protection requiring the status doesn't block the PullRequests.
+//
+// The status is reported in the Repo, where branch protection reads the
+// required statuses, the commit of a change from a Fork is only in the Repo
+// once the PullRequest is opened, so its status is reported after that.
+func CommitStatus(label, description string) UpdaterFunc {
+	return func(u *Updater) {
+		u.commitStatus = &scm.StatusInput{State: scm.StateSuccess, Label: label, Desc: description}
+	}
+}
+
+// reportStatus reports the CommitStatus for the commit, the change has been
+// made, so failures are logged rather than returned.
+func (u *Updater) reportStatus(ctx context.Context, repo, sha string) {
+	if u.commitStatus == nil || sha == "" {
+		return
+	}
+	status := *u.commitStatus
+	if err := u.gitClient.CreateStatus(ctx, repo, sha, &status); err != nil {
+		u.log.Error(err, "failed to report the commit status", "repo", repo, "sha", sha, "label", status.Label)
+		return
+	}
+	u.log.Info("reported commit status", "sha", sha, "label", status.Label)
+}
@@ -0,0 +1,138 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateReportsCommitStatus(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CommitStatus("gitops-updater", "rendered manifests valid"))
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertStatusCreated(testGitHubRepo, r.Commit, &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater", Desc: "rendered manifests valid"})
+}
+
+func TestUpdateBatchReportsCommitStatus(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CommitStatus("gitops-updater", "rendered manifests valid"))
+	staging, production := makeInput(), makeInput()
+	production.Filename = testSecondFilePath
+
+	results, err := updater.UpdateBatchResults(context.Background(), &Batch{Inputs: []*Input{staging, production}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertStatusCreated(testGitHubRepo, results[0].Commit, &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater", Desc: "rendered manifests valid"})
+}
+
+func TestUpdateWithFailingCommitStatus(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.CreateStatusErr = errors.New("forbidden")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CommitStatus("gitops-updater", "rendered manifests valid"))
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+}
+
+func TestUpdateWithoutCommitStatus(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	r, err := updater.Update(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.RefuteStatusCreated(testGitHubRepo, r.Commit)
+}
+
+func TestUpdateWithForkReportsCommitStatus(t *testing.T) {
+	statusTests := []struct {
+		name   string
+		update func(u *Updater, input *Input) (string, error)
+	}{
+		{"update", func(u *Updater, input *Input) (string, error) {
+			r, err := u.Update(context.Background(), input)
+			if err != nil {
+				return "", err
+			}
+			return r.Commit, nil
+		}},
+		{"batch", func(u *Updater, input *Input) (string, error) {
+			results, err := u.UpdateBatchResults(context.Background(), &Batch{Inputs: []*Input{input}, GroupByRepo: true})
+			if err != nil {
+				return "", err
+			}
+			return results[0].Commit, nil
+		}},
+	}
+
+	for _, tt := range statusTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CommitStatus("gitops-updater", "rendered manifests valid"))
+			input := makeInput()
+			input.Fork = "octocat/testrepo-fork"
+
+			sha, err := tt.update(updater, input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			m.AssertStatusCreated(testGitHubRepo, sha, &scm.StatusInput{State: scm.StateSuccess, Label: "gitops-updater", Desc: "rendered manifests valid"})
+			m.RefuteStatusCreated("octocat/testrepo-fork", sha)
+		})
+	}
+}
+
+func TestUpdateWithForkAndFailingPullRequestReportsNoStatus(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.CreatePullRequestErr = errors.New("validation failed")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), CommitStatus("gitops-updater", "rendered manifests valid"))
+	input := makeInput()
+	input.Fork = "octocat/testrepo-fork"
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "validation failed", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	sha, err := m.GetBranchHead(context.Background(), "octocat/testrepo-fork", "test-branch-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.RefuteStatusCreated(testGitHubRepo, sha)
+	m.RefuteStatusCreated("octocat/testrepo-fork", sha)
+}
@@ -0,0 +1,79 @@
+package updater
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+// SupersedePolicy configures what happens to the open PullRequests from
+// previous updates, when an update opens a new PullRequest.
+//
+// The previous PullRequests are those into the same Branch, from branches with
+// the BranchGenerateName prefix, that change the same files and keys, which
+// are recorded in the body of the PullRequests opened with a SupersedePolicy.
+type SupersedePolicy int
+
+const (
+	// SupersedeNone leaves the previous PullRequests open.
+	SupersedeNone SupersedePolicy = iota
+	// SupersedeClose closes the previous PullRequests, and deletes their
+	// branches.
+	SupersedeClose
+	// SupersedeCloseWithComment comments on the previous PullRequests with a
+	// link to the new PullRequest, before closing them, and deleting their
+	// branches.
+	SupersedeCloseWithComment
+)
+
+// supersede closes the previous PullRequests that record the same targets, and
+// returns the PullRequests that were closed.
+//
+// The update has been made, so failures are logged rather than returned.
+func (u *Updater) supersede(ctx context.Context, commit CommitInput, policy SupersedePolicy, pr *scm.PullRequest, branch string, targets []string) []*scm.PullRequest {
+	if policy == SupersedeNone || commit.BranchGenerateName == "" {
+		return nil
+	}
+	prs, err := u.automationPRs(ctx, commit.Repo, AutomationSelector{BranchPrefix: commit.BranchGenerateName, Target: commit.base()})
+	if err != nil {
+		u.log.Error(err, "failed to list the pull requests to supersede", "repo", commit.Repo)
+		return nil
+	}
+	superseded := []*scm.PullRequest{}
+	for _, previous := range prs {
+		if previous.Number == pr.Number || previous.Source == branch || !hasTargets(previous.Body, targets) {
+			continue
+		}
+		if err := u.closeSuperseded(ctx, commit, policy, previous, pr); err != nil {
+			u.log.Error(err, "failed to close superseded PullRequest", "number", previous.Number)
+			continue
+		}
+		superseded = append(superseded, previous)
+	}
+	return superseded
+}
+
+func (u *Updater) closeSuperseded(ctx context.Context, commit CommitInput, policy SupersedePolicy, previous, pr *scm.PullRequest) error {
+	repo := commit.Repo
+	if policy == SupersedeCloseWithComment {
+		if err := u.gitClient.CreateComment(ctx, repo, previous.Number, u.sanitize(TextComment, supersededMessage(pr))); err != nil {
+			return fmt.Errorf("failed to comment on pull request %d: %w", previous.Number, err)
+		}
+	}
+	if err := u.gitClient.ClosePullRequest(ctx, repo, previous.Number); err != nil {
+		return fmt.Errorf("failed to close pull request %d: %w", previous.Number, err)
+	}
+	if err := u.gitClient.DeleteBranch(ctx, commit.headRepo(), previous.Source); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", previous.Source, err)
+	}
+	u.log.Info("closed superseded PullRequest", "number", previous.Number, "supersededBy", pr.Number)
+	return nil
+}
+
+func supersededMessage(pr *scm.PullRequest) string {
+	if pr.Link != "" {
+		return fmt.Sprintf("Superseded by %s.", pr.Link)
+	}
+	return fmt.Sprintf("Superseded by #%d.", pr.Number)
+}
@@ -0,0 +1,147 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+// testTargetBody is the body of a PullRequest that changes the key of
+// makeInput.
+const testTargetBody = "Update-Target: " + testFilePath + "#test.image"
+
+func TestUpdateWithSupersede(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-xyz", Target: testBranch, Body: testTargetBody})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 4, Source: "test-branch-uvw", Target: "staging", Body: testTargetBody})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 5, Source: "other-branch-xyz", Target: testBranch, Body: testTargetBody})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 6, Source: "test-branch-rst", Target: testBranch, Body: "Update-Target: " + testSecondFilePath + "#test.image"})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 8, Source: "test-branch-opq", Target: testBranch})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Supersede = SupersedeCloseWithComment
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	if l := len(r.Superseded); l != 1 || r.Superseded[0].Number != 3 {
+		t.Fatalf("got superseded %v, want #3", r.Superseded)
+	}
+	m.AssertCommentCreated(testGitHubRepo, 3, "Superseded by https://example.com/pull-request/1.")
+	m.AssertPullRequestClosed(testGitHubRepo, 3)
+	m.AssertBranchDeleted(testGitHubRepo, "test-branch-xyz")
+	m.RefutePullRequestClosed(testGitHubRepo, 4)
+	m.RefutePullRequestClosed(testGitHubRepo, 5)
+	m.RefutePullRequestClosed(testGitHubRepo, 6)
+	m.RefutePullRequestClosed(testGitHubRepo, 8)
+	m.RefuteBranchDeleted(testGitHubRepo, "test-branch-rst")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "This is a test PR",
+		Body:  "This is the body\n\n" + testTargetBody,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithSupersedePolicies(t *testing.T) {
+	policyTests := []struct {
+		name        string
+		policy      SupersedePolicy
+		wantClosed  bool
+		wantComment bool
+	}{
+		{"none", SupersedeNone, false, false},
+		{"close", SupersedeClose, true, false},
+		{"close with comment", SupersedeCloseWithComment, true, true},
+	}
+
+	for _, tt := range policyTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-xyz", Target: testBranch, Body: testTargetBody})
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.Supersede = tt.policy
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if closed := len(r.Superseded) == 1; closed != tt.wantClosed {
+				rt.Fatalf("got superseded %v, want closed %v", r.Superseded, tt.wantClosed)
+			}
+			if tt.wantClosed {
+				m.AssertPullRequestClosed(testGitHubRepo, 3)
+			} else {
+				m.RefutePullRequestClosed(testGitHubRepo, 3)
+			}
+			if tt.wantComment {
+				m.AssertCommentCreated(testGitHubRepo, 3, "Superseded by https://example.com/pull-request/1.")
+			}
+		})
+	}
+}
+
+func TestUpdateWithSupersedeAndCloseFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "test-branch-xyz", Target: testBranch, Body: testTargetBody})
+	m.ClosePullRequestErr = errors.New("forbidden")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Supersede = SupersedeClose
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	if len(r.Superseded) != 0 {
+		t.Fatalf("got superseded %v, want none", r.Superseded)
+	}
+}
+
+func TestUpdateBatchWithSupersede(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testSecondFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 3, Source: "batch-xyz", Target: testBranch, Body: "Updating.\n\nUpdate-Target: " + testFilePath + "#test.image\nUpdate-Target: " + testSecondFilePath + "#test.image"})
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 4, Source: "batch-uvw", Target: testBranch, Body: testTargetBody})
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	second := makeInput()
+	second.Filename = testSecondFilePath
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{
+		Inputs:             []*Input{makeInput(), second},
+		GroupByRepo:        true,
+		BranchGenerateName: "batch-",
+		Supersede:          SupersedeClose,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.AssertPullRequestClosed(testGitHubRepo, 3)
+	m.AssertBranchDeleted(testGitHubRepo, "batch-xyz")
+	m.RefutePullRequestClosed(testGitHubRepo, 4)
+}
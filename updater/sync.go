@@ -0,0 +1,343 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"gopkg.in/yaml.v3"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/verify"
+)
+
+// maxSyncCommits is the number of upstream commits listed in a sync
+// PullRequest body.
+const maxSyncCommits = 20
+
+// Upstream is a file, or directory, in an upstream repo that is mirrored.
+type Upstream struct {
+	Repo          string // e.g. my-org/charts
+	Branch        string // e.g. main
+	Path          string // relative path to the file, or directory, in the repository
+	Directory     bool   // Mirror all the files in the Path and its subdirectories
+	LatestRelease bool   // Mirror the tag of the latest Release, rather than the Branch
+	Verification  *Verification
+}
+
+// Verification configures checking the Upstream content before it's
+// committed, the files are read from the Upstream repo at the mirrored commit.
+type Verification struct {
+	Checksums string // A sha256sum file listing the mirrored files, relative to its directory, e.g. charts/SHA256SUMS
+	Signature string // A detached signature of the Checksums, or of the file if there are no Checksums, e.g. charts/SHA256SUMS.asc
+	Keyring   string // ASCII armored public keys trusted to make the Signature
+}
+
+// SyncInput configures mirroring an Upstream file, or directory, into a repo.
+//
+// The upstream commit that was last mirrored is recorded in the StateFile,
+// which is committed along with the mirrored files, and is used to list the
+// upstream commits in the PullRequest body.
+//
+// The PullRequests record the StateFile, and an open PullRequest for the
+// StateFile, from a branch with the BranchGenerateName prefix, is updated with
+// later upstream changes, rather than another being opened.
+type SyncInput struct {
+	Upstream           Upstream
+	Repo               string // e.g. my-org/my-repo
+	Branch             string // e.g. main
+	Path               string // relative path to mirror the Upstream to
+	StateFile          string // relative path to the file recording the mirrored commit, e.g. vendor/charts.sync.yaml
+	BranchGenerateName string // e.g. sync-charts-
+	CommitMessage      string // This defaults to describing the sync
+	PullRequest        PullRequestInput
+}
+
+// syncState is recorded in the StateFile.
+type syncState struct {
+	Repo string `yaml:"repo"`
+	Ref  string `yaml:"ref"`
+	SHA  string `yaml:"sha"`
+}
+
+// Sync mirrors the Upstream content into the repo, if it has changed since the
+// last sync, on a new branch with a PullRequest if the BranchGenerateName is
+// set.
+//
+// If the Upstream has a Verification, the content is only committed if it
+// matches, otherwise an error wrapping verify.ErrUnverified is returned.
+//
+// Files that are removed upstream are not removed from the repo.
+func (u *Updater) Sync(ctx context.Context, input *SyncInput) (*UpdateResult, error) {
+	if input.StateFile == "" {
+		return nil, errors.New("the sync has no StateFile")
+	}
+	if err := input.Upstream.validate(); err != nil {
+		return nil, err
+	}
+	commit := CommitInput{Repo: input.Repo, Branch: input.Branch, BranchGenerateName: input.BranchGenerateName}
+	if err := u.checkPolicy(commit); err != nil {
+		return nil, err
+	}
+	unlock, err := u.lockRepo(ctx, input.Repo)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := u.checkWritable(ctx, input.Repo); err != nil {
+		return nil, err
+	}
+	up := input.Upstream
+	ref := up.Branch
+	if up.LatestRelease {
+		tag, err := u.gitClient.GetLatestRelease(ctx, up.Repo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the latest release of %s: %w", up.Repo, err)
+		}
+		ref = tag
+	}
+	sha, err := u.gitClient.ResolveRef(ctx, up.Repo, ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s in %s: %w", ref, up.Repo, err)
+	}
+	u.log.Info("resolved upstream", "repo", up.Repo, "ref", ref, "sha", sha)
+	previous, stateSHA, err := u.readSyncState(ctx, input, input.Branch)
+	if err != nil {
+		return nil, err
+	}
+	result := &UpdateResult{State: Unchanged, Source: &SourceFile{Repo: up.Repo, Branch: ref, Filename: up.Path}}
+	if previous.SHA == sha {
+		u.log.Info("no change required", "repo", input.Repo, "path", input.Path, "sha", sha)
+		return result, nil
+	}
+	// The open sync PullRequest is reused, rather than opening another for
+	// each upstream change before it's merged.
+	targets := []string{targetTrailer + input.StateFile}
+	lookup := commit
+	if !commit.newBranch() && u.fallbackPrefix != "" {
+		// Including the PullRequest from a ProtectedBranchFallback.
+		lookup.BranchGenerateName = u.fallbackPrefix
+	}
+	reused, err := u.reusablePullRequest(ctx, lookup, true, targets)
+	if err != nil {
+		return nil, err
+	}
+	branch := input.Branch
+	if reused != nil {
+		branch = reused.Source
+		synced, branchStateSHA, err := u.readSyncState(ctx, input, branch)
+		if err != nil {
+			return nil, err
+		}
+		if synced.SHA == sha {
+			u.log.Info("no change required", "repo", input.Repo, "path", input.Path, "sha", sha, "number", reused.Number)
+			return result, nil
+		}
+		stateSHA = branchStateSHA
+	}
+	message := defaultString(input.CommitMessage, fmt.Sprintf("Sync %s from %s %s", input.Path, up.Repo, ref))
+	changes, err := u.syncChanges(ctx, input, branch, sha, message)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		u.log.Info("no change required", "repo", input.Repo, "path", input.Path, "sha", sha)
+		return result, nil
+	}
+	state, err := yaml.Marshal(syncState{Repo: up.Repo, Ref: ref, SHA: sha})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the sync state: %w", err)
+	}
+	changes = append(changes, &fileChange{filename: input.StateFile, message: message, sha: stateSHA, updated: state})
+	body, err := u.syncBody(ctx, input, previous.SHA, ref, sha)
+	if err != nil {
+		return nil, err
+	}
+	body = withTargets(body, targets, lookup.newBranch())
+	title := defaultString(input.PullRequest.Title, fmt.Sprintf("Sync %s from %s %s", input.Path, up.Repo, ref))
+	if reused != nil {
+		commit = CommitInput{Repo: input.Repo, Branch: branch}
+	}
+	r, err := u.commitChanges(ctx, commit, changes, PullRequestInput{Title: title, Body: body, Draft: input.PullRequest.Draft})
+	if err != nil {
+		return nil, err
+	}
+	r.Source = result.Source
+	if reused != nil {
+		if r.PullRequest, err = u.updatePullRequest(ctx, input.Repo, reused, title, body); err != nil {
+			return nil, err
+		}
+		r.State = PullRequestUpdated
+		return r, nil
+	}
+	if r.State == PullRequestCreated {
+		r.PostActionErrors = u.applyPostActions(ctx, input.Repo, r.PullRequest, withRequests(input.PullRequest, input.Branch, changedFiles(changes), nil))
+	}
+	return r, nil
+}
+
+// readSyncState returns the state recorded in the StateFile in the branch, and
+// the file's SHA, the state is empty if the file doesn't exist.
+func (u *Updater) readSyncState(ctx context.Context, input *SyncInput, branch string) (*syncState, string, error) {
+	current, err := u.gitClient.GetFile(ctx, input.Repo, branch, input.StateFile)
+	if client.IsNotFound(err) {
+		return &syncState{}, "", nil
+	}
+	if err != nil {
+		return nil, "", scmError(err, input.Repo, "get the sync state "+input.StateFile, nil)
+	}
+	state := &syncState{}
+	if err := yaml.Unmarshal(current.Data, state); err != nil {
+		return nil, "", fmt.Errorf("failed to parse the sync state %s: %w", input.StateFile, err)
+	}
+	return state, current.Sha, nil
+}
+
+// syncChanges returns the changes needed to mirror the upstream files at the
+// SHA into the branch, files that are already the same are skipped.
+func (u *Updater) syncChanges(ctx context.Context, input *SyncInput, branch, sha, message string) ([]*fileChange, error) {
+	up := input.Upstream
+	check, err := u.upstreamVerifier(ctx, up, sha)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{up.Path}
+	if up.Directory {
+		listed, err := u.gitClient.ListFiles(ctx, up.Repo, sha, up.Path)
+		if err != nil {
+			return nil, scmError(err, up.Repo, "list files in "+up.Path, nil)
+		}
+		files = listed
+	}
+	changes := []*fileChange{}
+	for _, f := range files {
+		source, err := u.gitClient.GetFile(ctx, up.Repo, sha, f)
+		if err != nil {
+			return nil, scmError(err, up.Repo, "get upstream file "+f, fileKinds)
+		}
+		if err := check(f, source.Data); err != nil {
+			return nil, fmt.Errorf("failed to verify upstream file %s: %w", f, err)
+		}
+		target := input.Path
+		if up.Directory {
+			target = path.Join(input.Path, strings.TrimPrefix(f, strings.TrimSuffix(up.Path, "/")+"/"))
+		}
+		current, err := u.gitClient.GetFile(ctx, input.Repo, branch, target)
+		if client.IsNotFound(err) {
+			current, err = &scm.Content{}, nil
+		}
+		if err != nil {
+			return nil, scmError(err, input.Repo, "get file "+target, nil)
+		}
+		if current.Sha != "" && bytes.Equal(current.Data, source.Data) {
+			continue
+		}
+		changes = append(changes, &fileChange{filename: target, message: message, sha: current.Sha, original: current.Data, updated: source.Data})
+	}
+	return changes, nil
+}
+
+// upstreamVerifier returns a func that checks an upstream file against the
+// Verification, the Checksums and Signature files themselves are not checked.
+func (u *Updater) upstreamVerifier(ctx context.Context, up Upstream, sha string) (func(string, []byte) error, error) {
+	v := up.Verification
+	if v == nil {
+		return func(string, []byte) error { return nil }, nil
+	}
+	read := func(name string) ([]byte, error) {
+		c, err := u.gitClient.GetFile(ctx, up.Repo, sha, name)
+		if err != nil {
+			return nil, scmError(err, up.Repo, "get upstream file "+name, fileKinds)
+		}
+		return c.Data, nil
+	}
+	var signature, sums []byte
+	var err error
+	if v.Signature != "" {
+		if signature, err = read(v.Signature); err != nil {
+			return nil, err
+		}
+	}
+	if v.Checksums == "" {
+		return func(name string, data []byte) error {
+			if name == v.Signature {
+				return nil
+			}
+			return verify.Signature(v.Keyring, data, signature)
+		}, nil
+	}
+	if sums, err = read(v.Checksums); err != nil {
+		return nil, err
+	}
+	if signature != nil {
+		if err := verify.Signature(v.Keyring, sums, signature); err != nil {
+			return nil, fmt.Errorf("failed to verify upstream file %s: %w", v.Checksums, err)
+		}
+	}
+	u.log.Info("verified upstream checksums", "repo", up.Repo, "checksums", v.Checksums, "signed", signature != nil)
+	dir := path.Dir(v.Checksums)
+	return func(name string, data []byte) error {
+		if name == v.Checksums || name == v.Signature {
+			return nil
+		}
+		if dir != "." {
+			name = strings.TrimPrefix(name, dir+"/")
+		}
+		return verify.SHA256Sum(sums, name, data)
+	}, nil
+}
+
+func (up Upstream) validate() error {
+	v := up.Verification
+	if v == nil {
+		return nil
+	}
+	if (v.Signature == "") != (v.Keyring == "") {
+		return errors.New("the upstream Verification needs both a Signature and a Keyring")
+	}
+	if v.Checksums == "" && v.Signature == "" {
+		return errors.New("the upstream Verification has no Checksums or Signature")
+	}
+	if up.Directory && v.Checksums == "" {
+		return errors.New("the upstream Verification of a directory needs Checksums")
+	}
+	return nil
+}
+
+// syncBody returns the PullRequest body, with the upstream commits since the
+// previously mirrored commit.
+func (u *Updater) syncBody(ctx context.Context, input *SyncInput, previous, ref, sha string) (string, error) {
+	up := input.Upstream
+	var b strings.Builder
+	if input.PullRequest.Body != "" {
+		b.WriteString(input.PullRequest.Body + "\n\n")
+	}
+	fmt.Fprintf(&b, "Synced %s from %s %s at %s (%s).", input.Path, up.Repo, up.Path, ref, shortSHA(sha))
+	if previous == "" {
+		return b.String(), nil
+	}
+	commits, err := u.gitClient.CompareCommits(ctx, up.Repo, previous, sha)
+	if err != nil {
+		return "", fmt.Errorf("failed to compare %s...%s in %s: %w", shortSHA(previous), shortSHA(sha), up.Repo, err)
+	}
+	fmt.Fprintf(&b, "\n\nUpstream commits %s...%s:\n", shortSHA(previous), shortSHA(sha))
+	for i, c := range commits {
+		if i == maxSyncCommits {
+			fmt.Fprintf(&b, "\n- and %d more", len(commits)-maxSyncCommits)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s %s", shortSHA(c.Sha), strings.SplitN(c.Message, "\n", 2)[0])
+	}
+	return b.String(), nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,385 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	"github.com/agill17/pkg/verify"
+)
+
+const (
+	testUpstreamRepo  = "upstream/charts"
+	testSyncStateFile = "vendor/app.sync.yaml"
+	testPreviousSHA   = "3f9a29b5d7e0b2a4c1f2e3d4c5b6a7980a1b2c3d"
+	testUpstreamSHA   = "aa218f56b14c9653891f9e74264a383fa43fefbd"
+)
+
+func TestSync(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+	m.AddRef(testUpstreamRepo, "v1.1.0", testUpstreamSHA)
+	m.AddFileContents(testUpstreamRepo, "charts/app/Chart.yaml", testUpstreamSHA, []byte("version: 1.1.0\n"))
+	m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, []byte("replicas: 1\n"))
+	m.AddFileContents(testUpstreamRepo, "charts/app/templates/service.yaml", testUpstreamSHA, []byte("kind: Service\n"))
+	m.AddFileContents(testGitHubRepo, "vendor/app/Chart.yaml", testBranch, []byte("version: 1.0.0\n"))
+	m.AddFileContents(testGitHubRepo, "vendor/app/values.yaml", testBranch, []byte("replicas: 1\n"))
+	m.AddMissingFile(testGitHubRepo, "vendor/app/templates/service.yaml", testBranch)
+	m.AddFileContents(testGitHubRepo, testSyncStateFile, testBranch, []byte("repo: upstream/charts\nref: v1.0.0\nsha: "+testPreviousSHA+"\n"))
+	m.AddCommits(testUpstreamRepo, testPreviousSHA, testUpstreamSHA,
+		&scm.Commit{Sha: "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d", Message: "Add a service\n\nThe service exposes the app."},
+		&scm.Commit{Sha: testUpstreamSHA, Message: "Release v1.1.0"})
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeSyncInput()
+	input.PullRequest.Labels = []string{"sync"}
+	input.PullRequest.Draft = true
+
+	result, err := updater.Sync(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestCreated)
+	}
+	m.AssertLabelsAdded(testGitHubRepo, 1, "sync")
+	m.AssertDraftPullRequest(testGitHubRepo, 1)
+	for path, want := range map[string]string{
+		"vendor/app/Chart.yaml":             "version: 1.1.0\n",
+		"vendor/app/values.yaml":            "",
+		"vendor/app/templates/service.yaml": "kind: Service\n",
+		testSyncStateFile:                   "repo: upstream/charts\nref: v1.1.0\nsha: " + testUpstreamSHA + "\n",
+	} {
+		if diff := cmp.Diff(want, string(m.GetUpdatedContents(testGitHubRepo, path, "test-branch-a"))); diff != "" {
+			t.Errorf("incorrect update of %s:\n%s", path, diff)
+		}
+	}
+	m.AssertCommitMessage(testGitHubRepo, "vendor/app/Chart.yaml", "test-branch-a", "Sync vendor/app from upstream/charts v1.1.0")
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Sync vendor/app from upstream/charts v1.1.0",
+		Body:  "Synced vendor/app from upstream/charts charts/app at v1.1.0 (aa218f5).\n\nUpstream commits 3f9a29b...aa218f5:\n\n- 7fd1a60 Add a service\n- aa218f5 Release v1.1.0\n\nUpdate-Target: " + testSyncStateFile,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if diff := cmp.Diff(&SourceFile{Repo: testUpstreamRepo, Branch: "v1.1.0", Filename: "charts/app"}, result.Source); diff != "" {
+		t.Fatalf("incorrect source:\n%s", diff)
+	}
+}
+
+func TestSyncFile(t *testing.T) {
+	m := mock.New(t)
+	m.AddRef(testUpstreamRepo, "main", testUpstreamSHA)
+	m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, []byte("replicas: 2\n"))
+	m.AddMissingFile(testGitHubRepo, "vendor/values.yaml", testBranch)
+	m.AddMissingFile(testGitHubRepo, testSyncStateFile, testBranch)
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeSyncInput()
+	input.Upstream = Upstream{Repo: testUpstreamRepo, Branch: "main", Path: "charts/app/values.yaml"}
+	input.Path = "vendor/values.yaml"
+	input.CommitMessage = "Vendor the values"
+	input.PullRequest = PullRequestInput{Title: "Vendor the values", Body: "This is the body"}
+
+	_, err := updater.Sync(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(m.GetUpdatedContents(testGitHubRepo, "vendor/values.yaml", "test-branch-a")); s != "replicas: 2\n" {
+		t.Fatalf("update failed, got %#v, want %#v", s, "replicas: 2\n")
+	}
+	m.AssertCommitMessage(testGitHubRepo, testSyncStateFile, "test-branch-a", "Vendor the values")
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: "Vendor the values",
+		Body:  "This is the body\n\nSynced vendor/values.yaml from upstream/charts charts/app/values.yaml at main (aa218f5).\n\nUpdate-Target: " + testSyncStateFile,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestSyncRepeatedWithOpenPullRequest(t *testing.T) {
+	const syncBranch = "sync-charts-a"
+	syncTests := []struct {
+		name      string
+		state     string
+		wantState UpdateState
+	}{
+		{"upstream changed again", "repo: upstream/charts\nref: v1.0.1\nsha: 7fd1a60b01f91b314f59955a4e4d4e80d8edf11d\n", PullRequestUpdated},
+		{"already synced in the PullRequest", "repo: upstream/charts\nref: v1.1.0\nsha: " + testUpstreamSHA + "\n", Unchanged},
+	}
+
+	for _, tt := range syncTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+			m.AddRef(testUpstreamRepo, "v1.1.0", testUpstreamSHA)
+			m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, []byte("replicas: 2\n"))
+			m.AddFileContents(testGitHubRepo, "vendor/values.yaml", testBranch, []byte("replicas: 1\n"))
+			m.AddFileContents(testGitHubRepo, "vendor/values.yaml", syncBranch, []byte("replicas: 3\n"))
+			m.AddFileContents(testGitHubRepo, testSyncStateFile, testBranch, []byte("repo: upstream/charts\nref: v1.0.0\nsha: "+testPreviousSHA+"\n"))
+			m.AddFileContents(testGitHubRepo, testSyncStateFile, syncBranch, []byte(tt.state))
+			m.AddCommits(testUpstreamRepo, testPreviousSHA, testUpstreamSHA, &scm.Commit{Sha: testUpstreamSHA, Message: "Release v1.1.0"})
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			m.AddBranchHead(testGitHubRepo, syncBranch, "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+			m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: syncBranch, Target: testBranch, Body: "Update-Target: " + testSyncStateFile})
+			m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 8, Source: "sync-charts-b", Target: testBranch, Body: "Update-Target: vendor/other.sync.yaml"})
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeSyncInput()
+			input.Upstream = Upstream{Repo: testUpstreamRepo, LatestRelease: true, Path: "charts/app/values.yaml"}
+			input.Path = "vendor/values.yaml"
+			input.BranchGenerateName = "sync-charts-"
+
+			result, err := updater.Sync(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if result.State != tt.wantState {
+				rt.Fatalf("got state %s, want %s", result.State, tt.wantState)
+			}
+			m.AssertNoBranchesCreated()
+			m.AssertNoPullRequestsCreated()
+			if tt.wantState != PullRequestUpdated {
+				return
+			}
+			if s := string(m.GetUpdatedContents(testGitHubRepo, "vendor/values.yaml", syncBranch)); s != "replicas: 2\n" {
+				rt.Fatalf("update failed, got %#v", s)
+			}
+			m.AssertPullRequestUpdated(testGitHubRepo, 7, &scm.PullRequestInput{
+				Title: "Sync vendor/values.yaml from upstream/charts v1.1.0",
+				Body:  "Synced vendor/values.yaml from upstream/charts charts/app/values.yaml at v1.1.0 (aa218f5).\n\nUpstream commits 3f9a29b...aa218f5:\n\n- aa218f5 Release v1.1.0\n\nUpdate-Target: " + testSyncStateFile,
+			})
+		})
+	}
+}
+
+func TestSyncWithProtectedBranchFallbackAndOpenPullRequest(t *testing.T) {
+	m := mock.New(t)
+	m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+	m.AddRef(testUpstreamRepo, "v1.1.0", testUpstreamSHA)
+	m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, []byte("replicas: 2\n"))
+	for _, branch := range []string{testBranch, "update-z"} {
+		m.AddFileContents(testGitHubRepo, "vendor/values.yaml", branch, []byte("replicas: 1\n"))
+		m.AddFileContents(testGitHubRepo, testSyncStateFile, branch, []byte("repo: upstream/charts\nref: v1.0.0\nsha: "+testPreviousSHA+"\n"))
+	}
+	m.AddCommits(testUpstreamRepo, testPreviousSHA, testUpstreamSHA, &scm.Commit{Sha: testUpstreamSHA, Message: "Release v1.1.0"})
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, "update-z", "7fd1a60b01f91b314f59955a4e4d4e80d8edf11d")
+	m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: "update-z", Target: testBranch, Body: "Update-Target: " + testSyncStateFile})
+	m.ProtectBranch(testGitHubRepo, testBranch)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), ProtectedBranchFallback("update-"))
+	input := makeSyncInput()
+	input.Upstream = Upstream{Repo: testUpstreamRepo, LatestRelease: true, Path: "charts/app/values.yaml"}
+	input.Path = "vendor/values.yaml"
+	input.BranchGenerateName = ""
+
+	result, err := updater.Sync(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.State != PullRequestUpdated {
+		t.Fatalf("got state %s, want %s", result.State, PullRequestUpdated)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+	if s := string(m.GetUpdatedContents(testGitHubRepo, "vendor/values.yaml", "update-z")); s != "replicas: 2\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestSyncWithNoChange(t *testing.T) {
+	syncTests := []struct {
+		name  string
+		state string
+	}{
+		{"already synced", "sha: " + testUpstreamSHA + "\n"},
+		{"upstream content unchanged", "sha: " + testPreviousSHA + "\n"},
+	}
+
+	for _, tt := range syncTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+			m.AddRef(testUpstreamRepo, "v1.1.0", testUpstreamSHA)
+			m.AddFileContents(testUpstreamRepo, "charts/app/Chart.yaml", testUpstreamSHA, []byte("version: 1.1.0\n"))
+			m.AddFileContents(testGitHubRepo, "vendor/app/Chart.yaml", testBranch, []byte("version: 1.1.0\n"))
+			m.AddFileContents(testGitHubRepo, testSyncStateFile, testBranch, []byte(tt.state))
+			updater := New(zap.New(), m)
+
+			result, err := updater.Sync(context.Background(), makeSyncInput())
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if result.State != Unchanged {
+				rt.Fatalf("got state %s, want %s", result.State, Unchanged)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestSyncErrors(t *testing.T) {
+	syncTests := []struct {
+		name    string
+		input   func(*SyncInput)
+		wantErr string
+	}{
+		{"no state file", func(i *SyncInput) { i.StateFile = "" }, "the sync has no StateFile"},
+		{"no release", func(i *SyncInput) { i.Upstream.Repo = "upstream/missing" }, "failed to get the latest release of upstream/missing: release not found"},
+		{"unknown ref", func(i *SyncInput) { i.Upstream.LatestRelease, i.Upstream.Branch = false, "unknown" }, "failed to resolve unknown in upstream/charts: ref not found"},
+		{"signature without keyring", func(i *SyncInput) { i.Upstream.Verification = &Verification{Checksums: "SUMS", Signature: "SUMS.asc"} }, "needs both a Signature and a Keyring"},
+		{"empty verification", func(i *SyncInput) { i.Upstream.Verification = &Verification{} }, "has no Checksums or Signature"},
+		{"directory without checksums", func(i *SyncInput) { i.Upstream.Verification = &Verification{Signature: "SUMS.asc", Keyring: "keys"} }, "of a directory needs Checksums"},
+	}
+
+	for _, tt := range syncTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+			updater := New(zap.New(), m)
+			input := makeSyncInput()
+			tt.input(input)
+
+			_, err := updater.Sync(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestSyncWithVerification(t *testing.T) {
+	signer, keyring := newTestSigner(t)
+	chart, values := []byte("version: 1.1.0\n"), []byte("replicas: 1\n")
+	sums := []byte(fmt.Sprintf("%x  app/Chart.yaml\n%x  app/values.yaml\n", sha256.Sum256(chart), sha256.Sum256(values)))
+	verifyTests := []struct {
+		name      string
+		values    []byte
+		signature []byte
+		wantErr   string
+	}{
+		{"signed checksums", values, detachSign(t, signer, sums), ""},
+		{"tampered file", []byte("replicas: 100\n"), detachSign(t, signer, sums), "failed to verify upstream file charts/app/values.yaml: content failed verification: app/values.yaml does not match its sha256 checksum"},
+		{"tampered checksums", values, detachSign(t, signer, []byte("other sums")), "failed to verify upstream file charts/SHA256SUMS: content failed verification: openpgp: invalid signature"},
+	}
+
+	for _, tt := range verifyTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddLatestRelease(testUpstreamRepo, "v1.1.0")
+			m.AddRef(testUpstreamRepo, "v1.1.0", testUpstreamSHA)
+			m.AddFileContents(testUpstreamRepo, "charts/app/Chart.yaml", testUpstreamSHA, chart)
+			m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, tt.values)
+			m.AddFileContents(testUpstreamRepo, "charts/SHA256SUMS", testUpstreamSHA, sums)
+			m.AddFileContents(testUpstreamRepo, "charts/SHA256SUMS.asc", testUpstreamSHA, tt.signature)
+			m.AddMissingFile(testGitHubRepo, "vendor/app/Chart.yaml", testBranch)
+			m.AddMissingFile(testGitHubRepo, "vendor/app/values.yaml", testBranch)
+			m.AddMissingFile(testGitHubRepo, testSyncStateFile, testBranch)
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeSyncInput()
+			input.Upstream.Verification = &Verification{Checksums: "charts/SHA256SUMS", Signature: "charts/SHA256SUMS.asc", Keyring: keyring}
+
+			_, err := updater.Sync(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			if tt.wantErr != "" {
+				if !errors.Is(err, verify.ErrUnverified) {
+					rt.Fatalf("got %v, want ErrUnverified", err)
+				}
+				m.AssertNoInteractions()
+				return
+			}
+			if s := string(m.GetUpdatedContents(testGitHubRepo, "vendor/app/values.yaml", "test-branch-a")); s != string(values) {
+				rt.Fatalf("update failed, got %#v, want %#v", s, string(values))
+			}
+		})
+	}
+}
+
+func TestSyncFileWithSignature(t *testing.T) {
+	signer, keyring := newTestSigner(t)
+	values := []byte("replicas: 2\n")
+	m := mock.New(t)
+	m.AddRef(testUpstreamRepo, "main", testUpstreamSHA)
+	m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml", testUpstreamSHA, values)
+	m.AddFileContents(testUpstreamRepo, "charts/app/values.yaml.asc", testUpstreamSHA, detachSign(t, signer, []byte("replicas: 3\n")))
+	m.AddMissingFile(testGitHubRepo, testSyncStateFile, testBranch)
+	updater := New(zap.New(), m)
+	input := makeSyncInput()
+	input.Upstream = Upstream{
+		Repo:         testUpstreamRepo,
+		Branch:       "main",
+		Path:         "charts/app/values.yaml",
+		Verification: &Verification{Signature: "charts/app/values.yaml.asc", Keyring: keyring},
+	}
+	input.Path = "vendor/values.yaml"
+
+	_, err := updater.Sync(context.Background(), input)
+
+	if !errors.Is(err, verify.ErrUnverified) {
+		t.Fatalf("got %v, want ErrUnverified", err)
+	}
+	m.AssertNoInteractions()
+}
+
+// newTestSigner returns a new signing key, and its armored public key.
+func newTestSigner(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	e, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return e, b.String()
+}
+
+func detachSign(t *testing.T, e *openpgp.Entity, data []byte) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&b, e, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func makeSyncInput() *SyncInput {
+	return &SyncInput{
+		Upstream:           Upstream{Repo: testUpstreamRepo, Path: "charts/app", Directory: true, LatestRelease: true},
+		Repo:               testGitHubRepo,
+		Branch:             testBranch,
+		Path:               "vendor/app",
+		StateFile:          testSyncStateFile,
+		BranchGenerateName: "test-branch-",
+	}
+}
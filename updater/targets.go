@@ -0,0 +1,78 @@
+package updater
+
+import (
+	"sort"
+	"strings"
+)
+
+// targetTrailer prefixes the lines of a PullRequest body that record the files
+// and keys it changes, e.g.
+//
+//	Update-Target: environments/test/deployment.yaml#spec.image
+//
+// so that later updates of the same keys can reuse or supersede it.
+const targetTrailer = "Update-Target: "
+
+// changeTargets returns the trailer lines recording the files and keys changed
+// by the inputs, sorted, files changed by a ContentUpdater are recorded without
+// a key.
+func changeTargets(inputs []*Input) []string {
+	seen := map[string]bool{}
+	targets := []string{}
+	add := func(filename, key string) {
+		t := targetTrailer + filename
+		if key != "" {
+			t = t + "#" + key
+		}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	for _, input := range inputs {
+		for _, i := range input.fileInputs() {
+			if i.ContentUpdater != nil {
+				add(i.Filename, "")
+			} else {
+				add(i.Filename, i.Key)
+			}
+			for _, v := range i.Values {
+				add(i.Filename, v.Key)
+			}
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// withTargets appends the targets to the body, if the PullRequest is reused or
+// superseded by later updates.
+func withTargets(body string, targets []string, record bool) string {
+	if !record || len(targets) == 0 {
+		return body
+	}
+	return appendParagraph(body, strings.Join(targets, "\n"))
+}
+
+// hasTargets returns true if the body records exactly the targets.
+func hasTargets(body string, targets []string) bool {
+	recorded := []string{}
+	seen := map[string]bool{}
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, targetTrailer) && !seen[line] {
+			seen[line] = true
+			recorded = append(recorded, line)
+		}
+	}
+	if len(recorded) == 0 || len(recorded) != len(targets) {
+		return false
+	}
+	sort.Strings(recorded)
+	for n := range recorded {
+		if recorded[n] != targets[n] {
+			return false
+		}
+	}
+	return true
+}
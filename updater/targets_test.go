@@ -0,0 +1,55 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChangeTargets(t *testing.T) {
+	input := makeInput()
+	input.Values = []KeyValue{{Key: "test.tag", NewValue: "v1"}}
+	input.Files = []FileChange{
+		{Filename: "config/other.yaml", Key: "image"},
+		{Filename: "config/transformed.yaml", ContentUpdater: func(b []byte) ([]byte, error) { return b, nil }},
+	}
+	duplicate := makeInput()
+	transformed := makeInput()
+	transformed.Filename, transformed.ContentUpdater = "config/values.yaml", func(b []byte) ([]byte, error) { return b, nil }
+	transformed.Values = []KeyValue{{Key: "tag", NewValue: "v1"}}
+
+	want := []string{
+		"Update-Target: config/other.yaml#image",
+		"Update-Target: config/transformed.yaml",
+		"Update-Target: config/values.yaml",
+		"Update-Target: config/values.yaml#tag",
+		"Update-Target: " + testFilePath + "#test.image",
+		"Update-Target: " + testFilePath + "#test.tag",
+	}
+	if diff := cmp.Diff(want, changeTargets([]*Input{input, duplicate, transformed})); diff != "" {
+		t.Fatalf("targets differ:\n%s", diff)
+	}
+}
+
+func TestHasTargets(t *testing.T) {
+	targets := []string{"Update-Target: a.yaml#image", "Update-Target: b.yaml#image"}
+	targetTests := []struct {
+		name string
+		body string
+		want bool
+	}{
+		{"same targets", "Body\n\nUpdate-Target: b.yaml#image\nUpdate-Target: a.yaml#image", true},
+		{"no targets", "Body", false},
+		{"fewer targets", "Update-Target: a.yaml#image", false},
+		{"other targets", "Update-Target: a.yaml#image\nUpdate-Target: c.yaml#image", false},
+		{"more targets", "Update-Target: a.yaml#image\nUpdate-Target: b.yaml#image\nUpdate-Target: c.yaml#image", false},
+	}
+
+	for _, tt := range targetTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasTargets(tt.body, targets); got != tt.want {
+				t.Fatalf("hasTargets() got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
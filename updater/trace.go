@@ -0,0 +1,25 @@
+package updater
+
+import (
+	"github.com/go-logr/logr"
+
+	"github.com/agill17/pkg/syaml"
+)
+
+// Tracing is an option func for the Updater creation function, when
+// configured, the tracer is called with each updated YAML document, with the
+// values of keys matching the redactKeys regular expressions replaced.
+//
+// Tracing is opt-in, as documents may contain secrets e.g. decrypted files.
+func Tracing(t syaml.Tracer, redactKeys ...string) UpdaterFunc {
+	return func(u *Updater) {
+		u.traceOptions = []syaml.Option{syaml.WithTracer(t), syaml.RedactKeys(redactKeys...)}
+	}
+}
+
+// LogTracer returns a Tracer that logs the documents at debug level.
+func LogTracer(l logr.Logger) syaml.Tracer {
+	return func(doc []byte) {
+		l.V(1).Info("updated document", "document", string(doc))
+	}
+}
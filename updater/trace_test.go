@@ -0,0 +1,54 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client/mock"
+)
+
+func TestUpdateYAMLWithTracing(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  password: secret\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	traced := []string{}
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Tracing(func(doc []byte) {
+		traced = append(traced, string(doc))
+	}, `password$`))
+
+	_, err := updater.UpdateYAML(context.Background(), makeInput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"test:\n    image: new-image\n    password: REDACTED\n"}
+	if diff := cmp.Diff(want, traced); diff != "" {
+		t.Fatalf("incorrect trace:\n%s", diff)
+	}
+	updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")
+	if diff := cmp.Diff("test:\n  image: new-image\n  password: secret\n", string(updated)); diff != "" {
+		t.Fatalf("redaction changed the update:\n%s", diff)
+	}
+}
+
+func TestUpdateBatchWithTracing(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	traced := 0
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), Tracing(func([]byte) {
+		traced++
+	}))
+
+	_, err := updater.UpdateBatch(context.Background(), &Batch{Inputs: []*Input{makeInput()}, GroupByRepo: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if traced != 1 {
+		t.Fatalf("got %d traces, want 1", traced)
+	}
+}
@@ -0,0 +1,93 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/gjson"
+	"sigs.k8s.io/yaml"
+
+	"github.com/agill17/pkg/dockerfile"
+	"github.com/agill17/pkg/helm"
+	"github.com/agill17/pkg/syaml"
+)
+
+var transformFuncs = template.FuncMap{
+	"add":          func(a, b int64) int64 { return a + b },
+	"sub":          func(a, b int64) int64 { return a - b },
+	"replace":      func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix":   func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix":   func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"regexReplace": regexReplace,
+	"repository":   dockerfile.Repository,
+	"bump":         bumpVersion,
+}
+
+// TransformUpdater is a ContentUpdater that computes the new value of the key
+// in a YAML file by executing a Go template with the current value as dot.
+//
+// TransformUpdater("build", "{{ add . 1 }}")
+// TransformUpdater("image", `{{ repository . }}:v2`)
+//
+// In addition to the standard template functions, add, sub, replace,
+// trimPrefix, trimSuffix, regexReplace, repository and bump (e.g.
+// {{ bump "minor" . }}) are available, string arguments are last so that they
+// can be used in pipelines.
+//
+// If the current value is a string, the new value is a string, otherwise the
+// output is parsed as YAML, so numbers remain numbers.
+func TransformUpdater(key, expr string, opts ...syaml.Option) ContentUpdater {
+	return func(b []byte) ([]byte, error) {
+		tmpl, err := template.New(key).Funcs(transformFuncs).Option("missingkey=error").Parse(expr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the transform for key %s: %w", key, err)
+		}
+		j, err := yaml.YAMLToJSON(b)
+		if err != nil {
+			return nil, err
+		}
+		current := gjson.GetBytes(j, key)
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, templateValue(current)); err != nil {
+			return nil, fmt.Errorf("failed to execute the transform for key %s: %w", key, err)
+		}
+		var newValue interface{} = buf.String()
+		if current.Type != gjson.String {
+			if err := yaml.Unmarshal(buf.Bytes(), &newValue); err != nil {
+				newValue = buf.String()
+			}
+		}
+		return syaml.SetBytes(b, key, newValue, opts...)
+	}
+}
+
+// templateValue converts the value for use in templates, JSON numbers are
+// float64, whole numbers are converted to int64 so that they can be used with
+// add and sub.
+func templateValue(r gjson.Result) interface{} {
+	if r.Type == gjson.Number && r.Num == math.Trunc(r.Num) {
+		return r.Int()
+	}
+	return r.Value()
+}
+
+func regexReplace(pattern, replacement, s string) (string, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, replacement), nil
+}
+
+func bumpVersion(component, version string) (string, error) {
+	bumps := map[string]helm.Bump{"patch": helm.Patch, "minor": helm.Minor, "major": helm.Major}
+	bump, ok := bumps[component]
+	if !ok {
+		return "", fmt.Errorf("unknown version component %q", component)
+	}
+	return helm.BumpVersion(version, bump)
+}
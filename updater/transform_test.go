@@ -0,0 +1,60 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/agill17/pkg/test"
+)
+
+func TestTransformUpdater(t *testing.T) {
+	transformTests := []struct {
+		name  string
+		input string
+		key   string
+		expr  string
+		want  string
+	}{
+		{"increment build number", "build: 41\n", "build", "{{ add . 1 }}", "build: 42\n"},
+		{"string number stays a string", "build: \"41\"\n", "build", "{{ . }}2", "build: \"412\"\n"},
+		{"change the tag", "spec:\n  image: registry.example.com:5000/app:v1 # pinned\n", "spec.image", "{{ repository . }}:v2", "spec:\n  image: registry.example.com:5000/app:v2 # pinned\n"},
+		{"bump version", "version: v1.2.3\n", "version", `{{ bump "minor" . }}`, "version: v1.3.0\n"},
+		{"pipeline", "name: app-staging\n", "name", `{{ . | trimSuffix "-staging" }}-prod`, "name: app-prod\n"},
+		{"regex replace", "tag: 1.2.3-rc.1\n", "tag", `{{ regexReplace "-rc\\.\\d+$" "" . }}`, "tag: 1.2.3\n"},
+		{"missing value", "name: test\n", "replicas", `{{ if . }}{{ . }}{{ else }}3{{ end }}`, "name: test\nreplicas: 3\n"},
+	}
+
+	for _, tt := range transformTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			got, err := TransformUpdater(tt.key, tt.expr)([]byte(tt.input))
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				rt.Errorf("failed to transform:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTransformUpdaterErrors(t *testing.T) {
+	errorTests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{"invalid template", "{{ add . }", "failed to parse the transform for key version"},
+		{"failing function", `{{ bump "huge" . }}`, `failed to execute the transform for key version: .* unknown version component "huge"`},
+		{"invalid version", `{{ bump "minor" . }}`, `invalid semantic version "latest"`},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			_, err := TransformUpdater("version", tt.expr)([]byte("version: latest\n"))
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
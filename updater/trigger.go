@@ -0,0 +1,56 @@
+package updater
+
+// TriggerSource identifies the kind of event that triggered an update.
+type TriggerSource string
+
+const (
+	// TriggerRegistry is an image being pushed to a container registry.
+	TriggerRegistry TriggerSource = "registry"
+	// TriggerWebhook is a webhook from a git provider or CI system.
+	TriggerWebhook TriggerSource = "webhook"
+	// TriggerPoll is a change discovered by polling.
+	TriggerPoll TriggerSource = "poll"
+	// TriggerManual is a user-requested update.
+	TriggerManual TriggerSource = "manual"
+)
+
+// Trigger describes the event that caused an update, it's carried with the
+// Input so that it can be logged and recorded with the change.
+type Trigger struct {
+	Source     TriggerSource
+	Image      string // e.g. quay.io/my-org/my-image
+	Tag        string // e.g. v1.4.2
+	Digest     string // e.g. sha256:...
+	Commit     string // the SHA of the commit that the event relates to
+	Actor      string // the user or system that caused the event
+	PayloadRef string // a reference to the raw event payload e.g. a delivery ID
+	RunURL     string // the URL of the pipeline run that caused the event, e.g. the image build
+}
+
+// ImageRef returns the full image reference, including the tag and digest if
+// they are known.
+func (t Trigger) ImageRef() string {
+	ref := t.Image
+	if t.Tag != "" {
+		ref = ref + ":" + t.Tag
+	}
+	if t.Digest != "" {
+		ref = ref + "@" + t.Digest
+	}
+	return ref
+}
+
+func (t *Trigger) keysAndValues() []interface{} {
+	if t == nil {
+		return nil
+	}
+	kv := []interface{}{"trigger", string(t.Source)}
+	for _, f := range []struct {
+		k, v string
+	}{{"image", t.ImageRef()}, {"commit", t.Commit}, {"actor", t.Actor}, {"payloadRef", t.PayloadRef}, {"runURL", t.RunURL}} {
+		if f.v != "" {
+			kv = append(kv, f.k, f.v)
+		}
+	}
+	return kv
+}
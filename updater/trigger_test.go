@@ -0,0 +1,38 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTriggerImageRef(t *testing.T) {
+	refTests := []struct {
+		trigger Trigger
+		want    string
+	}{
+		{Trigger{Image: "quay.io/test/image"}, "quay.io/test/image"},
+		{Trigger{Image: "quay.io/test/image", Tag: "v1"}, "quay.io/test/image:v1"},
+		{Trigger{Image: "quay.io/test/image", Tag: "v1", Digest: "sha256:abc"}, "quay.io/test/image:v1@sha256:abc"},
+		{Trigger{Image: "quay.io/test/image", Digest: "sha256:abc"}, "quay.io/test/image@sha256:abc"},
+	}
+
+	for _, tt := range refTests {
+		if got := tt.trigger.ImageRef(); got != tt.want {
+			t.Errorf("ImageRef() got %#v, want %#v", got, tt.want)
+		}
+	}
+}
+
+func TestTriggerKeysAndValues(t *testing.T) {
+	var nilTrigger *Trigger
+	if kv := nilTrigger.keysAndValues(); kv != nil {
+		t.Fatalf("got %#v for a nil Trigger", kv)
+	}
+
+	trigger := &Trigger{Source: TriggerRegistry, Image: "quay.io/test/image", Tag: "v1", Actor: "robot"}
+	want := []interface{}{"trigger", "registry", "image", "quay.io/test/image:v1", "actor", "robot"}
+	if diff := cmp.Diff(want, trigger.keysAndValues()); diff != "" {
+		t.Fatalf("keysAndValues differ:\n%s", diff)
+	}
+}
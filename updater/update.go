@@ -1,16 +1,26 @@
 package updater
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/jenkins-x/go-scm/scm"
 
+	"github.com/agill17/pkg/buildinfo"
 	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/features"
+	"github.com/agill17/pkg/formats"
+	"github.com/agill17/pkg/lock"
 	"github.com/agill17/pkg/names"
+	"github.com/agill17/pkg/syaml"
 )
 
 // ContentUpdater takes an existing body, it should transform it, and return the
@@ -25,20 +35,115 @@ type CommitInput struct {
 	Repo               string // e.g. my-org/my-repo
 	Filename           string // relative path to the file in the repository
 	Branch             string // e.g. main
+	BaseBranch         string // e.g. release-1.2, the change is committed to, or the PullRequest opened against, this rather than the Branch
+	Fork               string // e.g. my-bot/my-repo, the new branch is pushed to this fork of the Repo, and a cross-repo PullRequest opened
 	NewBranchName      string // e.g. feature-update-image
 	BranchGenerateName string // e.g. update-image-
 	CommitMessage      string // This is used for the commit when updating the file
+	ResetBranch        bool   // Reset an existing NewBranchName to the base branch, rather than failing to create it
 }
 
 // PullRequestInput provides configuration for the PullRequest to be opened.
 type PullRequestInput struct {
-	SourceBranch string // e.g. 'main'
-	NewBranch    string
-	Repo         string // e.g. my-org/my-repo
-	Title        string
-	Body         string
+	SourceBranch  string // e.g. 'main'
+	NewBranch     string
+	Repo          string // e.g. my-org/my-repo
+	Title         string
+	Body          string
+	IncludeDiff   bool      // A diff of each changed file is appended to the Body
+	DiffStyle     DiffStyle // The style of the diff appended to the Body
+	MaxDiffLines  int       // The diff of each file is truncated to this many lines, the default is 250
+	Labels        []string  // Added to the PullRequest once it's created, e.g. automated
+	Reviewers     []string  // Users requested to review the PullRequest, e.g. octocat
+	TeamReviewers []string  // Teams requested to review the PullRequest, e.g. my-org/frontend
+	Assignees     []string  // Users assigned to the PullRequest, e.g. octocat
+	Draft         bool      // Open the PullRequest as a draft, which must be marked ready before it's merged
+	AutoMerge     string    // Merge the PullRequest with this method e.g. client.Squash, once its checks pass, or now if they have
+	Milestone     string    // The title of the open milestone the PullRequest is added to, e.g. v1.2.0
+	Project       string    // The node ID of the GitHub project the PullRequest is added to, e.g. PVT_kwDOA
+	ProjectColumn string    // The Status of the PullRequest in the Project, e.g. In review
+	Comment       string    // Posted on the PullRequest once it's created, a template with the MessageValues
+	CodeOwners    bool      // Request reviews from the owners of the changed files in the CODEOWNERS file
 }
 
+// DiffStyle configures how diffs are rendered in PullRequest bodies.
+type DiffStyle int
+
+const (
+	// TextDiff renders a unified diff of the lines in the file.
+	TextDiff DiffStyle = iota
+	// SemanticDiff renders the keys that were added, changed or removed,
+	// ignoring formatting.
+	SemanticDiff
+)
+
+// Input is used to configure the update of a key in a YAML file, and the
+// optional PullRequest for the change.
+//
+// The CommitMessage, and PullRequest Title and Body are templates, with the
+// MessageValues of the update, and the BranchGenerateName is a template with
+// the BranchValues.
+//
+// The values of SecretKeys, and all the values of files with an Encryption,
+// are redacted in messages, diffs and traces, and the UpdateResult records a
+// sha256 hash of the Previous value.
+//
+// With ReusePullRequest, the open PullRequest from the NewBranchName, or from a
+// branch with the BranchGenerateName prefix that changes the same files and
+// keys, is reused, the files and keys are recorded in the body of the
+// PullRequests opened with ReusePullRequest or a SupersedePolicy.
+type Input struct {
+	Repo               string          // e.g. my-org/my-repo
+	Filename           string          // relative path to the file in the repository
+	Branch             string          // e.g. main, the file is read from this branch
+	BaseBranch         string          // e.g. release-1.2, the change is committed to, or the PullRequest opened against, this rather than the Branch
+	Key                string          // e.g. metadata.annotations.reviewed
+	Format             string          // The format of the file in the formats registry e.g. json, this defaults to YAML
+	NewValue           interface{}     // e.g. test-user
+	BranchGenerateName string          // e.g. update-image-
+	NewBranchName      string          // e.g. update-service-a-image, this is used rather than generating a name
+	Fork               string          // e.g. my-bot/my-repo, the new branch is pushed to this fork of the Repo, and a cross-repo PullRequest opened
+	ResetBranch        bool            // Reset an existing NewBranchName to the base branch, and reuse its open PullRequest
+	ReusePullRequest   bool            // Commit to the branch of an open PullRequest from the NewBranchName, or the BranchGenerateName for the same keys, and update its Title and Body
+	Supersede          SupersedePolicy // What to do with the open PullRequests from previous updates, when a PullRequest is opened
+	CommitMessage      string          // This is used for the commit when updating the file
+	PullRequest        PullRequestInput
+	NoChange           NoChangePolicy  // What to do when the file already has the value
+	TrackingIssue      int             // Issue number used by NoChangeComment
+	Validator          syaml.Validator // Optional validation of the updated file
+	Trigger            *Trigger        // The event that caused the update
+	StrictPaths        bool            // Fail if the parents of the Key don't exist
+	EnsurePath         bool            // Create missing parents of the Key, even with StrictPaths
+	Encryption         Encryption      // Decrypts and re-encrypts encrypted files e.g. SOPS
+	ExpectedValue      *string         // Only update if the Key currently has this value
+	ExpectedPattern    string          // Only update if the Key currently matches this regular expression
+	SkipOnMismatch     bool            // Skip rather than fail the update if the current value is not expected
+	Source             *SourceFile     // Read the content from a file in another repo, rather than the Filename
+	Values             []KeyValue      // Further keys to set in the file, in the same commit
+	Files              []FileChange    // Further files to change in the same branch and PullRequest
+	ContentUpdater     ContentUpdater  `json:"-"` // Transforms the file, rather than setting the Key, before the Values are set
+	Delete             bool            // Remove the Key, rather than setting it to the NewValue, a nil NewValue sets null
+	SecretKeys         []string        // Regular expressions matching the paths of keys with secret values, which are not shown
+	PostActions        []PostAction    // Applied to the PullRequest after it's created e.g. AddLabels("automated")
+}
+
+// NoChangePolicy configures the behaviour of UpdateYAML when applying the
+// update would leave the file unchanged.
+type NoChangePolicy int
+
+const (
+	// NoChangeSkip does nothing, no branch, commit or PullRequest is created.
+	NoChangeSkip NoChangePolicy = iota
+	// NoChangePullRequest opens a PullRequest anyway, with an explanatory note
+	// appended to the body, as a visible record of the evaluation.
+	//
+	// This requires a BranchGenerateName.
+	NoChangePullRequest
+	// NoChangeComment posts an explanatory comment on the TrackingIssue in the
+	// repo, and no branch, commit or PullRequest is created.
+	NoChangeComment
+)
+
 var timeSeed = rand.New(rand.NewSource(time.Now().UnixNano()))
 
 // NameGenerator is an option func for the Updater creation function.
@@ -48,9 +153,18 @@ func NameGenerator(g names.Generator) UpdaterFunc {
 	}
 }
 
+// BuildInfoTrailer is an option func for the Updater creation function, when
+// configured, PullRequest bodies end with a trailer identifying the version
+// of the updater e.g. "Updated-By: agill17-pkg/v1.2.0 (abc1234)".
+func BuildInfoTrailer() UpdaterFunc {
+	return func(u *Updater) {
+		u.buildTrailer = true
+	}
+}
+
 // New creates and returns a new Updater.
 func New(l logr.Logger, c client.GitClient, opts ...UpdaterFunc) *Updater {
-	u := &Updater{gitClient: c, nameGenerator: names.New(timeSeed), log: l}
+	u := &Updater{gitClient: c, nameGenerator: names.New(timeSeed), log: l, now: time.Now, pollInterval: defaultPollInterval}
 	for _, o := range opts {
 		o(u)
 	}
@@ -59,71 +173,736 @@ func New(l logr.Logger, c client.GitClient, opts ...UpdaterFunc) *Updater {
 
 // Updater can update a Git repo with an updated version of a file.
 type Updater struct {
-	gitClient     client.GitClient
-	nameGenerator names.Generator
-	log           logr.Logger
+	gitClient       client.GitClient
+	nameGenerator   names.Generator
+	log             logr.Logger
+	preflight       bool
+	bundleWriter    io.Writer
+	sanitizers      []Sanitizer
+	buildTrailer    bool
+	provenance      bool
+	fallbackPrefix  string
+	forks           *forkOptions
+	cleanupBranches bool
+	requirePRs      bool
+	store           Store
+	traceOptions    []syaml.Option
+	secretKeys      map[string][]string
+	hashBranches    bool
+	gates           *features.Gates
+	now             func() time.Time
+	locker          lock.Locker
+	lockTTL         time.Duration
+	lockInterval    time.Duration
+	decisions       *decisionLog
+	pollInterval    time.Duration
+	commitStatus    *scm.StatusInput
 }
 
 // ApplyUpdateToFile does the job of fetching the existing file, passing it to a
 // user-provided function, and optionally creating a PR.
+//
+// If the function returns the file unchanged, an error wrapping ErrNoChange is
+// returned, use ApplyUpdate, or UpdateFile in version 2, for a result with the
+// State Unchanged.
 func (u *Updater) ApplyUpdateToFile(ctx context.Context, input CommitInput, f ContentUpdater) (string, error) {
+	if err := u.checkPolicy(input); err != nil {
+		return "", err
+	}
+	unlock, err := u.lockRepo(ctx, input.Repo)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	if err := u.preflightChecks(ctx, input, []string{input.Filename}); err != nil {
+		return "", err
+	}
+	if err := u.checkWritable(ctx, input.Repo); err != nil {
+		return "", err
+	}
 	current, err := u.gitClient.GetFile(ctx, input.Repo, input.Branch, input.Filename)
 	if err != nil {
 		u.log.Info("failed to get file from repo", "err", err)
-		return "", err
+		return "", scmError(err, input.Repo, fmt.Sprintf("get file %s from branch %s", input.Filename, input.Branch), fileKinds)
 	}
 	u.log.Info("got existing file", "sha", current.Sha)
 	updated, err := f(current.Data)
 	if err != nil {
 		return "", err
 	}
-	return u.applyUpdate(ctx, input, current.Sha, updated)
+	if bytes.Equal(current.Data, updated) {
+		u.log.Info("no change required", "filename", input.Filename)
+		return "", fmt.Errorf("%w: %s in repo %s is already up to date", ErrNoChange, input.Filename, input.Repo)
+	}
+	newBranchName, _, err := u.applyUpdate(ctx, input, current.Sha, updated)
+	return newBranchName, err
+}
+
+// UpdateYAML does the job of fetching the existing file, updating the key in
+// it, and optionally creating a PR.
+//
+// If no BranchGenerateName is configured, the change is committed directly to
+// the branch and no PullRequest is returned, use Update to distinguish this
+// from no change being required, where the UpdateResult State is Unchanged and
+// no branch or PullRequest is created.
+func (u *Updater) UpdateYAML(ctx context.Context, input *Input) (*scm.PullRequest, error) {
+	r, err := u.Update(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return r.PullRequest, nil
+}
+
+// ApplyUpdate does the job of fetching the existing file, passing it to the
+// ContentUpdater rather than updating the Key, and optionally creating a PR,
+// the result records what was done.
+//
+// The branch, commit and PullRequest handling is the same as Update, and the
+// Input is not modified.
+func (u *Updater) ApplyUpdate(ctx context.Context, input *Input, f ContentUpdater) (*UpdateResult, error) {
+	i := *input
+	i.ContentUpdater = f
+	return u.Update(ctx, &i)
+}
+
+// Update does the job of fetching the existing file, updating the key in it,
+// and optionally creating a PR, the result records what was done.
+//
+// The Files of the Input are changed in the same branch, and included in the
+// same PullRequest, each file is committed separately, as are the files
+// matching a glob Filename e.g. environments/*/services/service-a/*.yaml.
+//
+// With RepoLocks, the update waits for the repo's lock.
+//
+// The Decision for the outcome is recorded, see Decisions.
+func (u *Updater) Update(ctx context.Context, input *Input) (*UpdateResult, error) {
+	r, err := u.lockedUpdate(ctx, input)
+	u.decide(input, r, err)
+	return r, err
+}
+
+func (u *Updater) lockedUpdate(ctx context.Context, input *Input) (*UpdateResult, error) {
+	unlock, err := u.lockRepo(ctx, input.Repo)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if u.bundleWriter == nil {
+		return u.update(ctx, input)
+	}
+	rec := &recordingClient{GitClient: u.gitClient}
+	recording := *u
+	recording.gitClient = rec
+	start := time.Now()
+	r, err := recording.update(ctx, input)
+	if err != nil {
+		u.writeSupportBundle(input, rec.calls, start, err)
+	}
+	return r, err
+}
+
+func (u *Updater) update(ctx context.Context, input *Input) (*UpdateResult, error) {
+	if len(input.Files) > 0 || isGlob(input.Filename) {
+		return u.updateFiles(ctx, input)
+	}
+	if input.NoChange == NoChangePullRequest && !input.commitInput().newBranch() {
+		return nil, fmt.Errorf("a BranchGenerateName is required to open a PullRequest when no change is required")
+	}
+	original := input
+	input, err := input.withBranchPrefix(u.now())
+	if err != nil {
+		return nil, err
+	}
+	input = u.hashedBranch(input)
+	if err := u.checkPolicy(input.commitInput()); err != nil {
+		return nil, err
+	}
+	secrets, err := u.secrets(input)
+	if err != nil {
+		return nil, err
+	}
+	if input.Trigger != nil {
+		u.log.Info("update triggered", input.Trigger.keysAndValues()...)
+	}
+	if err := u.preflightChecks(ctx, input.commitInput(), preflightFiles([]*Input{input})); err != nil {
+		return nil, err
+	}
+	if err := u.checkWritable(ctx, input.Repo); err != nil {
+		return nil, err
+	}
+	commit, err := u.withFork(ctx, input.commitInput())
+	if err != nil {
+		return nil, err
+	}
+	input.Fork = commit.Fork
+	targets := changeTargets([]*Input{input})
+	reuse, err := u.reusablePullRequest(ctx, input.commitInput(), input.ReusePullRequest, targets)
+	if err != nil {
+		return nil, err
+	}
+	if reuse != nil {
+		input = input.onBranch(reuse.Source)
+	}
+	current, plaintext, base, err := u.readFiles(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	updated, previous, err := input.apply(base, secrets.options(u.traceOptions)...)
+	err = secrets.maskError(input.Key, err)
+	if input.skipMismatch(err) {
+		u.log.Info("skipping update, the current value is not expected", "filename", input.Filename, "key", input.Key, "reason", err.Error())
+		return &UpdateResult{State: Skipped}, nil
+	}
+	if err != nil {
+		return nil, input.applyError(err)
+	}
+	values := secrets.maskValues(input.messageValues(previous))
+	// The previous value of a secret is only recorded as a hash.
+	previous = secrets.maskPrevious(input.Key, previous)
+	newValue := secrets.maskInput(input).NewValue
+	if input.Delete || input.ContentUpdater != nil {
+		newValue = nil
+	}
+	d := secrets.diffFile(input.Filename, current.Data, updated, input.Encryption != nil)
+	values.Diff = d.unified
+	if d.hidden != "" {
+		u.log.V(1).Info("not showing the diff of the file", "filename", input.Filename, "reason", d.hidden)
+	} else {
+		u.log.V(1).Info("calculated diff", "filename", input.Filename, "diff", values.Diff)
+	}
+	diffBody := ""
+	if input.PullRequest.IncludeDiff {
+		if diffBody, err = d.render(input.PullRequest); err != nil {
+			return nil, err
+		}
+	}
+	if input, err = input.withMessages(values); err != nil {
+		return nil, err
+	}
+	input.CommitMessage = u.withProvenance(input.CommitMessage, input.Trigger)
+	prBody := input.PullRequest.Body
+	if diffBody != "" {
+		prBody = appendParagraph(prBody, diffBody)
+	}
+	if bytes.Equal(plaintext, updated) {
+		u.log.Info("no change required", "filename", input.Filename, "key", input.Key)
+		switch input.NoChange {
+		case NoChangePullRequest:
+			if reuse != nil {
+				// The open PullRequest is already a record of the change.
+				return &UpdateResult{State: Unchanged, Previous: previous, NewValue: newValue, Source: input.Source}, nil
+			}
+			prBody = appendParagraph(prBody, noChangeMessage(secrets.maskInput(input)))
+		case NoChangeComment:
+			if err := u.gitClient.CreateComment(ctx, input.Repo, input.TrackingIssue, u.sanitize(TextComment, noChangeMessage(secrets.maskInput(input)))); err != nil {
+				return nil, fmt.Errorf("failed to comment on tracking issue: %w", err)
+			}
+			u.log.Info("commented on tracking issue", "number", input.TrackingIssue)
+			return &UpdateResult{State: Unchanged, Previous: previous, NewValue: newValue, Source: input.Source}, nil
+		default:
+			return &UpdateResult{State: Unchanged, Previous: previous, NewValue: newValue, Source: input.Source}, nil
+		}
+	}
+	prBody = u.withProvenance(prBody, input.Trigger)
+	prBody = withTargets(prBody, targets, input.ReusePullRequest || input.Supersede != SupersedeNone)
+	content, err := input.encrypt(ctx, updated, current.Data)
+	if err != nil {
+		return nil, err
+	}
+	if reuse == nil && input.commitInput().newBranch() {
+		if err := u.checkDrafts(ctx, input.PullRequest.Draft); err != nil {
+			return nil, err
+		}
+	}
+	newBranchName, baseSHA, err := u.applyUpdate(ctx, input.commitInput(), current.Sha, content)
+	if u.fallback(input.commitInput(), err) {
+		return u.update(ctx, u.fallbackInput(original))
+	}
+	if err != nil {
+		return nil, err
+	}
+	result := &UpdateResult{Branch: newBranchName, Commit: u.headCommit(ctx, input.commitInput().headRepo(), newBranchName), Base: baseSHA, Previous: previous, NewValue: newValue, Source: input.Source}
+	if input.Fork == "" {
+		u.reportStatus(ctx, input.Repo, result.Commit)
+	}
+	// The content of files with secret values is not recorded.
+	if !secrets.any() {
+		result.Content = content
+	}
+	if reuse != nil {
+		pr, err := u.updatePullRequest(ctx, input.Repo, reuse, input.PullRequest.Title, prBody)
+		if err != nil {
+			return nil, err
+		}
+		result.State, result.PullRequest = PullRequestUpdated, pr
+		return result, nil
+	}
+	if !input.commitInput().newBranch() {
+		result.State = Committed
+		return result, nil
+	}
+	pr, state, err := u.openPullRequest(ctx, input.commitInput(), PullRequestInput{
+		SourceBranch: input.base(),
+		NewBranch:    newBranchName,
+		Repo:         input.Repo,
+		Title:        input.PullRequest.Title,
+		Body:         prBody,
+		Draft:        input.PullRequest.Draft,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if input.Fork != "" {
+		// The commit from the fork is in the Repo once the PullRequest is
+		// opened.
+		u.reportStatus(ctx, input.Repo, result.Commit)
+	}
+	result.State, result.PullRequest = state, pr
+	if state == PullRequestCreated {
+		result.Superseded = u.supersede(ctx, input.commitInput(), input.Supersede, pr, newBranchName, targets)
+		u.cleanup(ctx, input.commitInput())
+		result.PostActionErrors = u.applyPostActions(ctx, input.Repo, pr, withRequests(input.PullRequest, input.base(), []string{input.Filename}, input.PostActions))
+	}
+	return result, nil
+}
+
+func (i *Input) commitInput() CommitInput {
+	return CommitInput{
+		Repo:               i.Repo,
+		Filename:           i.Filename,
+		Branch:             i.Branch,
+		BaseBranch:         i.BaseBranch,
+		Fork:               i.Fork,
+		NewBranchName:      i.NewBranchName,
+		BranchGenerateName: i.BranchGenerateName,
+		CommitMessage:      i.CommitMessage,
+		ResetBranch:        i.ResetBranch,
+	}
+}
+
+// base returns the branch that the change is committed to, or the PullRequest
+// opened against, the BaseBranch if there is one, or the Branch.
+func (c CommitInput) base() string {
+	if c.BaseBranch != "" {
+		return c.BaseBranch
+	}
+	return c.Branch
+}
+
+// base returns the BaseBranch if there is one, or the Branch.
+func (i *Input) base() string {
+	return i.commitInput().base()
+}
+
+// baseFileSHA returns the SHA of the file in the base branch, for committing
+// the file read from the Branch, it's empty if the file is not in the base
+// branch, and the sha if the branches are the same.
+func (u *Updater) baseFileSHA(ctx context.Context, input CommitInput, filename, sha string) (string, error) {
+	if input.base() == input.Branch {
+		return sha, nil
+	}
+	current, err := u.gitClient.GetFile(ctx, input.Repo, input.base(), filename)
+	if client.IsNotFound(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", scmError(err, input.Repo, fmt.Sprintf("get file %s from branch %s", filename, input.base()), fileKinds)
+	}
+	return current.Sha, nil
+}
+
+// newBranch returns true if the change is committed to a new branch, rather
+// than directly to the Branch.
+func (c CommitInput) newBranch() bool {
+	return c.BranchGenerateName != "" || c.NewBranchName != ""
+}
+
+// apply sets, or deletes, the Key in the body, and then sets the Values, it
+// returns the value of the Key that was replaced when setting the Key.
+//
+// The current value expectations apply to the Key, and the Validator to the
+// final body.
+func (i *Input) apply(b []byte, opts ...syaml.Option) ([]byte, *string, error) {
+	if i.ContentUpdater != nil {
+		return i.applyContent(b, opts)
+	}
+	if !i.isYAML() {
+		return i.applyFormat(b)
+	}
+	keyOpts := append(i.syamlOptions(len(i.Values) == 0, true), opts...)
+	var updated []byte
+	var previous *string
+	var err error
+	if i.Delete {
+		updated, err = syaml.DeleteBytes(b, i.Key, keyOpts...)
+	} else {
+		updated, previous, err = syaml.SetBytesWithOld(b, i.Key, i.NewValue, keyOpts...)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if updated, err = i.setValues(updated, opts); err != nil {
+		return nil, nil, err
+	}
+	return updated, previous, nil
+}
+
+// setValues sets the Values in the body, the Validator applies to the body
+// after the last value is set.
+func (i *Input) setValues(b []byte, opts []syaml.Option) ([]byte, error) {
+	for n, v := range i.Values {
+		valueOpts := append(i.syamlOptions(n == len(i.Values)-1, false), opts...)
+		var err error
+		if b, err = syaml.SetBytes(b, v.Key, v.NewValue, valueOpts...); err != nil {
+			return nil, fmt.Errorf("failed to update key %s: %w", v.Key, err)
+		}
+	}
+	return b, nil
 }
 
-func (u *Updater) applyUpdate(ctx context.Context, input CommitInput, currentSHA string, newBody []byte) (string, error) {
-	branchRef, err := u.gitClient.GetBranchHead(ctx, input.Repo, input.Branch)
+// applyContent is apply for a ContentUpdater, the Values are set in the
+// transformed body, and the Validator applies to the final body.
+func (i *Input) applyContent(b []byte, opts []syaml.Option) ([]byte, *string, error) {
+	if !i.isYAML() && (i.Validator != nil || len(i.Values) > 0) {
+		return nil, nil, fmt.Errorf("the Values and Validator of a ContentUpdater are only supported for YAML files, not %s", i.Format)
+	}
+	updated, err := i.ContentUpdater(b)
+	if err != nil || !i.isYAML() {
+		return updated, nil, err
+	}
+	if len(i.Values) == 0 {
+		if err := syaml.ValidateBytes(updated, append(i.syamlOptions(true, false), opts...)...); err != nil {
+			return nil, nil, err
+		}
+		return updated, nil, nil
+	}
+	if updated, err = i.setValues(updated, opts); err != nil {
+		return nil, nil, err
+	}
+	return updated, nil, nil
+}
+
+// applyFormat is apply for files in a Format other than YAML, the current
+// value expectations are checked against the value returned by the Format.
+func (i *Input) applyFormat(b []byte) ([]byte, *string, error) {
+	if i.Validator != nil || i.StrictPaths || i.EnsurePath {
+		return nil, nil, fmt.Errorf("the Validator, StrictPaths and EnsurePath are only supported for YAML files, not %s", i.Format)
+	}
+	f, err := formats.Lookup(i.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	var previous *string
+	current, err := f.Get(b, i.Key)
+	if err == nil {
+		previous = &current
+	} else if !errors.Is(err, formats.ErrKeyNotFound) {
+		return nil, nil, err
+	}
+	if err := i.checkCurrent(previous); err != nil {
+		return nil, nil, err
+	}
+	var updated []byte
+	if i.Delete {
+		// As with YAML, the previous value is only returned when it's set.
+		previous = nil
+		updated, err = f.Delete(b, i.Key)
+	} else {
+		updated, err = f.Set(b, i.Key, i.NewValue)
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get branch head: %v", err)
+		return nil, nil, err
+	}
+	for _, v := range i.Values {
+		if updated, err = f.Set(updated, v.Key, v.NewValue); err != nil {
+			return nil, nil, fmt.Errorf("failed to update key %s: %w", v.Key, err)
+		}
+	}
+	return updated, previous, nil
+}
+
+// checkCurrent returns an error wrapping syaml.ErrUnexpectedValue if the
+// current value doesn't match the ExpectedValue or ExpectedPattern, a missing
+// value never matches.
+func (i *Input) checkCurrent(current *string) error {
+	if i.ExpectedValue == nil && i.ExpectedPattern == "" {
+		return nil
+	}
+	if current == nil {
+		return fmt.Errorf("%w: %s has no value", syaml.ErrUnexpectedValue, i.Key)
+	}
+	if i.ExpectedValue != nil && *current != *i.ExpectedValue {
+		return fmt.Errorf("%w: %s is %q, expected %q", syaml.ErrUnexpectedValue, i.Key, *current, *i.ExpectedValue)
+	}
+	if i.ExpectedPattern != "" {
+		re, err := regexp.Compile(i.ExpectedPattern)
+		if err != nil {
+			return fmt.Errorf("invalid expected pattern %q: %w", i.ExpectedPattern, err)
+		}
+		if !re.MatchString(*current) {
+			return fmt.Errorf("%w: %s is %q, expected a match for %q", syaml.ErrUnexpectedValue, i.Key, *current, i.ExpectedPattern)
+		}
+	}
+	return nil
+}
+
+// isYAML returns true if the file is updated as YAML, which has the most
+// complete support for options.
+func (i *Input) isYAML() bool {
+	return i.Format == "" || strings.EqualFold(i.Format, "yaml")
+}
+
+// applyError describes a failure to apply the update to the file.
+func (i *Input) applyError(err error) error {
+	if i.ContentUpdater != nil {
+		return fmt.Errorf("failed to update file %s: %w", i.Filename, err)
+	}
+	return fmt.Errorf("failed to update key %s in file %s: %w", i.Key, i.Filename, err)
+}
+
+func (i *Input) syamlOptions(validate, expect bool) []syaml.Option {
+	opts := []syaml.Option{}
+	if i.Validator != nil && validate {
+		opts = append(opts, syaml.WithValidator(i.Validator))
+	}
+	if i.StrictPaths {
+		opts = append(opts, syaml.Strict())
+	}
+	if i.EnsurePath {
+		opts = append(opts, syaml.EnsurePath())
+	}
+	if i.ExpectedValue != nil && expect {
+		opts = append(opts, syaml.ExpectValue(*i.ExpectedValue))
+	}
+	if i.ExpectedPattern != "" && expect {
+		opts = append(opts, syaml.ExpectMatch(i.ExpectedPattern))
+	}
+	return opts
+}
+
+// skipMismatch returns true if the error is an unexpected current value and
+// the Input is configured to skip the update.
+func (i *Input) skipMismatch(err error) bool {
+	return i.SkipOnMismatch && errors.Is(err, syaml.ErrUnexpectedValue)
+}
+
+// defaultMaxDiffLines is the number of lines of the diff of a file that are
+// included in a PullRequest body by default, GitHub rejects bodies longer
+// than 65536 characters.
+const defaultMaxDiffLines = 250
+
+// renderDiff renders the change as a fenced block for including in a
+// PullRequest body, truncated to the maximum lines, or the default if it's 0.
+func renderDiff(style DiffStyle, filename string, original, updated []byte, maxLines int) (string, error) {
+	fence, diff := "```diff\n", ""
+	if style == SemanticDiff {
+		changes, err := syaml.SemanticDiff(original, updated)
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate the changes to %s: %w", filename, err)
+		}
+		fence, diff = "```\n", syaml.FormatChanges(changes)
+	} else {
+		diff = syaml.UnifiedDiff(filename, original, updated)
+	}
+	if maxLines <= 0 {
+		maxLines = defaultMaxDiffLines
+	}
+	lines := strings.SplitAfter(diff, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= maxLines {
+		return fence + diff + "```", nil
+	}
+	truncated := strings.Join(lines[:maxLines], "")
+	return fmt.Sprintf("%s%s```\n\nThe diff of `%s` is truncated, %d more lines are not shown.", fence, truncated, filename, len(lines)-maxLines), nil
+}
+
+func noChangeMessage(input *Input) string {
+	if input.ContentUpdater != nil {
+		return fmt.Sprintf("No change required, %s is already up to date.", input.Filename)
+	}
+	if input.Delete {
+		return fmt.Sprintf("No change required, %s is not in %s.", input.Key, input.Filename)
+	}
+	return fmt.Sprintf("No change required, %s in %s already has the value %v.", input.Key, input.Filename, input.NewValue)
+}
+
+// pullRequestBody sanitizes the body, and appends the trailer, if configured.
+func (u *Updater) pullRequestBody(body string) string {
+	body = u.sanitize(TextPullRequestBody, body)
+	// The provenance trailers include the build info.
+	if u.buildTrailer && !u.provenance {
+		body = appendParagraph(body, buildInfoTrailer())
+	}
+	return body
+}
+
+func buildInfoTrailer() string {
+	return "Updated-By: " + buildinfo.Name + "/" + buildinfo.Get().String()
+}
+
+func appendParagraph(body, p string) string {
+	if body == "" {
+		return p
+	}
+	return body + "\n\n" + p
+}
+
+func (u *Updater) applyUpdate(ctx context.Context, input CommitInput, currentSHA string, newBody []byte) (string, string, error) {
+	branchRef, err := u.gitClient.GetBranchHead(ctx, input.Repo, input.base())
+	if err != nil {
+		return "", "", scmError(err, input.Repo, "get branch head", nil)
 	}
 	newBranchName, err := u.createBranchIfNecessary(ctx, input, branchRef)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if currentSHA, err = u.baseFileSHA(ctx, input, input.Filename, currentSHA); err != nil {
+		return "", "", err
 	}
-	err = u.gitClient.UpdateFile(ctx, input.Repo, newBranchName, input.Filename, input.CommitMessage, currentSHA, newBody)
+	err = u.gitClient.UpdateFile(ctx, input.headRepo(), newBranchName, input.Filename, u.sanitize(TextCommitMessage, input.CommitMessage), currentSHA, newBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to update file: %w", err)
+		return "", "", scmError(err, input.Repo, "update file", commitKinds(input, err))
 	}
 	u.log.Info("updated file", "filename", input.Filename)
-	return newBranchName, nil
+	return newBranchName, branchRef, nil
+}
+
+// headCommit returns the SHA of the head of the branch after a commit, the
+// change has been made, so failures are logged rather than returned.
+func (u *Updater) headCommit(ctx context.Context, repo, branch string) string {
+	sha, err := u.gitClient.GetBranchHead(ctx, repo, branch)
+	if err != nil {
+		u.log.Error(err, "failed to get the commit", "repo", repo, "branch", branch)
+		return ""
+	}
+	return sha
 }
 
 func (u *Updater) createBranchIfNecessary(ctx context.Context, input CommitInput, sourceRef string) (string, error) {
 	newBranchName := input.NewBranchName
 	if input.BranchGenerateName == "" && newBranchName == "" {
-		u.log.Info("no branchGenerateName/newBranchName configured, reusing source branch", "branch", input.Branch)
-		return input.Branch, nil
+		u.log.Info("no branchGenerateName/newBranchName configured, reusing source branch", "branch", input.base())
+		return input.base(), nil
+	}
+	if input.ResetBranch {
+		if newBranchName == "" {
+			return "", errors.New("a NewBranchName is required to reset the branch")
+		}
+		if err := u.gitClient.ResetBranch(ctx, input.headRepo(), newBranchName, sourceRef); err != nil {
+			return "", scmError(err, input.Repo, "reset branch", nil)
+		}
+		u.log.Info("reset branch", "branch", newBranchName, "ref", sourceRef)
+		return newBranchName, nil
 	}
 	if newBranchName == "" {
 		newBranchName = u.nameGenerator.PrefixedName(input.BranchGenerateName)
 		u.log.Info("generating new branch", "name", newBranchName)
 	}
-	err := u.gitClient.CreateBranch(ctx, input.Repo, newBranchName, sourceRef)
+	err := u.gitClient.CreateBranch(ctx, input.headRepo(), newBranchName, sourceRef)
 	if err != nil {
-		return "", fmt.Errorf("failed to create branch: %w", err)
+		return "", scmError(err, input.Repo, "create branch", branchKinds)
 	}
 	u.log.Info("created branch", "branch", newBranchName, "ref", sourceRef)
 	return newBranchName, nil
 }
 
+// openPullRequest creates the PullRequest, or if the branch was reset, reuses
+// the open PullRequest from the branch, if there is one.
+func (u *Updater) openPullRequest(ctx context.Context, commit CommitInput, input PullRequestInput) (*scm.PullRequest, UpdateState, error) {
+	if commit.ResetBranch {
+		pr, err := u.findPullRequest(ctx, commit, input.NewBranch)
+		if err == nil {
+			u.log.Info("reusing PullRequest", "number", pr.Number, "branch", input.NewBranch)
+			return pr, PullRequestUpdated, nil
+		}
+		if !client.IsNotFound(err) {
+			return nil, Unchanged, fmt.Errorf("failed to find a pull request from branch %s: %w", input.NewBranch, err)
+		}
+	}
+	branch := input.NewBranch
+	input.NewBranch = commit.head(branch)
+	pr, err := u.createPullRequest(ctx, input)
+	if err != nil {
+		u.cleanupFork(ctx, commit, branch)
+		return nil, Unchanged, err
+	}
+	return pr, PullRequestCreated, nil
+}
+
+// CreatePR creates a PullRequest from the new branch to the source branch,
+// and adds the Labels, Reviewers, TeamReviewers and Assignees.
+//
+// If these can't be added, the PullRequest is returned with the error.
 func (u *Updater) CreatePR(ctx context.Context, input PullRequestInput) (*scm.PullRequest, error) {
-	pr, err := u.gitClient.CreatePullRequest(ctx, input.Repo, &scm.PullRequestInput{
-		Title: input.Title,
-		Body:  input.Body,
+	pr, err := u.createPullRequest(ctx, input)
+	if err != nil {
+		return pr, err
+	}
+	requests := []struct {
+		op     string
+		values []string
+		add    func(ctx context.Context, repo string, number int, values []string) error
+	}{
+		{"add labels to", input.Labels, u.gitClient.AddLabels},
+		{"request reviewers for", input.Reviewers, u.gitClient.RequestReviewers},
+		{"request team reviewers for", input.TeamReviewers, u.gitClient.RequestTeamReviewers},
+		{"add assignees to", input.Assignees, u.gitClient.AddAssignees},
+	}
+	for _, r := range requests {
+		if len(r.values) == 0 {
+			continue
+		}
+		if err := r.add(ctx, input.Repo, pr.Number, r.values); err != nil {
+			return pr, scmError(err, input.Repo, fmt.Sprintf("%s pull request %d", r.op, pr.Number), nil)
+		}
+	}
+	return pr, nil
+}
+
+// ErrDraftsUnsupported is returned when a Draft PullRequest is configured, and
+// the git provider doesn't support drafts.
+var ErrDraftsUnsupported = errors.New("the git provider does not support draft pull requests")
+
+// checkDrafts returns ErrDraftsUnsupported for a Draft PullRequest if the git
+// provider doesn't support drafts, it's checked before the change is committed,
+// rather than opening a PullRequest that could be merged.
+func (u *Updater) checkDrafts(ctx context.Context, draft bool) error {
+	if !draft {
+		return nil
+	}
+	caps, err := u.gitClient.Capabilities(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the git provider capabilities: %w", err)
+	}
+	if !caps.DraftPullRequests {
+		return ErrDraftsUnsupported
+	}
+	return nil
+}
+
+// createPullRequest creates the PullRequest without the Labels, Reviewers,
+// TeamReviewers and Assignees, the updates add them with the PostActions, so
+// that failures are recorded in the UpdateResult.
+func (u *Updater) createPullRequest(ctx context.Context, input PullRequestInput) (*scm.PullRequest, error) {
+	inp := &scm.PullRequestInput{
+		Title: u.sanitize(TextPullRequestTitle, input.Title),
+		Body:  u.pullRequestBody(input.Body),
 		Head:  input.NewBranch,
 		Base:  input.SourceBranch,
-	})
+	}
+	if input.Draft {
+		pr, err := u.gitClient.CreateDraftPullRequest(ctx, input.Repo, inp)
+		if err != nil {
+			return nil, scmError(err, input.Repo, "create a draft pull request", nil)
+		}
+		u.log.Info("created draft PullRequest", "number", pr.Number)
+		return pr, nil
+	}
+	pr, err := u.gitClient.CreatePullRequest(ctx, input.Repo, inp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create a pull request: %w", err)
+		return nil, scmError(err, input.Repo, "create a pull request", nil)
 	}
 	u.log.Info("created PullRequest", "number", pr.Number)
 	return pr, nil
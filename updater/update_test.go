@@ -5,7 +5,10 @@ import (
 	"errors"
 	"testing"
 
+	"github.com/agill17/pkg/buildinfo"
 	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/syaml"
+	"github.com/agill17/pkg/test"
 	"github.com/jenkins-x/go-scm/scm"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -57,7 +60,7 @@ func TestApplyUpdateToFileMissingFile(t *testing.T) {
 		return []byte("testing"), nil
 	})
 
-	if err != testErr {
+	if !errors.Is(err, testErr) {
 		t.Fatalf("got %s, want %s", err, testErr)
 	}
 	updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")
@@ -92,6 +95,27 @@ func TestApplyUpdateToFileWithBranchCreationFailure(t *testing.T) {
 	m.AssertNoPullRequestsCreated()
 }
 
+func TestApplyUpdateToFileWithNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+
+	branch, err := updater.ApplyUpdateToFile(context.Background(), makeCommitInput(), UpdateYAML("test.image", "old-image"))
+
+	if !errors.Is(err, ErrNoChange) {
+		t.Fatalf("got %v, want %v", err, ErrNoChange)
+	}
+	if !test.MatchError(t, "no change required: environments/test/services/service-a/test.yaml in repo testorg/testrepo is already up to date", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if branch != "" {
+		t.Fatalf("got branch %q, want no branch", branch)
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
 func TestCreatePullRequest(t *testing.T) {
 	m := mock.New(t)
 	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
@@ -113,6 +137,24 @@ func TestCreatePullRequest(t *testing.T) {
 	}
 }
 
+func TestCreatePullRequestWithBuildInfoTrailer(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}), BuildInfoTrailer())
+	input := makePullRequestInput()
+
+	_, err := updater.CreatePR(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.Title,
+		Body:  input.Body + "\n\nUpdated-By: agill17-pkg/" + buildinfo.Get().String(),
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
 func TestCreatePullRequestHandlingErrors(t *testing.T) {
 	m := mock.New(t)
 	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
@@ -127,6 +169,896 @@ func TestCreatePullRequestHandlingErrors(t *testing.T) {
 	}
 }
 
+func TestCreatePullRequestWithLabels(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makePullRequestInput()
+	input.Labels = []string{"automated", "env/prod"}
+
+	pr, err := updater.CreatePR(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertLabelsAdded(testGitHubRepo, pr.Number, "automated", "env/prod")
+}
+
+func TestCreatePullRequestWithLabelsFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddLabelsErr = errors.New("labels are not available")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makePullRequestInput()
+	input.Labels = []string{"automated"}
+
+	pr, err := updater.CreatePR(context.Background(), input)
+
+	if !test.MatchError(t, "failed to add labels to pull request 1: labels are not available", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if pr == nil || pr.Number != 1 {
+		t.Fatalf("got PullRequest %#v, want the created PullRequest", pr)
+	}
+}
+
+func TestUpdateWithLabels(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Labels = []string{"automated", "image-update"}
+
+	r, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.PostActionErrors != nil {
+		t.Fatalf("got post-action errors %v", r.PostActionErrors)
+	}
+	m.AssertLabelsAdded(testGitHubRepo, 1, "automated", "image-update")
+}
+
+func TestUpdateWithLabelsFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddLabelsErr = errors.New("labels are not available")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Labels = []string{"automated"}
+
+	r, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.State != PullRequestCreated || len(r.PostActionErrors) != 1 {
+		t.Fatalf("got state %s and errors %v, want the PullRequest created with a post-action error", r.State, r.PostActionErrors)
+	}
+	if !test.MatchError(t, "failed to apply post-action add-labels: labels are not available", r.PostActionErrors[0]) {
+		t.Fatalf("failed to match error: %s", r.PostActionErrors[0])
+	}
+}
+
+func TestCreatePullRequestWithReviewersAndAssignees(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makePullRequestInput()
+	input.Reviewers = []string{"octocat"}
+	input.TeamReviewers = []string{"my-org/frontend"}
+	input.Assignees = []string{"hubot"}
+
+	pr, err := updater.CreatePR(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertReviewersRequested(testGitHubRepo, pr.Number, "octocat")
+	m.AssertTeamReviewersRequested(testGitHubRepo, pr.Number, "my-org/frontend")
+	m.AssertAssigneesAdded(testGitHubRepo, pr.Number, "hubot")
+}
+
+func TestCreatePullRequestWithTeamReviewersFailure(t *testing.T) {
+	m := mock.New(t)
+	m.RequestTeamReviewersErr = errors.New("team reviewers are not supported")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makePullRequestInput()
+	input.TeamReviewers = []string{"my-org/frontend"}
+	input.Assignees = []string{"hubot"}
+
+	pr, err := updater.CreatePR(context.Background(), input)
+
+	if !test.MatchError(t, "failed to request team reviewers for pull request 1: team reviewers are not supported", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if pr == nil || pr.Number != 1 {
+		t.Fatalf("got PullRequest %#v, want the created PullRequest", pr)
+	}
+	m.AssertAssigneesAdded(testGitHubRepo, 1)
+}
+
+func TestUpdateWithReviewersAndAssignees(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddAssigneesErr = errors.New("hubot can't be assigned")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Reviewers = []string{"octocat"}
+	input.PullRequest.TeamReviewers = []string{"my-org/frontend"}
+	input.PullRequest.Assignees = []string{"hubot"}
+
+	r, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertReviewersRequested(testGitHubRepo, 1, "octocat")
+	m.AssertTeamReviewersRequested(testGitHubRepo, 1, "my-org/frontend")
+	if l := len(r.PostActionErrors); l != 1 {
+		t.Fatalf("got %d post-action errors, want 1", l)
+	}
+	if !test.MatchError(t, "failed to apply post-action add-assignees: hubot can't be assigned", r.PostActionErrors[0]) {
+		t.Fatalf("failed to match error: %s", r.PostActionErrors[0])
+	}
+}
+
+func TestUpdateWithDraft(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Draft = true
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated || !r.PullRequest.Draft {
+		t.Fatalf("got state %s and PullRequest %#v, want a draft PullRequest", r.State, r.PullRequest)
+	}
+	m.AssertDraftPullRequest(testGitHubRepo, 1)
+}
+
+func TestUpdateWithDraftUnsupported(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.Caps.DraftPullRequests = false
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.Draft = true
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !errors.Is(err, ErrDraftsUnsupported) {
+		t.Fatalf("got %v, want ErrDraftsUnsupported", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateYAML(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+
+	pr, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")
+	if s := string(updated); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v, want %#v", s, "test:\n  image: new-image\n")
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if pr.Link != "https://example.com/pull-request/1" {
+		t.Fatalf("link to PR is incorrect: got %#v, want %#v", pr.Link, "https://example.com/pull-request/1")
+	}
+}
+
+func TestUpdateYAMLWithDiff(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.IncludeDiff = true
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  "This is the body\n\n```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,2 +1,2 @@\n test:\n-  image: old-image\n+  image: new-image\n```",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateYAMLWithSemanticDiff(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.IncludeDiff = true
+	input.PullRequest.DiffStyle = SemanticDiff
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  "This is the body\n\n```\n~ test.image: \"old-image\" -> \"new-image\"\n```",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithBaseBranch(t *testing.T) {
+	releaseSHA := "3b1f0a4d8c9e7f6a5b4c3d2e1f0a9b8c7d6e5f4a"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddFileContents(testGitHubRepo, testFilePath, "release-1.2", []byte("test:\n  image: release-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, "release-1.2", releaseSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BaseBranch = "release-1.2"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.Base != releaseSHA {
+		t.Fatalf("got base %q, want %q", r.Base, releaseSHA)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", releaseSHA)
+	if b := m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a"); string(b) != "test:\n  image: new-image\n" {
+		t.Fatalf("got updated contents %q", b)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body,
+		Head:  "test-branch-a",
+		Base:  "release-1.2",
+	})
+}
+
+func TestUpdateWithBaseBranchCommitsDirectly(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddMissingFile(testGitHubRepo, testFilePath, "release-1.2")
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	m.AddBranchHead(testGitHubRepo, "release-1.2", "3b1f0a4d8c9e7f6a5b4c3d2e1f0a9b8c7d6e5f4a")
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.BaseBranch, input.BranchGenerateName = "release-1.2", ""
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != Committed || r.Branch != "release-1.2" {
+		t.Fatalf("got %s to branch %q, want a commit to release-1.2", r.State, r.Branch)
+	}
+	if b := m.GetUpdatedContents(testGitHubRepo, testFilePath, "release-1.2"); string(b) != "test:\n  image: new-image\n" {
+		t.Fatalf("got updated contents %q", b)
+	}
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateYAMLWithTruncatedDiff(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.PullRequest.IncludeDiff = true
+	input.PullRequest.MaxDiffLines = 4
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  "This is the body\n\n```diff\n--- a/" + testFilePath + "\n+++ b/" + testFilePath + "\n@@ -1,2 +1,2 @@\n test:\n```\n\nThe diff of `" + testFilePath + "` is truncated, 2 more lines are not shown.",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateYAMLWithFailingValidation(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	v, err := syaml.NewSchemaValidator([]byte(`{"properties": {"test": {"properties": {"image": {"type": "integer"}}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := makeInput()
+	input.Validator = v
+
+	_, err = updater.UpdateYAML(context.Background(), input)
+
+	if !test.MatchError(t, "failed to update key test.image.*document failed validation", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateYAMLWithStrictPaths(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Key = "tset.image"
+	input.StrictPaths = true
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if !errors.Is(err, syaml.ErrMissingParent) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateYAMLWithDelete(t *testing.T) {
+	deleteTests := []struct {
+		name     string
+		delete   bool
+		newValue interface{}
+		want     string
+	}{
+		{"delete the key", true, nil, "test:\n  tag: v1\n"},
+		{"set the key to null", false, nil, "test:\n  image: null\n  tag: v1\n"},
+	}
+
+	for _, tt := range deleteTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.Delete, input.NewValue = tt.delete, tt.newValue
+
+			_, err := updater.UpdateYAML(context.Background(), input)
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")); s != tt.want {
+				rt.Fatalf("update failed, got %#v, want %#v", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateYAMLWithDeleteOfMissingKey(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  tag: v1\n"))
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.Delete = true
+	input.NoChange = NoChangeComment
+	input.TrackingIssue = 12
+
+	r, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.State != Unchanged {
+		t.Fatalf("got state %s, want unchanged", r.State)
+	}
+	m.AssertCommentCreated(testGitHubRepo, 12, "No change required, test.image is not in "+testFilePath+".")
+}
+
+func TestUpdateYAMLWithExpectedValue(t *testing.T) {
+	expectedTests := []struct {
+		name     string
+		expected string
+		pattern  string
+		skip     bool
+		wantErr  string
+		wantPR   bool
+	}{
+		{"matching value", "old-image", "", false, "", true},
+		{"matching pattern", "", "^old-", false, "", true},
+		{"different value", "other-image", "", false, `failed to update key test.image .*: unexpected current value: test.image is "old-image", expected "other-image"`, false},
+		{"different value with skip", "other-image", "", true, "", false},
+	}
+
+	for _, tt := range expectedTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			if tt.expected != "" {
+				input.ExpectedValue = &tt.expected
+			}
+			input.ExpectedPattern = tt.pattern
+			input.SkipOnMismatch = tt.skip
+
+			pr, err := updater.UpdateYAML(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			if (pr != nil) != tt.wantPR {
+				rt.Fatalf("got PullRequest %v, want a PullRequest %v", pr, tt.wantPR)
+			}
+			if !tt.wantPR {
+				m.AssertNoInteractions()
+			}
+		})
+	}
+}
+
+func TestUpdateYAMLWithValues(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  checksum: abc\nversion: v1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	v, err := syaml.NewSchemaValidator([]byte(`{"properties": {"version": {"const": "v2"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := "old-image"
+	input := makeInput()
+	input.ExpectedValue = &old
+	input.Validator = v
+	input.Values = []KeyValue{
+		{Key: "test.checksum", NewValue: "def"},
+		{Key: "version", NewValue: "v2"},
+	}
+
+	_, err = updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "test:\n  image: new-image\n  checksum: def\nversion: v2\n"
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")); s != want {
+		t.Fatalf("update failed, got %#v, want %#v", s, want)
+	}
+	m.AssertCommitMessage(testGitHubRepo, testFilePath, "test-branch-a", input.CommitMessage)
+}
+
+func TestUpdateYAMLWithValuesFailure(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.StrictPaths = true
+	input.Values = []KeyValue{{Key: "tset.checksum", NewValue: "def"}}
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if !test.MatchError(t, "failed to update key tset.checksum", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateWithFormat(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "package.json", testBranch, []byte("{\n  \"image\": \"old-image\",\n  \"checksum\": \"abc\",\n  \"legacy\": true\n}\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	old := "old-image"
+	input := makeInput()
+	input.Filename, input.Format, input.Key = "package.json", "json", "image"
+	input.ExpectedValue = &old
+	input.Values = []KeyValue{{Key: "checksum", NewValue: "def"}}
+
+	r, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Previous == nil || *r.Previous != old {
+		t.Fatalf("got previous value %v, want %q", r.Previous, old)
+	}
+	want := "{\n  \"image\": \"new-image\",\n  \"checksum\": \"def\",\n  \"legacy\": true\n}\n"
+	if s := string(m.GetUpdatedContents(testGitHubRepo, "package.json", "test-branch-a")); s != want {
+		t.Fatalf("update failed, got %#v, want %#v", s, want)
+	}
+}
+
+func TestUpdateWithFormatDelete(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "config.ini", testBranch, []byte("[server]\nhost = localhost\ndebug = true\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Filename, input.Format, input.Key, input.Delete = "config.ini", "ini", "server.debug", true
+
+	_, err := updater.Update(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, "config.ini", "test-branch-a")); s != "[server]\nhost = localhost\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestUpdateWithFormatFailures(t *testing.T) {
+	other := "other-image"
+	failureTests := []struct {
+		name    string
+		modify  func(*Input)
+		wantErr string
+	}{
+		{"unknown format", func(i *Input) { i.Format = "cue" }, `failed to update key image in file package.json: unknown format "cue"`},
+		{"different value", func(i *Input) { i.ExpectedValue = &other }, `unexpected current value: image is "old-image", expected "other-image"`},
+		{"missing value", func(i *Input) { i.Key, i.ExpectedPattern = "tag", "^v" }, "unexpected current value: tag has no value"},
+		{"different pattern", func(i *Input) { i.ExpectedPattern = "^new-" }, `unexpected current value: image is "old-image", expected a match for "\^new-"`},
+		{"yaml options", func(i *Input) { i.StrictPaths = true }, "the Validator, StrictPaths and EnsurePath are only supported for YAML files, not json"},
+	}
+
+	for _, tt := range failureTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, "package.json", testBranch, []byte(`{"image": "old-image"}`))
+			updater := New(zap.New(), m)
+			input := makeInput()
+			input.Filename, input.Format, input.Key = "package.json", "json", "image"
+			tt.modify(input)
+
+			_, err := updater.Update(context.Background(), input)
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestApplyUpdate(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "scripts/deploy.sh", testBranch, []byte("VERSION=v1.0.0\n./deploy.sh\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Filename, input.Key, input.NewValue = "scripts/deploy.sh", "", nil
+
+	r, err := updater.ApplyUpdate(context.Background(), input, RegexReplace(`VERSION=v[\d.]+`, "VERSION=v1.1.0", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	if s := string(m.GetUpdatedContents(testGitHubRepo, "scripts/deploy.sh", "test-branch-a")); s != "VERSION=v1.1.0\n./deploy.sh\n" {
+		t.Fatalf("update failed, got %#v, want %#v", s, "VERSION=v1.1.0\n./deploy.sh\n")
+	}
+	m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body,
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	if input.ContentUpdater != nil {
+		t.Fatal("the input was modified")
+	}
+}
+
+func TestApplyUpdateWithValues(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.Key, input.NewValue = "", nil
+	input.Values = []KeyValue{{Key: "test.tag", NewValue: "v2"}}
+
+	_, err := updater.ApplyUpdate(context.Background(), input, RegexReplace("old-image", "new-image", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "test-branch-a")); s != "test:\n  image: new-image\n  tag: v2\n" {
+		t.Fatalf("update failed, got %#v", s)
+	}
+}
+
+func TestApplyUpdateWithFailingValidation(t *testing.T) {
+	validationTests := []struct {
+		name   string
+		values []KeyValue
+	}{
+		{"content", nil},
+		{"values", []KeyValue{{Key: "test.tag", NewValue: "v2"}}},
+	}
+
+	for _, tt := range validationTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n  tag: v1\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			v, err := syaml.NewSchemaValidator([]byte(`{"properties": {"test": {"properties": {"image": {"const": "old-image"}}}}}`))
+			if err != nil {
+				rt.Fatal(err)
+			}
+			input := makeInput()
+			input.Key, input.NewValue, input.Values, input.Validator = "", nil, tt.values, v
+
+			_, err = updater.ApplyUpdate(context.Background(), input, RegexReplace("old-image", "new-image", 0))
+
+			if !test.MatchError(rt, "failed to update file "+testFilePath+".*document failed validation", err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			m.AssertNoInteractions()
+		})
+	}
+}
+
+func TestApplyUpdateWithValuesInOtherFormats(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, "package.json", testBranch, []byte(`{"image": "old-image"}`))
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.Filename, input.Format, input.Key, input.NewValue = "package.json", "json", "", nil
+	input.Values = []KeyValue{{Key: "tag", NewValue: "v2"}}
+
+	_, err := updater.ApplyUpdate(context.Background(), input, RegexReplace("old-image", "new-image", 0))
+
+	if !test.MatchError(t, "the Values and Validator of a ContentUpdater are only supported for YAML files, not json", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestApplyUpdateWithNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.NoChange = NoChangeComment
+	input.TrackingIssue = 12
+
+	r, err := updater.ApplyUpdate(context.Background(), input, ReplaceContents([]byte("test:\n  image: new-image\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != Unchanged {
+		t.Fatalf("got state %s, want %s", r.State, Unchanged)
+	}
+	m.AssertCommentCreated(testGitHubRepo, 12, "No change required, "+testFilePath+" is already up to date.")
+}
+
+func TestApplyUpdateWithFailingUpdate(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	updater := New(zap.New(), m)
+
+	_, err := updater.ApplyUpdate(context.Background(), makeInput(), RegexReplace(`[`, "", 0))
+
+	if !test.MatchError(t, "failed to update file "+testFilePath+`: failed to compile pattern "\["`, err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateWithNewBranchName(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.BranchGenerateName = ""
+	input.NewBranchName = "update-service-a-image"
+
+	r, err := updater.Update(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	m.AssertBranchCreated(testGitHubRepo, "update-service-a-image", testSHA)
+	m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+		Title: input.PullRequest.Title,
+		Body:  input.PullRequest.Body,
+		Head:  "update-service-a-image",
+		Base:  testBranch,
+	})
+}
+
+func TestUpdateWithResetBranch(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	resetTests := []struct {
+		name      string
+		openPR    bool
+		wantState UpdateState
+		wantPR    int
+	}{
+		{"open PullRequest", true, PullRequestUpdated, 7},
+		{"no open PullRequest", false, PullRequestCreated, 1},
+	}
+
+	for _, tt := range resetTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+			if tt.openPR {
+				m.AddOpenPullRequest(testGitHubRepo, &scm.PullRequest{Number: 7, Source: "update-service-a-image"})
+			}
+			updater := New(zap.New(), m)
+			input := makeInput()
+			input.NewBranchName = "update-service-a-image"
+			input.ResetBranch = true
+
+			r, err := updater.Update(context.Background(), input)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			if r.State != tt.wantState {
+				rt.Fatalf("got state %s, want %s", r.State, tt.wantState)
+			}
+			if r.PullRequest.Number != tt.wantPR {
+				rt.Fatalf("got PullRequest %d, want %d", r.PullRequest.Number, tt.wantPR)
+			}
+			m.AssertBranchReset(testGitHubRepo, "update-service-a-image", testSHA)
+			if got := string(m.GetUpdatedContents(testGitHubRepo, testFilePath, "update-service-a-image")); got != "test:\n  image: new-image\n" {
+				rt.Fatalf("got %#v", got)
+			}
+			if tt.openPR {
+				m.AssertNoPullRequestsCreated()
+			}
+		})
+	}
+}
+
+func TestUpdateWithResetBranchAndNoNewBranchName(t *testing.T) {
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, "980a0d5f19a64b4b30a87d4206aade58726b60e3")
+	updater := New(zap.New(), m)
+	input := makeInput()
+	input.ResetBranch = true
+
+	_, err := updater.Update(context.Background(), input)
+
+	if !test.MatchError(t, "a NewBranchName is required to reset the branch", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	m.AssertNoBranchesCreated()
+}
+
+func TestUpdateYAMLWithNoBranchGenerateName(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	m := mock.New(t)
+	m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BranchGenerateName = ""
+
+	pr, err := updater.UpdateYAML(context.Background(), input)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pr != nil {
+		t.Fatalf("got a PullRequest %#v, want nil", pr)
+	}
+	updated := m.GetUpdatedContents(testGitHubRepo, testFilePath, testBranch)
+	if s := string(updated); s != "test:\n  image: new-image\n" {
+		t.Fatalf("update failed, got %#v, want %#v", s, "test:\n  image: new-image\n")
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateYAMLWithNoChange(t *testing.T) {
+	testSHA := "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+	noChangeTests := []struct {
+		name   string
+		policy NoChangePolicy
+		assert func(t *testing.T, m *mock.MockClient, pr *scm.PullRequest)
+	}{
+		{"skip", NoChangeSkip, func(t *testing.T, m *mock.MockClient, pr *scm.PullRequest) {
+			if pr != nil {
+				t.Fatalf("got a PullRequest %#v, want nil", pr)
+			}
+			m.AssertNoInteractions()
+		}},
+		{"pull request", NoChangePullRequest, func(t *testing.T, m *mock.MockClient, pr *scm.PullRequest) {
+			m.AssertBranchCreated(testGitHubRepo, "test-branch-a", testSHA)
+			m.AssertPullRequestCreated(testGitHubRepo, &scm.PullRequestInput{
+				Title: "This is a test PR",
+				Body:  "This is the body\n\nNo change required, test.image in " + testFilePath + " already has the value new-image.",
+				Head:  "test-branch-a",
+				Base:  testBranch,
+			})
+		}},
+		{"comment", NoChangeComment, func(t *testing.T, m *mock.MockClient, pr *scm.PullRequest) {
+			if pr != nil {
+				t.Fatalf("got a PullRequest %#v, want nil", pr)
+			}
+			m.AssertNoBranchesCreated()
+			m.AssertNoPullRequestsCreated()
+			m.AssertCommentCreated(testGitHubRepo, 12, "No change required, test.image in "+testFilePath+" already has the value new-image.")
+		}},
+	}
+
+	for _, tt := range noChangeTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddFileContents(testGitHubRepo, testFilePath, testBranch, []byte("test:\n  image: new-image\n"))
+			m.AddBranchHead(testGitHubRepo, testBranch, testSHA)
+			updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+			input := makeInput()
+			input.NoChange = tt.policy
+			input.TrackingIssue = 12
+
+			pr, err := updater.UpdateYAML(context.Background(), input)
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			tt.assert(rt, m, pr)
+		})
+	}
+}
+
+func TestUpdateYAMLWithNoChangePullRequestAndNoBranchGenerateName(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m, NameGenerator(stubNameGenerator{"a"}))
+	input := makeInput()
+	input.BranchGenerateName = ""
+	input.NoChange = NoChangePullRequest
+
+	_, err := updater.UpdateYAML(context.Background(), input)
+
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	m.AssertNoInteractions()
+}
+
 type stubNameGenerator struct {
 	name string
 }
@@ -154,3 +1086,19 @@ func makePullRequestInput() PullRequestInput {
 		Body:         "This is the body",
 	}
 }
+
+func makeInput() *Input {
+	return &Input{
+		Repo:               testGitHubRepo,
+		Filename:           testFilePath,
+		Branch:             testBranch,
+		Key:                "test.image",
+		NewValue:           "new-image",
+		BranchGenerateName: "test-branch-",
+		CommitMessage:      "just a test commit",
+		PullRequest: PullRequestInput{
+			Title: "This is a test PR",
+			Body:  "This is the body",
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package updater
+
+import (
+	"fmt"
+
+	v1 "github.com/agill17/pkg/updater"
+)
+
+// ContentUpdater takes an existing body, it should transform it, and return the
+// updated body.
+type ContentUpdater = v1.ContentUpdater
+
+// Change is a change to one or more files in a repo branch.
+type Change struct {
+	Repo   string // e.g. my-org/my-repo
+	Branch string // e.g. main
+	Files  []FileChange
+}
+
+// FileChange is a change to a file, the Filename can be a glob matching
+// several files e.g. environments/*/services/service-a/deployment.yaml.
+type FileChange struct {
+	Filename string      // relative path to the file in the repository
+	Key      string      // e.g. spec.template.spec.containers.0.image
+	NewValue interface{} // e.g. my-org/my-image:v2
+	Format   string      // The format of the file in the formats registry e.g. json, this defaults to YAML
+	Delete   bool        // Remove the Key, rather than setting it to the NewValue
+	// ContentUpdater transforms the file, rather than setting the Key.
+	ContentUpdater ContentUpdater `json:"-"`
+}
+
+// Input returns the version 1 Input for the Change, with the options applied,
+// the first file is the Input's Filename, and the others are its Files.
+func (c Change) Input(opts ...UpdateOption) (*v1.Input, error) {
+	if len(c.Files) == 0 {
+		return nil, fmt.Errorf("%w: in repo %s", ErrNoFiles, c.Repo)
+	}
+	input := &v1.Input{Repo: c.Repo, Branch: c.Branch}
+	for n, f := range c.Files {
+		change := f.fileChange()
+		if n == 0 {
+			input.Filename, input.Key, input.NewValue, input.ContentUpdater = change.Filename, change.Key, change.NewValue, change.ContentUpdater
+			input.Format = change.Format
+			continue
+		}
+		if change.Format == "" && input.Format != "" {
+			// The Files of a version 1 Input default to its Format.
+			change.Format = "yaml"
+		}
+		input.Files = append(input.Files, change)
+	}
+	for _, o := range opts {
+		o(input)
+	}
+	return input, nil
+}
+
+// fileChange returns the version 1 FileChange, a Delete is applied with a
+// ContentUpdater, as the Input's Delete applies to all its files.
+func (f FileChange) fileChange() v1.FileChange {
+	change := v1.FileChange{Filename: f.Filename, Key: f.Key, NewValue: f.NewValue, Format: f.Format, ContentUpdater: f.ContentUpdater}
+	if f.Delete && f.ContentUpdater == nil {
+		change.ContentUpdater = v1.DeleteYAML(f.Key)
+		if f.Format != "" {
+			change.ContentUpdater = v1.DeleteFormat(f.Format, f.Key)
+		}
+	}
+	return change
+}
+
+// FromInput returns the Change for a version 1 Input, and an option with the
+// rest of its configuration, so that Update applies the same update as the
+// version 1 Update.
+func FromInput(input *v1.Input) (Change, UpdateOption) {
+	c := Change{Repo: input.Repo, Branch: input.Branch}
+	if input.Filename != "" {
+		c.Files = append(c.Files, FileChange{Filename: input.Filename, Key: input.Key, NewValue: input.NewValue, Format: input.Format, ContentUpdater: input.ContentUpdater})
+	}
+	for _, f := range input.Files {
+		format := f.Format
+		if format == "" {
+			format = input.Format
+		}
+		c.Files = append(c.Files, FileChange{Filename: f.Filename, Key: f.Key, NewValue: f.NewValue, Format: format, ContentUpdater: f.ContentUpdater})
+	}
+	return c, func(i *v1.Input) {
+		configured := *input
+		configured.Repo, configured.Branch = i.Repo, i.Branch
+		configured.Filename, configured.Key, configured.NewValue, configured.ContentUpdater = i.Filename, i.Key, i.NewValue, i.ContentUpdater
+		configured.Format = i.Format
+		configured.Files = i.Files
+		*i = configured
+	}
+}
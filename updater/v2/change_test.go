@@ -0,0 +1,156 @@
+package updater
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	v1 "github.com/agill17/pkg/updater"
+)
+
+func TestChangeInput(t *testing.T) {
+	c := Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files: []FileChange{
+			{Filename: testFile, Key: "test.image", NewValue: "new-image"},
+			{Filename: "config/other.yaml", Key: "test.image", NewValue: "new-image"},
+		},
+	}
+
+	input, err := c.Input(NewBranch("test-branch-"), CommitMessage("updating the image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &v1.Input{
+		Repo:               testRepo,
+		Branch:             testBranch,
+		Filename:           testFile,
+		Key:                "test.image",
+		NewValue:           "new-image",
+		BranchGenerateName: "test-branch-",
+		CommitMessage:      "updating the image",
+		Files:              []v1.FileChange{{Filename: "config/other.yaml", Key: "test.image", NewValue: "new-image"}},
+	}
+	if diff := cmp.Diff(want, input); diff != "" {
+		t.Fatalf("failed to convert the change:\n%s", diff)
+	}
+}
+
+func TestChangeInputWithDelete(t *testing.T) {
+	c := Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files:  []FileChange{{Filename: testFile, Key: "test.debug", Delete: true}},
+	}
+
+	input, err := c.Input()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if input.Delete {
+		t.Error("got Delete, want a ContentUpdater")
+	}
+	b, err := input.ContentUpdater([]byte("test:\n  image: old-image\n  debug: true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("test:\n  image: old-image\n", string(b)); diff != "" {
+		t.Fatalf("failed to delete the key:\n%s", diff)
+	}
+}
+
+func TestChangeInputWithFormats(t *testing.T) {
+	c := Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files: []FileChange{
+			{Filename: "package.json", Key: "version", NewValue: "1.1.0", Format: "json"},
+			{Filename: testFile, Key: "test.version", NewValue: "1.1.0"},
+			{Filename: "config.ini", Key: "app.debug", Format: "ini", Delete: true},
+		},
+	}
+
+	input, err := c.Input()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if input.Format != "json" {
+		t.Errorf("got Format %q, want json", input.Format)
+	}
+	// The YAML file doesn't inherit the Format of the first file.
+	if f := input.Files[0].Format; f != "yaml" {
+		t.Errorf("got Format %q, want yaml", f)
+	}
+	b, err := input.Files[1].ContentUpdater([]byte("[app]\nname = test\ndebug = true\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("[app]\nname = test\n", string(b)); diff != "" {
+		t.Fatalf("failed to delete the key:\n%s", diff)
+	}
+}
+
+func TestChangeInputWithNoFiles(t *testing.T) {
+	_, err := Change{Repo: testRepo, Branch: testBranch}.Input()
+
+	if !errors.Is(err, ErrNoFiles) {
+		t.Fatalf("got %v, want ErrNoFiles", err)
+	}
+}
+
+func TestFromInput(t *testing.T) {
+	expected := "old-image"
+	inputTests := []struct {
+		name  string
+		input *v1.Input
+	}{
+		{"single file", &v1.Input{
+			Repo: testRepo, Branch: testBranch, Filename: testFile, Key: "test.image", NewValue: "new-image",
+			BranchGenerateName: "test-branch-", CommitMessage: "updating the image", ExpectedValue: &expected,
+			PullRequest: v1.PullRequestInput{Title: "Update the image", Body: "Updating the image."},
+		}},
+		{"delete", &v1.Input{Repo: testRepo, Branch: testBranch, Filename: testFile, Key: "test.debug", Delete: true}},
+		{"multiple files", &v1.Input{
+			Repo: testRepo, Branch: testBranch, Filename: testFile, Key: "test.image", NewValue: "new-image",
+			Values: []v1.KeyValue{{Key: "test.tag", NewValue: "v2"}},
+			Files:  []v1.FileChange{{Filename: "config/other.yaml", Key: "test.image", NewValue: "new-image"}},
+		}},
+		{"formats", &v1.Input{
+			Repo: testRepo, Branch: testBranch, Filename: "package.json", Key: "version", NewValue: "1.1.0", Format: "json",
+			Files: []v1.FileChange{{Filename: "main.tf", Key: "module.app.version", NewValue: "1.1.0", Format: "hcl"}},
+		}},
+		{"only files", &v1.Input{
+			Repo: testRepo, Branch: testBranch, SecretKeys: []string{"password$"},
+			Files: []v1.FileChange{
+				{Filename: testFile, Key: "test.image", NewValue: "new-image"},
+				{Filename: "config/other.yaml", Key: "test.image", NewValue: "new-image"},
+			},
+		}},
+	}
+
+	for _, tt := range inputTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			c, opt := FromInput(tt.input)
+
+			input, err := c.Input(opt)
+			if err != nil {
+				rt.Fatal(err)
+			}
+
+			want := *tt.input
+			if want.Filename == "" {
+				want.Filename, want.Key, want.NewValue = want.Files[0].Filename, want.Files[0].Key, want.Files[0].NewValue
+				want.Files = want.Files[1:]
+			}
+			if diff := cmp.Diff(&want, input, cmpopts.EquateEmpty()); diff != "" {
+				rt.Fatalf("failed to convert the input:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package updater
+
+import (
+	"github.com/agill17/pkg/syaml"
+	v1 "github.com/agill17/pkg/updater"
+)
+
+// UpdateOption configures a single update, options are applied in order, so
+// later options override earlier ones.
+type UpdateOption func(*v1.Input)
+
+// DiffStyle configures how diffs are rendered in PullRequest bodies.
+type DiffStyle = v1.DiffStyle
+
+const (
+	// TextDiff renders a unified diff of the lines in the file.
+	TextDiff = v1.TextDiff
+	// SemanticDiff renders the keys that were added, changed or removed.
+	SemanticDiff = v1.SemanticDiff
+)
+
+// NoChangePolicy configures what's done when the files already have the
+// values.
+type NoChangePolicy = v1.NoChangePolicy
+
+const (
+	// NoChangeSkip does nothing.
+	NoChangeSkip = v1.NoChangeSkip
+	// NoChangePullRequest opens a PullRequest anyway, with an explanatory
+	// note.
+	NoChangePullRequest = v1.NoChangePullRequest
+	// NoChangeComment posts an explanatory comment on a tracking issue.
+	NoChangeComment = v1.NoChangeComment
+)
+
+// SupersedePolicy configures what's done with the open PullRequests from
+// previous updates, when a PullRequest is opened.
+type SupersedePolicy = v1.SupersedePolicy
+
+const (
+	// SupersedeNone leaves the previous PullRequests open.
+	SupersedeNone = v1.SupersedeNone
+	// SupersedeClose closes the previous PullRequests, and deletes their
+	// branches.
+	SupersedeClose = v1.SupersedeClose
+	// SupersedeCloseWithComment comments on the previous PullRequests with a
+	// link to the new PullRequest before closing them.
+	SupersedeCloseWithComment = v1.SupersedeCloseWithComment
+)
+
+// NewBranch commits the change to a new branch, with a name generated from
+// the prefix e.g. update-image-.
+func NewBranch(prefix string) UpdateOption {
+	return func(i *v1.Input) {
+		i.BranchGenerateName = prefix
+	}
+}
+
+// BranchName commits the change to the named branch, rather than generating a
+// name, with reset, an existing branch is reset to the Change's Branch.
+func BranchName(name string, reset bool) UpdateOption {
+	return func(i *v1.Input) {
+		i.NewBranchName, i.ResetBranch = name, reset
+	}
+}
+
+// BaseBranch commits the change to, or opens the PullRequest against, the
+// branch, rather than the Change's Branch that the file is read from.
+func BaseBranch(branch string) UpdateOption {
+	return func(i *v1.Input) {
+		i.BaseBranch = branch
+	}
+}
+
+// Fork pushes the new branch to the fork of the Change's Repo, e.g.
+// my-bot/my-repo, and opens a cross-repo PullRequest.
+func Fork(repo string) UpdateOption {
+	return func(i *v1.Input) {
+		i.Fork = repo
+	}
+}
+
+// CommitMessage sets the message template for the commits.
+func CommitMessage(message string) UpdateOption {
+	return func(i *v1.Input) {
+		i.CommitMessage = message
+	}
+}
+
+// PullRequest sets the title and body templates of the PullRequest opened for
+// a new branch.
+func PullRequest(title, body string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Title, i.PullRequest.Body = title, body
+	}
+}
+
+// Labels are added to the PullRequest once it's created, failures are
+// recorded in the Result.
+func Labels(labels ...string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Labels = append(i.PullRequest.Labels, labels...)
+	}
+}
+
+// Reviewers are requested to review the PullRequest once it's created,
+// failures are recorded in the Result.
+func Reviewers(logins ...string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Reviewers = append(i.PullRequest.Reviewers, logins...)
+	}
+}
+
+// TeamReviewers are requested to review the PullRequest once it's created,
+// e.g. my-org/frontend, failures are recorded in the Result.
+func TeamReviewers(teams ...string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.TeamReviewers = append(i.PullRequest.TeamReviewers, teams...)
+	}
+}
+
+// Assignees are assigned to the PullRequest once it's created, failures are
+// recorded in the Result.
+func Assignees(logins ...string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Assignees = append(i.PullRequest.Assignees, logins...)
+	}
+}
+
+// Draft opens the PullRequest as a draft, which must be marked ready for
+// review, it fails if the git provider doesn't support drafts.
+func Draft() UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Draft = true
+	}
+}
+
+// CodeOwners requests reviews of the PullRequest from the owners of the
+// changed files in the repo's CODEOWNERS file.
+func CodeOwners() UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.CodeOwners = true
+	}
+}
+
+// AutoMerge merges the PullRequest with the method e.g. "squash" once its
+// checks pass, or immediately if they already have.
+func AutoMerge(method string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.AutoMerge = method
+	}
+}
+
+// Milestone adds the PullRequest to the open milestone with the title, e.g.
+// "v1.2.0".
+func Milestone(title string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Milestone = title
+	}
+}
+
+// Project adds the PullRequest to the GitHub project with the node ID, and if
+// the column isn't empty, sets its Status, e.g. "In review".
+func Project(project, column string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Project, i.PullRequest.ProjectColumn = project, column
+	}
+}
+
+// Comment posts the comment on the PullRequest once it's created, e.g. with
+// instructions for reviewers, a template with the MessageValues.
+func Comment(comment string) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.Comment = comment
+	}
+}
+
+// IncludeDiff appends a diff of the change to the PullRequest body.
+func IncludeDiff(style DiffStyle) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.IncludeDiff, i.PullRequest.DiffStyle = true, style
+	}
+}
+
+// MaxDiffLines truncates the diff of each file included in the PullRequest
+// body to the number of lines.
+func MaxDiffLines(n int) UpdateOption {
+	return func(i *v1.Input) {
+		i.PullRequest.MaxDiffLines = n
+	}
+}
+
+// ReusePullRequest commits to the branch of an open PullRequest from a
+// previous update, and updates its title and body.
+func ReusePullRequest() UpdateOption {
+	return func(i *v1.Input) {
+		i.ReusePullRequest = true
+	}
+}
+
+// Supersede configures what's done with the open PullRequests from previous
+// updates.
+func Supersede(p SupersedePolicy) UpdateOption {
+	return func(i *v1.Input) {
+		i.Supersede = p
+	}
+}
+
+// OnNoChange configures what's done when the files already have the values,
+// the tracking issue is used by NoChangeComment.
+func OnNoChange(p NoChangePolicy, trackingIssue int) UpdateOption {
+	return func(i *v1.Input) {
+		i.NoChange, i.TrackingIssue = p, trackingIssue
+	}
+}
+
+// ExpectValue only updates the Keys if they currently have the value, with
+// skip, the update is Skipped rather than failing with ErrUnexpectedValue.
+func ExpectValue(value string, skip bool) UpdateOption {
+	return func(i *v1.Input) {
+		i.ExpectedValue, i.SkipOnMismatch = &value, skip
+	}
+}
+
+// ExpectPattern only updates the Keys if they currently match the regular
+// expression, with skip, the update is Skipped rather than failing with
+// ErrUnexpectedValue.
+func ExpectPattern(pattern string, skip bool) UpdateOption {
+	return func(i *v1.Input) {
+		i.ExpectedPattern, i.SkipOnMismatch = pattern, skip
+	}
+}
+
+// StrictPaths fails the update with ErrMissingParent if the parents of a Key
+// don't exist, unless ensure is true, which creates them.
+func StrictPaths(ensure bool) UpdateOption {
+	return func(i *v1.Input) {
+		i.StrictPaths, i.EnsurePath = true, ensure
+	}
+}
+
+// Validate validates the updated files.
+func Validate(v syaml.Validator) UpdateOption {
+	return func(i *v1.Input) {
+		i.Validator = v
+	}
+}
+
+// Encrypted decrypts and re-encrypts the files e.g. with SOPS.
+func Encrypted(e v1.Encryption) UpdateOption {
+	return func(i *v1.Input) {
+		i.Encryption = e
+	}
+}
+
+// SecretKeys redacts the values of keys with paths matching the regular
+// expressions in messages, diffs and traces.
+func SecretKeys(patterns ...string) UpdateOption {
+	return func(i *v1.Input) {
+		i.SecretKeys = append(i.SecretKeys, patterns...)
+	}
+}
+
+// TriggeredBy records the event that caused the update.
+func TriggeredBy(t *v1.Trigger) UpdateOption {
+	return func(i *v1.Input) {
+		i.Trigger = t
+	}
+}
+
+// PostActions are applied to the PullRequest after it's created, e.g.
+// v1.AddLabels("automated"), failures are recorded in the Result.
+func PostActions(actions ...v1.PostAction) UpdateOption {
+	return func(i *v1.Input) {
+		i.PostActions = append(i.PostActions, actions...)
+	}
+}
@@ -0,0 +1,72 @@
+package updater
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	v1 "github.com/agill17/pkg/updater"
+)
+
+func TestUpdateOptions(t *testing.T) {
+	expected := "old-image"
+	trigger := &v1.Trigger{Source: "test"}
+	optionTests := []struct {
+		name string
+		opts []UpdateOption
+		want v1.Input
+	}{
+		{"NewBranch", []UpdateOption{NewBranch("test-branch-")}, v1.Input{BranchGenerateName: "test-branch-"}},
+		{"BranchName", []UpdateOption{BranchName("update-image", true)}, v1.Input{NewBranchName: "update-image", ResetBranch: true}},
+		{"BaseBranch", []UpdateOption{BaseBranch("release-1.2")}, v1.Input{BaseBranch: "release-1.2"}},
+		{"Fork", []UpdateOption{Fork("my-bot/my-repo")}, v1.Input{Fork: "my-bot/my-repo"}},
+		{"CommitMessage", []UpdateOption{CommitMessage("updating the image")}, v1.Input{CommitMessage: "updating the image"}},
+		{"PullRequest", []UpdateOption{PullRequest("Update the image", "Updating the image."), IncludeDiff(SemanticDiff)},
+			v1.Input{PullRequest: v1.PullRequestInput{Title: "Update the image", Body: "Updating the image.", IncludeDiff: true, DiffStyle: SemanticDiff}}},
+		{"MaxDiffLines", []UpdateOption{MaxDiffLines(100)}, v1.Input{PullRequest: v1.PullRequestInput{MaxDiffLines: 100}}},
+		{"Labels", []UpdateOption{Labels("automated"), Labels("env/prod")}, v1.Input{PullRequest: v1.PullRequestInput{Labels: []string{"automated", "env/prod"}}}},
+		{"Draft", []UpdateOption{Draft()}, v1.Input{PullRequest: v1.PullRequestInput{Draft: true}}},
+		{"AutoMerge", []UpdateOption{AutoMerge("squash")}, v1.Input{PullRequest: v1.PullRequestInput{AutoMerge: "squash"}}},
+		{"CodeOwners", []UpdateOption{CodeOwners()}, v1.Input{PullRequest: v1.PullRequestInput{CodeOwners: true}}},
+		{"Comment", []UpdateOption{Comment("Please review")}, v1.Input{PullRequest: v1.PullRequestInput{Comment: "Please review"}}},
+		{"Milestone", []UpdateOption{Milestone("v1.2.0")}, v1.Input{PullRequest: v1.PullRequestInput{Milestone: "v1.2.0"}}},
+		{"Project", []UpdateOption{Project("PVT_kwDOA", "In review")}, v1.Input{PullRequest: v1.PullRequestInput{Project: "PVT_kwDOA", ProjectColumn: "In review"}}},
+		{"Reviewers", []UpdateOption{Reviewers("octocat"), TeamReviewers("my-org/frontend"), Assignees("hubot")}, v1.Input{PullRequest: v1.PullRequestInput{Reviewers: []string{"octocat"}, TeamReviewers: []string{"my-org/frontend"}, Assignees: []string{"hubot"}}}},
+		{"ReusePullRequest", []UpdateOption{ReusePullRequest(), Supersede(SupersedeClose)}, v1.Input{ReusePullRequest: true, Supersede: SupersedeClose}},
+		{"OnNoChange", []UpdateOption{OnNoChange(NoChangeComment, 12)}, v1.Input{NoChange: NoChangeComment, TrackingIssue: 12}},
+		{"ExpectValue", []UpdateOption{ExpectValue(expected, true)}, v1.Input{ExpectedValue: &expected, SkipOnMismatch: true}},
+		{"ExpectPattern", []UpdateOption{ExpectPattern("^old-", false)}, v1.Input{ExpectedPattern: "^old-"}},
+		{"StrictPaths", []UpdateOption{StrictPaths(true)}, v1.Input{StrictPaths: true, EnsurePath: true}},
+		{"SecretKeys", []UpdateOption{SecretKeys("password$"), SecretKeys("token$")}, v1.Input{SecretKeys: []string{"password$", "token$"}}},
+		{"TriggeredBy", []UpdateOption{TriggeredBy(trigger)}, v1.Input{Trigger: trigger}},
+		{"later options override", []UpdateOption{CommitMessage("first"), CommitMessage("second")}, v1.Input{CommitMessage: "second"}},
+	}
+
+	for _, tt := range optionTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			input := v1.Input{}
+			for _, o := range tt.opts {
+				o(&input)
+			}
+
+			if diff := cmp.Diff(tt.want, input); diff != "" {
+				rt.Fatalf("failed to apply options:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPostActionsOption(t *testing.T) {
+	input := v1.Input{}
+
+	PostActions(v1.AddLabels("automated"))(&input)
+	PostActions(v1.RequestReviewers("octocat"))(&input)
+
+	names := []string{}
+	for _, a := range input.PostActions {
+		names = append(names, a.Name())
+	}
+	if diff := cmp.Diff([]string{"add-labels", "request-reviewers"}, names); diff != "" {
+		t.Fatalf("failed to apply options:\n%s", diff)
+	}
+}
@@ -0,0 +1,154 @@
+// Package updater is version 2 of the updater API, it applies a Change to one
+// or more files in a repo with a single context-first call, configured with
+// per-call options, and returns a typed Result.
+//
+// It's built on the version 1 Updater, so the behaviour is the same, and the
+// adapters in this package allow consumers to migrate incrementally.
+package updater
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-logr/logr"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/syaml"
+	v1 "github.com/agill17/pkg/updater"
+)
+
+// Option configures an Updater, the version 1 options can be used e.g.
+// v1.RepoLocks.
+type Option = v1.UpdaterFunc
+
+// State describes the outcome of an update.
+type State = v1.UpdateState
+
+const (
+	// Unchanged indicates that the files already had the values, and nothing
+	// was committed.
+	Unchanged = v1.Unchanged
+	// Committed indicates that the change was committed without a
+	// PullRequest, directly to the branch, or by UpdateFile to the Branch of
+	// the Result.
+	Committed = v1.Committed
+	// PullRequestCreated indicates that the change was committed to a new
+	// branch, and a PullRequest was opened.
+	PullRequestCreated = v1.PullRequestCreated
+	// Skipped indicates that a current value was not the expected value, and
+	// the update was configured to skip.
+	Skipped = v1.Skipped
+	// PullRequestUpdated indicates that the change was committed to the
+	// branch of an existing PullRequest.
+	PullRequestUpdated = v1.PullRequestUpdated
+)
+
+// Result records the outcome of an update.
+type Result = v1.UpdateResult
+
+// Plan is what an update would do, as calculated by Updater.Plan.
+type Plan = v1.UpdatePlan
+
+// The errors returned by Update can be identified with errors.Is.
+var (
+	// ErrNoFiles is returned when the Change has no files.
+	ErrNoFiles = errors.New("the change has no files")
+	// ErrDirectCommit is returned when the Updater requires PullRequests,
+	// and the update would commit directly to the branch.
+	ErrDirectCommit = v1.ErrDirectCommit
+	// ErrUnexpectedValue is returned when a current value is not the
+	// expected value, and the update is not configured to skip.
+	ErrUnexpectedValue = syaml.ErrUnexpectedValue
+	// ErrMissingParent is returned when the parents of a Key don't exist, and
+	// the update has StrictPaths.
+	ErrMissingParent = syaml.ErrMissingParent
+	// ErrFileNotFound is matched when a file doesn't exist in the repo.
+	ErrFileNotFound = v1.ErrFileNotFound
+	// ErrBranchExists is matched when the branch to create already exists.
+	ErrBranchExists = v1.ErrBranchExists
+	// ErrPermissionDenied is matched when the git provider rejects a request
+	// as unauthorized or forbidden.
+	ErrPermissionDenied = v1.ErrPermissionDenied
+	// ErrRepoReadOnly is matched when the repo is archived or disabled.
+	ErrRepoReadOnly = v1.ErrRepoReadOnly
+	// ErrNoChange is matched when the version 1 ApplyUpdateToFile leaves the
+	// file unchanged, UpdateFile returns an Unchanged Result instead.
+	ErrNoChange = v1.ErrNoChange
+	// ErrDraftsUnsupported is returned when the update is a Draft, and the
+	// git provider doesn't support drafts.
+	ErrDraftsUnsupported = v1.ErrDraftsUnsupported
+)
+
+// SCMError is returned when a request to the git provider fails, use
+// errors.As to get the response status.
+type SCMError = v1.SCMError
+
+// CommitInput configures the file, branch and commit of UpdateFile.
+type CommitInput = v1.CommitInput
+
+// ReadOnlyRepoError is returned when the repo is archived or disabled, before
+// any changes are made.
+type ReadOnlyRepoError = v1.ReadOnlyRepoError
+
+// New creates and returns a new Updater.
+func New(l logr.Logger, c client.GitClient, opts ...Option) *Updater {
+	return Wrap(v1.New(l, c, opts...))
+}
+
+// Wrap returns an Updater that applies changes with an existing version 1
+// Updater.
+func Wrap(u *v1.Updater) *Updater {
+	return &Updater{updater: u}
+}
+
+// Updater applies Changes to the files in Git repos.
+type Updater struct {
+	updater *v1.Updater
+}
+
+// V1 returns the version 1 Updater, for the operations that are not part of
+// this API.
+func (u *Updater) V1() *v1.Updater {
+	return u.updater
+}
+
+// Update applies the Change, committing the changed files to a single branch,
+// the options configure the branch, commit and PullRequest.
+//
+// Without a NewBranch or BranchName option, the change is committed directly
+// to the Change's Branch.
+func (u *Updater) Update(ctx context.Context, c Change, opts ...UpdateOption) (*Result, error) {
+	input, err := c.Input(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return u.updater.Update(ctx, input)
+}
+
+// UpdateFile applies the ContentUpdater to the file of the CommitInput and
+// commits the change, to a new branch if it has a BranchGenerateName, as the
+// version 1 ApplyUpdateToFile does, without opening a PullRequest.
+//
+// If the file is unchanged, the State is Unchanged and no branch or commit is
+// created, otherwise it's Committed, with the Branch the change was committed
+// to.
+func (u *Updater) UpdateFile(ctx context.Context, input CommitInput, f ContentUpdater) (*Result, error) {
+	branch, err := u.updater.ApplyUpdateToFile(ctx, input, f)
+	if errors.Is(err, v1.ErrNoChange) {
+		return &Result{State: Unchanged}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{State: Committed, Branch: branch}, nil
+}
+
+// Plan calculates what Update would do with the Change and options, without
+// creating branches, commits or PullRequests.
+func (u *Updater) Plan(ctx context.Context, c Change, opts ...UpdateOption) (*Plan, error) {
+	input, err := c.Input(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return u.updater.Plan(ctx, input)
+}
@@ -0,0 +1,247 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+	v1 "github.com/agill17/pkg/updater"
+)
+
+const (
+	testRepo   = "testorg/testrepo"
+	testBranch = "main"
+	testFile   = "config/my/file.yaml"
+	testSHA    = "980a0d5f19a64b4b30a87d4206aade58726b60e3"
+)
+
+type stubNameGenerator struct {
+	name string
+}
+
+func (s stubNameGenerator) PrefixedName(p string) string {
+	return p + s.name
+}
+
+func TestUpdate(t *testing.T) {
+	updateTests := []struct {
+		name       string
+		opts       []UpdateOption
+		wantState  State
+		wantBranch string
+	}{
+		{"direct commit", nil, Committed, testBranch},
+		{"new branch", []UpdateOption{NewBranch("test-branch-"), PullRequest("Update the image", "Updating the image.")}, PullRequestCreated, "test-branch-a"},
+		{"named branch", []UpdateOption{BranchName("update-image", false), PullRequest("Update the image", "Updating the image.")}, PullRequestCreated, "update-image"},
+		{"skipped", []UpdateOption{ExpectValue("older-image", true)}, Skipped, ""},
+	}
+
+	for _, tt := range updateTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := newMockClient(rt)
+			u := New(zap.New(), m, v1.NameGenerator(stubNameGenerator{"a"}))
+
+			r, err := u.Update(context.Background(), Change{
+				Repo:   testRepo,
+				Branch: testBranch,
+				Files:  []FileChange{{Filename: testFile, Key: "test.image", NewValue: "new-image"}},
+			}, append([]UpdateOption{CommitMessage("updating the image")}, tt.opts...)...)
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if r.State != tt.wantState {
+				rt.Errorf("got state %s, want %s", r.State, tt.wantState)
+			}
+			if r.Branch != tt.wantBranch {
+				rt.Errorf("got branch %q, want %q", r.Branch, tt.wantBranch)
+			}
+		})
+	}
+}
+
+func TestUpdateWithMultipleFiles(t *testing.T) {
+	m := newMockClient(t)
+	m.AddFileContents(testRepo, "config/other.yaml", testBranch, []byte("test:\n  image: old-image\n  debug: true\n"))
+	u := New(zap.New(), m, v1.NameGenerator(stubNameGenerator{"a"}))
+
+	r, err := u.Update(context.Background(), Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files: []FileChange{
+			{Filename: testFile, Key: "test.image", NewValue: "new-image"},
+			{Filename: "config/other.yaml", Key: "test.debug", Delete: true},
+		},
+	}, NewBranch("test-branch-"), CommitMessage("updating the image"), PullRequest("Update the image", "Updating the image."))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", r.State, PullRequestCreated)
+	}
+	m.AssertPullRequestCreated(testRepo, &scm.PullRequestInput{
+		Title: "Update the image",
+		Body:  "Updating the image.",
+		Head:  "test-branch-a",
+		Base:  testBranch,
+	})
+	files := map[string]string{
+		testFile:            "test:\n  image: new-image\n",
+		"config/other.yaml": "test:\n  image: old-image\n",
+	}
+	for filename, want := range files {
+		if diff := cmp.Diff(want, string(m.GetUpdatedContents(testRepo, filename, "test-branch-a"))); diff != "" {
+			t.Errorf("failed to update %s:\n%s", filename, diff)
+		}
+	}
+}
+
+func TestUpdateFile(t *testing.T) {
+	fileTests := []struct {
+		name       string
+		f          ContentUpdater
+		wantState  State
+		wantBranch string
+	}{
+		{"changed", v1.UpdateYAML("test.image", "new-image"), Committed, "test-branch-a"},
+		{"unchanged", v1.UpdateYAML("test.image", "old-image"), Unchanged, ""},
+	}
+
+	for _, tt := range fileTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := newMockClient(rt)
+			u := New(zap.New(), m, v1.NameGenerator(stubNameGenerator{"a"}))
+
+			r, err := u.UpdateFile(context.Background(), CommitInput{
+				Repo:               testRepo,
+				Branch:             testBranch,
+				Filename:           testFile,
+				BranchGenerateName: "test-branch-",
+				CommitMessage:      "updating the image",
+			}, tt.f)
+
+			if err != nil {
+				rt.Fatal(err)
+			}
+			if r.State != tt.wantState {
+				rt.Errorf("got state %s, want %s", r.State, tt.wantState)
+			}
+			if r.Branch != tt.wantBranch {
+				rt.Errorf("got branch %q, want %q", r.Branch, tt.wantBranch)
+			}
+			if tt.wantState == Unchanged {
+				m.AssertNoBranchesCreated()
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	m := newMockClient(t)
+	u := New(zap.New(), m, v1.NameGenerator(stubNameGenerator{"a"}))
+
+	p, err := u.Plan(context.Background(), Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files:  []FileChange{{Filename: testFile, Key: "test.image", NewValue: "new-image"}},
+	}, NewBranch("test-branch-"), CommitMessage("updating the image"), PullRequest("Update the image", "Updating the image."))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.State != PullRequestCreated {
+		t.Fatalf("got state %s, want %s", p.State, PullRequestCreated)
+	}
+	if diff := cmp.Diff("test:\n  image: new-image\n", string(p.Files[0].Content)); diff != "" {
+		t.Fatalf("incorrect content:\n%s", diff)
+	}
+	if p.PullRequest.Title != "Update the image" {
+		t.Fatalf("got title %q, want %q", p.PullRequest.Title, "Update the image")
+	}
+	m.AssertNoBranchesCreated()
+	m.AssertNoPullRequestsCreated()
+}
+
+func TestUpdateWithNoFiles(t *testing.T) {
+	u := New(zap.New(), mock.New(t))
+
+	_, err := u.Update(context.Background(), Change{Repo: testRepo, Branch: testBranch})
+
+	if !errors.Is(err, ErrNoFiles) {
+		t.Fatalf("got %v, want ErrNoFiles", err)
+	}
+}
+
+func TestUpdateErrors(t *testing.T) {
+	errorTests := []struct {
+		name    string
+		key     string
+		opts    []Option
+		update  []UpdateOption
+		want    error
+		wantErr string
+	}{
+		{"direct commit", "test.image", []Option{v1.RequirePullRequests()}, nil, ErrDirectCommit, "a BranchGenerateName is required"},
+		{"unexpected value", "test.image", nil, []UpdateOption{ExpectValue("older-image", false)}, ErrUnexpectedValue, `test.image is "old-image", expected "older-image"`},
+		{"missing parent", "spec.image", nil, []UpdateOption{StrictPaths(false)}, ErrMissingParent, "parent does not exist"},
+	}
+
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			u := New(zap.New(), newMockClient(rt), tt.opts...)
+
+			_, err := u.Update(context.Background(), Change{
+				Repo:   testRepo,
+				Branch: testBranch,
+				Files:  []FileChange{{Filename: testFile, Key: tt.key, NewValue: "new-image"}},
+			}, tt.update...)
+
+			if !errors.Is(err, tt.want) {
+				rt.Fatalf("got %v, want %v", err, tt.want)
+			}
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestUpdateWithArchivedRepo(t *testing.T) {
+	m := newMockClient(t)
+	m.AddRepoStatus(testRepo, &client.RepoStatus{Archived: true})
+	u := New(zap.New(), m)
+
+	_, err := u.Update(context.Background(), Change{
+		Repo:   testRepo,
+		Branch: testBranch,
+		Files:  []FileChange{{Filename: testFile, Key: "test.image", NewValue: "new-image"}},
+	})
+
+	var readOnly *ReadOnlyRepoError
+	if !errors.Is(err, ErrRepoReadOnly) || !errors.As(err, &readOnly) {
+		t.Fatalf("got %v, want a ReadOnlyRepoError", err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	u := v1.New(zap.New(), mock.New(t))
+
+	if w := Wrap(u); w.V1() != u {
+		t.Fatalf("got %v, want %v", w.V1(), u)
+	}
+}
+
+func newMockClient(t *testing.T) *mock.MockClient {
+	m := mock.New(t)
+	m.AddFileContents(testRepo, testFile, testBranch, []byte("test:\n  image: old-image\n"))
+	m.AddBranchHead(testRepo, testBranch, testSHA)
+	return m
+}
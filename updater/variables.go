@@ -0,0 +1,101 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agill17/pkg/client"
+)
+
+// ErrVariablesUnsupported is returned when the git provider doesn't support
+// CI variables.
+var ErrVariablesUnsupported = errors.New("the git provider does not support CI variables")
+
+// VariableInput is used to configure the update of a provider-side CI
+// variable, e.g. a GitHub Actions or GitLab CI variable.
+type VariableInput struct {
+	Repo    string   // e.g. my-org/my-repo
+	Name    string   // e.g. IMAGE_TAG
+	Value   string   // e.g. v1.2.0
+	Trigger *Trigger // The event that caused the update
+}
+
+// UpdateVariable updates a CI variable in the repo, creating it if it doesn't
+// exist, and returns true if the variable was changed.
+//
+// If the variable already has the value, no change is made.
+//
+// As with Update, the update waits for the repo's lock with RepoLocks, a
+// read-only repo is an error that matches ErrRepoReadOnly, and with
+// RequirePullRequests, the update is rejected with ErrDirectCommit as it can't
+// be reviewed. The Decision is recorded, with the Name as the Key, and a
+// failed update writes a SupportBundle, with the Value redacted.
+func (u *Updater) UpdateVariable(ctx context.Context, input *VariableInput) (bool, error) {
+	changed, err := u.lockedUpdateVariable(ctx, input)
+	r := &UpdateResult{State: Unchanged}
+	if changed {
+		r.State = Committed
+	}
+	u.decide(input.updateInput(), r, err)
+	return changed, err
+}
+
+func (u *Updater) lockedUpdateVariable(ctx context.Context, input *VariableInput) (bool, error) {
+	unlock, err := u.lockRepo(ctx, input.Repo)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+	if u.bundleWriter == nil {
+		return u.updateVariable(ctx, input)
+	}
+	rec := &recordingClient{GitClient: u.gitClient}
+	recording := *u
+	recording.gitClient = rec
+	start := time.Now()
+	changed, err := recording.updateVariable(ctx, input)
+	if err != nil {
+		u.writeSupportBundle(input.updateInput(), rec.calls, start, err)
+	}
+	return changed, err
+}
+
+func (u *Updater) updateVariable(ctx context.Context, input *VariableInput) (bool, error) {
+	if input.Trigger != nil {
+		u.log.Info("update triggered", input.Trigger.keysAndValues()...)
+	}
+	if u.requirePRs {
+		return false, fmt.Errorf("%w: variable %s in repo %s can't be updated with a PullRequest", ErrDirectCommit, input.Name, input.Repo)
+	}
+	caps, err := u.gitClient.Capabilities(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get the git provider capabilities: %w", err)
+	}
+	if !caps.Variables {
+		return false, ErrVariablesUnsupported
+	}
+	if err := u.checkWritable(ctx, input.Repo); err != nil {
+		return false, err
+	}
+	current, err := u.gitClient.GetVariable(ctx, input.Repo, input.Name)
+	if err != nil && !client.IsNotFound(err) {
+		return false, scmError(err, input.Repo, "get variable "+input.Name, nil)
+	}
+	if err == nil && current == input.Value {
+		u.log.Info("no change required", "repo", input.Repo, "variable", input.Name)
+		return false, nil
+	}
+	if err := u.gitClient.SetVariable(ctx, input.Repo, input.Name, input.Value); err != nil {
+		return false, scmError(err, input.Repo, "set variable "+input.Name, nil)
+	}
+	u.log.Info("updated variable", "repo", input.Repo, "variable", input.Name)
+	return true, nil
+}
+
+// updateInput returns the Input recorded in Decisions and SupportBundles for
+// the update of the variable.
+func (i *VariableInput) updateInput() *Input {
+	return &Input{Repo: i.Repo, Key: i.Name, NewValue: i.Value, Trigger: i.Trigger}
+}
@@ -0,0 +1,156 @@
+package updater
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+func TestUpdateVariable(t *testing.T) {
+	m := mock.New(t)
+	m.AddVariable(testGitHubRepo, "IMAGE_TAG", "v1")
+	updater := New(zap.New(), m)
+
+	changed, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("variable was not changed")
+	}
+	m.AssertVariableSet(testGitHubRepo, "IMAGE_TAG", "v2")
+}
+
+func TestUpdateVariableWithMissingVariable(t *testing.T) {
+	m := mock.New(t)
+	updater := New(zap.New(), m)
+
+	changed, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("variable was not changed")
+	}
+	m.AssertVariableSet(testGitHubRepo, "IMAGE_TAG", "v2")
+}
+
+func TestUpdateVariableWithNoChange(t *testing.T) {
+	m := mock.New(t)
+	m.AddVariable(testGitHubRepo, "IMAGE_TAG", "v2")
+	updater := New(zap.New(), m)
+
+	changed, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed {
+		t.Fatal("variable was changed")
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateVariableWithUnsupportedProvider(t *testing.T) {
+	m := mock.New(t)
+	m.Caps = client.Capabilities{}
+	updater := New(zap.New(), m)
+
+	_, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if !errors.Is(err, ErrVariablesUnsupported) {
+		t.Fatalf("got %v, want ErrVariablesUnsupported", err)
+	}
+	m.AssertNoInteractions()
+}
+
+func TestUpdateVariableWithSetFailure(t *testing.T) {
+	m := mock.New(t)
+	m.SetVariableErr = errors.New("forbidden")
+	updater := New(zap.New(), m)
+
+	_, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if !test.MatchError(t, "failed to set variable IMAGE_TAG: forbidden", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+func TestUpdateVariableWithPolicies(t *testing.T) {
+	policyTests := []struct {
+		name string
+		opts []UpdaterFunc
+		want error
+	}{
+		{"require pull requests", []UpdaterFunc{RequirePullRequests()}, ErrDirectCommit},
+		{"read-only repo", nil, ErrRepoReadOnly},
+	}
+
+	for _, tt := range policyTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddRepoStatus(testGitHubRepo, &client.RepoStatus{Archived: true})
+			updater := New(zap.New(), m, tt.opts...)
+
+			_, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+			if !errors.Is(err, tt.want) {
+				rt.Fatalf("got %v, want %v", err, tt.want)
+			}
+			m.RefuteVariableSet(testGitHubRepo, "IMAGE_TAG")
+		})
+	}
+}
+
+func TestUpdateVariableRecordsDecision(t *testing.T) {
+	m := mock.New(t)
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, Decisions(&buf))
+
+	_, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions := readDecisions(t, &buf)
+	if l := len(decisions); l != 1 {
+		t.Fatalf("got %d decisions, want 1", l)
+	}
+	if d := decisions[0]; d.Key != "IMAGE_TAG" || d.Reason != DecisionApplied {
+		t.Fatalf("got decision %#v", d)
+	}
+}
+
+func TestUpdateVariableWritesSupportBundleOnFailure(t *testing.T) {
+	m := mock.New(t)
+	m.SetVariableErr = client.NewStatusError("failed to set variable", http.StatusForbidden)
+	var buf bytes.Buffer
+	updater := New(zap.New(), m, SupportBundles(&buf))
+
+	_, err := updater.UpdateVariable(context.Background(), &VariableInput{Repo: testGitHubRepo, Name: "IMAGE_TAG", Value: "v2"})
+
+	if !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("got %v, want %v", err, ErrPermissionDenied)
+	}
+	bundle := SupportBundle{}
+	if err := json.Unmarshal(buf.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Input.Key != "IMAGE_TAG" || bundle.Input.NewValue != redacted {
+		t.Fatalf("got input %#v", bundle.Input)
+	}
+	if l := len(bundle.Calls); l == 0 || bundle.Calls[l-1].Method != "SetVariable" {
+		t.Fatalf("got calls %#v", bundle.Calls)
+	}
+}
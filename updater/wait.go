@@ -0,0 +1,122 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+)
+
+const defaultPollInterval = 15 * time.Second
+
+var (
+	// ErrPullRequestClosed is matched by the errors when a PullRequest that
+	// is being waited for is closed without being merged.
+	ErrPullRequestClosed = errors.New("the pull request was closed without being merged")
+	// ErrChecksFailed is matched by the errors when a check of a PullRequest
+	// that is being waited for fails.
+	ErrChecksFailed = errors.New("the pull request checks failed")
+)
+
+// PollInterval is an option func for the Updater creation function, it sets
+// how often WaitForChecks and WaitForMerge poll the PullRequest, the default
+// is 15 seconds.
+func PollInterval(d time.Duration) UpdaterFunc {
+	return func(u *Updater) {
+		u.pollInterval = d
+	}
+}
+
+// WaitForChecks blocks until the checks of the head commit of the PullRequest
+// pass, and returns the SHA of the commit, use a context with a deadline to
+// limit the wait.
+//
+// At least one check must be reported, as checks that haven't started can't
+// be told apart from there being none, an error matching ErrChecksFailed is
+// returned if any fail.
+func (u *Updater) WaitForChecks(ctx context.Context, repo string, number int) (string, error) {
+	var sha string
+	err := u.poll(ctx, repo, number, "to pass its checks", func(pr *scm.PullRequest) (bool, error) {
+		sha = pr.Sha
+		if pr.Merged {
+			return true, nil
+		}
+		if pr.Closed {
+			return false, fmt.Errorf("%w: %s#%d", ErrPullRequestClosed, repo, number)
+		}
+		return u.checksPassed(ctx, repo, number, pr.Sha)
+	})
+	return sha, err
+}
+
+// WaitForMerge blocks until the PullRequest is merged, e.g. by auto-merge once
+// its checks pass, and returns the SHA of the merge commit, use a context with
+// a deadline to limit the wait.
+//
+// An error matching ErrChecksFailed is returned if any check fails, as the
+// PullRequest won't be merged, and one matching ErrPullRequestClosed if it's
+// closed.
+func (u *Updater) WaitForMerge(ctx context.Context, repo string, number int) (string, error) {
+	var sha string
+	err := u.poll(ctx, repo, number, "to be merged", func(pr *scm.PullRequest) (bool, error) {
+		if pr.Merged {
+			sha = pr.MergeSha
+			return true, nil
+		}
+		if pr.Closed {
+			return false, fmt.Errorf("%w: %s#%d", ErrPullRequestClosed, repo, number)
+		}
+		_, err := u.checksPassed(ctx, repo, number, pr.Sha)
+		return false, err
+	})
+	return sha, err
+}
+
+// poll gets the PullRequest every poll interval until done returns true, or
+// an error.
+func (u *Updater) poll(ctx context.Context, repo string, number int, waitingFor string, done func(*scm.PullRequest) (bool, error)) error {
+	ticker := time.NewTicker(u.pollInterval)
+	defer ticker.Stop()
+	for {
+		pr, err := u.gitClient.GetPullRequest(ctx, repo, number)
+		if err != nil {
+			return scmError(err, repo, fmt.Sprintf("get pull request %d", number), nil)
+		}
+		ok, err := done(pr)
+		if err != nil || ok {
+			return err
+		}
+		u.log.V(1).Info("waiting for PullRequest "+waitingFor, "repo", repo, "number", number)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for pull request %s#%d %s: %w", repo, number, waitingFor, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// checksPassed returns true if the checks of the commit passed, and an error
+// matching ErrChecksFailed, naming the failed checks, if any failed.
+func (u *Updater) checksPassed(ctx context.Context, repo string, number int, sha string) (bool, error) {
+	status, err := u.gitClient.GetCombinedStatus(ctx, repo, sha)
+	if err != nil {
+		return false, scmError(err, repo, "get the status of commit "+sha, nil)
+	}
+	switch status.State {
+	case scm.StateSuccess:
+		return true, nil
+	case scm.StateFailure, scm.StateError, scm.StateCanceled:
+		failed := []string{}
+		for _, s := range status.Statuses {
+			switch s.State {
+			case scm.StateFailure, scm.StateError, scm.StateCanceled:
+				failed = append(failed, s.Label)
+			}
+		}
+		return false, fmt.Errorf("%w: %s#%d: %s", ErrChecksFailed, repo, number, strings.Join(failed, ", "))
+	}
+	return false, nil
+}
@@ -0,0 +1,141 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/agill17/pkg/client"
+	"github.com/agill17/pkg/client/mock"
+	"github.com/agill17/pkg/test"
+)
+
+const testHeadSHA = "6dcb09b5b57875f334f61aebed695e2e4193db5e"
+
+func TestWaitForMerge(t *testing.T) {
+	m := mock.New(t)
+	m.AddPullRequestStates(testGitHubRepo,
+		&scm.PullRequest{Number: 1, Sha: testHeadSHA},
+		&scm.PullRequest{Number: 1, Sha: testHeadSHA},
+		&scm.PullRequest{Number: 1, Sha: testHeadSHA, Merged: true, Closed: true, MergeSha: "e5bd3914e2e596debea16f433f57875b5b90bcd6"})
+	m.AddCombinedStatuses(testGitHubRepo, testHeadSHA,
+		&scm.CombinedStatus{State: scm.StatePending},
+		&scm.CombinedStatus{State: scm.StateSuccess})
+	updater := New(zap.New(), m, PollInterval(time.Millisecond))
+
+	sha, err := updater.WaitForMerge(context.Background(), testGitHubRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sha != "e5bd3914e2e596debea16f433f57875b5b90bcd6" {
+		t.Fatalf("got merge SHA %q", sha)
+	}
+}
+
+func TestWaitForMergeFailures(t *testing.T) {
+	failureTests := []struct {
+		name    string
+		pr      *scm.PullRequest
+		status  *scm.CombinedStatus
+		wantErr error
+		want    string
+	}{
+		{
+			name:    "closed",
+			pr:      &scm.PullRequest{Number: 1, Sha: testHeadSHA, Closed: true},
+			wantErr: ErrPullRequestClosed,
+			want:    "the pull request was closed without being merged: testorg/testrepo#1",
+		},
+		{
+			name: "failed checks",
+			pr:   &scm.PullRequest{Number: 1, Sha: testHeadSHA},
+			status: &scm.CombinedStatus{State: scm.StateFailure, Statuses: []*scm.Status{
+				{Label: "lint", State: scm.StateSuccess},
+				{Label: "test", State: scm.StateFailure},
+				{Label: "e2e", State: scm.StateCanceled},
+			}},
+			wantErr: ErrChecksFailed,
+			want:    "the pull request checks failed: testorg/testrepo#1: test, e2e",
+		},
+		{
+			name:    "timed out",
+			pr:      &scm.PullRequest{Number: 1, Sha: testHeadSHA},
+			status:  &scm.CombinedStatus{State: scm.StatePending},
+			wantErr: context.DeadlineExceeded,
+			want:    "stopped waiting for pull request testorg/testrepo#1 to be merged: context deadline exceeded",
+		},
+	}
+
+	for _, tt := range failureTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			m := mock.New(rt)
+			m.AddPullRequestStates(testGitHubRepo, tt.pr)
+			if tt.status != nil {
+				m.AddCombinedStatuses(testGitHubRepo, testHeadSHA, tt.status)
+			}
+			updater := New(zap.New(), m, PollInterval(time.Millisecond))
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			_, err := updater.WaitForMerge(ctx, testGitHubRepo, 1)
+
+			if !errors.Is(err, tt.wantErr) {
+				rt.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if !test.MatchError(rt, tt.want, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestWaitForMergeWithMissingPullRequest(t *testing.T) {
+	updater := New(zap.New(), mock.New(t), PollInterval(time.Millisecond))
+
+	_, err := updater.WaitForMerge(context.Background(), testGitHubRepo, 1)
+
+	if !test.MatchError(t, "failed to get pull request 1: pull request not found", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+	if client.StatusCode(err) != 404 {
+		t.Fatalf("got status %d, want 404", client.StatusCode(err))
+	}
+}
+
+func TestWaitForChecks(t *testing.T) {
+	m := mock.New(t)
+	m.AddPullRequestStates(testGitHubRepo, &scm.PullRequest{Number: 1, Sha: testHeadSHA})
+	m.AddCombinedStatuses(testGitHubRepo, testHeadSHA,
+		&scm.CombinedStatus{State: scm.StateUnknown},
+		&scm.CombinedStatus{State: scm.StatePending},
+		&scm.CombinedStatus{State: scm.StateSuccess})
+	updater := New(zap.New(), m, PollInterval(time.Millisecond))
+
+	sha, err := updater.WaitForChecks(context.Background(), testGitHubRepo, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sha != testHeadSHA {
+		t.Fatalf("got SHA %q, want %q", sha, testHeadSHA)
+	}
+}
+
+func TestWaitForChecksWithFailedCheck(t *testing.T) {
+	m := mock.New(t)
+	m.AddPullRequestStates(testGitHubRepo, &scm.PullRequest{Number: 1, Sha: testHeadSHA})
+	m.AddCombinedStatuses(testGitHubRepo, testHeadSHA,
+		&scm.CombinedStatus{State: scm.StateError, Statuses: []*scm.Status{{Label: "ci/build", State: scm.StateError}}})
+	updater := New(zap.New(), m, PollInterval(time.Millisecond))
+
+	_, err := updater.WaitForChecks(context.Background(), testGitHubRepo, 1)
+
+	if !test.MatchError(t, "the pull request checks failed: testorg/testrepo#1: ci/build", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
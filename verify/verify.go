@@ -0,0 +1,67 @@
+// Package verify checks content fetched from other repos before it's used,
+// against sha256sum checksum files, and detached OpenPGP signatures.
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+// ErrUnverified is returned when content doesn't match its checksum or
+// signature.
+var ErrUnverified = errors.New("content failed verification")
+
+// SHA256Sum checks the data against the checksum for the name in sums, which
+// is in the format written by sha256sum e.g.
+//
+//	4d4d...  charts/app/values.yaml
+//
+// Names are compared without a leading "./", and a name with no checksum fails
+// verification.
+func SHA256Sum(sums []byte, name string, data []byte) error {
+	name = strings.TrimPrefix(name, "./")
+	scanner := bufio.NewScanner(bytes.NewReader(sums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		// sha256sum marks files read in binary mode with a leading "*".
+		if strings.TrimPrefix(strings.TrimPrefix(fields[1], "*"), "./") != name {
+			continue
+		}
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("%w: %s does not match its sha256 checksum", ErrUnverified, name)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the checksums: %w", err)
+	}
+	return fmt.Errorf("%w: %s has no sha256 checksum", ErrUnverified, name)
+}
+
+// Signature checks that the detached signature of the data, which can be
+// ASCII armored, was made by one of the keys in the armored keyring.
+func Signature(keyring string, data, signature []byte) error {
+	keys, err := openpgp.ReadArmoredKeyRing(strings.NewReader(keyring))
+	if err != nil {
+		return fmt.Errorf("failed to read the keyring: %w", err)
+	}
+	check := openpgp.CheckDetachedSignature
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		check = openpgp.CheckArmoredDetachedSignature
+	}
+	if _, err := check(keys, bytes.NewReader(data), bytes.NewReader(signature)); err != nil {
+		return fmt.Errorf("%w: %s", ErrUnverified, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package verify
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+
+	"github.com/agill17/pkg/test"
+)
+
+const testSums = `e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  empty.txt
+5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03 *./charts/app/values.yaml
+`
+
+func TestSHA256Sum(t *testing.T) {
+	sumTests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{"matching checksum", "empty.txt", "", ""},
+		{"binary mode and relative name", "charts/app/values.yaml", "hello\n", ""},
+		{"different content", "empty.txt", "tampered", "content failed verification: empty.txt does not match its sha256 checksum"},
+		{"no checksum", "charts/app/Chart.yaml", "", "content failed verification: charts/app/Chart.yaml has no sha256 checksum"},
+	}
+
+	for _, tt := range sumTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := SHA256Sum([]byte(testSums), tt.file, []byte(tt.data))
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	signer, keyring := newTestKey(t)
+	data := []byte(testSums)
+	signatureTests := []struct {
+		name      string
+		data      []byte
+		signature func() []byte
+		wantErr   string
+	}{
+		{"armored signature", data, func() []byte { return sign(t, signer, data, true) }, ""},
+		{"binary signature", data, func() []byte { return sign(t, signer, data, false) }, ""},
+		{"tampered content", []byte("tampered"), func() []byte { return sign(t, signer, data, true) }, "content failed verification: openpgp: invalid signature"},
+		{"unknown key", data, func() []byte { other, _ := newTestKey(t); return sign(t, other, data, true) }, "content failed verification: openpgp: signature made by unknown entity"},
+	}
+
+	for _, tt := range signatureTests {
+		t.Run(tt.name, func(rt *testing.T) {
+			err := Signature(keyring, tt.data, tt.signature())
+
+			if !test.MatchError(rt, tt.wantErr, err) {
+				rt.Fatalf("failed to match error: %s", err)
+			}
+			if tt.wantErr != "" && !errors.Is(err, ErrUnverified) {
+				rt.Fatalf("got %v, want ErrUnverified", err)
+			}
+		})
+	}
+}
+
+func TestSignatureWithInvalidKeyring(t *testing.T) {
+	err := Signature("not a keyring", []byte(testSums), []byte("signature"))
+
+	if !test.MatchError(t, "failed to read the keyring", err) {
+		t.Fatalf("failed to match error: %s", err)
+	}
+}
+
+// newTestKey returns a new signing key, and its armored public key.
+func newTestKey(t *testing.T) (*openpgp.Entity, string) {
+	t.Helper()
+	e, err := openpgp.NewEntity("Test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return e, b.String()
+}
+
+func sign(t *testing.T, e *openpgp.Entity, data []byte, armored bool) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	sign := openpgp.DetachSign
+	if armored {
+		sign = openpgp.ArmoredDetachSign
+	}
+	if err := sign(&b, e, bytes.NewReader(data), nil); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}